@@ -0,0 +1,493 @@
+// Package archive moves the events of dormant aggregates into cold storage.
+//
+// Applications with millions of dormant aggregates can reduce the size of
+// their primary ("hot") event store by archiving aggregates that are not
+// modified anymore. Archiving an aggregate pins a snapshot of its current
+// version, moves its events into a secondary ("cold") event store, and marks
+// the aggregate as archived in an Index:
+//
+//	store := archive.New(hot, cold, snapshots, archive.NewMemoryIndex(), func(ref aggregate.Ref) aggregate.Aggregate {
+//		return NewOrder(ref.ID)
+//	})
+//
+//	results, err := store.Archive(context.TODO(), aggregate.Ref{Name: "order", ID: id})
+//
+// The Store is an event.Store that must be used in place of the hot store. A
+// repository that uses the Store and the snapshot store fetches archived
+// aggregates from their pinned snapshots without touching the cold store.
+// Queries that require archived events of specific aggregates (e.g. because the
+// aggregate has no snapshot, or because an older version of the aggregate is
+// fetched) and inserts of new events into archived aggregates transparently
+// restore the archived aggregates into the hot store first. Use the Async
+// option to restore aggregates in the background instead, in which case a
+// *RestoringError is returned until the aggregate has been restored.
+//
+// Queries that do not filter by aggregate ID, like the queries of projection
+// jobs, do not restore archived aggregates and do not return archived events.
+// Projections that need the full event history must query the cold store in
+// addition to the Store (see eventstore.Merge).
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// DefaultRestoreTimeout is the default timeout for restorations of archived
+// aggregates in the background (see Async).
+const DefaultRestoreTimeout = time.Minute
+
+// ErrRestoring is returned by a Store that uses the Async option while an
+// archived aggregate is being restored. Errors returned by the Store are
+// *RestoringErrors that wrap ErrRestoring.
+var ErrRestoring = errors.New("aggregate is being restored")
+
+// RestoringError is returned by a Store that uses the Async option if a query
+// or insert requires an archived aggregate that has not been restored yet.
+// Callers should retry the operation after some time.
+type RestoringError struct {
+	// Aggregate is the aggregate that is being restored.
+	Aggregate aggregate.Ref
+}
+
+// Error implements error.
+func (err *RestoringError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrRestoring, err.Aggregate)
+}
+
+// Unwrap returns ErrRestoring.
+func (err *RestoringError) Unwrap() error {
+	return ErrRestoring
+}
+
+// Store is an event store that archives aggregates into a cold event store,
+// and restores them when they are needed.
+type Store struct {
+	event.Store
+
+	cold      event.Store
+	snapshots snapshot.Store
+	index     Index
+	newFunc   func(aggregate.Ref) aggregate.Aggregate
+	async     bool
+	timeout   time.Duration
+	onRestore []func(aggregate.Ref, error)
+
+	mux       sync.Mutex
+	restoring map[aggregate.Ref]struct{}
+
+	restoreMux sync.Mutex
+}
+
+// Option is an option for a Store.
+type Option func(*Store)
+
+// Result is the result of archiving a single aggregate.
+type Result struct {
+	// Aggregate is the archived aggregate.
+	Aggregate aggregate.Ref
+
+	// Version is the version of the pinned snapshot.
+	Version int
+
+	// Events is the number of events that were moved into the cold store.
+	Events int
+}
+
+// Async returns an Option that restores archived aggregates in the background.
+// Queries and inserts that require an archived aggregate return a
+// *RestoringError until the aggregate has been restored. Errors of background
+// restorations are only reported to the OnRestore functions; use OnRestore to
+// get notified about failed restorations. Background restorations are canceled
+// after the RestoreTimeout.
+func Async() Option {
+	return func(s *Store) {
+		s.async = true
+	}
+}
+
+// RestoreTimeout returns an Option that sets the timeout for restorations of
+// archived aggregates in the background (see Async). A zero Duration means no
+// timeout. Default is DefaultRestoreTimeout.
+func RestoreTimeout(d time.Duration) Option {
+	return func(s *Store) {
+		s.timeout = d
+	}
+}
+
+// OnRestore returns an Option that calls fn after each restoration of an
+// archived aggregate, with the error of the restoration, if any. fn is also
+// called if a restoration fails before the aggregate is known to be archived.
+func OnRestore(fn func(aggregate.Ref, error)) Option {
+	return func(s *Store) {
+		s.onRestore = append(s.onRestore, fn)
+	}
+}
+
+// New returns a Store that archives the aggregates of the hot store into the
+// cold store. The provided function must return a new, empty instance of the
+// given aggregate, which is used to take the pinned snapshots of archived
+// aggregates. The aggregates must therefore implement snapshot.Marshaler (or
+// encoding.BinaryMarshaler / encoding.TextMarshaler).
+func New(hot, cold event.Store, snapshots snapshot.Store, index Index, newFunc func(aggregate.Ref) aggregate.Aggregate, opts ...Option) *Store {
+	if newFunc == nil {
+		panic("[goes/aggregate/archive.New] aggregate factory is nil")
+	}
+	s := &Store{
+		Store:     hot,
+		cold:      cold,
+		snapshots: snapshots,
+		index:     index,
+		newFunc:   newFunc,
+		timeout:   DefaultRestoreTimeout,
+		restoring: make(map[aggregate.Ref]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Cold returns the cold event store.
+func (s *Store) Cold() event.Store {
+	return s.cold
+}
+
+// Archive archives the given aggregates. Aggregates that are already archived
+// or have no events are skipped. Archive stops at the first error and returns
+// the results of the aggregates that have been archived so far. Archiving a
+// single aggregate is idempotent, so a failed Archive can be retried.
+//
+// Archive must not be called for aggregates that are concurrently modified.
+func (s *Store) Archive(ctx context.Context, aggregates ...aggregate.Ref) ([]Result, error) {
+	results := make([]Result, 0, len(aggregates))
+	for _, ref := range aggregates {
+		res, err := s.archive(ctx, ref)
+		if err != nil {
+			return results, fmt.Errorf("archive %s: %w", ref, err)
+		}
+		if res.Events > 0 {
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// Restore restores the given archived aggregates into the hot store.
+// Aggregates that are not archived are skipped.
+func (s *Store) Restore(ctx context.Context, aggregates ...aggregate.Ref) error {
+	for _, ref := range aggregates {
+		entry, archived, err := s.index.Lookup(ctx, ref.ID)
+		if err != nil {
+			return fmt.Errorf("lookup %s: %w", ref, err)
+		}
+		if !archived || entry.Aggregate.Name != ref.Name {
+			continue
+		}
+		if err := s.restore(ctx, ref); err != nil {
+			return fmt.Errorf("restore %s: %w", ref, err)
+		}
+	}
+	return nil
+}
+
+// Insert inserts the given events into the hot store. Archived aggregates of
+// the events are restored before the events are inserted.
+func (s *Store) Insert(ctx context.Context, events ...event.Event) error {
+	refs := make([]aggregate.Ref, 0, len(events))
+	for _, evt := range events {
+		if id, name, _ := evt.Aggregate(); id != uuid.Nil {
+			refs = append(refs, aggregate.Ref{Name: name, ID: id})
+		}
+	}
+
+	if err := s.require(ctx, refs, nil); err != nil {
+		return err
+	}
+
+	return s.Store.Insert(ctx, events...)
+}
+
+// Find returns the event with the given id from the hot store, or from the
+// cold store if the event is archived.
+func (s *Store) Find(ctx context.Context, id uuid.UUID) (event.Event, error) {
+	evt, err := s.Store.Find(ctx, id)
+	if err == nil {
+		return evt, nil
+	}
+
+	if evt, coldErr := s.cold.Find(ctx, id); coldErr == nil {
+		return evt, nil
+	}
+
+	return nil, err
+}
+
+// Query queries the hot store. If the query filters by aggregate ID and may
+// return archived events of the filtered aggregates, the archived aggregates
+// are restored before the hot store is queried.
+func (s *Store) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	refs := targets(q)
+	if err := s.require(ctx, refs, q.AggregateVersions()); err != nil {
+		return nil, nil, err
+	}
+	return s.Store.Query(ctx, q)
+}
+
+func (s *Store) archive(ctx context.Context, ref aggregate.Ref) (Result, error) {
+	res := Result{Aggregate: ref}
+
+	if _, archived, err := s.index.Lookup(ctx, ref.ID); err != nil {
+		return res, fmt.Errorf("lookup: %w", err)
+	} else if archived {
+		return res, nil
+	}
+
+	events, err := s.aggregateEvents(ctx, s.Store, ref)
+	if err != nil {
+		return res, fmt.Errorf("query hot events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return res, nil
+	}
+
+	a := s.newFunc(ref)
+	if err := aggregate.ApplyHistory(a, events); err != nil {
+		return res, fmt.Errorf("apply history: %w", err)
+	}
+	res.Version = aggregate.UncommittedVersion(a)
+
+	snap, err := snapshot.New(a)
+	if err != nil {
+		return res, fmt.Errorf("take snapshot: %w", err)
+	}
+
+	if err := s.snapshots.Save(ctx, snap); err != nil {
+		return res, fmt.Errorf("save snapshot: %w", err)
+	}
+
+	if err := s.copy(ctx, s.cold, ref, events); err != nil {
+		return res, fmt.Errorf("insert cold events: %w", err)
+	}
+
+	if err := s.index.Save(ctx, Entry{
+		Aggregate: ref,
+		Version:   res.Version,
+		Time:      time.Now(),
+	}); err != nil {
+		return res, fmt.Errorf("save index entry: %w", err)
+	}
+
+	if err := s.Store.Delete(ctx, events...); err != nil {
+		return res, fmt.Errorf("delete hot events: %w", err)
+	}
+	res.Events = len(events)
+
+	return res, nil
+}
+
+// require restores the archived aggregates of refs whose archived events are
+// included in the given version constraints.
+func (s *Store) require(ctx context.Context, refs []aggregate.Ref, versions version.Constraints) error {
+	for _, ref := range refs {
+		entry, archived, err := s.index.Lookup(ctx, ref.ID)
+		if err != nil {
+			return fmt.Errorf("lookup %s: %w", ref, err)
+		}
+
+		if !archived || (ref.Name != "" && entry.Aggregate.Name != ref.Name) || !includesArchived(versions, entry.Version) {
+			continue
+		}
+
+		if s.async {
+			s.restoreAsync(ctx, entry.Aggregate)
+			return &RestoringError{Aggregate: entry.Aggregate}
+		}
+
+		if err := s.restore(ctx, entry.Aggregate); err != nil {
+			return fmt.Errorf("restore %s: %w", entry.Aggregate, err)
+		}
+	}
+	return nil
+}
+
+// restoreAsync restores the aggregate in the background, unless it is already
+// being restored. The restoration keeps the values of ctx, but is not canceled
+// together with ctx. Errors are reported to the OnRestore functions.
+func (s *Store) restoreAsync(ctx context.Context, ref aggregate.Ref) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.restoring[ref]; ok {
+		return
+	}
+	s.restoring[ref] = struct{}{}
+
+	ctx = context.WithoutCancel(ctx)
+	cancel := func() {}
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	}
+
+	go func() {
+		defer func() {
+			s.mux.Lock()
+			defer s.mux.Unlock()
+			delete(s.restoring, ref)
+		}()
+		defer cancel()
+
+		// The error is reported to the OnRestore functions.
+		_ = s.restore(ctx, ref)
+	}()
+}
+
+// restore moves the events of the archived aggregate back into the hot store.
+// The OnRestore functions are called if the aggregate is restored or the
+// restoration fails.
+func (s *Store) restore(ctx context.Context, ref aggregate.Ref) (err error) {
+	s.restoreMux.Lock()
+	defer s.restoreMux.Unlock()
+
+	var skipped bool
+	defer func() {
+		if skipped {
+			return
+		}
+		for _, fn := range s.onRestore {
+			fn(ref, err)
+		}
+	}()
+
+	// The aggregate may have been restored while waiting for the lock.
+	if _, archived, err := s.index.Lookup(ctx, ref.ID); err != nil {
+		return fmt.Errorf("lookup: %w", err)
+	} else if !archived {
+		skipped = true
+		return nil
+	}
+
+	events, err := s.aggregateEvents(ctx, s.cold, ref)
+	if err != nil {
+		return fmt.Errorf("query cold events: %w", err)
+	}
+
+	if err := s.copy(ctx, s.Store, ref, events); err != nil {
+		return fmt.Errorf("insert hot events: %w", err)
+	}
+
+	if err := s.index.Remove(ctx, ref); err != nil {
+		return fmt.Errorf("remove index entry: %w", err)
+	}
+
+	if len(events) > 0 {
+		if err := s.cold.Delete(ctx, events...); err != nil {
+			return fmt.Errorf("delete cold events: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// copy inserts the events into the given store, skipping the events that the
+// store already contains from a previous, interrupted archival or restoration.
+func (s *Store) copy(ctx context.Context, store event.Store, ref aggregate.Ref, events []event.Event) error {
+	existing, err := s.aggregateEvents(ctx, store, ref)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if len(existing) > 0 {
+		current = pick.AggregateVersion(existing[len(existing)-1])
+	}
+
+	missing := make([]event.Event, 0, len(events))
+	for _, evt := range events {
+		if pick.AggregateVersion(evt) > current {
+			missing = append(missing, evt)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return store.Insert(ctx, missing...)
+}
+
+func (s *Store) aggregateEvents(ctx context.Context, store event.Store, ref aggregate.Ref) ([]event.Event, error) {
+	str, errs, err := store.Query(ctx, query.New(
+		query.Aggregate(ref.Name, ref.ID),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return streams.Drain(ctx, str, errs)
+}
+
+// targets returns the aggregates that are filtered by the given query. Only
+// queries that filter by aggregate ID target specific aggregates.
+func targets(q event.Query) []aggregate.Ref {
+	var refs []aggregate.Ref
+	for _, ref := range q.Aggregates() {
+		if ref.ID != uuid.Nil {
+			refs = append(refs, aggregate.Ref{Name: ref.Name, ID: ref.ID})
+		}
+	}
+	for _, id := range q.AggregateIDs() {
+		refs = append(refs, aggregate.Ref{ID: id})
+	}
+	return refs
+}
+
+// includesArchived reports whether the version constraints may include any of
+// the archived versions 1 to v. The check is conservative: it only returns
+// false if one of the constraints excludes all archived versions.
+func includesArchived(versions version.Constraints, v int) bool {
+	if versions == nil {
+		return true
+	}
+
+	if exact := versions.Exact(); len(exact) > 0 && !anyOf(exact, func(e int) bool { return e >= 1 && e <= v }) {
+		return false
+	}
+
+	if min := versions.Min(); len(min) > 0 && !anyOf(min, func(m int) bool { return m <= v }) {
+		return false
+	}
+
+	if max := versions.Max(); len(max) > 0 && !anyOf(max, func(m int) bool { return m >= 1 }) {
+		return false
+	}
+
+	if ranges := versions.Ranges(); len(ranges) > 0 && !anyOf(ranges, func(r version.Range) bool { return r.Start() <= v && r.End() >= 1 }) {
+		return false
+	}
+
+	return true
+}
+
+func anyOf[T any](values []T, fn func(T) bool) bool {
+	for _, v := range values {
+		if fn(v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,348 @@
+package archive_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/archive"
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/streams"
+)
+
+type mockAggregate struct {
+	*aggregate.Base
+	mockState
+}
+
+type mockState struct {
+	Count int
+}
+
+func newMockAggregate(id uuid.UUID) *mockAggregate {
+	a := &mockAggregate{Base: aggregate.New("foo", id)}
+	event.ApplyWith(a, func(event.Of[int]) { a.Count++ }, "counted")
+	return a
+}
+
+func newAggregate(ref aggregate.Ref) aggregate.Aggregate {
+	return newMockAggregate(ref.ID)
+}
+
+func TestStore_Archive(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := eventstore.New(), eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, hot, 5)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	store := archive.New(hot, cold, snapshots, archive.NewMemoryIndex(), newAggregate)
+
+	results, err := store.Archive(ctx, ref)
+	if err != nil {
+		t.Fatalf("Archive() failed with %q", err)
+	}
+
+	if len(results) != 1 || results[0].Version != 5 || results[0].Events != 5 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	if versions := queryVersions(t, hot, a.ID); len(versions) != 0 {
+		t.Fatalf("hot store should have no events; has %v", versions)
+	}
+
+	if versions := queryVersions(t, cold, a.ID); fmt.Sprint(versions) != fmt.Sprint([]int{1, 2, 3, 4, 5}) {
+		t.Fatalf("cold store should have all events; has %v", versions)
+	}
+
+	snap, err := snapshots.Latest(ctx, "foo", a.ID)
+	if err != nil {
+		t.Fatalf("pinned snapshot should exist: %v", err)
+	}
+
+	if snap.AggregateVersion() != 5 {
+		t.Fatalf("pinned snapshot should have version %d; has %d", 5, snap.AggregateVersion())
+	}
+
+	repo := repository.New(store, repository.WithSnapshots(snapshots, nil))
+	fetched := newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if fetched.Count != 5 || fetched.AggregateVersion() != 5 {
+		t.Fatalf("aggregate should be fetched from the pinned snapshot; Count=%d Version=%d", fetched.Count, fetched.AggregateVersion())
+	}
+
+	if versions := queryVersions(t, hot, a.ID); len(versions) != 0 {
+		t.Fatalf("fetching from the pinned snapshot should not restore the aggregate")
+	}
+}
+
+func TestStore_Query_restores(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := eventstore.New(), eventstore.New()
+
+	a := setup(t, hot, 5)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	var restored []aggregate.Ref
+	store := archive.New(hot, cold, snapshot.NewStore(), archive.NewMemoryIndex(), newAggregate, archive.OnRestore(func(ref aggregate.Ref, err error) {
+		if err != nil {
+			t.Errorf("restore failed: %v", err)
+		}
+		restored = append(restored, ref)
+	}))
+
+	if _, err := store.Archive(ctx, ref); err != nil {
+		t.Fatalf("Archive() failed with %q", err)
+	}
+
+	repo := repository.New(store)
+	fetched := newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if fetched.Count != 5 {
+		t.Fatalf("Count should be %d; is %d", 5, fetched.Count)
+	}
+
+	if len(restored) != 1 || restored[0] != ref {
+		t.Fatalf("aggregate should have been restored once; restored %v", restored)
+	}
+
+	if versions := queryVersions(t, hot, a.ID); len(versions) != 5 {
+		t.Fatalf("hot store should have all events after restoration; has %v", versions)
+	}
+
+	if versions := queryVersions(t, cold, a.ID); len(versions) != 0 {
+		t.Fatalf("cold store should have no events after restoration; has %v", versions)
+	}
+
+	aggregate.Next(fetched, "counted", 6)
+	if err := repo.Save(ctx, fetched); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+}
+
+func TestStore_Insert_restores(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := eventstore.New(), eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, hot, 3)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	store := archive.New(hot, cold, snapshots, archive.NewMemoryIndex(), newAggregate)
+
+	if _, err := store.Archive(ctx, ref); err != nil {
+		t.Fatalf("Archive() failed with %q", err)
+	}
+
+	repo := repository.New(store, repository.WithSnapshots(snapshots, nil))
+	fetched := newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	aggregate.Next(fetched, "counted", 4)
+	if err := repo.Save(ctx, fetched); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	if versions := queryVersions(t, hot, a.ID); fmt.Sprint(versions) != fmt.Sprint([]int{1, 2, 3, 4}) {
+		t.Fatalf("hot store should have the restored and the new events; has %v", versions)
+	}
+}
+
+func TestAsync(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := eventstore.New(), eventstore.New()
+
+	a := setup(t, hot, 3)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	restored := make(chan error, 1)
+	store := archive.New(hot, cold, snapshot.NewStore(), archive.NewMemoryIndex(), newAggregate, archive.Async(), archive.OnRestore(func(_ aggregate.Ref, err error) {
+		restored <- err
+	}))
+
+	if _, err := store.Archive(ctx, ref); err != nil {
+		t.Fatalf("Archive() failed with %q", err)
+	}
+
+	repo := repository.New(store)
+	err := repo.Fetch(ctx, newMockAggregate(a.ID))
+
+	var restoringErr *archive.RestoringError
+	if !errors.As(err, &restoringErr) {
+		t.Fatalf("Fetch() should fail with a %T error; got %v", restoringErr, err)
+	}
+
+	if !errors.Is(err, archive.ErrRestoring) || restoringErr.Aggregate != ref {
+		t.Fatalf("unexpected RestoringError: %v", err)
+	}
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timed out. aggregate not restored?")
+	case err := <-restored:
+		if err != nil {
+			t.Fatalf("restore failed with %q", err)
+		}
+	}
+
+	fetched := newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if fetched.Count != 3 {
+		t.Fatalf("Count should be %d; is %d", 3, fetched.Count)
+	}
+}
+
+func TestStore_Query_newerVersions(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := eventstore.New(), eventstore.New()
+
+	a := setup(t, hot, 5)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	store := archive.New(hot, cold, snapshot.NewStore(), archive.NewMemoryIndex(), newAggregate, archive.OnRestore(func(ref aggregate.Ref, err error) {
+		t.Errorf("aggregate should not be restored; restored %s", ref)
+	}))
+
+	if _, err := store.Archive(ctx, ref); err != nil {
+		t.Fatalf("Archive() failed with %q", err)
+	}
+
+	for _, q := range []event.Query{
+		query.New(query.Aggregate("foo", a.ID), query.AggregateVersion(version.Min(6))),
+		query.New(query.Aggregate("foo", a.ID), query.AggregateVersion(version.Exact(6, 7))),
+		query.New(query.Aggregate("foo", a.ID), query.AggregateVersion(version.InRange(version.Range{6, 10}))),
+	} {
+		str, errs, err := store.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("Query() failed with %q", err)
+		}
+		if _, err := streams.Drain(ctx, str, errs); err != nil {
+			t.Fatalf("drain events: %v", err)
+		}
+	}
+}
+
+func TestRestoreTimeout(t *testing.T) {
+	ctx := context.Background()
+	hot := eventstore.New()
+	cold := &blockingStore{Store: eventstore.New()}
+
+	a := setup(t, hot, 3)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	restored := make(chan error, 1)
+	store := archive.New(hot, cold, snapshot.NewStore(), archive.NewMemoryIndex(), newAggregate,
+		archive.Async(),
+		archive.RestoreTimeout(50*time.Millisecond),
+		archive.OnRestore(func(_ aggregate.Ref, err error) {
+			restored <- err
+		}),
+	)
+
+	if _, err := store.Archive(ctx, ref); err != nil {
+		t.Fatalf("Archive() failed with %q", err)
+	}
+	cold.blocked.Store(true)
+
+	if err := repository.New(store).Fetch(ctx, newMockAggregate(a.ID)); !errors.Is(err, archive.ErrRestoring) {
+		t.Fatalf("Fetch() should fail with %q; got %v", archive.ErrRestoring, err)
+	}
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timed out. restoration not canceled?")
+	case err := <-restored:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("restoration should fail with %q; got %v", context.DeadlineExceeded, err)
+		}
+	}
+}
+
+// blockingStore is an event store whose queries block until the context is
+// canceled, once it is blocked.
+type blockingStore struct {
+	event.Store
+
+	blocked atomic.Bool
+}
+
+func (s *blockingStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	if !s.blocked.Load() {
+		return s.Store.Query(ctx, q)
+	}
+	<-ctx.Done()
+	return nil, nil, ctx.Err()
+}
+
+func setup(t *testing.T, events event.Store, n int) *mockAggregate {
+	a := newMockAggregate(uuid.New())
+	for i := 0; i < n; i++ {
+		aggregate.Next(a, "counted", i)
+	}
+
+	if err := events.Insert(context.Background(), a.AggregateChanges()...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	return a
+}
+
+func queryVersions(t *testing.T, store event.Store, id uuid.UUID) []int {
+	str, errs, err := store.Query(context.Background(), query.New(
+		query.Aggregate("foo", id),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	versions := make([]int, len(events))
+	for i, evt := range events {
+		_, _, versions[i] = evt.Aggregate()
+	}
+	return versions
+}
+
+func (a *mockAggregate) MarshalSnapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a.mockState); err != nil {
+		return nil, fmt.Errorf("gob: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *mockAggregate) UnmarshalSnapshot(p []byte) error {
+	if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&a.mockState); err != nil {
+		return fmt.Errorf("gob: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package archive
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+)
+
+// Entry is an entry of an Index that marks an aggregate as archived.
+type Entry struct {
+	// Aggregate is the archived aggregate.
+	Aggregate aggregate.Ref
+
+	// Version is the version of the aggregate at the time it was archived. The
+	// snapshot of the aggregate at this version is pinned and must not be
+	// deleted while the aggregate is archived.
+	Version int
+
+	// Time is the time at which the aggregate was archived.
+	Time time.Time
+}
+
+// Index keeps track of archived aggregates.
+type Index interface {
+	// Save marks the aggregate of the entry as archived.
+	Save(context.Context, Entry) error
+
+	// Lookup returns the entry of the archived aggregate with the given id.
+	// If the aggregate is not archived, Lookup returns false.
+	Lookup(context.Context, uuid.UUID) (Entry, bool, error)
+
+	// Remove removes the given aggregate from the index.
+	Remove(context.Context, aggregate.Ref) error
+}
+
+type memoryIndex struct {
+	mux     sync.RWMutex
+	entries map[uuid.UUID]Entry
+}
+
+// NewMemoryIndex returns an in-memory Index.
+func NewMemoryIndex() Index {
+	return &memoryIndex{entries: make(map[uuid.UUID]Entry)}
+}
+
+func (idx *memoryIndex) Save(_ context.Context, entry Entry) error {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	idx.entries[entry.Aggregate.ID] = entry
+	return nil
+}
+
+func (idx *memoryIndex) Lookup(_ context.Context, id uuid.UUID) (Entry, bool, error) {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+	entry, ok := idx.entries[id]
+	return entry, ok, nil
+}
+
+func (idx *memoryIndex) Remove(_ context.Context, ref aggregate.Ref) error {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+	if entry, ok := idx.entries[ref.ID]; ok && entry.Aggregate.Name == ref.Name {
+		delete(idx.entries, ref.ID)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+// Package backfill appends synthetic events to existing aggregates.
+//
+// When a new event is introduced, existing aggregates sometimes need to be
+// "stamped" with it, e.g. a "currency.assigned" event for all legacy orders
+// that were placed before orders had a currency. Run appends such an event to
+// every aggregate whose events match a query:
+//
+//	res, err := backfill.Run(ctx, store, annotations, backfill.Spec{
+//		Event:  "shop.order.currency_assigned",
+//		Query:  query.New(query.AggregateName("shop.order")),
+//		Author: "bob",
+//		Reason: "orders before v2 have no currency",
+//		Data: func(ref aggregate.Ref, version int) (any, bool) {
+//			return CurrencyAssigned{Currency: "EUR"}, true
+//		},
+//	}, backfill.DryRun())
+//
+// Backfilled events are tagged with TagBackfilled and the tag of the run (see
+// RunTag) in the provided annotation store, and are annotated with the author
+// and reason of the backfill. A run can be undone using Rollback.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/annotation"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// TagBackfilled marks events that were appended by a backfill.
+const TagBackfilled = "backfilled"
+
+// ErrNotLatest is returned by Rollback if a backfilled event cannot be removed
+// because other events have been appended to its aggregate since the backfill.
+var ErrNotLatest = errors.New("backfilled event is not the latest event of its aggregate")
+
+// Spec describes a backfill.
+type Spec struct {
+	// Event is the name of the event that is appended.
+	Event string
+
+	// Query selects the aggregates to backfill: the event is appended to every
+	// aggregate that has an event that matches the query.
+	Query event.Query
+
+	// Data returns the data of the event that is appended to the given
+	// aggregate, whose current version is version. If Data returns false, the
+	// aggregate is skipped.
+	Data func(ref aggregate.Ref, version int) (any, bool)
+
+	// Author and Reason are recorded in the annotation of the backfilled events.
+	Author string
+	Reason string
+}
+
+// Option is an option for Run.
+type Option func(*config)
+
+type config struct {
+	dryRun bool
+	force  bool
+	now    func() time.Time
+}
+
+// DryRun returns an Option that makes Run only report the events that would
+// be appended, without inserting or annotating them.
+func DryRun() Option {
+	return func(cfg *config) {
+		cfg.dryRun = true
+	}
+}
+
+// Force returns an Option that makes Run append the event to aggregates that
+// already have an event with the same name. By default, such aggregates are
+// skipped, so that a backfill can safely be run again after a partial failure.
+func Force() Option {
+	return func(cfg *config) {
+		cfg.force = true
+	}
+}
+
+// Result is the result of a backfill.
+type Result struct {
+	// Run is the id of the run. Use Rollback to undo the run.
+	Run uuid.UUID
+
+	// DryRun reports whether the run was a dry run.
+	DryRun bool
+
+	// Events are the appended events, or the events that would have been
+	// appended in a dry run.
+	Events []event.Event
+
+	// Skipped are the aggregates that were skipped, either because they already
+	// have the event or because Spec.Data returned false.
+	Skipped []aggregate.Ref
+
+	// Failed are the aggregates that could not be backfilled, together with the
+	// error. Insertion fails, e.g., if the aggregate was modified concurrently.
+	Failed map[aggregate.Ref]error
+}
+
+// RunTag returns the annotation tag of the events that were appended by the
+// given run.
+func RunTag(run uuid.UUID) string {
+	return fmt.Sprintf("backfill:%s", run)
+}
+
+// Run appends the event that is described by the spec to each aggregate that
+// has an event matching the spec's query. Aggregates are backfilled one after
+// another; failures of single aggregates are reported in Result.Failed and do
+// not stop the run.
+func Run(ctx context.Context, store event.Store, annotations annotation.Store, spec Spec, opts ...Option) (Result, error) {
+	cfg := config{now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if spec.Event == "" {
+		return Result{}, errors.New("missing event name")
+	}
+
+	if spec.Data == nil {
+		return Result{}, errors.New("missing data function")
+	}
+
+	if spec.Query == nil {
+		spec.Query = query.New()
+	}
+
+	refs, err := aggregates(ctx, store, spec.Query)
+	if err != nil {
+		return Result{}, fmt.Errorf("query aggregates: %w", err)
+	}
+
+	res := Result{
+		Run:    uuid.New(),
+		DryRun: cfg.dryRun,
+		Failed: make(map[aggregate.Ref]error),
+	}
+	runTag := RunTag(res.Run)
+
+	for _, ref := range refs {
+		last, hasEvent, err := inspect(ctx, store, ref, spec.Event)
+		if err != nil {
+			res.Failed[ref] = err
+			continue
+		}
+
+		if hasEvent && !cfg.force {
+			res.Skipped = append(res.Skipped, ref)
+			continue
+		}
+
+		data, ok := spec.Data(ref, last)
+		if !ok {
+			res.Skipped = append(res.Skipped, ref)
+			continue
+		}
+
+		evt := event.New(spec.Event, data, event.Aggregate(ref.ID, ref.Name, last+1), event.Time(cfg.now())).Any()
+		res.Events = append(res.Events, evt)
+
+		if cfg.dryRun {
+			continue
+		}
+
+		if err := store.Insert(ctx, evt); err != nil {
+			res.Events = res.Events[:len(res.Events)-1]
+			res.Failed[ref] = fmt.Errorf("insert %q event: %w", spec.Event, err)
+			continue
+		}
+
+		if err := annotation.Tag(ctx, annotations, evt, TagBackfilled, runTag); err != nil {
+			return res, fmt.Errorf("tag %q event of %s: %w", spec.Event, ref, err)
+		}
+
+		if err := annotation.Annotate(ctx, annotations, evt, spec.Author, spec.Reason); err != nil {
+			return res, fmt.Errorf("annotate %q event of %s: %w", spec.Event, ref, err)
+		}
+	}
+
+	return res, nil
+}
+
+// Rollback deletes the events that were appended by the given run, together
+// with their annotations, and returns the number of deleted events. An event
+// that is no longer the latest event of its aggregate is not deleted, and
+// Rollback returns an error that unwraps to ErrNotLatest for it.
+func Rollback(ctx context.Context, store event.Store, annotations annotation.Store, run uuid.UUID) (int, error) {
+	str, errs, err := annotations.Query(ctx, annotation.Query{Tags: []string{RunTag(run)}})
+	if err != nil {
+		return 0, fmt.Errorf("query annotations: %w", err)
+	}
+
+	tagged, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return 0, fmt.Errorf("query annotations: %w", err)
+	}
+
+	var deleted int
+	var failed []error
+
+	for _, a := range tagged {
+		evt, err := store.Find(ctx, a.EventID)
+		if err != nil {
+			failed = append(failed, fmt.Errorf("find %q event (%s): %w", a.EventName, a.EventID, err))
+			continue
+		}
+
+		id, name, _ := evt.Aggregate()
+		ref := aggregate.Ref{Name: name, ID: id}
+		last, _, err := inspect(ctx, store, ref, "")
+		if err != nil {
+			failed = append(failed, err)
+			continue
+		}
+
+		if last != pick.AggregateVersion(evt) {
+			failed = append(failed, fmt.Errorf("%q event (%s) of %s: %w", evt.Name(), evt.ID(), ref, ErrNotLatest))
+			continue
+		}
+
+		if err := store.Delete(ctx, evt); err != nil {
+			failed = append(failed, fmt.Errorf("delete %q event (%s): %w", evt.Name(), evt.ID(), err))
+			continue
+		}
+
+		if err := annotations.Delete(ctx, evt.ID()); err != nil {
+			failed = append(failed, fmt.Errorf("delete annotation of %q event (%s): %w", evt.Name(), evt.ID(), err))
+		}
+
+		deleted++
+	}
+
+	return deleted, errors.Join(failed...)
+}
+
+// aggregates returns the distinct aggregates of the events that match the
+// query, in the order of their first event.
+func aggregates(ctx context.Context, store event.Store, q event.Query) ([]aggregate.Ref, error) {
+	str, errs, err := store.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[aggregate.Ref]bool)
+	var refs []aggregate.Ref
+
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		id, name, _ := evt.Aggregate()
+		ref := aggregate.Ref{Name: name, ID: id}
+		if ref.ID == uuid.Nil || ref.Name == "" || seen[ref] {
+			return nil
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+		return nil
+	}, str, errs); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// inspect returns the current version of the aggregate, and whether the
+// aggregate has an event with the given name.
+func inspect(ctx context.Context, store event.Store, ref aggregate.Ref, name string) (int, bool, error) {
+	str, errs, err := store.Query(ctx, query.New(
+		query.Aggregate(ref.Name, ref.ID),
+		query.AggregateVersion(version.Min(0)),
+	))
+	if err != nil {
+		return 0, false, fmt.Errorf("query events of %s: %w", ref, err)
+	}
+
+	var last int
+	var hasEvent bool
+
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		if v := pick.AggregateVersion(evt); v > last {
+			last = v
+		}
+		if evt.Name() == name {
+			hasEvent = true
+		}
+		return nil
+	}, str, errs); err != nil {
+		return 0, false, fmt.Errorf("query events of %s: %w", ref, err)
+	}
+
+	return last, hasEvent, nil
+}
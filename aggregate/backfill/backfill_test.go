@@ -0,0 +1,186 @@
+package backfill_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/backfill"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/annotation"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	store, legacy, current := setup(t)
+	annotations := annotation.NewStore()
+
+	res, err := backfill.Run(ctx, store, annotations, spec())
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	if len(res.Events) != 1 {
+		t.Fatalf("Run should append 1 event; got %d", len(res.Events))
+	}
+
+	if len(res.Skipped) != 1 || res.Skipped[0] != current {
+		t.Fatalf("Run should skip %v; got %v", current, res.Skipped)
+	}
+
+	evts := queryEvents(t, store, legacy)
+	if len(evts) != 3 {
+		t.Fatalf("legacy order should have 3 events; got %d", len(evts))
+	}
+
+	last := evts[len(evts)-1]
+	if last.Name() != "order.currency_assigned" || pick.AggregateVersion(last) != 3 {
+		t.Fatalf("backfilled event should be the 3rd event of the order; got %q (v%d)", last.Name(), pick.AggregateVersion(last))
+	}
+
+	a, err := annotations.Find(ctx, last.ID())
+	if err != nil {
+		t.Fatalf("backfilled event should be annotated: %v", err)
+	}
+
+	if len(a.Notes) != 1 || a.Notes[0].Author != "bob" || a.Notes[0].Text != "legacy orders" {
+		t.Fatalf("annotation should record the author and reason; got %+v", a.Notes)
+	}
+
+	if !a.HasTag(backfill.TagBackfilled) || !a.HasTag(backfill.RunTag(res.Run)) {
+		t.Fatalf("annotation should have the backfill tags; got %v", a.Tags)
+	}
+
+	again, err := backfill.Run(ctx, store, annotations, spec())
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	if len(again.Events) != 0 {
+		t.Fatalf("second Run should not append events; got %d", len(again.Events))
+	}
+}
+
+func TestRun_DryRun(t *testing.T) {
+	ctx := context.Background()
+	store, legacy, _ := setup(t)
+	annotations := annotation.NewStore()
+
+	res, err := backfill.Run(ctx, store, annotations, spec(), backfill.DryRun())
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	if !res.DryRun || len(res.Events) != 1 {
+		t.Fatalf("dry run should report 1 event; got %+v", res)
+	}
+
+	if evts := queryEvents(t, store, legacy); len(evts) != 2 {
+		t.Fatalf("dry run should not append events; got %d events", len(evts))
+	}
+
+	if _, err := annotations.Find(ctx, res.Events[0].ID()); !errors.Is(err, annotation.ErrNotFound) {
+		t.Fatalf("dry run should not annotate events; got %v", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	store, legacy, _ := setup(t)
+	annotations := annotation.NewStore()
+
+	res, err := backfill.Run(ctx, store, annotations, spec())
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	n, err := backfill.Rollback(ctx, store, annotations, res.Run)
+	if err != nil {
+		t.Fatalf("Rollback failed with %q", err)
+	}
+
+	if n != 1 {
+		t.Fatalf("Rollback should delete 1 event; got %d", n)
+	}
+
+	if evts := queryEvents(t, store, legacy); len(evts) != 2 {
+		t.Fatalf("legacy order should have 2 events after rollback; got %d", len(evts))
+	}
+
+	if _, err := annotations.Find(ctx, res.Events[0].ID()); !errors.Is(err, annotation.ErrNotFound) {
+		t.Fatalf("Rollback should delete the annotation; got %v", err)
+	}
+}
+
+func TestRollback_notLatest(t *testing.T) {
+	ctx := context.Background()
+	store, legacy, _ := setup(t)
+	annotations := annotation.NewStore()
+
+	res, err := backfill.Run(ctx, store, annotations, spec())
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	if err := store.Insert(ctx, event.New("order.shipped", 0, event.Aggregate(legacy.ID, legacy.Name, 4)).Any()); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	n, err := backfill.Rollback(ctx, store, annotations, res.Run)
+	if !errors.Is(err, backfill.ErrNotLatest) {
+		t.Fatalf("Rollback should fail with %q; got %q", backfill.ErrNotLatest, err)
+	}
+
+	if n != 0 {
+		t.Fatalf("Rollback should not delete events; deleted %d", n)
+	}
+}
+
+func spec() backfill.Spec {
+	return backfill.Spec{
+		Event:  "order.currency_assigned",
+		Query:  query.New(query.AggregateName("order")),
+		Author: "bob",
+		Reason: "legacy orders",
+		Data: func(aggregate.Ref, int) (any, bool) {
+			return "EUR", true
+		},
+	}
+}
+
+// setup returns a store with a legacy order without a currency and a current
+// order with a currency.
+func setup(t *testing.T) (event.Store, aggregate.Ref, aggregate.Ref) {
+	legacy := aggregate.Ref{Name: "order", ID: uuid.New()}
+	current := aggregate.Ref{Name: "order", ID: uuid.New()}
+
+	store := eventstore.New()
+	if err := store.Insert(context.Background(),
+		event.New("order.placed", 0, event.Aggregate(legacy.ID, legacy.Name, 1)).Any(),
+		event.New("order.paid", 0, event.Aggregate(legacy.ID, legacy.Name, 2)).Any(),
+		event.New("order.placed", 0, event.Aggregate(current.ID, current.Name, 1)).Any(),
+		event.New("order.currency_assigned", "USD", event.Aggregate(current.ID, current.Name, 2)).Any(),
+	); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	return store, legacy, current
+}
+
+func queryEvents(t *testing.T, store event.Store, ref aggregate.Ref) []event.Event {
+	str, errs, err := store.Query(context.Background(), query.New(query.Aggregate(ref.Name, ref.ID), query.SortByAggregate()))
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+	evts, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+	return evts
+}
@@ -29,6 +29,10 @@ type Base struct {
 
 	eventHandlers
 	commandHandlers
+
+	invariants []func() error
+	violation  *InvariantViolation
+	clock      event.Clock
 }
 
 type eventHandlers = event.Handlers
@@ -109,9 +113,14 @@ func (b *Base) AggregateChanges() []event.Event {
 }
 
 // RecordChange appends the provided events to the Changes slice of the Base
-// aggregate.
+// aggregate. The invariants of the aggregate are checked after the changes
+// have been recorded.
 func (b *Base) RecordChange(events ...event.Event) {
 	b.Changes = append(b.Changes, events...)
+
+	if len(b.invariants) > 0 && b.violation == nil && len(events) > 0 {
+		b.violation = b.checkInvariants(events[len(events)-1])
+	}
 }
 
 // Commit updates the aggregate version to the version of its latest change and
@@ -122,12 +131,15 @@ func (b *Base) Commit() {
 	}
 	b.Version = pick.AggregateVersion(b.Changes[len(b.Changes)-1])
 	b.Changes = b.Changes[:0]
+	b.violation = nil
 }
 
 // DiscardChanges resets the list of recorded changes to an empty state,
-// effectively discarding any uncommitted changes made to the aggregate.
+// effectively discarding any uncommitted changes made to the aggregate. An
+// invariant violation that was caused by the changes is discarded as well.
 func (b *Base) DiscardChanges() {
 	b.Changes = b.Changes[:0]
+	b.violation = nil
 }
 
 // ApplyEvent applies the given event to the aggregate by calling the
@@ -226,8 +238,13 @@ func NextVersion(a Aggregate) int {
 
 // nextTime returns the Time for the next event of the given aggregate. The time
 // should most of the time just be time.Now(), but nextTime guarantees that the
-// returned Time is at least 1 nanosecond after the previous event.
+// returned Time is at least 1 nanosecond after the previous event. If the
+// aggregate provides a Clock, the time is taken from that Clock instead.
 func nextTime(a Aggregate) time.Time {
+	if clock := clockOf(a); clock != nil {
+		return nextClockTime(a, clock)
+	}
+
 	changes := a.AggregateChanges()
 	now := xtime.Now()
 
@@ -0,0 +1,58 @@
+package aggregate
+
+import (
+	"time"
+
+	"github.com/modernice/goes/event"
+)
+
+// Clocked is an aggregate that provides the Clock for the times of its events.
+// Next takes the time of new events from the Clock of a Clocked aggregate
+// instead of the system time. *Base implements Clocked.
+type Clocked interface {
+	// AggregateClock returns the Clock of the aggregate, or nil if the
+	// aggregate uses the system time.
+	AggregateClock() event.Clock
+}
+
+// WithClock returns an Option that sets the Clock that provides the times of
+// the events that are raised by the aggregate through Next. Next still
+// guarantees that the time of a new event is at least 1 nanosecond after the
+// time of the previous uncommitted event.
+//
+//	clock := event.Monotonic(ntpClock)
+//	foo := &Foo{Base: aggregate.New("foo", id, aggregate.WithClock(clock))}
+func WithClock(c event.Clock) Option {
+	return func(b *Base) {
+		b.clock = c
+	}
+}
+
+// AggregateClock returns the Clock of the aggregate, or nil if the aggregate
+// uses the system time.
+func (b *Base) AggregateClock() event.Clock {
+	return b.clock
+}
+
+func clockOf(a Aggregate) event.Clock {
+	if c, ok := a.(Clocked); ok {
+		return c.AggregateClock()
+	}
+	return nil
+}
+
+// nextClockTime returns the time of the next event of the given aggregate,
+// using the given Clock. Unlike for the system time, nextClockTime does not
+// wait for the clock to pass the time of the previous event, because the clock
+// may not be related to the system time.
+func nextClockTime(a Aggregate, clock event.Clock) time.Time {
+	now := clock.Now()
+
+	if changes := a.AggregateChanges(); len(changes) > 0 {
+		if latest := changes[len(changes)-1].Time(); !now.After(latest) {
+			return latest.Add(time.Nanosecond)
+		}
+	}
+
+	return now
+}
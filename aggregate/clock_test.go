@@ -0,0 +1,27 @@
+package aggregate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+)
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := event.ClockFunc(func() time.Time { return now })
+
+	a := aggregate.New("foo", uuid.New(), aggregate.WithClock(clock))
+
+	first := aggregate.Next(a, "foo", 1)
+	if !first.Time().Equal(now) {
+		t.Fatalf("event should have the time of the clock. want=%v got=%v", now, first.Time())
+	}
+
+	second := aggregate.Next(a, "foo", 2)
+	if want := now.Add(time.Nanosecond); !second.Time().Equal(want) {
+		t.Fatalf("event should have a time after the previous event. want=%v got=%v", want, second.Time())
+	}
+}
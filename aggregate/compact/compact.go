@@ -0,0 +1,385 @@
+// Package compact compacts the event streams of aggregates by folding runs of
+// consecutive events into single events.
+//
+// Aggregates that raise many fine-grained events (e.g. a counter that raises a
+// "counter.incremented" event for every increment) can accumulate huge event
+// histories. A Rule folds a run of such events into a single event that has
+// the same effect on the aggregate (e.g. a "counter.set" event):
+//
+//	rule := compact.FoldInto("counter.set", func(state aggregate.Aggregate, run []event.Event) (int, error) {
+//		return state.(*Counter).Value + len(run), nil
+//	}, "counter.incremented")
+//
+//	c := compact.New(store, func(ref aggregate.Ref) aggregate.Aggregate {
+//		return NewCounter(ref.ID)
+//	}, compact.WithRules(rule))
+//
+//	results, err := c.Compact(context.TODO(), aggregate.Ref{Name: "counter", ID: id})
+//
+// Before the compacted stream is written, the Compactor verifies that it
+// hydrates to the same aggregate state as the original stream. The states are
+// compared using snapshot.Marshal, so the aggregates must implement
+// snapshot.Marshaler (or encoding.BinaryMarshaler / encoding.TextMarshaler).
+//
+// Compaction rewrites the event stream of an aggregate: the original events are
+// deleted and the compacted events are inserted with renumbered aggregate
+// versions. Compaction must therefore be run offline, while no other process
+// writes to the compacted aggregates. Snapshots of compacted aggregates are
+// invalidated by the renumbering and must be deleted (see Snapshots).
+//
+// If the event store implements eventstore.AtomicReplacer (the in-memory and
+// Postgres stores do), the stream is rewritten atomically. Otherwise, the
+// original events are deleted before the compacted events are inserted, and
+// if the insert fails, the Compactor re-inserts the original events. If that
+// fails too, a *RecoveryError that contains the original events is returned,
+// and the caller must restore the events before retrying.
+package compact
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	squery "github.com/modernice/goes/aggregate/snapshot/query"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// ErrVerification is returned by a Compactor if the compacted event stream of
+// an aggregate does not hydrate to the same state as the original stream.
+var ErrVerification = errors.New("compacted stream does not match original stream")
+
+// RecoveryError is returned by a Compactor if the compacted events of an
+// aggregate could not be inserted into an event store that does not support
+// atomic replacement, and the original events could not be restored either.
+// The event stream of the aggregate is then incomplete until Events are
+// re-inserted into the store.
+type RecoveryError struct {
+	// Aggregate is the aggregate whose event stream must be recovered.
+	Aggregate aggregate.Ref
+
+	// Events are the original events of the aggregate.
+	Events []event.Event
+
+	// Err is the error that occurred while inserting the compacted events.
+	Err error
+
+	// RestoreErr is the error that occurred while restoring the original events.
+	RestoreErr error
+}
+
+// Error implements error.
+func (err *RecoveryError) Error() string {
+	return fmt.Sprintf("insert compacted events: %v (restore %d original events: %v)", err.Err, len(err.Events), err.RestoreErr)
+}
+
+// Unwrap returns the error that occurred while inserting the compacted events.
+func (err *RecoveryError) Unwrap() error {
+	return err.Err
+}
+
+// Compactor compacts the event streams of aggregates using fold rules.
+type Compactor struct {
+	store     event.Store
+	newFunc   func(aggregate.Ref) aggregate.Aggregate
+	rules     []Rule
+	snapshots snapshot.Store
+	dryRun    bool
+}
+
+// Option is an option for a Compactor.
+type Option func(*Compactor)
+
+// Rule folds runs of consecutive events into a single event.
+type Rule struct {
+	// Events are the names of the events that are folded by the rule. A run
+	// consists of consecutive events whose names are all in Events.
+	Events []string
+
+	// Min is the minimum length of a run that is folded. Runs with less than
+	// Min events (at least 2) are kept as-is.
+	Min int
+
+	// Fold folds a run of events into a single event. state is the aggregate,
+	// hydrated with the compacted events before the run. The ID, name and data
+	// of the returned event are used for the folded event. Its time and
+	// aggregate information are overridden by the Compactor.
+	Fold func(state aggregate.Aggregate, run []event.Event) (event.Event, error)
+}
+
+// Result is the result of compacting a single aggregate.
+type Result struct {
+	// Aggregate is the compacted aggregate.
+	Aggregate aggregate.Ref
+
+	// Before is the number of events before the compaction.
+	Before int
+
+	// After is the number of events after the compaction.
+	After int
+}
+
+// FoldInto returns a Rule that folds runs of the given events into a single
+// event with the given name. The data of the event is returned by fold.
+func FoldInto[Data any](name string, fold func(state aggregate.Aggregate, run []event.Event) (Data, error), events ...string) Rule {
+	return Rule{
+		Events: events,
+		Fold: func(state aggregate.Aggregate, run []event.Event) (event.Event, error) {
+			data, err := fold(state, run)
+			if err != nil {
+				return nil, err
+			}
+			return event.New(name, data).Any(), nil
+		},
+	}
+}
+
+// WithRules returns an Option that adds fold rules to a Compactor. If an event
+// matches multiple rules, the first rule is used.
+func WithRules(rules ...Rule) Option {
+	return func(c *Compactor) {
+		c.rules = append(c.rules, rules...)
+	}
+}
+
+// Snapshots returns an Option that makes the Compactor delete the snapshots of
+// compacted aggregates from the given snapshot store.
+func Snapshots(store snapshot.Store) Option {
+	return func(c *Compactor) {
+		c.snapshots = store
+	}
+}
+
+// DryRun returns an Option that makes the Compactor compute and verify the
+// compacted event streams without writing them to the event store.
+func DryRun(dryRun bool) Option {
+	return func(c *Compactor) {
+		c.dryRun = dryRun
+	}
+}
+
+// New returns a Compactor that compacts the event streams in the given store.
+// newFunc must return a new, empty instance of the given aggregate.
+func New(store event.Store, newFunc func(aggregate.Ref) aggregate.Aggregate, opts ...Option) *Compactor {
+	c := &Compactor{
+		store:   store,
+		newFunc: newFunc,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Compact compacts the event streams of the given aggregates. Compact stops at
+// the first error and returns the results of the aggregates that have been
+// compacted so far.
+func (c *Compactor) Compact(ctx context.Context, aggregates ...aggregate.Ref) ([]Result, error) {
+	results := make([]Result, 0, len(aggregates))
+	for _, ref := range aggregates {
+		res, err := c.compact(ctx, ref)
+		if err != nil {
+			return results, fmt.Errorf("compact %s: %w", ref, err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (c *Compactor) compact(ctx context.Context, ref aggregate.Ref) (Result, error) {
+	res := Result{Aggregate: ref}
+
+	str, errs, err := c.store.Query(ctx, query.New(
+		query.Aggregate(ref.Name, ref.ID),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		return res, fmt.Errorf("query events: %w", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return res, fmt.Errorf("query events: %w", err)
+	}
+
+	res.Before = len(events)
+	res.After = len(events)
+
+	state := c.newFunc(ref)
+	compacted, err := c.fold(ref, state, events)
+	if err != nil {
+		return res, err
+	}
+
+	if len(compacted) == len(events) {
+		return res, nil
+	}
+
+	original := c.newFunc(ref)
+	if err := aggregate.ApplyHistory(original, events); err != nil {
+		return res, fmt.Errorf("hydrate original stream: %w", err)
+	}
+
+	if err := verify(original, state); err != nil {
+		return res, err
+	}
+
+	res.After = len(compacted)
+
+	if c.dryRun {
+		return res, nil
+	}
+
+	if err := c.replace(ctx, ref, events, compacted); err != nil {
+		return res, err
+	}
+
+	if c.snapshots != nil {
+		if err := c.deleteSnapshots(ctx, ref); err != nil {
+			return res, fmt.Errorf("delete snapshots: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// replace replaces the original events of an aggregate with the compacted
+// events, atomically if the store supports it.
+func (c *Compactor) replace(ctx context.Context, ref aggregate.Ref, events, compacted []event.Event) error {
+	if r, ok := c.store.(eventstore.AtomicReplacer); ok {
+		if err := r.ReplaceAtomic(ctx, events, compacted); err != nil {
+			return fmt.Errorf("replace events: %w", err)
+		}
+		return nil
+	}
+
+	if err := c.store.Delete(ctx, events...); err != nil {
+		return fmt.Errorf("delete original events: %w", err)
+	}
+
+	if err := c.store.Insert(ctx, compacted...); err != nil {
+		// Remove the compacted events that may have been partially inserted
+		// before restoring the original events.
+		restoreErr := c.store.Delete(ctx, compacted...)
+		if restoreErr == nil {
+			restoreErr = c.store.Insert(ctx, events...)
+		}
+		if restoreErr != nil {
+			return &RecoveryError{
+				Aggregate:  ref,
+				Events:     events,
+				Err:        err,
+				RestoreErr: restoreErr,
+			}
+		}
+		return fmt.Errorf("insert compacted events: %w", err)
+	}
+
+	return nil
+}
+
+// fold folds the events using the rules of the Compactor and applies the
+// compacted events to state.
+func (c *Compactor) fold(ref aggregate.Ref, state aggregate.Aggregate, events []event.Event) ([]event.Event, error) {
+	out := make([]event.Event, 0, len(events))
+
+	push := func(evt, at event.Event) error {
+		rebuilt := event.New(
+			evt.Name(),
+			evt.Data(),
+			event.ID(evt.ID()),
+			event.Time(at.Time()),
+			event.Aggregate(ref.ID, ref.Name, len(out)+1),
+		).Any()
+		if err := aggregate.ApplyHistory(state, []event.Event{rebuilt}); err != nil {
+			return fmt.Errorf("hydrate compacted stream: %w", err)
+		}
+		out = append(out, rebuilt)
+		return nil
+	}
+
+	for i := 0; i < len(events); {
+		rule, ok := c.rule(events[i].Name())
+		if !ok {
+			if err := push(events[i], events[i]); err != nil {
+				return out, err
+			}
+			i++
+			continue
+		}
+
+		end := i + 1
+		for end < len(events) && slices.Contains(rule.Events, events[end].Name()) {
+			end++
+		}
+		run := events[i:end]
+		i = end
+
+		if len(run) < max(rule.Min, 2) {
+			for _, evt := range run {
+				if err := push(evt, evt); err != nil {
+					return out, err
+				}
+			}
+			continue
+		}
+
+		folded, err := rule.Fold(state, run)
+		if err != nil {
+			return out, fmt.Errorf("fold %d %q events: %w", len(run), run[0].Name(), err)
+		}
+
+		if err := push(folded, run[len(run)-1]); err != nil {
+			return out, err
+		}
+	}
+
+	return out, nil
+}
+
+func (c *Compactor) rule(name string) (Rule, bool) {
+	for _, rule := range c.rules {
+		if slices.Contains(rule.Events, name) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+func (c *Compactor) deleteSnapshots(ctx context.Context, ref aggregate.Ref) error {
+	str, errs, err := c.snapshots.Query(ctx, squery.New(
+		squery.Name(ref.Name),
+		squery.ID(ref.ID),
+	))
+	if err != nil {
+		return fmt.Errorf("query snapshots: %w", err)
+	}
+
+	return streams.Walk(ctx, func(snap snapshot.Snapshot) error {
+		return c.snapshots.Delete(ctx, snap)
+	}, str, errs)
+}
+
+func verify(original, compacted aggregate.Aggregate) error {
+	want, err := snapshot.Marshal(original)
+	if err != nil {
+		return fmt.Errorf("marshal original aggregate: %w", err)
+	}
+
+	got, err := snapshot.Marshal(compacted)
+	if err != nil {
+		return fmt.Errorf("marshal compacted aggregate: %w", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		return ErrVerification
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package compact_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/compact"
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+type counter struct {
+	*aggregate.Base
+
+	Value int
+}
+
+func newCounter(id uuid.UUID) *counter {
+	c := &counter{Base: aggregate.New("counter", id)}
+	event.ApplyWith(c, func(event.Of[int]) { c.Value++ }, "counter.incremented")
+	event.ApplyWith(c, func(evt event.Of[int]) { c.Value = evt.Data() }, "counter.set")
+	event.ApplyWith(c, func(event.Of[int]) { c.Value *= 2 }, "counter.doubled")
+	return c
+}
+
+func (c *counter) MarshalSnapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(c.Value)), nil
+}
+
+func (c *counter) UnmarshalSnapshot(p []byte) (err error) {
+	c.Value, err = strconv.Atoi(string(p))
+	return
+}
+
+func newFunc(ref aggregate.Ref) aggregate.Aggregate {
+	return newCounter(ref.ID)
+}
+
+var foldIncrements = compact.FoldInto("counter.set", func(state aggregate.Aggregate, run []event.Event) (int, error) {
+	return state.(*counter).Value + len(run), nil
+}, "counter.incremented")
+
+func TestCompactor_Compact(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+
+	c := setup(t, store, "counter.incremented", "counter.incremented", "counter.incremented", "counter.doubled", "counter.incremented", "counter.doubled", "counter.incremented", "counter.incremented")
+
+	results, err := compact.New(store, newFunc, compact.WithRules(foldIncrements)).Compact(ctx, aggregate.Ref{Name: "counter", ID: c.ID})
+	if err != nil {
+		t.Fatalf("Compact() failed with %q", err)
+	}
+
+	if results[0].Before != 8 || results[0].After != 5 {
+		t.Fatalf("result should be %d -> %d events; is %d -> %d", 8, 5, results[0].Before, results[0].After)
+	}
+
+	events := queryEvents(t, store, c.ID)
+	wantNames := []string{"counter.set", "counter.doubled", "counter.incremented", "counter.doubled", "counter.set"}
+	for i, evt := range events {
+		if evt.Name() != wantNames[i] {
+			t.Fatalf("event #%d should be a %q event; is %q", i, wantNames[i], evt.Name())
+		}
+		if _, _, v := evt.Aggregate(); v != i+1 {
+			t.Fatalf("event #%d should have version %d; has %d", i, i+1, v)
+		}
+	}
+
+	fetched := newCounter(c.ID)
+	if err := repository.New(store).Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if fetched.Value != c.Value {
+		t.Fatalf("Value should be %d; is %d", c.Value, fetched.Value)
+	}
+}
+
+func TestCompactor_Compact_verificationFailed(t *testing.T) {
+	store := eventstore.New()
+
+	c := setup(t, store, "counter.incremented", "counter.incremented", "counter.incremented")
+
+	broken := compact.FoldInto("counter.set", func(state aggregate.Aggregate, run []event.Event) (int, error) {
+		return len(run) - 1, nil
+	}, "counter.incremented")
+
+	_, err := compact.New(store, newFunc, compact.WithRules(broken)).Compact(context.Background(), aggregate.Ref{Name: "counter", ID: c.ID})
+	if !errors.Is(err, compact.ErrVerification) {
+		t.Fatalf("Compact() should fail with %q; got %q", compact.ErrVerification, err)
+	}
+
+	if events := queryEvents(t, store, c.ID); len(events) != 3 {
+		t.Fatalf("original events should be kept; %d events remain", len(events))
+	}
+}
+
+func TestCompactor_Compact_nonAtomicRestore(t *testing.T) {
+	mem := eventstore.New()
+	c := setup(t, mem, "counter.incremented", "counter.incremented", "counter.incremented")
+
+	store := &failingInsertStore{Store: mem, failures: 1}
+
+	_, err := compact.New(store, newFunc, compact.WithRules(foldIncrements)).Compact(context.Background(), aggregate.Ref{Name: "counter", ID: c.ID})
+	if !errors.Is(err, errInsertFailed) {
+		t.Fatalf("Compact() should fail with %q; got %v", errInsertFailed, err)
+	}
+
+	var recoveryErr *compact.RecoveryError
+	if errors.As(err, &recoveryErr) {
+		t.Fatalf("Compact() should restore the original events; got %v", err)
+	}
+
+	if events := queryEvents(t, mem, c.ID); len(events) != 3 {
+		t.Fatalf("original events should be restored; %d events remain", len(events))
+	}
+}
+
+func TestRecoveryError(t *testing.T) {
+	mem := eventstore.New()
+	c := setup(t, mem, "counter.incremented", "counter.incremented", "counter.incremented")
+
+	store := &failingInsertStore{Store: mem, failures: 2}
+
+	_, err := compact.New(store, newFunc, compact.WithRules(foldIncrements)).Compact(context.Background(), aggregate.Ref{Name: "counter", ID: c.ID})
+
+	var recoveryErr *compact.RecoveryError
+	if !errors.As(err, &recoveryErr) {
+		t.Fatalf("Compact() should fail with a %T; got %v", recoveryErr, err)
+	}
+
+	if len(recoveryErr.Events) != 3 {
+		t.Fatalf("RecoveryError should contain %d events; contains %d", 3, len(recoveryErr.Events))
+	}
+
+	if err := mem.Insert(context.Background(), recoveryErr.Events...); err != nil {
+		t.Fatalf("restore events: %v", err)
+	}
+
+	if events := queryEvents(t, mem, c.ID); len(events) != 3 {
+		t.Fatalf("original events should be restored; %d events remain", len(events))
+	}
+}
+
+var errInsertFailed = errors.New("insert failed")
+
+// failingInsertStore does not implement eventstore.AtomicReplacer.
+type failingInsertStore struct {
+	event.Store
+	failures int
+}
+
+func (s *failingInsertStore) Insert(ctx context.Context, events ...event.Event) error {
+	if s.failures > 0 {
+		s.failures--
+		return errInsertFailed
+	}
+	return s.Store.Insert(ctx, events...)
+}
+
+func TestDryRun(t *testing.T) {
+	store := eventstore.New()
+
+	c := setup(t, store, "counter.incremented", "counter.incremented", "counter.incremented")
+
+	results, err := compact.New(store, newFunc, compact.WithRules(foldIncrements), compact.DryRun(true)).Compact(context.Background(), aggregate.Ref{Name: "counter", ID: c.ID})
+	if err != nil {
+		t.Fatalf("Compact() failed with %q", err)
+	}
+
+	if results[0].After != 1 {
+		t.Fatalf("After should be %d; is %d", 1, results[0].After)
+	}
+
+	if events := queryEvents(t, store, c.ID); len(events) != 3 {
+		t.Fatalf("original events should be kept; %d events remain", len(events))
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	c := setup(t, store, "counter.incremented", "counter.incremented", "counter.incremented")
+
+	snap, err := snapshot.New(c)
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+	if err := snapshots.Save(ctx, snap); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	if _, err := compact.New(store, newFunc, compact.WithRules(foldIncrements), compact.Snapshots(snapshots)).Compact(ctx, aggregate.Ref{Name: "counter", ID: c.ID}); err != nil {
+		t.Fatalf("Compact() failed with %q", err)
+	}
+
+	if _, err := snapshots.Latest(ctx, "counter", c.ID); !errors.Is(err, snapshot.ErrNotFound) {
+		t.Fatalf("snapshot should have been deleted; Latest() returned %v", err)
+	}
+}
+
+func setup(t *testing.T, store event.Store, names ...string) *counter {
+	c := newCounter(uuid.New())
+	for _, name := range names {
+		aggregate.Next(c, name, 0)
+	}
+
+	if err := store.Insert(context.Background(), c.AggregateChanges()...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	return c
+}
+
+func queryEvents(t *testing.T, store event.Store, id uuid.UUID) []event.Event {
+	str, errs, err := store.Query(context.Background(), query.New(
+		query.Aggregate("counter", id),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	return events
+}
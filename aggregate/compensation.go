@@ -0,0 +1,91 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+)
+
+// CompensationSuffix is appended to the name of an event to build the name of
+// the event that compensates it. For example, a "shop.order.placed" event is
+// compensated by a "shop.order.placed.compensated" event.
+const CompensationSuffix = ".compensated"
+
+// Compensation is the event data of a compensating event. A compensating event
+// logically reverts a previous event of the same aggregate without modifying
+// the immutable event stream. The compensated event is referenced by its id
+// (the causation of the compensating event).
+type Compensation struct {
+	EventID      uuid.UUID
+	EventName    string
+	EventVersion int
+	Reason       string
+}
+
+// CompensationEvent returns the name of the event that compensates events
+// with the given name.
+func CompensationEvent(name string) string {
+	return name + CompensationSuffix
+}
+
+// RegisterCompensations registers the compensating events of the given events
+// into a registry.
+func RegisterCompensations(r codec.Registerer, events ...string) {
+	for _, name := range events {
+		codec.Register[Compensation](r, CompensationEvent(name))
+	}
+}
+
+// Compensate raises an event that compensates the given event of the
+// aggregate. The name of the compensating event is built using
+// CompensationEvent. The aggregate applies the compensating event like any
+// other event, so it must register an event handler for it if its state
+// depends on the compensated event:
+//
+//	func NewOrder(id uuid.UUID) *Order {
+//		o := &Order{Base: aggregate.New("shop.order", id)}
+//		event.ApplyWith(o, o.placed, "shop.order.placed")
+//		event.ApplyWith(o, o.unplaced, aggregate.CompensationEvent("shop.order.placed"))
+//		return o
+//	}
+//
+//	func (o *Order) RevertPlacement(evt event.Event) error {
+//		_, err := aggregate.Compensate(o, evt, "payment declined")
+//		return err
+//	}
+//
+// Compensate returns an error if the event does not belong to the aggregate.
+func Compensate(a Aggregate, evt event.Event, reason string, opts ...event.Option) (event.Evt[Compensation], error) {
+	id, name, _ := a.Aggregate()
+	eid, ename, ev := evt.Aggregate()
+
+	if eid != id || ename != name {
+		return event.Evt[Compensation]{}, fmt.Errorf(
+			"cannot compensate %q event of aggregate %s(%s) within aggregate %s(%s)",
+			evt.Name(), ename, eid, name, id,
+		)
+	}
+
+	return Next(a, CompensationEvent(evt.Name()), Compensation{
+		EventID:      evt.ID(),
+		EventName:    evt.Name(),
+		EventVersion: ev,
+		Reason:       reason,
+	}, opts...), nil
+}
+
+// IsCompensation returns the Compensation of the given event if it is a
+// compensating event.
+func IsCompensation(evt event.Event) (Compensation, bool) {
+	switch data := evt.Data().(type) {
+	case Compensation:
+		return data, true
+	case *Compensation:
+		if data != nil {
+			return *data, true
+		}
+	}
+	return Compensation{}, false
+}
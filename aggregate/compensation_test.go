@@ -0,0 +1,56 @@
+package aggregate_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/test"
+)
+
+func TestCompensate(t *testing.T) {
+	a := aggregate.New("foo", uuid.New())
+	evt := aggregate.Next(a, "foo", test.FooEventData{}).Any()
+
+	comp, err := aggregate.Compensate(a, evt, "mistake")
+	if err != nil {
+		t.Fatalf("Compensate() failed with %q", err)
+	}
+
+	if comp.Name() != "foo.compensated" {
+		t.Errorf("compensating event should be named %q; got %q", "foo.compensated", comp.Name())
+	}
+
+	want := aggregate.Compensation{
+		EventID:      evt.ID(),
+		EventName:    "foo",
+		EventVersion: 1,
+		Reason:       "mistake",
+	}
+
+	if comp.Data() != want {
+		t.Errorf("compensation should be %v; got %v", want, comp.Data())
+	}
+
+	if v := aggregate.UncommittedVersion(a); v != 2 {
+		t.Errorf("aggregate should be at version %d; got %d", 2, v)
+	}
+
+	if data, ok := aggregate.IsCompensation(comp.Any()); !ok || data != want {
+		t.Errorf("IsCompensation() should return %v; got %v", want, data)
+	}
+}
+
+func TestCompensate_otherAggregate(t *testing.T) {
+	a := aggregate.New("foo", uuid.New())
+	evt := event.New("foo", test.FooEventData{}, event.Aggregate(uuid.New(), "foo", 1)).Any()
+
+	if _, err := aggregate.Compensate(a, evt, "mistake"); err == nil {
+		t.Fatalf("Compensate() should fail for events of other aggregates")
+	}
+
+	if changes := a.AggregateChanges(); len(changes) != 0 {
+		t.Fatalf("aggregate should have no changes; got %d", len(changes))
+	}
+}
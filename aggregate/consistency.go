@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/modernice/goes/command"
 	"github.com/modernice/goes/event"
 )
 
@@ -233,6 +234,13 @@ func (err *ConsistencyError) IsConsistencyError() bool {
 	return true
 }
 
+// ErrorKind implements [command.ClassifiedError]. A ConsistencyError is always
+// classified as a [command.KindConflict], so that dispatchers of a command can
+// detect concurrent modifications of the aggregate.
+func (err *ConsistencyError) ErrorKind() command.ErrorKind {
+	return command.KindConflict
+}
+
 // String returns a string representation of the ConsistencyKind value, such as
 // "<InconsistentID>", "<InconsistentName>", "<InconsistentVersion>", or
 // "<InconsistentTime>".
@@ -0,0 +1,276 @@
+// Package determinism verifies that aggregates are hydrated deterministically.
+//
+// An aggregate whose event handlers depend on anything other than the applied
+// events (e.g. the iteration order of maps or the current time) hydrates to
+// different states each time its events are replayed. Such aggregates silently
+// diverge from their snapshots, so that fetching an aggregate from a snapshot
+// yields a different state than fetching it from its events. A Checker
+// hydrates aggregates multiple times and reports the aggregates whose states
+// differ:
+//
+//	c := determinism.New(store, func(ref aggregate.Ref) aggregate.Aggregate {
+//		return NewOrder(ref.ID)
+//	}, determinism.Snapshots(snapshots))
+//
+//	report, err := c.CheckSample(context.TODO(), 100, "shop.order")
+//	for _, m := range report.Mismatches {
+//		log.Println(m)
+//	}
+//
+// States are compared using snapshot.Marshal, so the aggregates must implement
+// snapshot.Marshaler (or encoding.BinaryMarshaler / encoding.TextMarshaler).
+package determinism
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// DefaultReplays is the default number of times an aggregate is hydrated from
+// its events.
+const DefaultReplays = 2
+
+const (
+	// Replay is the Source of a Mismatch between two hydrations of an aggregate
+	// from its events.
+	Replay = Source("replay")
+
+	// Snapshot is the Source of a Mismatch between the hydration of an
+	// aggregate from its latest snapshot and from its events.
+	Snapshot = Source("snapshot")
+)
+
+// Source is the kind of hydration that produced a mismatching state.
+type Source string
+
+// Checker verifies that aggregates are hydrated deterministically.
+type Checker struct {
+	store     event.Store
+	newFunc   func(aggregate.Ref) aggregate.Aggregate
+	snapshots snapshot.Store
+	replays   int
+}
+
+// Option is an option for a Checker.
+type Option func(*Checker)
+
+// Mismatch is a difference between two hydrations of an aggregate.
+type Mismatch struct {
+	// Aggregate is the aggregate whose states differ.
+	Aggregate aggregate.Ref
+
+	// Source is the kind of hydration that produced the state Got.
+	Source Source
+
+	// Version is the version of the aggregate at which the hydrations were
+	// compared. Events that are inserted while an aggregate is checked are
+	// ignored.
+	Version int
+
+	// SnapshotVersion is the version of the latest snapshot up to Version that
+	// was used to hydrate the aggregate. SnapshotVersion is only set if Source
+	// is Snapshot.
+	SnapshotVersion int
+
+	// Want is the encoded state of the first hydration from events, and Got is
+	// the encoded state that differs from it.
+	Want, Got []byte
+}
+
+// Report is the result of a check.
+type Report struct {
+	// Checked are the checked aggregates.
+	Checked []aggregate.Ref
+
+	// Mismatches are the differences that were found.
+	Mismatches []Mismatch
+}
+
+// Snapshots returns an Option that makes the Checker also hydrate aggregates
+// from their latest snapshot in the given store and compare the result with the
+// hydration from events.
+func Snapshots(store snapshot.Store) Option {
+	return func(c *Checker) {
+		c.snapshots = store
+	}
+}
+
+// Replays returns an Option that configures how often an aggregate is hydrated
+// from its events. Values below 2 are ignored. Defaults to DefaultReplays.
+func Replays(n int) Option {
+	return func(c *Checker) {
+		if n >= 2 {
+			c.replays = n
+		}
+	}
+}
+
+// New returns a Checker that hydrates the aggregates from the given store.
+// newFunc must return a new, empty instance of the given aggregate.
+func New(store event.Store, newFunc func(aggregate.Ref) aggregate.Aggregate, opts ...Option) *Checker {
+	c := &Checker{
+		store:   store,
+		newFunc: newFunc,
+		replays: DefaultReplays,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Check checks the given aggregates. Check stops at the first error and
+// returns the report of the aggregates that have been checked so far.
+func (c *Checker) Check(ctx context.Context, aggregates ...aggregate.Ref) (Report, error) {
+	var report Report
+	for _, ref := range aggregates {
+		mismatches, err := c.check(ctx, ref)
+		if err != nil {
+			return report, fmt.Errorf("check %s: %w", ref, err)
+		}
+		report.Checked = append(report.Checked, ref)
+		report.Mismatches = append(report.Mismatches, mismatches...)
+	}
+	return report, nil
+}
+
+// CheckSample checks a random sample of n aggregates with the given names. If
+// there are less than n aggregates, or if n <= 0, all aggregates are checked.
+func (c *Checker) CheckSample(ctx context.Context, n int, names ...string) (Report, error) {
+	refs, err := c.aggregates(ctx, names)
+	if err != nil {
+		return Report{}, fmt.Errorf("query aggregates: %w", err)
+	}
+
+	if n > 0 && n < len(refs) {
+		rand.Shuffle(len(refs), func(i, j int) { refs[i], refs[j] = refs[j], refs[i] })
+		refs = refs[:n]
+	}
+
+	return c.Check(ctx, refs...)
+}
+
+func (c *Checker) check(ctx context.Context, ref aggregate.Ref) ([]Mismatch, error) {
+	repo := repository.New(c.store)
+
+	a := c.newFunc(ref)
+	if err := repo.Fetch(ctx, a); err != nil {
+		return nil, fmt.Errorf("hydrate from events: fetch aggregate: %w", err)
+	}
+	_, _, version := a.Aggregate()
+
+	want, err := snapshot.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("hydrate from events: marshal aggregate: %w", err)
+	}
+
+	// Events may be inserted while the aggregate is checked, so every further
+	// hydration fetches the version of the first hydration.
+	var mismatches []Mismatch
+	for i := 1; i < c.replays; i++ {
+		got, err := c.hydrate(ctx, repo, ref, version)
+		if err != nil {
+			return mismatches, fmt.Errorf("hydrate from events: %w", err)
+		}
+
+		if !bytes.Equal(want, got) {
+			mismatches = append(mismatches, Mismatch{
+				Aggregate: ref,
+				Source:    Replay,
+				Version:   version,
+				Want:      want,
+				Got:       got,
+			})
+			break
+		}
+	}
+
+	if c.snapshots == nil {
+		return mismatches, nil
+	}
+
+	snap, err := c.snapshots.Limit(ctx, ref.Name, ref.ID, version)
+	if err != nil || snap == nil {
+		// The aggregate has no snapshot up to the checked version.
+		return mismatches, nil
+	}
+
+	got, err := c.hydrate(ctx, repository.New(c.store, repository.WithSnapshots(c.snapshots, nil)), ref, version)
+	if err != nil {
+		return mismatches, fmt.Errorf("hydrate from snapshot: %w", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		mismatches = append(mismatches, Mismatch{
+			Aggregate:       ref,
+			Source:          Snapshot,
+			Version:         version,
+			SnapshotVersion: snap.AggregateVersion(),
+			Want:            want,
+			Got:             got,
+		})
+	}
+
+	return mismatches, nil
+}
+
+// hydrate fetches the given version of a new instance of the aggregate using
+// the given repository and returns its encoded state.
+func (c *Checker) hydrate(ctx context.Context, repo *repository.Repository, ref aggregate.Ref, version int) ([]byte, error) {
+	a := c.newFunc(ref)
+	if err := repo.FetchVersion(ctx, a, version); err != nil {
+		return nil, fmt.Errorf("fetch aggregate: %w [version=%d]", err, version)
+	}
+
+	state, err := snapshot.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("marshal aggregate: %w", err)
+	}
+
+	return state, nil
+}
+
+// aggregates returns the distinct aggregates with the given names, in the
+// order of their first event.
+func (c *Checker) aggregates(ctx context.Context, names []string) ([]aggregate.Ref, error) {
+	str, errs, err := c.store.Query(ctx, query.New(query.AggregateName(names...)))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[aggregate.Ref]bool)
+	var refs []aggregate.Ref
+
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		id, name, _ := evt.Aggregate()
+		ref := aggregate.Ref{Name: name, ID: id}
+		if ref.ID == uuid.Nil || ref.Name == "" || seen[ref] {
+			return nil
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+		return nil
+	}, str, errs); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// String returns a description of the mismatch.
+func (m Mismatch) String() string {
+	if m.Source == Snapshot {
+		return fmt.Sprintf("%s (v%d) hydrates to a different state from snapshot v%d than from events", m.Aggregate, m.Version, m.SnapshotVersion)
+	}
+	return fmt.Sprintf("%s (v%d) hydrates to different states when its events are replayed", m.Aggregate, m.Version)
+}
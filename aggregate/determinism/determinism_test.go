@@ -0,0 +1,207 @@
+package determinism_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/determinism"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+)
+
+type counter struct {
+	*aggregate.Base
+
+	Value int
+}
+
+// replays counts the hydrations of non-deterministic counters.
+var replays int
+
+func newCounter(id uuid.UUID, deterministic bool) *counter {
+	c := &counter{Base: aggregate.New("counter", id)}
+	event.ApplyWith(c, func(event.Of[int]) {
+		c.Value++
+		if !deterministic {
+			replays++
+			c.Value += replays
+		}
+	}, "counter.incremented")
+	return c
+}
+
+func (c *counter) MarshalSnapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(c.Value)), nil
+}
+
+func (c *counter) UnmarshalSnapshot(p []byte) (err error) {
+	c.Value, err = strconv.Atoi(string(p))
+	return
+}
+
+func TestChecker_Check(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+
+	good := setup(t, store, uuid.New(), 3)
+	bad := setup(t, store, uuid.New(), 3)
+
+	c := determinism.New(store, func(ref aggregate.Ref) aggregate.Aggregate {
+		return newCounter(ref.ID, ref.ID != bad.ID)
+	})
+
+	report, err := c.Check(ctx, good.Ref(), bad.Ref())
+	if err != nil {
+		t.Fatalf("Check() failed with %q", err)
+	}
+
+	if len(report.Checked) != 2 {
+		t.Fatalf("%d aggregates should have been checked; got %d", 2, len(report.Checked))
+	}
+
+	if len(report.Mismatches) != 1 {
+		t.Fatalf("Check() should report %d mismatch; got %d", 1, len(report.Mismatches))
+	}
+
+	m := report.Mismatches[0]
+	if m.Aggregate != bad.Ref() {
+		t.Fatalf("mismatch should be reported for %s; got %s", bad.Ref(), m.Aggregate)
+	}
+
+	if m.Source != determinism.Replay {
+		t.Fatalf("mismatch source should be %q; is %q", determinism.Replay, m.Source)
+	}
+
+	if m.Version != 3 {
+		t.Fatalf("mismatch version should be %d; is %d", 3, m.Version)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	good := setup(t, store, uuid.New(), 3)
+	bad := setup(t, store, uuid.New(), 3)
+
+	for _, a := range []*counter{good, bad} {
+		state := []byte(strconv.Itoa(a.Value))
+		if a == bad {
+			state = []byte("42")
+		}
+		snap, err := snapshot.New(a, snapshot.Data(state))
+		if err != nil {
+			t.Fatalf("create snapshot: %v", err)
+		}
+		if err := snapshots.Save(ctx, snap); err != nil {
+			t.Fatalf("save snapshot: %v", err)
+		}
+	}
+
+	c := determinism.New(store, func(ref aggregate.Ref) aggregate.Aggregate {
+		return newCounter(ref.ID, true)
+	}, determinism.Snapshots(snapshots))
+
+	report, err := c.CheckSample(ctx, 0, "counter")
+	if err != nil {
+		t.Fatalf("CheckSample() failed with %q", err)
+	}
+
+	if len(report.Checked) != 2 {
+		t.Fatalf("%d aggregates should have been checked; got %d", 2, len(report.Checked))
+	}
+
+	if len(report.Mismatches) != 1 {
+		t.Fatalf("CheckSample() should report %d mismatch; got %d", 1, len(report.Mismatches))
+	}
+
+	m := report.Mismatches[0]
+	if m.Aggregate != bad.Ref() || m.Source != determinism.Snapshot || m.SnapshotVersion != 3 {
+		t.Fatalf("snapshot mismatch should be reported for %s; got %v", bad.Ref(), m)
+	}
+
+	if string(m.Want) != "3" || string(m.Got) != "42" {
+		t.Fatalf("mismatch should report states %q and %q; got %q and %q", "3", "42", m.Want, m.Got)
+	}
+}
+
+func TestChecker_Check_concurrentInsert(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, store, uuid.New(), 3)
+
+	var calls int
+	c := determinism.New(store, func(ref aggregate.Ref) aggregate.Aggregate {
+		// Insert a new event and snapshot after the first hydration.
+		if calls++; calls == 2 {
+			aggregate.Next(a, "counter.incremented", 3)
+			if err := store.Insert(ctx, a.AggregateChanges()...); err != nil {
+				t.Fatalf("insert events: %v", err)
+			}
+			a.Commit()
+
+			snap, err := snapshot.New(a)
+			if err != nil {
+				t.Fatalf("create snapshot: %v", err)
+			}
+			if err := snapshots.Save(ctx, snap); err != nil {
+				t.Fatalf("save snapshot: %v", err)
+			}
+		}
+		return newCounter(ref.ID, true)
+	}, determinism.Snapshots(snapshots))
+
+	report, err := c.Check(ctx, a.Ref())
+	if err != nil {
+		t.Fatalf("Check() failed with %q", err)
+	}
+
+	if len(report.Mismatches) != 0 {
+		t.Fatalf("events that are inserted during a check should not cause mismatches; got %v", report.Mismatches)
+	}
+}
+
+func TestChecker_CheckSample(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+
+	for i := 0; i < 5; i++ {
+		setup(t, store, uuid.New(), 1)
+	}
+
+	c := determinism.New(store, func(ref aggregate.Ref) aggregate.Aggregate {
+		return newCounter(ref.ID, true)
+	})
+
+	report, err := c.CheckSample(ctx, 3, "counter")
+	if err != nil {
+		t.Fatalf("CheckSample() failed with %q", err)
+	}
+
+	if len(report.Checked) != 3 {
+		t.Fatalf("%d aggregates should have been checked; got %d", 3, len(report.Checked))
+	}
+}
+
+func setup(t *testing.T, store event.Store, id uuid.UUID, n int) *counter {
+	t.Helper()
+
+	c := newCounter(id, true)
+	for i := 0; i < n; i++ {
+		aggregate.Next(c, "counter.incremented", i)
+	}
+
+	if err := store.Insert(context.Background(), c.AggregateChanges()...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+	c.Commit()
+
+	return c
+}
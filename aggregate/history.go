@@ -24,7 +24,8 @@ type History interface {
 // ApplyHistory applies a sequence of events to the given Aggregate, ensuring
 // consistency before applying. If the Aggregate implements the Committer
 // interface, changes are recorded and committed after applying the events.
-// Returns an error if consistency validation fails.
+// Returns an error if consistency validation fails. The invariants of the
+// aggregate are not checked (see Base.Invariant).
 func ApplyHistory[Events ~[]event.Of[any]](a Aggregate, events Events) error {
 	id, name, _ := a.Aggregate()
 	version := UncommittedVersion(a)
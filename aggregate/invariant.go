@@ -0,0 +1,117 @@
+package aggregate
+
+import (
+	"fmt"
+
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/event"
+)
+
+// InvariantChecker is an aggregate that declares invariants about its state.
+// *Base implements InvariantChecker.
+type InvariantChecker interface {
+	// CheckInvariants returns an *InvariantViolation if an invariant of the
+	// aggregate is violated.
+	CheckInvariants() error
+}
+
+// InvariantViolation is returned if an invariant of an aggregate is violated.
+type InvariantViolation struct {
+	// Aggregate is the aggregate whose invariant is violated.
+	Aggregate Ref
+
+	// Version is the version of the aggregate at the time of the violation.
+	Version int
+
+	// Event is the event after which the invariant was violated. Event is nil
+	// if the violation was not detected while recording a change.
+	Event event.Event
+
+	// Err is the error returned by the invariant.
+	Err error
+}
+
+// Invariant adds an invariant to the aggregate. Invariants are checked after
+// every change that is recorded through RecordChange (e.g. by Next), and by
+// CheckInvariants. An invariant returns a non-nil error if the state of the
+// aggregate is invalid. The first violation is remembered by the aggregate and
+// returned by CheckInvariants, so that it is reported by repositories before
+// the aggregate is saved. The violation is cleared when the changes are
+// committed or discarded.
+//
+// Invariants are not checked while the aggregate is hydrated from its event
+// history (see ApplyHistory), so that invariants that were introduced after
+// the events were recorded do not prevent the aggregate from being fetched.
+//
+//	func NewCounter(id uuid.UUID) *Counter {
+//		c := &Counter{Base: aggregate.New("counter", id)}
+//		c.Invariant(func() error {
+//			if c.Value < 0 {
+//				return errors.New("value must not be negative")
+//			}
+//			return nil
+//		})
+//		return c
+//	}
+func (b *Base) Invariant(fn func() error) {
+	b.invariants = append(b.invariants, fn)
+}
+
+// CheckInvariants returns the first invariant violation that was detected
+// while recording changes of the aggregate. If no violation was detected,
+// CheckInvariants checks the invariants against the current state of the
+// aggregate.
+func (b *Base) CheckInvariants() error {
+	if b.violation != nil {
+		return b.violation
+	}
+	if v := b.checkInvariants(nil); v != nil {
+		return v
+	}
+	return nil
+}
+
+func (b *Base) checkInvariants(evt event.Event) *InvariantViolation {
+	for _, fn := range b.invariants {
+		if err := fn(); err != nil {
+			v := &InvariantViolation{
+				Aggregate: b.Ref(),
+				Version:   b.Version,
+				Event:     evt,
+				Err:       err,
+			}
+			if evt != nil {
+				_, _, v.Version = evt.Aggregate()
+			}
+			return v
+		}
+	}
+	return nil
+}
+
+// CheckInvariants checks the invariants of the given aggregate if it
+// implements InvariantChecker.
+func CheckInvariants(a Aggregate) error {
+	if c, ok := a.(InvariantChecker); ok {
+		return c.CheckInvariants()
+	}
+	return nil
+}
+
+// Error implements error.
+func (v *InvariantViolation) Error() string {
+	if v.Event == nil {
+		return fmt.Sprintf("invariant of %s violated at version %d: %v", v.Aggregate, v.Version, v.Err)
+	}
+	return fmt.Sprintf("invariant of %s violated after %q event (version %d): %v", v.Aggregate, v.Event.Name(), v.Version, v.Err)
+}
+
+// Unwrap returns the error returned by the invariant.
+func (v *InvariantViolation) Unwrap() error {
+	return v.Err
+}
+
+// ErrorKind returns command.KindValidation.
+func (v *InvariantViolation) ErrorKind() command.ErrorKind {
+	return command.KindValidation
+}
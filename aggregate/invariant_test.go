@@ -0,0 +1,126 @@
+package aggregate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+)
+
+var errNegative = errors.New("value must not be negative")
+
+type invariantCounter struct {
+	*aggregate.Base
+
+	Value int
+}
+
+func newInvariantCounter(id uuid.UUID) *invariantCounter {
+	c := &invariantCounter{Base: aggregate.New("counter", id)}
+	event.ApplyWith(c, func(evt event.Of[int]) { c.Value += evt.Data() }, "counter.added")
+	c.Invariant(func() error {
+		if c.Value < 0 {
+			return errNegative
+		}
+		return nil
+	})
+	return c
+}
+
+func TestBase_Invariant(t *testing.T) {
+	c := newInvariantCounter(uuid.New())
+
+	aggregate.Next(c, "counter.added", 3)
+
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatalf("CheckInvariants() should return nil; got %q", err)
+	}
+
+	evt := aggregate.Next(c, "counter.added", -5)
+	aggregate.Next(c, "counter.added", 10)
+
+	err := c.CheckInvariants()
+
+	var violation *aggregate.InvariantViolation
+	if !errors.As(err, &violation) {
+		t.Fatalf("CheckInvariants() should return an %T; got %v", violation, err)
+	}
+
+	if violation.Event.ID() != evt.ID() {
+		t.Fatalf("violation should report the %q event at version %d", evt.Name(), 2)
+	}
+
+	if violation.Version != 2 {
+		t.Fatalf("Version should be %d; is %d", 2, violation.Version)
+	}
+
+	if !errors.Is(err, errNegative) {
+		t.Fatalf("CheckInvariants() should return %q; got %q", errNegative, err)
+	}
+
+	if kind := command.ErrorKindOf(err); kind != command.KindValidation {
+		t.Fatalf("error kind should be %q; is %q", command.KindValidation, kind)
+	}
+}
+
+func TestApplyHistory_invariantViolated(t *testing.T) {
+	id := uuid.New()
+	source := aggregate.New("counter", id)
+	events := []event.Event{
+		aggregate.Next(source, "counter.added", -1).Any(),
+	}
+
+	c := newInvariantCounter(id)
+	if err := aggregate.ApplyHistory(c, events); err != nil {
+		t.Fatalf("ApplyHistory() should not check invariants; got %q", err)
+	}
+
+	if c.Value != -1 {
+		t.Fatalf("Value should be %d; is %d", -1, c.Value)
+	}
+
+	aggregate.Next(c, "counter.added", 5)
+
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatalf("CheckInvariants() should return nil; got %q", err)
+	}
+}
+
+func TestBase_DiscardChanges_invariantViolated(t *testing.T) {
+	c := newInvariantCounter(uuid.New())
+
+	aggregate.Next(c, "counter.added", -1)
+
+	if err := c.CheckInvariants(); !errors.Is(err, errNegative) {
+		t.Fatalf("CheckInvariants() should return %q; got %v", errNegative, err)
+	}
+
+	c.DiscardChanges()
+	c.Value = 0
+
+	if err := c.CheckInvariants(); err != nil {
+		t.Fatalf("CheckInvariants() should return nil after discarding the changes; got %q", err)
+	}
+}
+
+func TestRepository_Save_invariantViolated(t *testing.T) {
+	store := eventstore.New()
+	repo := repository.New(store)
+
+	c := newInvariantCounter(uuid.New())
+	aggregate.Next(c, "counter.added", -1)
+
+	if err := repo.Save(context.Background(), c); !errors.Is(err, errNegative) {
+		t.Fatalf("Save() should fail with %q; got %q", errNegative, err)
+	}
+
+	if len(c.AggregateChanges()) != 1 {
+		t.Fatalf("changes should not be committed")
+	}
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+)
+
+// DryRun is a copy-on-write aggregate.Repository that never persists
+// aggregates. Aggregates are fetched from an underlying repository, but Save
+// only records the changes of an aggregate instead of inserting them into the
+// event store. Subsequent fetches of the same aggregate from the DryRun
+// repository include the recorded changes, so a command handler that saves
+// multiple times sees its own changes. Use Events to get the events that
+// would have been persisted.
+//
+// DryRun is used to execute commands that were dispatched using
+// command.DryRun(). FetchVersion and Query only return persisted state, and
+// Delete does nothing.
+type DryRun struct {
+	aggregate.Repository
+
+	mux     sync.Mutex
+	changes map[aggregate.Ref][]event.Event
+	events  []event.Event
+}
+
+// NewDryRun returns a DryRun repository that reads aggregates from the
+// provided repository.
+func NewDryRun(repo aggregate.Repository) *DryRun {
+	return &DryRun{
+		Repository: repo,
+		changes:    make(map[aggregate.Ref][]event.Event),
+	}
+}
+
+// Events returns the events that would have been persisted, in the order in
+// which they were saved.
+func (r *DryRun) Events() []event.Event {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	out := make([]event.Event, len(r.events))
+	copy(out, r.events)
+	return out
+}
+
+// Save records the changes of the aggregate and commits them, without
+// inserting them into the event store.
+func (r *DryRun) Save(ctx context.Context, a aggregate.Aggregate) error {
+	ref := refOf(a)
+	_, _, version := a.Aggregate()
+	changes := a.AggregateChanges()
+
+	if err := aggregate.ValidateConsistency(ref, version, changes); err != nil {
+		return fmt.Errorf("validate consistency: %w", err)
+	}
+
+	if err := aggregate.CheckInvariants(a); err != nil {
+		return err
+	}
+
+	r.mux.Lock()
+	r.changes[ref] = append(r.changes[ref], changes...)
+	r.events = append(r.events, changes...)
+	r.mux.Unlock()
+
+	if c, ok := a.(aggregate.Committer); ok {
+		c.Commit()
+	}
+
+	return nil
+}
+
+// Fetch fetches the aggregate from the underlying repository and applies the
+// changes that were recorded for the aggregate.
+func (r *DryRun) Fetch(ctx context.Context, a aggregate.Aggregate) error {
+	if err := r.Repository.Fetch(ctx, a); err != nil {
+		return err
+	}
+
+	_, _, version := a.Aggregate()
+
+	r.mux.Lock()
+	var pending []event.Event
+	for _, evt := range r.changes[refOf(a)] {
+		if _, _, v := evt.Aggregate(); v > version {
+			pending = append(pending, evt)
+		}
+	}
+	r.mux.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	if err := aggregate.ApplyHistory(a, pending); err != nil {
+		return fmt.Errorf("apply recorded changes: %w", err)
+	}
+
+	return nil
+}
+
+// Use fetches the aggregate, calls fn, and records the changes of the
+// aggregate.
+func (r *DryRun) Use(ctx context.Context, a aggregate.Aggregate, fn func() error) error {
+	if err := r.Fetch(ctx, a); err != nil {
+		return fmt.Errorf("fetch aggregate: %w", err)
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	if err := r.Save(ctx, a); err != nil {
+		return fmt.Errorf("save aggregate: %w", err)
+	}
+
+	return nil
+}
+
+// Delete does nothing.
+func (r *DryRun) Delete(context.Context, aggregate.Aggregate) error {
+	return nil
+}
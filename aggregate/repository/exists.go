@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	equery "github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// Exists reports whether the event store contains events of the given
+// aggregate. Exists does not fetch the aggregate's events and does not check
+// if the aggregate was soft-deleted. Command handlers can use Exists to
+// validate references to other aggregates:
+//
+//	if ok, err := repo.Exists(ctx, aggregate.Ref{Name: "customer", ID: cmd.CustomerID}); err != nil {
+//		return err
+//	} else if !ok {
+//		return ErrCustomerNotFound
+//	}
+func (r *Repository) Exists(ctx context.Context, ref aggregate.Ref) (bool, error) {
+	v, err := r.Version(ctx, ref)
+	if err != nil {
+		return false, err
+	}
+	return v > 0, nil
+}
+
+// Version returns the current version of the given aggregate, or 0 if the
+// event store contains no events of the aggregate. If the event store
+// implements eventstore.Versioner, the version is looked up directly.
+// Otherwise, only the latest event of the aggregate is queried.
+func (r *Repository) Version(ctx context.Context, ref aggregate.Ref) (int, error) {
+	if v, ok := r.store.(eventstore.Versioner); ok {
+		version, err := v.AggregateVersion(ctx, ref)
+		if err != nil {
+			return 0, fmt.Errorf("look up version of %s: %w", ref, err)
+		}
+		return version, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	str, errs, err := r.store.Query(ctx, equery.New(
+		equery.AggregateName(ref.Name),
+		equery.AggregateID(ref.ID),
+		equery.SortBy(event.SortAggregateVersion, event.SortDesc),
+	))
+	if err != nil {
+		return 0, fmt.Errorf("query version of %s: %w", ref, err)
+	}
+
+	events, err := streams.Take(ctx, 1, str, errs)
+	if err != nil {
+		return 0, fmt.Errorf("query version of %s: %w", ref, err)
+	}
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	_, _, v := events[0].Aggregate()
+
+	return v, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	stdtime "time"
+
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+	equery "github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/time"
+	"github.com/modernice/goes/event/query/version"
+)
+
+// HistoryOption is an option for Repository.History.
+type HistoryOption func(*historyOptions)
+
+type historyOptions struct {
+	names []string
+	since stdtime.Time
+	until stdtime.Time
+}
+
+// HistoryNames returns a HistoryOption that only streams events with one of
+// the given names.
+func HistoryNames(names ...string) HistoryOption {
+	return func(o *historyOptions) {
+		o.names = append(o.names, names...)
+	}
+}
+
+// HistorySince returns a HistoryOption that only streams events that occurred
+// at or after the given time.
+func HistorySince(t stdtime.Time) HistoryOption {
+	return func(o *historyOptions) {
+		o.since = t
+	}
+}
+
+// HistoryUntil returns a HistoryOption that only streams events that occurred
+// at or before the given time.
+func HistoryUntil(t stdtime.Time) HistoryOption {
+	return func(o *historyOptions) {
+		o.until = t
+	}
+}
+
+// History streams the events of the given aggregate with versions between from
+// and to (both inclusive), sorted by version, without applying them to the
+// aggregate. A to of 0 or less streams all events from the version from
+// onwards. History is meant for audit logs and similar views of an aggregate's
+// history that do not need the aggregate's state:
+//
+//	events, errs, err := repo.History(ctx, aggregate.Ref{Name: "order", ID: id}, 1, 0,
+//		repository.HistorySince(time.Now().AddDate(0, -1, 0)),
+//	)
+func (r *Repository) History(ctx context.Context, ref aggregate.Ref, from, to int, opts ...HistoryOption) (<-chan event.Event, <-chan error, error) {
+	var options historyOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	versionOpts := []version.Option{version.Min(from)}
+	if to > 0 {
+		versionOpts = append(versionOpts, version.Max(to))
+	}
+
+	queryOpts := []equery.Option{
+		equery.AggregateName(ref.Name),
+		equery.AggregateID(ref.ID),
+		equery.AggregateVersion(versionOpts...),
+		equery.SortBy(event.SortAggregateVersion, event.SortAsc),
+	}
+
+	if len(options.names) > 0 {
+		queryOpts = append(queryOpts, equery.Name(options.names...))
+	}
+
+	var timeOpts []time.Option
+	if !options.since.IsZero() {
+		timeOpts = append(timeOpts, time.Min(options.since))
+	}
+	if !options.until.IsZero() {
+		timeOpts = append(timeOpts, time.Max(options.until))
+	}
+	if len(timeOpts) > 0 {
+		queryOpts = append(queryOpts, equery.Time(timeOpts...))
+	}
+
+	events, errs, err := r.store.Query(ctx, equery.New(queryOpts...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("query events: %w", err)
+	}
+
+	return events, errs, nil
+}
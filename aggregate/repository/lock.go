@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event/eventstore"
+)
+
+// ErrLockingUnsupported is returned by Repository.Lock if the event store of
+// the repository does not implement eventstore.Locker.
+var ErrLockingUnsupported = eventstore.ErrLockingUnsupported
+
+type lockCtxKey struct{}
+
+// Lock is an exclusive, expiring lock on an aggregate that is acquired using
+// Repository.Lock. A Lock is safe for concurrent use.
+type Lock struct {
+	locker eventstore.Locker
+
+	mux   sync.RWMutex
+	lease eventstore.Lease
+}
+
+// CheckLocks returns an Option that makes the Repository check the locks of
+// aggregates before changing them. When an aggregate is locked (see
+// Repository.Lock), Save and Delete fail with an error that unwraps to
+// eventstore.ErrLocked, unless the context that is passed to them carries the
+// active lock of the aggregate (see WithLock). If the context carries a lock
+// whose lease has expired or was replaced by another lease, they fail with an
+// error that unwraps to eventstore.ErrLockLost. If the event store of the
+// Repository does not implement eventstore.Locker, they fail with an error
+// that unwraps to ErrLockingUnsupported.
+//
+// Locks are advisory. The lease is checked before the events are inserted, and
+// not atomically with the insert, so a lock that is acquired or expires while
+// the events are being inserted is not detected. Locks keep workflows and
+// operators from accidentally changing an aggregate that is held by another
+// workflow; they do not fence off a holder whose lease expired in the middle of
+// a change. The TTL of a lock should leave enough room for the operations that
+// are performed under the lock.
+func CheckLocks() Option {
+	return func(r *Repository) {
+		r.checkLocks = true
+	}
+}
+
+// Lock acquires an exclusive lock on the given aggregate that expires after
+// the given TTL. Use Lock to hold an aggregate across multiple commands of an
+// administrative workflow, and pass the lock to the operations of the workflow
+// using WithLock:
+//
+//	lock, err := repo.Lock(ctx, aggregate.Ref{Name: "order", ID: id}, time.Minute)
+//	if err != nil {
+//		return err
+//	}
+//	defer lock.Unlock(ctx)
+//
+//	ctx = repository.WithLock(ctx, lock)
+//	// fetch, change, and save the order using ctx
+//
+// If the aggregate is already locked, Lock returns an error that unwraps to
+// eventstore.ErrLocked. If the event store does not implement
+// eventstore.Locker, Lock returns ErrLockingUnsupported. The lock is only
+// checked by repositories that use the CheckLocks option.
+func (r *Repository) Lock(ctx context.Context, ref aggregate.Ref, ttl time.Duration) (*Lock, error) {
+	locker, ok := r.store.(eventstore.Locker)
+	if !ok {
+		return nil, fmt.Errorf("%w [store=%T]", ErrLockingUnsupported, r.store)
+	}
+
+	lease, err := locker.Lock(ctx, ref, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", ref, err)
+	}
+
+	return &Lock{locker: locker, lease: lease}, nil
+}
+
+// Aggregate returns the locked aggregate.
+func (l *Lock) Aggregate() aggregate.Ref {
+	return l.Lease().Aggregate
+}
+
+// Token returns the token of the lease of the lock. Tokens increase with every
+// lock that is acquired on the same aggregate.
+func (l *Lock) Token() int64 {
+	return l.Lease().Token
+}
+
+// Expires returns the time at which the lock expires.
+func (l *Lock) Expires() time.Time {
+	return l.Lease().Expires
+}
+
+// Lease returns the current lease of the lock.
+func (l *Lock) Lease() eventstore.Lease {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	return l.lease
+}
+
+// Renew extends the lock by the given TTL, starting now. If the lock has
+// already expired, Renew returns an error that unwraps to
+// eventstore.ErrLockLost.
+func (l *Lock) Renew(ctx context.Context, ttl time.Duration) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	lease, err := l.locker.Renew(ctx, l.lease, ttl)
+	if err != nil {
+		return fmt.Errorf("renew lock of %s: %w", l.lease.Aggregate, err)
+	}
+	l.lease = lease
+
+	return nil
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock(ctx context.Context) error {
+	lease := l.Lease()
+	if err := l.locker.Unlock(ctx, lease); err != nil {
+		return fmt.Errorf("unlock %s: %w", lease.Aggregate, err)
+	}
+	return nil
+}
+
+// WithLock returns a context that carries the given lock. Repositories that use
+// the CheckLocks option accept changes to the locked aggregate only if the
+// context that is passed to Save carries the lock. A context may carry the
+// locks of multiple aggregates.
+func WithLock(ctx context.Context, lock *Lock) context.Context {
+	locks, _ := ctx.Value(lockCtxKey{}).(map[aggregate.Ref]*Lock)
+
+	next := make(map[aggregate.Ref]*Lock, len(locks)+1)
+	for ref, l := range locks {
+		next[ref] = l
+	}
+	next[lock.Aggregate()] = lock
+
+	return context.WithValue(ctx, lockCtxKey{}, next)
+}
+
+// LockOf returns the lock of the given aggregate that is carried by the given
+// context, or nil if the context carries no lock of the aggregate.
+func LockOf(ctx context.Context, ref aggregate.Ref) *Lock {
+	locks, _ := ctx.Value(lockCtxKey{}).(map[aggregate.Ref]*Lock)
+	return locks[ref]
+}
+
+// checkLock returns an error if the given aggregate is locked by a lease that
+// is not carried by ctx.
+func (r *Repository) checkLock(ctx context.Context, ref aggregate.Ref) error {
+	locker, ok := r.store.(eventstore.Locker)
+	if !ok {
+		return fmt.Errorf("check lock of %s: %w [store=%T]", ref, ErrLockingUnsupported, r.store)
+	}
+
+	lease, locked, err := locker.Lease(ctx, ref)
+	if err != nil {
+		return fmt.Errorf("look up lock of %s: %w", ref, err)
+	}
+
+	held := LockOf(ctx, ref)
+
+	if held == nil {
+		if locked {
+			return fmt.Errorf("%w [aggregate=%s, expires=%v]", eventstore.ErrLocked, ref, lease.Expires)
+		}
+		return nil
+	}
+
+	if !locked || lease.Token != held.Token() {
+		return fmt.Errorf("%w [aggregate=%s, token=%d]", eventstore.ErrLockLost, ref, held.Token())
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/modernice/goes/aggregate"
+)
+
+// FetchStats provides information about the hydration of an aggregate by
+// Fetch or FetchVersion.
+type FetchStats struct {
+	// Aggregate is the fetched aggregate.
+	Aggregate aggregate.Ref
+
+	// Version is the version of the aggregate after it was fetched.
+	Version int
+
+	// Events is the number of events that were replayed onto the aggregate.
+	Events int
+
+	// Snapshot reports whether the aggregate was restored from a snapshot.
+	Snapshot bool
+
+	// SnapshotVersion is the version of the snapshot that the aggregate was
+	// restored from, if Snapshot is true.
+	SnapshotVersion int
+
+	// Duration is the time it took to fetch the aggregate.
+	Duration time.Duration
+
+	// Err is the error that was returned by the fetch, if any.
+	Err error
+}
+
+// SaveStats provides information about the saving of an aggregate by Save.
+type SaveStats struct {
+	// Aggregate is the saved aggregate.
+	Aggregate aggregate.Ref
+
+	// Events is the number of changes of the aggregate that were inserted.
+	Events int
+
+	// Snapshot reports whether a snapshot of the aggregate was taken.
+	Snapshot bool
+
+	// Duration is the time it took to save the aggregate.
+	Duration time.Duration
+
+	// Err is the error that was returned by the save, if any.
+	Err error
+}
+
+// OnFetch returns an Option that calls the provided function after every
+// Fetch and FetchVersion with information about the hydrated aggregate. Use
+// OnFetch to report metrics such as the number of replayed events, snapshot
+// usage, and hydration durations.
+func OnFetch(fn func(context.Context, FetchStats)) Option {
+	return func(r *Repository) {
+		r.onFetch = append(r.onFetch, fn)
+	}
+}
+
+// OnSave returns an Option that calls the provided function after every Save
+// with information about the saved aggregate.
+func OnSave(fn func(context.Context, SaveStats)) Option {
+	return func(r *Repository) {
+		r.onSave = append(r.onSave, fn)
+	}
+}
+
+// SlowHydration returns an Option that logs every aggregate whose Fetch or
+// FetchVersion takes at least the given threshold. Slow hydrations usually
+// indicate aggregates that should use snapshots.
+func SlowHydration(threshold time.Duration) Option {
+	return OnFetch(func(_ context.Context, stats FetchStats) {
+		if stats.Duration < threshold {
+			return
+		}
+		log.Printf(
+			"[goes/aggregate/repository.Repository] Slow hydration of %q aggregate (id=%s, version=%d): took %v (events=%d, snapshot=%t)",
+			stats.Aggregate.Name,
+			stats.Aggregate.ID,
+			stats.Version,
+			stats.Duration,
+			stats.Events,
+			stats.Snapshot,
+		)
+	})
+}
+
+func (r *Repository) reportFetch(ctx context.Context, a aggregate.Aggregate, start time.Time, stats FetchStats) {
+	stats.Duration = time.Since(start)
+	stats.Aggregate = refOf(a)
+	_, _, stats.Version = a.Aggregate()
+	for _, fn := range r.onFetch {
+		fn(ctx, stats)
+	}
+}
+
+func (r *Repository) reportSave(ctx context.Context, stats SaveStats) {
+	for _, fn := range r.onSave {
+		fn(ctx, stats)
+	}
+}
+
+func refOf(a aggregate.Aggregate) aggregate.Ref {
+	id, name, _ := a.Aggregate()
+	return aggregate.Ref{Name: name, ID: id}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/modernice/goes/aggregate"
 	"github.com/modernice/goes/aggregate/query"
@@ -46,8 +47,11 @@ type Repository struct {
 	afterInsert    []func(context.Context, aggregate.Aggregate) error
 	onFailedInsert []func(context.Context, aggregate.Aggregate, error) error
 	onDelete       []func(context.Context, aggregate.Aggregate) error
+	onFetch        []func(context.Context, FetchStats)
+	onSave         []func(context.Context, SaveStats)
 
 	validateConsistency bool
+	checkLocks          bool
 }
 
 // WithSnapshots configures the Repository to use the provided snapshot.Store
@@ -146,11 +150,40 @@ func newRepository(store event.Store, opts ...Option) *Repository {
 // consistency and calls the appropriate hooks before and after inserting
 // events. If an error occurs, it calls the OnFailedInsert hook.
 func (r *Repository) Save(ctx context.Context, a aggregate.Aggregate) error {
+	if len(r.onSave) == 0 {
+		_, err := r.save(ctx, a)
+		return err
+	}
+
+	start := time.Now()
+	stats := SaveStats{
+		Aggregate: refOf(a),
+		Events:    len(a.AggregateChanges()),
+	}
+
+	stats.Snapshot, stats.Err = r.save(ctx, a)
+	stats.Duration = time.Since(start)
+	r.reportSave(ctx, stats)
+
+	return stats.Err
+}
+
+func (r *Repository) save(ctx context.Context, a aggregate.Aggregate) (snapshotted bool, _ error) {
 	if r.validateConsistency {
 		id, name, version := a.Aggregate()
 		ref := aggregate.Ref{Name: name, ID: id}
 		if err := aggregate.ValidateConsistency(ref, version, a.AggregateChanges()); err != nil {
-			return fmt.Errorf("validate consistency: %w", err)
+			return false, fmt.Errorf("validate consistency: %w", err)
+		}
+	}
+
+	if err := aggregate.CheckInvariants(a); err != nil {
+		return false, err
+	}
+
+	if r.checkLocks {
+		if err := r.checkLock(ctx, refOf(a)); err != nil {
+			return false, err
 		}
 	}
 
@@ -161,23 +194,23 @@ func (r *Repository) Save(ctx context.Context, a aggregate.Aggregate) error {
 
 	for _, fn := range r.beforeInsert {
 		if err := fn(ctx, a); err != nil {
-			return fmt.Errorf("BeforeInsert: %w", err)
+			return false, fmt.Errorf("BeforeInsert: %w", err)
 		}
 	}
 
 	if err := r.store.Insert(ctx, a.AggregateChanges()...); err != nil {
 		for _, fn := range r.onFailedInsert {
 			if hookError := fn(ctx, a, err); hookError != nil {
-				return fmt.Errorf("OnFailedInsert (%s): %w", err, hookError)
+				return false, fmt.Errorf("OnFailedInsert (%s): %w", err, hookError)
 			}
 		}
 
-		return fmt.Errorf("insert events: %w", err)
+		return false, fmt.Errorf("insert events: %w", err)
 	}
 
 	for _, fn := range r.afterInsert {
 		if err := fn(ctx, a); err != nil {
-			return fmt.Errorf("AfterInsert: %w", err)
+			return false, fmt.Errorf("AfterInsert: %w", err)
 		}
 	}
 
@@ -187,11 +220,11 @@ func (r *Repository) Save(ctx context.Context, a aggregate.Aggregate) error {
 
 	if snap {
 		if err := r.makeSnapshot(ctx, a); err != nil {
-			return fmt.Errorf("make snapshot: %w", err)
+			return false, fmt.Errorf("make snapshot: %w", err)
 		}
 	}
 
-	return nil
+	return snap, nil
 }
 
 func (r *Repository) makeSnapshot(ctx context.Context, a aggregate.Aggregate) error {
@@ -210,21 +243,34 @@ func (r *Repository) makeSnapshot(ctx context.Context, a aggregate.Aggregate) er
 // store is configured, Fetch loads the latest snapshot and applies events that
 // occurred after the snapshot was taken.
 func (r *Repository) Fetch(ctx context.Context, a aggregate.Aggregate) error {
+	if len(r.onFetch) == 0 {
+		return r.fetchLatest(ctx, a, &FetchStats{})
+	}
+
+	start := time.Now()
+	var stats FetchStats
+	stats.Err = r.fetchLatest(ctx, a, &stats)
+	r.reportFetch(ctx, a, start, stats)
+
+	return stats.Err
+}
+
+func (r *Repository) fetchLatest(ctx context.Context, a aggregate.Aggregate, stats *FetchStats) error {
 	if _, ok := a.(snapshot.Target); ok && r.snapshots != nil {
-		return r.fetchLatestWithSnapshot(ctx, a)
+		return r.fetchLatestWithSnapshot(ctx, a, stats)
 	}
 
-	return r.fetch(ctx, a, equery.AggregateVersion(
+	return r.fetch(ctx, a, stats, equery.AggregateVersion(
 		version.Min(aggregate.UncommittedVersion(a)+1),
 	))
 }
 
-func (r *Repository) fetchLatestWithSnapshot(ctx context.Context, a aggregate.Aggregate) error {
+func (r *Repository) fetchLatestWithSnapshot(ctx context.Context, a aggregate.Aggregate, stats *FetchStats) error {
 	id, name, _ := a.Aggregate()
 
 	snap, err := r.snapshots.Latest(ctx, name, id)
 	if err != nil || snap == nil {
-		return r.fetch(ctx, a, equery.AggregateVersion(
+		return r.fetch(ctx, a, stats, equery.AggregateVersion(
 			version.Min(aggregate.UncommittedVersion(a)+1),
 		))
 	}
@@ -235,14 +281,16 @@ func (r *Repository) fetchLatestWithSnapshot(ctx context.Context, a aggregate.Ag
 		if err := snapshot.Unmarshal(snap, a); err != nil {
 			return fmt.Errorf("unmarshal snapshot: %w", err)
 		}
+		stats.Snapshot = true
+		stats.SnapshotVersion = snap.AggregateVersion()
 	}
 
-	return r.fetch(ctx, a, equery.AggregateVersion(
+	return r.fetch(ctx, a, stats, equery.AggregateVersion(
 		version.Min(aggregate.UncommittedVersion(a)+1),
 	))
 }
 
-func (r *Repository) fetch(ctx context.Context, a aggregate.Aggregate, opts ...equery.Option) error {
+func (r *Repository) fetch(ctx context.Context, a aggregate.Aggregate, stats *FetchStats, opts ...equery.Option) error {
 	id, name, _ := a.Aggregate()
 
 	opts = append([]equery.Option{
@@ -252,6 +300,7 @@ func (r *Repository) fetch(ctx context.Context, a aggregate.Aggregate, opts ...e
 	}, opts...)
 
 	events, err := r.queryEvents(ctx, equery.New(opts...))
+	stats.Events += len(events)
 	if err != nil {
 		return fmt.Errorf("query events: %w", err)
 	}
@@ -304,19 +353,32 @@ func (r *Repository) FetchVersion(ctx context.Context, a aggregate.Aggregate, v
 		v = 0
 	}
 
+	if len(r.onFetch) == 0 {
+		return r.fetchVersionOf(ctx, a, v, &FetchStats{})
+	}
+
+	start := time.Now()
+	var stats FetchStats
+	stats.Err = r.fetchVersionOf(ctx, a, v, &stats)
+	r.reportFetch(ctx, a, start, stats)
+
+	return stats.Err
+}
+
+func (r *Repository) fetchVersionOf(ctx context.Context, a aggregate.Aggregate, v int, stats *FetchStats) error {
 	if r.snapshots != nil {
-		return r.fetchVersionWithSnapshot(ctx, a, v)
+		return r.fetchVersionWithSnapshot(ctx, a, v, stats)
 	}
 
-	return r.fetchVersion(ctx, a, v)
+	return r.fetchVersion(ctx, a, v, stats)
 }
 
-func (r *Repository) fetchVersionWithSnapshot(ctx context.Context, a aggregate.Aggregate, v int) error {
+func (r *Repository) fetchVersionWithSnapshot(ctx context.Context, a aggregate.Aggregate, v int, stats *FetchStats) error {
 	id, name, _ := a.Aggregate()
 
 	snap, err := r.snapshots.Limit(ctx, name, id, v)
 	if err != nil || snap == nil {
-		return r.fetchVersion(ctx, a, v)
+		return r.fetchVersion(ctx, a, v, stats)
 	}
 
 	if a, ok := a.(snapshot.Target); !ok {
@@ -325,13 +387,15 @@ func (r *Repository) fetchVersionWithSnapshot(ctx context.Context, a aggregate.A
 		if err = snapshot.Unmarshal(snap, a); err != nil {
 			return fmt.Errorf("unmarshal snapshot: %w", err)
 		}
+		stats.Snapshot = true
+		stats.SnapshotVersion = snap.AggregateVersion()
 	}
 
-	return r.fetchVersion(ctx, a, v)
+	return r.fetchVersion(ctx, a, v, stats)
 }
 
-func (r *Repository) fetchVersion(ctx context.Context, a aggregate.Aggregate, v int) error {
-	if err := r.fetch(ctx, a, equery.AggregateVersion(
+func (r *Repository) fetchVersion(ctx context.Context, a aggregate.Aggregate, v int, stats *FetchStats) error {
+	if err := r.fetch(ctx, a, stats, equery.AggregateVersion(
 		version.Min(aggregate.UncommittedVersion(a)+1),
 		version.Max(v),
 	)); err != nil {
@@ -352,6 +416,12 @@ func (r *Repository) fetchVersion(ctx context.Context, a aggregate.Aggregate, v
 func (r *Repository) Delete(ctx context.Context, a aggregate.Aggregate) error {
 	id, name, _ := a.Aggregate()
 
+	if r.checkLocks {
+		if err := r.checkLock(ctx, aggregate.Ref{Name: name, ID: id}); err != nil {
+			return err
+		}
+	}
+
 	str, errs, err := r.store.Query(ctx, equery.New(
 		equery.AggregateName(name),
 		equery.AggregateID(id),
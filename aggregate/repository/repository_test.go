@@ -6,7 +6,10 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"log"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -821,3 +824,334 @@ func (a *mockAggregate) MarshalSnapshot() ([]byte, error) {
 func (a *mockAggregate) UnmarshalSnapshot(p []byte) error {
 	return gob.NewDecoder(bytes.NewReader(p)).Decode(&a.mockState)
 }
+
+func TestRepository_History(t *testing.T) {
+	id := uuid.New()
+	foo := test.NewFoo(id)
+	for i := 0; i < 5; i++ {
+		name := "foo"
+		if i%2 == 1 {
+			name = "bar"
+		}
+		aggregate.Next(foo, name, etest.FooEventData{A: fmt.Sprint(i)})
+	}
+	events := foo.AggregateChanges()
+
+	r := repository.New(eventstore.New())
+	if err := r.Save(context.Background(), foo); err != nil {
+		t.Fatalf("Save failed with %q", err)
+	}
+
+	ref := aggregate.Ref{Name: "foo", ID: id}
+
+	tests := []struct {
+		name     string
+		from, to int
+		opts     []repository.HistoryOption
+		want     []event.Event
+	}{
+		{name: "all", from: 0, to: 0, want: events},
+		{name: "version range", from: 2, to: 4, want: events[1:4]},
+		{name: "open end", from: 3, to: 0, want: events[2:]},
+		{name: "names", from: 0, to: 0, opts: []repository.HistoryOption{repository.HistoryNames("bar")}, want: []event.Event{events[1], events[3]}},
+		{name: "time", from: 0, to: 0, opts: []repository.HistoryOption{repository.HistorySince(events[2].Time()), repository.HistoryUntil(events[3].Time())}, want: events[2:4]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			str, errs, err := r.History(context.Background(), ref, tt.from, tt.to, tt.opts...)
+			if err != nil {
+				t.Fatalf("History failed with %q", err)
+			}
+
+			got, err := streams.Drain(context.Background(), str, errs)
+			if err != nil {
+				t.Fatalf("drain history: %v", err)
+			}
+
+			etest.AssertEqualEvents(t, tt.want, got)
+		})
+	}
+}
+
+func TestRepository_Version(t *testing.T) {
+	id := uuid.New()
+	foo := test.NewFoo(id)
+	for i := 0; i < 3; i++ {
+		aggregate.Next(foo, "foo", etest.FooEventData{})
+	}
+
+	store := eventstore.New()
+	if err := repository.New(store).Save(context.Background(), foo); err != nil {
+		t.Fatalf("Save failed with %q", err)
+	}
+
+	stores := map[string]event.Store{
+		"Versioner": store,
+		"Query":     struct{ event.Store }{store},
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			r := repository.New(store)
+
+			v, err := r.Version(context.Background(), aggregate.Ref{Name: "foo", ID: id})
+			if err != nil {
+				t.Fatalf("Version failed with %q", err)
+			}
+			if v != 3 {
+				t.Fatalf("Version should return %d; got %d", 3, v)
+			}
+
+			v, err = r.Version(context.Background(), aggregate.Ref{Name: "foo", ID: uuid.New()})
+			if err != nil {
+				t.Fatalf("Version failed with %q", err)
+			}
+			if v != 0 {
+				t.Fatalf("Version should return %d for an unknown aggregate; got %d", 0, v)
+			}
+		})
+	}
+}
+
+func TestRepository_Exists(t *testing.T) {
+	foo := test.NewFoo(uuid.New())
+	aggregate.Next(foo, "foo", etest.FooEventData{})
+
+	r := repository.New(eventstore.New())
+	if err := r.Save(context.Background(), foo); err != nil {
+		t.Fatalf("Save failed with %q", err)
+	}
+
+	if ok, err := r.Exists(context.Background(), aggregate.Ref{Name: "foo", ID: foo.AggregateID()}); err != nil {
+		t.Fatalf("Exists failed with %q", err)
+	} else if !ok {
+		t.Fatalf("Exists should return true for a saved aggregate")
+	}
+
+	if ok, err := r.Exists(context.Background(), aggregate.Ref{Name: "bar", ID: foo.AggregateID()}); err != nil {
+		t.Fatalf("Exists failed with %q", err)
+	} else if ok {
+		t.Fatalf("Exists should return false for an unknown aggregate")
+	}
+}
+
+func TestOnFetch(t *testing.T) {
+	ctx := context.Background()
+
+	var stats []repository.FetchStats
+	r := repository.New(
+		eventstore.New(),
+		repository.WithSnapshots(snapshot.NewStore(), snapshot.Every(3)),
+		repository.OnFetch(func(_ context.Context, s repository.FetchStats) {
+			stats = append(stats, s)
+		}),
+	)
+
+	foo := &mockAggregate{Base: aggregate.New("foo", uuid.New())}
+	for _, n := range []int{3, 2} {
+		for i := 0; i < n; i++ {
+			aggregate.Next(foo, "foo", etest.FooEventData{})
+		}
+		if err := r.Save(ctx, foo); err != nil {
+			t.Fatalf("Save failed with %q", err)
+		}
+	}
+
+	fetched := &mockAggregate{Base: aggregate.New("foo", foo.AggregateID())}
+	if err := r.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch failed with %q", err)
+	}
+
+	if len(stats) != 1 {
+		t.Fatalf("OnFetch hook should be called %d time; got %d", 1, len(stats))
+	}
+
+	s := stats[0]
+	if s.Aggregate != fetched.Ref() {
+		t.Errorf("FetchStats has wrong Aggregate. want=%v got=%v", fetched.Ref(), s.Aggregate)
+	}
+	if s.Version != 5 {
+		t.Errorf("FetchStats has wrong Version. want=%d got=%d", 5, s.Version)
+	}
+	if !s.Snapshot || s.SnapshotVersion != 3 {
+		t.Errorf("FetchStats should report snapshot of version %d; got snapshot=%t version=%d", 3, s.Snapshot, s.SnapshotVersion)
+	}
+	if s.Events != 2 {
+		t.Errorf("FetchStats has wrong number of replayed events. want=%d got=%d", 2, s.Events)
+	}
+	if s.Duration <= 0 {
+		t.Errorf("FetchStats should have a Duration")
+	}
+
+	if err := r.FetchVersion(ctx, &mockAggregate{Base: aggregate.New("foo", foo.AggregateID())}, 2); err != nil {
+		t.Fatalf("FetchVersion failed with %q", err)
+	}
+
+	if len(stats) != 2 {
+		t.Fatalf("OnFetch hook should be called %d times; got %d", 2, len(stats))
+	}
+
+	if s := stats[1]; s.Snapshot || s.Events != 2 || s.Version != 2 {
+		t.Errorf("FetchStats of FetchVersion are wrong: %+v", s)
+	}
+}
+
+func TestOnSave(t *testing.T) {
+	ctx := context.Background()
+
+	var stats []repository.SaveStats
+	r := repository.New(
+		eventstore.New(),
+		repository.WithSnapshots(snapshot.NewStore(), snapshot.Every(3)),
+		repository.OnSave(func(_ context.Context, s repository.SaveStats) {
+			stats = append(stats, s)
+		}),
+	)
+
+	foo := &mockAggregate{Base: aggregate.New("foo", uuid.New())}
+	for i := 0; i < 3; i++ {
+		aggregate.Next(foo, "foo", etest.FooEventData{})
+	}
+
+	if err := r.Save(ctx, foo); err != nil {
+		t.Fatalf("Save failed with %q", err)
+	}
+
+	if len(stats) != 1 {
+		t.Fatalf("OnSave hook should be called %d time; got %d", 1, len(stats))
+	}
+
+	if s := stats[0]; s.Aggregate != foo.Ref() || s.Events != 3 || !s.Snapshot || s.Err != nil {
+		t.Errorf("SaveStats are wrong: %+v", s)
+	}
+}
+
+func TestSlowHydration(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	r := repository.New(eventstore.New(), repository.SlowHydration(time.Hour))
+	foo := test.NewFoo(uuid.New())
+
+	if err := r.Fetch(context.Background(), foo); err != nil {
+		t.Fatalf("Fetch failed with %q", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("fast hydrations should not be logged; got %q", buf.String())
+	}
+
+	r = repository.New(eventstore.New(), repository.SlowHydration(0))
+	if err := r.Fetch(context.Background(), foo); err != nil {
+		t.Fatalf("Fetch failed with %q", err)
+	}
+
+	if !strings.Contains(buf.String(), foo.AggregateID().String()) {
+		t.Fatalf("slow hydration should be logged with the aggregate id; got %q", buf.String())
+	}
+}
+
+func TestRepository_Lock(t *testing.T) {
+	ctx := context.Background()
+	r := repository.New(eventstore.New(), repository.CheckLocks())
+
+	foo := test.NewFoo(uuid.New())
+	ref := aggregate.Ref{Name: "foo", ID: foo.AggregateID()}
+
+	lock, err := r.Lock(ctx, ref, time.Minute)
+	if err != nil {
+		t.Fatalf("Lock failed with %q", err)
+	}
+
+	if _, err := r.Lock(ctx, ref, time.Minute); !errors.Is(err, eventstore.ErrLocked) {
+		t.Fatalf("Lock should fail with %q for a locked aggregate; got %q", eventstore.ErrLocked, err)
+	}
+
+	aggregate.Next(foo, "foo", etest.FooEventData{})
+	if err := r.Save(ctx, foo); !errors.Is(err, eventstore.ErrLocked) {
+		t.Fatalf("Save should fail with %q without the lock; got %q", eventstore.ErrLocked, err)
+	}
+
+	if err := r.Save(repository.WithLock(ctx, lock), foo); err != nil {
+		t.Fatalf("Save should succeed with the lock; got %q", err)
+	}
+
+	if err := lock.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock failed with %q", err)
+	}
+
+	if err := lock.Renew(ctx, time.Minute); !errors.Is(err, eventstore.ErrLockLost) {
+		t.Fatalf("Renew should fail with %q after Unlock; got %q", eventstore.ErrLockLost, err)
+	}
+
+	next, err := r.Lock(ctx, ref, time.Minute)
+	if err != nil {
+		t.Fatalf("Lock failed with %q", err)
+	}
+
+	if next.Token() <= lock.Token() {
+		t.Fatalf("lock token should increase; was %d, is %d", lock.Token(), next.Token())
+	}
+
+	aggregate.Next(foo, "foo", etest.FooEventData{})
+	if err := r.Save(repository.WithLock(ctx, lock), foo); !errors.Is(err, eventstore.ErrLockLost) {
+		t.Fatalf("Save should fail with %q with a stale lock; got %q", eventstore.ErrLockLost, err)
+	}
+}
+
+func TestRepository_Lock_decoratedStore(t *testing.T) {
+	ctx := context.Background()
+
+	store := eventstore.WithRetry(eventstore.New(), func(error) bool { return false })
+	r := repository.New(store, repository.CheckLocks())
+
+	foo := test.NewFoo(uuid.New())
+	if _, err := r.Lock(ctx, aggregate.Ref{Name: "foo", ID: foo.AggregateID()}, time.Minute); err != nil {
+		t.Fatalf("Lock failed with %q", err)
+	}
+
+	aggregate.Next(foo, "foo", etest.FooEventData{})
+	if err := r.Save(ctx, foo); !errors.Is(err, eventstore.ErrLocked) {
+		t.Fatalf("Save should fail with %q without the lock; got %q", eventstore.ErrLocked, err)
+	}
+}
+
+func TestCheckLocks_unsupported(t *testing.T) {
+	ctx := context.Background()
+
+	store := eventstore.WithRetry(struct{ event.Store }{eventstore.New()}, func(error) bool { return false })
+	r := repository.New(store, repository.CheckLocks())
+
+	foo := test.NewFoo(uuid.New())
+	if _, err := r.Lock(ctx, aggregate.Ref{Name: "foo", ID: foo.AggregateID()}, time.Minute); !errors.Is(err, repository.ErrLockingUnsupported) {
+		t.Fatalf("Lock should fail with %q; got %q", repository.ErrLockingUnsupported, err)
+	}
+
+	aggregate.Next(foo, "foo", etest.FooEventData{})
+	if err := r.Save(ctx, foo); !errors.Is(err, repository.ErrLockingUnsupported) {
+		t.Fatalf("Save should fail with %q; got %q", repository.ErrLockingUnsupported, err)
+	}
+}
+
+func TestRepository_Lock_expiry(t *testing.T) {
+	ctx := context.Background()
+	r := repository.New(eventstore.New())
+	ref := aggregate.Ref{Name: "foo", ID: uuid.New()}
+
+	lock, err := r.Lock(ctx, ref, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Lock failed with %q", err)
+	}
+
+	if err := lock.Renew(ctx, 20*time.Millisecond); err != nil {
+		t.Fatalf("Renew failed with %q", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, err := r.Lock(ctx, ref, time.Minute); err != nil {
+		t.Fatalf("Lock should succeed after the previous lock expired; got %q", err)
+	}
+}
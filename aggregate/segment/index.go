@@ -0,0 +1,78 @@
+package segment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/aggregate"
+)
+
+// Segment is a sealed segment of the event stream of an aggregate. The events
+// of a sealed segment are stored in the history store, and the state of the
+// aggregate at the end of the segment is anchored by a snapshot.
+type Segment struct {
+	// Aggregate is the aggregate of the segment.
+	Aggregate aggregate.Ref
+
+	// Epoch is the number of the segment, starting at 1.
+	Epoch int
+
+	// From is the version of the first event of the segment.
+	From int
+
+	// To is the version of the last event of the segment. The snapshot of the
+	// aggregate at this version anchors the next segment and must not be
+	// deleted.
+	To int
+
+	// Time is the time at which the segment was sealed.
+	Time time.Time
+}
+
+// Index keeps track of the sealed segments of aggregates.
+type Index interface {
+	// Save saves a sealed segment.
+	Save(context.Context, Segment) error
+
+	// Segments returns the sealed segments of the given aggregate, sorted by
+	// epoch.
+	Segments(context.Context, aggregate.Ref) ([]Segment, error)
+}
+
+type memoryIndex struct {
+	mux      sync.RWMutex
+	segments map[aggregate.Ref][]Segment
+}
+
+// NewMemoryIndex returns an in-memory Index.
+func NewMemoryIndex() Index {
+	return &memoryIndex{segments: make(map[aggregate.Ref][]Segment)}
+}
+
+func (idx *memoryIndex) Save(_ context.Context, seg Segment) error {
+	idx.mux.Lock()
+	defer idx.mux.Unlock()
+
+	segments := idx.segments[seg.Aggregate]
+	for i, s := range segments {
+		if s.Epoch == seg.Epoch {
+			segments[i] = seg
+			return nil
+		}
+	}
+	idx.segments[seg.Aggregate] = append(segments, seg)
+
+	return nil
+}
+
+func (idx *memoryIndex) Segments(_ context.Context, ref aggregate.Ref) ([]Segment, error) {
+	idx.mux.RLock()
+	defer idx.mux.RUnlock()
+
+	segments := idx.segments[ref]
+	out := make([]Segment, len(segments))
+	copy(out, segments)
+
+	return out, nil
+}
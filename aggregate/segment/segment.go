@@ -0,0 +1,426 @@
+// Package segment splits the event streams of aggregates with unbounded
+// histories into segments.
+//
+// Aggregates that live forever, like accounts or devices, accumulate events
+// that are only needed for audits, but that still bloat the primary event
+// store and its indexes. Sealing the event stream of such an aggregate takes a
+// snapshot of its current version, which anchors the next segment of the
+// stream, and moves the events of the sealed segment into a secondary
+// ("history") event store:
+//
+//	store := segment.New(hot, history, snapshots, segment.NewMemoryIndex(), func(ref aggregate.Ref) aggregate.Aggregate {
+//		return NewAccount(ref.ID)
+//	}, segment.Size(1000))
+//
+// The Store is an event.Store that must be used in place of the hot store. With
+// the Size option, a segment is sealed automatically whenever the current
+// segment of an aggregate reaches the given number of events. Segments can also
+// be sealed manually using Seal.
+//
+// A repository that uses the Store and the snapshot store fetches the current
+// state of an aggregate from the anchoring snapshot and the events of the
+// current segment, without touching the history store. Queries that may
+// return events of sealed segments, like queries for the full history of an
+// aggregate, or queries that do not filter by aggregate, are run against both
+// stores and their results are merged, so that the full history remains
+// reachable for audits and projections.
+package segment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// Store is an event store that seals the event streams of aggregates into
+// segments, and moves the events of sealed segments into a history store.
+type Store struct {
+	event.Store
+
+	history   event.Store
+	merged    *eventstore.MergedStore
+	snapshots snapshot.Store
+	index     Index
+	newFunc   func(aggregate.Ref) aggregate.Aggregate
+	size      int
+	onSeal    []func(Segment, error)
+
+	sealMux sync.Mutex
+}
+
+// Option is an option for a Store.
+type Option func(*Store)
+
+// Size returns an Option that seals the current segment of an aggregate when
+// it reaches the given number of events. The segment is sealed after the
+// events that complete the segment have been inserted. Sealing errors do not
+// fail the insert; use OnSeal to get notified about them.
+func Size(events int) Option {
+	return func(s *Store) {
+		s.size = events
+	}
+}
+
+// OnSeal returns an Option that calls fn after each automatic sealing of a
+// segment (see Size), with the error of the sealing, if any.
+func OnSeal(fn func(Segment, error)) Option {
+	return func(s *Store) {
+		s.onSeal = append(s.onSeal, fn)
+	}
+}
+
+// New returns a Store that moves the sealed segments of the aggregates in the
+// hot store into the history store. The provided function must return a new,
+// empty instance of the given aggregate, which is used to take the anchoring
+// snapshots of the segments. The aggregates must therefore implement
+// snapshot.Marshaler and snapshot.Unmarshaler (or the encoding equivalents).
+func New(hot, history event.Store, snapshots snapshot.Store, index Index, newFunc func(aggregate.Ref) aggregate.Aggregate, opts ...Option) *Store {
+	if newFunc == nil {
+		panic("[goes/aggregate/segment.New] aggregate factory is nil")
+	}
+	s := &Store{
+		Store:     hot,
+		history:   history,
+		merged:    eventstore.Merge(hot, history),
+		snapshots: snapshots,
+		index:     index,
+		newFunc:   newFunc,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// History returns the history event store.
+func (s *Store) History() event.Store {
+	return s.history
+}
+
+// Segments returns the sealed segments of the given aggregate.
+func (s *Store) Segments(ctx context.Context, ref aggregate.Ref) ([]Segment, error) {
+	return s.index.Segments(ctx, ref)
+}
+
+// Seal seals the current segments of the given aggregates. Aggregates without
+// events in their current segment are skipped. Seal stops at the first error
+// and returns the segments that have been sealed so far. Sealing is
+// idempotent, so a failed Seal can be retried.
+func (s *Store) Seal(ctx context.Context, aggregates ...aggregate.Ref) ([]Segment, error) {
+	segments := make([]Segment, 0, len(aggregates))
+	for _, ref := range aggregates {
+		seg, err := s.seal(ctx, ref)
+		if err != nil {
+			return segments, fmt.Errorf("seal %s: %w", ref, err)
+		}
+		if seg.Epoch > 0 {
+			segments = append(segments, seg)
+		}
+	}
+	return segments, nil
+}
+
+// Insert inserts the given events into the hot store. If the Size option is
+// used, the current segments of the aggregates of the events are sealed when
+// they reach the configured size.
+func (s *Store) Insert(ctx context.Context, events ...event.Event) error {
+	if err := s.Store.Insert(ctx, events...); err != nil {
+		return err
+	}
+
+	if s.size <= 0 {
+		return nil
+	}
+
+	latest := make(map[aggregate.Ref]int)
+	var refs []aggregate.Ref
+	for _, evt := range events {
+		id, name, v := evt.Aggregate()
+		if id == uuid.Nil {
+			continue
+		}
+		ref := aggregate.Ref{Name: name, ID: id}
+		if _, ok := latest[ref]; !ok {
+			refs = append(refs, ref)
+		}
+		if v > latest[ref] {
+			latest[ref] = v
+		}
+	}
+
+	for _, ref := range refs {
+		s.sealIfFull(ctx, ref, latest[ref])
+	}
+
+	return nil
+}
+
+// Find returns the event with the given id from the hot store, or from the
+// history store if the event belongs to a sealed segment.
+func (s *Store) Find(ctx context.Context, id uuid.UUID) (event.Event, error) {
+	return s.merged.Find(ctx, id)
+}
+
+// Query queries the events of the current segments from the hot store. If the
+// query may return events of sealed segments, the query is run against both
+// the hot store and the history store, and the results are merged.
+func (s *Store) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	current, err := s.currentOnly(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if current {
+		return s.Store.Query(ctx, q)
+	}
+
+	return s.merged.Query(ctx, q)
+}
+
+func (s *Store) sealIfFull(ctx context.Context, ref aggregate.Ref, latest int) {
+	segments, err := s.index.Segments(ctx, ref)
+	if err != nil {
+		s.sealed(Segment{Aggregate: ref}, fmt.Errorf("get segments: %w", err))
+		return
+	}
+
+	var sealed int
+	if len(segments) > 0 {
+		sealed = segments[len(segments)-1].To
+	}
+
+	if latest-sealed < s.size {
+		return
+	}
+
+	seg, err := s.seal(ctx, ref)
+	if seg.Epoch == 0 {
+		seg.Aggregate = ref
+	}
+	s.sealed(seg, err)
+}
+
+func (s *Store) sealed(seg Segment, err error) {
+	for _, fn := range s.onSeal {
+		fn(seg, err)
+	}
+}
+
+func (s *Store) seal(ctx context.Context, ref aggregate.Ref) (Segment, error) {
+	s.sealMux.Lock()
+	defer s.sealMux.Unlock()
+
+	segments, err := s.index.Segments(ctx, ref)
+	if err != nil {
+		return Segment{}, fmt.Errorf("get segments: %w", err)
+	}
+
+	var prev Segment
+	if len(segments) > 0 {
+		prev = segments[len(segments)-1]
+	}
+
+	events, err := aggregateEvents(ctx, s.Store, ref, prev.To)
+	if err != nil {
+		return Segment{}, fmt.Errorf("query hot events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return Segment{}, nil
+	}
+
+	a := s.newFunc(ref)
+	if prev.To > 0 {
+		if err := s.restoreAnchor(ctx, a, prev); err != nil {
+			return Segment{}, fmt.Errorf("restore anchor of epoch %d: %w", prev.Epoch, err)
+		}
+	}
+
+	if err := aggregate.ApplyHistory(a, events); err != nil {
+		return Segment{}, fmt.Errorf("apply history: %w", err)
+	}
+
+	seg := Segment{
+		Aggregate: ref,
+		Epoch:     prev.Epoch + 1,
+		From:      prev.To + 1,
+		To:        aggregate.UncommittedVersion(a),
+		Time:      time.Now(),
+	}
+
+	snap, err := snapshot.New(a)
+	if err != nil {
+		return seg, fmt.Errorf("take snapshot: %w", err)
+	}
+
+	if err := s.snapshots.Save(ctx, snap); err != nil {
+		return seg, fmt.Errorf("save snapshot: %w", err)
+	}
+
+	if err := s.copy(ctx, ref, events); err != nil {
+		return seg, fmt.Errorf("insert history events: %w", err)
+	}
+
+	if err := s.index.Save(ctx, seg); err != nil {
+		return seg, fmt.Errorf("save segment: %w", err)
+	}
+
+	if err := s.Store.Delete(ctx, events...); err != nil {
+		return seg, fmt.Errorf("delete hot events: %w", err)
+	}
+
+	return seg, nil
+}
+
+// restoreAnchor restores the state of the aggregate at the end of the given
+// segment from its anchoring snapshot. If the snapshot does not exist, the
+// events of the sealed segments are applied instead.
+func (s *Store) restoreAnchor(ctx context.Context, a aggregate.Aggregate, seg Segment) error {
+	if target, ok := a.(snapshot.Target); ok {
+		if snap, err := s.snapshots.Version(ctx, seg.Aggregate.Name, seg.Aggregate.ID, seg.To); err == nil && snap != nil {
+			return snapshot.Unmarshal(snap, target)
+		}
+	}
+
+	events, err := aggregateEvents(ctx, s.history, seg.Aggregate, 0)
+	if err != nil {
+		return fmt.Errorf("query history events: %w", err)
+	}
+
+	return aggregate.ApplyHistory(a, events)
+}
+
+// copy inserts the events into the history store, skipping the events that the
+// history store already contains from a previous, interrupted sealing.
+func (s *Store) copy(ctx context.Context, ref aggregate.Ref, events []event.Event) error {
+	existing, err := aggregateEvents(ctx, s.history, ref, pick.AggregateVersion(events[0])-1)
+	if err != nil {
+		return err
+	}
+
+	copied := make(map[int]bool, len(existing))
+	for _, evt := range existing {
+		copied[pick.AggregateVersion(evt)] = true
+	}
+
+	missing := make([]event.Event, 0, len(events))
+	for _, evt := range events {
+		if !copied[pick.AggregateVersion(evt)] {
+			missing = append(missing, evt)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return s.history.Insert(ctx, missing...)
+}
+
+// currentOnly reports whether the query only returns events of the current
+// segments of specific aggregates.
+func (s *Store) currentOnly(ctx context.Context, q event.Query) (bool, error) {
+	refs, ok := targets(q)
+	if !ok {
+		return false, nil
+	}
+
+	for _, ref := range refs {
+		segments, err := s.index.Segments(ctx, ref)
+		if err != nil {
+			return false, fmt.Errorf("get segments of %s: %w", ref, err)
+		}
+
+		if len(segments) > 0 && includesSealed(q.AggregateVersions(), segments[len(segments)-1].To) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func aggregateEvents(ctx context.Context, store event.Store, ref aggregate.Ref, after int) ([]event.Event, error) {
+	str, errs, err := store.Query(ctx, query.New(
+		query.Aggregate(ref.Name, ref.ID),
+		query.AggregateVersion(version.Min(after+1)),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return streams.Drain(ctx, str, errs)
+}
+
+// targets returns the aggregates that are filtered by the given query. If the
+// query does not filter by specific aggregates, targets returns false.
+func targets(q event.Query) ([]aggregate.Ref, bool) {
+	var refs []aggregate.Ref
+	for _, ref := range q.Aggregates() {
+		if ref.ID == uuid.Nil {
+			return nil, false
+		}
+		refs = append(refs, aggregate.Ref{Name: ref.Name, ID: ref.ID})
+	}
+
+	if ids := q.AggregateIDs(); len(ids) > 0 {
+		names := q.AggregateNames()
+		if len(names) == 0 {
+			return nil, false
+		}
+		for _, id := range ids {
+			for _, name := range names {
+				refs = append(refs, aggregate.Ref{Name: name, ID: id})
+			}
+		}
+	}
+
+	return refs, len(refs) > 0
+}
+
+// includesSealed reports whether the version constraints may include any of
+// the sealed versions 1 to v. The check is conservative: it only returns false
+// if one of the constraints excludes all sealed versions.
+func includesSealed(versions version.Constraints, v int) bool {
+	if versions == nil {
+		return true
+	}
+
+	if exact := versions.Exact(); len(exact) > 0 && !anyOf(exact, func(e int) bool { return e <= v }) {
+		return false
+	}
+
+	if min := versions.Min(); len(min) > 0 && !anyOf(min, func(m int) bool { return m <= v }) {
+		return false
+	}
+
+	if max := versions.Max(); len(max) > 0 && !anyOf(max, func(m int) bool { return m >= 1 }) {
+		return false
+	}
+
+	if ranges := versions.Ranges(); len(ranges) > 0 && !anyOf(ranges, func(r version.Range) bool { return r.Start() <= v && r.End() >= 1 }) {
+		return false
+	}
+
+	return true
+}
+
+func anyOf[T any](values []T, fn func(T) bool) bool {
+	for _, v := range values {
+		if fn(v) {
+			return true
+		}
+	}
+	return false
+}
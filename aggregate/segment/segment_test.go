@@ -0,0 +1,325 @@
+package segment_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/aggregate/segment"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+type mockAggregate struct {
+	*aggregate.Base
+	mockState
+}
+
+type mockState struct {
+	Count int
+}
+
+func newMockAggregate(id uuid.UUID) *mockAggregate {
+	a := &mockAggregate{Base: aggregate.New("foo", id)}
+	event.ApplyWith(a, func(event.Of[int]) { a.Count++ }, "counted")
+	return a
+}
+
+func newAggregate(ref aggregate.Ref) aggregate.Aggregate {
+	return newMockAggregate(ref.ID)
+}
+
+// queryCountingStore counts the queries that are run against an event store.
+type queryCountingStore struct {
+	event.Store
+	queries int
+}
+
+func (s *queryCountingStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	s.queries++
+	return s.Store.Query(ctx, q)
+}
+
+func TestStore_Seal(t *testing.T) {
+	ctx := context.Background()
+	hot, history := eventstore.New(), eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, hot, 5)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	store := segment.New(hot, history, snapshots, segment.NewMemoryIndex(), newAggregate)
+
+	segments, err := store.Seal(ctx, ref)
+	if err != nil {
+		t.Fatalf("Seal() failed with %q", err)
+	}
+
+	if len(segments) != 1 || segments[0].Epoch != 1 || segments[0].From != 1 || segments[0].To != 5 {
+		t.Fatalf("unexpected segments: %+v", segments)
+	}
+
+	if versions := queryVersions(t, hot, a.ID); len(versions) != 0 {
+		t.Fatalf("hot store should have no events; has %v", versions)
+	}
+
+	if versions := queryVersions(t, history, a.ID); fmt.Sprint(versions) != fmt.Sprint([]int{1, 2, 3, 4, 5}) {
+		t.Fatalf("history store should have all events; has %v", versions)
+	}
+
+	snap, err := snapshots.Version(ctx, "foo", a.ID, 5)
+	if err != nil {
+		t.Fatalf("anchoring snapshot should exist: %v", err)
+	}
+
+	var state mockState
+	if err := gob.NewDecoder(bytes.NewReader(snap.State())).Decode(&state); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+
+	if state.Count != 5 {
+		t.Fatalf("anchoring snapshot should have Count %d; has %d", 5, state.Count)
+	}
+
+	if segments, err := store.Seal(ctx, ref); err != nil || len(segments) != 0 {
+		t.Fatalf("sealing an empty segment should be a no-op; got %+v, %v", segments, err)
+	}
+}
+
+func TestStore_Seal_nextEpoch(t *testing.T) {
+	ctx := context.Background()
+	hot, history := eventstore.New(), eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, hot, 3)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	store := segment.New(hot, history, snapshots, segment.NewMemoryIndex(), newAggregate)
+
+	if _, err := store.Seal(ctx, ref); err != nil {
+		t.Fatalf("Seal() failed with %q", err)
+	}
+
+	repo := repository.New(store, repository.WithSnapshots(snapshots, nil))
+	fetched := newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	aggregate.Next(fetched, "counted", 4)
+	aggregate.Next(fetched, "counted", 5)
+	if err := repo.Save(ctx, fetched); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	segments, err := store.Seal(ctx, ref)
+	if err != nil {
+		t.Fatalf("Seal() failed with %q", err)
+	}
+
+	if len(segments) != 1 || segments[0].Epoch != 2 || segments[0].From != 4 || segments[0].To != 5 {
+		t.Fatalf("unexpected segments: %+v", segments)
+	}
+
+	snap, err := snapshots.Version(ctx, "foo", a.ID, 5)
+	if err != nil {
+		t.Fatalf("anchoring snapshot should exist: %v", err)
+	}
+
+	var state mockState
+	if err := gob.NewDecoder(bytes.NewReader(snap.State())).Decode(&state); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+
+	if state.Count != 5 {
+		t.Fatalf("anchoring snapshot should build on the previous anchor; Count=%d", state.Count)
+	}
+
+	all, err := store.Segments(ctx, ref)
+	if err != nil {
+		t.Fatalf("Segments() failed with %q", err)
+	}
+
+	if len(all) != 2 {
+		t.Fatalf("aggregate should have %d segments; has %d", 2, len(all))
+	}
+}
+
+func TestStore_Query_currentSegment(t *testing.T) {
+	ctx := context.Background()
+	hot := eventstore.New()
+	history := &queryCountingStore{Store: eventstore.New()}
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, hot, 5)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	store := segment.New(hot, history, snapshots, segment.NewMemoryIndex(), newAggregate)
+
+	if _, err := store.Seal(ctx, ref); err != nil {
+		t.Fatalf("Seal() failed with %q", err)
+	}
+
+	repo := repository.New(store, repository.WithSnapshots(snapshots, nil))
+	fetched := newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	aggregate.Next(fetched, "counted", 6)
+	if err := repo.Save(ctx, fetched); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	history.queries = 0
+
+	fetched = newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if fetched.Count != 6 || fetched.AggregateVersion() != 6 {
+		t.Fatalf("aggregate should be fetched from the anchor and the current segment; Count=%d Version=%d", fetched.Count, fetched.AggregateVersion())
+	}
+
+	if history.queries != 0 {
+		t.Fatalf("fetching the current state should not query the history store; queried %d times", history.queries)
+	}
+}
+
+func TestStore_Query_fullHistory(t *testing.T) {
+	ctx := context.Background()
+	hot, history := eventstore.New(), eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, hot, 3)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	store := segment.New(hot, history, snapshots, segment.NewMemoryIndex(), newAggregate)
+
+	if _, err := store.Seal(ctx, ref); err != nil {
+		t.Fatalf("Seal() failed with %q", err)
+	}
+
+	aggregate.Next(a, "counted", 4)
+	if err := store.Insert(ctx, a.AggregateChanges()[3:]...); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	if versions := queryVersions(t, store, a.ID); fmt.Sprint(versions) != fmt.Sprint([]int{1, 2, 3, 4}) {
+		t.Fatalf("query should return the full history; got %v", versions)
+	}
+
+	repo := repository.New(store)
+	fetched := newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if fetched.Count != 4 {
+		t.Fatalf("Count should be %d; is %d", 4, fetched.Count)
+	}
+
+	evt, err := store.Find(ctx, a.AggregateChanges()[0].ID())
+	if err != nil {
+		t.Fatalf("Find() should find sealed events; failed with %q", err)
+	}
+
+	if evt.ID() != a.AggregateChanges()[0].ID() {
+		t.Fatalf("Find() returned the wrong event")
+	}
+}
+
+func TestSize(t *testing.T) {
+	ctx := context.Background()
+	hot, history := eventstore.New(), eventstore.New()
+
+	var sealed []segment.Segment
+	store := segment.New(hot, history, snapshot.NewStore(), segment.NewMemoryIndex(), newAggregate, segment.Size(3), segment.OnSeal(func(seg segment.Segment, err error) {
+		if err != nil {
+			t.Errorf("seal failed: %v", err)
+		}
+		sealed = append(sealed, seg)
+	}))
+
+	a := newMockAggregate(uuid.New())
+	for i := 0; i < 7; i++ {
+		aggregate.Next(a, "counted", i)
+		if err := store.Insert(ctx, a.AggregateChanges()[i]); err != nil {
+			t.Fatalf("Insert() failed with %q", err)
+		}
+	}
+
+	if len(sealed) != 2 {
+		t.Fatalf("%d segments should have been sealed; got %d", 2, len(sealed))
+	}
+
+	if sealed[0].To != 3 || sealed[1].From != 4 || sealed[1].To != 6 {
+		t.Fatalf("unexpected segments: %+v", sealed)
+	}
+
+	if versions := queryVersions(t, hot, a.ID); fmt.Sprint(versions) != fmt.Sprint([]int{7}) {
+		t.Fatalf("hot store should only have the events of the current segment; has %v", versions)
+	}
+
+	if versions := queryVersions(t, history, a.ID); fmt.Sprint(versions) != fmt.Sprint([]int{1, 2, 3, 4, 5, 6}) {
+		t.Fatalf("history store should have the events of the sealed segments; has %v", versions)
+	}
+}
+
+func setup(t *testing.T, events event.Store, n int) *mockAggregate {
+	a := newMockAggregate(uuid.New())
+	for i := 0; i < n; i++ {
+		aggregate.Next(a, "counted", i)
+	}
+
+	if err := events.Insert(context.Background(), a.AggregateChanges()...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	return a
+}
+
+func queryVersions(t *testing.T, store event.Store, id uuid.UUID) []int {
+	str, errs, err := store.Query(context.Background(), query.New(
+		query.Aggregate("foo", id),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	versions := make([]int, len(events))
+	for i, evt := range events {
+		_, _, versions[i] = evt.Aggregate()
+	}
+	return versions
+}
+
+func (a *mockAggregate) MarshalSnapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a.mockState); err != nil {
+		return nil, fmt.Errorf("gob: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *mockAggregate) UnmarshalSnapshot(p []byte) error {
+	if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&a.mockState); err != nil {
+		return fmt.Errorf("gob: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCompressMinSize is the default minimum size of a snapshot state in
+// bytes for the state to be compressed.
+const DefaultCompressMinSize = 1024
+
+// compressedPrefix marks the state of a snapshot as compressed. States that do
+// not start with the prefix are returned as-is, so that compression can be
+// enabled for stores that already contain uncompressed snapshots.
+var compressedPrefix = []byte("\x00goes:gzip\x00")
+
+// CompressOption is an option for Compress.
+type CompressOption func(*compressedStore)
+
+type compressedStore struct {
+	Store
+
+	level   int
+	minSize int
+}
+
+// CompressionLevel returns a CompressOption that sets the gzip compression
+// level. Defaults to gzip.DefaultCompression.
+func CompressionLevel(level int) CompressOption {
+	return func(s *compressedStore) {
+		s.level = level
+	}
+}
+
+// CompressMinSize returns a CompressOption that sets the minimum size of a
+// snapshot state in bytes for the state to be compressed. Smaller states are
+// saved uncompressed. Defaults to DefaultCompressMinSize.
+func CompressMinSize(size int) CompressOption {
+	return func(s *compressedStore) {
+		s.minSize = size
+	}
+}
+
+// Compress returns a Store that transparently compresses the states of
+// snapshots before saving them into the provided Store, and decompresses them
+// when they are fetched. Snapshots that were saved without compression are
+// returned as-is, so compression can be enabled for existing stores.
+//
+// To use compression together with lazy loading, wrap the compressed store:
+//
+//	store := snapshot.Lazy(snapshot.Compress(mongo.NewSnapshotStore()))
+func Compress(store Store, opts ...CompressOption) Store {
+	s := &compressedStore{
+		Store:   store,
+		level:   gzip.DefaultCompression,
+		minSize: DefaultCompressMinSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *compressedStore) Save(ctx context.Context, snap Snapshot) error {
+	state := snap.State()
+	if len(state) < s.minSize || bytes.HasPrefix(state, compressedPrefix) {
+		return s.Store.Save(ctx, snap)
+	}
+
+	compressed, err := compress(state, s.level)
+	if err != nil {
+		return fmt.Errorf("compress snapshot: %w", err)
+	}
+
+	return s.Store.Save(ctx, withState(snap, compressed))
+}
+
+func (s *compressedStore) Latest(ctx context.Context, name string, id uuid.UUID) (Snapshot, error) {
+	return decompressed(s.Store.Latest(ctx, name, id))
+}
+
+func (s *compressedStore) Version(ctx context.Context, name string, id uuid.UUID, v int) (Snapshot, error) {
+	return decompressed(s.Store.Version(ctx, name, id, v))
+}
+
+func (s *compressedStore) Limit(ctx context.Context, name string, id uuid.UUID, v int) (Snapshot, error) {
+	return decompressed(s.Store.Limit(ctx, name, id, v))
+}
+
+func (s *compressedStore) Query(ctx context.Context, q Query) (<-chan Snapshot, <-chan error, error) {
+	snaps, errs, err := s.Store.Query(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, outErrs := make(chan Snapshot), make(chan error)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		for snaps != nil || errs != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case outErrs <- err:
+				}
+			case snap, ok := <-snaps:
+				if !ok {
+					snaps = nil
+					break
+				}
+
+				var err error
+				if snap, err = decompressed(snap, nil); err != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case outErrs <- err:
+					}
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case out <- snap:
+				}
+			}
+		}
+	}()
+
+	return out, outErrs, nil
+}
+
+// QueryMetadata implements MetadataQuerier.
+func (s *compressedStore) QueryMetadata(ctx context.Context, q Query) (<-chan Snapshot, <-chan error, error) {
+	return QueryMetadata(ctx, s.Store, q)
+}
+
+func decompressed(snap Snapshot, err error) (Snapshot, error) {
+	if err != nil {
+		return snap, err
+	}
+
+	state := snap.State()
+	if !bytes.HasPrefix(state, compressedPrefix) {
+		return snap, nil
+	}
+
+	decompressed, err := decompress(state)
+	if err != nil {
+		return nil, fmt.Errorf("decompress snapshot of %s(%s) at version %d: %w", snap.AggregateName(), snap.AggregateID(), snap.AggregateVersion(), err)
+	}
+
+	return withState(snap, decompressed), nil
+}
+
+func withState(snap Snapshot, state []byte) Snapshot {
+	return &snapshot{
+		id:      snap.AggregateID(),
+		name:    snap.AggregateName(),
+		version: snap.AggregateVersion(),
+		time:    snap.Time(),
+		state:   state,
+	}
+}
+
+func compress(state []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(compressedPrefix)
+
+	w, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(state); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decompress(state []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(state[len(compressedPrefix):]))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
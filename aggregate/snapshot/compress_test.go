@@ -0,0 +1,91 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/aggregate/snapshot/query"
+	"github.com/modernice/goes/aggregate/snapshot/storetest"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestCompress(t *testing.T) {
+	t.Run("store", func(t *testing.T) {
+		storetest.Run(t, func() snapshot.Store {
+			return snapshot.Compress(snapshot.NewStore(), snapshot.CompressMinSize(0))
+		})
+	})
+
+	ctx := context.Background()
+	inner := snapshot.NewStore()
+	store := snapshot.Compress(inner)
+
+	state := bytes.Repeat([]byte("foo"), 1000)
+	snap, err := snapshot.New(aggregate.New("foo", uuid.New(), aggregate.Version(3)), snapshot.Data(state))
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+
+	if err := store.Save(ctx, snap); err != nil {
+		t.Fatalf("Save failed with %q", err)
+	}
+
+	raw, err := inner.Latest(ctx, "foo", snap.AggregateID())
+	if err != nil {
+		t.Fatalf("fetch raw snapshot: %v", err)
+	}
+
+	if len(raw.State()) >= len(state) {
+		t.Fatalf("saved state should be compressed; has %d bytes (uncompressed %d bytes)", len(raw.State()), len(state))
+	}
+
+	latest, err := store.Latest(ctx, "foo", snap.AggregateID())
+	if err != nil {
+		t.Fatalf("Latest failed with %q", err)
+	}
+
+	if !bytes.Equal(latest.State(), state) {
+		t.Fatalf("Latest should return the decompressed state")
+	}
+
+	snaps, errs, err := store.Query(ctx, query.New())
+	if err != nil {
+		t.Fatalf("Query failed with %q", err)
+	}
+
+	queried, err := streams.Drain(ctx, snaps, errs)
+	if err != nil {
+		t.Fatalf("drain snapshots: %v", err)
+	}
+
+	if len(queried) != 1 || !bytes.Equal(queried[0].State(), state) {
+		t.Fatalf("Query should return the decompressed snapshot")
+	}
+}
+
+func TestCompress_uncompressed(t *testing.T) {
+	ctx := context.Background()
+	inner := snapshot.NewStore()
+
+	snap, err := snapshot.New(aggregate.New("foo", uuid.New()), snapshot.Data([]byte("foo")))
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+
+	if err := inner.Save(ctx, snap); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	latest, err := snapshot.Compress(inner).Latest(ctx, "foo", snap.AggregateID())
+	if err != nil {
+		t.Fatalf("Latest failed with %q", err)
+	}
+
+	if !bytes.Equal(latest.State(), []byte("foo")) {
+		t.Fatalf("Latest should return uncompressed states as-is; got %q", latest.State())
+	}
+}
@@ -0,0 +1,482 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate/snapshot/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// DefaultFullSnapshotInterval is the default interval at which full snapshots
+// are saved by a diff store.
+const DefaultFullSnapshotInterval = 10
+
+// diffPrefix marks the state of a snapshot as a diff against a previous
+// snapshot. States that do not start with the prefix are full snapshots, so
+// that diffs can be enabled for stores that already contain snapshots.
+var diffPrefix = []byte("\x00goes:diff\x00")
+
+// diffBlockSize is the size of the blocks of the previous state that are
+// matched against the new state.
+const diffBlockSize = 32
+
+const (
+	opCopy = byte(iota)
+	opInsert
+)
+
+var errCorruptDiff = errors.New("corrupt snapshot diff")
+
+// DiffOption is an option for Diff.
+type DiffOption func(*diffStore)
+
+type diffStore struct {
+	Store
+
+	fullEvery int
+}
+
+type diffHeader struct {
+	base     int
+	depth    int
+	checksum uint32
+}
+
+// FullEvery returns a DiffOption that sets the interval at which full
+// snapshots are saved. Every n-th snapshot of an aggregate is saved in full,
+// which bounds the number of snapshots that must be fetched to restore the
+// state of a snapshot. An interval <= 1 disables diffs. Defaults to
+// DefaultFullSnapshotInterval.
+func FullEvery(n int) DiffOption {
+	return func(s *diffStore) {
+		s.fullEvery = n
+	}
+}
+
+// Diff returns a Store that saves the states of snapshots as diffs against the
+// previous snapshot of the same aggregate, with periodic full snapshots (see
+// FullEvery). This cuts snapshot storage for large aggregates whose state
+// changes incrementally. The states of fetched snapshots are restored from the
+// last full snapshot and the diffs that follow it. If a diff would not be
+// smaller than the state itself, the snapshot is saved in full.
+//
+// Snapshots that were saved without diffs are returned as-is, so diffs can be
+// enabled for existing stores. Deleting a snapshot, or overwriting it with a
+// new state, saves the snapshots that are based on it in full, so that their
+// states can still be restored.
+//
+// To compress the diffs, wrap a compressed store:
+//
+//	store := snapshot.Diff(snapshot.Compress(mongo.NewSnapshotStore()))
+func Diff(store Store, opts ...DiffOption) Store {
+	s := &diffStore{
+		Store:     store,
+		fullEvery: DefaultFullSnapshotInterval,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *diffStore) Save(ctx context.Context, snap Snapshot) error {
+	state := snap.State()
+	if s.fullEvery <= 1 || bytes.HasPrefix(state, diffPrefix) {
+		return s.save(ctx, snap)
+	}
+
+	prev, err := s.Store.Limit(ctx, snap.AggregateName(), snap.AggregateID(), snap.AggregateVersion()-1)
+	if err != nil {
+		// There is no previous snapshot to diff against.
+		return s.save(ctx, snap)
+	}
+
+	depth := 1
+	if h, _, ok := parseDiff(prev.State()); ok {
+		depth = h.depth + 1
+	}
+	if depth >= s.fullEvery {
+		return s.save(ctx, snap)
+	}
+
+	base, err := s.restore(ctx, prev)
+	if err != nil {
+		return fmt.Errorf("restore previous snapshot: %w", err)
+	}
+
+	diff := encodeDiff(diffHeader{
+		base:     prev.AggregateVersion(),
+		depth:    depth,
+		checksum: crc32.ChecksumIEEE(base),
+	}, base, state)
+
+	if len(diff) >= len(state) {
+		return s.save(ctx, snap)
+	}
+
+	return s.save(ctx, withState(snap, diff))
+}
+
+// save saves the given snapshot into the underlying store. If the snapshot
+// overwrites an existing snapshot, the snapshots that are based on it are
+// saved in full first.
+func (s *diffStore) save(ctx context.Context, snap Snapshot) error {
+	if _, err := s.Store.Version(ctx, snap.AggregateName(), snap.AggregateID(), snap.AggregateVersion()); err == nil {
+		if err := s.rebase(ctx, snap); err != nil {
+			return err
+		}
+	}
+	return s.Store.Save(ctx, snap)
+}
+
+func (s *diffStore) Latest(ctx context.Context, name string, id uuid.UUID) (Snapshot, error) {
+	return s.restored(ctx)(s.Store.Latest(ctx, name, id))
+}
+
+func (s *diffStore) Version(ctx context.Context, name string, id uuid.UUID, v int) (Snapshot, error) {
+	return s.restored(ctx)(s.Store.Version(ctx, name, id, v))
+}
+
+func (s *diffStore) Limit(ctx context.Context, name string, id uuid.UUID, v int) (Snapshot, error) {
+	return s.restored(ctx)(s.Store.Limit(ctx, name, id, v))
+}
+
+func (s *diffStore) Query(ctx context.Context, q Query) (<-chan Snapshot, <-chan error, error) {
+	snaps, errs, err := s.Store.Query(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, outErrs := make(chan Snapshot), make(chan error)
+	restored := s.restored(ctx)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		for snaps != nil || errs != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case outErrs <- err:
+				}
+			case snap, ok := <-snaps:
+				if !ok {
+					snaps = nil
+					break
+				}
+
+				var err error
+				if snap, err = restored(snap, nil); err != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case outErrs <- err:
+					}
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case out <- snap:
+				}
+			}
+		}
+	}()
+
+	return out, outErrs, nil
+}
+
+// QueryMetadata implements MetadataQuerier.
+func (s *diffStore) QueryMetadata(ctx context.Context, q Query) (<-chan Snapshot, <-chan error, error) {
+	return QueryMetadata(ctx, s.Store, q)
+}
+
+// Delete deletes the given snapshot. The snapshots that are based on it are
+// saved in full before the snapshot is deleted.
+func (s *diffStore) Delete(ctx context.Context, snap Snapshot) error {
+	if err := s.rebase(ctx, snap); err != nil {
+		return err
+	}
+	return s.Store.Delete(ctx, snap)
+}
+
+// rebase saves the snapshots that are diffs against the given snapshot in full.
+func (s *diffStore) rebase(ctx context.Context, snap Snapshot) error {
+	str, errs, err := s.Store.Query(ctx, query.New(
+		query.Name(snap.AggregateName()),
+		query.ID(snap.AggregateID()),
+		query.Version(version.Min(snap.AggregateVersion()+1)),
+	))
+	if err != nil {
+		return fmt.Errorf("query dependent snapshots: %w", err)
+	}
+
+	snaps, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return fmt.Errorf("query dependent snapshots: %w", err)
+	}
+
+	for _, dep := range snaps {
+		h, _, ok := parseDiff(dep.State())
+		if !ok || h.base != snap.AggregateVersion() {
+			continue
+		}
+
+		state, err := s.restore(ctx, dep)
+		if err != nil {
+			return fmt.Errorf("restore dependent snapshot: %w", err)
+		}
+
+		if err := s.Store.Save(ctx, withState(dep, state)); err != nil {
+			return fmt.Errorf("save dependent snapshot in full: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *diffStore) restored(ctx context.Context) func(Snapshot, error) (Snapshot, error) {
+	return func(snap Snapshot, err error) (Snapshot, error) {
+		if err != nil {
+			return snap, err
+		}
+
+		if !bytes.HasPrefix(snap.State(), diffPrefix) {
+			return snap, nil
+		}
+
+		state, err := s.restore(ctx, snap)
+		if err != nil {
+			return nil, err
+		}
+
+		return withState(snap, state), nil
+	}
+}
+
+// restore returns the full state of the given snapshot by applying the chain
+// of diffs that leads from the last full snapshot to the given snapshot.
+func (s *diffStore) restore(ctx context.Context, snap Snapshot) ([]byte, error) {
+	type link struct {
+		header diffHeader
+		diff   []byte
+	}
+
+	var chain []link
+	current := snap
+	for {
+		h, diff, ok := parseDiff(current.State())
+		if !ok {
+			break
+		}
+		chain = append(chain, link{h, diff})
+
+		base, err := s.Store.Version(ctx, snap.AggregateName(), snap.AggregateID(), h.base)
+		if err != nil {
+			return nil, fmt.Errorf("fetch base of snapshot of %s(%s) at version %d: %w", snap.AggregateName(), snap.AggregateID(), current.AggregateVersion(), err)
+		}
+		current = base
+	}
+
+	state := current.State()
+	for i := len(chain) - 1; i >= 0; i-- {
+		if crc32.ChecksumIEEE(state) != chain[i].header.checksum {
+			return nil, fmt.Errorf("restore snapshot of %s(%s) at version %d: base at version %d has changed: %w", snap.AggregateName(), snap.AggregateID(), snap.AggregateVersion(), chain[i].header.base, errCorruptDiff)
+		}
+
+		var err error
+		if state, err = applyDiff(state, chain[i].diff); err != nil {
+			return nil, fmt.Errorf("restore snapshot of %s(%s) at version %d: %w", snap.AggregateName(), snap.AggregateID(), snap.AggregateVersion(), err)
+		}
+	}
+
+	return state, nil
+}
+
+// encodeDiff encodes the diff between base and target. The diff consists of
+// copy operations that copy ranges of base, and insert operations that insert
+// the bytes of target that cannot be found in base.
+func encodeDiff(h diffHeader, base, target []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(diffPrefix)
+	writeUvarint(&buf, uint64(h.base))
+	writeUvarint(&buf, uint64(h.depth))
+	binary.Write(&buf, binary.BigEndian, h.checksum)
+	writeUvarint(&buf, uint64(len(target)))
+
+	insert := func(b []byte) {
+		if len(b) == 0 {
+			return
+		}
+		buf.WriteByte(opInsert)
+		writeUvarint(&buf, uint64(len(b)))
+		buf.Write(b)
+	}
+
+	if len(base) < diffBlockSize || len(target) < diffBlockSize {
+		insert(target)
+		return buf.Bytes()
+	}
+
+	// index the blocks of base by their rolling hash
+	blocks := make(map[uint64]int, len(base)/diffBlockSize)
+	for off := 0; off+diffBlockSize <= len(base); off += diffBlockSize {
+		h := blockHash(base[off : off+diffBlockSize])
+		if _, ok := blocks[h]; !ok {
+			blocks[h] = off
+		}
+	}
+
+	var (
+		pos   int
+		start int
+		hash  = blockHash(target[:diffBlockSize])
+	)
+
+	for pos+diffBlockSize <= len(target) {
+		off, ok := blocks[hash]
+		if ok && bytes.Equal(base[off:off+diffBlockSize], target[pos:pos+diffBlockSize]) {
+			// extend the match in both directions
+			for pos > start && off > 0 && base[off-1] == target[pos-1] {
+				pos--
+				off--
+			}
+			n := 0
+			for pos+n < len(target) && off+n < len(base) && base[off+n] == target[pos+n] {
+				n++
+			}
+
+			insert(target[start:pos])
+			buf.WriteByte(opCopy)
+			writeUvarint(&buf, uint64(off))
+			writeUvarint(&buf, uint64(n))
+
+			pos += n
+			start = pos
+			if pos+diffBlockSize <= len(target) {
+				hash = blockHash(target[pos : pos+diffBlockSize])
+			}
+			continue
+		}
+
+		if pos+diffBlockSize < len(target) {
+			hash = rollHash(hash, target[pos], target[pos+diffBlockSize])
+		}
+		pos++
+	}
+
+	insert(target[start:])
+
+	return buf.Bytes()
+}
+
+// parseDiff parses the header of the given state and returns the header and
+// the remaining diff. parseDiff returns false if the state is not a diff.
+func parseDiff(state []byte) (diffHeader, []byte, bool) {
+	if !bytes.HasPrefix(state, diffPrefix) {
+		return diffHeader{}, nil, false
+	}
+
+	r := bytes.NewReader(state[len(diffPrefix):])
+
+	var h diffHeader
+	base, err := binary.ReadUvarint(r)
+	if err != nil {
+		return h, nil, false
+	}
+	depth, err := binary.ReadUvarint(r)
+	if err != nil {
+		return h, nil, false
+	}
+	if err := binary.Read(r, binary.BigEndian, &h.checksum); err != nil {
+		return h, nil, false
+	}
+	h.base, h.depth = int(base), int(depth)
+
+	return h, state[len(state)-r.Len():], true
+}
+
+// applyDiff applies the given diff (without its header) to base.
+func applyDiff(base, diff []byte) ([]byte, error) {
+	r := bytes.NewReader(diff)
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errCorruptDiff
+	}
+
+	out := make([]byte, 0, size)
+	for r.Len() > 0 {
+		op, _ := r.ReadByte()
+		switch op {
+		case opCopy:
+			off, err1 := binary.ReadUvarint(r)
+			n, err2 := binary.ReadUvarint(r)
+			if err1 != nil || err2 != nil || off+n > uint64(len(base)) {
+				return nil, errCorruptDiff
+			}
+			out = append(out, base[off:off+n]...)
+		case opInsert:
+			n, err := binary.ReadUvarint(r)
+			if err != nil || n > uint64(r.Len()) {
+				return nil, errCorruptDiff
+			}
+			b := make([]byte, n)
+			r.Read(b)
+			out = append(out, b...)
+		default:
+			return nil, errCorruptDiff
+		}
+	}
+
+	if uint64(len(out)) != size {
+		return nil, errCorruptDiff
+	}
+
+	return out, nil
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+const hashBase = 1099511628211
+
+// hashPow is hashBase^(diffBlockSize-1), used to remove the first byte of a
+// block from its rolling hash.
+var hashPow = func() uint64 {
+	p := uint64(1)
+	for i := 0; i < diffBlockSize-1; i++ {
+		p *= hashBase
+	}
+	return p
+}()
+
+func blockHash(b []byte) uint64 {
+	var h uint64
+	for _, c := range b {
+		h = h*hashBase + uint64(c)
+	}
+	return h
+}
+
+func rollHash(h uint64, out, in byte) uint64 {
+	return (h-uint64(out)*hashPow)*hashBase + uint64(in)
+}
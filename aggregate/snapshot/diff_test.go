@@ -0,0 +1,188 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/aggregate/snapshot/query"
+	"github.com/modernice/goes/aggregate/snapshot/storetest"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestDiff(t *testing.T) {
+	t.Run("store", func(t *testing.T) {
+		storetest.Run(t, func() snapshot.Store {
+			return snapshot.Diff(snapshot.NewStore())
+		})
+	})
+
+	ctx := context.Background()
+	inner := snapshot.NewStore()
+	store := snapshot.Diff(inner, snapshot.FullEvery(3))
+
+	id := uuid.New()
+	states := diffStates(5)
+	for i, state := range states {
+		saveDiffSnapshot(t, store, id, i+1, state)
+	}
+
+	for i, state := range states {
+		raw, err := inner.Version(ctx, "foo", id, i+1)
+		if err != nil {
+			t.Fatalf("fetch raw snapshot: %v", err)
+		}
+
+		// versions 1 and 4 are full snapshots
+		full := i%3 == 0
+		if got := len(raw.State()) == len(state); got != full {
+			t.Fatalf("snapshot at version %d should be saved in full: %v (saved %d bytes of %d)", i+1, full, len(raw.State()), len(state))
+		}
+		if !full && len(raw.State()) >= len(state)/10 {
+			t.Fatalf("diff at version %d should be small; has %d bytes (full state %d bytes)", i+1, len(raw.State()), len(state))
+		}
+
+		snap, err := store.Version(ctx, "foo", id, i+1)
+		if err != nil {
+			t.Fatalf("Version failed with %q", err)
+		}
+
+		if !bytes.Equal(snap.State(), state) {
+			t.Fatalf("Version should return the restored state of version %d", i+1)
+		}
+	}
+
+	latest, err := store.Latest(ctx, "foo", id)
+	if err != nil {
+		t.Fatalf("Latest failed with %q", err)
+	}
+	if !bytes.Equal(latest.State(), states[4]) {
+		t.Fatalf("Latest should return the restored state")
+	}
+
+	snaps, errs, err := store.Query(ctx, query.New(query.ID(id)))
+	if err != nil {
+		t.Fatalf("Query failed with %q", err)
+	}
+
+	queried, err := streams.Drain(ctx, snaps, errs)
+	if err != nil {
+		t.Fatalf("drain snapshots: %v", err)
+	}
+
+	if len(queried) != len(states) {
+		t.Fatalf("Query should return %d snapshots; got %d", len(states), len(queried))
+	}
+	for _, snap := range queried {
+		if !bytes.Equal(snap.State(), states[snap.AggregateVersion()-1]) {
+			t.Fatalf("Query should return the restored state of version %d", snap.AggregateVersion())
+		}
+	}
+}
+
+func TestDiff_Delete(t *testing.T) {
+	ctx := context.Background()
+	store := snapshot.Diff(snapshot.NewStore())
+
+	id := uuid.New()
+	states := diffStates(3)
+	for i, state := range states {
+		saveDiffSnapshot(t, store, id, i+1, state)
+	}
+
+	base, err := store.Version(ctx, "foo", id, 1)
+	if err != nil {
+		t.Fatalf("Version failed with %q", err)
+	}
+
+	if err := store.Delete(ctx, base); err != nil {
+		t.Fatalf("Delete failed with %q", err)
+	}
+
+	for i, state := range states[1:] {
+		snap, err := store.Version(ctx, "foo", id, i+2)
+		if err != nil {
+			t.Fatalf("Version failed with %q", err)
+		}
+		if !bytes.Equal(snap.State(), state) {
+			t.Fatalf("Version should return the restored state of version %d after its base was deleted", i+2)
+		}
+	}
+}
+
+func TestDiff_overwrite(t *testing.T) {
+	ctx := context.Background()
+	store := snapshot.Diff(snapshot.NewStore())
+
+	id := uuid.New()
+	states := diffStates(2)
+	for i, state := range states {
+		saveDiffSnapshot(t, store, id, i+1, state)
+	}
+
+	saveDiffSnapshot(t, store, id, 1, bytes.Repeat([]byte("x"), 1024))
+
+	snap, err := store.Version(ctx, "foo", id, 2)
+	if err != nil {
+		t.Fatalf("Version failed with %q", err)
+	}
+	if !bytes.Equal(snap.State(), states[1]) {
+		t.Fatalf("Version should return the restored state of version 2 after its base was overwritten")
+	}
+}
+
+func TestDiff_full(t *testing.T) {
+	ctx := context.Background()
+	inner := snapshot.NewStore()
+
+	snap, err := snapshot.New(aggregate.New("foo", uuid.New()), snapshot.Data([]byte("foo")))
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+
+	if err := inner.Save(ctx, snap); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	latest, err := snapshot.Diff(inner).Latest(ctx, "foo", snap.AggregateID())
+	if err != nil {
+		t.Fatalf("Latest failed with %q", err)
+	}
+
+	if !bytes.Equal(latest.State(), []byte("foo")) {
+		t.Fatalf("Latest should return full states as-is; got %q", latest.State())
+	}
+}
+
+// diffStates returns n states of an aggregate whose state changes
+// incrementally.
+func diffStates(n int) [][]byte {
+	var buf bytes.Buffer
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&buf, `{"item":%d,"name":"item-%d","qty":1},`, i, i)
+	}
+
+	out := make([][]byte, n)
+	for i := range out {
+		fmt.Fprintf(&buf, `{"item":%d,"name":"new-%d","qty":2},`, 1000+i, i)
+		out[i] = append([]byte(nil), buf.Bytes()...)
+		copy(out[i][100:], fmt.Sprintf("%08d", i))
+	}
+
+	return out
+}
+
+func saveDiffSnapshot(t *testing.T, store snapshot.Store, id uuid.UUID, v int, state []byte) {
+	snap, err := snapshot.New(aggregate.New("foo", id, aggregate.Version(v)), snapshot.Data(state))
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+
+	if err := store.Save(context.Background(), snap); err != nil {
+		t.Fatalf("Save failed with %q", err)
+	}
+}
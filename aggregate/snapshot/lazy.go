@@ -0,0 +1,183 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MetadataQuerier is a Store that can query snapshots without their states.
+// Stores implement MetadataQuerier to avoid loading large snapshot states from
+// the database when only the metadata of the snapshots is needed.
+type MetadataQuerier interface {
+	// QueryMetadata queries the snapshots that fit the given Query, like Query
+	// does, but the returned snapshots have no state.
+	QueryMetadata(context.Context, Query) (<-chan Snapshot, <-chan error, error)
+}
+
+// QueryMetadata queries the metadata of the snapshots in the provided store.
+// If the store implements MetadataQuerier, its QueryMetadata method is used.
+// Otherwise, the snapshots are queried using Query and their states are
+// dropped as soon as they are received.
+func QueryMetadata(ctx context.Context, store Store, q Query) (<-chan Snapshot, <-chan error, error) {
+	if mq, ok := store.(MetadataQuerier); ok {
+		return mq.QueryMetadata(ctx, q)
+	}
+
+	snaps, errs, err := store.Query(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make(chan Snapshot)
+
+	go func() {
+		defer close(out)
+		for snap := range snaps {
+			select {
+			case <-ctx.Done():
+				// Drain the remaining snapshots so that the store is not blocked.
+				for range snaps {
+				}
+				return
+			case out <- withState(snap, nil):
+			}
+		}
+	}()
+
+	return out, errs, nil
+}
+
+// Lazy returns a Store that queries only the metadata of snapshots and loads
+// their states when they are actually used. Snapshots returned by the Query
+// method of a lazy store are *LazySnapshots. This reduces memory spikes when
+// querying many large snapshots, e.g. to decide which of them to restore.
+//
+// The states of lazy snapshots are loaded using the Version method of the
+// provided store. The Latest, Version and Limit methods return the snapshots
+// of the provided store as-is.
+func Lazy(store Store) Store {
+	return &lazyStore{Store: store}
+}
+
+type lazyStore struct {
+	Store
+}
+
+func (s *lazyStore) Query(ctx context.Context, q Query) (<-chan Snapshot, <-chan error, error) {
+	snaps, errs, err := QueryMetadata(ctx, s.Store, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make(chan Snapshot)
+
+	go func() {
+		defer close(out)
+		for snap := range snaps {
+			select {
+			case <-ctx.Done():
+				for range snaps {
+				}
+				return
+			case out <- NewLazySnapshot(s.Store, snap):
+			}
+		}
+	}()
+
+	return out, errs, nil
+}
+
+// QueryMetadata implements MetadataQuerier.
+func (s *lazyStore) QueryMetadata(ctx context.Context, q Query) (<-chan Snapshot, <-chan error, error) {
+	return QueryMetadata(ctx, s.Store, q)
+}
+
+// LazySnapshot is a Snapshot whose state is loaded from a Store on first use.
+type LazySnapshot struct {
+	store Store
+	meta  Snapshot
+
+	mux    sync.Mutex
+	loaded bool
+	state  []byte
+	err    error
+}
+
+// NewLazySnapshot returns a LazySnapshot that has the metadata of the provided
+// snapshot and loads its state from the provided store.
+func NewLazySnapshot(store Store, meta Snapshot) *LazySnapshot {
+	return &LazySnapshot{store: store, meta: meta}
+}
+
+// AggregateName implements Snapshot.
+func (s *LazySnapshot) AggregateName() string {
+	return s.meta.AggregateName()
+}
+
+// AggregateID implements Snapshot.
+func (s *LazySnapshot) AggregateID() uuid.UUID {
+	return s.meta.AggregateID()
+}
+
+// AggregateVersion implements Snapshot.
+func (s *LazySnapshot) AggregateVersion() int {
+	return s.meta.AggregateVersion()
+}
+
+// Time implements Snapshot.
+func (s *LazySnapshot) Time() time.Time {
+	return s.meta.Time()
+}
+
+// Loaded reports whether the state of the snapshot has been loaded.
+func (s *LazySnapshot) Loaded() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.loaded
+}
+
+// Load loads the state of the snapshot from the store, if it has not been
+// loaded yet. If loading fails, Load can be called again to retry.
+func (s *LazySnapshot) Load(ctx context.Context) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.loaded {
+		return nil
+	}
+
+	snap, err := s.store.Version(ctx, s.meta.AggregateName(), s.meta.AggregateID(), s.meta.AggregateVersion())
+	if err != nil {
+		s.err = fmt.Errorf("load snapshot of %s(%s) at version %d: %w", s.meta.AggregateName(), s.meta.AggregateID(), s.meta.AggregateVersion(), err)
+		return s.err
+	}
+
+	s.state = snap.State()
+	s.loaded = true
+	s.err = nil
+
+	return nil
+}
+
+// Err returns the error of the last failed attempt to load the state.
+func (s *LazySnapshot) Err() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.err
+}
+
+// State implements Snapshot. If the state has not been loaded yet, State loads
+// it using context.Background(). If loading fails, State returns nil and the
+// error can be retrieved using Err. Call Load before State to control the
+// context and handle errors.
+func (s *LazySnapshot) State() []byte {
+	s.Load(context.Background())
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.state
+}
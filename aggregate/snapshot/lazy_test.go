@@ -0,0 +1,96 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/aggregate/snapshot/query"
+	"github.com/modernice/goes/aggregate/snapshot/storetest"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestLazy(t *testing.T) {
+	t.Run("store", func(t *testing.T) {
+		storetest.Run(t, func() snapshot.Store {
+			return snapshot.Lazy(snapshot.NewStore())
+		})
+	})
+
+	ctx := context.Background()
+	store := snapshot.Lazy(snapshot.Compress(snapshot.NewStore(), snapshot.CompressMinSize(0)))
+
+	snap, err := snapshot.New(aggregate.New("foo", uuid.New(), aggregate.Version(3)), snapshot.Data([]byte("foo")))
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+
+	if err := store.Save(ctx, snap); err != nil {
+		t.Fatalf("Save failed with %q", err)
+	}
+
+	snaps, errs, err := store.Query(ctx, query.New())
+	if err != nil {
+		t.Fatalf("Query failed with %q", err)
+	}
+
+	queried, err := streams.Drain(ctx, snaps, errs)
+	if err != nil {
+		t.Fatalf("drain snapshots: %v", err)
+	}
+
+	if len(queried) != 1 {
+		t.Fatalf("Query should return 1 snapshot; got %d", len(queried))
+	}
+
+	lazy, ok := queried[0].(*snapshot.LazySnapshot)
+	if !ok {
+		t.Fatalf("Query should return %T; got %T", lazy, queried[0])
+	}
+
+	if lazy.AggregateID() != snap.AggregateID() || lazy.AggregateVersion() != 3 {
+		t.Fatalf("lazy snapshot should have the metadata of the saved snapshot")
+	}
+
+	if lazy.Loaded() {
+		t.Fatalf("state should not be loaded before it is used")
+	}
+
+	if err := lazy.Load(ctx); err != nil {
+		t.Fatalf("Load failed with %q", err)
+	}
+
+	if !lazy.Loaded() {
+		t.Fatalf("state should be loaded")
+	}
+
+	if !bytes.Equal(lazy.State(), []byte("foo")) {
+		t.Fatalf("State should return %q; got %q", "foo", lazy.State())
+	}
+}
+
+func TestLazySnapshot_Load_notFound(t *testing.T) {
+	store := snapshot.NewStore()
+
+	meta, err := snapshot.New(aggregate.New("foo", uuid.New()))
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+
+	lazy := snapshot.NewLazySnapshot(store, meta)
+
+	if state := lazy.State(); state != nil {
+		t.Fatalf("State should return nil; got %q", state)
+	}
+
+	if lazy.Err() == nil {
+		t.Fatalf("Err should return an error")
+	}
+
+	if lazy.Loaded() {
+		t.Fatalf("state should not be loaded")
+	}
+}
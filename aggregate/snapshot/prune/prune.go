@@ -0,0 +1,264 @@
+// Package prune deletes the events of aggregates that are covered by a
+// snapshot of the aggregate.
+//
+// Aggregates with huge event histories can be pruned to reduce the storage
+// size of the event store: If an aggregate has a snapshot, the events up to
+// the version of the snapshot are not needed to hydrate the aggregate anymore,
+// as long as the aggregate repository is configured to use the snapshot store
+// (see repository.WithSnapshots).
+//
+// Pruning is irreversible. After pruning, the aggregate can only be fetched up
+// to the version of the latest snapshot, so FetchVersion() for older versions
+// fails, and projections can no longer be rebuilt from the pruned events.
+// Never delete the snapshots that the pruning was anchored to. To protect
+// against corrupted snapshots, the Pruner verifies each snapshot against the
+// event history of the aggregate before pruning, unless the SkipVerification
+// option is used. Aggregates that were already pruned are verified against the
+// history after the snapshot that the previous pruning was anchored to.
+package prune
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// ErrVerification is returned by a Pruner if the aggregate that is hydrated
+// from its snapshot does not match the aggregate that is hydrated from its
+// full event history.
+var ErrVerification = errors.New("snapshot does not match event history")
+
+// Pruner deletes the events of aggregates that are covered by their latest
+// snapshot.
+type Pruner struct {
+	events    event.Store
+	snapshots snapshot.Store
+	margin    int
+	newFunc   func(aggregate.Ref) aggregate.Aggregate
+	verify    bool
+}
+
+// Option is an option for a Pruner.
+type Option func(*Pruner)
+
+// Result is the result of pruning a single aggregate.
+type Result struct {
+	// Aggregate is the pruned aggregate.
+	Aggregate aggregate.Ref
+
+	// Anchor is the version of the snapshot that the pruning was anchored to.
+	// Anchor is 0 if the aggregate has no snapshot.
+	Anchor int
+
+	// Deleted is the number of deleted events.
+	Deleted int
+}
+
+// Margin returns an Option that configures the number of events before the
+// snapshot version that are kept as a safety margin. For example, with a margin
+// of 10 and a snapshot at version 100, the events up to version 90 are deleted.
+func Margin(n int) Option {
+	return func(p *Pruner) {
+		p.margin = n
+	}
+}
+
+// SkipVerification returns an Option that disables the verification of
+// snapshots. Only skip the verification if the snapshots are known to be
+// correct, because pruning events based on a corrupted snapshot loses data.
+func SkipVerification() Option {
+	return func(p *Pruner) {
+		p.verify = false
+	}
+}
+
+// New returns a Pruner that prunes events from the given event store, using
+// the snapshots in the given snapshot store as anchors.
+//
+// The Pruner verifies the snapshot of an aggregate before pruning its events.
+// The provided function must return a new, empty instance of the given
+// aggregate. The Pruner hydrates the aggregate once from its full event
+// history and once from its snapshot and the events after it, and only prunes
+// if the marshaled states and versions of both aggregates are equal. If the
+// aggregate was already pruned, the history is hydrated from the latest
+// earlier snapshot instead, which must cover the pruned events. Aggregates
+// without events to prune are neither verified nor modified.
+// Otherwise, ErrVerification is returned. New panics if newFunc is nil, unless
+// the SkipVerification option is used.
+func New(events event.Store, snapshots snapshot.Store, newFunc func(aggregate.Ref) aggregate.Aggregate, opts ...Option) *Pruner {
+	p := &Pruner{
+		events:    events,
+		snapshots: snapshots,
+		newFunc:   newFunc,
+		verify:    true,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.verify && p.newFunc == nil {
+		panic("[goes/aggregate/snapshot/prune.New] aggregate factory is nil")
+	}
+	return p
+}
+
+// Prune prunes the events of the given aggregates. Aggregates without a
+// snapshot are skipped. Errors of the snapshot store other than
+// snapshot.ErrNotFound are returned. Prune stops at the first error and returns the results
+// of the aggregates that have been pruned so far.
+func (p *Pruner) Prune(ctx context.Context, aggregates ...aggregate.Ref) ([]Result, error) {
+	results := make([]Result, 0, len(aggregates))
+	for _, ref := range aggregates {
+		res, err := p.prune(ctx, ref)
+		if err != nil {
+			return results, fmt.Errorf("prune %s: %w", ref, err)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (p *Pruner) prune(ctx context.Context, ref aggregate.Ref) (Result, error) {
+	res := Result{Aggregate: ref}
+
+	snap, err := p.snapshots.Latest(ctx, ref.Name, ref.ID)
+	if errors.Is(err, snapshot.ErrNotFound) {
+		return res, nil
+	}
+	if err != nil {
+		return res, fmt.Errorf("fetch latest snapshot: %w", err)
+	}
+	if snap == nil {
+		return res, nil
+	}
+	res.Anchor = snap.AggregateVersion()
+
+	limit := res.Anchor - p.margin
+	if limit < 1 {
+		return res, nil
+	}
+
+	str, errs, err := p.events.Query(ctx, query.New(
+		query.Aggregate(ref.Name, ref.ID),
+		query.AggregateVersion(version.Max(limit)),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		return res, fmt.Errorf("query events: %w", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return res, fmt.Errorf("query events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return res, nil
+	}
+
+	if p.verify {
+		_, _, oldest := events[0].Aggregate()
+		if err := p.verifySnapshot(ctx, ref, snap, oldest); err != nil {
+			return res, err
+		}
+	}
+
+	if err := p.events.Delete(ctx, events...); err != nil {
+		return res, fmt.Errorf("delete events: %w", err)
+	}
+	res.Deleted = len(events)
+
+	return res, nil
+}
+
+// verifySnapshot verifies the given snapshot against the event history of the
+// aggregate, starting at the oldest version that is still in the event store.
+// If the aggregate was already pruned, the history is hydrated from the latest
+// earlier snapshot that covers the pruned events, which was verified when it
+// was used as an anchor.
+func (p *Pruner) verifySnapshot(ctx context.Context, ref aggregate.Ref, snap snapshot.Snapshot, oldest int) error {
+	full := p.newFunc(ref)
+	var after int
+	if oldest > 1 {
+		base, err := p.snapshots.Limit(ctx, ref.Name, ref.ID, snap.AggregateVersion()-1)
+		if err != nil && !errors.Is(err, snapshot.ErrNotFound) {
+			return fmt.Errorf("fetch base snapshot: %w", err)
+		}
+		if base == nil || base.AggregateVersion() < oldest-1 {
+			return fmt.Errorf("%w: no snapshot covers the pruned events before version %d", ErrVerification, oldest)
+		}
+		if err := unmarshal(base, full); err != nil {
+			return err
+		}
+		after = base.AggregateVersion()
+	}
+	if err := p.hydrate(ctx, full, after); err != nil {
+		return fmt.Errorf("hydrate from history: %w", err)
+	}
+
+	fromSnap := p.newFunc(ref)
+	if err := unmarshal(snap, fromSnap); err != nil {
+		return err
+	}
+	if err := p.hydrate(ctx, fromSnap, snap.AggregateVersion()); err != nil {
+		return fmt.Errorf("hydrate from snapshot: %w", err)
+	}
+
+	if aggregate.UncommittedVersion(full) != aggregate.UncommittedVersion(fromSnap) {
+		return fmt.Errorf("%w: version %d != %d", ErrVerification, aggregate.UncommittedVersion(full), aggregate.UncommittedVersion(fromSnap))
+	}
+
+	fullState, err := snapshot.Marshal(full)
+	if err != nil {
+		return fmt.Errorf("marshal aggregate: %w", err)
+	}
+
+	snapState, err := snapshot.Marshal(fromSnap)
+	if err != nil {
+		return fmt.Errorf("marshal aggregate: %w", err)
+	}
+
+	if !bytes.Equal(fullState, snapState) {
+		return fmt.Errorf("%w: state differs", ErrVerification)
+	}
+
+	return nil
+}
+
+func unmarshal(snap snapshot.Snapshot, a aggregate.Aggregate) error {
+	target, ok := a.(snapshot.Target)
+	if !ok {
+		return fmt.Errorf("aggregate does not implement %T", target)
+	}
+	if err := snapshot.Unmarshal(snap, target); err != nil {
+		return fmt.Errorf("unmarshal snapshot: %w [version=%d]", err, snap.AggregateVersion())
+	}
+	return nil
+}
+
+func (p *Pruner) hydrate(ctx context.Context, a aggregate.Aggregate, after int) error {
+	id, name, _ := a.Aggregate()
+
+	str, errs, err := p.events.Query(ctx, query.New(
+		query.Aggregate(name, id),
+		query.AggregateVersion(version.Min(after+1)),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	return aggregate.ApplyHistory(a, events)
+}
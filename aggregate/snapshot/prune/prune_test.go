@@ -0,0 +1,305 @@
+package prune_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/aggregate/snapshot/prune"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+type mockAggregate struct {
+	*aggregate.Base
+	mockState
+}
+
+type mockState struct {
+	Count int
+}
+
+func newMockAggregate(id uuid.UUID) *mockAggregate {
+	a := &mockAggregate{Base: aggregate.New("foo", id)}
+	event.ApplyWith(a, func(event.Of[int]) { a.Count++ }, "counted")
+	return a
+}
+
+func TestPruner_Prune(t *testing.T) {
+	ctx := context.Background()
+	events := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, events, snapshots, 10, 6)
+
+	p := prune.New(events, snapshots, newAggregate, prune.Margin(2))
+
+	results, err := p.Prune(ctx, aggregate.Ref{Name: "foo", ID: a.ID})
+	if err != nil {
+		t.Fatalf("Prune() failed with %q", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Prune() should return 1 result; got %d", len(results))
+	}
+
+	if results[0].Anchor != 6 {
+		t.Fatalf("Anchor should be %d; is %d", 6, results[0].Anchor)
+	}
+
+	if results[0].Deleted != 4 {
+		t.Fatalf("Deleted should be %d; is %d", 4, results[0].Deleted)
+	}
+
+	remaining := queryVersions(t, events, a.ID)
+	if want := []int{5, 6, 7, 8, 9, 10}; fmt.Sprint(remaining) != fmt.Sprint(want) {
+		t.Fatalf("remaining event versions should be %v; are %v", want, remaining)
+	}
+
+	repo := repository.New(events, repository.WithSnapshots(snapshots, nil))
+	fetched := newMockAggregate(a.ID)
+	if err := repo.Fetch(ctx, fetched); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if fetched.Count != 10 {
+		t.Fatalf("Count should be %d; is %d", 10, fetched.Count)
+	}
+
+	if fetched.AggregateVersion() != 10 {
+		t.Fatalf("AggregateVersion() should return %d; got %d", 10, fetched.AggregateVersion())
+	}
+}
+
+func TestPruner_Prune_twice(t *testing.T) {
+	ctx := context.Background()
+	events := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, events, snapshots, 10, 6)
+	ref := aggregate.Ref{Name: "foo", ID: a.ID}
+
+	p := prune.New(events, snapshots, newAggregate, prune.Margin(2))
+
+	if _, err := p.Prune(ctx, ref); err != nil {
+		t.Fatalf("first Prune() failed with %q", err)
+	}
+
+	results, err := p.Prune(ctx, ref)
+	if err != nil {
+		t.Fatalf("Prune() without new snapshot failed with %q", err)
+	}
+	if results[0].Deleted != 0 {
+		t.Fatalf("Deleted should be %d; is %d", 0, results[0].Deleted)
+	}
+
+	for i := 0; i < 4; i++ {
+		aggregate.Next(a, "counted", i)
+	}
+	if err := events.Insert(ctx, a.AggregateChanges()[10:]...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	snapped := newMockAggregate(a.ID)
+	if err := aggregate.ApplyHistory(snapped, a.AggregateChanges()[:12]); err != nil {
+		t.Fatalf("apply history: %v", err)
+	}
+	snap, err := snapshot.New(snapped)
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+	if err := snapshots.Save(ctx, snap); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	if results, err = p.Prune(ctx, ref); err != nil {
+		t.Fatalf("Prune() with new snapshot failed with %q", err)
+	}
+	if results[0].Anchor != 12 || results[0].Deleted != 6 {
+		t.Fatalf("Prune() should delete %d events anchored to version %d; got %+v", 6, 12, results[0])
+	}
+
+	remaining := queryVersions(t, events, a.ID)
+	if want := []int{11, 12, 13, 14}; fmt.Sprint(remaining) != fmt.Sprint(want) {
+		t.Fatalf("remaining event versions should be %v; are %v", want, remaining)
+	}
+}
+
+func TestPruner_Prune_noSnapshot(t *testing.T) {
+	events := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, events, snapshots, 5, 0)
+
+	results, err := prune.New(events, snapshots, newAggregate).Prune(context.Background(), aggregate.Ref{Name: "foo", ID: a.ID})
+	if err != nil {
+		t.Fatalf("Prune() failed with %q", err)
+	}
+
+	if results[0].Deleted != 0 {
+		t.Fatalf("Deleted should be %d; is %d", 0, results[0].Deleted)
+	}
+
+	if versions := queryVersions(t, events, a.ID); len(versions) != 5 {
+		t.Fatalf("no events should have been deleted; %d events remain", len(versions))
+	}
+}
+
+func TestPruner_Prune_verificationFailed(t *testing.T) {
+	events := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, events, snapshots, 10, 0)
+
+	// snapshot with a corrupted state
+	corrupted := newMockAggregate(a.ID)
+	aggregate.ApplyHistory(corrupted, a.AggregateChanges()[:6])
+	corrupted.Count = 3
+	snap, err := snapshot.New(corrupted)
+	if err != nil {
+		t.Fatalf("create snapshot: %v", err)
+	}
+	if err := snapshots.Save(context.Background(), snap); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	p := prune.New(events, snapshots, newAggregate)
+
+	if _, err := p.Prune(context.Background(), aggregate.Ref{Name: "foo", ID: a.ID}); !errors.Is(err, prune.ErrVerification) {
+		t.Fatalf("Prune() should fail with %q; got %q", prune.ErrVerification, err)
+	}
+
+	if versions := queryVersions(t, events, a.ID); len(versions) != 10 {
+		t.Fatalf("no events should have been deleted; %d events remain", len(versions))
+	}
+}
+
+func TestPruner_Prune_snapshotStoreError(t *testing.T) {
+	events := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, events, snapshots, 10, 6)
+
+	p := prune.New(events, failingSnapshotStore{snapshots}, newAggregate)
+
+	if _, err := p.Prune(context.Background(), aggregate.Ref{Name: "foo", ID: a.ID}); !errors.Is(err, errSnapshotStore) {
+		t.Fatalf("Prune() should fail with %q; got %v", errSnapshotStore, err)
+	}
+
+	if versions := queryVersions(t, events, a.ID); len(versions) != 10 {
+		t.Fatalf("no events should have been deleted; %d events remain", len(versions))
+	}
+}
+
+func TestSkipVerification(t *testing.T) {
+	events := eventstore.New()
+	snapshots := snapshot.NewStore()
+
+	a := setup(t, events, snapshots, 10, 6)
+
+	results, err := prune.New(events, snapshots, nil, prune.SkipVerification()).Prune(context.Background(), aggregate.Ref{Name: "foo", ID: a.ID})
+	if err != nil {
+		t.Fatalf("Prune() failed with %q", err)
+	}
+
+	if results[0].Deleted != 6 {
+		t.Fatalf("Deleted should be %d; is %d", 6, results[0].Deleted)
+	}
+}
+
+func TestNew_nilFactory(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("New() should panic if the aggregate factory is nil")
+		}
+	}()
+	prune.New(eventstore.New(), snapshot.NewStore(), nil)
+}
+
+var errSnapshotStore = errors.New("snapshot store unavailable")
+
+type failingSnapshotStore struct{ snapshot.Store }
+
+func (failingSnapshotStore) Latest(context.Context, string, uuid.UUID) (snapshot.Snapshot, error) {
+	return nil, errSnapshotStore
+}
+
+func newAggregate(ref aggregate.Ref) aggregate.Aggregate {
+	return newMockAggregate(ref.ID)
+}
+
+// setup creates an aggregate with n events and inserts them into the event
+// store. If snapAt is not 0, a snapshot of the aggregate at version snapAt is
+// saved.
+func setup(t *testing.T, events event.Store, snapshots snapshot.Store, n, snapAt int) *mockAggregate {
+	a := newMockAggregate(uuid.New())
+	for i := 0; i < n; i++ {
+		aggregate.Next(a, "counted", i)
+	}
+
+	changes := a.AggregateChanges()
+	if err := events.Insert(context.Background(), changes...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	if snapAt > 0 {
+		snapped := newMockAggregate(a.ID)
+		if err := aggregate.ApplyHistory(snapped, changes[:snapAt]); err != nil {
+			t.Fatalf("apply history: %v", err)
+		}
+		snap, err := snapshot.New(snapped)
+		if err != nil {
+			t.Fatalf("create snapshot: %v", err)
+		}
+		if err := snapshots.Save(context.Background(), snap); err != nil {
+			t.Fatalf("save snapshot: %v", err)
+		}
+	}
+
+	return a
+}
+
+func queryVersions(t *testing.T, store event.Store, id uuid.UUID) []int {
+	str, errs, err := store.Query(context.Background(), query.New(
+		query.Aggregate("foo", id),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	versions := make([]int, len(events))
+	for i, evt := range events {
+		_, _, versions[i] = evt.Aggregate()
+	}
+	return versions
+}
+
+func (a *mockAggregate) MarshalSnapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a.mockState); err != nil {
+		return nil, fmt.Errorf("gob: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (a *mockAggregate) UnmarshalSnapshot(p []byte) error {
+	if err := gob.NewDecoder(bytes.NewReader(p)).Decode(&a.mockState); err != nil {
+		return fmt.Errorf("gob: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+// Package unitofwork commits the changes of multiple aggregates together.
+//
+// A command handler that changes multiple aggregates stages them in a
+// UnitOfWork and commits them at once:
+//
+//	uow := unitofwork.New(store)
+//	uow.Stage(order, customer)
+//	if err := uow.Commit(ctx); err != nil {
+//		return err
+//	}
+//
+// If the event store implements eventstore.AtomicInserter (the in-memory store,
+// the MongoDB store and the Postgres store do), the events of all staged
+// aggregates are inserted atomically. MongoDB requires a replica set or a
+// sharded cluster for this.
+//
+// Other event stores fall back to inserting the events of each aggregate one
+// after another. If an insert fails, the events that were already inserted are
+// deleted again. This fallback is not atomic: other processes may observe the
+// events before they are deleted, and the deletion itself may fail, in which
+// case Commit returns a *CommitError that lists the aggregates whose events
+// remain in the store. Use the RequireAtomic option to reject such stores.
+//
+// A UnitOfWork inserts events directly into the event store. Hooks and
+// snapshots of an aggregate repository are not applied.
+package unitofwork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+)
+
+// ErrNotAtomic is returned by Commit if the RequireAtomic option is used and
+// the event store does not implement eventstore.AtomicInserter.
+var ErrNotAtomic = errors.New("event store does not support atomic inserts")
+
+// UnitOfWork stages the changes of multiple aggregates and commits them
+// together. A UnitOfWork is safe for concurrent use.
+type UnitOfWork struct {
+	store         event.Store
+	requireAtomic bool
+
+	mux    sync.Mutex
+	staged []staged
+}
+
+// Option is an option for a UnitOfWork.
+type Option func(*UnitOfWork)
+
+// CommitError is returned by Commit if the non-atomic fallback fails to insert
+// the events of an aggregate and fails to delete the events that were already
+// inserted.
+type CommitError struct {
+	// Failed is the aggregate whose events could not be inserted.
+	Failed aggregate.Ref
+
+	// Err is the error of the failed insert.
+	Err error
+
+	// Remaining are the aggregates whose events remain in the store.
+	Remaining []aggregate.Ref
+
+	// RollbackErr is the error of the deletion of the remaining events.
+	RollbackErr error
+}
+
+type staged struct {
+	ref       aggregate.Ref
+	aggregate aggregate.Aggregate
+	events    []event.Event
+}
+
+// RequireAtomic returns an Option that makes Commit fail with ErrNotAtomic if
+// the event store does not support atomic inserts, instead of falling back to
+// non-atomic inserts.
+func RequireAtomic() Option {
+	return func(u *UnitOfWork) {
+		u.requireAtomic = true
+	}
+}
+
+// New returns a UnitOfWork that commits to the given event store.
+func New(store event.Store, opts ...Option) *UnitOfWork {
+	u := &UnitOfWork{store: store}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
+
+// Atomic reports whether the UnitOfWork commits atomically.
+func (u *UnitOfWork) Atomic() bool {
+	_, ok := u.store.(eventstore.AtomicInserter)
+	return ok
+}
+
+// Stage stages the uncommitted changes of the given aggregates. Changes are
+// read when Commit is called, so changes that are made to an aggregate after
+// it was staged are committed, too. Staging an aggregate multiple times has no
+// effect.
+func (u *UnitOfWork) Stage(aggregates ...aggregate.Aggregate) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	for _, a := range aggregates {
+		id, name, _ := a.Aggregate()
+		ref := aggregate.Ref{Name: name, ID: id}
+		if u.index(ref) >= 0 {
+			continue
+		}
+		u.staged = append(u.staged, staged{ref: ref, aggregate: a})
+	}
+}
+
+// StageEvents stages events of a single aggregate, e.g. events that are not
+// produced by an aggregate instance.
+func (u *UnitOfWork) StageEvents(ref aggregate.Ref, events ...event.Event) {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	if i := u.index(ref); i >= 0 {
+		u.staged[i].events = append(u.staged[i].events, events...)
+		return
+	}
+
+	u.staged = append(u.staged, staged{ref: ref, events: events})
+}
+
+// Staged returns the aggregates that are currently staged.
+func (u *UnitOfWork) Staged() []aggregate.Ref {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	out := make([]aggregate.Ref, len(u.staged))
+	for i, s := range u.staged {
+		out[i] = s.ref
+	}
+
+	return out
+}
+
+// Discard unstages all aggregates and events without committing them.
+func (u *UnitOfWork) Discard() {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+	u.staged = nil
+}
+
+// Commit validates the consistency and invariants of the staged aggregates
+// and inserts their changes into the event store. After a successful commit,
+// the changes of the staged aggregates are committed (see
+// aggregate.Committer) and the UnitOfWork is empty again. If Commit fails,
+// the aggregates stay staged.
+func (u *UnitOfWork) Commit(ctx context.Context) error {
+	u.mux.Lock()
+	defer u.mux.Unlock()
+
+	streams := make([][]event.Event, 0, len(u.staged))
+	refs := make([]aggregate.Ref, 0, len(u.staged))
+
+	for _, s := range u.staged {
+		events := s.events
+
+		if s.aggregate != nil {
+			_, _, version := s.aggregate.Aggregate()
+			changes := s.aggregate.AggregateChanges()
+
+			if err := aggregate.ValidateConsistency(s.ref, version, changes); err != nil {
+				return fmt.Errorf("validate consistency of %s: %w", s.ref, err)
+			}
+
+			if err := aggregate.CheckInvariants(s.aggregate); err != nil {
+				return fmt.Errorf("%s: %w", s.ref, err)
+			}
+
+			events = slices.Concat(changes, events)
+		}
+
+		if len(events) == 0 {
+			continue
+		}
+
+		streams = append(streams, events)
+		refs = append(refs, s.ref)
+	}
+
+	if err := u.insert(ctx, refs, streams); err != nil {
+		return err
+	}
+
+	for _, s := range u.staged {
+		if c, ok := s.aggregate.(aggregate.Committer); ok {
+			c.Commit()
+		}
+	}
+
+	u.staged = nil
+
+	return nil
+}
+
+func (u *UnitOfWork) insert(ctx context.Context, refs []aggregate.Ref, streams [][]event.Event) error {
+	if len(streams) == 0 {
+		return nil
+	}
+
+	if ai, ok := u.store.(eventstore.AtomicInserter); ok {
+		if err := ai.InsertAtomic(ctx, streams...); err != nil {
+			return fmt.Errorf("insert events: %w", err)
+		}
+		return nil
+	}
+
+	if u.requireAtomic {
+		return ErrNotAtomic
+	}
+
+	for i, events := range streams {
+		err := u.store.Insert(ctx, events...)
+		if err == nil {
+			continue
+		}
+
+		if rollbackErr := u.rollback(ctx, streams[:i]); rollbackErr != nil {
+			return &CommitError{
+				Failed:      refs[i],
+				Err:         err,
+				Remaining:   refs[:i],
+				RollbackErr: rollbackErr,
+			}
+		}
+
+		return fmt.Errorf("insert events of %s: %w", refs[i], err)
+	}
+
+	return nil
+}
+
+func (u *UnitOfWork) rollback(ctx context.Context, streams [][]event.Event) error {
+	var errs []error
+	for i := len(streams) - 1; i >= 0; i-- {
+		if err := u.store.Delete(ctx, streams[i]...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (u *UnitOfWork) index(ref aggregate.Ref) int {
+	for i, s := range u.staged {
+		if s.ref == ref {
+			return i
+		}
+	}
+	return -1
+}
+
+// Error implements error.
+func (err *CommitError) Error() string {
+	return fmt.Sprintf("insert events of %s: %v (rollback of %v failed: %v)", err.Failed, err.Err, err.Remaining, err.RollbackErr)
+}
+
+// Unwrap returns the error of the failed insert.
+func (err *CommitError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,150 @@
+package unitofwork_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/unitofwork"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+var errMockInsert = errors.New("mock insert error")
+
+func TestUnitOfWork_Commit(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+	uow := unitofwork.New(store)
+
+	if !uow.Atomic() {
+		t.Fatalf("UnitOfWork should commit atomically to the in-memory store")
+	}
+
+	order, customer := newAggregates()
+	uow.Stage(order, customer, order)
+
+	if staged := uow.Staged(); len(staged) != 2 {
+		t.Fatalf("Staged should return %d aggregates; got %d", 2, len(staged))
+	}
+
+	if err := uow.Commit(ctx); err != nil {
+		t.Fatalf("Commit failed with %q", err)
+	}
+
+	if n := countEvents(t, store); n != 3 {
+		t.Fatalf("store should contain %d events; got %d", 3, n)
+	}
+
+	if len(order.AggregateChanges()) != 0 || len(customer.AggregateChanges()) != 0 {
+		t.Fatalf("changes of the aggregates should be committed")
+	}
+
+	if staged := uow.Staged(); len(staged) != 0 {
+		t.Fatalf("UnitOfWork should be empty after Commit; got %v", staged)
+	}
+}
+
+func TestUnitOfWork_Commit_atomic(t *testing.T) {
+	ctx := context.Background()
+	order, customer := newAggregates()
+
+	store := eventstore.New()
+	if err := store.Insert(ctx, customer.AggregateChanges()[0]); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	uow := unitofwork.New(store)
+	uow.Stage(order, customer)
+
+	if err := uow.Commit(ctx); err == nil {
+		t.Fatalf("Commit should fail if an event already exists")
+	}
+
+	if n := countEvents(t, store); n != 1 {
+		t.Fatalf("no events should be inserted if the commit fails; got %d events", n)
+	}
+
+	if len(order.AggregateChanges()) != 2 {
+		t.Fatalf("changes of the aggregates should not be committed if the commit fails")
+	}
+}
+
+func TestUnitOfWork_Commit_fallback(t *testing.T) {
+	ctx := context.Background()
+	order, customer := newAggregates()
+
+	store := &nonAtomicStore{Store: eventstore.New(), failOn: "customer"}
+	uow := unitofwork.New(store)
+
+	if uow.Atomic() {
+		t.Fatalf("UnitOfWork should not commit atomically to a store without atomic inserts")
+	}
+
+	uow.Stage(order, customer)
+
+	if err := uow.Commit(ctx); !errors.Is(err, errMockInsert) {
+		t.Fatalf("Commit should fail with %q; got %q", errMockInsert, err)
+	}
+
+	if n := countEvents(t, store); n != 0 {
+		t.Fatalf("inserted events should be deleted if the commit fails; got %d events", n)
+	}
+}
+
+func TestRequireAtomic(t *testing.T) {
+	order, _ := newAggregates()
+
+	store := &nonAtomicStore{Store: eventstore.New()}
+	uow := unitofwork.New(store, unitofwork.RequireAtomic())
+	uow.Stage(order)
+
+	if err := uow.Commit(context.Background()); !errors.Is(err, unitofwork.ErrNotAtomic) {
+		t.Fatalf("Commit should fail with %q; got %q", unitofwork.ErrNotAtomic, err)
+	}
+}
+
+func newAggregates() (*aggregate.Base, *aggregate.Base) {
+	order := aggregate.New("order", uuid.New())
+	aggregate.Next(order, "order.placed", 0)
+	aggregate.Next(order, "order.paid", 0)
+
+	customer := aggregate.New("customer", uuid.New())
+	aggregate.Next(customer, "customer.ordered", 0)
+
+	return order, customer
+}
+
+func countEvents(t *testing.T, store event.Store) int {
+	str, errs, err := store.Query(context.Background(), query.New())
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	return len(events)
+}
+
+// nonAtomicStore is an event store without atomic inserts that fails to insert
+// the events of the aggregates with the given name.
+type nonAtomicStore struct {
+	event.Store
+	failOn string
+}
+
+func (s *nonAtomicStore) Insert(ctx context.Context, events ...event.Event) error {
+	for _, evt := range events {
+		if _, name, _ := evt.Aggregate(); name == s.failOn {
+			return errMockInsert
+		}
+	}
+	return s.Store.Insert(ctx, events...)
+}
@@ -0,0 +1,155 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/modernice/goes/codec/claimcheck"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ claimcheck.BlobStore = (*GridFSBlobStore)(nil)
+
+// GridFSBlobStore is a claimcheck.BlobStore that stores blobs in a GridFS
+// bucket. Use it to offload large event payloads from the event store:
+//
+//	blobs := mongo.NewGridFSBlobStore(client.Database("events"))
+//	enc := claimcheck.New(reg, blobs)
+//	store := mongo.NewEventStore(enc)
+//
+// Blobs are stored as GridFS files with the key as their filename. Every upload
+// uses its own file id, and a unique index on the filenames ensures that only
+// one upload per key succeeds, so that concurrent uploads of the same key never
+// interfere with each other.
+type GridFSBlobStore struct {
+	db     *mongo.Database
+	bucket string
+
+	indexMux sync.Mutex
+	indexed  bool
+}
+
+// GridFSOption is an option for a GridFSBlobStore.
+type GridFSOption func(*GridFSBlobStore)
+
+// GridFSBucket returns a GridFSOption that sets the name of the GridFS bucket.
+// Defaults to "blobs".
+func GridFSBucket(name string) GridFSOption {
+	return func(s *GridFSBlobStore) {
+		s.bucket = name
+	}
+}
+
+// NewGridFSBlobStore returns a GridFSBlobStore that stores blobs in the given
+// database.
+func NewGridFSBlobStore(db *mongo.Database, opts ...GridFSOption) *GridFSBlobStore {
+	s := &GridFSBlobStore{db: db, bucket: "blobs"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Put stores the data as a GridFS file with the key as its filename. If a file
+// with the key already exists, Put does nothing.
+func (s *GridFSBlobStore) Put(ctx context.Context, key string, data []byte) error {
+	bucket, err := s.open(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureIndex(ctx, bucket); err != nil {
+		return err
+	}
+
+	files := bucket.GetFilesCollection()
+	if err := files.FindOne(ctx, bson.D{{Key: "filename", Value: key}}).Err(); err == nil {
+		return nil
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("mongo: find file: %w", err)
+	}
+
+	id := primitive.NewObjectID()
+	if err := bucket.UploadFromStreamWithID(id, key, bytes.NewReader(data)); err != nil {
+		// The chunks of a failed upload belong to no file. Only the chunks of
+		// this upload are removed, because they are the only chunks with its id.
+		if _, derr := bucket.GetChunksCollection().DeleteMany(ctx, bson.D{{Key: "files_id", Value: id}}); derr != nil {
+			return fmt.Errorf("mongo: delete chunks of failed upload: %w (upload error: %w)", derr, err)
+		}
+
+		// Another upload of the same key succeeded first.
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+
+		return fmt.Errorf("mongo: upload file: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the data of the GridFS file with the key as its filename.
+func (s *GridFSBlobStore) Get(ctx context.Context, key string) ([]byte, error) {
+	bucket, err := s.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := bucket.DownloadToStreamByName(key, &buf); err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return nil, fmt.Errorf("%w: %s", claimcheck.ErrBlobNotFound, key)
+		}
+		return nil, fmt.Errorf("mongo: download file: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ensureIndex creates the unique index on the filenames of the bucket if it
+// was not already created by the GridFSBlobStore.
+func (s *GridFSBlobStore) ensureIndex(ctx context.Context, bucket *gridfs.Bucket) error {
+	s.indexMux.Lock()
+	defer s.indexMux.Unlock()
+
+	if s.indexed {
+		return nil
+	}
+
+	if _, err := bucket.GetFilesCollection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "filename", Value: 1}},
+		Options: options.Index().SetName("goes_blob_key").SetUnique(true),
+	}); err != nil {
+		return fmt.Errorf("mongo: create filename index: %w", err)
+	}
+	s.indexed = true
+
+	return nil
+}
+
+// open opens the bucket with the deadline of ctx. Buckets are opened per
+// operation because the deadlines of a bucket are not safe for concurrent use.
+func (s *GridFSBlobStore) open(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(s.db, options.GridFSBucket().SetName(s.bucket))
+	if err != nil {
+		return nil, fmt.Errorf("mongo: open bucket: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := bucket.SetWriteDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("mongo: set write deadline: %w", err)
+		}
+		if err := bucket.SetReadDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("mongo: set read deadline: %w", err)
+		}
+	}
+
+	return bucket, nil
+}
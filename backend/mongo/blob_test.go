@@ -0,0 +1,91 @@
+//go:build mongo
+
+package mongo_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/modernice/goes/backend/mongo"
+	"github.com/modernice/goes/codec/claimcheck"
+	gomongo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestGridFSBlobStore(t *testing.T) {
+	ctx := context.Background()
+
+	client, err := gomongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGOSTORE_URL")))
+	if err != nil {
+		t.Fatalf("mongo.Connect: %v", err)
+	}
+	defer client.Disconnect(ctx)
+
+	blobs := mongo.NewGridFSBlobStore(client.Database(nextEventDatabase()))
+
+	if _, err := blobs.Get(ctx, "foo"); !errors.Is(err, claimcheck.ErrBlobNotFound) {
+		t.Fatalf("Get() should fail with %q; got %v", claimcheck.ErrBlobNotFound, err)
+	}
+
+	data := bytes.Repeat([]byte("x"), 1024*1024)
+	if err := blobs.Put(ctx, "foo", data); err != nil {
+		t.Fatalf("Put() failed with %q", err)
+	}
+
+	if err := blobs.Put(ctx, "foo", data); err != nil {
+		t.Fatalf("Put() should not fail for existing blobs; got %q", err)
+	}
+
+	got, err := blobs.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Get() failed with %q", err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Get() returned the wrong data")
+	}
+}
+
+func TestGridFSBlobStore_Put_concurrent(t *testing.T) {
+	ctx := context.Background()
+
+	client, err := gomongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGOSTORE_URL")))
+	if err != nil {
+		t.Fatalf("mongo.Connect: %v", err)
+	}
+	defer client.Disconnect(ctx)
+
+	blobs := mongo.NewGridFSBlobStore(client.Database(nextEventDatabase()))
+	data := bytes.Repeat([]byte("x"), 4*1024*1024)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- blobs.Put(ctx, "foo", data)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Put() failed with %q", err)
+		}
+	}
+
+	got, err := blobs.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Get() failed with %q", err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Fatalf("concurrent uploads should not corrupt the blob")
+	}
+}
@@ -0,0 +1,217 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	stdtime "time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/internal/concurrent"
+)
+
+// DefaultResumeCollection is the default collection in which a
+// ChangeStreamBus stores its resume tokens.
+const DefaultResumeCollection = "resume_tokens"
+
+// ChangeStreamBus is an event bus that uses MongoDB change streams on the
+// events collection of an EventStore. Subscribers receive every event that is
+// inserted into the event store, which gives small deployments pub/sub without
+// running a separate message broker:
+//
+//	store := mongo.NewEventStore(enc, mongo.Transactions(true))
+//	bus := mongo.NewChangeStreamBus(store, mongo.Resume("order-service"))
+//
+// Because inserting events into the event store publishes them, the event
+// store must not be wrapped with eventstore.WithBus using a ChangeStreamBus.
+// Publish inserts events into the event store.
+//
+// Change streams require a MongoDB replica set or sharded cluster.
+type ChangeStreamBus struct {
+	store       *EventStore
+	resumeName  string
+	resumeCol   string
+	maxAwaitDur stdtime.Duration
+}
+
+// ChangeStreamOption is an option for a ChangeStreamBus.
+type ChangeStreamOption func(*ChangeStreamBus)
+
+// Resume returns a ChangeStreamOption that persists the resume tokens of
+// subscriptions under the given name. After a restart, a subscription to the
+// same events resumes after the last event that was delivered to the
+// subscriber, so no events are missed in between. Without this option,
+// subscriptions only receive events that are inserted after subscribing.
+//
+// Resume tokens are stored per name and subscribed events. Subscriptions that
+// use the same name and events must not run concurrently.
+func Resume(name string) ChangeStreamOption {
+	return func(b *ChangeStreamBus) {
+		b.resumeName = name
+	}
+}
+
+// ResumeCollection returns a ChangeStreamOption that sets the collection in
+// which resume tokens are stored. Defaults to DefaultResumeCollection.
+func ResumeCollection(name string) ChangeStreamOption {
+	return func(b *ChangeStreamBus) {
+		b.resumeCol = name
+	}
+}
+
+// MaxAwaitTime returns a ChangeStreamOption that sets the maximum time that
+// the server waits for new events before responding to a change stream
+// request.
+func MaxAwaitTime(d stdtime.Duration) ChangeStreamOption {
+	return func(b *ChangeStreamBus) {
+		b.maxAwaitDur = d
+	}
+}
+
+// NewChangeStreamBus returns a ChangeStreamBus that watches the events
+// collection of the given event store.
+func NewChangeStreamBus(store *EventStore, opts ...ChangeStreamOption) *ChangeStreamBus {
+	b := &ChangeStreamBus{
+		store:     store,
+		resumeCol: DefaultResumeCollection,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish inserts the given events into the event store, which publishes them
+// to the subscribers of the bus.
+func (b *ChangeStreamBus) Publish(ctx context.Context, events ...event.Event) error {
+	return b.store.Insert(ctx, events...)
+}
+
+// Subscribe subscribes to the events with the given names. Events are
+// received when they are inserted into the event store.
+func (b *ChangeStreamBus) Subscribe(ctx context.Context, names ...string) (<-chan event.Event, <-chan error, error) {
+	store := b.store
+	if store.isTransactionStore {
+		store = store.root
+	}
+
+	if err := store.connectOnce(ctx); err != nil {
+		return nil, nil, fmt.Errorf("connect: %w", err)
+	}
+
+	key := b.resumeKey(names)
+
+	opts := options.ChangeStream()
+	if b.maxAwaitDur > 0 {
+		opts.SetMaxAwaitTime(b.maxAwaitDur)
+	}
+
+	if key != "" {
+		token, err := b.loadToken(ctx, store, key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load resume token: %w", err)
+		}
+		if token != nil {
+			opts.SetStartAfter(token)
+		}
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.D{
+		{Key: "operationType", Value: "insert"},
+		{Key: "fullDocument.name", Value: bson.D{{Key: "$in", Value: names}}},
+	}}}}
+
+	stream, err := store.entries.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("watch events: %w", err)
+	}
+
+	out := make(chan event.Event)
+	errs, fail := concurrent.Errors(ctx)
+
+	go func() {
+		defer close(out)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			var change struct {
+				FullDocument entry `bson:"fullDocument"`
+			}
+			if err := stream.Decode(&change); err != nil {
+				fail(fmt.Errorf("decode change: %w", err))
+				continue
+			}
+
+			evt, err := change.FullDocument.event(store.enc)
+			if err != nil {
+				fail(err)
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- evt:
+			}
+
+			if key != "" {
+				if err := b.saveToken(ctx, store, key, stream.ResumeToken()); err != nil {
+					fail(fmt.Errorf("save resume token: %w", err))
+				}
+			}
+		}
+
+		if err := stream.Err(); err != nil && !errors.Is(err, context.Canceled) {
+			fail(fmt.Errorf("change stream: %w", err))
+		}
+	}()
+
+	return out, errs, nil
+}
+
+func (b *ChangeStreamBus) resumeKey(names []string) string {
+	if b.resumeName == "" {
+		return ""
+	}
+	sorted := slices.Clone(names)
+	slices.Sort(sorted)
+	return b.resumeName + ":" + strings.Join(sorted, ",")
+}
+
+type resumeToken struct {
+	Key       string       `bson:"_id"`
+	Token     bson.Raw     `bson:"token"`
+	UpdatedAt stdtime.Time `bson:"updatedAt"`
+}
+
+func (b *ChangeStreamBus) loadToken(ctx context.Context, store *EventStore, key string) (bson.Raw, error) {
+	res := store.db.Collection(b.resumeCol).FindOne(ctx, bson.D{{Key: "_id", Value: key}})
+
+	var t resumeToken
+	if err := res.Decode(&t); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("decode document: %w", err)
+	}
+
+	return t.Token, nil
+}
+
+func (b *ChangeStreamBus) saveToken(ctx context.Context, store *EventStore, key string, token bson.Raw) error {
+	if _, err := store.db.Collection(b.resumeCol).ReplaceOne(
+		ctx,
+		bson.D{{Key: "_id", Value: key}},
+		resumeToken{Key: key, Token: token, UpdatedAt: stdtime.Now()},
+		options.Replace().SetUpsert(true),
+	); err != nil {
+		return fmt.Errorf("mongo: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+//go:build mongo
+
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/backend/mongo"
+	"github.com/modernice/goes/event"
+	etest "github.com/modernice/goes/event/test"
+)
+
+func TestChangeStreamBus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store := newReplicaSetStore()
+	bus := mongo.NewChangeStreamBus(store)
+
+	events, errs, err := bus.Subscribe(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	foo := event.New[any]("foo", etest.FooEventData{A: "foo"})
+	bar := event.New[any]("bar", etest.FooEventData{A: "bar"})
+
+	if err := store.Insert(ctx, bar, foo); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		t.Fatalf("timed out waiting for event")
+	case err := <-errs:
+		t.Fatalf("subscription failed: %v", err)
+	case evt := <-events:
+		if evt.ID() != foo.ID() {
+			t.Fatalf("subscriber should receive %q event; got %q", "foo", evt.Name())
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store := newReplicaSetStore()
+	bus := mongo.NewChangeStreamBus(store, mongo.Resume("test"))
+
+	subCtx, cancelSub := context.WithCancel(ctx)
+	events, _, err := bus.Subscribe(subCtx, "foo")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	first := event.New[any]("foo", etest.FooEventData{A: "first"})
+	if err := bus.Publish(ctx, first); err != nil {
+		t.Fatalf("Publish failed with %q", err)
+	}
+
+	if evt := <-events; evt.ID() != first.ID() {
+		t.Fatalf("subscriber should receive the first event")
+	}
+
+	// Wait until the resume token of the first event has been saved.
+	time.Sleep(200 * time.Millisecond)
+	cancelSub()
+
+	second := event.New[any]("foo", etest.FooEventData{A: "second"})
+	if err := bus.Publish(ctx, second); err != nil {
+		t.Fatalf("Publish failed with %q", err)
+	}
+
+	events, _, err = bus.Subscribe(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		t.Fatalf("timed out waiting for event")
+	case evt := <-events:
+		if evt.ID() != second.ID() {
+			t.Fatalf("resumed subscription should receive the event that was published in between")
+		}
+	}
+}
+
+func newReplicaSetStore() *mongo.EventStore {
+	return mongo.NewEventStore(
+		etest.NewEncoder(),
+		mongo.URL(os.Getenv("MONGOREPLSTORE_URL")),
+		mongo.Transactions(true),
+		mongo.Database(nextEventDatabase()),
+	)
+}
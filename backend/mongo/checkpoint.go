@@ -0,0 +1,78 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/projection"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ projection.CheckpointStore = &CheckpointStore{}
+
+// CheckpointStore is a MongoDB backed projection.CheckpointStore. Each
+// checkpoint is stored as a single document that uses the name of the
+// projection as its "_id".
+type CheckpointStore struct {
+	col *mongo.Collection
+}
+
+type checkpointEntry struct {
+	Projection string      `bson:"_id"`
+	TimeNano   int64       `bson:"timeNano"`
+	Events     []uuid.UUID `bson:"events"`
+}
+
+// NewCheckpointStore returns a MongoDB backed checkpoint store that stores the
+// checkpoints in the given collection.
+func NewCheckpointStore(col *mongo.Collection) *CheckpointStore {
+	return &CheckpointStore{col: col}
+}
+
+// Collection returns the MongoDB collection of the checkpoints.
+func (s *CheckpointStore) Collection() *mongo.Collection {
+	return s.col
+}
+
+// Checkpoint returns the checkpoint of the given projection, or the zero
+// checkpoint if no checkpoint was saved for the projection.
+func (s *CheckpointStore) Checkpoint(ctx context.Context, name string) (projection.Checkpoint, error) {
+	var e checkpointEntry
+	if err := s.col.FindOne(ctx, bson.D{{Key: "_id", Value: name}}).Decode(&e); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return projection.Checkpoint{}, nil
+		}
+		return projection.Checkpoint{}, fmt.Errorf("mongo: decode result: %w", err)
+	}
+
+	var cp projection.Checkpoint
+	if e.TimeNano != 0 {
+		cp.Time = stdtime.Unix(0, e.TimeNano)
+	}
+	cp.Events = e.Events
+
+	return cp, nil
+}
+
+// SaveCheckpoint saves the checkpoint of the given projection using the
+// MongoDB "ReplaceOne" command with the upsert option set to true.
+func (s *CheckpointStore) SaveCheckpoint(ctx context.Context, name string, cp projection.Checkpoint) error {
+	e := checkpointEntry{
+		Projection: name,
+		Events:     cp.Events,
+	}
+	if !cp.Time.IsZero() {
+		e.TimeNano = cp.Time.UnixNano()
+	}
+
+	if _, err := s.col.ReplaceOne(ctx, bson.D{{Key: "_id", Value: name}}, e, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("mongo: %w", err)
+	}
+
+	return nil
+}
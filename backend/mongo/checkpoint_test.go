@@ -0,0 +1,51 @@
+//go:build mongo
+
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/backend/mongo"
+	"github.com/modernice/goes/backend/mongo/mongotest"
+	"github.com/modernice/goes/projection"
+	gomongo "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestCheckpointStore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := gomongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGOSTORE_URL")))
+	if err != nil {
+		t.Fatalf("connect to mongo: %v", err)
+	}
+
+	store := mongo.NewCheckpointStore(client.Database(mongotest.UniqueName("checkpoint_")).Collection("checkpoints"))
+
+	cp, err := store.Checkpoint(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Checkpoint failed with %q", err)
+	}
+
+	if !cp.Equal(projection.Checkpoint{}) {
+		t.Fatalf("Checkpoint should return the zero checkpoint for unknown projections; got %v", cp)
+	}
+
+	want := projection.Checkpoint{Time: time.Now(), Events: []uuid.UUID{uuid.New(), uuid.New()}}
+	if err := store.SaveCheckpoint(ctx, "foo", want); err != nil {
+		t.Fatalf("SaveCheckpoint failed with %q", err)
+	}
+
+	if cp, err = store.Checkpoint(ctx, "foo"); err != nil {
+		t.Fatalf("Checkpoint failed with %q", err)
+	}
+
+	if !cp.Equal(want) {
+		t.Fatalf("Checkpoint should return %v; got %v", want, cp)
+	}
+}
@@ -1,10 +1,14 @@
 package mongo_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/modernice/goes/aggregate"
 	"github.com/modernice/goes/backend/mongo"
+	gomongo "go.mongodb.org/mongo-driver/mongo"
 )
 
 func TestVersionError_IsInconsistencyError(t *testing.T) {
@@ -18,3 +22,33 @@ func TestVersionError_IsInconsistencyError(t *testing.T) {
 		t.Fatalf("aggregate.IsConsistencyError() should return %v for a mongo.CommandError; got %v", true, got)
 	}
 }
+
+func TestIsTransientError(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"nil":                       {err: nil, want: false},
+		"generic error":             {err: errors.New("foo"), want: false},
+		"canceled context":          {err: context.Canceled, want: false},
+		"expired context":           {err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: false},
+		"version error":             {err: mongo.VersionError{}, want: false},
+		"duplicate key":             {err: gomongo.CommandError{Code: 11000}, want: false},
+		"primary stepdown":          {err: gomongo.CommandError{Code: 189}, want: true},
+		"not writable primary":      {err: fmt.Errorf("insert: %w", gomongo.CommandError{Code: 10107}), want: true},
+		"network timeout":           {err: gomongo.CommandError{Code: 89}, want: true},
+		"transient transaction":     {err: gomongo.CommandError{Labels: []string{"TransientTransactionError"}}, want: true},
+		"wrapped transient command": {err: mongo.CommandError(gomongo.CommandError{Labels: []string{"UnknownTransactionCommitResult"}}), want: true},
+		"write concern stepdown": {err: gomongo.WriteException{
+			WriteConcernError: &gomongo.WriteConcernError{Code: 11602},
+		}, want: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := mongo.IsTransientError(tt.err); got != tt.want {
+				t.Fatalf("IsTransientError(%v) should return %v; got %v", tt.err, tt.want, got)
+			}
+		})
+	}
+}
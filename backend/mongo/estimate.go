@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ eventstore.Estimator = (*EventStore)(nil)
+
+// Estimate counts the events that match the given query, without fetching
+// them. The returned Size is the total BSON size of the matching documents.
+func (s *EventStore) Estimate(ctx context.Context, q event.Query) (eventstore.QueryEstimate, error) {
+	if s.isTransactionStore {
+		return s.root.Estimate(ctx, q)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return eventstore.QueryEstimate{}, fmt.Errorf("connect: %w", err)
+	}
+
+	cur, err := s.entries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: makeFilter(q)}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "events", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "size", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$bsonSize", Value: "$$ROOT"}}}}},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return eventstore.QueryEstimate{}, fmt.Errorf("mongo: %w", err)
+	}
+
+	var result []struct {
+		Events int64 `bson:"events"`
+		Size   int64 `bson:"size"`
+	}
+	if err := cur.All(ctx, &result); err != nil {
+		return eventstore.QueryEstimate{}, fmt.Errorf("decode estimate: %w", err)
+	}
+
+	est := eventstore.QueryEstimate{Exact: true}
+	if len(result) > 0 {
+		est.Events = result[0].Events
+		est.Size = result[0].Size
+	}
+
+	return est, nil
+}
@@ -0,0 +1,49 @@
+//go:build mongo
+
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/modernice/goes/backend/mongo"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	etest "github.com/modernice/goes/event/test"
+)
+
+func TestEventStore_Estimate(t *testing.T) {
+	s := mongo.NewEventStore(etest.NewEncoder(), mongo.URL(os.Getenv("MONGOSTORE_URL")), mongo.Database(nextEventDatabase()))
+
+	events := []event.Event{
+		event.New[any]("foo", etest.FooEventData{}),
+		event.New[any]("foo", etest.FooEventData{}),
+		event.New[any]("bar", etest.BarEventData{}),
+	}
+
+	if err := s.Insert(context.Background(), events...); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	est, err := s.Estimate(context.Background(), query.New(query.Name("foo")))
+	if err != nil {
+		t.Fatalf("Estimate failed with %q", err)
+	}
+
+	if est.Events != 2 {
+		t.Fatalf("Estimate should report %d events; got %d", 2, est.Events)
+	}
+
+	if est.Size <= 0 {
+		t.Fatalf("Estimate should report the size of the events")
+	}
+
+	if est, err = s.Estimate(context.Background(), query.New(query.Name("baz"))); err != nil {
+		t.Fatalf("Estimate failed with %q", err)
+	}
+
+	if est.Events != 0 || est.Size != 0 {
+		t.Fatalf("Estimate should report no events; got %v", est)
+	}
+}
@@ -0,0 +1,131 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ eventstore.Explainer = (*EventStore)(nil)
+
+// Explain returns the query plan that MongoDB chooses for the given query, by
+// running the "explain" command for the "find" command that Query would run.
+// By default, the query is not executed. Use eventstore.ExplainExecution to
+// also collect execution statistics.
+func (s *EventStore) Explain(ctx context.Context, q event.Query, opts ...eventstore.ExplainOption) (eventstore.QueryPlan, error) {
+	if s.isTransactionStore {
+		return s.root.Explain(ctx, q, opts...)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return eventstore.QueryPlan{}, fmt.Errorf("connect: %w", err)
+	}
+
+	cfg := eventstore.NewExplainConfig(opts...)
+
+	findOpts := options.Find().SetAllowDiskUse(true)
+	findOpts = applySortings(findOpts, q.Sortings()...)
+	for _, interceptor := range s.queryInterceptors {
+		findOpts = interceptor(findOpts)
+	}
+
+	find := bson.D{
+		{Key: "find", Value: s.entries.Name()},
+		{Key: "filter", Value: makeFilter(q)},
+	}
+	if findOpts.Sort != nil {
+		find = append(find, bson.E{Key: "sort", Value: findOpts.Sort})
+	}
+	if findOpts.Hint != nil {
+		find = append(find, bson.E{Key: "hint", Value: findOpts.Hint})
+	}
+	if findOpts.AllowDiskUse != nil {
+		find = append(find, bson.E{Key: "allowDiskUse", Value: *findOpts.AllowDiskUse})
+	}
+
+	verbosity := "queryPlanner"
+	if cfg.Execute {
+		verbosity = "executionStats"
+	}
+
+	var raw bson.M
+	if err := s.db.RunCommand(ctx, bson.D{
+		{Key: "explain", Value: find},
+		{Key: "verbosity", Value: verbosity},
+	}).Decode(&raw); err != nil {
+		return eventstore.QueryPlan{}, fmt.Errorf("mongo: explain: %w", err)
+	}
+
+	return parseQueryPlan(raw), nil
+}
+
+func parseQueryPlan(raw bson.M) eventstore.QueryPlan {
+	plan := eventstore.QueryPlan{Raw: raw}
+
+	if planner, ok := raw["queryPlanner"].(bson.M); ok {
+		winning, _ := planner["winningPlan"].(bson.M)
+		// MongoDB 7.0+ nests the plan of the slot-based execution engine.
+		if qp, ok := winning["queryPlan"].(bson.M); ok {
+			winning = qp
+		}
+		walkPlanStage(&plan, winning)
+	}
+
+	if stats, ok := raw["executionStats"].(bson.M); ok {
+		plan.Executed = true
+		plan.Returned = toInt64(stats["nReturned"])
+		plan.KeysExamined = toInt64(stats["totalKeysExamined"])
+		plan.DocsExamined = toInt64(stats["totalDocsExamined"])
+	}
+
+	return plan
+}
+
+func walkPlanStage(plan *eventstore.QueryPlan, stage bson.M) {
+	if stage == nil {
+		return
+	}
+
+	if name, ok := stage["stage"].(string); ok {
+		plan.Stages = append(plan.Stages, name)
+		switch name {
+		case "COLLSCAN":
+			plan.CollectionScan = true
+		case "SORT":
+			plan.InMemorySort = true
+		}
+	}
+
+	if index, ok := stage["indexName"].(string); ok {
+		plan.Indexes = append(plan.Indexes, index)
+	}
+
+	if input, ok := stage["inputStage"].(bson.M); ok {
+		walkPlanStage(plan, input)
+	}
+
+	if inputs, ok := stage["inputStages"].(bson.A); ok {
+		for _, input := range inputs {
+			if input, ok := input.(bson.M); ok {
+				walkPlanStage(plan, input)
+			}
+		}
+	}
+}
+
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
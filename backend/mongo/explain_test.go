@@ -0,0 +1,64 @@
+package mongo
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseQueryPlan(t *testing.T) {
+	plan := parseQueryPlan(bson.M{
+		"queryPlanner": bson.M{
+			"winningPlan": bson.M{
+				"stage": "SORT",
+				"inputStage": bson.M{
+					"stage": "FETCH",
+					"inputStage": bson.M{
+						"stage":     "IXSCAN",
+						"indexName": "goes_name",
+					},
+				},
+			},
+		},
+		"executionStats": bson.M{
+			"nReturned":         int32(3),
+			"totalKeysExamined": int32(3),
+			"totalDocsExamined": int64(3),
+		},
+	})
+
+	if want := []string{"SORT", "FETCH", "IXSCAN"}; !slices.Equal(want, plan.Stages) {
+		t.Fatalf("Stages should be %v; is %v", want, plan.Stages)
+	}
+
+	if !plan.UsesIndex() || !slices.Equal(plan.Indexes, []string{"goes_name"}) {
+		t.Fatalf("plan should use the %q index; uses %v", "goes_name", plan.Indexes)
+	}
+
+	if !plan.InMemorySort {
+		t.Fatalf("plan should sort in memory")
+	}
+
+	if !plan.Executed || plan.Returned != 3 || plan.KeysExamined != 3 || plan.DocsExamined != 3 {
+		t.Fatalf("unexpected execution stats: %+v", plan)
+	}
+}
+
+func TestParseQueryPlan_collectionScan(t *testing.T) {
+	plan := parseQueryPlan(bson.M{
+		"queryPlanner": bson.M{
+			"winningPlan": bson.M{
+				"queryPlan": bson.M{"stage": "COLLSCAN"},
+			},
+		},
+	})
+
+	if !plan.CollectionScan || plan.UsesIndex() {
+		t.Fatalf("plan should scan the collection: %+v", plan)
+	}
+
+	if plan.Executed {
+		t.Fatalf("plan should not be executed")
+	}
+}
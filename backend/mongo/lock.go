@@ -0,0 +1,166 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ eventstore.Locker = (*EventStore)(nil)
+
+type lockEntry struct {
+	ID            string       `bson:"_id"`
+	AggregateName string       `bson:"aggregateName"`
+	AggregateID   uuid.UUID    `bson:"aggregateId"`
+	Token         int64        `bson:"token"`
+	Expires       stdtime.Time `bson:"expires"`
+}
+
+// LockCollection returns an Option that specifies the name of the Collection
+// where the locks of aggregates are stored in (see eventstore.Locker).
+//
+// Defaults to "locks".
+func LockCollection(name string) EventStoreOption {
+	return func(s *EventStore) {
+		s.locksCol = name
+	}
+}
+
+// Lock acquires a lease on the given aggregate. A lease is a document in the
+// lock collection that is atomically updated using findAndModify, so that only
+// one lease can be active per aggregate. The expiry of leases is based on the
+// clock of the process that acquires the lease, so the clocks of the processes
+// that use the same locks should be synchronized.
+func (s *EventStore) Lock(ctx context.Context, ref event.AggregateRef, ttl stdtime.Duration) (eventstore.Lease, error) {
+	if s.isTransactionStore {
+		return s.root.Lock(ctx, ref, ttl)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return eventstore.Lease{}, fmt.Errorf("connect: %w", err)
+	}
+
+	now := stdtime.Now()
+
+	// Matches only expired or released leases. If the lease is active, the
+	// upsert fails with a duplicate key error.
+	res := s.locks.FindOneAndUpdate(ctx, bson.D{
+		{Key: "_id", Value: lockID(ref)},
+		{Key: "expires", Value: bson.D{{Key: "$lte", Value: now}}},
+	}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "aggregateName", Value: ref.Name},
+			{Key: "aggregateId", Value: ref.ID},
+			{Key: "expires", Value: now.Add(ttl)},
+		}},
+		{Key: "$inc", Value: bson.D{{Key: "token", Value: int64(1)}}},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After))
+
+	var e lockEntry
+	if err := res.Decode(&e); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return eventstore.Lease{}, fmt.Errorf("%w [aggregate=%s]", eventstore.ErrLocked, ref)
+		}
+		return eventstore.Lease{}, fmt.Errorf("mongo: %w", err)
+	}
+
+	return e.lease(), nil
+}
+
+// Renew extends the given lease if it is still active.
+func (s *EventStore) Renew(ctx context.Context, lease eventstore.Lease, ttl stdtime.Duration) (eventstore.Lease, error) {
+	if s.isTransactionStore {
+		return s.root.Renew(ctx, lease, ttl)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return lease, fmt.Errorf("connect: %w", err)
+	}
+
+	now := stdtime.Now()
+
+	res := s.locks.FindOneAndUpdate(ctx, bson.D{
+		{Key: "_id", Value: lockID(lease.Aggregate)},
+		{Key: "token", Value: lease.Token},
+		{Key: "expires", Value: bson.D{{Key: "$gt", Value: now}}},
+	}, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "expires", Value: now.Add(ttl)}}},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	var e lockEntry
+	if err := res.Decode(&e); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return lease, fmt.Errorf("%w [aggregate=%s, token=%d]", eventstore.ErrLockLost, lease.Aggregate, lease.Token)
+		}
+		return lease, fmt.Errorf("mongo: %w", err)
+	}
+
+	return e.lease(), nil
+}
+
+// Unlock releases the given lease. The lease document is kept, so that the
+// tokens of the aggregate keep increasing.
+func (s *EventStore) Unlock(ctx context.Context, lease eventstore.Lease) error {
+	if s.isTransactionStore {
+		return s.root.Unlock(ctx, lease)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	if _, err := s.locks.UpdateOne(ctx, bson.D{
+		{Key: "_id", Value: lockID(lease.Aggregate)},
+		{Key: "token", Value: lease.Token},
+	}, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "expires", Value: stdtime.Time{}}}},
+	}); err != nil {
+		return fmt.Errorf("mongo: %w", err)
+	}
+
+	return nil
+}
+
+// Lease returns the active lease of the given aggregate.
+func (s *EventStore) Lease(ctx context.Context, ref event.AggregateRef) (eventstore.Lease, bool, error) {
+	if s.isTransactionStore {
+		return s.root.Lease(ctx, ref)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return eventstore.Lease{}, false, fmt.Errorf("connect: %w", err)
+	}
+
+	var e lockEntry
+	if err := s.locks.FindOne(ctx, bson.D{
+		{Key: "_id", Value: lockID(ref)},
+		{Key: "expires", Value: bson.D{{Key: "$gt", Value: stdtime.Now()}}},
+	}).Decode(&e); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return eventstore.Lease{}, false, nil
+		}
+		return eventstore.Lease{}, false, fmt.Errorf("mongo: %w", err)
+	}
+
+	return e.lease(), true, nil
+}
+
+func (e lockEntry) lease() eventstore.Lease {
+	return eventstore.Lease{
+		Aggregate: event.AggregateRef{Name: e.AggregateName, ID: e.AggregateID},
+		Token:     e.Token,
+		Expires:   e.Expires,
+	}
+}
+
+func lockID(ref event.AggregateRef) string {
+	return ref.String()
+}
@@ -0,0 +1,66 @@
+//go:build mongo
+
+package mongo_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/backend/mongo"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+)
+
+func TestEventStore_Lock(t *testing.T) {
+	ctx := context.Background()
+	store := mongo.NewEventStore(codec.New(), mongo.URL(os.Getenv("MONGOSTORE_URL")), mongo.Database(nextEventDatabase()))
+	ref := event.AggregateRef{Name: "foo", ID: uuid.New()}
+
+	if _, ok, err := store.Lease(ctx, ref); err != nil || ok {
+		t.Fatalf("Lease() should return no lease; got ok=%v, err=%v", ok, err)
+	}
+
+	lease, err := store.Lock(ctx, ref, time.Minute)
+	if err != nil {
+		t.Fatalf("Lock() failed with %q", err)
+	}
+
+	if lease.Token != 1 {
+		t.Fatalf("first lease should have token %d; got %d", 1, lease.Token)
+	}
+
+	if _, err := store.Lock(ctx, ref, time.Minute); !errors.Is(err, eventstore.ErrLocked) {
+		t.Fatalf("Lock() should fail with %q; got %v", eventstore.ErrLocked, err)
+	}
+
+	active, ok, err := store.Lease(ctx, ref)
+	if err != nil || !ok || active.Token != lease.Token {
+		t.Fatalf("Lease() should return the active lease; got %v, ok=%v, err=%v", active, ok, err)
+	}
+
+	if lease, err = store.Renew(ctx, lease, time.Minute); err != nil {
+		t.Fatalf("Renew() failed with %q", err)
+	}
+
+	if err := store.Unlock(ctx, lease); err != nil {
+		t.Fatalf("Unlock() failed with %q", err)
+	}
+
+	if _, err := store.Renew(ctx, lease, time.Minute); !errors.Is(err, eventstore.ErrLockLost) {
+		t.Fatalf("Renew() should fail with %q after Unlock(); got %v", eventstore.ErrLockLost, err)
+	}
+
+	next, err := store.Lock(ctx, ref, time.Minute)
+	if err != nil {
+		t.Fatalf("Lock() failed with %q", err)
+	}
+
+	if next.Token != 2 {
+		t.Fatalf("second lease should have token %d; got %d", 2, next.Token)
+	}
+}
@@ -0,0 +1,182 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event/eventstore"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ eventstore.Maintainer = (*EventStore)(nil)
+
+type storageStats struct {
+	StorageStats struct {
+		Count           int64            `bson:"count"`
+		Size            int64            `bson:"size"`
+		StorageSize     int64            `bson:"storageSize"`
+		FreeStorageSize int64            `bson:"freeStorageSize"`
+		TotalIndexSize  int64            `bson:"totalIndexSize"`
+		IndexSizes      map[string]int64 `bson:"indexSizes"`
+	} `bson:"storageStats"`
+}
+
+type nameStats struct {
+	Name   string `bson:"_id"`
+	Events int64  `bson:"events"`
+	Size   int64  `bson:"size"`
+}
+
+// Stats returns storage statistics of the event collection, including the
+// number and size of the events per event name. The size of the events is
+// computed from the BSON documents, which requires MongoDB 4.4 or later.
+//
+// Computing the statistics per event name scans the entire event collection,
+// so Stats should not be called frequently on large collections.
+func (s *EventStore) Stats(ctx context.Context) (eventstore.Stats, error) {
+	if s.isTransactionStore {
+		return s.root.Stats(ctx)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return eventstore.Stats{}, fmt.Errorf("connect: %w", err)
+	}
+
+	stats := eventstore.Stats{IndexSizes: make(map[string]int64)}
+
+	cur, err := s.entries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$collStats", Value: bson.D{{Key: "storageStats", Value: bson.D{}}}}},
+	})
+	if err != nil {
+		return stats, fmt.Errorf("mongo: %w", err)
+	}
+
+	var collStats []storageStats
+	if err := cur.All(ctx, &collStats); err != nil {
+		return stats, fmt.Errorf("decode collection stats: %w", err)
+	}
+
+	// A sharded collection returns the stats of every shard.
+	for _, shard := range collStats {
+		stats.Events += shard.StorageStats.Count
+		stats.Size += shard.StorageStats.Size
+		stats.StorageSize += shard.StorageStats.StorageSize
+		stats.FreeStorageSize += shard.StorageStats.FreeStorageSize
+		stats.IndexSize += shard.StorageStats.TotalIndexSize
+		for name, size := range shard.StorageStats.IndexSizes {
+			stats.IndexSizes[name] += size
+		}
+	}
+
+	if cur, err = s.entries.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$name"},
+			{Key: "events", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "size", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$bsonSize", Value: "$$ROOT"}}}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}, options.Aggregate().SetAllowDiskUse(true)); err != nil {
+		return stats, fmt.Errorf("mongo: %w", err)
+	}
+
+	var names []nameStats
+	if err := cur.All(ctx, &names); err != nil {
+		return stats, fmt.Errorf("decode event name stats: %w", err)
+	}
+
+	stats.Names = make([]eventstore.NameStats, len(names))
+	for i, n := range names {
+		stats.Names[i] = eventstore.NameStats(n)
+	}
+
+	return stats, nil
+}
+
+// Compact runs the "compact" command on the event and state collections to
+// release their free storage to the operating system. Depending on the MongoDB
+// version, compaction may block operations on the collections, so it should be
+// run within a low-traffic window:
+//
+//	w := eventstore.MaintenanceWindow{Start: 2 * time.Hour, End: 4 * time.Hour}
+//	errs := eventstore.RunMaintenance(ctx, w, store.Compact)
+func (s *EventStore) Compact(ctx context.Context) error {
+	if s.isTransactionStore {
+		return s.root.Compact(ctx)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	for _, col := range []string{s.entriesCol, s.statesCol} {
+		if err := s.db.RunCommand(ctx, bson.D{{Key: "compact", Value: col}}).Err(); err != nil {
+			return fmt.Errorf("compact %q collection: %w", col, err)
+		}
+	}
+
+	return nil
+}
+
+// OrphanedSnapshots returns the aggregates that have snapshots in the given
+// snapshot store, but no events in the event store. Orphaned snapshots are
+// usually left behind when the events of an aggregate are deleted without its
+// snapshots.
+func (s *EventStore) OrphanedSnapshots(ctx context.Context, snapshots *SnapshotStore) ([]aggregate.Ref, error) {
+	if s.isTransactionStore {
+		return s.root.OrphanedSnapshots(ctx, snapshots)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+
+	if err := snapshots.connectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("connect to snapshot store: %w", err)
+	}
+
+	cur, err := snapshots.col.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{{Key: "_id", Value: bson.D{
+			{Key: "name", Value: "$aggregateName"},
+			{Key: "id", Value: "$aggregateId"},
+		}}}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("mongo: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	var orphans []aggregate.Ref
+	for cur.Next(ctx) {
+		var group struct {
+			Ref struct {
+				Name string    `bson:"name"`
+				ID   uuid.UUID `bson:"id"`
+			} `bson:"_id"`
+		}
+		if err := cur.Decode(&group); err != nil {
+			return orphans, fmt.Errorf("decode snapshot aggregate: %w", err)
+		}
+
+		count, err := s.entries.CountDocuments(ctx, bson.D{
+			{Key: "aggregateName", Value: group.Ref.Name},
+			{Key: "aggregateId", Value: group.Ref.ID},
+		}, options.Count().SetLimit(1))
+		if err != nil {
+			return orphans, fmt.Errorf("count events of %s(%s): %w", group.Ref.Name, group.Ref.ID, err)
+		}
+
+		if count == 0 {
+			orphans = append(orphans, aggregate.Ref{Name: group.Ref.Name, ID: group.Ref.ID})
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		return orphans, fmt.Errorf("cursor: %w", err)
+	}
+
+	return orphans, nil
+}
@@ -0,0 +1,86 @@
+//go:build mongo
+
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/backend/mongo"
+	"github.com/modernice/goes/event"
+	etest "github.com/modernice/goes/event/test"
+)
+
+func TestEventStore_Stats(t *testing.T) {
+	s := mongo.NewEventStore(etest.NewEncoder(), mongo.URL(os.Getenv("MONGOSTORE_URL")), mongo.Database(nextEventDatabase()))
+
+	events := []event.Event{
+		event.New[any]("foo", etest.FooEventData{}),
+		event.New[any]("foo", etest.FooEventData{}),
+		event.New[any]("bar", etest.BarEventData{}),
+	}
+
+	if err := s.Insert(context.Background(), events...); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	stats, err := s.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats failed with %q", err)
+	}
+
+	if stats.Events != 3 {
+		t.Fatalf("Stats should report %d events; got %d", 3, stats.Events)
+	}
+
+	if len(stats.Names) != 2 {
+		t.Fatalf("Stats should report %d event names; got %d", 2, len(stats.Names))
+	}
+
+	if stats.Names[0].Name != "bar" || stats.Names[0].Events != 1 {
+		t.Fatalf("Stats should report 1 %q event; got %v", "bar", stats.Names[0])
+	}
+
+	if stats.Names[1].Name != "foo" || stats.Names[1].Events != 2 {
+		t.Fatalf("Stats should report 2 %q events; got %v", "foo", stats.Names[1])
+	}
+
+	if stats.Names[1].Size <= 0 {
+		t.Fatalf("Stats should report the size of %q events", "foo")
+	}
+}
+
+func TestEventStore_OrphanedSnapshots(t *testing.T) {
+	s := mongo.NewEventStore(etest.NewEncoder(), mongo.URL(os.Getenv("MONGOSTORE_URL")), mongo.Database(nextEventDatabase()))
+	snapshots := newStore().(*mongo.SnapshotStore)
+
+	live := aggregate.New("foo", uuid.New())
+	orphan := aggregate.New("foo", uuid.New())
+
+	if err := s.Insert(context.Background(), event.New[any]("foo", etest.FooEventData{}, event.Aggregate(live.AggregateID(), live.AggregateName(), 1))); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	for _, a := range []*aggregate.Base{live, orphan} {
+		snap, err := snapshot.New(a)
+		if err != nil {
+			t.Fatalf("create snapshot: %v", err)
+		}
+		if err := snapshots.Save(context.Background(), snap); err != nil {
+			t.Fatalf("save snapshot: %v", err)
+		}
+	}
+
+	orphans, err := s.OrphanedSnapshots(context.Background(), snapshots)
+	if err != nil {
+		t.Fatalf("OrphanedSnapshots failed with %q", err)
+	}
+
+	if len(orphans) != 1 || orphans[0].ID != orphan.AggregateID() {
+		t.Fatalf("OrphanedSnapshots should return %v; got %v", orphan.AggregateID(), orphans)
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/modernice/goes/backend/mongo"
 	"github.com/modernice/goes/backend/mongo/mongotest"
 	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
 	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
 	gomongo "go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -84,3 +86,36 @@ func TestCollection(t *testing.T) {
 		t.Errorf("expected store.Collection().Name() to return %q; got %q", "custom", col.Name())
 	}
 }
+
+func TestQueryBatchSize(t *testing.T) {
+	ctx := context.Background()
+
+	store := mongo.NewEventStore(
+		test.NewEncoder(),
+		mongo.QueryBatchSize(2),
+		mongo.URL(os.Getenv("MONGOSTORE_URL")),
+		mongo.Database(nextEventDatabase()),
+	)
+
+	events := make([]event.Event, 5)
+	for i := range events {
+		events[i] = event.New("foo", test.FooEventData{A: "foo"}, event.Aggregate(uuid.New(), "foo", 1)).Any()
+	}
+	if err := store.Insert(ctx, events...); err != nil {
+		t.Fatalf("store.Insert: %#v", err)
+	}
+
+	str, errs, err := store.Query(ctx, query.New())
+	if err != nil {
+		t.Fatalf("store.Query: %#v", err)
+	}
+
+	result, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		t.Fatalf("drain events: %v", err)
+	}
+
+	if len(result) != len(events) {
+		t.Fatalf("query should return %d events across multiple batches; got %d", len(events), len(result))
+	}
+}
@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
+)
+
+// transientCodes are the codes of server errors that are caused by elections,
+// shutdowns and network failures within a replica set or sharded cluster.
+var transientCodes = [...]int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	262,   // ExceededTimeLimit
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// IsTransientError reports whether err is a transient MongoDB error that may
+// not occur again if the failed operation is retried, e.g. a network error, a
+// timeout of the driver, or a stepdown of the primary. Errors that are caused
+// by the canceled or expired context of the caller are not transient.
+//
+// Use IsTransientError with eventstore.WithRetry to retry the operations of an
+// EventStore that fail with transient errors:
+//
+//	store := eventstore.WithRetry(mongo.NewEventStore(enc), mongo.IsTransientError)
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var cmdError CommandError
+	if errors.As(err, &cmdError) {
+		err = cmdError.CommandError()
+	}
+
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+
+	var serverError mongo.ServerError
+	if !errors.As(err, &serverError) {
+		return false
+	}
+
+	if serverError.HasErrorLabel(driver.TransientTransactionError) ||
+		serverError.HasErrorLabel(driver.UnknownTransactionCommitResult) ||
+		serverError.HasErrorLabel(driver.RetryableWriteError) {
+		return true
+	}
+
+	for _, code := range transientCodes {
+		if serverError.HasErrorCode(code) {
+			return true
+		}
+	}
+
+	return false
+}
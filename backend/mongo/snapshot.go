@@ -20,7 +20,9 @@ import (
 
 var (
 	// ErrNotFound is returned when a snapshot can't be found in the database.
-	ErrNotFound = errors.New("snapshot not found")
+	// ErrNotFound is snapshot.ErrNotFound, so that callers can check for
+	// missing snapshots independently of the snapshot store.
+	ErrNotFound = snapshot.ErrNotFound
 )
 
 // SnapshotStore is the MongoDB implementation of a snapshot store.
@@ -201,8 +203,21 @@ func (s *SnapshotStore) Limit(ctx context.Context, name string, id uuid.UUID, v
 // snapshot.Query. The channels are closed when there are no more Snapshots or
 // an error occurs, respectively.
 func (s *SnapshotStore) Query(ctx context.Context, q snapshot.Query) (<-chan snapshot.Snapshot, <-chan error, error) {
+	return s.query(ctx, q, options.Find())
+}
+
+// QueryMetadata implements snapshot.MetadataQuerier. QueryMetadata queries
+// Snapshots like Query does, but does not fetch their states from the database.
+func (s *SnapshotStore) QueryMetadata(ctx context.Context, q snapshot.Query) (<-chan snapshot.Snapshot, <-chan error, error) {
+	return s.query(ctx, q, options.Find().SetProjection(bson.D{{Key: "data", Value: 0}}))
+}
+
+func (s *SnapshotStore) query(ctx context.Context, q snapshot.Query, opts *options.FindOptions) (<-chan snapshot.Snapshot, <-chan error, error) {
+	if err := s.connectOnce(ctx); err != nil {
+		return nil, nil, fmt.Errorf("connect: %w", err)
+	}
+
 	filter := makeSnapshotFilter(q)
-	opts := options.Find()
 	applySnapshotSortings(opts, q.Sortings()...)
 	cur, err := s.col.Find(ctx, filter, opts)
 	if err != nil {
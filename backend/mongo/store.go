@@ -37,6 +37,11 @@ const (
 	// should return an error if anything goes wrong, causing the transaction to
 	// abort.
 	PostInsert = TransactionHook("post:insert")
+
+	// DefaultMaxPayloadSize is the default limit for the size of encoded event
+	// data. MongoDB rejects documents larger than 16MiB; the remaining 64KiB
+	// are reserved for the other fields of the stored event.
+	DefaultMaxPayloadSize = 16*1024*1024 - 64*1024
 )
 
 // EventStore is a type that provides an interface to store, retrieve, and
@@ -54,18 +59,22 @@ type EventStore struct {
 	dbname            string
 	entriesCol        string
 	statesCol         string
+	locksCol          string
 	noIndex           bool
 	transactions      bool
 	validateVersions  bool
+	maxPayloadSize    int
 	additionalIndices []mongo.IndexModel
 	preInsertHooks    []func(TransactionContext) error
 	postInsertHooks   []func(TransactionContext) error
 	queryInterceptors []func(*options.FindOptions) *options.FindOptions
+	queryBatchSize    int32
 
 	client  *mongo.Client
 	db      *mongo.Database
 	entries *mongo.Collection
 	states  *mongo.Collection
+	locks   *mongo.Collection
 
 	isTransactionStore bool
 	tx                 *transaction
@@ -230,6 +239,19 @@ func ValidateVersions(v bool) EventStoreOption {
 	}
 }
 
+// MaxPayloadSize returns an Option that limits the size of encoded event data.
+// Insert returns a *codec.PayloadSizeError for events whose encoded data
+// exceeds the limit, instead of failing deep inside the MongoDB driver. A limit
+// <= 0 disables the limit. Limits of the codec.Encoding, if it implements
+// codec.SizeValidator, are validated in addition to this limit.
+//
+// Defaults to DefaultMaxPayloadSize.
+func MaxPayloadSize(limit int) EventStoreOption {
+	return func(s *EventStore) {
+		s.maxPayloadSize = limit
+	}
+}
+
 // NoIndex returns an option to completely disable index creation when
 // connecting to the event bus.
 func NoIndex(ni bool) EventStoreOption {
@@ -260,6 +282,18 @@ func WithQueryOptions(fn func(*options.FindOptions) *options.FindOptions) EventS
 	}
 }
 
+// QueryBatchSize returns an EventStoreOption that sets the batch size of the
+// MongoDB cursors of queries, which is the maximum number of events that are
+// fetched from the database per round trip. Larger batches increase the
+// throughput of large replays at the cost of memory. A size <= 0 uses the
+// default of the MongoDB driver. Use eventstore.WithPrefetch to additionally
+// decode events ahead of the consumer of a query.
+func QueryBatchSize(size int32) EventStoreOption {
+	return func(s *EventStore) {
+		s.queryBatchSize = size
+	}
+}
+
 // TransactionHook represents a hook that can be executed before or after
 // inserting events into the EventStore. The hook function should return an
 // error if anything goes wrong, causing the transaction to abort.
@@ -333,11 +367,13 @@ func WithTransactionHook(hook TransactionHook, fn func(TransactionContext) error
 // underlying MongoDB client to use, the database and collections to store
 // events in, and whether or not to validate event versions. If no database or
 // collection names are provided via EventStoreOptions, default names will be
-// used. By default, version validation is enabled.
+// used. By default, version validation is enabled and the size of encoded event
+// data is limited to DefaultMaxPayloadSize.
 func NewEventStore(enc codec.Encoding, opts ...EventStoreOption) *EventStore {
 	s := EventStore{
 		enc:              enc,
 		validateVersions: true,
+		maxPayloadSize:   DefaultMaxPayloadSize,
 	}
 	for _, opt := range opts {
 		opt(&s)
@@ -351,6 +387,9 @@ func NewEventStore(enc codec.Encoding, opts ...EventStoreOption) *EventStore {
 	if strings.TrimSpace(s.statesCol) == "" {
 		s.statesCol = "states"
 	}
+	if strings.TrimSpace(s.locksCol) == "" {
+		s.locksCol = "locks"
+	}
 	return &s
 }
 
@@ -450,6 +489,66 @@ func (s *EventStore) Insert(ctx context.Context, events ...event.Event) (out err
 	return nil
 }
 
+// InsertAtomic inserts the events of multiple aggregates within a single
+// MongoDB transaction. Each stream contains the events of a single aggregate
+// and its versions are validated like in Insert. Pre-insert and post-insert
+// hooks are called once for all streams. InsertAtomic uses a transaction even
+// if the Transactions option is not enabled, so it can only be used in replica
+// sets or sharded clusters.
+func (s *EventStore) InsertAtomic(ctx context.Context, streams ...[]event.Event) error {
+	if s.isTransactionStore {
+		for _, events := range streams {
+			if err := s.txInsert(ctx, events); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	tx, err := s.createTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	// EndSession aborts the transaction if it has not been committed.
+	defer tx.Session().EndSession(ctx)
+
+	sessionCtx := mongo.NewSessionContext(ctx, tx.Session())
+
+	if err := sessionCtx.StartTransaction(); err != nil {
+		return fmt.Errorf("start transaction: %w", err)
+	}
+
+	txCtx := newTransactionContext(sessionCtx, tx)
+	for _, hook := range s.preInsertHooks {
+		if err := hook(txCtx); err != nil {
+			return fmt.Errorf("pre-insert hook: %w", err)
+		}
+	}
+
+	for _, events := range streams {
+		if err := s.insertInSession(sessionCtx, events); err != nil {
+			return err
+		}
+		tx.appendEvents(events)
+	}
+
+	for _, hook := range s.postInsertHooks {
+		if err := hook(txCtx); err != nil {
+			return fmt.Errorf("post-insert hook: %w", err)
+		}
+	}
+
+	if err := sessionCtx.CommitTransaction(sessionCtx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
 func (s *EventStore) txInsert(ctx context.Context, events []event.Event) error {
 	if err := s.root.connectOnce(ctx); err != nil {
 		return fmt.Errorf("connect: %w", err)
@@ -576,11 +675,19 @@ func (s *EventStore) insert(ctx context.Context, events []event.Event) error {
 
 	docs := make([]any, len(events))
 	for i, evt := range events {
-		b, err := s.enc.Marshal(evt.Data())
+		b, err := codec.Encode(s.enc, evt.Name(), evt.Data())
 		if err != nil {
 			return fmt.Errorf("encode %q event data: %w", evt.Name(), err)
 		}
 
+		if s.maxPayloadSize > 0 && len(b) > s.maxPayloadSize {
+			return fmt.Errorf("insert %q event: %w", evt.Name(), &codec.PayloadSizeError{
+				Name:  evt.Name(),
+				Size:  len(b),
+				Limit: s.maxPayloadSize,
+			})
+		}
+
 		id, name, v := evt.Aggregate()
 		docs[i] = entry{
 			ID:               evt.ID(),
@@ -613,12 +720,46 @@ func (s *EventStore) Find(ctx context.Context, id uuid.UUID) (event.Event, error
 
 	var e entry
 	if err := res.Decode(&e); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("decode document: %w: %w", event.ErrNotFound, err)
+		}
 		return nil, fmt.Errorf("decode document: %w", err)
 	}
 
 	return e.event(s.enc)
 }
 
+// AggregateVersion returns the highest version of the events of the given
+// aggregate, or 0 if the database contains no events of the aggregate. Only
+// the version of the latest event is fetched from the database.
+func (s *EventStore) AggregateVersion(ctx context.Context, ref event.AggregateRef) (int, error) {
+	if s.isTransactionStore {
+		return s.root.AggregateVersion(ctx, ref)
+	}
+
+	if err := s.connectOnce(ctx); err != nil {
+		return 0, fmt.Errorf("connect: %w", err)
+	}
+
+	res := s.entries.FindOne(ctx, bson.D{
+		{Key: "aggregateName", Value: ref.Name},
+		{Key: "aggregateId", Value: ref.ID},
+	}, options.FindOne().
+		SetSort(bson.D{{Key: "aggregateVersion", Value: -1}}).
+		SetProjection(bson.D{{Key: "aggregateVersion", Value: 1}}),
+	)
+
+	var e entry
+	if err := res.Decode(&e); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("decode document: %w", err)
+	}
+
+	return e.AggregateVersion, nil
+}
+
 // Delete deletes the given event from the database.
 func (s *EventStore) Delete(ctx context.Context, events ...event.Event) error {
 	if s.root != nil {
@@ -740,6 +881,10 @@ func (s *EventStore) Query(ctx context.Context, q event.Query) (<-chan event.Eve
 	opts := options.Find().SetAllowDiskUse(true)
 	opts = applySortings(opts, q.Sortings()...)
 
+	if s.queryBatchSize > 0 {
+		opts = opts.SetBatchSize(s.queryBatchSize)
+	}
+
 	for _, interceptor := range s.queryInterceptors {
 		opts = interceptor(opts)
 	}
@@ -851,6 +996,7 @@ func (s *EventStore) connect(ctx context.Context, opts ...*options.ClientOptions
 	s.db = s.client.Database(s.dbname)
 	s.entries = s.db.Collection(s.entriesCol)
 	s.states = s.db.Collection(s.statesCol)
+	s.locks = s.db.Collection(s.locksCol)
 	return nil
 }
 
@@ -94,6 +94,36 @@ func TestEventStore_Insert_versionError(t *testing.T) {
 	}
 }
 
+func TestEventStore_Insert_payloadTooLarge(t *testing.T) {
+	enc := etest.NewEncoder()
+	s := mongo.NewEventStore(enc, mongo.URL(os.Getenv("MONGOSTORE_URL")), mongo.Database(nextEventDatabase()), mongo.MaxPayloadSize(8))
+
+	evt := event.New[any]("foo", etest.FooEventData{A: "this payload is too large"})
+
+	err := s.Insert(context.Background(), evt)
+
+	var sizeError *codec.PayloadSizeError
+	if !errors.As(err, &sizeError) {
+		t.Fatalf("Insert should fail with a %T error; got %T", sizeError, err)
+	}
+
+	if !errors.Is(err, codec.ErrPayloadTooLarge) {
+		t.Errorf("error should wrap %q", codec.ErrPayloadTooLarge)
+	}
+
+	if sizeError.Name != "foo" {
+		t.Errorf("PayloadSizeError should have Name %q; got %q", "foo", sizeError.Name)
+	}
+
+	if sizeError.Limit != 8 {
+		t.Errorf("PayloadSizeError should have Limit %d; got %d", 8, sizeError.Limit)
+	}
+
+	if _, err := s.Find(context.Background(), evt.ID()); err == nil {
+		t.Errorf("event should not have been inserted")
+	}
+}
+
 // TestEventStore_Insert_preAndPostHooks tests the following scenario
 // Given: [0: "insert:pre", 1: "insert:pre", 2: "insert:post", 3: "insert:post"] hooks, then
 //
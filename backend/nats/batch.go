@@ -0,0 +1,203 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+)
+
+// batchWindow is the number of batch ids that a subscription remembers to
+// ignore the copies of batches that it already received.
+const batchWindow = 4096
+
+// batchNamespace is the UUID namespace that is used to derive the ids of
+// atomically published batches from the ids of their events.
+var batchNamespace = uuid.MustParse("5f0c1a7e-3b8d-4c62-9e1f-8a4d2b6c7e90")
+
+// AtomicPublish returns an option that publishes the events of a single call to
+// Publish atomically: each subscriber receives either all of the published
+// events it subscribed to, or none of them.
+//
+// Instead of publishing every event as a separate message, the event bus
+// encodes the events into a single batch message, and publishes this message
+// to the subject of every event in the batch. When a subscriber receives the
+// batch message from any of these subjects, it receives all events of the
+// batch that it subscribed to at once, and ignores the batch message from the
+// other subjects. If publishing fails after the batch message was published to
+// some of the subjects, the subscribers of those subjects still receive all of
+// their events of the batch.
+//
+// The id of a batch is derived from the ids of its events, so retrying a
+// failed Publish with the same events does not deliver the batch twice to a
+// subscriber that already received it. Each call to Subscribe remembers the
+// ids of the last 4096 batches it received; a copy of a batch that arrives
+// after more batches were received in between is delivered again. When using
+// the JetStream driver, the JetStream server additionally deduplicates the
+// batch messages within the duplicate window of the stream.
+//
+// Subscribers understand batch messages regardless of this option, so the
+// option can be enabled for publishers only. Batches are delivered once per
+// subscriber, not once per queue group: when combined with QueueGroup or
+// LoadBalancer, the events of a batch may be received by multiple members of
+// the group.
+func AtomicPublish() EventBusOption {
+	return func(bus *EventBus) {
+		bus.atomic = true
+	}
+}
+
+func (bus *EventBus) publishAtomic(ctx context.Context, events []event.Event) error {
+	batch := envelope{
+		BatchID: batchID(events),
+		Batch:   make([]envelope, len(events)),
+	}
+
+	var subjects []string
+	seen := make(map[string]struct{})
+	for i, evt := range events {
+		env, err := encodeEnvelope(bus.enc, evt)
+		if err != nil {
+			return err
+		}
+		batch.Batch[i] = env
+
+		subject := bus.subjectFunc(evt.Name())
+		if _, ok := seen[subject]; !ok {
+			seen[subject] = struct{}{}
+			subjects = append(subjects, subject)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(batch); err != nil {
+		return fmt.Errorf("encode batch envelope: %w", err)
+	}
+
+	for _, subject := range subjects {
+		if err := bus.driver.publishBatch(ctx, bus, subject, batch.BatchID, buf.Bytes()); err != nil {
+			return fmt.Errorf("publish batch: %w [batch=%v, subject=%v]", err, batch.BatchID, subject)
+		}
+	}
+
+	return nil
+}
+
+func encodeEnvelope(enc codec.Encoding, evt event.Event) (envelope, error) {
+	b, err := codec.Encode(enc, evt.Name(), evt.Data())
+	if err != nil {
+		return envelope{}, fmt.Errorf("encode event data: %w [event=%v, type(data)=%T]", err, evt.Name(), evt.Data())
+	}
+
+	id, name, v := evt.Aggregate()
+
+	return envelope{
+		ID:               evt.ID(),
+		Name:             evt.Name(),
+		Time:             evt.Time(),
+		Data:             b,
+		AggregateName:    name,
+		AggregateID:      id,
+		AggregateVersion: v,
+	}, nil
+}
+
+func decodeEnvelope(enc codec.Encoding, env envelope) (event.Event, error) {
+	data, err := enc.Unmarshal(env.Data, env.Name)
+	if err != nil {
+		return nil, fmt.Errorf("decode event data: %w [event=%v]", err, env.Name)
+	}
+
+	return event.New(
+		env.Name,
+		data,
+		event.ID(env.ID),
+		event.Time(env.Time),
+		event.Aggregate(
+			env.AggregateID,
+			env.AggregateName,
+			env.AggregateVersion,
+		),
+	), nil
+}
+
+func batchID(events []event.Event) uuid.UUID {
+	ids := make([]byte, 0, len(events)*16)
+	for _, evt := range events {
+		id := evt.ID()
+		ids = append(ids, id[:]...)
+	}
+	return uuid.NewSHA1(batchNamespace, ids)
+}
+
+// delivery is a delivery of events from a subscription to its recipients. If
+// batch is not uuid.Nil, the events were published atomically.
+type delivery struct {
+	events []event.Event
+	batch  uuid.UUID
+}
+
+// batchFilter ensures that the recipients of a single call to Subscribe
+// receive the events of an atomically published batch only once, although the
+// batch message is received from the subject of every event in the batch, and
+// again if a failed Publish is retried. The filter remembers the ids of the
+// last batchWindow batches it claimed.
+type batchFilter struct {
+	subjectFunc func(string) string
+	wildcard    bool
+	subjects    map[string]bool
+
+	mux  sync.Mutex
+	seen map[uuid.UUID]struct{}
+	ring []uuid.UUID
+	next int
+}
+
+func newBatchFilter(bus *EventBus, rcpts []recipient) *batchFilter {
+	f := &batchFilter{
+		subjectFunc: bus.subjectFunc,
+		subjects:    make(map[string]bool),
+		seen:        make(map[uuid.UUID]struct{}),
+		ring:        make([]uuid.UUID, batchWindow),
+	}
+	for _, rcpt := range rcpts {
+		if rcpt.sub.event == "*" {
+			f.wildcard = true
+			continue
+		}
+		f.subjects[bus.subjectFunc(rcpt.sub.event)] = true
+	}
+	return f
+}
+
+// claim returns the events of the batch that were subscribed to, if the batch
+// is claimed for the first time. Otherwise, claim returns nil.
+func (f *batchFilter) claim(id uuid.UUID, events []event.Event) []event.Event {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if _, ok := f.seen[id]; ok {
+		return nil
+	}
+
+	if len(f.seen) == len(f.ring) {
+		delete(f.seen, f.ring[f.next])
+	}
+	f.seen[id] = struct{}{}
+	f.ring[f.next] = id
+	f.next = (f.next + 1) % len(f.ring)
+
+	out := make([]event.Event, 0, len(events))
+	for _, evt := range events {
+		if f.wildcard || f.subjects[f.subjectFunc(evt.Name())] {
+			out = append(out, evt)
+		}
+	}
+
+	return out
+}
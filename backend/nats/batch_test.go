@@ -0,0 +1,62 @@
+//go:build nats
+
+package nats
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/test"
+)
+
+func TestBatchFilter_retry(t *testing.T) {
+	bus := NewEventBus(test.NewEncoder())
+	f := newBatchFilter(bus, []recipient{
+		{sub: &subscription{event: "foo"}},
+		{sub: &subscription{event: "bar"}},
+	})
+
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}).Any(),
+		event.New("bar", test.BarEventData{}).Any(),
+	}
+	id := batchID(events)
+
+	// The first attempt publishes the batch to the "foo" subject and fails
+	// before the batch is published to the "bar" subject.
+	received := f.claim(id, events)
+
+	// The retry publishes the batch to both subjects.
+	received = append(received, f.claim(id, events)...)
+	received = append(received, f.claim(id, events)...)
+
+	if len(received) != len(events) {
+		t.Fatalf("the events of the batch should be received exactly once; received %d events", len(received))
+	}
+	for i, evt := range received {
+		if evt.ID() != events[i].ID() {
+			t.Fatalf("received event #%d should be %q; got %q", i, events[i].ID(), evt.ID())
+		}
+	}
+}
+
+func TestBatchFilter_window(t *testing.T) {
+	f := newBatchFilter(NewEventBus(test.NewEncoder()), []recipient{{sub: &subscription{event: "*"}}})
+
+	events := []event.Event{event.New("foo", test.FooEventData{}).Any()}
+	first := uuid.New()
+	f.claim(first, events)
+
+	for i := 0; i < batchWindow; i++ {
+		f.claim(uuid.New(), events)
+	}
+
+	if len(f.seen) != batchWindow {
+		t.Fatalf("filter should remember the last %d batches; remembers %d", batchWindow, len(f.seen))
+	}
+
+	if got := f.claim(first, events); len(got) != 1 {
+		t.Fatalf("batches that left the window should be claimed again; got %d events", len(got))
+	}
+}
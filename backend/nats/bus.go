@@ -38,16 +38,25 @@ type EventBus struct {
 	enc codec.Encoding
 
 	eatErrors   bool
+	atomic      bool
 	url         string
 	pullTimeout time.Duration
 
 	subjectFunc func(eventName string) (subject string)
 	queueFunc   func(eventName string) (queue string)
 
+	pendingMsgs  int
+	pendingBytes int
+
 	conn     *nats.Conn
 	natsOpts []nats.Option
+	pool     *ConnPool
 	driver   Driver
 
+	reloadSignals  []os.Signal
+	reloadFiles    []string
+	reloadInterval time.Duration
+
 	onceConnect sync.Once
 	stop        chan struct{}
 }
@@ -62,6 +71,7 @@ type Driver interface {
 	name() string
 	subscribe(ctx context.Context, bus *EventBus, subject string) (recipient, error)
 	publish(ctx context.Context, bus *EventBus, evt event.Event) error
+	publishBatch(ctx context.Context, bus *EventBus, subject string, id uuid.UUID, msg []byte) error
 }
 
 type envelope struct {
@@ -72,6 +82,11 @@ type envelope struct {
 	AggregateName    string
 	AggregateID      uuid.UUID
 	AggregateVersion int
+
+	// BatchID and Batch are set if the envelope contains the events of an
+	// atomically published batch instead of a single event.
+	BatchID uuid.UUID
+	Batch   []envelope
 }
 
 // NewEventBus returns a NATS event bus.
@@ -87,7 +102,12 @@ func NewEventBus(enc codec.Encoding, opts ...EventBusOption) *EventBus {
 		enc = event.NewRegistry()
 	}
 
-	bus := &EventBus{enc: enc, stop: make(chan struct{})}
+	bus := &EventBus{
+		enc:          enc,
+		stop:         make(chan struct{}),
+		pendingMsgs:  -1,
+		pendingBytes: -1,
+	}
 	for _, opt := range opts {
 		opt(bus)
 	}
@@ -108,6 +128,13 @@ func (bus *EventBus) Connection() *nats.Conn {
 func (bus *EventBus) Connect(ctx context.Context) error {
 	var err error
 	bus.onceConnect.Do(func() {
+		// The stop channel has been closed by a previous Disconnect.
+		select {
+		case <-bus.stop:
+			bus.stop = make(chan struct{})
+		default:
+		}
+
 		if err = bus.connect(ctx); err != nil {
 			return
 		}
@@ -118,6 +145,8 @@ func (bus *EventBus) Connect(ctx context.Context) error {
 				return
 			}
 		}
+
+		bus.watchReload()
 	})
 	return err
 }
@@ -129,6 +158,13 @@ func (bus *EventBus) connect(ctx context.Context) error {
 	}
 
 	var err error
+	if bus.pool != nil {
+		if bus.conn, err = bus.pool.Acquire(ctx); err != nil {
+			return fmt.Errorf("acquire connection from pool: %w", err)
+		}
+		return nil
+	}
+
 	if bus.conn, err = nats.Connect(bus.natsURL(), bus.natsOpts...); err != nil {
 		return fmt.Errorf("connect: %w [url=%v]", err, bus.natsURL())
 	}
@@ -137,12 +173,23 @@ func (bus *EventBus) connect(ctx context.Context) error {
 }
 
 // Disconnect closes the underlying *nats.Conn. Should ctx be canceled before
-// the connection is closed, ctx.Err() is returned.
+// the connection is closed, ctx.Err() is returned. If the event bus uses a
+// ConnPool, the subscriptions of the event bus are closed and the connection
+// is released back to the pool instead. The event bus can be connected again
+// after it has been disconnected.
 func (bus *EventBus) Disconnect(ctx context.Context) error {
 	if bus.conn == nil {
 		return nil
 	}
 
+	if bus.pool != nil {
+		close(bus.stop)
+		bus.pool.Release(bus.conn)
+		bus.conn = nil
+		bus.onceConnect = sync.Once{}
+		return nil
+	}
+
 	closed := make(chan struct{})
 	bus.conn.SetClosedHandler(func(*nats.Conn) { close(closed) })
 	bus.conn.Close()
@@ -150,20 +197,27 @@ func (bus *EventBus) Disconnect(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
 		bus.conn = nil
+		bus.onceConnect = sync.Once{}
 		return ctx.Err()
 	case <-closed:
 		close(bus.stop)
 		bus.conn = nil
+		bus.onceConnect = sync.Once{}
 		return nil
 	}
 }
 
-// Publish publishes events.
+// Publish publishes events. If the AtomicPublish option is used, multiple
+// events are published atomically.
 func (bus *EventBus) Publish(ctx context.Context, events ...event.Event) error {
 	if err := bus.Connect(ctx); err != nil {
 		return fmt.Errorf("connect: %w", err)
 	}
 
+	if bus.atomic && len(events) > 1 {
+		return bus.publishAtomic(ctx, events)
+	}
+
 	for _, evt := range events {
 		if err := bus.driver.publish(ctx, bus, evt); err != nil {
 			return fmt.Errorf("publish event: %w [event=%v]", err, evt.Name())
@@ -246,25 +300,34 @@ func (bus *EventBus) fanInEvents(rcpts []recipient) <-chan event.Event {
 		))
 	}
 
+	batches := newBatchFilter(bus, rcpts)
+
 	for _, rcpt := range rcpts {
 		go func(rcpt recipient) {
 			defer wg.Done()
-			for evt := range rcpt.events {
-				var timeout <-chan time.Time
-				stop := func() bool { return false }
-				if bus.pullTimeout > 0 {
-					timer := time.NewTimer(bus.pullTimeout)
-					timeout = timer.C
+			for d := range rcpt.events {
+				events := d.events
+				if d.batch != uuid.Nil {
+					events = batches.claim(d.batch, events)
 				}
 
-				select {
-				case <-rcpt.unsubbed:
-					return
-				case <-timeout:
-					drop(rcpt, evt)
-					stop()
-				case out <- evt:
-					stop()
+				for _, evt := range events {
+					var timeout <-chan time.Time
+					stop := func() bool { return false }
+					if bus.pullTimeout > 0 {
+						timer := time.NewTimer(bus.pullTimeout)
+						timeout = timer.C
+					}
+
+					select {
+					case <-rcpt.unsubbed:
+						return
+					case <-timeout:
+						drop(rcpt, evt)
+						stop()
+					case out <- evt:
+						stop()
+					}
 				}
 			}
 		}(rcpt)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/modernice/goes/event"
 	"github.com/nats-io/nats.go"
 )
@@ -57,8 +58,8 @@ func (core *core) subscribe(ctx context.Context, bus *EventBus, event string) (r
 		}
 	}
 
-	if err := nsub.SetPendingLimits(-1, -1); err != nil {
-		return recipient{}, fmt.Errorf("SetPendingLimits(-1, -1) on nats subscription: %w", err)
+	if err := nsub.SetPendingLimits(bus.pendingMsgs, bus.pendingBytes); err != nil {
+		return recipient{}, fmt.Errorf("SetPendingLimits(%d, %d) on nats subscription: %w", bus.pendingMsgs, bus.pendingBytes, err)
 	}
 
 	sub := newSubscription(event, bus, nsub, msgs)
@@ -82,21 +83,9 @@ func (core *core) subscribe(ctx context.Context, bus *EventBus, event string) (r
 }
 
 func (core *core) publish(ctx context.Context, bus *EventBus, evt event.Event) error {
-	b, err := bus.enc.Marshal(evt.Data())
+	env, err := encodeEnvelope(bus.enc, evt)
 	if err != nil {
-		return fmt.Errorf("encode event data: %w [event=%v, type(data)=%T]", err, evt.Name(), evt.Data())
-	}
-
-	id, name, v := evt.Aggregate()
-
-	env := envelope{
-		ID:               evt.ID(),
-		Name:             evt.Name(),
-		Time:             evt.Time(),
-		Data:             b,
-		AggregateName:    name,
-		AggregateID:      id,
-		AggregateVersion: v,
+		return err
 	}
 
 	var buf bytes.Buffer
@@ -111,3 +100,10 @@ func (core *core) publish(ctx context.Context, bus *EventBus, evt event.Event) e
 
 	return nil
 }
+
+func (core *core) publishBatch(_ context.Context, bus *EventBus, subject string, _ uuid.UUID, msg []byte) error {
+	if err := bus.conn.Publish(subject, msg); err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
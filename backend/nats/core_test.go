@@ -24,6 +24,11 @@ func TestEventBus_Core(t *testing.T) {
 		eventbustest.RunWildcard(t, newQueueCoreEventBus, eventbustest.Cleanup(coreCleanup))
 		testEventBus(t, newQueueCoreEventBus)
 	})
+
+	t.Run("Atomic", func(t *testing.T) {
+		eventbustest.RunCore(t, newAtomicCoreEventBus, eventbustest.Cleanup(coreCleanup))
+		eventbustest.RunAtomic(t, newAtomicCoreEventBus, eventbustest.Cleanup(coreCleanup))
+	})
 }
 
 func newCoreEventBus(enc codec.Encoding) event.Bus {
@@ -34,6 +39,10 @@ func newQueueCoreEventBus(enc codec.Encoding) event.Bus {
 	return nats.NewEventBus(enc, nats.EatErrors(), nats.SubjectPrefix("core:"), nats.LoadBalancer("queue"))
 }
 
+func newAtomicCoreEventBus(enc codec.Encoding) event.Bus {
+	return nats.NewEventBus(enc, nats.EatErrors(), nats.SubjectPrefix("core_atomic:"), nats.AtomicPublish())
+}
+
 func coreCleanup(bus *nats.EventBus) error {
 	return bus.Disconnect(context.Background())
 }
@@ -79,6 +79,18 @@ func SubOpts(opts ...nats.SubOpt) JetStreamOption {
 	}
 }
 
+// MaxAckPending returns an option that limits the number of messages that a
+// JetStream consumer delivers without them being acknowledged. Messages are
+// acknowledged when the subscriber receives the event from the subscription,
+// so a slow subscriber, or a subscriber that is limited by an
+// eventbus.FlowControl, pushes back to the JetStream server instead of piling up
+// messages in the client.
+func MaxAckPending(n int) JetStreamOption {
+	return func(js *jetStream) {
+		js.maxAckPending = n
+	}
+}
+
 // JetStream returns the NATS JetStream Driver:
 //
 //	bus := NewEventBus(enc, Use(JetStream()))
@@ -104,9 +116,10 @@ func JetStream(opts ...JetStreamOption) Driver {
 type jetStream struct {
 	sync.RWMutex
 
-	stream      string
-	subOpts     []nats.SubOpt
-	durableFunc func(subject string, queue string) string
+	stream        string
+	subOpts       []nats.SubOpt
+	durableFunc   func(subject string, queue string) string
+	maxAckPending int
 
 	ctx  nats.JetStreamContext
 	subs map[string]*subscription
@@ -160,6 +173,7 @@ func (js *jetStream) subscribe(ctx context.Context, bus *EventBus, event string)
 
 	nsub, err := js.natsSubscribe(
 		ctx,
+		bus,
 		msgs,
 		event,
 		subject,
@@ -176,6 +190,7 @@ func (js *jetStream) subscribe(ctx context.Context, bus *EventBus, event string)
 
 func (js *jetStream) natsSubscribe(
 	ctx context.Context,
+	bus *EventBus,
 	msgs chan<- []byte,
 	event,
 	subject,
@@ -213,8 +228,8 @@ func (js *jetStream) natsSubscribe(
 		return nsub, err
 	}
 
-	if err := nsub.SetPendingLimits(-1, -1); err != nil {
-		return nsub, fmt.Errorf("SetPendingLimits(-1, -1) on nats subscription: %w", err)
+	if err := nsub.SetPendingLimits(bus.pendingMsgs, bus.pendingBytes); err != nil {
+		return nsub, fmt.Errorf("SetPendingLimits(%d, %d) on nats subscription: %w", bus.pendingMsgs, bus.pendingBytes, err)
 	}
 
 	return nsub, nil
@@ -242,21 +257,9 @@ func (js *jetStream) addRecipient(ctx context.Context, bus *EventBus, event stri
 }
 
 func (js *jetStream) publish(ctx context.Context, bus *EventBus, evt event.Event) error {
-	b, err := bus.enc.Marshal(evt.Data())
+	env, err := encodeEnvelope(bus.enc, evt)
 	if err != nil {
-		return fmt.Errorf("encode event data: %w [event=%v, type(data)=%T]", err, evt.Name(), evt.Data())
-	}
-
-	id, name, v := evt.Aggregate()
-
-	env := envelope{
-		ID:               evt.ID(),
-		Name:             evt.Name(),
-		Time:             evt.Time(),
-		Data:             b,
-		AggregateName:    name,
-		AggregateID:      id,
-		AggregateVersion: v,
+		return err
 	}
 
 	var buf bytes.Buffer
@@ -278,6 +281,16 @@ func (js *jetStream) publish(ctx context.Context, bus *EventBus, evt event.Event
 	return nil
 }
 
+// publishBatch publishes the message of an atomically published batch. The
+// message id contains the subject, because the same batch is published to the
+// subject of every event in the batch.
+func (js *jetStream) publishBatch(_ context.Context, _ *EventBus, subject string, id uuid.UUID, msg []byte) error {
+	if _, err := js.ctx.Publish(subject, msg, nats.MsgId(fmt.Sprintf("%s:%s", id, subject))); err != nil {
+		return fmt.Errorf("jetstream: %w", err)
+	}
+	return nil
+}
+
 func (js *jetStream) ensureStream(ctx context.Context) error {
 	info, err := js.ctx.StreamInfo(js.stream)
 	if err == nil {
@@ -330,6 +343,7 @@ func (js *jetStream) ensureConsumer(ctx context.Context, bus *EventBus, name, ev
 		DeliverGroup:   queue,
 		AckPolicy:      nats.AckAllPolicy,
 		FilterSubject:  subject,
+		MaxAckPending:  js.maxAckPending,
 	}
 
 	if _, err := js.ctx.AddConsumer(js.stream, &cfg); err != nil {
@@ -353,11 +367,17 @@ func (js *jetStream) makeSubOpts(consumerName string) []nats.SubOpt {
 	}
 
 	// Let NATS create an ephemeral consumer.
-	return append([]nats.SubOpt{
+	opts := []nats.SubOpt{
 		nats.BindStream(js.stream),
 		nats.DeliverNew(),
 		nats.AckAll(),
-	}, js.subOpts...)
+	}
+
+	if js.maxAckPending > 0 {
+		opts = append(opts, nats.MaxAckPending(js.maxAckPending))
+	}
+
+	return append(opts, js.subOpts...)
 }
 
 func jsConsumerName(durable, queue, eventName string) string {
@@ -20,6 +20,10 @@ func TestEventBus_JetStream(t *testing.T) {
 	t.Run("Durable", jetStreamTest(newDurableJetStreamBus))
 	t.Run("Queue", jetStreamTest(newQueueGroupJetStreamBus))
 	t.Run("Durable+Queue", jetStreamTest(newDurableQueueGroupJetStreamBus))
+	t.Run("Atomic", func(t *testing.T) {
+		jetStreamTest(newAtomicJetStreamBus)(t)
+		eventbustest.RunAtomic(t, newAtomicJetStreamBus, eventbustest.Cleanup(cleanup))
+	})
 }
 
 func jetStreamTest(newBus func(codec.Encoding) event.Bus) func(t *testing.T) {
@@ -42,6 +46,17 @@ func newJetStreamBus(enc codec.Encoding) event.Bus {
 	)
 }
 
+func newAtomicJetStreamBus(enc codec.Encoding) event.Bus {
+	return nats.NewEventBus(
+		enc,
+		nats.EatErrors(),
+		nats.Use(nats.JetStream()),
+		nats.URL(os.Getenv("JETSTREAM_URL")),
+		nats.SubjectPrefix("jetstream_atomic:"),
+		nats.AtomicPublish(),
+	)
+}
+
 func newDurableJetStreamBus(enc codec.Encoding) event.Bus {
 	return nats.NewEventBus(
 		enc,
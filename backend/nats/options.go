@@ -36,6 +36,28 @@ func Conn(conn *nats.Conn) EventBusOption {
 	}
 }
 
+// Pool returns an option that makes the event bus acquire its connection from
+// the provided ConnPool instead of opening its own connection to NATS. The
+// connection is released back to the pool when the event bus is disconnected.
+func Pool(p *ConnPool) EventBusOption {
+	return func(bus *EventBus) {
+		bus.pool = p
+	}
+}
+
+// PendingLimits returns an option that sets the slow-consumer limits of the
+// NATS subscriptions of the event bus. When a subscription has more than msgs
+// pending messages or more than bytes pending bytes, NATS drops new messages
+// for the subscription and reports nats.ErrSlowConsumer to the error handler
+// of the connection. A limit of -1 means "no limit". By default, subscriptions
+// have no limits.
+func PendingLimits(msgs, bytes int) EventBusOption {
+	return func(bus *EventBus) {
+		bus.pendingMsgs = msgs
+		bus.pendingBytes = bytes
+	}
+}
+
 // EatErrors returns an option that discards any asynchronous errors of
 // subscriptions. When subscribing to an event, you can safely ignore the
 // returned error channel:
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -119,6 +120,106 @@ func TestConnection(t *testing.T) {
 	}
 }
 
+func TestPool(t *testing.T) {
+	pool := NewConnPool(PoolSize(2))
+	defer pool.Close()
+
+	buses := make([]*EventBus, 4)
+	for i := range buses {
+		buses[i] = NewEventBus(test.NewEncoder(), Pool(pool))
+		if err := buses[i].Connect(context.Background()); err != nil {
+			t.Fatalf("Connect failed with %q", err)
+		}
+	}
+
+	if pool.Len() != 2 {
+		t.Fatalf("pool should have %d connections; has %d", 2, pool.Len())
+	}
+
+	if buses[0].conn != buses[2].conn || buses[1].conn != buses[3].conn {
+		t.Fatalf("connections should be shared by the event buses")
+	}
+
+	if err := buses[0].Disconnect(context.Background()); err != nil {
+		t.Fatalf("Disconnect failed with %q", err)
+	}
+
+	if buses[2].conn.IsClosed() {
+		t.Fatalf("disconnecting an event bus should not close a pooled connection")
+	}
+}
+
+func TestPendingLimits(t *testing.T) {
+	bus := NewEventBus(test.NewEncoder())
+
+	if bus.pendingMsgs != -1 || bus.pendingBytes != -1 {
+		t.Fatalf("pending limits should be disabled by default; are (%d, %d)", bus.pendingMsgs, bus.pendingBytes)
+	}
+
+	bus = NewEventBus(test.NewEncoder(), PendingLimits(1000, 1<<20))
+
+	if bus.pendingMsgs != 1000 || bus.pendingBytes != 1<<20 {
+		t.Fatalf("pending limits should be (%d, %d); are (%d, %d)", 1000, 1<<20, bus.pendingMsgs, bus.pendingBytes)
+	}
+}
+
+func TestConnOptions(t *testing.T) {
+	var called bool
+	bus := NewEventBus(test.NewEncoder(), ConnOptions(func(*nats.Options) error {
+		called = true
+		return nil
+	}))
+
+	if err := bus.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed with %q", err)
+	}
+	defer bus.Disconnect(context.Background())
+
+	if !called {
+		t.Fatalf("connection options should be applied when connecting to NATS")
+	}
+}
+
+func TestNkeyFromSeed_invalidFile(t *testing.T) {
+	bus := NewEventBus(test.NewEncoder(), NkeyFromSeed(filepath.Join(t.TempDir(), "missing.nk")))
+
+	if err := bus.Connect(context.Background()); err == nil {
+		t.Fatalf("Connect should fail if the nkey seed cannot be read")
+	}
+}
+
+func TestReloadOnChange(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "user.creds")
+	if err := os.WriteFile(file, []byte("foo"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	bus := NewEventBus(test.NewEncoder(), ReloadOnChange(50*time.Millisecond, file))
+	if err := bus.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect failed with %q", err)
+	}
+	defer bus.Disconnect(context.Background())
+
+	reconnected := make(chan struct{}, 1)
+	bus.conn.SetReconnectHandler(func(*nats.Conn) {
+		select {
+		case reconnected <- struct{}{}:
+		default:
+		}
+	})
+
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatalf("change modification time: %v", err)
+	}
+
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatalf("event bus should reconnect after a watched file changed")
+	case <-reconnected:
+	}
+}
+
 func TestSubjectFunc(t *testing.T) {
 	bus := NewEventBus(test.NewEncoder(), SubjectFunc(func(eventName string) string {
 		return "prefix." + eventName
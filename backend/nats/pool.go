@@ -0,0 +1,225 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultPoolSize is the default maximum number of connections of a ConnPool.
+const DefaultPoolSize = 1
+
+// ErrPoolClosed is returned by a ConnPool that has been closed.
+var ErrPoolClosed = errors.New("connection pool closed")
+
+// ConnPool is a pool of NATS connections that can be shared by multiple event
+// buses. By default, every event bus opens its own connection to NATS, which
+// results in excessive connections in services that create many event buses,
+// e.g. one per event handler. Event buses that are created with the Pool
+// option acquire a connection from the pool instead:
+//
+//	pool := nats.NewConnPool(nats.PoolSize(4))
+//	defer pool.Close()
+//
+//	busA := nats.NewEventBus(enc, nats.Pool(pool))
+//	busB := nats.NewEventBus(enc, nats.Pool(pool))
+//
+// Connections are opened lazily until the pool size is reached. Afterwards, an
+// event bus gets the connection that is shared by the fewest event buses.
+type ConnPool struct {
+	url      string
+	size     int
+	natsOpts []nats.Option
+
+	mux        sync.Mutex
+	conns      []*pooledConn
+	connecting int
+	connected  chan struct{}
+	closed     bool
+}
+
+// PoolOption is an option for a ConnPool.
+type PoolOption func(*ConnPool)
+
+type pooledConn struct {
+	conn *nats.Conn
+	refs int
+}
+
+// PoolURL returns a PoolOption that sets the connection URL to the NATS
+// server. Defaults to the environment variable `NATS_URL`, or nats.DefaultURL
+// if that is not set.
+func PoolURL(url string) PoolOption {
+	return func(p *ConnPool) {
+		p.url = url
+	}
+}
+
+// PoolSize returns a PoolOption that sets the maximum number of connections of
+// the pool. Defaults to DefaultPoolSize.
+func PoolSize(n int) PoolOption {
+	return func(p *ConnPool) {
+		p.size = n
+	}
+}
+
+// PoolConnOptions returns a PoolOption that adds options for the connections
+// of the pool, e.g. nats.ErrorHandler to be notified about slow consumers.
+func PoolConnOptions(opts ...nats.Option) PoolOption {
+	return func(p *ConnPool) {
+		p.natsOpts = append(p.natsOpts, opts...)
+	}
+}
+
+// NewConnPool returns a new connection pool.
+func NewConnPool(opts ...PoolOption) *ConnPool {
+	p := &ConnPool{size: DefaultPoolSize}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.size < 1 {
+		p.size = 1
+	}
+	return p
+}
+
+// Acquire returns a connection from the pool. The connection must be released
+// using Release when it is no longer used. New connections are opened without
+// holding the lock of the pool, so that a slow connect does not block event
+// buses that can use an existing connection.
+func (p *ConnPool) Acquire(ctx context.Context) (*nats.Conn, error) {
+	for {
+		p.mux.Lock()
+
+		if p.closed {
+			p.mux.Unlock()
+			return nil, ErrPoolClosed
+		}
+
+		if err := ctx.Err(); err != nil {
+			p.mux.Unlock()
+			return nil, err
+		}
+
+		// Replace connections that have been closed.
+		conns := p.conns[:0]
+		for _, pc := range p.conns {
+			if !pc.conn.IsClosed() {
+				conns = append(conns, pc)
+			}
+		}
+		p.conns = conns
+
+		if len(p.conns)+p.connecting < p.size {
+			p.connecting++
+			p.mux.Unlock()
+			return p.connect()
+		}
+
+		if len(p.conns) > 0 {
+			least := p.conns[0]
+			for _, pc := range p.conns[1:] {
+				if pc.refs < least.refs {
+					least = pc
+				}
+			}
+			least.refs++
+			p.mux.Unlock()
+			return least.conn, nil
+		}
+
+		// All connections are still being opened; wait for one of them.
+		if p.connected == nil {
+			p.connected = make(chan struct{})
+		}
+		connected := p.connected
+		p.mux.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-connected:
+		}
+	}
+}
+
+// connect opens a new connection and adds it to the pool. The caller must
+// have reserved a slot for the connection by incrementing p.connecting.
+func (p *ConnPool) connect() (*nats.Conn, error) {
+	conn, err := nats.Connect(p.natsURL(), p.natsOpts...)
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.connecting--
+	if p.connected != nil {
+		close(p.connected)
+		p.connected = nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w [url=%v]", err, p.natsURL())
+	}
+
+	if p.closed {
+		conn.Close()
+		return nil, ErrPoolClosed
+	}
+
+	p.conns = append(p.conns, &pooledConn{conn: conn, refs: 1})
+
+	return conn, nil
+}
+
+// Release releases a connection that was acquired from the pool. Connections
+// are kept open until the pool is closed.
+func (p *ConnPool) Release(conn *nats.Conn) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	for _, pc := range p.conns {
+		if pc.conn == conn && pc.refs > 0 {
+			pc.refs--
+			return
+		}
+	}
+}
+
+// Len returns the number of open connections of the pool.
+func (p *ConnPool) Len() int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	return len(p.conns)
+}
+
+// Close closes all connections of the pool. Event buses that use the pool
+// can no longer publish or receive events afterwards.
+func (p *ConnPool) Close() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.closed = true
+
+	var errs []error
+	for _, pc := range p.conns {
+		if err := pc.conn.Drain(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+			errs = append(errs, err)
+		}
+	}
+	p.conns = nil
+
+	return errors.Join(errs...)
+}
+
+func (p *ConnPool) natsURL() string {
+	if p.url != "" {
+		return p.url
+	}
+	if url := os.Getenv("NATS_URL"); url != "" {
+		return url
+	}
+	return nats.DefaultURL
+}
@@ -0,0 +1,186 @@
+package nats
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultReloadInterval is the default interval at which ReloadOnChange checks
+// the watched files for changes.
+const DefaultReloadInterval = 30 * time.Second
+
+// ConnOptions returns an option that adds options for the connection that the
+// event bus opens to NATS. ConnOptions has no effect if the connection is
+// provided using the Conn or Pool option. Use ConnOptions for authentication
+// methods that have no dedicated option, e.g. for servers that use auth
+// callouts:
+//
+//	bus := NewEventBus(enc, ConnOptions(nats.UserInfo("user", "password")))
+func ConnOptions(opts ...nats.Option) EventBusOption {
+	return func(bus *EventBus) {
+		bus.natsOpts = append(bus.natsOpts, opts...)
+	}
+}
+
+// TLS returns an option that enables TLS for the connection to NATS, using
+// the provided TLS configuration. Use the ClientCert and RootCAs options to
+// load certificates from files that can be rotated.
+func TLS(cfg *tls.Config) EventBusOption {
+	return ConnOptions(nats.Secure(cfg))
+}
+
+// ClientCert returns an option that enables TLS for the connection to NATS and
+// authenticates the event bus using the client certificate in the given files.
+// The files are read every time the event bus (re)connects to NATS.
+func ClientCert(certFile, keyFile string) EventBusOption {
+	return ConnOptions(nats.ClientCert(certFile, keyFile))
+}
+
+// RootCAs returns an option that enables TLS for the connection to NATS and
+// verifies the server certificate using the root CAs in the given files. The
+// files are read every time the event bus (re)connects to NATS.
+func RootCAs(files ...string) EventBusOption {
+	return ConnOptions(nats.RootCAs(files...))
+}
+
+// Credentials returns an option that authenticates the event bus using the
+// user JWT and nkey seed in the given credentials file. If seedFiles are
+// provided, the seed is read from the first seed file instead. The files are
+// read every time the event bus (re)connects to NATS.
+func Credentials(file string, seedFiles ...string) EventBusOption {
+	return ConnOptions(nats.UserCredentials(file, seedFiles...))
+}
+
+// NkeyFromSeed returns an option that authenticates the event bus using the
+// nkey in the given seed file. If the seed file cannot be read, Connect fails.
+func NkeyFromSeed(seedFile string) EventBusOption {
+	return func(bus *EventBus) {
+		opt, err := nats.NkeyOptionFromSeed(seedFile)
+		if err != nil {
+			bus.natsOpts = append(bus.natsOpts, func(*nats.Options) error {
+				return fmt.Errorf("load nkey seed: %w", err)
+			})
+			return
+		}
+		bus.natsOpts = append(bus.natsOpts, opt)
+	}
+}
+
+// ReloadOnSignal returns an option that reconnects the event bus to NATS when
+// the process receives one of the given signals. If no signals are provided,
+// the event bus reconnects on SIGHUP. Because the ClientCert, RootCAs and
+// Credentials options read their files on every reconnect, reconnecting picks
+// up rotated certificates and credentials. Subscriptions of the event bus are
+// kept across reconnects.
+//
+// If multiple event buses share a connection of a ConnPool, use this option
+// for only one of them.
+func ReloadOnSignal(sigs ...os.Signal) EventBusOption {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	return func(bus *EventBus) {
+		bus.reloadSignals = append(bus.reloadSignals, sigs...)
+	}
+}
+
+// ReloadOnChange returns an option that reconnects the event bus to NATS when
+// one of the given files changes, e.g. a credentials file or a certificate
+// that is rotated by a secret manager. The modification times of the files are
+// checked at the given interval; an interval of 0 or less means
+// DefaultReloadInterval. See ReloadOnSignal for details.
+func ReloadOnChange(interval time.Duration, files ...string) EventBusOption {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	return func(bus *EventBus) {
+		bus.reloadInterval = interval
+		bus.reloadFiles = append(bus.reloadFiles, files...)
+	}
+}
+
+// Reload reconnects the event bus to NATS, which picks up rotated
+// certificates and credentials without closing the subscriptions of the
+// event bus. Reload does nothing if the event bus is not connected.
+func (bus *EventBus) Reload() error {
+	conn := bus.conn
+	if conn == nil {
+		return nil
+	}
+	if err := conn.ForceReconnect(); err != nil {
+		return fmt.Errorf("reconnect: %w", err)
+	}
+	return nil
+}
+
+// watchReload reconnects the event bus on the signals and file changes that
+// are configured using ReloadOnSignal and ReloadOnChange, until the event bus
+// is disconnected.
+func (bus *EventBus) watchReload() {
+	if len(bus.reloadSignals) == 0 && len(bus.reloadFiles) == 0 {
+		return
+	}
+
+	var sigs chan os.Signal
+	if len(bus.reloadSignals) > 0 {
+		sigs = make(chan os.Signal, 1)
+		signal.Notify(sigs, bus.reloadSignals...)
+	}
+
+	modTimes := make(map[string]time.Time)
+	bus.filesChanged(modTimes)
+
+	stop := bus.stop
+	go func() {
+		if sigs != nil {
+			defer signal.Stop(sigs)
+		}
+
+		var tick <-chan time.Time
+		if len(bus.reloadFiles) > 0 {
+			ticker := time.NewTicker(bus.reloadInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-sigs:
+			case <-tick:
+				if !bus.filesChanged(modTimes) {
+					continue
+				}
+			}
+
+			if err := bus.Reload(); err != nil {
+				log.Printf("[goes/backend/nats.EventBus] Failed to reload connection: %v", err)
+			}
+		}
+	}()
+}
+
+// filesChanged updates the modification times of the watched files and
+// reports whether any of them changed since the last call.
+func (bus *EventBus) filesChanged(modTimes map[string]time.Time) bool {
+	var changed bool
+	for _, file := range bus.reloadFiles {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if prev, ok := modTimes[file]; ok && !prev.Equal(info.ModTime()) {
+			changed = true
+		}
+		modTimes[file] = info.ModTime()
+	}
+	return changed
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/google/uuid"
 	"github.com/modernice/goes/event"
 	"github.com/nats-io/nats.go"
 )
@@ -28,7 +29,7 @@ type subscription struct {
 
 type recipient struct {
 	sub      *subscription
-	events   chan event.Event
+	events   chan delivery
 	errs     chan error
 	unsubbed chan struct{}
 }
@@ -123,28 +124,29 @@ func (sub *subscription) send(bus *EventBus, msg []byte) error {
 		return fmt.Errorf("gob decode envelope: %w", err)
 	}
 
-	data, err := bus.enc.Unmarshal(env.Data, env.Name)
-	if err != nil {
-		return fmt.Errorf("decode event data: %w [event=%v]", err, env.Name)
+	d := delivery{batch: env.BatchID}
+	if env.BatchID == uuid.Nil {
+		evt, err := decodeEnvelope(bus.enc, env)
+		if err != nil {
+			return err
+		}
+		d.events = []event.Event{evt}
+	} else {
+		d.events = make([]event.Event, len(env.Batch))
+		for i, benv := range env.Batch {
+			evt, err := decodeEnvelope(bus.enc, benv)
+			if err != nil {
+				return fmt.Errorf("%w [batch=%v]", err, env.BatchID)
+			}
+			d.events[i] = evt
+		}
 	}
 
-	evt := event.New(
-		env.Name,
-		data,
-		event.ID(env.ID),
-		event.Time(env.Time),
-		event.Aggregate(
-			env.AggregateID,
-			env.AggregateName,
-			env.AggregateVersion,
-		),
-	)
-
 	for _, rcpt := range sub.recipients {
 		select {
 		case <-rcpt.sub.stop:
 			return nil
-		case rcpt.events <- evt:
+		case rcpt.events <- d:
 		}
 	}
 
@@ -156,7 +158,7 @@ func (sub *subscription) subscribe(ctx context.Context) (recipient, error) {
 
 	rcpt := recipient{
 		sub:      sub,
-		events:   make(chan event.Event),
+		events:   make(chan delivery),
 		errs:     make(chan error),
 		unsubbed: make(chan struct{}),
 	}
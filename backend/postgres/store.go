@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -16,6 +17,7 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/modernice/goes/codec"
 	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
 	"github.com/modernice/goes/internal/slice"
 )
 
@@ -242,11 +244,22 @@ func (store *EventStore) createIndexes(ctx context.Context) error {
 
 // Insert inserts events into the event store.
 func (store *EventStore) Insert(ctx context.Context, events ...event.Event) error {
+	return store.InsertAtomic(ctx, events)
+}
+
+// InsertAtomic inserts the events of multiple aggregates within a single
+// transaction.
+func (store *EventStore) InsertAtomic(ctx context.Context, streams ...[]event.Event) error {
 	if err := store.Connect(ctx); err != nil {
 		return fmt.Errorf("connect: %w", err)
 	}
 
-	if len(events) == 0 {
+	var n int
+	for _, events := range streams {
+		n += len(events)
+	}
+
+	if n == 0 {
 		return nil
 	}
 
@@ -256,10 +269,20 @@ func (store *EventStore) Insert(ctx context.Context, events ...event.Event) erro
 	}
 	defer tx.Rollback(ctx)
 
+	for _, events := range streams {
+		if err := store.insert(ctx, tx, events); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (store *EventStore) insert(ctx context.Context, tx pgx.Tx, events []event.Event) error {
 	for _, evt := range events {
 		aggregateID, aggregateName, aggregateVersion := evt.Aggregate()
 
-		b, err := store.enc.Marshal(evt.Data())
+		b, err := codec.Encode(store.enc, evt.Name(), evt.Data())
 		if err != nil {
 			return fmt.Errorf("marshal %q event data: %w", evt.Name(), err)
 		}
@@ -292,7 +315,7 @@ func (store *EventStore) Insert(ctx context.Context, events ...event.Event) erro
 		}
 	}
 
-	return tx.Commit(ctx)
+	return nil
 }
 
 // Find fetches the event with the given id from the event store.
@@ -315,12 +338,35 @@ func (store *EventStore) Find(ctx context.Context, id uuid.UUID) (event.Event, e
 		&evt.AggregateVersion,
 		&evt.Data,
 	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("query event: %w: %w", event.ErrNotFound, err)
+		}
 		return nil, fmt.Errorf("query event: %w", err)
 	}
 
 	return store.decodeEvent(evt)
 }
 
+// AggregateVersion returns the highest version of the events of the given
+// aggregate, or 0 if the event store contains no events of the aggregate.
+func (store *EventStore) AggregateVersion(ctx context.Context, ref event.AggregateRef) (int, error) {
+	if err := store.Connect(ctx); err != nil {
+		return 0, fmt.Errorf("connect: %w", err)
+	}
+
+	var v int
+	if err := store.pool.QueryRow(
+		ctx,
+		`SELECT COALESCE(MAX(aggregate_version), 0) FROM events WHERE aggregate_name = $1 AND aggregate_id = $2`,
+		ref.Name,
+		ref.ID,
+	).Scan(&v); err != nil {
+		return 0, fmt.Errorf("query aggregate version: %w", err)
+	}
+
+	return v, nil
+}
+
 func (store *EventStore) decodeEvent(devt dbevent) (event.Event, error) {
 	opts := []event.Option{event.ID(devt.ID), event.Time(time.Unix(0, devt.Time))}
 	if devt.AggregateID != nil && devt.AggregateName != nil && devt.AggregateVersion != nil {
@@ -396,12 +442,66 @@ func (store *EventStore) Query(ctx context.Context, query event.Query) (<-chan e
 	return out, errs, nil
 }
 
+// Estimate counts the events that match the given query, without fetching
+// them. The returned Size is the total stored size of the event data.
+func (store *EventStore) Estimate(ctx context.Context, query event.Query) (eventstore.QueryEstimate, error) {
+	sql, args, err := store.filter(squirrel.
+		Select("COUNT(*)", "COALESCE(SUM(pg_column_size(data)), 0)").
+		From(store.table).
+		PlaceholderFormat(squirrel.Dollar), query).ToSql()
+	if err != nil {
+		return eventstore.QueryEstimate{}, fmt.Errorf("build sql: %w", err)
+	}
+
+	est := eventstore.QueryEstimate{Exact: true}
+	if err := store.pool.QueryRow(ctx, sql, args...).Scan(&est.Events, &est.Size); err != nil {
+		return eventstore.QueryEstimate{}, fmt.Errorf("count events: %w", err)
+	}
+
+	return est, nil
+}
+
 func (store *EventStore) buildQuery(query event.Query) (string, []any, error) {
-	builder := squirrel.
+	builder := store.filter(squirrel.
 		Select("id", "name", "time", "aggregate_id", "aggregate_name", "aggregate_version", "data").
 		From(store.table).
-		PlaceholderFormat(squirrel.Dollar)
+		PlaceholderFormat(squirrel.Dollar), query)
+
+	if sortings := query.Sortings(); len(sortings) > 0 {
+		orders := make([]string, len(sortings))
+		for i, sorting := range sortings {
+			dir := "ASC"
+			if sorting.Dir == event.SortDesc {
+				dir = "DESC"
+			}
+
+			var field string
+			switch sorting.Sort {
+			case event.SortAggregateID:
+				field = "aggregate_id"
+			case event.SortAggregateName:
+				field = "aggregate_name"
+			case event.SortAggregateVersion:
+				field = "aggregate_version"
+			case event.SortTime:
+				field = "time"
+			}
+
+			orders[i] = fmt.Sprintf("%s %s", field, dir)
+		}
+
+		builder = builder.OrderBy(orders...)
+	}
+
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return "", nil, fmt.Errorf("build sql: %w", err)
+	}
+
+	return sql, args, nil
+}
 
+func (store *EventStore) filter(builder squirrel.SelectBuilder, query event.Query) squirrel.SelectBuilder {
 	if ids := query.AggregateIDs(); len(ids) > 0 {
 		builder = builder.Where(buildOREq("aggregate_id", ids))
 	}
@@ -484,44 +584,33 @@ func (store *EventStore) buildQuery(query event.Query) (string, []any, error) {
 		}
 	}
 
-	if sortings := query.Sortings(); len(sortings) > 0 {
-		orders := make([]string, len(sortings))
-		for i, sorting := range sortings {
-			dir := "ASC"
-			if sorting.Dir == event.SortDesc {
-				dir = "DESC"
-			}
-
-			var field string
-			switch sorting.Sort {
-			case event.SortAggregateID:
-				field = "aggregate_id"
-			case event.SortAggregateName:
-				field = "aggregate_name"
-			case event.SortAggregateVersion:
-				field = "aggregate_version"
-			case event.SortTime:
-				field = "time"
-			}
-
-			orders[i] = fmt.Sprintf("%s %s", field, dir)
-		}
+	return builder
+}
 
-		builder = builder.OrderBy(orders...)
+// Delete deletes the given events from the event store.
+func (store *EventStore) Delete(ctx context.Context, events ...event.Event) error {
+	if len(events) == 0 {
+		return nil
 	}
 
-	sql, args, err := builder.ToSql()
+	tx, err := store.pool.Begin(ctx)
 	if err != nil {
-		return "", nil, fmt.Errorf("build sql: %w", err)
+		return fmt.Errorf("begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	return sql, args, nil
+	if err := store.delete(ctx, tx, events); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
-// Delete deletes the given events from the event store.
-func (store *EventStore) Delete(ctx context.Context, events ...event.Event) error {
-	if len(events) == 0 {
-		return nil
+// ReplaceAtomic deletes the events in remove and inserts the events in insert
+// within a single transaction.
+func (store *EventStore) ReplaceAtomic(ctx context.Context, remove, insert []event.Event) error {
+	if err := store.Connect(ctx); err != nil {
+		return fmt.Errorf("connect: %w", err)
 	}
 
 	tx, err := store.pool.Begin(ctx)
@@ -530,6 +619,18 @@ func (store *EventStore) Delete(ctx context.Context, events ...event.Event) erro
 	}
 	defer tx.Rollback(ctx)
 
+	if err := store.delete(ctx, tx, remove); err != nil {
+		return err
+	}
+
+	if err := store.insert(ctx, tx, insert); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (store *EventStore) delete(ctx context.Context, tx pgx.Tx, events []event.Event) error {
 	for _, evt := range events {
 		sql, args, err := squirrel.Delete(store.table).Where(squirrel.Eq{"id": evt.ID()}).PlaceholderFormat(squirrel.Dollar).ToSql()
 		if err != nil {
@@ -540,8 +641,7 @@ func (store *EventStore) Delete(ctx context.Context, events ...event.Event) erro
 			return fmt.Errorf("delete event: %w [id=%s]", err, evt.ID())
 		}
 	}
-
-	return tx.Commit(ctx)
+	return nil
 }
 
 type dbevent struct {
@@ -0,0 +1,324 @@
+// Package redis provides a Redis backed model repository for projections.
+//
+// The package does not depend on a specific Redis driver. Instead, it requires
+// a Client that evaluates Lua scripts, which every Redis driver supports. Using
+// github.com/redis/go-redis:
+//
+//	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	client := goesredis.ClientFunc(func(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+//		return rdb.Eval(ctx, script, keys, args...).Result()
+//	})
+//	repo := goesredis.NewModelRepository[*Presence, uuid.UUID](client, "presence", goesredis.ModelTTL(time.Minute))
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/persistence/model"
+)
+
+// DefaultUseRetries is the default number of times ModelRepository.Use retries
+// a call after a version conflict.
+const DefaultUseRetries = 3
+
+// ErrVersionConflict is returned when a model is saved with an expected version
+// that does not match the version that is stored in Redis, which happens if the
+// model was modified concurrently.
+var ErrVersionConflict = errors.New("version conflict")
+
+var _ model.Repository[model.Model[uuid.UUID], uuid.UUID] = (*ModelRepository[model.Model[uuid.UUID], uuid.UUID])(nil)
+
+const (
+	// fetchScript returns the data and version of a model.
+	fetchScript = `return redis.call('HMGET', KEYS[1], 'data', 'version')`
+
+	// saveScript saves the data of a model if the stored version matches the
+	// expected version (ARGV[2]), increments the version and refreshes the
+	// expiration. An expected version of -1 disables the version check.
+	saveScript = `local v = tonumber(redis.call('HGET', KEYS[1], 'version') or '0')
+if ARGV[2] ~= '-1' and v ~= tonumber(ARGV[2]) then
+	return {0, v}
+end
+redis.call('HSET', KEYS[1], 'data', ARGV[1], 'version', v + 1)
+if tonumber(ARGV[3]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return {1, v + 1}`
+
+	// deleteScript deletes a model.
+	deleteScript = `return redis.call('DEL', KEYS[1])`
+)
+
+// Client evaluates Lua scripts in Redis. The result of a script must be
+// returned in the format of github.com/redis/go-redis, i.e. Lua tables are
+// returned as []any, integers as int64 and bulk strings as string.
+type Client interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// ClientFunc allows functions to be used as Clients.
+type ClientFunc func(ctx context.Context, script string, keys []string, args ...any) (any, error)
+
+// ModelRepository is a Redis backed model repository. Each model is stored as
+// a hash that contains the JSON encoded model and its version. Saves through
+// Use are optimistically locked, and models can optionally expire, which makes
+// the repository a good fit for ephemeral read models like presence or rate
+// counters.
+type ModelRepository[Model model.Model[ID], ID model.ID] struct {
+	modelRepositoryOptions
+	client Client
+	prefix string
+}
+
+// ModelRepositoryOption is an option for the model repository.
+type ModelRepositoryOption func(*modelRepositoryOptions)
+
+type modelRepositoryOptions struct {
+	ttl              time.Duration
+	retries          int
+	factory          func(any) any
+	createIfNotFound bool
+	customDecoder    func([]byte, any) error
+	customEncoder    func(any) ([]byte, error)
+}
+
+// ModelTTL returns a ModelRepositoryOption that makes saved models expire after
+// the given duration. Every save refreshes the expiration. By default, models
+// do not expire.
+func ModelTTL(ttl time.Duration) ModelRepositoryOption {
+	return func(o *modelRepositoryOptions) {
+		o.ttl = ttl
+	}
+}
+
+// ModelRetries returns a ModelRepositoryOption that specifies how often Use
+// retries a call after a version conflict. Defaults to DefaultUseRetries.
+func ModelRetries(n int) ModelRepositoryOption {
+	return func(o *modelRepositoryOptions) {
+		o.retries = n
+	}
+}
+
+// ModelDecoder returns a ModelRepositoryOption that specifies a custom decoder
+// for the model.
+func ModelDecoder[Model model.Model[ID], ID model.ID](decode func([]byte, *Model) error) ModelRepositoryOption {
+	return func(o *modelRepositoryOptions) {
+		o.customDecoder = func(b []byte, m any) error {
+			return decode(b, m.(*Model))
+		}
+	}
+}
+
+// ModelEncoder returns a ModelRepositoryOption that specifies a custom encoder
+// for the model.
+func ModelEncoder[Model model.Model[ID], ID model.ID](encode func(Model) ([]byte, error)) ModelRepositoryOption {
+	return func(o *modelRepositoryOptions) {
+		o.customEncoder = func(m any) ([]byte, error) {
+			return encode(m.(Model))
+		}
+	}
+}
+
+// ModelFactory returns a ModelRepositoryOption that provides a factory function
+// for the models to a model repository. The repository will use the function to
+// create the model before decoding the stored data into it. If
+// `createIfNotFound` is true, the repository will create and return the model
+// using the factory function instead of returning a model.ErrNotFound error.
+func ModelFactory[Model model.Model[ID], ID model.ID](factory func(ID) Model, createIfNotFound bool) ModelRepositoryOption {
+	return func(o *modelRepositoryOptions) {
+		o.createIfNotFound = createIfNotFound
+		o.factory = func(id any) any {
+			return factory(id.(ID))
+		}
+	}
+}
+
+// NewModelRepository returns a Redis backed model repository. Models are
+// stored under the key "<prefix>:<id>".
+func NewModelRepository[Model model.Model[ID], ID model.ID](client Client, prefix string, opts ...ModelRepositoryOption) *ModelRepository[Model, ID] {
+	options := modelRepositoryOptions{retries: DefaultUseRetries}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &ModelRepository[Model, ID]{
+		modelRepositoryOptions: options,
+		client:                 client,
+		prefix:                 prefix,
+	}
+}
+
+// Key returns the Redis key of the model with the given id.
+func (r *ModelRepository[Model, ID]) Key(id ID) string {
+	return r.prefix + ":" + id.String()
+}
+
+// Save saves the given model to Redis, regardless of the stored version.
+func (r *ModelRepository[Model, ID]) Save(ctx context.Context, m Model) error {
+	_, err := r.SaveVersion(ctx, m, -1)
+	return err
+}
+
+// SaveVersion saves the given model to Redis if the stored version of the model
+// matches the expected version, and returns the new version of the model. A
+// model that does not exist has version 0. If the versions do not match, an
+// error that unwraps to ErrVersionConflict is returned. An expected version of
+// -1 disables the version check.
+func (r *ModelRepository[Model, ID]) SaveVersion(ctx context.Context, m Model, expected int) (int, error) {
+	b, err := r.encode(m)
+	if err != nil {
+		return 0, fmt.Errorf("encode model: %w", err)
+	}
+
+	res, err := r.client.Eval(ctx, saveScript, []string{r.Key(m.ModelID())}, string(b), expected, r.ttl.Milliseconds())
+	if err != nil {
+		return 0, fmt.Errorf("save model: %w", err)
+	}
+
+	vals, ok := res.([]any)
+	if !ok || len(vals) != 2 {
+		return 0, fmt.Errorf("save model: unexpected result %v", res)
+	}
+
+	saved, err := toInt(vals[0])
+	if err != nil {
+		return 0, fmt.Errorf("save model: %w", err)
+	}
+
+	version, err := toInt(vals[1])
+	if err != nil {
+		return 0, fmt.Errorf("save model: %w", err)
+	}
+
+	if saved == 0 {
+		return version, fmt.Errorf("%w: expected version %d, stored version is %d", ErrVersionConflict, expected, version)
+	}
+
+	return version, nil
+}
+
+// Fetch fetches the given model from Redis. If the model cannot be found, an
+// error that unwraps to model.ErrNotFound is returned.
+func (r *ModelRepository[Model, ID]) Fetch(ctx context.Context, id ID) (Model, error) {
+	m, _, err := r.FetchVersion(ctx, id)
+	return m, err
+}
+
+// FetchVersion fetches the given model and its version from Redis. If the model
+// cannot be found, an error that unwraps to model.ErrNotFound is returned,
+// unless the ModelFactory option is used with `createIfNotFound` set to true, in
+// which case the created model is returned with version 0.
+func (r *ModelRepository[Model, ID]) FetchVersion(ctx context.Context, id ID) (Model, int, error) {
+	var m Model
+	if r.factory != nil {
+		m = r.factory(id).(Model)
+	}
+
+	res, err := r.client.Eval(ctx, fetchScript, []string{r.Key(id)})
+	if err != nil {
+		return m, 0, fmt.Errorf("fetch model: %w", err)
+	}
+
+	vals, ok := res.([]any)
+	if !ok || len(vals) != 2 {
+		return m, 0, fmt.Errorf("fetch model: unexpected result %v", res)
+	}
+
+	if vals[0] == nil {
+		if r.createIfNotFound && r.factory != nil {
+			return m, 0, nil
+		}
+		return m, 0, fmt.Errorf("%w: %s", model.ErrNotFound, r.Key(id))
+	}
+
+	data, ok := vals[0].(string)
+	if !ok {
+		return m, 0, fmt.Errorf("fetch model: unexpected data %v", vals[0])
+	}
+
+	version, err := toInt(vals[1])
+	if err != nil {
+		return m, 0, fmt.Errorf("fetch model: %w", err)
+	}
+
+	if err := r.decode([]byte(data), &m); err != nil {
+		return m, 0, fmt.Errorf("decode model: %w", err)
+	}
+
+	return m, version, nil
+}
+
+// Use fetches the given model from Redis, passes the model to the provided
+// function and finally saves the model back to Redis. If the model was modified
+// concurrently, the model is fetched again and fn is called again, up to the
+// number of retries configured by the ModelRetries option. If there are no
+// retries left, an error that unwraps to ErrVersionConflict is returned.
+func (r *ModelRepository[Model, ID]) Use(ctx context.Context, id ID, fn func(Model) error) error {
+	for attempt := 0; ; attempt++ {
+		m, version, err := r.FetchVersion(ctx, id)
+		if err != nil {
+			return fmt.Errorf("fetch model: %w", err)
+		}
+
+		if err := fn(m); err != nil {
+			return err
+		}
+
+		_, err = r.SaveVersion(ctx, m, version)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, ErrVersionConflict) || attempt >= r.retries {
+			return fmt.Errorf("save model: %w", err)
+		}
+	}
+}
+
+// Delete deletes the given model from Redis.
+func (r *ModelRepository[Model, ID]) Delete(ctx context.Context, m Model) error {
+	if _, err := r.client.Eval(ctx, deleteScript, []string{r.Key(m.ModelID())}); err != nil {
+		return fmt.Errorf("delete model: %w", err)
+	}
+	return nil
+}
+
+func (r *ModelRepository[Model, ID]) encode(m Model) ([]byte, error) {
+	if r.customEncoder != nil {
+		return r.customEncoder(m)
+	}
+	return json.Marshal(m)
+}
+
+func (r *ModelRepository[Model, ID]) decode(b []byte, m *Model) error {
+	if r.customDecoder != nil {
+		return r.customDecoder(b, m)
+	}
+	return json.Unmarshal(b, m)
+}
+
+// Eval returns fn(ctx, script, keys, args...).
+func (fn ClientFunc) Eval(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	return fn(ctx, script, keys, args...)
+}
+
+func toInt(v any) (int, error) {
+	switch v := v.(type) {
+	case int64:
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		return strconv.Atoi(v)
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unexpected integer %v (%T)", v, v)
+	}
+}
@@ -0,0 +1,262 @@
+//go:build redis
+
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/backend/redis"
+	"github.com/modernice/goes/persistence/model"
+)
+
+type counter struct {
+	ID    uuid.UUID `json:"id"`
+	Count int       `json:"count"`
+}
+
+func (c *counter) ModelID() uuid.UUID {
+	return c.ID
+}
+
+func TestModelRepository_Save_Fetch(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	r := redis.NewModelRepository[*counter, uuid.UUID](client, "counters")
+
+	c := &counter{ID: uuid.New(), Count: 3}
+
+	if _, err := r.Fetch(ctx, c.ID); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("Fetch() should fail with %q; got %q", model.ErrNotFound, err)
+	}
+
+	if err := r.Save(ctx, c); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	fetched, version, err := r.FetchVersion(ctx, c.ID)
+	if err != nil {
+		t.Fatalf("FetchVersion() failed with %q", err)
+	}
+
+	if *fetched != *c {
+		t.Fatalf("fetched model should be %v; is %v", c, fetched)
+	}
+
+	if version != 1 {
+		t.Fatalf("version should be %d; is %d", 1, version)
+	}
+
+	if err := r.Delete(ctx, c); err != nil {
+		t.Fatalf("Delete() failed with %q", err)
+	}
+
+	if _, err := r.Fetch(ctx, c.ID); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("Fetch() should fail with %q after Delete(); got %q", model.ErrNotFound, err)
+	}
+}
+
+func TestModelRepository_SaveVersion(t *testing.T) {
+	ctx := context.Background()
+	r := redis.NewModelRepository[*counter, uuid.UUID](newClient(t), "counters")
+
+	c := &counter{ID: uuid.New()}
+
+	if _, err := r.SaveVersion(ctx, c, 1); !errors.Is(err, redis.ErrVersionConflict) {
+		t.Fatalf("SaveVersion() should fail with %q; got %q", redis.ErrVersionConflict, err)
+	}
+
+	version, err := r.SaveVersion(ctx, c, 0)
+	if err != nil {
+		t.Fatalf("SaveVersion() failed with %q", err)
+	}
+
+	if version != 1 {
+		t.Fatalf("version should be %d; is %d", 1, version)
+	}
+
+	if _, err := r.SaveVersion(ctx, c, 0); !errors.Is(err, redis.ErrVersionConflict) {
+		t.Fatalf("SaveVersion() should fail with %q; got %q", redis.ErrVersionConflict, err)
+	}
+}
+
+func TestModelRepository_Use(t *testing.T) {
+	ctx := context.Background()
+	r := redis.NewModelRepository[*counter, uuid.UUID](newClient(t), "counters", redis.ModelFactory(func(id uuid.UUID) *counter {
+		return &counter{ID: id}
+	}, true))
+
+	id := uuid.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Use(ctx, id, func(c *counter) error {
+				c.Count++
+				return nil
+			}); err != nil && !errors.Is(err, redis.ErrVersionConflict) {
+				t.Errorf("Use() failed with %q", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c, version, err := r.FetchVersion(ctx, id)
+	if err != nil {
+		t.Fatalf("FetchVersion() failed with %q", err)
+	}
+
+	if c.Count != version {
+		t.Fatalf("every successful Use() should increment the count exactly once; count is %d, version is %d", c.Count, version)
+	}
+}
+
+func TestModelRepository_Use_conflict(t *testing.T) {
+	ctx := context.Background()
+	r := redis.NewModelRepository[*counter, uuid.UUID](newClient(t), "counters", redis.ModelRetries(1))
+
+	c := &counter{ID: uuid.New()}
+	if err := r.Save(ctx, c); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	var calls int
+	err := r.Use(ctx, c.ID, func(c *counter) error {
+		calls++
+		// Simulate a concurrent modification.
+		return r.Save(ctx, &counter{ID: c.ID})
+	})
+
+	if !errors.Is(err, redis.ErrVersionConflict) {
+		t.Fatalf("Use() should fail with %q; got %q", redis.ErrVersionConflict, err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("fn should have been called %d times; was called %d times", 2, calls)
+	}
+}
+
+func TestModelTTL(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t)
+	r := redis.NewModelRepository[*counter, uuid.UUID](client, "presence", redis.ModelTTL(time.Minute))
+
+	c := &counter{ID: uuid.New()}
+	if err := r.Save(ctx, c); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	if ttl := client.ttl(t, r.Key(c.ID)); ttl != time.Minute {
+		t.Fatalf("TTL of %q should be %v; is %v", r.Key(c.ID), time.Minute, ttl)
+	}
+}
+
+// respClient is a minimal Redis client that evaluates the scripts of the
+// repository in a real Redis server, which is configured by the REDIS_ADDR
+// environment variable.
+type respClient struct {
+	mux  sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newClient(t *testing.T) *respClient {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("connect to Redis: %v [addr=%v]", err, addr)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &respClient{conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *respClient) ttl(t *testing.T, key string) time.Duration {
+	res, err := c.Eval(context.Background(), `return redis.call('PTTL', KEYS[1])`, []string{key})
+	if err != nil {
+		t.Fatalf("get TTL of %q: %v", key, err)
+	}
+	return time.Duration(res.(int64)) * time.Millisecond
+}
+
+func (c *respClient) Eval(_ context.Context, script string, keys []string, args ...any) (any, error) {
+	cmd := []string{"EVAL", script, strconv.Itoa(len(keys))}
+	cmd = append(cmd, keys...)
+	for _, arg := range args {
+		cmd = append(cmd, fmt.Sprint(arg))
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	w := bufio.NewWriter(c.conn)
+	fmt.Fprintf(w, "*%d\r\n", len(cmd))
+	for _, arg := range cmd {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return c.read()
+}
+
+func (c *respClient) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("invalid reply %q", line)
+	}
+	kind, val := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return val, nil
+	case '-':
+		return nil, errors.New(val)
+	case ':':
+		return strconv.ParseInt(val, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("invalid reply %q", line)
+	}
+}
@@ -0,0 +1,95 @@
+package eventbustest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/test"
+)
+
+// RunAtomic tests an event bus that publishes the events of a single call to
+// Publish atomically. The test is successful if every subscriber receives each
+// of the published events it subscribed to exactly once.
+func RunAtomic(t *testing.T, newBus EventBusFactory, opts ...Option) {
+	cfg := configure(opts...)
+
+	t.Run("Atomic", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		bus := newBus(enc)
+
+		defer cfg.Cleanup(t, bus)
+
+		named := MustSub(bus.Subscribe(ctx, "foo", "bar"))
+		all := MustSub(bus.Subscribe(ctx, event.All))
+
+		events := []event.Event{
+			event.New("foo", test.FooEventData{}).Any(),
+			event.New("bar", test.BarEventData{}).Any(),
+			event.New("baz", test.BazEventData{}).Any(),
+			event.New("foo", test.FooEventData{}).Any(),
+		}
+
+		if err := bus.Publish(ctx, events...); err != nil {
+			t.Fatalf("publish events: %v", err)
+		}
+
+		namedErr := make(chan error, 1)
+		go func() { namedErr <- receiveOnce(named, 900*time.Millisecond, events[0], events[1], events[3]) }()
+
+		if err := receiveOnce(all, 900*time.Millisecond, events...); err != nil {
+			t.Fatalf("wildcard subscriber: %v", err)
+		}
+
+		if err := <-namedErr; err != nil {
+			t.Fatalf("named subscriber: %v", err)
+		}
+	})
+}
+
+func receiveOnce(sub Subscription, timeout time.Duration, events ...event.Event) error {
+	want := make(map[uuid.UUID]bool, len(events))
+	for _, evt := range events {
+		want[evt.ID()] = false
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			for id, received := range want {
+				if !received {
+					return fmt.Errorf("event %s not received", id)
+				}
+			}
+			return nil
+		case err, ok := <-sub.errs:
+			if !ok {
+				sub.errs = nil
+				break
+			}
+			return fmt.Errorf("received error: %w", err)
+		case evt, ok := <-sub.events:
+			if !ok {
+				return fmt.Errorf("event channel is closed")
+			}
+
+			received, ok := want[evt.ID()]
+			if !ok {
+				continue
+			}
+
+			if received {
+				return fmt.Errorf("%q event %s received twice", evt.Name(), evt.ID())
+			}
+			want[evt.ID()] = true
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package eventstoretest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+)
+
+// ErrInjectedTimeout is returned by a FlakyStore when it injects a timeout. It
+// unwraps to context.DeadlineExceeded.
+var ErrInjectedTimeout = fmt.Errorf("injected timeout: %w", context.DeadlineExceeded)
+
+// Operation is an operation of an event store.
+type Operation string
+
+const (
+	// InsertOp is the Insert operation of an event store.
+	InsertOp = Operation("insert")
+
+	// FindOp is the Find operation of an event store.
+	FindOp = Operation("find")
+
+	// QueryOp is the Query operation of an event store.
+	QueryOp = Operation("query")
+
+	// DeleteOp is the Delete operation of an event store.
+	DeleteOp = Operation("delete")
+)
+
+// Failure is a kind of failure that is injected by a FlakyStore.
+type Failure string
+
+const (
+	// Timeout fails the operation with ErrInjectedTimeout without performing
+	// the operation.
+	Timeout = Failure("timeout")
+
+	// AmbiguousTimeout performs the operation and then fails it with
+	// ErrInjectedTimeout, so that the caller cannot know whether the operation
+	// succeeded. Retrying an ambiguously timed out insert usually results in a
+	// duplicate-key error.
+	AmbiguousTimeout = Failure("ambiguous_timeout")
+
+	// DuplicateKey fails an insert with a *DuplicateKeyError without inserting
+	// the events, as if another writer inserted events with the same
+	// aggregate versions first.
+	DuplicateKey = Failure("duplicate_key")
+
+	// PartialWrite inserts only some of the events of an insert and then fails
+	// with a *PartialWriteError.
+	PartialWrite = Failure("partial_write")
+)
+
+// FailureConfig configures the failures that are injected by a FlakyStore.
+// Probabilities are in the range [0, 1]. At most one failure is injected per
+// operation; if the probabilities add up to more than 1, failures are
+// considered in the order Timeout, AmbiguousTimeout, DuplicateKey,
+// PartialWrite.
+type FailureConfig struct {
+	// Timeout is the probability that an operation fails with a timeout
+	// before it is performed.
+	Timeout float64
+
+	// AmbiguousTimeout is the probability that an insert or delete fails with
+	// a timeout after it has been performed.
+	AmbiguousTimeout float64
+
+	// TimeoutDelay is the duration that an operation blocks before it fails
+	// with a timeout. If the context of the operation is canceled before,
+	// the operation fails with the error of the context instead.
+	TimeoutDelay stdtime.Duration
+
+	// DuplicateKey is the probability that an insert fails with a
+	// duplicate-key error.
+	DuplicateKey float64
+
+	// PartialWrite is the probability that an insert of multiple events
+	// writes only some of the events.
+	PartialWrite float64
+
+	// Operations restricts the failure injection to the given operations.
+	// Defaults to all operations.
+	Operations []Operation
+
+	// Seed is the seed of the random source that decides which operations
+	// fail, which makes failure sequences reproducible.
+	Seed int64
+}
+
+// InjectedFailure is a failure that was injected by a FlakyStore.
+type InjectedFailure struct {
+	Operation Operation
+	Failure   Failure
+	Err       error
+}
+
+// DuplicateKeyError is returned by a FlakyStore when it injects a
+// duplicate-key error. It is a consistency error (see
+// aggregate.IsConsistencyError), like the version errors of the event store
+// backends.
+type DuplicateKeyError struct {
+	Events []event.Event
+}
+
+func (err *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("injected duplicate key error: %d events", len(err.Events))
+}
+
+// IsConsistencyError returns true.
+func (err *DuplicateKeyError) IsConsistencyError() bool {
+	return true
+}
+
+// PartialWriteError is returned by a FlakyStore when it injects a partial
+// write. The first Written events of Events have been inserted.
+type PartialWriteError struct {
+	Events  []event.Event
+	Written int
+}
+
+func (err *PartialWriteError) Error() string {
+	return fmt.Sprintf("injected partial write: wrote %d of %d events", err.Written, len(err.Events))
+}
+
+// FlakyStore is an event store that injects failures into the operations of
+// an underlying store. Use Flaky to create a FlakyStore.
+type FlakyStore struct {
+	event.Store
+
+	cfg FailureConfig
+	ops map[Operation]bool
+
+	mux      sync.Mutex
+	rand     *rand.Rand
+	next     map[Operation][]Failure
+	injected []InjectedFailure
+}
+
+// Flaky decorates the given event store to inject timeouts, duplicate-key
+// errors, and partial writes into its operations. Use Flaky to test
+// repositories, outbox relays, and retry policies against realistic storage
+// failures:
+//
+//	store := eventstoretest.Flaky(eventstore.New(), eventstoretest.FailureConfig{
+//		Timeout:      0.1,
+//		DuplicateKey: 0.05,
+//		PartialWrite: 0.05,
+//		Seed:         42,
+//	})
+//
+// Failures can also be injected deterministically using FailNext.
+func Flaky(store event.Store, cfg FailureConfig) *FlakyStore {
+	s := &FlakyStore{
+		Store: store,
+		cfg:   cfg,
+		rand:  rand.New(rand.NewSource(cfg.Seed)),
+		next:  make(map[Operation][]Failure),
+	}
+
+	if len(cfg.Operations) > 0 {
+		s.ops = make(map[Operation]bool, len(cfg.Operations))
+		for _, op := range cfg.Operations {
+			s.ops[op] = true
+		}
+	}
+
+	return s
+}
+
+// FailNext injects the given failures into the next calls of the given
+// operation, in order, regardless of the configured probabilities. Find and
+// Query only fail with timeouts, and Delete only with timeouts and ambiguous
+// timeouts. Other failures that are injected into them result in a timeout.
+func (s *FlakyStore) FailNext(op Operation, failures ...Failure) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.next[op] = append(s.next[op], failures...)
+}
+
+// Injected returns the failures that have been injected so far.
+func (s *FlakyStore) Injected() []InjectedFailure {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]InjectedFailure(nil), s.injected...)
+}
+
+// Insert inserts the events into the underlying store, or injects a failure.
+func (s *FlakyStore) Insert(ctx context.Context, events ...event.Event) error {
+	f := s.failure(InsertOp, len(events))
+
+	var err error
+	switch f {
+	case Timeout:
+		err = s.timeout(ctx)
+	case AmbiguousTimeout:
+		if err = s.Store.Insert(ctx, events...); err == nil {
+			err = s.timeout(ctx)
+		}
+	case DuplicateKey:
+		err = &DuplicateKeyError{Events: events}
+	case PartialWrite:
+		var written int
+		if len(events) > 1 {
+			written = s.intn(len(events))
+		}
+		if err = s.Store.Insert(ctx, events[:written]...); err == nil {
+			err = &PartialWriteError{Events: events, Written: written}
+		}
+	default:
+		return s.Store.Insert(ctx, events...)
+	}
+
+	return s.injectedErr(InsertOp, f, err)
+}
+
+// Find finds the event in the underlying store, or injects a timeout.
+func (s *FlakyStore) Find(ctx context.Context, id uuid.UUID) (event.Event, error) {
+	if f := s.failure(FindOp, 0); f != "" {
+		return nil, s.injectedErr(FindOp, Timeout, s.timeout(ctx))
+	}
+	return s.Store.Find(ctx, id)
+}
+
+// Query queries the underlying store, or injects a timeout.
+func (s *FlakyStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	if f := s.failure(QueryOp, 0); f != "" {
+		return nil, nil, s.injectedErr(QueryOp, Timeout, s.timeout(ctx))
+	}
+	return s.Store.Query(ctx, q)
+}
+
+// Delete deletes the events from the underlying store, or injects a timeout.
+func (s *FlakyStore) Delete(ctx context.Context, events ...event.Event) error {
+	f := s.failure(DeleteOp, 0)
+
+	var err error
+	switch f {
+	case "":
+		return s.Store.Delete(ctx, events...)
+	case AmbiguousTimeout:
+		if err = s.Store.Delete(ctx, events...); err == nil {
+			err = s.timeout(ctx)
+		}
+	default:
+		f = Timeout
+		err = s.timeout(ctx)
+	}
+
+	return s.injectedErr(DeleteOp, f, err)
+}
+
+// failure returns the failure to inject into the given operation, or an empty
+// Failure if the operation should succeed. Insert-specific failures are only
+// injected into inserts, and partial writes only into inserts of multiple
+// events.
+func (s *FlakyStore) failure(op Operation, events int) Failure {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if next := s.next[op]; len(next) > 0 {
+		s.next[op] = next[1:]
+		return next[0]
+	}
+
+	if s.ops != nil && !s.ops[op] {
+		return ""
+	}
+
+	p := s.rand.Float64()
+	for _, c := range []struct {
+		failure     Failure
+		probability float64
+		applies     bool
+	}{
+		{Timeout, s.cfg.Timeout, true},
+		{AmbiguousTimeout, s.cfg.AmbiguousTimeout, op == InsertOp || op == DeleteOp},
+		{DuplicateKey, s.cfg.DuplicateKey, op == InsertOp},
+		{PartialWrite, s.cfg.PartialWrite, op == InsertOp && events > 1},
+	} {
+		if !c.applies {
+			continue
+		}
+		if p < c.probability {
+			return c.failure
+		}
+		p -= c.probability
+	}
+
+	return ""
+}
+
+func (s *FlakyStore) timeout(ctx context.Context) error {
+	if s.cfg.TimeoutDelay <= 0 {
+		return ErrInjectedTimeout
+	}
+
+	timer := stdtime.NewTimer(s.cfg.TimeoutDelay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return ErrInjectedTimeout
+	}
+}
+
+func (s *FlakyStore) intn(n int) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rand.Intn(n)
+}
+
+func (s *FlakyStore) injectedErr(op Operation, f Failure, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// errors of the underlying store are not injected
+	var dup *DuplicateKeyError
+	var partial *PartialWriteError
+	if !errors.Is(err, ErrInjectedTimeout) && !errors.As(err, &dup) && !errors.As(err, &partial) {
+		return err
+	}
+
+	s.mux.Lock()
+	s.injected = append(s.injected, InjectedFailure{Operation: op, Failure: f, Err: err})
+	s.mux.Unlock()
+
+	return err
+}
@@ -0,0 +1,108 @@
+package eventstoretest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/backend/testing/eventstoretest"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestFlaky_FailNext(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoretest.Flaky(eventstore.New(), eventstoretest.FailureConfig{})
+
+	id := uuid.New()
+	events := []event.Event{
+		event.New("foo", 1, event.Aggregate(id, "foo", 1)).Any(),
+		event.New("foo", 2, event.Aggregate(id, "foo", 2)).Any(),
+		event.New("foo", 3, event.Aggregate(id, "foo", 3)).Any(),
+	}
+
+	store.FailNext(eventstoretest.InsertOp, eventstoretest.Timeout, eventstoretest.DuplicateKey, eventstoretest.PartialWrite)
+
+	if err := store.Insert(ctx, events...); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Insert() should time out; got %v", err)
+	}
+
+	if err := store.Insert(ctx, events...); !aggregate.IsConsistencyError(err) {
+		t.Fatalf("Insert() should fail with a consistency error; got %v", err)
+	}
+
+	if n := countEvents(t, store, id); n != 0 {
+		t.Fatalf("failed inserts should not insert events; store has %d events", n)
+	}
+
+	err := store.Insert(ctx, events...)
+
+	var partial *eventstoretest.PartialWriteError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Insert() should fail with a %T error; got %v", partial, err)
+	}
+
+	if n := countEvents(t, store, id); n != partial.Written {
+		t.Fatalf("store should have %d events after a partial write; has %d", partial.Written, n)
+	}
+
+	if err := store.Insert(ctx, events[partial.Written:]...); err != nil {
+		t.Fatalf("Insert() should not fail after the injected failures; got %v", err)
+	}
+
+	if injected := store.Injected(); len(injected) != 3 {
+		t.Fatalf("%d failures should have been injected; got %d", 3, len(injected))
+	}
+}
+
+func TestFlaky_probabilities(t *testing.T) {
+	ctx := context.Background()
+	store := eventstoretest.Flaky(eventstore.New(), eventstoretest.FailureConfig{
+		Timeout:    0.5,
+		Operations: []eventstoretest.Operation{eventstoretest.FindOp},
+		Seed:       1,
+	})
+
+	evt := event.New("foo", 1).Any()
+	for i := 0; i < 100; i++ {
+		if err := store.Insert(ctx, event.New("foo", i).Any()); err != nil {
+			t.Fatalf("Insert() should not fail when only Find is flaky; got %v", err)
+		}
+	}
+
+	if err := store.Insert(ctx, evt); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	var failed int
+	for i := 0; i < 100; i++ {
+		if _, err := store.Find(ctx, evt.ID()); err != nil {
+			if !errors.Is(err, eventstoretest.ErrInjectedTimeout) {
+				t.Fatalf("Find() should fail with %q; got %v", eventstoretest.ErrInjectedTimeout, err)
+			}
+			failed++
+		}
+	}
+
+	if failed < 25 || failed > 75 {
+		t.Fatalf("about half of the operations should fail; %d of 100 failed", failed)
+	}
+}
+
+func countEvents(t *testing.T, store event.Store, id uuid.UUID) int {
+	str, errs, err := store.Query(context.Background(), query.New(query.AggregateID(id)))
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	return len(events)
+}
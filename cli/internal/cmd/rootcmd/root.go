@@ -4,6 +4,7 @@ import (
 	"github.com/MakeNowJust/heredoc"
 	"github.com/modernice/goes/cli/internal/clifactory"
 	"github.com/modernice/goes/cli/internal/cmd/projectioncmd"
+	"github.com/modernice/goes/cli/internal/cmd/servicecmd"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +17,7 @@ func New(f *clifactory.Factory) *cobra.Command {
 		SilenceUsage:  true,
 		Example: heredoc.Doc(`
 			$ goes projection trigger foo bar baz --reset
+			$ goes new-service github.com/acme/shop --aggregate order
 		`),
 	}
 
@@ -34,6 +36,7 @@ func New(f *clifactory.Factory) *cobra.Command {
 	)
 
 	cmd.AddCommand(projectioncmd.New(f))
+	cmd.AddCommand(servicecmd.New())
 
 	return cmd
 }
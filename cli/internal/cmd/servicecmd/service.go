@@ -0,0 +1,145 @@
+package servicecmd
+
+import (
+	"fmt"
+	"path"
+	"runtime/debug"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/logrusorgru/aurora"
+	"github.com/modernice/goes/cli/internal/cliargs"
+	"github.com/modernice/goes/cli/scaffold"
+	"github.com/spf13/cobra"
+)
+
+// New returns the new-service command.
+func New() *cobra.Command {
+	var cfg struct {
+		dir         string
+		service     string
+		aggregate   string
+		commands    []string
+		bus         string
+		store       string
+		goesVersion string
+	}
+
+	cmd := &cobra.Command{
+		Use:   "new-service <module>",
+		Short: "Generate a service skeleton",
+		Long: heredoc.Doc(`
+			Generate the skeleton of a service that uses goes.
+
+			The generated service contains an aggregate with commands and
+			events, a projection that is kept up-to-date by a continuous
+			schedule, and a main package that wires up the chosen backends.
+			Commands are specified as <command>:<event> pairs, where the event
+			is raised by the command.
+
+			Run "go mod tidy" in the generated directory to add the
+			requirements of the service.
+		`),
+		Example: heredoc.Doc(`
+			Generate an "order" service with an in-memory backend:
+
+			$ goes new-service github.com/acme/order --aggregate order
+
+			Generate a "shop" service that uses NATS and MongoDB:
+
+			$ goes new-service github.com/acme/shop --aggregate order \
+				--command place:placed --command cancel:canceled \
+				--bus nats --store mongo
+		`),
+		Args: cliargs.MinimumN(1, "Must provide the module path of the service."),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			module := args[0]
+
+			commands, err := parseCommands(cfg.commands)
+			if err != nil {
+				return err
+			}
+
+			files, err := scaffold.Generate(scaffold.Config{
+				Module:      module,
+				Service:     cfg.service,
+				Aggregate:   cfg.aggregate,
+				Commands:    commands,
+				Bus:         scaffold.Backend(cfg.bus),
+				Store:       scaffold.Backend(cfg.store),
+				GoesVersion: cfg.goesVersion,
+			})
+			if err != nil {
+				return err
+			}
+
+			dir := cfg.dir
+			if dir == "" {
+				dir = path.Base(module)
+			}
+
+			if err := scaffold.Write(dir, files); err != nil {
+				return err
+			}
+
+			paths := make([]string, len(files))
+			for i, f := range files {
+				paths[i] = "  " + f.Path
+			}
+
+			cmd.Print(aurora.Green(heredoc.Docf(`
+				Service generated.
+
+				Directory: %s
+				Files:
+			`, dir)).String())
+			cmd.Println(strings.Join(paths, "\n"))
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&cfg.dir, "dir", "", "Output directory (defaults to the last element of the module path)")
+	cmd.Flags().StringVar(&cfg.service, "service", "", "Service name (defaults to the last element of the module path)")
+	cmd.Flags().StringVar(&cfg.aggregate, "aggregate", "", "Aggregate name")
+	cmd.Flags().StringArrayVar(&cfg.commands, "command", nil, "Command of the aggregate as <command>:<event> (repeatable)")
+	cmd.Flags().StringVar(&cfg.bus, "bus", string(scaffold.Memory), "Event bus backend (memory, nats)")
+	cmd.Flags().StringVar(&cfg.store, "store", string(scaffold.Memory), "Event store backend (memory, mongo, postgres)")
+	cmd.Flags().StringVar(&cfg.goesVersion, "goes-version", goesVersion(), "Required version of goes")
+	cmd.MarkFlagRequired("aggregate")
+
+	return cmd
+}
+
+func parseCommands(flags []string) ([]scaffold.Command, error) {
+	commands := make([]scaffold.Command, 0, len(flags))
+	for _, f := range flags {
+		name, evt, ok := strings.Cut(f, ":")
+		if !ok || name == "" || evt == "" {
+			return nil, fmt.Errorf("invalid command %q: must be specified as <command>:<event>", f)
+		}
+		commands = append(commands, scaffold.Command{Name: name, Event: evt})
+	}
+	return commands, nil
+}
+
+// goesVersion returns the version of goes that the CLI was built with, or an
+// empty string if the version is unknown.
+func goesVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	if info.Main.Path == "github.com/modernice/goes" && strings.HasPrefix(info.Main.Version, "v") {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/modernice/goes" && strings.HasPrefix(dep.Version, "v") {
+			return dep.Version
+		}
+	}
+
+	return ""
+}
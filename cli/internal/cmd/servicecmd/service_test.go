@@ -0,0 +1,73 @@
+package servicecmd_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modernice/goes/cli/internal/cmd/servicecmd"
+)
+
+func TestCommand(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "shop")
+
+	cmd := servicecmd.New()
+	cmd.SetArgs([]string{
+		"github.com/acme/shop",
+		"--dir", dir,
+		"--aggregate", "order",
+		"--command", "place:placed",
+		"--command", "cancel:canceled",
+		"--bus", "nats",
+		"--store", "mongo",
+		"--goes-version", "v0.5.0",
+	})
+
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute failed with %q", err)
+	}
+
+	if !strings.Contains(out.String(), "order/order.go") {
+		t.Fatalf("output should list the generated files\n\n%s", out.String())
+	}
+
+	events, err := os.ReadFile(filepath.Join(dir, "order", "events.go"))
+	if err != nil {
+		t.Fatalf("read events: %v", err)
+	}
+
+	for _, name := range []string{`"shop.order.placed"`, `"shop.order.canceled"`} {
+		if !bytes.Contains(events, []byte(name)) {
+			t.Fatalf("events.go should contain %s\n\n%s", name, events)
+		}
+	}
+
+	gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatalf("read go.mod: %v", err)
+	}
+
+	if !bytes.Contains(gomod, []byte("github.com/modernice/goes v0.5.0")) {
+		t.Fatalf("go.mod should require goes v0.5.0\n\n%s", gomod)
+	}
+}
+
+func TestCommand_invalidCommand(t *testing.T) {
+	cmd := servicecmd.New()
+	cmd.SetArgs([]string{
+		"github.com/acme/shop",
+		"--dir", t.TempDir(),
+		"--aggregate", "order",
+		"--command", "place",
+	})
+	cmd.SetOutput(&bytes.Buffer{})
+
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("Execute should fail for a command without an event")
+	}
+}
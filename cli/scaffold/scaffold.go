@@ -0,0 +1,310 @@
+// Package scaffold generates the skeleton of a goes service.
+//
+// A generated service contains an aggregate with commands and events, a
+// projection that is kept up-to-date by a continuous schedule, and a main
+// package that wires everything up using the chosen backends:
+//
+//	files, err := scaffold.Generate(scaffold.Config{
+//		Module:    "github.com/acme/shop",
+//		Aggregate: "order",
+//		Commands: []scaffold.Command{
+//			{Name: "place", Event: "placed"},
+//			{Name: "cancel", Event: "canceled"},
+//		},
+//		Bus:   scaffold.NATS,
+//		Store: scaffold.Mongo,
+//	})
+//	// handle err
+//	err = scaffold.Write("./shop", files)
+//
+// The generated go.mod does not contain any requirements. Run "go mod tidy"
+// within the generated directory to add them.
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Backend is a backend of a generated service.
+type Backend string
+
+const (
+	// Memory is the in-memory backend. It can be used as the event bus and
+	// the event store.
+	Memory = Backend("memory")
+
+	// NATS is the NATS backend. It can be used as the event bus.
+	NATS = Backend("nats")
+
+	// Mongo is the MongoDB backend. It can be used as the event store.
+	Mongo = Backend("mongo")
+
+	// Postgres is the PostgreSQL backend. It can be used as the event store.
+	Postgres = Backend("postgres")
+)
+
+var (
+	// ErrInvalidConfig is returned by Generate if the provided Config is
+	// invalid.
+	ErrInvalidConfig = errors.New("invalid config")
+
+	// ErrFileExists is returned by Write if a file that would be written
+	// already exists.
+	ErrFileExists = errors.New("file already exists")
+)
+
+// DefaultCommands are the commands of a generated aggregate if no commands are
+// configured.
+var DefaultCommands = []Command{{Name: "create", Event: "created"}}
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9]*([_-][a-z0-9]+)*$`)
+
+// reservedPackages are the package names that would conflict with the imports
+// or variables of the generated code.
+var reservedPackages = map[string]bool{
+	"aggregate": true, "bus": true, "cbus": true, "codec": true, "command": true,
+	"commands": true, "context": true, "event": true, "events": true, "handler": true,
+	"main": true, "projection": true, "repo": true, "repository": true, "schedule": true,
+	"store": true, "summary": true, "uuid": true,
+}
+
+// Config configures a generated service.
+type Config struct {
+	// Module is the module path of the service, e.g. "github.com/acme/shop".
+	Module string
+
+	// Service is the name of the service. Event, command, and aggregate
+	// names are prefixed with the service name. Defaults to the last element
+	// of the module path.
+	Service string
+
+	// Aggregate is the name of the aggregate, e.g. "order".
+	Aggregate string
+
+	// Commands are the commands of the aggregate. Defaults to
+	// DefaultCommands.
+	Commands []Command
+
+	// Bus is the backend of the event bus, either Memory or NATS. Defaults
+	// to Memory.
+	Bus Backend
+
+	// Store is the backend of the event store, either Memory, Mongo, or
+	// Postgres. Defaults to Memory.
+	Store Backend
+
+	// GoesVersion, if provided, is the version of goes that is required by
+	// the generated go.mod.
+	GoesVersion string
+}
+
+// Command is a command of a generated aggregate.
+type Command struct {
+	// Name is the name of the command, e.g. "place".
+	Name string
+
+	// Event is the name of the event that is raised by the command, e.g.
+	// "placed".
+	Event string
+}
+
+// File is a generated file.
+type File struct {
+	// Path is the slash-separated path of the file, relative to the
+	// directory of the service.
+	Path string
+
+	// Content is the content of the file.
+	Content []byte
+}
+
+// Generate generates the files of the service that is configured by cfg.
+// Generate returns an error that unwraps to ErrInvalidConfig if the Config is
+// invalid.
+func Generate(cfg Config) ([]File, error) {
+	data, err := newTemplateData(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := data.Package
+	sources := []struct {
+		path string
+		tmpl *template.Template
+	}{
+		{"go.mod", goModTemplate},
+		{"README.md", readmeTemplate},
+		{path.Join(pkg, "events.go"), eventsTemplate},
+		{path.Join(pkg, "commands.go"), commandsTemplate},
+		{path.Join(pkg, pkg+".go"), aggregateTemplate},
+		{path.Join(pkg, "summary.go"), summaryTemplate},
+		{path.Join("cmd", data.Service, "main.go"), mainTemplate},
+	}
+
+	files := make([]File, 0, len(sources))
+	for _, src := range sources {
+		var buf bytes.Buffer
+		if err := src.tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("execute %q template: %w", src.path, err)
+		}
+
+		content := buf.Bytes()
+		if strings.HasSuffix(src.path, ".go") {
+			if content, err = format.Source(content); err != nil {
+				return nil, fmt.Errorf("format %q: %w", src.path, err)
+			}
+		}
+
+		files = append(files, File{Path: src.path, Content: content})
+	}
+
+	return files, nil
+}
+
+// Write writes the given files into dir. Write does not overwrite existing
+// files: if any of the files already exists, Write returns an error that
+// unwraps to ErrFileExists before writing any file.
+func Write(dir string, files []File) error {
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if _, err := os.Stat(p); err == nil {
+			return fmt.Errorf("%w: %s", ErrFileExists, p)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("stat %s: %w", p, err)
+		}
+	}
+
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return fmt.Errorf("create directory: %w", err)
+		}
+		if err := os.WriteFile(p, f.Content, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", p, err)
+		}
+	}
+
+	return nil
+}
+
+type templateData struct {
+	Module      string
+	Service     string
+	Package     string
+	Type        string
+	Receiver    string
+	Aggregate   string
+	Commands    []templateCommand
+	Bus         Backend
+	Store       Backend
+	GoesVersion string
+}
+
+type templateCommand struct {
+	Name       string
+	Event      string
+	Method     string
+	Func       string
+	EventConst string
+	CmdConst   string
+	Data       string
+	Applier    string
+}
+
+func newTemplateData(cfg Config) (templateData, error) {
+	if cfg.Module == "" {
+		return templateData{}, fmt.Errorf("%w: module path is required", ErrInvalidConfig)
+	}
+
+	if cfg.Service == "" {
+		cfg.Service = path.Base(cfg.Module)
+	}
+	if cfg.Bus == "" {
+		cfg.Bus = Memory
+	}
+	if cfg.Store == "" {
+		cfg.Store = Memory
+	}
+	if len(cfg.Commands) == 0 {
+		cfg.Commands = DefaultCommands
+	}
+
+	if !namePattern.MatchString(cfg.Service) {
+		return templateData{}, fmt.Errorf("%w: invalid service name %q", ErrInvalidConfig, cfg.Service)
+	}
+	if !namePattern.MatchString(cfg.Aggregate) {
+		return templateData{}, fmt.Errorf("%w: invalid aggregate name %q", ErrInvalidConfig, cfg.Aggregate)
+	}
+	if cfg.Bus != Memory && cfg.Bus != NATS {
+		return templateData{}, fmt.Errorf("%w: unsupported event bus %q", ErrInvalidConfig, cfg.Bus)
+	}
+	if cfg.Store != Memory && cfg.Store != Mongo && cfg.Store != Postgres {
+		return templateData{}, fmt.Errorf("%w: unsupported event store %q", ErrInvalidConfig, cfg.Store)
+	}
+
+	pkg := strings.NewReplacer("_", "", "-", "").Replace(cfg.Aggregate)
+	if reservedPackages[pkg] || token.IsKeyword(pkg) {
+		return templateData{}, fmt.Errorf("%w: aggregate name %q conflicts with the generated code", ErrInvalidConfig, cfg.Aggregate)
+	}
+
+	typ := identifier(cfg.Aggregate)
+	data := templateData{
+		Module:      cfg.Module,
+		Service:     cfg.Service,
+		Package:     pkg,
+		Type:        typ,
+		Receiver:    strings.ToLower(typ[:1]),
+		Aggregate:   fmt.Sprintf("%s.%s", cfg.Service, cfg.Aggregate),
+		Bus:         cfg.Bus,
+		Store:       cfg.Store,
+		GoesVersion: cfg.GoesVersion,
+	}
+
+	seen := make(map[string]bool)
+	for _, cmd := range cfg.Commands {
+		if !namePattern.MatchString(cmd.Name) {
+			return templateData{}, fmt.Errorf("%w: invalid command name %q", ErrInvalidConfig, cmd.Name)
+		}
+		if !namePattern.MatchString(cmd.Event) {
+			return templateData{}, fmt.Errorf("%w: invalid event name %q", ErrInvalidConfig, cmd.Event)
+		}
+		if seen["cmd:"+cmd.Name] || seen["evt:"+cmd.Event] {
+			return templateData{}, fmt.Errorf("%w: duplicate command %q or event %q", ErrInvalidConfig, cmd.Name, cmd.Event)
+		}
+		seen["cmd:"+cmd.Name], seen["evt:"+cmd.Event] = true, true
+
+		method, event := identifier(cmd.Name), identifier(cmd.Event)
+		data.Commands = append(data.Commands, templateCommand{
+			Name:       fmt.Sprintf("%s.%s", data.Aggregate, cmd.Name),
+			Event:      fmt.Sprintf("%s.%s", data.Aggregate, cmd.Event),
+			Method:     method,
+			Func:       method + typ,
+			EventConst: typ + event,
+			CmdConst:   method + typ + "Cmd",
+			Data:       typ + event + "Data",
+			Applier:    strings.ToLower(event[:1]) + event[1:],
+		})
+	}
+
+	return data, nil
+}
+
+// identifier converts a name like "line_item" to an exported Go identifier
+// like "LineItem".
+func identifier(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	for i, p := range parts {
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,145 @@
+package scaffold_test
+
+import (
+	"bytes"
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modernice/goes/cli/scaffold"
+)
+
+func TestGenerate(t *testing.T) {
+	files, err := scaffold.Generate(scaffold.Config{
+		Module:    "github.com/acme/shop",
+		Aggregate: "line_item",
+		Commands: []scaffold.Command{
+			{Name: "add", Event: "added"},
+			{Name: "remove", Event: "removed"},
+		},
+		Bus:   scaffold.NATS,
+		Store: scaffold.Postgres,
+	})
+	if err != nil {
+		t.Fatalf("Generate failed with %q", err)
+	}
+
+	want := []string{
+		"go.mod",
+		"README.md",
+		"lineitem/events.go",
+		"lineitem/commands.go",
+		"lineitem/lineitem.go",
+		"lineitem/summary.go",
+		"cmd/shop/main.go",
+	}
+
+	if len(files) != len(want) {
+		t.Fatalf("Generate should return %d files; got %d", len(want), len(files))
+	}
+
+	contents := make(map[string][]byte)
+	for i, f := range files {
+		if f.Path != want[i] {
+			t.Fatalf("file %d should be %q; is %q", i, want[i], f.Path)
+		}
+		contents[f.Path] = f.Content
+
+		if strings.HasSuffix(f.Path, ".go") {
+			if _, err := parser.ParseFile(token.NewFileSet(), f.Path, f.Content, 0); err != nil {
+				t.Fatalf("%s should be valid Go code: %v", f.Path, err)
+			}
+		}
+	}
+
+	for path, snippets := range map[string][]string{
+		"go.mod":               {"module github.com/acme/shop"},
+		"lineitem/events.go":   {`LineItemAdded   = "shop.line_item.added"`, "LineItemEvents"},
+		"lineitem/commands.go": {`AddLineItemCmd    = "shop.line_item.add"`, "func RemoveLineItem(id uuid.UUID"},
+		"lineitem/lineitem.go": {`const LineItemAggregate = "shop.line_item"`, "command.ApplyWith(l, l.Remove, RemoveLineItemCmd)"},
+		"lineitem/summary.go":  {"schedule.Continuously(bus, store, LineItemEvents[:], opts...)"},
+		"cmd/shop/main.go":     {"nats.NewEventBus(events)", "postgres.NewEventStore(events)"},
+	} {
+		for _, snippet := range snippets {
+			if !bytes.Contains(contents[path], []byte(snippet)) {
+				t.Errorf("%s should contain %q\n\n%s", path, snippet, contents[path])
+			}
+		}
+	}
+}
+
+func TestGenerate_goesVersion(t *testing.T) {
+	files, err := scaffold.Generate(scaffold.Config{
+		Module:      "github.com/acme/shop",
+		Aggregate:   "order",
+		GoesVersion: "v0.5.0",
+	})
+	if err != nil {
+		t.Fatalf("Generate failed with %q", err)
+	}
+
+	if !bytes.Contains(files[0].Content, []byte("require github.com/modernice/goes v0.5.0")) {
+		t.Fatalf("go.mod should require goes v0.5.0\n\n%s", files[0].Content)
+	}
+}
+
+func TestGenerate_invalidConfig(t *testing.T) {
+	valid := scaffold.Config{Module: "github.com/acme/shop", Aggregate: "order"}
+
+	tests := map[string]func(*scaffold.Config){
+		"missing module":    func(cfg *scaffold.Config) { cfg.Module = "" },
+		"missing aggregate": func(cfg *scaffold.Config) { cfg.Aggregate = "" },
+		"invalid aggregate": func(cfg *scaffold.Config) { cfg.Aggregate = "Order" },
+		"reserved package":  func(cfg *scaffold.Config) { cfg.Aggregate = "event" },
+		"keyword package":   func(cfg *scaffold.Config) { cfg.Aggregate = "type" },
+		"invalid service":   func(cfg *scaffold.Config) { cfg.Service = "my service" },
+		"invalid bus":       func(cfg *scaffold.Config) { cfg.Bus = scaffold.Mongo },
+		"invalid store":     func(cfg *scaffold.Config) { cfg.Store = scaffold.NATS },
+		"invalid command":   func(cfg *scaffold.Config) { cfg.Commands = []scaffold.Command{{Name: "place"}} },
+		"duplicate command": func(cfg *scaffold.Config) {
+			cfg.Commands = []scaffold.Command{{Name: "place", Event: "placed"}, {Name: "place", Event: "replaced"}}
+		},
+	}
+
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := valid
+			modify(&cfg)
+
+			if _, err := scaffold.Generate(cfg); !errors.Is(err, scaffold.ErrInvalidConfig) {
+				t.Fatalf("Generate should fail with %q; got %q", scaffold.ErrInvalidConfig, err)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	files, err := scaffold.Generate(scaffold.Config{Module: "github.com/acme/shop", Aggregate: "order"})
+	if err != nil {
+		t.Fatalf("Generate failed with %q", err)
+	}
+
+	if err := scaffold.Write(dir, files); err != nil {
+		t.Fatalf("Write failed with %q", err)
+	}
+
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Path, err)
+		}
+		if !bytes.Equal(b, f.Content) {
+			t.Fatalf("%s has wrong content", f.Path)
+		}
+	}
+
+	if err := scaffold.Write(dir, files); !errors.Is(err, scaffold.ErrFileExists) {
+		t.Fatalf("Write should fail with %q; got %q", scaffold.ErrFileExists, err)
+	}
+}
@@ -0,0 +1,305 @@
+package scaffold
+
+import "text/template"
+
+var goModTemplate = template.Must(template.New("go.mod").Parse(`module {{.Module}}
+
+go 1.22
+{{- if .GoesVersion}}
+
+require github.com/modernice/goes {{.GoesVersion}}
+{{- end}}
+`))
+
+var readmeTemplate = template.Must(template.New("README.md").Parse("# {{.Service}}\n" + `
+This service was generated by ` + "`goes new-service`" + `.
+
+- ` + "`{{.Package}}/{{.Package}}.go`" + ` – the {{.Type}} aggregate
+- ` + "`{{.Package}}/events.go`" + ` – the events of the aggregate
+- ` + "`{{.Package}}/commands.go`" + ` – the commands of the aggregate
+- ` + "`{{.Package}}/summary.go`" + ` – a projection of the aggregate's events
+- ` + "`cmd/{{.Service}}/main.go`" + ` – wires up the backends, the command handler, and the projection
+
+## Getting started
+
+` + "```sh" + `
+go mod tidy
+go run ./cmd/{{.Service}}
+` + "```" + `
+{{- if or (eq .Bus "nats") (ne .Store "memory")}}
+
+The service connects to its backends using the following environment variables:
+{{if eq .Bus "nats"}}
+- ` + "`NATS_URL`" + ` – the URL of the NATS server
+{{- end}}
+{{- if eq .Store "mongo"}}
+- ` + "`MONGO_URL`" + ` – the URL of the MongoDB server
+{{- end}}
+{{- if eq .Store "postgres"}}
+- ` + "`POSTGRES_EVENTSTORE`" + ` – the connection string of the PostgreSQL server
+{{- end}}
+{{- end}}
+
+Events and commands are registered in ` + "`RegisterEvents`" + ` and
+` + "`RegisterCommands`" + `. When you add an event, also add it to
+` + "`{{.Type}}Events`" + `, so that the projection subscribes to it.
+`))
+
+var eventsTemplate = template.Must(template.New("events.go").Parse(`package {{.Package}}
+
+import "github.com/modernice/goes/codec"
+
+// Events
+const (
+{{- range .Commands}}
+	{{.EventConst}} = "{{.Event}}"
+{{- end}}
+)
+
+// {{.Type}}Events are all events of the {{.Type}} aggregate.
+var {{.Type}}Events = [...]string{
+{{- range .Commands}}
+	{{.EventConst}},
+{{- end}}
+}
+{{range .Commands}}
+// {{.Data}} is the event data of the {{.EventConst}} event.
+type {{.Data}} struct {
+	// TODO: add fields
+}
+{{end}}
+// RegisterEvents registers the events of the {{.Type}} aggregate into a
+// registry.
+func RegisterEvents(r codec.Registerer) {
+{{- range .Commands}}
+	codec.Register[{{.Data}}](r, {{.EventConst}})
+{{- end}}
+}
+`))
+
+var commandsTemplate = template.Must(template.New("commands.go").Parse(`package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/handler"
+)
+
+// Commands
+const (
+{{- range .Commands}}
+	{{.CmdConst}} = "{{.Name}}"
+{{- end}}
+)
+{{range .Commands}}
+// {{.Func}} returns the command that raises the {{.EventConst}} event for the
+// {{$.Type}} with the given id.
+func {{.Func}}(id uuid.UUID, data {{.Data}}) command.Cmd[{{.Data}}] {
+	return command.New({{.CmdConst}}, data, command.Aggregate({{$.Type}}Aggregate, id))
+}
+{{end}}
+// RegisterCommands registers the commands of the {{.Type}} aggregate into a
+// registry.
+func RegisterCommands(r codec.Registerer) {
+{{- range .Commands}}
+	codec.Register[{{.Data}}](r, {{.CmdConst}})
+{{- end}}
+}
+
+// HandleCommands handles the commands of the {{.Type}} aggregate that are
+// dispatched over the provided command bus until ctx is canceled. Command
+// errors are sent into the returned error channel.
+func HandleCommands(ctx context.Context, bus command.Bus, repo aggregate.Repository) <-chan error {
+	return handler.New(New, repo, bus).MustHandle(ctx)
+}
+`))
+
+var aggregateTemplate = template.Must(template.New("aggregate.go").Parse(`package {{.Package}}
+
+import (
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/handler"
+	"github.com/modernice/goes/event"
+)
+
+// {{.Type}}Aggregate is the name of the {{.Type}} aggregate.
+const {{.Type}}Aggregate = "{{.Aggregate}}"
+
+// {{.Type}} is the {{.Type}} aggregate.
+type {{.Type}} struct {
+	*aggregate.Base
+	*handler.BaseHandler
+}
+
+// New returns the {{.Type}} with the given id.
+func New(id uuid.UUID) *{{.Type}} {
+	{{.Receiver}} := &{{.Type}}{
+		Base:        aggregate.New({{.Type}}Aggregate, id),
+		BaseHandler: handler.NewBase(),
+	}
+
+	// Register the event appliers.
+{{- range .Commands}}
+	event.ApplyWith({{$.Receiver}}, {{$.Receiver}}.{{.Applier}}, {{.EventConst}})
+{{- end}}
+
+	// Register the command handlers.
+{{- range .Commands}}
+	command.ApplyWith({{$.Receiver}}, {{$.Receiver}}.{{.Method}}, {{.CmdConst}})
+{{- end}}
+
+	return {{.Receiver}}
+}
+{{range .Commands}}
+// {{.Method}} raises the {{.EventConst}} event.
+func ({{$.Receiver}} *{{$.Type}}) {{.Method}}(data {{.Data}}) error {
+	// TODO: validate the command against the current state.
+	aggregate.Next({{$.Receiver}}, {{.EventConst}}, data)
+	return nil
+}
+
+func ({{$.Receiver}} *{{$.Type}}) {{.Applier}}(evt event.Of[{{.Data}}]) {
+	// TODO: apply the event to the state.
+}
+{{end}}`))
+
+var summaryTemplate = template.Must(template.New("summary.go").Parse(`package {{.Package}}
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+// Summary is a read model that counts the events of each {{.Type}}.
+type Summary struct {
+	mux    sync.RWMutex
+	events map[uuid.UUID]int
+}
+
+// NewSummary returns a new Summary.
+func NewSummary() *Summary {
+	return &Summary{events: make(map[uuid.UUID]int)}
+}
+
+// {{.Type}}s returns the number of {{.Type}} aggregates.
+func (s *Summary) {{.Type}}s() int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return len(s.events)
+}
+
+// Events returns the number of events of the {{.Type}} with the given id.
+func (s *Summary) Events(id uuid.UUID) int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.events[id]
+}
+
+// ApplyEvent implements projection.EventApplier.
+func (s *Summary) ApplyEvent(evt event.Event) {
+	id, _, _ := evt.Aggregate()
+	s.events[id]++
+}
+
+// Project projects the Summary until ctx is canceled. The Summary is built
+// from the event store on startup and updated each time one of the
+// {{.Type}}Events is published.
+func (s *Summary) Project(ctx context.Context, bus event.Bus, store event.Store, opts ...schedule.ContinuousOption) (<-chan error, error) {
+	sched := schedule.Continuously(bus, store, {{.Type}}Events[:], opts...)
+
+	return sched.Subscribe(ctx, func(job projection.Job) error {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		return job.Apply(job, s)
+	}, projection.Startup())
+}
+`))
+
+var mainTemplate = template.Must(template.New("main.go").Parse(`package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/modernice/goes/aggregate/repository"
+{{- if eq .Store "mongo"}}
+	"github.com/modernice/goes/backend/mongo"
+{{- end}}
+{{- if eq .Bus "nats"}}
+	"github.com/modernice/goes/backend/nats"
+{{- end}}
+{{- if eq .Store "postgres"}}
+	"github.com/modernice/goes/backend/postgres"
+{{- end}}
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/cmdbus"
+	"github.com/modernice/goes/event"
+{{- if eq .Bus "memory"}}
+	"github.com/modernice/goes/event/eventbus"
+{{- end}}
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection/schedule"
+	"{{.Module}}/{{.Package}}"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	events := event.NewRegistry()
+	{{.Package}}.RegisterEvents(events)
+	cmdbus.RegisterEvents(events)
+
+	commands := command.NewRegistry()
+	{{.Package}}.RegisterCommands(commands)
+{{if eq .Bus "nats"}}
+	bus := nats.NewEventBus(events)
+	defer func() {
+		if err := bus.Disconnect(context.Background()); err != nil {
+			log.Printf("Failed to disconnect from NATS: %v", err)
+		}
+	}()
+{{- else}}
+	bus := eventbus.New()
+{{- end}}
+{{if eq .Store "mongo"}}
+	store := eventstore.WithBus(mongo.NewEventStore(events), bus)
+{{- else if eq .Store "postgres"}}
+	store := eventstore.WithBus(postgres.NewEventStore(events), bus)
+{{- else}}
+	store := eventstore.WithBus(eventstore.New(), bus)
+{{- end}}
+	repo := repository.New(store)
+	cbus := cmdbus.New[int](commands, bus)
+
+	commandErrors := {{.Package}}.HandleCommands(ctx, cbus, repo)
+
+	summary := {{.Package}}.NewSummary()
+	summaryErrors, err := summary.Project(ctx, bus, store, schedule.Debounce(100*time.Millisecond))
+	if err != nil {
+		log.Fatalf("Failed to project summary: %v", err)
+	}
+
+	log.Printf("Running %q service ...", "{{.Service}}")
+
+	for err := range streams.FanInAll(commandErrors, summaryErrors) {
+		log.Printf("Error: %v", err)
+	}
+}
+`))
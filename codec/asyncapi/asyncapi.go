@@ -0,0 +1,290 @@
+// Package asyncapi generates AsyncAPI documents from codec registries.
+//
+// A codec registry knows every event that a service can publish and the Go
+// type of its payload. Generate walks the registry and describes each event as
+// an AsyncAPI message with a JSON Schema of its payload, on a channel for the
+// subject that the event is published under. The document can be published so
+// that other teams can discover the event contracts of a service:
+//
+//	doc, err := asyncapi.Generate(reg,
+//		asyncapi.Title("Shop"),
+//		asyncapi.Version("1.4.0"),
+//		asyncapi.Server("production", asyncapi.ServerInfo{
+//			URL:      "nats://nats.example.com:4222",
+//			Protocol: "nats",
+//		}),
+//		asyncapi.Subject(func(name string) string {
+//			return strings.ReplaceAll(name, ".", "_")
+//		}),
+//		asyncapi.Describe("shop.order.placed", "An order was placed by a customer."),
+//	)
+//
+//	err = doc.WriteJSON(os.Stdout)
+package asyncapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/modernice/goes/codec"
+)
+
+// SpecVersion is the AsyncAPI version of generated documents.
+const SpecVersion = "2.6.0"
+
+// Document is an AsyncAPI document.
+type Document struct {
+	AsyncAPI   string                `json:"asyncapi"`
+	Info       Info                  `json:"info"`
+	Servers    map[string]ServerInfo `json:"servers,omitempty"`
+	Channels   map[string]Channel    `json:"channels"`
+	Components Components            `json:"components"`
+}
+
+// Info contains metadata about the service that is described by a Document.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// ServerInfo describes a message broker (event bus) that events flow over.
+type ServerInfo struct {
+	URL         string `json:"url"`
+	Protocol    string `json:"protocol"`
+	Description string `json:"description,omitempty"`
+
+	// Events are the names of the events that flow over the server. If Events
+	// is empty, all events flow over the server.
+	Events []string `json:"-"`
+}
+
+// Channel is a channel (subject) of a Document.
+type Channel struct {
+	Description string    `json:"description,omitempty"`
+	Servers     []string  `json:"servers,omitempty"`
+	Subscribe   Operation `json:"subscribe"`
+}
+
+// Operation is an operation of a Channel. Channels of a generated Document
+// only have "subscribe" operations, which describe the messages that other
+// services can subscribe to.
+type Operation struct {
+	OperationID string    `json:"operationId"`
+	Message     Reference `json:"message"`
+}
+
+// Reference is a JSON reference to a component of a Document.
+type Reference struct {
+	Ref string `json:"$ref"`
+}
+
+// Components are the reusable components of a Document.
+type Components struct {
+	Messages map[string]Message `json:"messages,omitempty"`
+	Schemas  map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Message describes an event.
+type Message struct {
+	Name        string    `json:"name"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	ContentType string    `json:"contentType"`
+	Payload     Reference `json:"payload"`
+}
+
+// Option is an option for Generate.
+type Option func(*generator)
+
+type generator struct {
+	info         Info
+	servers      map[string]ServerInfo
+	subject      func(string) string
+	descriptions map[string]string
+	filter       []func(string) bool
+	contentType  string
+}
+
+// Title returns an Option that sets the title of the document.
+func Title(title string) Option {
+	return func(g *generator) {
+		g.info.Title = title
+	}
+}
+
+// Version returns an Option that sets the version of the document.
+func Version(version string) Option {
+	return func(g *generator) {
+		g.info.Version = version
+	}
+}
+
+// Description returns an Option that sets the description of the document.
+func Description(desc string) Option {
+	return func(g *generator) {
+		g.info.Description = desc
+	}
+}
+
+// Server returns an Option that adds a server (event bus) to the document.
+func Server(name string, info ServerInfo) Option {
+	return func(g *generator) {
+		g.servers[name] = info
+	}
+}
+
+// Subject returns an Option that sets the function that returns the subject
+// (channel) that an event is published under. By default, the subject is the
+// name of the event.
+func Subject(fn func(eventName string) string) Option {
+	return func(g *generator) {
+		g.subject = fn
+	}
+}
+
+// Describe returns an Option that sets the description of an event.
+func Describe(eventName, desc string) Option {
+	return func(g *generator) {
+		g.descriptions[eventName] = desc
+	}
+}
+
+// Filter returns an Option that only includes the events for which fn returns
+// true, e.g. to exclude internal events from the document. Multiple filters
+// must all return true for an event to be included.
+func Filter(fn func(eventName string) bool) Option {
+	return func(g *generator) {
+		g.filter = append(g.filter, fn)
+	}
+}
+
+// ContentType returns an Option that sets the content type of messages.
+// Defaults to "application/json".
+func ContentType(ct string) Option {
+	return func(g *generator) {
+		g.contentType = ct
+	}
+}
+
+// Generate returns an AsyncAPI document that describes the events registered
+// in the given registry. The payload schemas are derived from the registered
+// Go types (see SchemaOf).
+func Generate(reg *codec.Registry, opts ...Option) (Document, error) {
+	g := generator{
+		info:         Info{Title: "Events", Version: "0.0.0"},
+		servers:      make(map[string]ServerInfo),
+		subject:      func(name string) string { return name },
+		descriptions: make(map[string]string),
+		contentType:  "application/json",
+	}
+	for _, opt := range opts {
+		opt(&g)
+	}
+
+	doc := Document{
+		AsyncAPI: SpecVersion,
+		Info:     g.info,
+		Channels: make(map[string]Channel),
+		Components: Components{
+			Messages: make(map[string]Message),
+			Schemas:  make(map[string]*Schema),
+		},
+	}
+
+	if len(g.servers) > 0 {
+		doc.Servers = g.servers
+	}
+
+	factories := reg.Map()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		if g.includes(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		subject := g.subject(name)
+		if other, ok := doc.Channels[subject]; ok {
+			return Document{}, fmt.Errorf("events %q and %q are published under the same subject %q", other.Subscribe.OperationID, name, subject)
+		}
+
+		typ := reflect.TypeOf(factories[name]())
+		for typ != nil && typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+
+		doc.Components.Schemas[name] = SchemaOf(typ)
+		doc.Components.Messages[name] = Message{
+			Name:        name,
+			Title:       typeName(typ),
+			Description: g.descriptions[name],
+			ContentType: g.contentType,
+			Payload:     Reference{Ref: "#/components/schemas/" + name},
+		}
+		doc.Channels[subject] = Channel{
+			Description: g.descriptions[name],
+			Servers:     g.serversOf(name),
+			Subscribe: Operation{
+				OperationID: name,
+				Message:     Reference{Ref: "#/components/messages/" + name},
+			},
+		}
+	}
+
+	return doc, nil
+}
+
+// WriteJSON writes the document as indented JSON to w.
+func (doc Document) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func (g generator) includes(name string) bool {
+	for _, fn := range g.filter {
+		if !fn(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// serversOf returns the servers that the event flows over, or nil if it flows
+// over all servers.
+func (g generator) serversOf(name string) []string {
+	var out []string
+	var restricted bool
+	for server, info := range g.servers {
+		if len(info.Events) == 0 {
+			out = append(out, server)
+			continue
+		}
+		restricted = true
+		if slices.Contains(info.Events, name) {
+			out = append(out, server)
+		}
+	}
+
+	if !restricted {
+		return nil
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+func typeName(typ reflect.Type) string {
+	if typ == nil {
+		return ""
+	}
+	return typ.String()
+}
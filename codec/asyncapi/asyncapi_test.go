@@ -0,0 +1,160 @@
+package asyncapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/codec/asyncapi"
+)
+
+type orderPlaced struct {
+	OrderID  uuid.UUID         `json:"orderId"`
+	Items    []item            `json:"items"`
+	Total    float64           `json:"total"`
+	Note     string            `json:"note,omitempty"`
+	PlacedAt time.Time         `json:"placedAt"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Internal string            `json:"-"`
+}
+
+type item struct {
+	SKU      string
+	Quantity int
+}
+
+type category struct {
+	Name     string
+	Children []category
+}
+
+type custom struct{}
+
+func (*custom) AsyncAPISchema() *asyncapi.Schema {
+	return &asyncapi.Schema{Type: "string", Format: "custom"}
+}
+
+func TestGenerate(t *testing.T) {
+	reg := codec.New()
+	codec.Register[orderPlaced](reg, "shop.order.placed")
+	codec.Register[int](reg, "shop.order.canceled")
+	codec.Register[string](reg, "shop.internal.synced")
+
+	doc, err := asyncapi.Generate(reg,
+		asyncapi.Title("Shop"),
+		asyncapi.Version("1.0.0"),
+		asyncapi.Server("production", asyncapi.ServerInfo{URL: "nats://localhost:4222", Protocol: "nats"}),
+		asyncapi.Server("audit", asyncapi.ServerInfo{URL: "nats://audit:4222", Protocol: "nats", Events: []string{"shop.order.canceled"}}),
+		asyncapi.Subject(func(name string) string { return strings.ReplaceAll(name, ".", "_") }),
+		asyncapi.Describe("shop.order.placed", "An order was placed."),
+		asyncapi.Filter(func(name string) bool { return !strings.Contains(name, ".internal.") }),
+	)
+	if err != nil {
+		t.Fatalf("Generate failed with %q", err)
+	}
+
+	if doc.AsyncAPI != asyncapi.SpecVersion || doc.Info.Title != "Shop" || doc.Info.Version != "1.0.0" {
+		t.Fatalf("unexpected document metadata: %q %+v", doc.AsyncAPI, doc.Info)
+	}
+
+	if len(doc.Channels) != 2 {
+		t.Fatalf("document should have %d channels; got %d", 2, len(doc.Channels))
+	}
+
+	placed, ok := doc.Channels["shop_order_placed"]
+	if !ok {
+		t.Fatalf("document should have a channel for subject %q", "shop_order_placed")
+	}
+
+	if placed.Description != "An order was placed." {
+		t.Fatalf("channel should have the event description; got %q", placed.Description)
+	}
+
+	if want := []string{"production"}; !slices.Equal(want, placed.Servers) {
+		t.Fatalf("%q event should flow over %v; got %v", "shop.order.placed", want, placed.Servers)
+	}
+
+	canceled := doc.Channels["shop_order_canceled"]
+	if want := []string{"audit", "production"}; !slices.Equal(want, canceled.Servers) {
+		t.Fatalf("%q event should flow over %v; got %v", "shop.order.canceled", want, canceled.Servers)
+	}
+
+	if placed.Subscribe.Message.Ref != "#/components/messages/shop.order.placed" {
+		t.Fatalf("channel should reference the message; got %q", placed.Subscribe.Message.Ref)
+	}
+
+	schema := doc.Components.Schemas["shop.order.placed"]
+	if schema == nil || schema.Type != "object" {
+		t.Fatalf("payload schema should be an object; got %+v", schema)
+	}
+
+	if want := []string{"orderId", "items", "total", "placedAt"}; !slices.Equal(want, schema.Required) {
+		t.Fatalf("required properties should be %v; got %v", want, schema.Required)
+	}
+
+	if _, ok := schema.Properties["Internal"]; ok {
+		t.Fatalf("skipped fields should not be described")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed with %q", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJSON should write valid JSON: %v", err)
+	}
+}
+
+func TestGenerate_duplicateSubject(t *testing.T) {
+	reg := codec.New()
+	codec.Register[int](reg, "foo.bar")
+	codec.Register[int](reg, "foo_bar")
+
+	if _, err := asyncapi.Generate(reg, asyncapi.Subject(func(name string) string {
+		return strings.ReplaceAll(name, ".", "_")
+	})); err == nil {
+		t.Fatalf("Generate should fail if two events are published under the same subject")
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	tests := []struct {
+		typ  reflect.Type
+		want asyncapi.Schema
+	}{
+		{typ: reflect.TypeOf(""), want: asyncapi.Schema{Type: "string"}},
+		{typ: reflect.TypeOf(0), want: asyncapi.Schema{Type: "integer"}},
+		{typ: reflect.TypeOf(true), want: asyncapi.Schema{Type: "boolean"}},
+		{typ: reflect.TypeOf([]byte{}), want: asyncapi.Schema{Type: "string", Format: "byte"}},
+		{typ: reflect.TypeOf(time.Time{}), want: asyncapi.Schema{Type: "string", Format: "date-time"}},
+		{typ: reflect.TypeOf(uuid.UUID{}), want: asyncapi.Schema{Type: "string"}},
+		{typ: reflect.TypeOf(custom{}), want: asyncapi.Schema{Type: "string", Format: "custom"}},
+	}
+
+	for _, tt := range tests {
+		if got := asyncapi.SchemaOf(tt.typ); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("SchemaOf(%s) should return %+v; got %+v", tt.typ, tt.want, *got)
+		}
+	}
+}
+
+func TestSchemaOf_recursive(t *testing.T) {
+	schema := asyncapi.SchemaOf(reflect.TypeOf(category{}))
+
+	children := schema.Properties["Children"]
+	if children == nil || children.Type != "array" || children.Items.Type != "object" {
+		t.Fatalf("recursive field should be an array of objects; got %+v", children)
+	}
+
+	if children.Items.Properties != nil {
+		t.Fatalf("recursive type should not be expanded a second time")
+	}
+}
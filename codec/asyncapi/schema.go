@@ -0,0 +1,159 @@
+package asyncapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Schemer can be implemented by payload types to provide their own schema,
+// e.g. if they implement a custom marshaler.
+type Schemer interface {
+	AsyncAPISchema() *Schema
+}
+
+var (
+	schemerType       = reflect.TypeOf((*Schemer)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+// SchemaOf returns the JSON Schema of the JSON encoding of the given type.
+// Struct fields are described according to their "json" tags; fields without
+// "omitempty" are required. Types that implement json.Marshaler are described
+// by an empty schema (any value), unless they implement Schemer; types that
+// implement encoding.TextMarshaler are described as strings.
+func SchemaOf(typ reflect.Type) *Schema {
+	return schemaOf(typ, make(map[reflect.Type]bool))
+}
+
+func schemaOf(typ reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if typ == nil {
+		return &Schema{}
+	}
+
+	if typ.Kind() == reflect.Pointer {
+		return schemaOf(typ.Elem(), visiting)
+	}
+
+	if reflect.PointerTo(typ).Implements(schemerType) {
+		if s := reflect.New(typ).Interface().(Schemer).AsyncAPISchema(); s != nil {
+			return s
+		}
+	}
+
+	switch {
+	case typ == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case implements(typ, jsonMarshalerType):
+		return &Schema{}
+	case implements(typ, textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 && typ.Kind() == reflect.Slice {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(typ.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(typ.Elem(), visiting)}
+	case reflect.Struct:
+		// Recursive types are not expanded a second time.
+		if visiting[typ] {
+			return &Schema{Type: "object"}
+		}
+		visiting[typ] = true
+		defer delete(visiting, typ)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		addFields(s, typ, visiting)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+func addFields(s *Schema, typ reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		name, omitempty, skip := jsonField(field)
+		if skip {
+			continue
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			addFields(s, ft, visiting)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = schemaOf(field.Type, visiting)
+		if !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// jsonField returns the name of the field in the "json" tag and whether the
+// field is omitted if empty or always skipped.
+func jsonField(field reflect.StructField) (name string, omitempty, skip bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return "", false, false
+	}
+
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+
+	return parts[0], omitempty, false
+}
+
+func implements(typ, iface reflect.Type) bool {
+	return typ.Implements(iface) || reflect.PointerTo(typ).Implements(iface)
+}
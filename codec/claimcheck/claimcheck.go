@@ -0,0 +1,232 @@
+// Package claimcheck offloads large payloads to a blob store.
+//
+// Event stores and buses store the encoded data of every event inline, so a
+// few large payloads, like documents or images, can bloat MongoDB documents
+// beyond their size limit and exceed the maximum message size of NATS. The
+// Encoding of this package implements the claim-check pattern: encoded data
+// that exceeds a size threshold is stored in a BlobStore, and the event only
+// carries a small reference to the blob, which is transparently resolved when
+// the data is decoded:
+//
+//	reg := codec.New()
+//	enc := claimcheck.New(reg, mongo.NewGridFSBlobStore(db), claimcheck.Threshold(64*1024))
+//
+//	store := mongo.NewEventStore(enc)
+//	bus := nats.NewEventBus(enc)
+//
+// Every service that decodes the events must use an Encoding with access to
+// the same BlobStore. Blobs are addressed by the SHA-256 hash of their
+// content, so storing the same payload twice stores a single blob.
+package claimcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/codec"
+)
+
+// DefaultThreshold is the default size threshold in bytes above which encoded
+// data is offloaded to the BlobStore.
+const DefaultThreshold = 256 * 1024
+
+// DefaultTimeout is the default timeout for storing and loading blobs.
+const DefaultTimeout = 30 * time.Second
+
+// ErrBlobNotFound is returned by a BlobStore if a blob does not exist.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// refMagic prefixes the references to offloaded data. Like the envelopes of
+// codec.UseFormat, references start with a zero byte, which JSON, gob, and
+// protobuf encoded data never starts with.
+var refMagic = []byte{0, 'c', 'l', 'a', 'i', 'm'}
+
+// BlobStore stores offloaded payloads.
+type BlobStore interface {
+	// Put stores the given data under the given key. Keys are derived from the
+	// content of the data, so Put may skip data whose key already exists.
+	Put(ctx context.Context, key string, data []byte) error
+
+	// Get returns the data that is stored under the given key, or an error
+	// that unwraps to ErrBlobNotFound if no such data exists.
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Encoding is a codec.Encoding that offloads encoded data that exceeds a size
+// threshold to a BlobStore.
+type Encoding struct {
+	enc       codec.Encoding
+	blobs     BlobStore
+	threshold int
+	timeout   time.Duration
+}
+
+// Option is an option for an Encoding.
+type Option func(*Encoding)
+
+// Threshold returns an Option that sets the size threshold in bytes above
+// which encoded data is offloaded. Default is DefaultThreshold.
+func Threshold(bytes int) Option {
+	return func(e *Encoding) {
+		e.threshold = bytes
+	}
+}
+
+// Timeout returns an Option that sets the timeout for storing and loading
+// blobs. Because the codec.Encoding interface does not accept a context, the
+// timeout is the only way to bound the duration of blob operations. A zero
+// Duration means no timeout. Default is DefaultTimeout.
+func Timeout(d time.Duration) Option {
+	return func(e *Encoding) {
+		e.timeout = d
+	}
+}
+
+// New returns an Encoding that encodes and decodes data using the given
+// Encoding, and offloads encoded data that exceeds the threshold to the given
+// BlobStore.
+//
+// If the given Encoding is a *codec.Registry with a MaxPayloadSize, the limit
+// applies to the data before it is offloaded, so the limit should be larger
+// than the threshold. Payload limits that are validated by event stores (see
+// codec.PayloadLimit) apply to the stored references of offloaded data.
+func New(enc codec.Encoding, blobs BlobStore, opts ...Option) *Encoding {
+	e := &Encoding{
+		enc:       enc,
+		blobs:     blobs,
+		threshold: DefaultThreshold,
+		timeout:   DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Marshal encodes the data using the underlying Encoding, and offloads the
+// encoded data if it exceeds the threshold.
+func (e *Encoding) Marshal(data any) ([]byte, error) {
+	b, err := e.enc.Marshal(data)
+	if err != nil {
+		return b, err
+	}
+	return e.offload(b)
+}
+
+// MarshalName encodes the data of the given data type using the underlying
+// Encoding, and offloads the encoded data if it exceeds the threshold.
+func (e *Encoding) MarshalName(name string, data any) ([]byte, error) {
+	m, ok := e.enc.(codec.NameMarshaler)
+	if !ok {
+		return e.Marshal(data)
+	}
+
+	b, err := m.MarshalName(name, data)
+	if err != nil {
+		return b, err
+	}
+	return e.offload(b)
+}
+
+// Unmarshal decodes the data of the given data type. If the data is a
+// reference to offloaded data, the data is loaded from the BlobStore first.
+func (e *Encoding) Unmarshal(b []byte, name string) (any, error) {
+	if key, ok := IsReference(b); ok {
+		ctx, cancel := e.context()
+		defer cancel()
+
+		blob, err := e.blobs.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("load offloaded %q data: %w [key=%s]", name, err, key)
+		}
+		b = blob
+	}
+
+	return e.enc.Unmarshal(b, name)
+}
+
+// ValidateSize validates the size of encoded data using the underlying
+// Encoding, if it implements codec.SizeValidator.
+func (e *Encoding) ValidateSize(name string, size int) error {
+	if v, ok := e.enc.(codec.SizeValidator); ok {
+		return v.ValidateSize(name, size)
+	}
+	return nil
+}
+
+func (e *Encoding) offload(b []byte) ([]byte, error) {
+	if e.threshold <= 0 || len(b) <= e.threshold {
+		return b, nil
+	}
+
+	sum := sha256.Sum256(b)
+	key := "sha256:" + hex.EncodeToString(sum[:])
+
+	ctx, cancel := e.context()
+	defer cancel()
+
+	if err := e.blobs.Put(ctx, key, b); err != nil {
+		return nil, fmt.Errorf("offload %d bytes: %w", len(b), err)
+	}
+
+	return append(append([]byte(nil), refMagic...), key...), nil
+}
+
+func (e *Encoding) context() (context.Context, context.CancelFunc) {
+	if e.timeout > 0 {
+		return context.WithTimeout(context.Background(), e.timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// IsReference reports whether the given encoded data is a reference to
+// offloaded data, and returns the key of the blob.
+func IsReference(b []byte) (string, bool) {
+	if !bytes.HasPrefix(b, refMagic) {
+		return "", false
+	}
+	return string(b[len(refMagic):]), true
+}
+
+// MemoryStore is a thread-safe in-memory BlobStore. Useful for testing.
+type MemoryStore struct {
+	mux   sync.RWMutex
+	blobs map[string][]byte
+}
+
+// NewMemoryStore returns an in-memory BlobStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{blobs: make(map[string][]byte)}
+}
+
+// Put implements BlobStore.
+func (s *MemoryStore) Put(_ context.Context, key string, data []byte) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.blobs[key] = append([]byte(nil), data...)
+	return nil
+}
+
+// Get implements BlobStore.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	b, ok := s.blobs[key]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return append([]byte(nil), b...), nil
+}
+
+// Len returns the number of stored blobs.
+func (s *MemoryStore) Len() int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return len(s.blobs)
+}
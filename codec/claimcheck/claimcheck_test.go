@@ -0,0 +1,96 @@
+package claimcheck_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/codec/claimcheck"
+)
+
+type document struct {
+	Content string
+}
+
+func TestEncoding(t *testing.T) {
+	reg := codec.New()
+	codec.Register[document](reg, "doc")
+
+	blobs := claimcheck.NewMemoryStore()
+	enc := claimcheck.New(reg, blobs, claimcheck.Threshold(100))
+
+	small, err := codec.Encode(enc, "doc", document{Content: "small"})
+	if err != nil {
+		t.Fatalf("Encode() failed with %q", err)
+	}
+
+	if _, ok := claimcheck.IsReference(small); ok {
+		t.Fatalf("data below the threshold should not be offloaded")
+	}
+
+	large := document{Content: strings.Repeat("x", 1000)}
+	b, err := codec.Encode(enc, "doc", large)
+	if err != nil {
+		t.Fatalf("Encode() failed with %q", err)
+	}
+
+	if _, ok := claimcheck.IsReference(b); !ok {
+		t.Fatalf("data above the threshold should be offloaded")
+	}
+
+	if len(b) >= 100 {
+		t.Fatalf("reference should be small; is %d bytes", len(b))
+	}
+
+	if blobs.Len() != 1 {
+		t.Fatalf("blob store should have %d blob; has %d", 1, blobs.Len())
+	}
+
+	decoded, err := enc.Unmarshal(b, "doc")
+	if err != nil {
+		t.Fatalf("Unmarshal() failed with %q", err)
+	}
+
+	if decoded.(document) != large {
+		t.Fatalf("decoded data should equal the original data")
+	}
+
+	if _, err := codec.Encode(enc, "doc", large); err != nil {
+		t.Fatalf("Encode() failed with %q", err)
+	}
+
+	if blobs.Len() != 1 {
+		t.Fatalf("encoding the same data twice should store a single blob; store has %d blobs", blobs.Len())
+	}
+}
+
+func TestEncoding_Unmarshal_missingBlob(t *testing.T) {
+	reg := codec.New()
+	codec.Register[document](reg, "doc")
+
+	b, err := codec.Encode(claimcheck.New(reg, claimcheck.NewMemoryStore(), claimcheck.Threshold(10)), "doc", document{Content: strings.Repeat("x", 100)})
+	if err != nil {
+		t.Fatalf("Encode() failed with %q", err)
+	}
+
+	enc := claimcheck.New(reg, claimcheck.NewMemoryStore())
+	if _, err := enc.Unmarshal(b, "doc"); !errors.Is(err, claimcheck.ErrBlobNotFound) {
+		t.Fatalf("Unmarshal() should fail with %q; got %v", claimcheck.ErrBlobNotFound, err)
+	}
+}
+
+func TestEncoding_ValidateSize(t *testing.T) {
+	reg := codec.New(codec.PayloadLimit("doc", 200))
+	codec.Register[document](reg, "doc")
+
+	enc := claimcheck.New(reg, claimcheck.NewMemoryStore(), claimcheck.Threshold(100))
+
+	if _, err := codec.Encode(enc, "doc", document{Content: strings.Repeat("x", 1000)}); err != nil {
+		t.Fatalf("payload limits should apply to the references of offloaded data; got %v", err)
+	}
+
+	if err := enc.ValidateSize("doc", 300); !errors.Is(err, codec.ErrPayloadTooLarge) {
+		t.Fatalf("ValidateSize() should fail with %q; got %v", codec.ErrPayloadTooLarge, err)
+	}
+}
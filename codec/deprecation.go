@@ -0,0 +1,66 @@
+package codec
+
+import "sort"
+
+// Deprecation describes a deprecated data type.
+type Deprecation struct {
+	// Name is the name of the deprecated data type.
+	Name string
+
+	// Replacement is the name of the data type that replaces the deprecated
+	// data type, or an empty string if the data type has no replacement.
+	Replacement string
+}
+
+// Deprecate returns an Option that marks the data type with the given name as
+// deprecated in favor of the given replacement. The replacement may be empty if
+// the data type is retired without a replacement. Deprecated data types can
+// still be encoded and decoded, so that events that have already been stored
+// remain readable. Event buses that are wrapped by eventbus.NewDeprecations
+// warn about or reject the publishing of deprecated events:
+//
+//	reg := codec.New(codec.Deprecate("shop.order.created", "shop.order.placed"))
+func Deprecate(name, replacement string) Option {
+	return func(r *Registry) {
+		if r.deprecations == nil {
+			r.deprecations = make(map[string]Deprecation)
+		}
+		r.deprecations[name] = Deprecation{Name: name, Replacement: replacement}
+	}
+}
+
+// Deprecated returns the Deprecation of the data type with the given name, or
+// false if the data type is not deprecated.
+func (r *Registry) Deprecated(name string) (Deprecation, bool) {
+	d, ok := r.deprecations[name]
+	return d, ok
+}
+
+// Deprecations returns the deprecated data types of the registry, sorted by
+// name.
+func (r *Registry) Deprecations() []Deprecation {
+	out := make([]Deprecation, 0, len(r.deprecations))
+	for _, d := range r.deprecations {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Replacement returns the name of the data type that finally replaces the data
+// type with the given name. If the replacement is itself deprecated, its
+// replacement is returned, and so on. Replacement returns false if the data type
+// is not deprecated or has no replacement.
+func (r *Registry) Replacement(name string) (string, bool) {
+	seen := map[string]bool{name: true}
+	current := name
+	for {
+		d, ok := r.deprecations[current]
+		if !ok || d.Replacement == "" || seen[d.Replacement] {
+			break
+		}
+		current = d.Replacement
+		seen[current] = true
+	}
+	return current, current != name
+}
@@ -0,0 +1,47 @@
+package codec_test
+
+import (
+	"testing"
+
+	"github.com/modernice/goes/codec"
+)
+
+func TestDeprecate(t *testing.T) {
+	r := codec.New(
+		codec.Deprecate("foo", "bar"),
+		codec.Deprecate("bar", "baz"),
+		codec.Deprecate("old", ""),
+	)
+
+	if _, ok := r.Deprecated("baz"); ok {
+		t.Errorf("%q should not be deprecated", "baz")
+	}
+
+	d, ok := r.Deprecated("foo")
+	if !ok {
+		t.Fatalf("%q should be deprecated", "foo")
+	}
+
+	if d.Replacement != "bar" {
+		t.Errorf("Replacement of %q should be %q; got %q", "foo", "bar", d.Replacement)
+	}
+
+	if replacement, ok := r.Replacement("foo"); !ok || replacement != "baz" {
+		t.Errorf("Replacement(%q) should return %q; got %q (%v)", "foo", "baz", replacement, ok)
+	}
+
+	if _, ok := r.Replacement("old"); ok {
+		t.Errorf("Replacement(%q) should return false", "old")
+	}
+
+	want := []string{"bar", "foo", "old"}
+	deps := r.Deprecations()
+	if len(deps) != len(want) {
+		t.Fatalf("Deprecations() should return %d deprecations; got %d", len(want), len(deps))
+	}
+	for i, d := range deps {
+		if d.Name != want[i] {
+			t.Errorf("Deprecations()[%d] should be %q; got %q", i, want[i], d.Name)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// JSON is the content type of the builtin JSON format.
+const JSON = "application/json"
+
+// envelopeMagic prefixes data that was encoded using a format that was selected
+// by UseFormat. The magic is followed by the length of the content type (1
+// byte), the content type, and the encoded data. JSON, gob, and protobuf
+// encoded data never starts with a zero byte, so data without an envelope is
+// unambiguous.
+var envelopeMagic = []byte{0, 'g', 'o', 'e', 's'}
+
+// NameMarshaler is implemented by encodings that encode data depending on the
+// name of its data type. Encode uses MarshalName instead of Marshal if the
+// encoding implements NameMarshaler.
+type NameMarshaler interface {
+	MarshalName(name string, data any) ([]byte, error)
+}
+
+type format struct {
+	marshal   func(any) ([]byte, error)
+	unmarshal func([]byte, any) error
+}
+
+// Format returns an Option that registers a serialization format with the given
+// content type. Use UseFormat to select the format for specific data types.
+// The JSON format is registered by default.
+func Format(contentType string, marshal func(any) ([]byte, error), unmarshal func([]byte, any) error) Option {
+	if contentType == "" || len(contentType) > 255 {
+		panic(fmt.Errorf("invalid content type %q", contentType))
+	}
+
+	if marshal == nil || unmarshal == nil {
+		panic("format marshal and unmarshal functions must not be nil")
+	}
+
+	return func(r *Registry) {
+		r.formats[contentType] = format{marshal: marshal, unmarshal: unmarshal}
+	}
+}
+
+// UseFormat returns an Option that encodes the data types with the given names
+// using the format with the given content type, which must be registered using
+// Format. Data that is encoded using a selected format is wrapped in an
+// envelope that contains the content type, and Unmarshal decodes enveloped
+// data using the format of the envelope, regardless of the currently selected
+// format. This allows to migrate the format of a data type without migrating
+// already stored data:
+//
+//	reg := codec.New(
+//		codec.Format("application/protobuf", marshalProto, unmarshalProto),
+//		codec.UseFormat("application/protobuf", "shop.order.placed"),
+//	)
+//
+// Data types without a selected format are encoded without an envelope, using
+// their Marshaler implementation or the default marshaler. New panics if a
+// content type was not registered using Format.
+func UseFormat(contentType string, names ...string) Option {
+	return func(r *Registry) {
+		for _, name := range names {
+			r.nameFormats[name] = contentType
+		}
+	}
+}
+
+// MarshalName marshals the provided data of the data type with the given name.
+// If a format was selected for the data type using UseFormat, the data is
+// encoded using that format and wrapped in an envelope. Otherwise, MarshalName
+// behaves like Marshal. Like Marshal, MarshalName enforces MaxPayloadSize, but
+// not the limits of PayloadLimit (see PayloadLimit).
+func (r *Registry) MarshalName(name string, data any) (b []byte, err error) {
+	defer func() {
+		var sizeErr *PayloadSizeError
+		if errors.As(err, &sizeErr) && sizeErr.Name == "" {
+			sizeErr.Name = name
+		}
+	}()
+
+	contentType, ok := r.nameFormats[name]
+	if !ok {
+		return r.Marshal(data)
+	}
+
+	if r.debug {
+		log.Printf("[goes/codec.Registry@MarshalName] marshaling type %T (%s) using %q format", data, name, contentType)
+	}
+
+	if b, err = r.formats[contentType].marshal(data); err != nil {
+		return nil, err
+	}
+
+	return r.sized(envelope(contentType, b), nil)
+}
+
+func (r *Registry) validateFormats() {
+	for name, contentType := range r.nameFormats {
+		if _, ok := r.formats[contentType]; !ok {
+			panic(fmt.Errorf("[goes/codec.Registry] format %q of %q is not registered", contentType, name))
+		}
+	}
+}
+
+func (r *Registry) unmarshalEnvelope(b []byte, name string, ptr any) (bool, error) {
+	contentType, data, ok := openEnvelope(b)
+	if !ok {
+		return false, nil
+	}
+
+	f, ok := r.formats[contentType]
+	if !ok {
+		return true, fmt.Errorf("unknown format %q for %q", contentType, name)
+	}
+
+	if r.debug {
+		log.Printf("[goes/codec.Registry@Unmarshal] unmarshaling type %T (%s) using %q format", resolve(ptr), name, contentType)
+	}
+
+	return true, f.unmarshal(data, ptr)
+}
+
+func defaultFormats() map[string]format {
+	return map[string]format{
+		JSON: {marshal: json.Marshal, unmarshal: json.Unmarshal},
+	}
+}
+
+func envelope(contentType string, b []byte) []byte {
+	out := make([]byte, 0, len(envelopeMagic)+1+len(contentType)+len(b))
+	out = append(out, envelopeMagic...)
+	out = append(out, byte(len(contentType)))
+	out = append(out, contentType...)
+	return append(out, b...)
+}
+
+func openEnvelope(b []byte) (string, []byte, bool) {
+	rest, ok := bytes.CutPrefix(b, envelopeMagic)
+	if !ok || len(rest) == 0 {
+		return "", nil, false
+	}
+
+	n := int(rest[0])
+	if len(rest) < 1+n {
+		return "", nil, false
+	}
+
+	return string(rest[1 : 1+n]), rest[1+n:], true
+}
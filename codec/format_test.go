@@ -0,0 +1,126 @@
+package codec_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/modernice/goes/codec"
+)
+
+const gobContentType = "application/x-gob"
+
+func gobMarshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobUnmarshal(b []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+func TestUseFormat(t *testing.T) {
+	r := codec.New(
+		codec.Format(gobContentType, gobMarshal, gobUnmarshal),
+		codec.UseFormat(gobContentType, "foo"),
+	)
+	codec.Register[FooData](r, "foo")
+	codec.Register[FooData](r, "bar")
+
+	data := FooData{Foo: "foo", Bar: 3}
+
+	b, err := r.MarshalName("foo", data)
+	if err != nil {
+		t.Fatalf("MarshalName failed with %q", err)
+	}
+
+	if json.Valid(b) {
+		t.Fatalf("data should not be encoded using the default JSON format; got %s", b)
+	}
+
+	decoded, err := r.Unmarshal(b, "foo")
+	if err != nil {
+		t.Fatalf("Unmarshal failed with %q", err)
+	}
+
+	if !cmp.Equal(data, decoded) {
+		t.Fatalf("decoded data differs from original data\n%s", cmp.Diff(data, decoded))
+	}
+
+	b, err = r.MarshalName("bar", data)
+	if err != nil {
+		t.Fatalf("MarshalName failed with %q", err)
+	}
+
+	if !json.Valid(b) {
+		t.Fatalf("data without a selected format should be encoded using the default marshaler; got %q", b)
+	}
+}
+
+func TestUseFormat_migration(t *testing.T) {
+	old := codec.New()
+	codec.Register[FooData](old, "foo")
+
+	migrated := codec.New(
+		codec.Format(gobContentType, gobMarshal, gobUnmarshal),
+		codec.UseFormat(gobContentType, "foo"),
+	)
+	codec.Register[FooData](migrated, "foo")
+
+	data := FooData{Foo: "foo", Bar: 3}
+
+	oldBytes, err := old.MarshalName("foo", data)
+	if err != nil {
+		t.Fatalf("MarshalName failed with %q", err)
+	}
+
+	decoded, err := migrated.Unmarshal(oldBytes, "foo")
+	if err != nil {
+		t.Fatalf("migrated registry should decode data of the old format; failed with %q", err)
+	}
+
+	if !cmp.Equal(data, decoded) {
+		t.Fatalf("decoded data differs from original data\n%s", cmp.Diff(data, decoded))
+	}
+
+	newBytes, err := migrated.MarshalName("foo", data)
+	if err != nil {
+		t.Fatalf("MarshalName failed with %q", err)
+	}
+
+	if _, err := old.Unmarshal(newBytes, "foo"); err == nil {
+		t.Fatalf("registry without the format should fail to decode enveloped data")
+	}
+}
+
+func TestUseFormat_unknownFormat(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("New should panic if a selected format is not registered")
+		}
+	}()
+
+	codec.New(codec.UseFormat(gobContentType, "foo"))
+}
+
+func TestEncode_NameMarshaler(t *testing.T) {
+	r := codec.New(
+		codec.Format(gobContentType, gobMarshal, gobUnmarshal),
+		codec.UseFormat(gobContentType, "foo"),
+	)
+	codec.Register[FooData](r, "foo")
+
+	b, err := codec.Encode(r, "foo", FooData{Foo: "foo"})
+	if err != nil {
+		t.Fatalf("Encode failed with %q", err)
+	}
+
+	if json.Valid(b) {
+		t.Fatalf("Encode should use the selected format; got %s", b)
+	}
+}
@@ -0,0 +1,114 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidName is returned by a NamePolicy if a name violates the policy.
+var ErrInvalidName = errors.New("invalid name")
+
+var identifier = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// irregularPastTense are common irregular past tense verbs that are accepted by
+// the "past_tense" placeholder of NamePattern.
+var irregularPastTense = []string{
+	"begun", "bought", "brought", "built", "chosen", "done", "drawn", "found",
+	"given", "gone", "held", "kept", "left", "lost", "made", "met", "paid",
+	"put", "read", "reset", "run", "sent", "set", "shut", "sold", "spent",
+	"taken", "told", "undone", "won", "written",
+}
+
+// NamePolicy validates the names of registered data types. A NamePolicy
+// returns an error that wraps ErrInvalidName if a name violates the policy.
+type NamePolicy func(name string) error
+
+// Names returns an Option that validates the names of registered data types
+// against the given policies. Register panics if a name violates a policy,
+// so that naming violations are detected when the application starts:
+//
+//	reg := codec.New(codec.Names(codec.NamePattern("context.aggregate.past_tense")))
+//	codec.Register[OrderPlaced](reg, "shop.order.placed") // ok
+//	codec.Register[OrderPlaced](reg, "PlaceOrder")        // panics
+func Names(policies ...NamePolicy) Option {
+	return func(r *Registry) {
+		r.namePolicies = append(r.namePolicies, policies...)
+	}
+}
+
+// NamePattern returns a NamePolicy that validates names against a pattern of
+// dot-separated segments. A name must have the same number of segments as the
+// pattern, and every segment must be a lowercase snake_case identifier. The
+// segments of the pattern are placeholders with the following meaning:
+//
+//   - "past_tense": the last word of the segment must be in past tense, e.g.
+//     "placed" or "payment_received". Regular verbs ("-ed") and common
+//     irregular verbs ("sent", "paid", ...) are accepted.
+//   - a segment in single quotes, e.g. "'command'": the segment must equal the
+//     quoted literal.
+//   - any other placeholder, e.g. "context" or "aggregate": any identifier.
+//
+// For example, the pattern "context.aggregate.past_tense" accepts
+// "shop.order.placed" but rejects "shop.order.place" and "shop.placed".
+func NamePattern(pattern string) NamePolicy {
+	placeholders := strings.Split(pattern, ".")
+
+	return func(name string) error {
+		segments := strings.Split(name, ".")
+		if len(segments) != len(placeholders) {
+			return fmt.Errorf("%w: %q does not match %q: expected %d segments, got %d", ErrInvalidName, name, pattern, len(placeholders), len(segments))
+		}
+
+		for i, segment := range segments {
+			placeholder := placeholders[i]
+
+			if literal, ok := strings.CutPrefix(placeholder, "'"); ok {
+				if literal = strings.TrimSuffix(literal, "'"); segment != literal {
+					return fmt.Errorf("%w: %q does not match %q: segment %d must be %q", ErrInvalidName, name, pattern, i+1, literal)
+				}
+				continue
+			}
+
+			if !identifier.MatchString(segment) {
+				return fmt.Errorf("%w: %q does not match %q: segment %q is not a lowercase snake_case identifier", ErrInvalidName, name, pattern, segment)
+			}
+
+			if placeholder == "past_tense" && !isPastTense(segment) {
+				return fmt.Errorf("%w: %q does not match %q: segment %q is not in past tense", ErrInvalidName, name, pattern, segment)
+			}
+		}
+
+		return nil
+	}
+}
+
+// NameRegexp returns a NamePolicy that validates names against the given
+// regular expression.
+func NameRegexp(re *regexp.Regexp) NamePolicy {
+	return func(name string) error {
+		if !re.MatchString(name) {
+			return fmt.Errorf("%w: %q does not match %q", ErrInvalidName, name, re)
+		}
+		return nil
+	}
+}
+
+// ValidateName validates the given name against the name policies of the
+// registry.
+func (r *Registry) ValidateName(name string) error {
+	for _, policy := range r.namePolicies {
+		if err := policy(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isPastTense(segment string) bool {
+	words := strings.Split(segment, "_")
+	last := words[len(words)-1]
+	return strings.HasSuffix(last, "ed") || slices.Contains(irregularPastTense, last)
+}
@@ -0,0 +1,70 @@
+package codec_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/modernice/goes/codec"
+)
+
+func TestNamePattern(t *testing.T) {
+	policy := codec.NamePattern("context.aggregate.past_tense")
+
+	tests := map[string]bool{
+		"shop.order.placed":           true,
+		"shop.order.payment_received": true,
+		"shop.order.paid":             true,
+		"shop.order.place":            false,
+		"shop.placed":                 false,
+		"shop.order.item.added":       false,
+		"Shop.order.placed":           false,
+		"shop.order-item.added":       false,
+	}
+
+	for name, valid := range tests {
+		err := policy(name)
+		if valid && err != nil {
+			t.Errorf("%q should be valid; got %q", name, err)
+		}
+		if !valid && !errors.Is(err, codec.ErrInvalidName) {
+			t.Errorf("%q should be invalid; got %v", name, err)
+		}
+	}
+}
+
+func TestNamePattern_literal(t *testing.T) {
+	policy := codec.NamePattern("context.'cmd'.action")
+
+	if err := policy("shop.cmd.place_order"); err != nil {
+		t.Fatalf("name should be valid; got %q", err)
+	}
+
+	if err := policy("shop.command.place_order"); !errors.Is(err, codec.ErrInvalidName) {
+		t.Fatalf("name should be invalid; got %v", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+	reg := codec.New(codec.Names(
+		codec.NamePattern("context.aggregate.past_tense"),
+		codec.NameRegexp(regexp.MustCompile(`^shop\.`)),
+	))
+
+	codec.Register[FooData](reg, "shop.order.placed")
+
+	if err := reg.ValidateName("billing.invoice.sent"); !errors.Is(err, codec.ErrInvalidName) {
+		t.Fatalf("ValidateName() should fail with %q; got %v", codec.ErrInvalidName, err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("Register() should panic for an invalid name")
+		}
+		if _, err := reg.New("PlaceOrder"); err == nil {
+			t.Fatalf("invalid name should not be registered")
+		}
+	}()
+
+	codec.Register[FooData](reg, "PlaceOrder")
+}
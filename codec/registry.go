@@ -8,7 +8,11 @@ import (
 	"sync"
 )
 
-var _ Encoding = &Registry{}
+var (
+	_ Encoding      = &Registry{}
+	_ SizeValidator = &Registry{}
+	_ NameMarshaler = &Registry{}
+)
 
 // Encoding can be used to encode registered data types to and from bytes.
 type Encoding interface {
@@ -29,6 +33,13 @@ type Registry struct {
 	defaultMarshal   func(any) ([]byte, error)
 	defaultUnmarshal func([]byte, any) error
 	debug            bool
+	namePolicies     []NamePolicy
+	maxPayloadSize   int
+	payloadLimits    map[string]int
+	onPayload        []func(PayloadStats)
+	formats          map[string]format
+	nameFormats      map[string]string
+	deprecations     map[string]Deprecation
 }
 
 // Marshaler can be implemented by data types to override the default marshaler.
@@ -71,10 +82,13 @@ func New(opts ...Option) *Registry {
 		factories:        make(map[string]func() any),
 		defaultMarshal:   json.Marshal,
 		defaultUnmarshal: json.Unmarshal,
+		formats:          defaultFormats(),
+		nameFormats:      make(map[string]string),
 	}
 	for _, opt := range opts {
 		opt(r)
 	}
+	r.validateFormats()
 	return r
 }
 
@@ -84,7 +98,14 @@ func New(opts ...Option) *Registry {
 //
 //	var r *codec.Registry
 //	codec.Register[FooData](r, "foo")
+//
+// Register panics if the name violates a name policy of the registry (see
+// Names).
 func (r *Registry) Register(name string, factory func() any) {
+	if err := r.ValidateName(name); err != nil {
+		panic(fmt.Errorf("[goes/codec.Registry] register %q: %w", name, err))
+	}
+
 	r.mux.Lock()
 	defer r.mux.Unlock()
 	r.factories[name] = factory
@@ -112,25 +133,38 @@ func (r *Registry) New(name string) (any, error) {
 	return f(), nil
 }
 
-// Marshal marshals the provided data to a byte slice.
+// Marshal marshals the provided data to a byte slice. If the registry has a
+// MaxPayloadSize and the encoded data exceeds it, a *PayloadSizeError is
+// returned.
 func (r *Registry) Marshal(data any) ([]byte, error) {
 	if m, ok := data.(Marshaler); ok {
 		if r.debug {
 			log.Printf("[goes/codec.Registry@Marshal] marshaling type %T using custom Marshaler", data)
 		}
 
-		return m.Marshal()
+		return r.sized(m.Marshal())
 	}
 
 	if r.debug {
 		log.Printf("[goes/codec.Registry@Marshal] marshaling type %T using default marshaler", data)
 	}
 
-	return r.defaultMarshal(data)
+	return r.sized(r.defaultMarshal(data))
+}
+
+func (r *Registry) sized(b []byte, err error) ([]byte, error) {
+	if err != nil {
+		return b, err
+	}
+	if err := r.checkSize(b); err != nil {
+		return nil, err
+	}
+	return b, nil
 }
 
 // Unmarshal unmarshals the provided bytes to the data type that is registered
-// under the given name.
+// under the given name. If the data was encoded by MarshalName using a format
+// selected by UseFormat, the data is decoded using the format of its envelope.
 func (r *Registry) Unmarshal(b []byte, name string) (any, error) {
 	f, ok := r.factories[name]
 	if !ok {
@@ -139,6 +173,13 @@ func (r *Registry) Unmarshal(b []byte, name string) (any, error) {
 
 	ptr := f()
 
+	if ok, err := r.unmarshalEnvelope(b, name, ptr); ok {
+		if err != nil {
+			return nil, err
+		}
+		return resolve(ptr), nil
+	}
+
 	if m, ok := ptr.(Unmarshaler); ok {
 		if r.debug {
 			log.Printf("[goes/codec.Registry@Unmarshal] unmarshaling type %T (%s) using custom Unmarshaler", resolve(ptr), name)
@@ -0,0 +1,157 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPayloadTooLarge is returned when encoded data exceeds a payload size
+// limit. Errors returned by Marshal and ValidateSize are *PayloadSizeErrors that
+// wrap ErrPayloadTooLarge.
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// PayloadSizeError is returned when encoded data exceeds a payload size limit.
+type PayloadSizeError struct {
+	// Name is the name of the data type, if known. Marshal does not know the
+	// name of the data it encodes, so Name is empty for errors returned by
+	// Marshal. Errors returned by MarshalName and Encode have Name set.
+	Name string
+
+	// Size is the size of the encoded data in bytes.
+	Size int
+
+	// Limit is the size limit that was exceeded.
+	Limit int
+}
+
+// SizeValidator is implemented by encodings that validate the size of encoded
+// data. Event stores call ValidateSize for every event they insert.
+type SizeValidator interface {
+	ValidateSize(name string, size int) error
+}
+
+// PayloadStats provides information about the size of encoded data that was
+// validated by ValidateSize.
+type PayloadStats struct {
+	// Name is the name of the data type.
+	Name string
+
+	// Size is the size of the encoded data in bytes.
+	Size int
+
+	// Limit is the size limit for the data type, or 0 if there is no limit.
+	Limit int
+}
+
+// Error returns the error message of the PayloadSizeError.
+func (err *PayloadSizeError) Error() string {
+	if err.Name == "" {
+		return fmt.Sprintf("%v: %d bytes exceed the limit of %d bytes", ErrPayloadTooLarge, err.Size, err.Limit)
+	}
+	return fmt.Sprintf("%v: %q payload of %d bytes exceeds the limit of %d bytes", ErrPayloadTooLarge, err.Name, err.Size, err.Limit)
+}
+
+// Unwrap returns ErrPayloadTooLarge.
+func (err *PayloadSizeError) Unwrap() error {
+	return ErrPayloadTooLarge
+}
+
+// MaxPayloadSize returns an Option that limits the size of encoded data to the
+// given number of bytes. Marshal returns a *PayloadSizeError if the encoded
+// data exceeds the limit, so that oversized payloads are rejected before they
+// reach the event store or the network. A limit <= 0 disables the limit.
+func MaxPayloadSize(limit int) Option {
+	return func(r *Registry) {
+		r.maxPayloadSize = limit
+	}
+}
+
+// PayloadLimit returns an Option that lowers the size limit of encoded data of
+// the given data type to the given number of bytes. PayloadLimit can only lower
+// the limit of MaxPayloadSize: a limit that is larger than MaxPayloadSize, or a
+// limit <= 0, has no effect.
+//
+// The limit is enforced by ValidateSize, which is called by Encode after the
+// data has been marshaled, and by event stores for every inserted event.
+// MarshalName does not enforce the limit itself, so that encodings which wrap
+// the Registry (e.g. claimcheck.Encoding) can reduce the size of the data
+// before it is validated. Use Encode to marshal data with its per-type limit.
+func PayloadLimit(name string, limit int) Option {
+	return func(r *Registry) {
+		if r.payloadLimits == nil {
+			r.payloadLimits = make(map[string]int)
+		}
+		r.payloadLimits[name] = limit
+	}
+}
+
+// OnPayload returns an Option that calls the provided function for every
+// payload that is validated by ValidateSize. Use OnPayload to report metrics
+// about payload sizes.
+func OnPayload(fn func(PayloadStats)) Option {
+	return func(r *Registry) {
+		r.onPayload = append(r.onPayload, fn)
+	}
+}
+
+// ValidateSize validates the size of encoded data of the given data type
+// against the payload size limits of the registry. If the size exceeds the
+// limit, a *PayloadSizeError is returned.
+func (r *Registry) ValidateSize(name string, size int) error {
+	limit := r.payloadLimit(name)
+
+	for _, fn := range r.onPayload {
+		fn(PayloadStats{Name: name, Size: size, Limit: limit})
+	}
+
+	if limit > 0 && size > limit {
+		return &PayloadSizeError{Name: name, Size: size, Limit: limit}
+	}
+
+	return nil
+}
+
+func (r *Registry) payloadLimit(name string) int {
+	if limit, ok := r.payloadLimits[name]; ok && limit > 0 && (r.maxPayloadSize <= 0 || limit < r.maxPayloadSize) {
+		return limit
+	}
+	return r.maxPayloadSize
+}
+
+func (r *Registry) checkSize(b []byte) error {
+	if r.maxPayloadSize > 0 && len(b) > r.maxPayloadSize {
+		return &PayloadSizeError{Size: len(b), Limit: r.maxPayloadSize}
+	}
+	return nil
+}
+
+// Encode marshals the data of the given data type using the provided encoding.
+// If the encoding implements NameMarshaler, the data is marshaled using
+// MarshalName. If the encoding implements SizeValidator, the size of the
+// encoded data is validated against the limits of the encoding. Event stores
+// and buses use Encode to encode event data and command payloads. A returned
+// *PayloadSizeError always has its Name set.
+func Encode(enc Encoding, name string, data any) ([]byte, error) {
+	var b []byte
+	var err error
+	if m, ok := enc.(NameMarshaler); ok {
+		b, err = m.MarshalName(name, data)
+	} else {
+		b, err = enc.Marshal(data)
+	}
+	if err != nil {
+		var sizeErr *PayloadSizeError
+		if errors.As(err, &sizeErr) && sizeErr.Name == "" {
+			sizeErr.Name = name
+		}
+		return nil, err
+	}
+
+	if v, ok := enc.(SizeValidator); ok {
+		if err := v.ValidateSize(name, len(b)); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
@@ -0,0 +1,116 @@
+package codec_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/modernice/goes/codec"
+)
+
+func TestMaxPayloadSize(t *testing.T) {
+	r := codec.New(codec.MaxPayloadSize(32))
+	codec.Register[FooData](r, "foo")
+
+	if _, err := r.Marshal(FooData{Foo: "foo"}); err != nil {
+		t.Fatalf("Marshal failed with %q", err)
+	}
+
+	_, err := r.Marshal(FooData{Foo: strings.Repeat("foo", 10)})
+
+	var sizeErr *codec.PayloadSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("Marshal should fail with a %T error; got %T", sizeErr, err)
+	}
+
+	if !errors.Is(err, codec.ErrPayloadTooLarge) {
+		t.Errorf("error should wrap %q", codec.ErrPayloadTooLarge)
+	}
+
+	if sizeErr.Limit != 32 {
+		t.Errorf("PayloadSizeError should have Limit %d; got %d", 32, sizeErr.Limit)
+	}
+
+	if sizeErr.Size <= 32 {
+		t.Errorf("PayloadSizeError should have a Size > %d; got %d", 32, sizeErr.Size)
+	}
+}
+
+func TestPayloadLimit(t *testing.T) {
+	var stats []codec.PayloadStats
+	r := codec.New(
+		codec.MaxPayloadSize(64),
+		codec.PayloadLimit("foo", 16),
+		codec.OnPayload(func(s codec.PayloadStats) { stats = append(stats, s) }),
+	)
+
+	if err := r.ValidateSize("bar", 32); err != nil {
+		t.Fatalf("ValidateSize failed with %q", err)
+	}
+
+	err := r.ValidateSize("foo", 32)
+
+	var sizeErr *codec.PayloadSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("ValidateSize should fail with a %T error; got %T", sizeErr, err)
+	}
+
+	if sizeErr.Name != "foo" || sizeErr.Size != 32 || sizeErr.Limit != 16 {
+		t.Errorf("unexpected PayloadSizeError: %#v", sizeErr)
+	}
+
+	want := []codec.PayloadStats{
+		{Name: "bar", Size: 32, Limit: 64},
+		{Name: "foo", Size: 32, Limit: 16},
+	}
+	if len(stats) != len(want) || stats[0] != want[0] || stats[1] != want[1] {
+		t.Errorf("OnPayload should be called with %v; got %v", want, stats)
+	}
+}
+
+func TestPayloadLimit_larger(t *testing.T) {
+	r := codec.New(codec.MaxPayloadSize(16), codec.PayloadLimit("foo", 64))
+
+	err := r.ValidateSize("foo", 32)
+
+	var sizeErr *codec.PayloadSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("ValidateSize should fail with a %T error; got %T", sizeErr, err)
+	}
+
+	if sizeErr.Limit != 16 {
+		t.Errorf("PayloadLimit should not raise the limit of MaxPayloadSize; got Limit %d", sizeErr.Limit)
+	}
+}
+
+func TestRegistry_MarshalName_maxPayloadSize(t *testing.T) {
+	r := codec.New(codec.MaxPayloadSize(8))
+	codec.Register[FooData](r, "foo")
+
+	_, err := r.MarshalName("foo", FooData{Foo: "foo"})
+
+	var sizeErr *codec.PayloadSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("MarshalName should fail with a %T error; got %T", sizeErr, err)
+	}
+
+	if sizeErr.Name != "foo" {
+		t.Errorf("PayloadSizeError should have Name %q; got %q", "foo", sizeErr.Name)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	r := codec.New(codec.MaxPayloadSize(8))
+	codec.Register[FooData](r, "foo")
+
+	_, err := codec.Encode(r, "foo", FooData{Foo: "foo"})
+
+	var sizeErr *codec.PayloadSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("Encode should fail with a %T error; got %T", sizeErr, err)
+	}
+
+	if sizeErr.Name != "foo" {
+		t.Errorf("PayloadSizeError should have Name %q; got %q", "foo", sizeErr.Name)
+	}
+}
@@ -0,0 +1,318 @@
+// Package breaker provides per-command circuit breakers for command dispatch.
+//
+// When the handler of a command fails repeatedly, e.g. because a downstream
+// service is unavailable, dispatchers keep waiting for it to fail again, which
+// ties up their resources and lets the failure cascade. A Bus wraps a command
+// bus and opens a circuit for a command name after a number of consecutive
+// failures. While the circuit is open, dispatches of the command fail fast with
+// an *OpenError. After a cooldown, a limited number of probe dispatches are let
+// through (half-open); if they succeed, the circuit closes again.
+//
+//	bus := breaker.New(cmdbus.New[int](enc, events),
+//		breaker.Threshold(5),
+//		breaker.Cooldown(30*time.Second),
+//	)
+//
+//	err := bus.Dispatch(ctx, cmd, dispatch.Sync())
+//	if errors.Is(err, breaker.ErrOpen) {
+//		// the circuit of the command is open
+//	}
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/command"
+)
+
+const (
+	// DefaultThreshold is the default number of consecutive failures after
+	// which the circuit of a command opens.
+	DefaultThreshold = 5
+
+	// DefaultCooldown is the default duration a circuit stays open before it
+	// becomes half-open.
+	DefaultCooldown = 30 * time.Second
+
+	// DefaultProbes is the default number of concurrent probe dispatches of a
+	// half-open circuit.
+	DefaultProbes = 1
+)
+
+// ErrOpen is returned by Bus.Dispatch if the circuit of the command is open.
+// Use errors.As with an *OpenError to get the details.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit.
+type State int
+
+const (
+	// Closed circuits let all dispatches through.
+	Closed State = iota
+
+	// Open circuits fail all dispatches fast.
+	Open
+
+	// HalfOpen circuits let a limited number of probe dispatches through.
+	HalfOpen
+)
+
+// OpenError is returned by Bus.Dispatch if the circuit of a command is open.
+type OpenError struct {
+	// Command is the name of the command.
+	Command string
+
+	// Until is the time at which the circuit becomes half-open.
+	Until time.Time
+}
+
+// Bus is a command bus that guards the dispatch of commands with per-command
+// circuit breakers.
+type Bus struct {
+	command.Bus
+
+	threshold     int
+	cooldown      time.Duration
+	probes        int
+	isFailure     func(error) bool
+	onStateChange []func(name string, from, to State)
+
+	mux      sync.Mutex
+	circuits map[string]*circuit
+}
+
+// Option is an option for a Bus.
+type Option func(*Bus)
+
+type circuit struct {
+	state    State
+	failures int
+	openedAt time.Time
+	probing  int
+}
+
+// Threshold returns an Option that sets the number of consecutive failures
+// after which the circuit of a command opens. Defaults to DefaultThreshold.
+func Threshold(n int) Option {
+	return func(b *Bus) {
+		b.threshold = n
+	}
+}
+
+// Cooldown returns an Option that sets the duration a circuit stays open
+// before it becomes half-open. Defaults to DefaultCooldown.
+func Cooldown(d time.Duration) Option {
+	return func(b *Bus) {
+		b.cooldown = d
+	}
+}
+
+// Probes returns an Option that sets the number of concurrent probe
+// dispatches of a half-open circuit. Defaults to DefaultProbes.
+func Probes(n int) Option {
+	return func(b *Bus) {
+		b.probes = n
+	}
+}
+
+// IsFailure returns an Option that determines which dispatch errors count as
+// failures. By default, all errors count as failures, including timeouts,
+// except canceled dispatches and errors of kind command.KindValidation,
+// command.KindNotFound and command.KindConflict, which are caused by the
+// dispatcher or the command rather than by the handler.
+func IsFailure(fn func(error) bool) Option {
+	return func(b *Bus) {
+		b.isFailure = fn
+	}
+}
+
+// OnStateChange returns an Option that calls fn when the circuit of a command
+// changes its state.
+func OnStateChange(fn func(name string, from, to State)) Option {
+	return func(b *Bus) {
+		b.onStateChange = append(b.onStateChange, fn)
+	}
+}
+
+// New returns a Bus that guards the dispatch of commands over the given bus
+// with circuit breakers.
+func New(bus command.Bus, opts ...Option) *Bus {
+	b := &Bus{
+		Bus:       bus,
+		threshold: DefaultThreshold,
+		cooldown:  DefaultCooldown,
+		probes:    DefaultProbes,
+		isFailure: isFailure,
+		circuits:  make(map[string]*circuit),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Dispatch dispatches the command over the underlying bus if the circuit of
+// the command allows it. Otherwise, Dispatch returns an *OpenError. Use
+// synchronous dispatches so that handler failures and timeouts are reported
+// back to the Bus; asynchronous dispatches only report failures to dispatch.
+func (b *Bus) Dispatch(ctx context.Context, cmd command.Command, opts ...command.DispatchOption) error {
+	probe, err := b.acquire(cmd.Name())
+	if err != nil {
+		return err
+	}
+
+	err = b.Bus.Dispatch(ctx, cmd, opts...)
+
+	b.release(cmd.Name(), probe, err != nil && b.isFailure(err))
+
+	return err
+}
+
+// State returns the state of the circuit of the given command.
+func (b *Bus) State(name string) State {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if c, ok := b.circuits[name]; ok {
+		b.refresh(name, c)
+		return c.state
+	}
+	return Closed
+}
+
+// Reset closes the circuit of the given command.
+func (b *Bus) Reset(name string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if c, ok := b.circuits[name]; ok {
+		b.transition(name, c, Closed)
+	}
+}
+
+// acquire reports whether the dispatch is a probe of a half-open circuit.
+func (b *Bus) acquire(name string) (bool, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	c, ok := b.circuits[name]
+	if !ok {
+		c = &circuit{}
+		b.circuits[name] = c
+	}
+
+	b.refresh(name, c)
+
+	switch c.state {
+	case Open:
+		return false, &OpenError{Command: name, Until: c.openedAt.Add(b.cooldown)}
+	case HalfOpen:
+		if c.probing >= b.probes {
+			return false, &OpenError{Command: name, Until: time.Now()}
+		}
+		c.probing++
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (b *Bus) release(name string, probe, failed bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	c := b.circuits[name]
+
+	if probe {
+		c.probing--
+		if c.state != HalfOpen {
+			return
+		}
+		if failed {
+			b.transition(name, c, Open)
+		} else {
+			b.transition(name, c, Closed)
+		}
+		return
+	}
+
+	if !failed {
+		c.failures = 0
+		return
+	}
+
+	c.failures++
+	if c.state == Closed && c.failures >= b.threshold {
+		b.transition(name, c, Open)
+	}
+}
+
+// refresh makes an open circuit half-open after the cooldown.
+func (b *Bus) refresh(name string, c *circuit) {
+	if c.state == Open && time.Since(c.openedAt) >= b.cooldown {
+		b.transition(name, c, HalfOpen)
+	}
+}
+
+func (b *Bus) transition(name string, c *circuit, to State) {
+	from := c.state
+	if from == to {
+		return
+	}
+
+	c.state = to
+	switch to {
+	case Open:
+		c.openedAt = time.Now()
+	case Closed:
+		c.failures = 0
+	}
+
+	for _, fn := range b.onStateChange {
+		fn(name, from, to)
+	}
+}
+
+func isFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	switch command.ErrorKindOf(err) {
+	case command.KindValidation, command.KindNotFound, command.KindConflict:
+		return false
+	default:
+		return true
+	}
+}
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Error implements error.
+func (err *OpenError) Error() string {
+	return fmt.Sprintf("%s: %q command", ErrOpen, err.Command)
+}
+
+// Is returns true for ErrOpen.
+func (err *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+// ErrorKind implements command.ClassifiedError.
+func (err *OpenError) ErrorKind() command.ErrorKind {
+	return command.KindInfrastructure
+}
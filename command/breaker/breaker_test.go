@@ -0,0 +1,147 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/breaker"
+)
+
+type dispatchFunc func(context.Context, command.Command) error
+
+type mockBus struct {
+	command.Bus
+
+	dispatch dispatchFunc
+}
+
+func (bus mockBus) Dispatch(ctx context.Context, cmd command.Command, _ ...command.DispatchOption) error {
+	return bus.dispatch(ctx, cmd)
+}
+
+func TestBus_Dispatch(t *testing.T) {
+	ctx := context.Background()
+	mockErr := errors.New("mock error")
+
+	var fail bool
+	var calls int
+	var transitions []string
+
+	bus := breaker.New(mockBus{dispatch: func(context.Context, command.Command) error {
+		calls++
+		if fail {
+			return mockErr
+		}
+		return nil
+	}},
+		breaker.Threshold(2),
+		breaker.Cooldown(50*time.Millisecond),
+		breaker.OnStateChange(func(name string, from, to breaker.State) {
+			transitions = append(transitions, from.String()+">"+to.String())
+		}),
+	)
+
+	foo := command.New("foo", struct{}{}).Any()
+	bar := command.New("bar", struct{}{}).Any()
+
+	fail = true
+	for i := 0; i < 2; i++ {
+		if err := bus.Dispatch(ctx, foo); !errors.Is(err, mockErr) {
+			t.Fatalf("Dispatch should fail with %q; got %q", mockErr, err)
+		}
+	}
+
+	if state := bus.State("foo"); state != breaker.Open {
+		t.Fatalf("circuit should be %s; is %s", breaker.Open, state)
+	}
+
+	err := bus.Dispatch(ctx, foo)
+	var openErr *breaker.OpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, breaker.ErrOpen) || openErr.Command != "foo" {
+		t.Fatalf("Dispatch should fail with an %T; got %q", openErr, err)
+	}
+
+	if calls != 2 {
+		t.Fatalf("open circuit should not dispatch the command; dispatched %d times", calls)
+	}
+
+	if command.ErrorKindOf(err) != command.KindInfrastructure {
+		t.Fatalf("open circuit error should be of kind %q", command.KindInfrastructure)
+	}
+
+	fail = false
+	if err := bus.Dispatch(ctx, bar); err != nil {
+		t.Fatalf("circuit of other commands should be closed; got %q", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if state := bus.State("foo"); state != breaker.HalfOpen {
+		t.Fatalf("circuit should be %s; is %s", breaker.HalfOpen, state)
+	}
+
+	if err := bus.Dispatch(ctx, foo); err != nil {
+		t.Fatalf("probe dispatch failed with %q", err)
+	}
+
+	if state := bus.State("foo"); state != breaker.Closed {
+		t.Fatalf("circuit should be %s; is %s", breaker.Closed, state)
+	}
+
+	want := []string{"closed>open", "open>half-open", "half-open>closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions should be %v; got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions should be %v; got %v", want, transitions)
+		}
+	}
+}
+
+func TestBus_Dispatch_ignoredErrors(t *testing.T) {
+	ctx := context.Background()
+
+	bus := breaker.New(mockBus{dispatch: func(context.Context, command.Command) error {
+		return command.NewError(1, errors.New("invalid"), command.WithErrorKind(command.KindValidation))
+	}}, breaker.Threshold(1))
+
+	cmd := command.New("foo", struct{}{}).Any()
+	for i := 0; i < 3; i++ {
+		bus.Dispatch(ctx, cmd)
+	}
+
+	if state := bus.State("foo"); state != breaker.Closed {
+		t.Fatalf("validation errors should not open the circuit; circuit is %s", state)
+	}
+}
+
+func TestBus_Dispatch_failedProbe(t *testing.T) {
+	ctx := context.Background()
+
+	bus := breaker.New(mockBus{dispatch: func(context.Context, command.Command) error {
+		return context.DeadlineExceeded
+	}}, breaker.Threshold(1), breaker.Cooldown(10*time.Millisecond))
+
+	cmd := command.New("foo", struct{}{}).Any()
+	bus.Dispatch(ctx, cmd)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if err := bus.Dispatch(ctx, cmd); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("probe should be dispatched; got %q", err)
+	}
+
+	if state := bus.State("foo"); state != breaker.Open {
+		t.Fatalf("failed probe should open the circuit; circuit is %s", state)
+	}
+
+	bus.Reset("foo")
+
+	if state := bus.State("foo"); state != breaker.Closed {
+		t.Fatalf("Reset should close the circuit; circuit is %s", state)
+	}
+}
@@ -63,6 +63,13 @@ type DispatchConfig struct {
 	//
 	// A non-nil Reporter makes the dispatch synchronous.
 	Reporter Reporter
+
+	// A dry run executes the Command without persisting or publishing the
+	// events that it raises. The events that would have been raised are
+	// reported to Reporter.
+	//
+	// A dry run is automatically made synchronous.
+	DryRun bool
 }
 
 // A Reporter reports execution results of a Command.
@@ -69,7 +69,7 @@ type Bus[ErrorCode constraints.Integer] struct {
 
 	subMux        sync.RWMutex
 	subscriptions map[string]*subscription
-	requested     map[uuid.UUID]command.Cmd[any]
+	requested     map[uuid.UUID]requestedCommand
 
 	dispatchMux sync.RWMutex
 	dispatched  map[uuid.UUID]dispatcher
@@ -87,9 +87,15 @@ type options struct {
 	assignTimeout  time.Duration
 	receiveTimeout time.Duration
 	filters        []func(command.Command) bool
+	minProtocol    int
 	debug          bool
 }
 
+type requestedCommand struct {
+	cmd    command.Cmd[any]
+	dryRun bool
+}
+
 type subscription struct {
 	commands chan command.Context
 	errs     chan error
@@ -101,6 +107,10 @@ type dispatcher struct {
 	accepted        chan struct{}
 	dispatchAborted chan struct{}
 	out             chan error
+
+	// incompatible is set when a command bus requested the command, but its
+	// protocol version does not support the command.
+	incompatible bool
 }
 
 // Option is a command bus option.
@@ -160,7 +170,7 @@ func New[ErrorCode constraints.Integer](enc codec.Encoding, events event.Bus, op
 			receiveTimeout: DefaultReceiveTimeout,
 		},
 		subscriptions: make(map[string]*subscription),
-		requested:     make(map[uuid.UUID]command.Cmd[any]),
+		requested:     make(map[uuid.UUID]requestedCommand),
 		dispatched:    make(map[uuid.UUID]dispatcher),
 		assigned:      make(map[uuid.UUID]dispatcher),
 		enc:           enc,
@@ -245,6 +255,17 @@ func (b *Bus[ErrorCode]) Run(ctx context.Context) (<-chan error, error) {
 //		log.Println(execError.Err)
 //	}
 //
+// Use command.ErrorKindOf to classify the returned error. Errors that happen
+// while transmitting the Command (e.g. ErrAssignTimeout) are classified as
+// command.KindInfrastructure, while execution errors keep the kind that was
+// assigned by the handler:
+//
+//	switch command.ErrorKindOf(err) {
+//	case command.KindValidation:
+//	case command.KindConflict:
+//	case command.KindInfrastructure:
+//	}
+//
 // # Execution result
 //
 // By default, Dispatch does not return information about the execution of a
@@ -260,6 +281,24 @@ func (b *Bus[ErrorCode]) Run(ctx context.Context) (<-chan error, error) {
 //	log.Println(fmt.Sprintf("Command: %v", rep.Command()))
 //	log.Println(fmt.Sprintf("Runtime: %v", rep.Runtime()))
 //	log.Println(fmt.Sprintf("Error: %v", err))
+//
+// # Dry run
+//
+// A Command that is dispatched using the command.DryRun() Option is executed
+// without persisting or publishing its events. The events that the Command
+// would have raised are encoded using the Encoding of the Bus and reported to
+// the Reporter of the dispatch.
+//
+// # Protocol versions
+//
+// Command buses announce their protocol version (see ProtocolVersion) when
+// dispatching and requesting commands. A dispatched Command is only assigned to
+// a command bus that implements the protocol version that is required by the
+// Command, so that instances of different goes versions can share an event bus
+// during rolling deploys. If only incompatible command buses request the
+// Command, Dispatch fails with an error that unwraps to both ErrAssignTimeout
+// and ErrIncompatibleProtocol. Use the MinProtocol Option to raise the minimum
+// protocol version after all instances have been upgraded.
 func (b *Bus[ErrorCode]) Dispatch(ctx context.Context, cmd command.Command, opts ...command.DispatchOption) (err error) {
 	b.debugLog("dispatching %q command ...", cmd.Name())
 
@@ -275,7 +314,7 @@ func (b *Bus[ErrorCode]) Dispatch(ctx context.Context, cmd command.Command, opts
 
 	cfg := dispatch.Configure(opts...)
 
-	load, err := b.enc.Marshal(cmd.Payload())
+	load, err := codec.Encode(b.enc, cmd.Name(), cmd.Payload())
 	if err != nil {
 		return fmt.Errorf("encode payload: %w", err)
 	}
@@ -288,6 +327,9 @@ func (b *Bus[ErrorCode]) Dispatch(ctx context.Context, cmd command.Command, opts
 		AggregateName: name,
 		AggregateID:   id,
 		Payload:       load,
+		DryRun:        cfg.DryRun,
+		Protocol:      ProtocolVersion,
+		Requires:      b.requiredProtocol(cfg),
 	})
 
 	out := make(chan error)
@@ -310,7 +352,7 @@ func (b *Bus[ErrorCode]) Dispatch(ctx context.Context, cmd command.Command, opts
 	b.debugLog("publishing %q event ...", evt.Name())
 
 	if err := b.bus.Publish(ctx, evt.Any()); err != nil {
-		return fmt.Errorf("publish %q event: %w", evt.Name(), err)
+		return infrastructure(fmt.Errorf("publish %q event: %w", evt.Name(), err))
 	}
 
 	var timeout <-chan time.Time
@@ -324,7 +366,10 @@ func (b *Bus[ErrorCode]) Dispatch(ctx context.Context, cmd command.Command, opts
 	case <-ctx.Done():
 		return ctx.Err()
 	case <-timeout:
-		return ErrAssignTimeout
+		if b.rejectedIncompatible(cmd.ID()) {
+			return infrastructure(fmt.Errorf("%w: %w", ErrAssignTimeout, ErrIncompatibleProtocol))
+		}
+		return infrastructure(ErrAssignTimeout)
 	case <-accepted:
 	}
 
@@ -340,6 +385,12 @@ func (b *Bus[ErrorCode]) Dispatch(ctx context.Context, cmd command.Command, opts
 	return nil
 }
 
+func (b *Bus[ErrorCode]) rejectedIncompatible(cmdID uuid.UUID) bool {
+	b.dispatchMux.RLock()
+	defer b.dispatchMux.RUnlock()
+	return b.dispatched[cmdID].incompatible
+}
+
 func (b *Bus[ErrorCode]) cleanupDispatch(cmdID uuid.UUID) {
 	b.dispatchMux.Lock()
 	defer b.dispatchMux.Unlock()
@@ -432,6 +483,13 @@ func (b *Bus[ErrorCode]) commandDispatched(evt event.Of[CommandDispatchedData])
 		return
 	}
 
+	// if the bus does not implement the protocol version that is required by
+	// the command, return
+	if data.Requires > ProtocolVersion {
+		b.debugLog("%q command requires protocol version %d; this bus implements version %d", data.Name, data.Requires, ProtocolVersion)
+		return
+	}
+
 	cmd := command.New(data.Name, load, command.ID(data.ID), command.Aggregate(data.AggregateName, data.AggregateID))
 
 	// apply user-defined filters
@@ -441,8 +499,9 @@ func (b *Bus[ErrorCode]) commandDispatched(evt event.Of[CommandDispatchedData])
 
 	// otherwise request to become the handler of the command
 	requestEvent := event.New(CommandRequested, CommandRequestedData{
-		ID:    data.ID,
-		BusID: b.id,
+		ID:       data.ID,
+		BusID:    b.id,
+		Protocol: ProtocolVersion,
 	})
 
 	b.debugLog("requesting to become the handler for %q command ... [id=%s]", data.Name, data.ID)
@@ -453,7 +512,7 @@ func (b *Bus[ErrorCode]) commandDispatched(evt event.Of[CommandDispatchedData])
 		return
 	}
 
-	b.requested[data.ID] = cmd
+	b.requested[data.ID] = requestedCommand{cmd: cmd, dryRun: data.DryRun}
 }
 
 func (b *Bus[ErrorCode]) handles(name string) bool {
@@ -492,11 +551,28 @@ func (b *Bus[ErrorCode]) commandRequested(evt event.Of[CommandRequestedData]) {
 	b.dispatchMux.Lock()
 	defer b.dispatchMux.Unlock()
 
+	// if the command has been assigned in the meantime, return
+	if cmd, ok = b.dispatched[data.ID]; !ok {
+		return
+	}
+
+	// if the requesting bus does not implement the protocol version that is
+	// required by the command, wait for another request
+	if protocol, required := protocolOf(data.Protocol), b.requiredProtocol(cmd.cfg); protocol < required {
+		b.debugLog("rejecting request of handler %q for %q command: handler implements protocol version %d, command requires version %d", data.BusID, cmd.cmd.Name(), protocol, required)
+		cmd.incompatible = true
+		b.dispatched[data.ID] = cmd
+		return
+	}
+
 	// otherwise remove the command from the dispatched commands
 	delete(b.dispatched, data.ID)
 
 	// and assign the command to the handler that requested to handle it
-	assignEvent := event.New(CommandAssigned, CommandAssignedData(data))
+	assignEvent := event.New(CommandAssigned, CommandAssignedData{
+		ID:    data.ID,
+		BusID: data.BusID,
+	})
 
 	b.debugLog("publishing %q event ...", assignEvent.Name())
 
@@ -513,10 +589,11 @@ func (b *Bus[ErrorCode]) commandAssigned(evt event.Of[CommandAssignedData]) {
 	data := evt.Data()
 
 	// if the bus did not request the command, return
-	cmd, ok := b.requested[data.ID]
+	req, ok := b.requested[data.ID]
 	if !ok {
 		return
 	}
+	cmd := req.cmd
 
 	// otherwise remove the command from the requested commands
 	delete(b.requested, data.ID)
@@ -544,6 +621,11 @@ func (b *Bus[ErrorCode]) commandAssigned(evt event.Of[CommandAssignedData]) {
 		return
 	}
 
+	base := command.WithToken(b.Context())
+	if req.dryRun {
+		base = command.WithDryRun(base)
+	}
+
 	var timeout <-chan time.Time
 	if b.receiveTimeout > 0 {
 		timer := time.NewTimer(b.receiveTimeout)
@@ -559,16 +641,16 @@ func (b *Bus[ErrorCode]) commandAssigned(evt event.Of[CommandAssignedData]) {
 		case sub.errs <- fmt.Errorf("dropping %q command: %w", cmd.Name(), ErrReceiveTimeout):
 		}
 	case sub.commands <- command.NewContext[any](
-		b.Context(),
+		base,
 		cmd,
 		command.WhenDone(func(ctx context.Context, cfg finish.Config) error {
-			return b.markDone(ctx, cmd, cfg)
+			return b.markDone(ctx, cmd, cfg, command.DryRunEvents(base), command.RecordedToken(base))
 		}),
 	):
 	}
 }
 
-func (b *Bus[ErrorCode]) markDone(ctx context.Context, cmd command.Command, cfg finish.Config) error {
+func (b *Bus[ErrorCode]) markDone(ctx context.Context, cmd command.Command, cfg finish.Config, dryRunEvents []event.Event, token string) error {
 	var errbytes []byte
 
 	if cfg.Err != nil {
@@ -584,10 +666,17 @@ func (b *Bus[ErrorCode]) markDone(ctx context.Context, cmd command.Command, cfg
 		errbytes = b
 	}
 
+	events, err := b.encodeDryRunEvents(dryRunEvents)
+	if err != nil {
+		return err
+	}
+
 	evt := event.New(CommandExecuted, CommandExecutedData{
 		ID:      cmd.ID(),
 		Runtime: cfg.Runtime,
 		Error:   errbytes,
+		Events:  events,
+		Token:   token,
 	})
 
 	b.debugLog("publishing %q event ...", evt.Name())
@@ -656,7 +745,7 @@ func (b *Bus[ErrorCode]) commandExecuted(evt event.Of[CommandExecutedData]) {
 	if len(data.Error) > 0 {
 		var errpb commandpb.Error
 		if err := proto.Unmarshal(data.Error, &errpb); err != nil {
-			err := fmt.Errorf("failed to unmarshal command error of %q command: %w", cmd.cmd.Name(), err)
+			err := infrastructure(fmt.Errorf("failed to unmarshal command error of %q command: %w", cmd.cmd.Name(), err))
 			select {
 			case <-b.Context().Done():
 			case <-cmd.dispatchAborted:
@@ -671,6 +760,16 @@ func (b *Bus[ErrorCode]) commandExecuted(evt event.Of[CommandExecutedData]) {
 	if cmd.cfg.Reporter != nil {
 		id, name := cmd.cmd.Aggregate().Split()
 
+		events, err := b.decodeDryRunEvents(data.Events)
+		if err != nil {
+			select {
+			case <-b.Context().Done():
+			case <-cmd.dispatchAborted:
+			case cmd.out <- infrastructure(fmt.Errorf("failed to decode dry-run events of %q command: %w", cmd.cmd.Name(), err)):
+			}
+			return
+		}
+
 		cmd.cfg.Reporter.Report(report.New(report.Command{
 			ID:            cmd.cmd.ID(),
 			Name:          cmd.cmd.Name(),
@@ -680,7 +779,7 @@ func (b *Bus[ErrorCode]) commandExecuted(evt event.Of[CommandExecutedData]) {
 		}, report.Runtime(data.Runtime), report.Error(&ExecutionError[any]{
 			Cmd: cmd.cmd,
 			Err: cmdError,
-		})))
+		}), report.Events(events...), report.Token(data.Token)))
 	}
 
 	// if command execution failed, send the error to the dispatcher error channel and return
@@ -701,6 +800,57 @@ func (b *Bus[ErrorCode]) commandExecuted(evt event.Of[CommandExecutedData]) {
 	close(cmd.out)
 }
 
+func (b *Bus[ErrorCode]) encodeDryRunEvents(events []event.Event) ([]DryRunEvent, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	out := make([]DryRunEvent, len(events))
+	for i, evt := range events {
+		data, err := codec.Encode(b.enc, evt.Name(), evt.Data())
+		if err != nil {
+			return nil, fmt.Errorf("encode %q event data: %w", evt.Name(), err)
+		}
+
+		id, name, v := evt.Aggregate()
+		out[i] = DryRunEvent{
+			ID:               evt.ID(),
+			Name:             evt.Name(),
+			Time:             evt.Time(),
+			Data:             data,
+			AggregateName:    name,
+			AggregateID:      id,
+			AggregateVersion: v,
+		}
+	}
+
+	return out, nil
+}
+
+func (b *Bus[ErrorCode]) decodeDryRunEvents(events []DryRunEvent) ([]event.Event, error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	out := make([]event.Event, len(events))
+	for i, evt := range events {
+		data, err := b.enc.Unmarshal(evt.Data, evt.Name)
+		if err != nil {
+			return nil, fmt.Errorf("decode %q event data: %w", evt.Name, err)
+		}
+
+		out[i] = event.New(
+			evt.Name,
+			data,
+			event.ID(evt.ID),
+			event.Time(evt.Time),
+			event.Aggregate(evt.AggregateID, evt.AggregateName, evt.AggregateVersion),
+		)
+	}
+
+	return out, nil
+}
+
 func (b *Bus[ErrorCode]) debugLog(format string, vals ...any) {
 	if b.debug {
 		log.Printf("[goes/command/cmdbus.Bus@debugLog] "+format, vals...)
@@ -8,7 +8,7 @@ func Configure(opts ...command.DispatchOption) command.DispatchConfig {
 	for _, opt := range opts {
 		opt(&cfg)
 	}
-	if cfg.Reporter != nil {
+	if cfg.Reporter != nil || cfg.DryRun {
 		cfg.Synchronous = true
 	}
 	return cfg
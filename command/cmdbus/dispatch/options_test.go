@@ -3,6 +3,7 @@ package dispatch_test
 import (
 	"testing"
 
+	"github.com/modernice/goes/command"
 	"github.com/modernice/goes/command/cmdbus/dispatch"
 	"github.com/modernice/goes/command/cmdbus/report"
 )
@@ -22,3 +23,10 @@ func TestReport(t *testing.T) {
 		t.Fatalf("cfg.Report should point to %p; got %v", &rep, cfg.Reporter)
 	}
 }
+
+func TestConfigure_dryRun(t *testing.T) {
+	cfg := dispatch.Configure(command.DryRun())
+	if !cfg.DryRun || !cfg.Synchronous {
+		t.Fatalf("a dry run should be synchronous; got %+v", cfg)
+	}
+}
@@ -50,3 +50,28 @@ func TestBus_enrichedError(t *testing.T) {
 		t.Fatalf("expected underlying error %q, got %q", errEnriched.Underlying(), cmdError.Underlying())
 	}
 }
+
+func TestBus_errorKind(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subBus, ebus, reg := newBus(context.Background(), cmdbus.ReceiveTimeout(0), cmdbus.Debug(false))
+	pubBus, _, _ := newBusWith(context.Background(), reg, ebus, cmdbus.AssignTimeout(0), cmdbus.Debug(false))
+
+	commands, errs, err := subBus.Subscribe(ctx, "foo-cmd")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	go streams.Walk(ctx, func(ctx command.Context) error {
+		return ctx.Finish(ctx, finish.WithError(command.NewError(10, errors.New("invalid"), command.WithErrorKind(command.KindValidation))))
+	}, commands, errs)
+
+	cmd := command.New("foo-cmd", mockPayload{})
+
+	dispatchError := pubBus.Dispatch(ctx, cmd.Any(), dispatch.Sync())
+
+	if kind := command.ErrorKindOf(dispatchError); kind != command.KindValidation {
+		t.Fatalf("expected kind %q, got %q", command.KindValidation, kind)
+	}
+}
@@ -35,3 +35,28 @@ func (err *ExecutionError[P]) Error() string {
 func (err *ExecutionError[P]) Unwrap() error {
 	return err.Err
 }
+
+// infrastructureError classifies errors that happen while transmitting a
+// command (and not while executing it) as [command.KindInfrastructure].
+type infrastructureError struct {
+	err error
+}
+
+func infrastructure(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &infrastructureError{err}
+}
+
+func (err *infrastructureError) Error() string {
+	return err.err.Error()
+}
+
+func (err *infrastructureError) Unwrap() error {
+	return err.err
+}
+
+func (err *infrastructureError) ErrorKind() command.ErrorKind {
+	return command.KindInfrastructure
+}
@@ -45,12 +45,28 @@ type CommandDispatchedData struct {
 
 	// Payload is the encoded domain-specific Command Payload.
 	Payload []byte
+
+	// DryRun indicates that the Command was dispatched as a dry run.
+	DryRun bool
+
+	// Protocol is the protocol version of the dispatching Bus. Zero if the
+	// dispatching Bus does not announce its protocol version.
+	Protocol int
+
+	// Requires is the protocol version that a Bus must implement to handle
+	// the Command. Command buses that implement an older protocol version
+	// don't request the Command.
+	Requires int
 }
 
 // CommandRequestedData is the event Data for the CommandRequested Event.
 type CommandRequestedData struct {
 	ID    uuid.UUID
 	BusID uuid.UUID
+
+	// Protocol is the protocol version of the requesting Bus. Zero if the
+	// requesting Bus does not announce its protocol version.
+	Protocol int
 }
 
 // CommandAssignedData is the event Data for the CommandAssigned Event.
@@ -70,6 +86,24 @@ type CommandExecutedData struct {
 	ID      uuid.UUID
 	Runtime time.Duration
 	Error   []byte // *google.protobuf.Any
+
+	// Events are the events that the Command would have raised if it was
+	// dispatched as a dry run.
+	Events []DryRunEvent
+
+	// Token is the consistency token that was recorded by the command handler.
+	Token string
+}
+
+// DryRunEvent is an event that a Command would have raised during a dry run.
+type DryRunEvent struct {
+	ID               uuid.UUID
+	Name             string
+	Time             time.Time
+	Data             []byte
+	AggregateName    string
+	AggregateID      uuid.UUID
+	AggregateVersion int
 }
 
 // RegisterEvents registers the command events into a Registry.
@@ -0,0 +1,70 @@
+package cmdbus
+
+import (
+	"errors"
+
+	"github.com/modernice/goes/command"
+)
+
+const (
+	// ProtocolVersion is the version of the command bus protocol that is
+	// implemented by this package. Command buses announce their protocol
+	// version in the events they publish, so that command buses of different
+	// goes versions can coexist on the same event bus during rolling deploys.
+	//
+	// Protocol versions:
+	//	1: dispatch, request, assign, accept and execute commands
+	//	2: dry runs, dry-run events, consistency tokens and protocol negotiation
+	ProtocolVersion = 2
+
+	// LegacyProtocol is the protocol version of command buses that do not
+	// announce their protocol version, which are command buses of goes
+	// versions before protocol negotiation was added.
+	LegacyProtocol = 1
+)
+
+// ErrIncompatibleProtocol is returned by a Bus when it fails to assign a
+// Command before the assign timeout, and at least one command bus requested
+// the Command but was rejected because its protocol version does not support
+// the Command.
+var ErrIncompatibleProtocol = errors.New("no command handler with a compatible protocol version")
+
+// MinProtocol returns an Option that sets the minimum protocol version that
+// command buses must implement to be assigned the commands that are dispatched
+// by the bus. Command buses that implement an older protocol version may still
+// request the commands, but the commands are never assigned to them.
+//
+// Use MinProtocol after a rolling deploy has finished to ensure that commands
+// are only assigned to upgraded instances. During a rolling deploy, the
+// default (LegacyProtocol) allows commands to be handled by instances of both
+// versions, as long as the dispatched commands do not require features of a
+// newer protocol version. For example, dry runs are never assigned to command
+// buses that do not implement protocol version 2, because such buses would
+// execute the command for real.
+func MinProtocol(v int) Option {
+	return func(opts *options) {
+		opts.minProtocol = v
+	}
+}
+
+// protocolOf returns the protocol version that was announced in an event.
+// Events of command buses that do not announce a version use LegacyProtocol.
+func protocolOf(v int) int {
+	if v < LegacyProtocol {
+		return LegacyProtocol
+	}
+	return v
+}
+
+// requiredProtocol returns the protocol version that a command bus must
+// implement to handle a Command that is dispatched with the given config.
+func (b *Bus[ErrorCode]) requiredProtocol(cfg command.DispatchConfig) int {
+	required := LegacyProtocol
+	if cfg.DryRun {
+		required = 2
+	}
+	if b.minProtocol > required {
+		required = b.minProtocol
+	}
+	return required
+}
@@ -0,0 +1,182 @@
+package cmdbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/cmdbus"
+	"github.com/modernice/goes/command/cmdbus/dispatch"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/internal/testutil"
+)
+
+func TestBus_Dispatch_legacyHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, ebus, _ := newBus(ctx, cmdbus.AssignTimeout(500*time.Millisecond))
+
+	assigned := legacyHandler(ctx, t, ebus)
+
+	cmd := command.New("foo-cmd", mockPayload{})
+	if err := bus.Dispatch(ctx, cmd.Any()); err != nil {
+		t.Fatalf("Dispatch() failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("command should have been assigned to the legacy handler")
+	case id := <-assigned:
+		if id != cmd.ID() {
+			t.Fatalf("wrong command assigned. want=%s got=%s", cmd.ID(), id)
+		}
+	}
+}
+
+func TestBus_Dispatch_dryRunLegacyHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, ebus, _ := newBus(ctx, cmdbus.AssignTimeout(500*time.Millisecond))
+
+	assigned := legacyHandler(ctx, t, ebus)
+
+	cmd := command.New("foo-cmd", mockPayload{})
+	err := bus.Dispatch(ctx, cmd.Any(), command.DryRun())
+
+	if !errors.Is(err, cmdbus.ErrAssignTimeout) || !errors.Is(err, cmdbus.ErrIncompatibleProtocol) {
+		t.Fatalf("Dispatch() should fail with %q and %q; got %q", cmdbus.ErrAssignTimeout, cmdbus.ErrIncompatibleProtocol, err)
+	}
+
+	select {
+	case id := <-assigned:
+		t.Fatalf("dry run should not be assigned to a legacy handler; assigned %s", id)
+	default:
+	}
+}
+
+func TestBus_Dispatch_prefersCompatibleHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pubBus, ebus, ereg := newBus(ctx, cmdbus.AssignTimeout(time.Second))
+	legacyHandler(ctx, t, ebus)
+
+	subBus, _, _ := newBusWith(ctx, ereg, ebus)
+	commands, errs, err := subBus.Subscribe(ctx, "foo-cmd")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	go testutil.PanicOn(errs)
+	go func() {
+		for ctx := range commands {
+			if !command.IsDryRun(ctx) {
+				panic("command should be executed as a dry run")
+			}
+			ctx.Finish(ctx)
+		}
+	}()
+
+	cmd := command.New("foo-cmd", mockPayload{})
+	if err := pubBus.Dispatch(ctx, cmd.Any(), command.DryRun(), dispatch.Sync()); err != nil {
+		t.Fatalf("Dispatch() failed with %q", err)
+	}
+}
+
+func TestMinProtocol(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus, ebus, _ := newBus(ctx, cmdbus.AssignTimeout(500*time.Millisecond), cmdbus.MinProtocol(cmdbus.ProtocolVersion))
+
+	legacyHandler(ctx, t, ebus)
+
+	cmd := command.New("foo-cmd", mockPayload{})
+	if err := bus.Dispatch(ctx, cmd.Any()); !errors.Is(err, cmdbus.ErrIncompatibleProtocol) {
+		t.Fatalf("Dispatch() should fail with %q; got %q", cmdbus.ErrIncompatibleProtocol, err)
+	}
+}
+
+func TestBus_newerProtocol(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subBus, ebus, ereg := newBus(ctx)
+	if _, errs, err := subBus.Subscribe(ctx, "foo-cmd"); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	} else {
+		go testutil.PanicOn(errs)
+	}
+
+	requests, errs, err := ebus.Subscribe(ctx, cmdbus.CommandRequested)
+	if err != nil {
+		t.Fatalf("failed to subscribe to %q events: %v", cmdbus.CommandRequested, err)
+	}
+	go testutil.PanicOn(errs)
+
+	load, err := codec.Encode(ereg, "foo-cmd", mockPayload{})
+	if err != nil {
+		t.Fatalf("failed to encode payload: %v", err)
+	}
+
+	evt := event.New(cmdbus.CommandDispatched, cmdbus.CommandDispatchedData{
+		ID:       uuid.New(),
+		Name:     "foo-cmd",
+		Payload:  load,
+		Protocol: cmdbus.ProtocolVersion + 1,
+		Requires: cmdbus.ProtocolVersion + 1,
+	})
+
+	if err := ebus.Publish(ctx, evt.Any()); err != nil {
+		t.Fatalf("failed to publish %q event: %v", evt.Name(), err)
+	}
+
+	select {
+	case evt := <-requests:
+		t.Fatalf("command that requires a newer protocol version should not be requested; got %v", evt.Data())
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// legacyHandler simulates a command bus that does not announce its protocol
+// version. It requests and accepts every dispatched command and returns a
+// channel that receives the ids of the commands that were assigned to it.
+func legacyHandler(ctx context.Context, t *testing.T, ebus event.Bus) <-chan uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	events, errs, err := ebus.Subscribe(ctx, cmdbus.CommandDispatched, cmdbus.CommandAssigned)
+	if err != nil {
+		t.Fatalf("failed to subscribe to command events: %v", err)
+	}
+	go testutil.PanicOn(errs)
+
+	assigned := make(chan uuid.UUID, 1)
+	go func() {
+		for evt := range events {
+			switch data := evt.Data().(type) {
+			case cmdbus.CommandDispatchedData:
+				req := event.New(cmdbus.CommandRequested, cmdbus.CommandRequestedData{ID: data.ID, BusID: id})
+				if err := ebus.Publish(ctx, req.Any()); err != nil {
+					panic(err)
+				}
+			case cmdbus.CommandAssignedData:
+				if data.BusID != id {
+					continue
+				}
+				acc := event.New(cmdbus.CommandAccepted, cmdbus.CommandAcceptedData{ID: data.ID, BusID: id})
+				if err := ebus.Publish(ctx, acc.Any()); err != nil {
+					panic(err)
+				}
+				assigned <- data.ID
+			}
+		}
+	}()
+
+	return assigned
+}
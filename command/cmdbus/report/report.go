@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
 )
 
 // A Report provides information about the execution of a Command.
@@ -11,6 +12,15 @@ type Report struct {
 	Command Command
 	Runtime time.Duration
 	Error   error
+
+	// Events are the events that the Command would have raised if it was
+	// dispatched as a dry run.
+	Events []event.Event
+
+	// Token is the consistency token of the events that were written by the
+	// Command, if the command handler recorded one (see command.RecordToken).
+	// Use projection.ParseToken to parse the token.
+	Token string
 }
 
 // Command represents a command to be executed in a system. It contains an ID,
@@ -55,10 +65,26 @@ func Error(err error) Option {
 	}
 }
 
+// Events returns an Option that adds the events that a Command would have
+// raised during a dry run to a Report.
+func Events(events ...event.Event) Option {
+	return func(r *Report) {
+		r.Events = events
+	}
+}
+
+// Token returns an Option that adds the consistency token of the events that
+// were written by a Command to a Report.
+func Token(token string) Option {
+	return func(r *Report) {
+		r.Token = token
+	}
+}
+
 // Report.Report updates the Report instance with the information from the
 // provided Report instance. It creates a new Report based on the Command in the
 // provided Report, and updates the runtime and error information. This method
 // is useful for aggregating multiple Reports into a single Report.
 func (r *Report) Report(rep Report) {
-	*r = New(rep.Command, Runtime(rep.Runtime), Error(rep.Error))
+	*r = New(rep.Command, Runtime(rep.Runtime), Error(rep.Error), Events(rep.Events...), Token(rep.Token))
 }
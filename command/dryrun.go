@@ -0,0 +1,79 @@
+package command
+
+import (
+	"context"
+	"sync"
+
+	"github.com/modernice/goes/event"
+)
+
+type dryRunKey struct{}
+
+type dryRun struct {
+	mux    sync.Mutex
+	events []event.Event
+}
+
+// DryRun returns a DispatchOption that dispatches a command as a dry run. The
+// command is executed against a copy-on-write repository that records the
+// events that would have been raised, without persisting or publishing them.
+// The recorded events are reported to the Reporter of the dispatch, which
+// allows to preview the effects of a command, e.g. in validation UIs:
+//
+//	var rep report.Report
+//	err := bus.Dispatch(ctx, cmd, command.DryRun(), dispatch.Report(&rep))
+//	// handle err
+//	for _, evt := range rep.Events {
+//		log.Println(evt.Name())
+//	}
+//
+// A dry run is always synchronous. Command handlers that are not created with
+// the command/handler package must check IsDryRun themselves and report the
+// events that they would raise using RecordDryRun.
+func DryRun() DispatchOption {
+	return func(cfg *DispatchConfig) {
+		cfg.DryRun = true
+	}
+}
+
+// WithDryRun returns a context for executing a command as a dry run. Command
+// buses use WithDryRun to create the base context of commands that were
+// dispatched as a dry run.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, &dryRun{})
+}
+
+// IsDryRun reports whether the command of the given context is executed as a
+// dry run. Command handlers must not persist or publish events during a dry
+// run.
+func IsDryRun(ctx context.Context) bool {
+	_, ok := ctx.Value(dryRunKey{}).(*dryRun)
+	return ok
+}
+
+// RecordDryRun records the events that a command would have raised if it was
+// not executed as a dry run. RecordDryRun does nothing if the command of the
+// given context is not executed as a dry run.
+func RecordDryRun(ctx context.Context, events ...event.Event) {
+	dr, ok := ctx.Value(dryRunKey{}).(*dryRun)
+	if !ok {
+		return
+	}
+	dr.mux.Lock()
+	defer dr.mux.Unlock()
+	dr.events = append(dr.events, events...)
+}
+
+// DryRunEvents returns the events that were recorded for the command of the
+// given context using RecordDryRun.
+func DryRunEvents(ctx context.Context) []event.Event {
+	dr, ok := ctx.Value(dryRunKey{}).(*dryRun)
+	if !ok {
+		return nil
+	}
+	dr.mux.Lock()
+	defer dr.mux.Unlock()
+	out := make([]event.Event, len(dr.events))
+	copy(out, dr.events)
+	return out
+}
@@ -3,13 +3,55 @@ package command
 import (
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/modernice/goes/codec"
 	"golang.org/x/exp/constraints"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
+const (
+	// KindUnknown is the [ErrorKind] of errors that have not been classified.
+	KindUnknown = ErrorKind("")
+
+	// KindValidation classifies errors that are caused by invalid input, e.g.
+	// a command payload that fails validation.
+	KindValidation = ErrorKind("VALIDATION")
+
+	// KindNotFound classifies errors that are caused by a missing resource,
+	// e.g. an aggregate that does not exist.
+	KindNotFound = ErrorKind("NOT_FOUND")
+
+	// KindConflict classifies errors that are caused by a conflicting state,
+	// e.g. a concurrent modification of an aggregate.
+	KindConflict = ErrorKind("CONFLICT")
+
+	// KindInfrastructure classifies errors that are caused by a failure of the
+	// underlying infrastructure, e.g. an unreachable event store or event bus.
+	KindInfrastructure = ErrorKind("INFRASTRUCTURE")
+)
+
+// ErrorKindDomain is the domain of the [*errdetails.ErrorInfo] detail that
+// carries the [ErrorKind] of an [*Err].
+const ErrorKindDomain = "goes.command"
+
+// CodecDetailTypePrefix is the type url prefix of error details that have
+// been encoded using a [codec.Encoding]. See [EncodeErrorDetail].
+const CodecDetailTypePrefix = "goes.codec/"
+
+// ErrorKind classifies an error independently of the application-specific
+// error code. Dispatchers can use the kind of an error to distinguish
+// validation errors from conflicts or infrastructure failures without knowing
+// the error codes of the application that handled the command.
+type ErrorKind string
+
+// ClassifiedError is an error that provides its [ErrorKind].
+type ClassifiedError interface {
+	ErrorKind() ErrorKind
+}
+
 // CodedError is an error with an error code.
 type CodedError[Code constraints.Integer] interface {
 	Code() Code
@@ -100,6 +142,53 @@ func NewErrorDetail(msg proto.Message) (*ErrDetail, error) {
 	return &ErrDetail{pb: pb}, nil
 }
 
+// EncodeErrorDetail creates a new [*ErrDetail] from arbitrary data that is
+// encoded using the provided [codec.Encoding] under the given name. This allows
+// to attach application-specific details to a command error without defining
+// protobuf messages:
+//
+//	type ValidationDetail struct { Field, Reason string }
+//
+//	reg := codec.New()
+//	codec.Register[ValidationDetail](reg, "validation")
+//
+//	d, err := command.EncodeErrorDetail(reg, "validation", ValidationDetail{...})
+//
+// The receiving side decodes the detail using [ErrDetail.Decode] or
+// [Err.DecodeDetail] with a registry that has the same data type registered.
+func EncodeErrorDetail(enc codec.Encoding, name string, data any) (*ErrDetail, error) {
+	b, err := codec.Encode(enc, name, data)
+	if err != nil {
+		return nil, fmt.Errorf("encode %q detail: %w", name, err)
+	}
+	return &ErrDetail{pb: &anypb.Any{
+		TypeUrl: CodecDetailTypePrefix + name,
+		Value:   b,
+	}}, nil
+}
+
+// Name returns the name under which the detail was encoded using
+// [EncodeErrorDetail]. If the detail is a protobuf message, the full name of
+// the message type is returned instead.
+func (detail *ErrDetail) Name() string {
+	url := detail.pb.GetTypeUrl()
+	if name, ok := strings.CutPrefix(url, CodecDetailTypePrefix); ok {
+		return name
+	}
+	return string(detail.pb.MessageName())
+}
+
+// Decode decodes a detail that was encoded using [EncodeErrorDetail]. If the
+// detail is a protobuf message, Decode returns the same as [ErrDetail.Value].
+func (detail *ErrDetail) Decode(enc codec.Encoding) (any, error) {
+	url := detail.pb.GetTypeUrl()
+	name, ok := strings.CutPrefix(url, CodecDetailTypePrefix)
+	if !ok {
+		return detail.Value()
+	}
+	return enc.Unmarshal(detail.pb.GetValue(), name)
+}
+
 // AsAny returns the underlying [*anypb.Any] that contains the detail as a protobuf message.
 func (detail *ErrDetail) AsAny() *anypb.Any {
 	return detail.pb
@@ -131,6 +220,7 @@ type ErrorOption func(*errorOptions)
 
 type errorOptions struct {
 	details []*ErrDetail
+	kind    ErrorKind
 }
 
 // WithErrorDetails adds details to the error.
@@ -140,6 +230,32 @@ func WithErrorDetails(details ...*ErrDetail) ErrorOption {
 	}
 }
 
+// WithErrorKind classifies the error as the given [ErrorKind]. The kind is
+// added to the error as an [*errdetails.ErrorInfo] detail, so that it survives
+// the transmission over the command bus.
+func WithErrorKind(kind ErrorKind) ErrorOption {
+	return func(opts *errorOptions) {
+		opts.kind = kind
+	}
+}
+
+// ErrorKindOf returns the [ErrorKind] of the provided error. If the error (or
+// any error in its chain) does not implement [ClassifiedError], KindUnknown is
+// returned.
+func ErrorKindOf(err error) ErrorKind {
+	var classified ClassifiedError
+	for err != nil {
+		if !errors.As(err, &classified) {
+			return KindUnknown
+		}
+		if kind := classified.ErrorKind(); kind != KindUnknown {
+			return kind
+		}
+		err = errors.Unwrap(classified.(error))
+	}
+	return KindUnknown
+}
+
 // Error converts the error to an [*Err]. If the error is already an [*Err], it
 // is returned as is. Otherwise, it first extracts the error code from the error,
 // then calls [NewError] with the error code and error. If the provided error
@@ -167,7 +283,9 @@ func Error[Code constraints.Integer](err error) *Err[Code] {
 
 // NewError creates a new [*Err] with the provided error code and underlying
 // error. If the underlying error implements [DetailedError], the details of the
-// underlying error will be applied to the returned [*Err].
+// underlying error will be applied to the returned [*Err]. If the underlying
+// error is classified (see [ErrorKindOf]) and no kind is provided using
+// [WithErrorKind], the kind of the underlying error is used.
 func NewError[Code constraints.Integer](code Code, underlying error, opts ...ErrorOption) *Err[Code] {
 	var baseOpts []ErrorOption
 	if derr, ok := underlying.(DetailedError); ok {
@@ -181,10 +299,19 @@ func NewError[Code constraints.Integer](code Code, underlying error, opts ...Err
 		opt(&errorOpts)
 	}
 
+	details := errorOpts.details
+	kind := errorOpts.kind
+	if kind == KindUnknown && underlying != nil {
+		kind = ErrorKindOf(underlying)
+	}
+	if kind != KindUnknown {
+		details = append(withoutKindDetail(details), kindDetail(kind))
+	}
+
 	return &Err[Code]{
 		code:       code,
 		underlying: underlying,
-		details:    errorOpts.details,
+		details:    details,
 	}
 }
 
@@ -219,6 +346,33 @@ func (err *Err[Code]) Details() []*ErrDetail {
 	return err.details
 }
 
+// ErrorKind returns the [ErrorKind] of the error. ErrorKind implements
+// [ClassifiedError].
+func (err *Err[Code]) ErrorKind() ErrorKind {
+	for _, detail := range err.details {
+		if kind, ok := detail.kind(); ok {
+			return kind
+		}
+	}
+	return KindUnknown
+}
+
+// DecodeDetail decodes the first detail that was encoded under the given name
+// using [EncodeErrorDetail]. If the error has no such detail, false is returned.
+func (err *Err[Code]) DecodeDetail(enc codec.Encoding, name string) (any, bool, error) {
+	for _, detail := range err.details {
+		if detail.Name() != name {
+			continue
+		}
+		data, derr := detail.Decode(enc)
+		if derr != nil {
+			return nil, true, derr
+		}
+		return data, true, nil
+	}
+	return nil, false, nil
+}
+
 // WithDetails returns a new [*Err] with the provided details appended to the
 // details of the original error. The returned error will have the same error
 // code as the original error but will not be the same instance.
@@ -241,3 +395,36 @@ func (err *Err[Code]) Localized(locale string) string {
 
 	return ""
 }
+
+func kindDetail(kind ErrorKind) *ErrDetail {
+	d, _ := NewErrorDetail(&errdetails.ErrorInfo{
+		Reason: string(kind),
+		Domain: ErrorKindDomain,
+	})
+	return d
+}
+
+func (detail *ErrDetail) kind() (ErrorKind, bool) {
+	if detail.pb.GetTypeUrl() == "" || !detail.pb.MessageIs((*errdetails.ErrorInfo)(nil)) {
+		return KindUnknown, false
+	}
+	msg, err := detail.Value()
+	if err != nil {
+		return KindUnknown, false
+	}
+	info, ok := msg.(*errdetails.ErrorInfo)
+	if !ok || info.GetDomain() != ErrorKindDomain {
+		return KindUnknown, false
+	}
+	return ErrorKind(info.GetReason()), true
+}
+
+func withoutKindDetail(details []*ErrDetail) []*ErrDetail {
+	out := make([]*ErrDetail, 0, len(details))
+	for _, d := range details {
+		if _, ok := d.kind(); !ok {
+			out = append(out, d)
+		}
+	}
+	return out
+}
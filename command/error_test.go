@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/modernice/goes/codec"
 	"github.com/modernice/goes/command"
 	"github.com/modernice/goes/internal/slice"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
@@ -236,3 +237,99 @@ func (err *errorWithCode) Error() string {
 func (err *errorWithCode) Code() errorCode {
 	return err.code
 }
+
+func TestWithErrorKind(t *testing.T) {
+	err := command.NewError(0, errUnderlying, command.WithErrorKind(command.KindValidation))
+
+	if kind := err.ErrorKind(); kind != command.KindValidation {
+		t.Fatalf("expected kind %q, got %q", command.KindValidation, kind)
+	}
+
+	if kind := command.ErrorKindOf(fmt.Errorf("wrapped: %w", err)); kind != command.KindValidation {
+		t.Fatalf("expected ErrorKindOf() to return %q, got %q", command.KindValidation, kind)
+	}
+}
+
+func TestNewError_underlyingKind(t *testing.T) {
+	err := command.NewError(0, &classifiedError{kind: command.KindConflict})
+
+	if kind := err.ErrorKind(); kind != command.KindConflict {
+		t.Fatalf("expected kind %q, got %q", command.KindConflict, kind)
+	}
+
+	if len(err.Details()) != 1 {
+		t.Fatalf("expected error to have 1 detail, got %d", len(err.Details()))
+	}
+
+	err = err.WithDetails(command.LocalizeError("en", "Localized message"))
+
+	if kind := err.ErrorKind(); kind != command.KindConflict {
+		t.Fatalf("expected kind %q, got %q", command.KindConflict, kind)
+	}
+
+	if len(err.Details()) != 2 {
+		t.Fatalf("expected error to have 2 details, got %d", len(err.Details()))
+	}
+}
+
+func TestErrorKindOf_unclassified(t *testing.T) {
+	if kind := command.ErrorKindOf(errUnderlying); kind != command.KindUnknown {
+		t.Fatalf("expected kind %q, got %q", command.KindUnknown, kind)
+	}
+
+	if kind := command.ErrorKindOf(command.NewError(0, errUnderlying)); kind != command.KindUnknown {
+		t.Fatalf("expected kind %q, got %q", command.KindUnknown, kind)
+	}
+}
+
+type validationDetail struct {
+	Field  string
+	Reason string
+}
+
+func TestEncodeErrorDetail(t *testing.T) {
+	reg := codec.New()
+	codec.Register[validationDetail](reg, "validation")
+
+	want := validationDetail{Field: "name", Reason: "required"}
+
+	d, err := command.EncodeErrorDetail(reg, "validation", want)
+	if err != nil {
+		t.Fatalf("encode detail: %v", err)
+	}
+
+	if d.Name() != "validation" {
+		t.Fatalf("expected detail name %q, got %q", "validation", d.Name())
+	}
+
+	cerr := command.NewError(0, errUnderlying, command.WithErrorDetails(d, command.LocalizeError("en", "Localized message")))
+
+	data, ok, derr := cerr.DecodeDetail(reg, "validation")
+	if derr != nil {
+		t.Fatalf("decode detail: %v", derr)
+	}
+
+	if !ok {
+		t.Fatalf("expected error to have a %q detail", "validation")
+	}
+
+	if data != want {
+		t.Fatalf("expected detail to be %v, got %v", want, data)
+	}
+
+	if msg := cerr.Localized("en"); msg != "Localized message" {
+		t.Fatalf("expected LocalizedMessage(%q) to return %q, got %q", "en", "Localized message", msg)
+	}
+}
+
+type classifiedError struct {
+	kind command.ErrorKind
+}
+
+func (err *classifiedError) Error() string {
+	return fmt.Sprintf("classified error (%s)", err.kind)
+}
+
+func (err *classifiedError) ErrorKind() command.ErrorKind {
+	return err.kind
+}
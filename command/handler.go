@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/modernice/goes/command/finish"
@@ -11,12 +12,33 @@ import (
 
 // Handler wraps a Bus to provide a convenient way to subscribe to and handle commands.
 type Handler[P any] struct {
-	bus Bus
+	bus     Bus
+	workers int
+}
+
+// HandlerOption is an option for a *Handler.
+type HandlerOption func(*handlerConfig)
+
+type handlerConfig struct {
+	workers int
+}
+
+// Workers returns a HandlerOption that handles up to n commands of a
+// subscription concurrently. By default, the commands of a subscription are
+// handled one after another.
+func Workers(n int) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.workers = n
+	}
 }
 
 // NewHandler wraps the provided Bus in a *Handler.
-func NewHandler[P any](bus Bus) *Handler[P] {
-	return &Handler[P]{bus}
+func NewHandler[P any](bus Bus, opts ...HandlerOption) *Handler[P] {
+	var cfg handlerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Handler[P]{bus: bus, workers: max(cfg.workers, 1)}
 }
 
 // Handle is a shortcut for
@@ -53,7 +75,20 @@ func (h *Handler[P]) Handle(ctx context.Context, name string, handler func(Ctx[P
 	}
 
 	out := make(chan error)
-	go h.handle(ctx, handler, str, errs, out)
+
+	var wg sync.WaitGroup
+	wg.Add(h.workers)
+	for i := 0; i < h.workers; i++ {
+		go func() {
+			defer wg.Done()
+			h.handle(ctx, handler, str, errs, out)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
 
 	return out, nil
 }
@@ -74,7 +109,6 @@ func (h *Handler[P]) handle(
 	errs <-chan error,
 	out chan<- error,
 ) {
-	defer close(out)
 	for {
 		if str == nil && errs == nil {
 			return
@@ -6,9 +6,12 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
 	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/queue"
 	"github.com/modernice/goes/event"
 	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
 )
 
 // BaseHandler can be embedded into an aggregate to implement [command.Registerer].
@@ -161,9 +164,61 @@ type Aggregate interface {
 // UUID and calls CommandNames() on it to extract the command names from the
 // registered handlers.
 type Of[A Aggregate] struct {
-	handler *command.Handler[any]
-	repo    aggregate.Repository
-	newFunc func(uuid.UUID) A
+	bus            command.Bus
+	repo           aggregate.Repository
+	newFunc        func(uuid.UUID) A
+	queue          *queue.Queue
+	concurrency    int
+	maxConcurrency map[string]int
+}
+
+// OfOption is an option for an aggregate command handler.
+type OfOption func(*ofConfig)
+
+type ofConfig struct {
+	queue          *queue.Queue
+	concurrency    int
+	maxConcurrency map[string]int
+}
+
+// Serialize returns an OfOption that executes the commands of an aggregate
+// one after another using the provided queue, instead of loading and saving
+// the same aggregate concurrently, which would fail with consistency errors.
+func Serialize(q *queue.Queue) OfOption {
+	return func(cfg *ofConfig) {
+		cfg.queue = q
+	}
+}
+
+// Concurrency returns an OfOption that handles up to n commands of each command
+// name concurrently. By default, the commands of each name are handled one
+// after another, while commands of different names are handled concurrently.
+// Use MaxConcurrency to limit the concurrency of specific commands, and
+// Serialize to avoid consistency errors for concurrent commands against the
+// same aggregate.
+func Concurrency(n int) OfOption {
+	return func(cfg *ofConfig) {
+		cfg.concurrency = n
+	}
+}
+
+// MaxConcurrency returns an OfOption that limits the number of commands with
+// the given name that are handled concurrently by this instance to n,
+// overriding the Concurrency option for these commands. Use MaxConcurrency to
+// throttle expensive commands, so that they cannot starve the process, while
+// cheaper commands are handled with full concurrency:
+//
+//	h := handler.New(image.New, repo, bus,
+//		handler.Concurrency(32),
+//		handler.MaxConcurrency("image.resize", 2),
+//	)
+func MaxConcurrency(name string, n int) OfOption {
+	return func(cfg *ofConfig) {
+		if cfg.maxConcurrency == nil {
+			cfg.maxConcurrency = make(map[string]int)
+		}
+		cfg.maxConcurrency[name] = n
+	}
 }
 
 // New returns a new command handler for commands of the given aggregate type
@@ -173,7 +228,7 @@ type Of[A Aggregate] struct {
 // extract from the aggregate which commands it handles.
 //
 // Under the hood, a generic [*command.Handler] is used.
-func New[A Aggregate](newFunc func(uuid.UUID) A, repo aggregate.Repository, bus command.Bus) *Of[A] {
+func New[A Aggregate](newFunc func(uuid.UUID) A, repo aggregate.Repository, bus command.Bus, opts ...OfOption) *Of[A] {
 	if newFunc == nil {
 		panic("[goes/command.NewHandlerOf] newFunc is nil")
 	}
@@ -186,10 +241,18 @@ func New[A Aggregate](newFunc func(uuid.UUID) A, repo aggregate.Repository, bus
 		panic("[goes/command.NewHandlerOf] bus is nil")
 	}
 
+	var cfg ofConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return &Of[A]{
-		handler: command.NewHandler[any](bus),
-		repo:    repo,
-		newFunc: newFunc,
+		bus:            bus,
+		repo:           repo,
+		newFunc:        newFunc,
+		queue:          cfg.queue,
+		concurrency:    cfg.concurrency,
+		maxConcurrency: cfg.maxConcurrency,
 	}
 }
 
@@ -209,12 +272,8 @@ func (h *Of[A]) Handle(ctx context.Context) (<-chan error, error) {
 
 	var out []<-chan error
 	for _, name := range names {
-		errs, err := h.handler.Handle(ctx, name, func(ctx command.Context) error {
-			a := h.newFunc(ctx.AggregateID())
-			return h.repo.Use(ctx, a, func() error {
-				return a.HandleCommand(ctx)
-			})
-		})
+		handler := command.NewHandler[any](h.bus, command.Workers(h.workers(name)))
+		errs, err := handler.Handle(ctx, name, h.handleCommand)
 		if err != nil {
 			return streams.FanInAll(out...), err
 		}
@@ -223,3 +282,58 @@ func (h *Of[A]) Handle(ctx context.Context) (<-chan error, error) {
 
 	return streams.FanInAll(out...), nil
 }
+
+// workers returns the number of commands with the given name that are handled
+// concurrently.
+func (h *Of[A]) workers(name string) int {
+	if n, ok := h.maxConcurrency[name]; ok {
+		return n
+	}
+	return h.concurrency
+}
+
+func (h *Of[A]) handleCommand(ctx command.Context) error {
+	a := h.newFunc(ctx.AggregateID())
+
+	if command.IsDryRun(ctx) {
+		return h.dryRun(ctx, a)
+	}
+
+	use := func(context.Context) error {
+		var changes []event.Event
+		if err := h.repo.Use(ctx, a, func() error {
+			if err := a.HandleCommand(ctx); err != nil {
+				return err
+			}
+			changes = append(changes[:0], a.AggregateChanges()...)
+			return nil
+		}); err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			command.RecordToken(ctx, projection.TokenOf(changes...).String())
+		}
+		return nil
+	}
+
+	if h.queue == nil {
+		return use(ctx)
+	}
+
+	id, name, _ := a.Aggregate()
+
+	return h.queue.Do(ctx, aggregate.Ref{Name: name, ID: id}, use)
+}
+
+// dryRun executes the command against a copy-on-write repository and records
+// the events that the aggregate would have raised.
+func (h *Of[A]) dryRun(ctx command.Context, a A) error {
+	repo := repository.NewDryRun(h.repo)
+	if err := repo.Use(ctx, a, func() error {
+		return a.HandleCommand(ctx)
+	}); err != nil {
+		return err
+	}
+	command.RecordDryRun(ctx, repo.Events()...)
+	return nil
+}
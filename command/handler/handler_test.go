@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,12 +13,15 @@ import (
 	"github.com/modernice/goes/command"
 	"github.com/modernice/goes/command/cmdbus"
 	"github.com/modernice/goes/command/cmdbus/dispatch"
+	"github.com/modernice/goes/command/cmdbus/report"
 	"github.com/modernice/goes/command/handler"
+	"github.com/modernice/goes/command/queue"
 	"github.com/modernice/goes/event"
 	"github.com/modernice/goes/event/eventbus"
 	"github.com/modernice/goes/event/eventstore"
 	"github.com/modernice/goes/event/test"
 	"github.com/modernice/goes/internal/testutil"
+	"github.com/modernice/goes/projection"
 )
 
 func TestBaseHandler_HandleCommand(t *testing.T) {
@@ -93,6 +97,216 @@ func TestOf_Handle(t *testing.T) {
 	}
 }
 
+func TestOf_Handle_dryRun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	eventBus := eventbus.New()
+	reg := codec.New()
+	codec.Register[string](reg, "foo")
+	codec.Register[string](reg, "set-bar")
+	codec.Register[test.BarEventData](reg, "bar")
+	eventStore := eventstore.WithBus(eventstore.New(), eventBus)
+	commandBus := cmdbus.New[int](reg, eventBus)
+	repo := repository.New(eventStore)
+
+	// The command must not be named like the event that it raises, because
+	// the dispatching bus decodes the events of a dry run using the same
+	// registry as the commands.
+	h := handler.New(func(id uuid.UUID) *HandlerAggregate {
+		a := NewHandlerAggregate(id)
+		command.HandleWith(a, func(ctx command.Ctx[string]) error {
+			return a.Bar(ctx.Payload())
+		}, "set-bar")
+		return a
+	}, repo, commandBus)
+
+	errs, err := h.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Handle() failed with %q", err)
+	}
+	go testutil.PanicOn(errs)
+
+	id := uuid.New()
+
+	if err := commandBus.Dispatch(ctx, command.New("foo", "abc", command.Aggregate("handler", id)).Any(), dispatch.Sync()); err != nil {
+		t.Fatalf("dispatch failed with %q", err)
+	}
+
+	var rep report.Report
+	if err := commandBus.Dispatch(ctx, command.New("set-bar", "xyz", command.Aggregate("handler", id)).Any(), command.DryRun(), dispatch.Report(&rep)); err != nil {
+		t.Fatalf("dispatch failed with %q", err)
+	}
+
+	if len(rep.Events) != 1 {
+		t.Fatalf("report should contain %d event; got %d", 1, len(rep.Events))
+	}
+
+	evt := rep.Events[0]
+	if _, _, v := evt.Aggregate(); evt.Name() != "bar" || v != 2 {
+		t.Fatalf("report should contain the %q event with version %d; got %q event with version %d", "bar", 2, evt.Name(), v)
+	}
+
+	if data, ok := evt.Data().(test.BarEventData); !ok || data.A != "xyz" {
+		t.Fatalf("reported event has wrong data: %v", evt.Data())
+	}
+
+	foo := NewHandlerAggregate(id)
+	if err := repo.Fetch(ctx, foo); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if foo.AggregateVersion() != 1 || foo.BarVal != "" {
+		t.Fatalf("dry run should not persist events")
+	}
+}
+
+func TestOf_Handle_token(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	eventBus := eventbus.New()
+	reg := codec.New()
+	codec.Register[string](reg, "foo")
+	eventStore := eventstore.WithBus(eventstore.New(), eventBus)
+	commandBus := cmdbus.New[int](reg, eventBus)
+	repo := repository.New(eventStore)
+
+	h := handler.New(NewHandlerAggregateOpts(), repo, commandBus)
+
+	errs, err := h.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Handle() failed with %q", err)
+	}
+	go testutil.PanicOn(errs)
+
+	id := uuid.New()
+
+	var rep report.Report
+	if err := commandBus.Dispatch(ctx, command.New("foo", "abc", command.Aggregate("handler", id)).Any(), dispatch.Sync(), dispatch.Report(&rep)); err != nil {
+		t.Fatalf("dispatch failed with %q", err)
+	}
+
+	token, err := projection.ParseToken(rep.Token)
+	if err != nil {
+		t.Fatalf("ParseToken() failed with %q", err)
+	}
+
+	foo := NewHandlerAggregate(id)
+	if err := repo.Fetch(ctx, foo); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	evt, err := eventStore.Find(ctx, token.EventID)
+	if err != nil {
+		t.Fatalf("report should contain the token of the saved event; Find() failed with %q", err)
+	}
+
+	if _, _, v := evt.Aggregate(); v != foo.AggregateVersion() {
+		t.Fatalf("token should reference the latest event (version %d); references version %d", foo.AggregateVersion(), v)
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	eventBus := eventbus.New()
+	cmdReg := codec.New()
+	codec.Register[string](cmdReg, "foo")
+	codec.Register[string](cmdReg, "bar")
+	eventStore := eventstore.WithBus(eventstore.New(), eventBus)
+	commandBus := cmdbus.New[int](cmdReg, eventBus)
+	repo := repository.New(eventStore)
+
+	h := handler.New(NewHandlerAggregateOpts(), repo, commandBus, handler.Serialize(queue.New()))
+
+	errs, err := h.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Handle() failed with %q", err)
+	}
+	go testutil.PanicOn(errs)
+
+	id := uuid.New()
+
+	for i := 0; i < 5; i++ {
+		var wg sync.WaitGroup
+		for _, name := range []string{"foo", "bar"} {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				if err := commandBus.Dispatch(ctx, command.New(name, "abc", command.Aggregate("handler", id)).Any(), dispatch.Sync()); err != nil {
+					t.Errorf("dispatch failed with %q", err)
+				}
+			}(name)
+		}
+		wg.Wait()
+	}
+
+	foo := NewHandlerAggregate(id)
+	if err := repo.Fetch(ctx, foo); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if v := foo.AggregateVersion(); v != 10 {
+		t.Fatalf("aggregate should have version %d; has %d", 10, v)
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bus := newChanBus()
+	repo := repository.New(eventstore.New())
+
+	var mux sync.Mutex
+	running := make(map[string]int)
+	maxRunning := make(map[string]int)
+
+	var handled sync.WaitGroup
+	track := handler.BeforeHandle(func(ctx command.Ctx[string]) error {
+		defer handled.Done()
+
+		mux.Lock()
+		running[ctx.Name()]++
+		maxRunning[ctx.Name()] = max(maxRunning[ctx.Name()], running[ctx.Name()])
+		mux.Unlock()
+
+		time.Sleep(50 * time.Millisecond)
+
+		mux.Lock()
+		running[ctx.Name()]--
+		mux.Unlock()
+
+		return nil
+	})
+
+	h := handler.New(NewHandlerAggregateOpts(track), repo, bus, handler.Concurrency(4), handler.MaxConcurrency("foo", 1))
+
+	errs, err := h.Handle(ctx)
+	if err != nil {
+		t.Fatalf("Handle() failed with %q", err)
+	}
+	go testutil.PanicOn(errs)
+
+	handled.Add(8)
+	for i := 0; i < 4; i++ {
+		for _, name := range []string{"foo", "bar"} {
+			go bus.dispatch(ctx, command.New[any](name, "abc", command.Aggregate("handler", uuid.New())))
+		}
+	}
+	handled.Wait()
+
+	if maxRunning["foo"] != 1 {
+		t.Errorf("at most 1 %q command should be handled at a time; %d were handled concurrently", "foo", maxRunning["foo"])
+	}
+
+	if maxRunning["bar"] < 2 {
+		t.Errorf("%q commands should be handled concurrently; at most %d were handled at a time", "bar", maxRunning["bar"])
+	}
+}
+
 func TestBeforeHandle(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -290,3 +504,40 @@ func (a *HandlerAggregate) Bar(input string) error {
 func (a *HandlerAggregate) bar(evt event.Of[test.BarEventData]) {
 	a.BarVal = evt.Data().A
 }
+
+// chanBus is a command bus that passes dispatched commands directly to the
+// subscriptions of the same process.
+type chanBus struct {
+	mux  sync.Mutex
+	subs map[string]chan command.Context
+}
+
+func newChanBus() *chanBus {
+	return &chanBus{subs: make(map[string]chan command.Context)}
+}
+
+func (b *chanBus) Subscribe(ctx context.Context, names ...string) (<-chan command.Context, <-chan error, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	out := make(chan command.Context)
+	for _, name := range names {
+		b.subs[name] = out
+	}
+	return out, make(chan error), nil
+}
+
+func (b *chanBus) Dispatch(ctx context.Context, cmd command.Command, _ ...command.DispatchOption) error {
+	b.dispatch(ctx, cmd)
+	return nil
+}
+
+func (b *chanBus) dispatch(ctx context.Context, cmd command.Command) {
+	b.mux.Lock()
+	sub := b.subs[cmd.Name()]
+	b.mux.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case sub <- command.NewContext[any](ctx, cmd):
+	}
+}
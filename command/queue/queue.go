@@ -0,0 +1,187 @@
+// Package queue provides a per-aggregate command queue that serializes the
+// execution of commands against the same aggregate.
+//
+// Commands that are executed concurrently against the same aggregate fight over
+// the version of the aggregate, so all but one of them fail with a consistency
+// error. A Queue executes such commands one after another instead, while
+// commands against different aggregates still run concurrently.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/aggregate"
+)
+
+var (
+	// ErrFull is returned by a Queue if the queue of an aggregate has reached
+	// its configured depth.
+	ErrFull = errors.New("command queue is full")
+
+	// ErrTimeout is returned by a Queue if a command waited longer than the
+	// configured timeout for its turn.
+	ErrTimeout = errors.New("command queue timeout")
+)
+
+// Locker serializes commands across processes. A Locker is typically
+// implemented using a distributed lock, e.g. Redis or a database.
+type Locker interface {
+	// Lock acquires the lock of the given aggregate. Lock blocks until the lock
+	// is acquired or ctx is canceled. The returned function releases the lock.
+	Lock(context.Context, aggregate.Ref) (unlock func(), err error)
+}
+
+// Queue serializes the execution of commands per aggregate.
+//
+//	q := queue.New(queue.Depth(100), queue.Timeout(5*time.Second))
+//	err := q.Do(ctx, aggregate.Ref{Name: "order", ID: id}, func(ctx context.Context) error {
+//		return repo.Use(ctx, order, func() error { ... })
+//	})
+type Queue struct {
+	depth   int
+	timeout time.Duration
+	locker  Locker
+
+	mux    sync.Mutex
+	queues map[aggregate.Ref]*aggregateQueue
+}
+
+// Option is an option for a Queue.
+type Option func(*Queue)
+
+type aggregateQueue struct {
+	// token is held by the command that is currently executed.
+	token chan struct{}
+
+	// pending is the number of commands that are executed or waiting.
+	pending int
+}
+
+// Depth returns an Option that limits the number of commands that may wait in
+// the queue of a single aggregate. Commands that exceed the depth fail
+// immediately with ErrFull. A depth <= 0 means no limit.
+func Depth(n int) Option {
+	return func(q *Queue) {
+		q.depth = n
+	}
+}
+
+// Timeout returns an Option that limits the time a command may wait in the
+// queue for its turn. Commands that exceed the timeout fail with ErrTimeout.
+// The timeout does not apply to the execution of the command itself.
+func Timeout(d time.Duration) Option {
+	return func(q *Queue) {
+		q.timeout = d
+	}
+}
+
+// Distributed returns an Option that makes the Queue acquire the lock of an
+// aggregate from the given Locker before executing a command, to serialize
+// commands across multiple processes. The lock is acquired after the command
+// got its turn in the local queue, so that each process holds at most one lock
+// per aggregate.
+func Distributed(l Locker) Option {
+	return func(q *Queue) {
+		q.locker = l
+	}
+}
+
+// New returns a new Queue.
+func New(opts ...Option) *Queue {
+	q := &Queue{queues: make(map[aggregate.Ref]*aggregateQueue)}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// Do waits for the turn of the command in the queue of the given aggregate and
+// then calls fn. Do returns the error returned by fn, ErrFull if the queue is
+// full, ErrTimeout if the command waited too long, or ctx.Err() if ctx is
+// canceled while waiting.
+func (q *Queue) Do(ctx context.Context, ref aggregate.Ref, fn func(context.Context) error) error {
+	aq, err := q.enqueue(ref)
+	if err != nil {
+		return err
+	}
+	defer q.dequeue(ref, aq)
+
+	waitCtx := ctx
+	if q.timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, q.timeout)
+		defer cancel()
+	}
+
+	select {
+	case <-waitCtx.Done():
+		return q.waitError(ctx, ref)
+	case aq.token <- struct{}{}:
+	}
+	defer func() { <-aq.token }()
+
+	if q.locker != nil {
+		unlock, err := q.locker.Lock(waitCtx, ref)
+		if err != nil {
+			if waitCtx.Err() != nil {
+				return q.waitError(ctx, ref)
+			}
+			return fmt.Errorf("lock %s: %w", ref, err)
+		}
+		defer unlock()
+	}
+
+	return fn(ctx)
+}
+
+// Len returns the number of commands that are executed or waiting for the
+// given aggregate.
+func (q *Queue) Len(ref aggregate.Ref) int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if aq, ok := q.queues[ref]; ok {
+		return aq.pending
+	}
+	return 0
+}
+
+func (q *Queue) enqueue(ref aggregate.Ref) (*aggregateQueue, error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	aq, ok := q.queues[ref]
+	if !ok {
+		aq = &aggregateQueue{token: make(chan struct{}, 1)}
+		q.queues[ref] = aq
+	}
+
+	// The executed command does not count towards the depth.
+	if q.depth > 0 && aq.pending > q.depth {
+		return nil, fmt.Errorf("%s: %w", ref, ErrFull)
+	}
+
+	aq.pending++
+
+	return aq, nil
+}
+
+func (q *Queue) dequeue(ref aggregate.Ref, aq *aggregateQueue) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+
+	aq.pending--
+	if aq.pending == 0 {
+		delete(q.queues, ref)
+	}
+}
+
+func (q *Queue) waitError(ctx context.Context, ref aggregate.Ref) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s: %w", ref, ErrTimeout)
+}
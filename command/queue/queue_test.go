@@ -0,0 +1,155 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/command/queue"
+)
+
+func TestQueue_Do(t *testing.T) {
+	q := queue.New()
+	ref := aggregate.Ref{Name: "foo", ID: uuid.New()}
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := q.Do(context.Background(), ref, func(context.Context) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				if n > maxRunning.Load() {
+					maxRunning.Store(n)
+				}
+				time.Sleep(time.Millisecond)
+				return nil
+			}); err != nil {
+				t.Errorf("Do failed with %q", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning.Load() != 1 {
+		t.Fatalf("commands against the same aggregate should not run concurrently; %d did", maxRunning.Load())
+	}
+
+	if q.Len(ref) != 0 {
+		t.Fatalf("queue should be empty; has length %d", q.Len(ref))
+	}
+}
+
+func TestQueue_Do_differentAggregates(t *testing.T) {
+	q := queue.New()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go q.Do(context.Background(), aggregate.Ref{Name: "foo", ID: uuid.New()}, func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		q.Do(context.Background(), aggregate.Ref{Name: "foo", ID: uuid.New()}, func(context.Context) error { return nil })
+		close(done)
+	}()
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("commands against different aggregates should run concurrently")
+	case <-done:
+	}
+}
+
+func TestDepth(t *testing.T) {
+	q := queue.New(queue.Depth(1))
+	ref := aggregate.Ref{Name: "foo", ID: uuid.New()}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	go q.Do(context.Background(), ref, func(context.Context) error {
+		<-release
+		return nil
+	})
+	waitForLen(t, q, ref, 1)
+
+	go q.Do(context.Background(), ref, func(context.Context) error { return nil })
+	waitForLen(t, q, ref, 2)
+
+	if err := q.Do(context.Background(), ref, func(context.Context) error { return nil }); !errors.Is(err, queue.ErrFull) {
+		t.Fatalf("Do should fail with %q; got %v", queue.ErrFull, err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	q := queue.New(queue.Timeout(20 * time.Millisecond))
+	ref := aggregate.Ref{Name: "foo", ID: uuid.New()}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	go q.Do(context.Background(), ref, func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	waitForLen(t, q, ref, 1)
+
+	if err := q.Do(context.Background(), ref, func(context.Context) error { return nil }); !errors.Is(err, queue.ErrTimeout) {
+		t.Fatalf("Do should fail with %q; got %v", queue.ErrTimeout, err)
+	}
+}
+
+func TestDistributed(t *testing.T) {
+	locker := &mockLocker{}
+	q := queue.New(queue.Distributed(locker))
+	ref := aggregate.Ref{Name: "foo", ID: uuid.New()}
+
+	if err := q.Do(context.Background(), ref, func(context.Context) error {
+		if locker.locked != ref {
+			t.Fatalf("%s should be locked while the command is executed", ref)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Do failed with %q", err)
+	}
+
+	if locker.locked != (aggregate.Ref{}) {
+		t.Fatalf("lock should be released after the command was executed")
+	}
+}
+
+type mockLocker struct {
+	locked aggregate.Ref
+}
+
+func (l *mockLocker) Lock(_ context.Context, ref aggregate.Ref) (func(), error) {
+	l.locked = ref
+	return func() { l.locked = aggregate.Ref{} }, nil
+}
+
+func waitForLen(t *testing.T, q *queue.Queue, ref aggregate.Ref, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for q.Len(ref) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue should have length %d; has %d", n, q.Len(ref))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"context"
+	"sync"
+)
+
+type tokenKey struct{}
+
+type recordedToken struct {
+	mux   sync.Mutex
+	token string
+}
+
+// WithToken returns a context in which the consistency token of an executed
+// command can be recorded using RecordToken. Command buses use WithToken to
+// create the base context of commands, so that the token can be reported back
+// to the dispatcher of the command.
+func WithToken(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tokenKey{}, &recordedToken{})
+}
+
+// RecordToken records the consistency token of the events that were written
+// by the command of the given context. The token is reported to the Reporter
+// of the dispatch, so that the dispatcher can wait until a read model reflects
+// the command (see projection.Token). Aggregate-based command handlers that
+// are created with the command/handler package record the token
+// automatically. RecordToken does nothing if the context was not created
+// using WithToken.
+func RecordToken(ctx context.Context, token string) {
+	rt, ok := ctx.Value(tokenKey{}).(*recordedToken)
+	if !ok {
+		return
+	}
+	rt.mux.Lock()
+	defer rt.mux.Unlock()
+	rt.token = token
+}
+
+// RecordedToken returns the consistency token that was recorded for the
+// command of the given context using RecordToken.
+func RecordedToken(ctx context.Context) string {
+	rt, ok := ctx.Value(tokenKey{}).(*recordedToken)
+	if !ok {
+		return ""
+	}
+	rt.mux.Lock()
+	defer rt.mux.Unlock()
+	return rt.token
+}
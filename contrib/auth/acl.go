@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/backend/memory"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/internal/slice"
+	"github.com/modernice/goes/persistence/model"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+// AccessList is the read-model for the access control list of a specific
+// aggregate. Where Permissions answers the question "what may this actor do?",
+// AccessList answers "who may act on this aggregate?", which allows
+// projections to embed the actors and roles that have access to an aggregate
+// into their own read-models (e.g. to filter lists by the current user).
+//
+// An AccessList only contains the permissions that were granted on exactly its
+// aggregate. Permissions that were granted using wildcards are projected into
+// the AccessList of the wildcard reference, e.g. a permission on all "foo"
+// aggregates is projected into the AccessList of
+//
+//	aggregate.Ref{Name: "foo", ID: uuid.Nil}
+//
+// Roles are not resolved to their members. Use the Permissions read-model to
+// check the full set of permissions of a specific actor.
+type AccessList struct {
+	*projection.Base
+	*projection.Progressor
+	AccessListDTO
+}
+
+// AccessListDTO is the DTO of AccessList.
+type AccessListDTO struct {
+	Aggregate aggregate.Ref                `json:"aggregate"`
+	Actors    map[uuid.UUID]map[string]int `json:"actors"`
+	Roles     map[uuid.UUID]map[string]int `json:"roles"`
+}
+
+// AccessListRepository is the repository for the access list read-models.
+type AccessListRepository = model.Repository[*AccessList, aggregate.Ref]
+
+// AccessListOf returns the access list read-model of the given aggregate. The
+// returned projection has an empty state. An *AccessListProjector can be used
+// to continuously project the access lists for all aggregates. Use an
+// AccessListRepository to fetch the projected access list of an aggregate:
+//
+//	var repo auth.AccessListRepository
+//	ref := aggregate.Ref{Name: "order", ID: orderID}
+//	acl, err := repo.Fetch(context.TODO(), ref)
+//	// handle err
+//	editors := acl.ActorsAllowedTo("update")
+func AccessListOf(ref aggregate.Ref) *AccessList {
+	acl := &AccessList{
+		Base:       projection.New(),
+		Progressor: projection.NewProgressor(),
+		AccessListDTO: AccessListDTO{
+			Aggregate: ref,
+			Actors:    make(map[uuid.UUID]map[string]int),
+			Roles:     make(map[uuid.UUID]map[string]int),
+		},
+	}
+
+	event.ApplyWith(acl, acl.granted, PermissionGranted)
+	event.ApplyWith(acl, acl.revoked, PermissionRevoked)
+
+	return acl
+}
+
+// InMemoryAccessListRepository returns an in-memory repository for the access
+// list read-models.
+func InMemoryAccessListRepository() AccessListRepository {
+	return memory.NewModelRepository[*AccessList, aggregate.Ref](memory.ModelFactory(AccessListOf))
+}
+
+// ModelID returns the reference to the aggregate of the access list. ModelID
+// implements goes/persistence/model.Model.
+func (acl AccessListDTO) ModelID() aggregate.Ref {
+	return acl.Aggregate
+}
+
+// ActorAllows returns whether the given actor was granted the permission to
+// perform the given action on the aggregate, ignoring the permissions of roles.
+func (acl AccessListDTO) ActorAllows(actorID uuid.UUID, action string) bool {
+	return allowsAction(acl.Actors[actorID], action)
+}
+
+// RoleAllows returns whether the given role was granted the permission to
+// perform the given action on the aggregate.
+func (acl AccessListDTO) RoleAllows(roleID uuid.UUID, action string) bool {
+	return allowsAction(acl.Roles[roleID], action)
+}
+
+// ActorsAllowedTo returns the sorted ids of the actors that were granted the
+// permission to perform the given action on the aggregate.
+func (acl AccessListDTO) ActorsAllowedTo(action string) []uuid.UUID {
+	return allowedTo(acl.Actors, action)
+}
+
+// RolesAllowedTo returns the sorted ids of the roles that were granted the
+// permission to perform the given action on the aggregate.
+func (acl AccessListDTO) RolesAllowedTo(action string) []uuid.UUID {
+	return allowedTo(acl.Roles, action)
+}
+
+func (acl *AccessList) granted(evt event.Of[PermissionGrantedData]) {
+	data := evt.Data()
+	if data.Aggregate != acl.Aggregate {
+		return
+	}
+
+	entries := acl.entries(pick.AggregateName(evt))
+	if entries == nil {
+		return
+	}
+
+	id := pick.AggregateID(evt)
+	actions, ok := entries[id]
+	if !ok {
+		actions = make(map[string]int)
+		entries[id] = actions
+	}
+	for _, action := range data.Actions {
+		actions[action]++
+	}
+}
+
+func (acl *AccessList) revoked(evt event.Of[PermissionRevokedData]) {
+	data := evt.Data()
+	if data.Aggregate != acl.Aggregate {
+		return
+	}
+
+	entries := acl.entries(pick.AggregateName(evt))
+	id := pick.AggregateID(evt)
+	actions, ok := entries[id]
+	if !ok {
+		return
+	}
+	for _, action := range data.Actions {
+		actions[action]--
+		if actions[action] <= 0 {
+			delete(actions, action)
+		}
+	}
+	if len(actions) == 0 {
+		delete(entries, id)
+	}
+}
+
+func (acl *AccessList) entries(aggregateName string) map[uuid.UUID]map[string]int {
+	switch aggregateName {
+	case ActorAggregate:
+		return acl.Actors
+	case RoleAggregate:
+		return acl.Roles
+	default:
+		return nil
+	}
+}
+
+func allowsAction(actions map[string]int, action string) bool {
+	return actions[action] > 0 || actions["*"] > 0
+}
+
+func allowedTo(entries map[uuid.UUID]map[string]int, action string) []uuid.UUID {
+	var out []uuid.UUID
+	for id, actions := range entries {
+		if allowsAction(actions, action) {
+			out = append(out, id)
+		}
+	}
+	slices.SortFunc(out, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	return out
+}
+
+// AccessListProjector continuously projects the AccessList read-model for all
+// aggregates that actors or roles were granted permissions on.
+type AccessListProjector struct {
+	schedule *schedule.Continuous
+	acls     AccessListRepository
+}
+
+var accessListEvents = [...]string{
+	PermissionGranted,
+	PermissionRevoked,
+}
+
+// NewAccessListProjector returns a new access list projector.
+func NewAccessListProjector(
+	acls AccessListRepository,
+	bus event.Bus,
+	store event.Store,
+	opts ...schedule.ContinuousOption,
+) *AccessListProjector {
+	return &AccessListProjector{
+		schedule: schedule.Continuously(bus, store, accessListEvents[:], opts...),
+		acls:     acls,
+	}
+}
+
+// Run projects access lists until ctx is canceled.
+func (proj *AccessListProjector) Run(ctx context.Context) (<-chan error, error) {
+	errs, err := proj.schedule.Subscribe(ctx, proj.applyJob)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to projection schedule: %w", err)
+	}
+
+	go proj.schedule.Trigger(ctx)
+
+	return errs, nil
+}
+
+func (proj *AccessListProjector) applyJob(ctx projection.Job) error {
+	refs, err := proj.extractRefsFromJob(ctx)
+	if err != nil {
+		return fmt.Errorf("extract aggregates from job: %w", err)
+	}
+
+	for _, ref := range refs {
+		if err := proj.acls.Use(ctx, ref, func(acl *AccessList) error {
+			return ctx.Apply(ctx, acl)
+		}); err != nil {
+			return fmt.Errorf("apply access list: %w [aggregate=%v]", err, ref)
+		}
+	}
+
+	return nil
+}
+
+func (proj *AccessListProjector) extractRefsFromJob(ctx projection.Job) ([]aggregate.Ref, error) {
+	events, errs, err := ctx.Events(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extract events from job: %w", err)
+	}
+
+	var out []aggregate.Ref
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		switch data := evt.Data().(type) {
+		case PermissionGrantedData:
+			out = append(out, data.Aggregate)
+		case PermissionRevokedData:
+			out = append(out, data.Aggregate)
+		}
+		return nil
+	}, events, errs); err != nil {
+		return out, err
+	}
+
+	return slice.Unique(out), nil
+}
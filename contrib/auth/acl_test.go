@@ -0,0 +1,102 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/contrib/auth"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/internal/testutil"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestAccessListProjector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.WithBus(eventstore.New(), bus)
+	acls := auth.InMemoryAccessListRepository()
+	repo := repository.New(store)
+	actors := auth.NewUUIDActorRepository(repo)
+	roles := auth.NewRoleRepository(repo)
+
+	proj := auth.NewAccessListProjector(acls, bus, store, schedule.Debounce(50*time.Millisecond))
+
+	errs, err := proj.Run(ctx)
+	if err != nil {
+		t.Fatalf("run projector: %v", err)
+	}
+	go testutil.PanicOn(errs)
+
+	order := aggregate.Ref{Name: "order", ID: uuid.New()}
+	other := aggregate.Ref{Name: "order", ID: uuid.New()}
+
+	owner := auth.NewUUIDActor(uuid.New())
+	owner.Grant(order, "view", "cancel")
+	owner.Grant(other, "view")
+
+	viewer := auth.NewUUIDActor(uuid.New())
+	viewer.Grant(order, "view")
+	viewer.Grant(order, "update")
+	viewer.Revoke(order, "update")
+
+	admins := auth.NewRole(uuid.New())
+	admins.Identify("admin")
+	admins.Grant(order, "*")
+
+	if err := actors.Save(ctx, owner); err != nil {
+		t.Fatalf("save owner: %v", err)
+	}
+	if err := actors.Save(ctx, viewer); err != nil {
+		t.Fatalf("save viewer: %v", err)
+	}
+	if err := roles.Save(ctx, admins); err != nil {
+		t.Fatalf("save %q role: %v", "admin", err)
+	}
+
+	<-time.After(200 * time.Millisecond)
+
+	acl, err := acls.Fetch(ctx, order)
+	if err != nil {
+		t.Fatalf("fetch access list: %v", err)
+	}
+
+	if viewers := acl.ActorsAllowedTo("view"); len(viewers) != 2 {
+		t.Fatalf("2 actors should be allowed to view the order; got %v", viewers)
+	}
+
+	if cancelers := acl.ActorsAllowedTo("cancel"); len(cancelers) != 1 || cancelers[0] != owner.AggregateID() {
+		t.Fatalf("only the owner should be allowed to cancel the order; got %v", cancelers)
+	}
+
+	if acl.ActorAllows(viewer.AggregateID(), "update") {
+		t.Fatalf("revoked permission should be removed from the access list")
+	}
+
+	if !acl.RoleAllows(admins.AggregateID(), "cancel") {
+		t.Fatalf("%q role should be allowed to cancel the order", "admin")
+	}
+
+	if acl.ActorAllows(owner.AggregateID(), "update") {
+		t.Fatalf("owner should not be allowed to update the order")
+	}
+
+	acl, err = acls.Fetch(ctx, other)
+	if err != nil {
+		t.Fatalf("fetch access list: %v", err)
+	}
+
+	if viewers := acl.ActorsAllowedTo("view"); len(viewers) != 1 || viewers[0] != owner.AggregateID() {
+		t.Fatalf("only the owner should be allowed to view the other order; got %v", viewers)
+	}
+
+	if len(acl.RolesAllowedTo("view")) != 0 {
+		t.Fatalf("no role should be allowed to view the other order")
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/command"
+)
+
+// ErrForbidden is returned by command handlers that are protected by
+// CommandPermission if the actor of a command is not allowed to perform the
+// required action on the aggregate of the command.
+var ErrForbidden = errors.New("forbidden")
+
+// CommandPermission returns a command handler middleware that protects a
+// command handler from unauthorized access. When a command is handled, the
+// middleware calls the provided actor function to extract the actor of the
+// command (typically from the command payload), and checks if the actor has the
+// permission to perform the given action on the aggregate of the command. Only
+// if the actor is allowed to perform the action, the next handler is called.
+// Otherwise the middleware returns an error that satisfies
+// errors.Is(err, ErrForbidden):
+//
+//	type cancelOrder struct{ UserID uuid.UUID }
+//
+//	var perms auth.PermissionFetcher
+//	protect := auth.CommandPermission(perms, "cancel", func(ctx command.Ctx[cancelOrder]) uuid.UUID {
+//		return ctx.Payload().UserID
+//	})
+//
+//	errs, err := command.Handle(ctx, bus, "order.cancel", protect(func(ctx command.Ctx[cancelOrder]) error {
+//		// the user is allowed to cancel the order
+//	}))
+//
+// Commands without an aggregate, and commands whose actor is uuid.Nil, are
+// always rejected. Use the Granter and GrantOwner to grant the permissions that
+// are checked by the middleware.
+func CommandPermission[P any](
+	perms PermissionFetcher,
+	action string,
+	actor func(command.Ctx[P]) uuid.UUID,
+) func(func(command.Ctx[P]) error) func(command.Ctx[P]) error {
+	return func(next func(command.Ctx[P]) error) func(command.Ctx[P]) error {
+		return func(ctx command.Ctx[P]) error {
+			ref := aggregate.Ref{Name: ctx.AggregateName(), ID: ctx.AggregateID()}
+			actorID := actor(ctx)
+
+			if ref.Name == "" || actorID == uuid.Nil {
+				return fmt.Errorf("%w [actor=%v, action=%v, aggregate=%v]", ErrForbidden, actorID, action, ref)
+			}
+
+			p, err := perms.Fetch(ctx, actorID)
+			if err != nil {
+				return fmt.Errorf("fetch permissions: %w [actor=%v]", err, actorID)
+			}
+
+			if !p.Allows(action, ref) {
+				return fmt.Errorf("%w [actor=%v, action=%v, aggregate=%v]", ErrForbidden, actorID, action, ref)
+			}
+
+			return next(ctx)
+		}
+	}
+}
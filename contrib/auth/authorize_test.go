@@ -0,0 +1,71 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/contrib/auth"
+)
+
+type cancelOrder struct {
+	UserID uuid.UUID
+}
+
+func TestCommandPermission(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	owner := uuid.New()
+	order := aggregate.Ref{Name: "order", ID: uuid.New()}
+
+	perms := auth.PermissionFetcherFunc(func(_ context.Context, actorID uuid.UUID) (auth.PermissionsDTO, error) {
+		perms := auth.PermissionsOf(actorID)
+		if actorID == owner {
+			perms.OfActor[order] = map[string]int{"cancel": 1}
+		}
+		return perms.PermissionsDTO, nil
+	})
+
+	protect := auth.CommandPermission(perms, "cancel", func(ctx command.Ctx[cancelOrder]) uuid.UUID {
+		return ctx.Payload().UserID
+	})
+
+	var handled int
+	handler := protect(func(command.Ctx[cancelOrder]) error {
+		handled++
+		return nil
+	})
+
+	handle := func(userID uuid.UUID, ref aggregate.Ref) error {
+		cmd := command.New("order.cancel", cancelOrder{UserID: userID}, command.Aggregate(ref.Name, ref.ID))
+		return handler(command.NewContext[cancelOrder](ctx, cmd))
+	}
+
+	if err := handle(owner, order); err != nil {
+		t.Fatalf("owner should be allowed to cancel the order; got %q", err)
+	}
+
+	if handled != 1 {
+		t.Fatalf("handler should have been called once; called %d times", handled)
+	}
+
+	if err := handle(uuid.New(), order); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("other actors should not be allowed to cancel the order; got %q", err)
+	}
+
+	if err := handle(owner, aggregate.Ref{Name: "order", ID: uuid.New()}); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("owner should not be allowed to cancel other orders; got %q", err)
+	}
+
+	if err := handle(uuid.Nil, order); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("commands without an actor should be rejected; got %q", err)
+	}
+
+	if handled != 1 {
+		t.Fatalf("handler should not be called for forbidden commands; called %d times", handled)
+	}
+}
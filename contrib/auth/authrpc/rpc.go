@@ -153,7 +153,7 @@ func (s *Server) GrantToActor(ctx context.Context, req *authpb.GrantRevokeReq) (
 	if err := actors.Use(ctx, actorID, func(a *auth.Actor) error {
 		return a.Grant(target, actions...)
 	}); err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, grpcstatus.FromError(err).Err()
 	}
 
 	return &emptypb.Empty{}, nil
@@ -172,7 +172,7 @@ func (s *Server) GrantToRole(ctx context.Context, req *authpb.GrantRevokeReq) (*
 	if err := s.roles.Use(ctx, roleID, func(r *auth.Role) error {
 		return r.Grant(target, actions...)
 	}); err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, grpcstatus.FromError(err).Err()
 	}
 
 	return &emptypb.Empty{}, nil
@@ -196,7 +196,7 @@ func (s *Server) RevokeFromActor(ctx context.Context, req *authpb.GrantRevokeReq
 	if err := actors.Use(ctx, actorID, func(a *auth.Actor) error {
 		return a.Revoke(target, actions...)
 	}); err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, grpcstatus.FromError(err).Err()
 	}
 
 	return &emptypb.Empty{}, nil
@@ -215,7 +215,7 @@ func (s *Server) RevokeFromRole(ctx context.Context, req *authpb.GrantRevokeReq)
 	if err := s.roles.Use(ctx, roleID, func(r *auth.Role) error {
 		return r.Revoke(target, actions...)
 	}); err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, grpcstatus.FromError(err).Err()
 	}
 
 	return &emptypb.Empty{}, nil
@@ -271,7 +271,7 @@ func (c *Client) GrantToActor(ctx context.Context, actorID uuid.UUID, ref aggreg
 		Target:      aggregatepb.NewRef(ref),
 		Actions:     actions,
 	})
-	return err
+	return commandError(err)
 }
 
 // GrantToRole implements auth.CommandClient.
@@ -281,7 +281,7 @@ func (c *Client) GrantToRole(ctx context.Context, roleID uuid.UUID, ref aggregat
 		Target:      aggregatepb.NewRef(ref),
 		Actions:     actions,
 	})
-	return err
+	return commandError(err)
 }
 
 // RevokeFromActor implements auth.CommandClient.
@@ -291,7 +291,7 @@ func (c *Client) RevokeFromActor(ctx context.Context, actorID uuid.UUID, ref agg
 		Target:      aggregatepb.NewRef(ref),
 		Actions:     actions,
 	})
-	return err
+	return commandError(err)
 }
 
 // RevokeFromRole implements auth.CommandClient.
@@ -301,5 +301,15 @@ func (c *Client) RevokeFromRole(ctx context.Context, roleID uuid.UUID, ref aggre
 		Target:      aggregatepb.NewRef(ref),
 		Actions:     actions,
 	})
-	return err
+	return commandError(err)
+}
+
+// commandError restores the command error of a status error that was returned
+// by the server, so that callers can use command.ErrorKindOf and the details
+// of the error.
+func commandError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return grpcstatus.AsError[int64](err)
 }
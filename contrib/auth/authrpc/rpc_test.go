@@ -2,6 +2,7 @@ package authrpc_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	aggregatepb "github.com/modernice/goes/api/proto/gen/aggregate"
 	commonpb "github.com/modernice/goes/api/proto/gen/common"
 	authpb "github.com/modernice/goes/api/proto/gen/contrib/auth"
+	"github.com/modernice/goes/command"
 	"github.com/modernice/goes/contrib/auth"
 	"github.com/modernice/goes/contrib/auth/authrpc"
 	"github.com/modernice/goes/event/eventbus"
@@ -21,6 +23,7 @@ import (
 	"github.com/modernice/goes/internal/testutil"
 	"github.com/modernice/goes/projection"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -281,3 +284,29 @@ func givenAnActorWithViewPermission(t *testing.T) (*auth.Actor, *auth.Permission
 
 	return actor, perms, repo
 }
+
+func TestClient_GrantToActor_commandError(t *testing.T) {
+	cerr := command.NewError[int64](3, errors.New("conflict"), command.WithErrorKind(command.KindConflict))
+	client := authrpc.NewClient(errorConn{grpcstatus.FromError(cerr).Err()})
+
+	err := client.GrantToActor(context.Background(), uuid.New(), testRef, "view")
+
+	if kind := command.ErrorKindOf(err); kind != command.KindConflict {
+		t.Fatalf("error should be classified as %q; got %q", command.KindConflict, kind)
+	}
+
+	if code := command.Error[int64](err).Code(); code != 3 {
+		t.Fatalf("error should have code %d; got %d", 3, code)
+	}
+}
+
+// errorConn is a client connection whose calls fail with err.
+type errorConn struct{ err error }
+
+func (c errorConn) Invoke(context.Context, string, any, any, ...grpc.CallOption) error {
+	return c.err
+}
+
+func (c errorConn) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, c.err
+}
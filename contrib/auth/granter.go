@@ -118,6 +118,41 @@ func GrantOn[Data any](handler func(TargetedGranter, event.Of[Data]) error, even
 	}
 }
 
+// GrantOwner returns a GranterOption that grants the owner of an aggregate the
+// permission to perform the given actions on the aggregate when one of the
+// given events is published. The owner function extracts the aggregate id of
+// the owning actor from the event, typically from the event that creates the
+// aggregate. This covers the common case of actors that may act on their own
+// resources:
+//
+//	type OrderPlaced struct{ CustomerID uuid.UUID }
+//
+//	g := auth.NewGranter(nil, ..., auth.GrantOwner(func(evt event.Of[OrderPlaced]) uuid.UUID {
+//		return evt.Data().CustomerID
+//	}, []string{"view", "cancel"}, "order.placed"))
+//
+// If the owner function returns uuid.Nil, no permissions are granted. Owners
+// that are identified by a formatted actor id can be resolved using the Lookup
+// of a TargetedGranter within a GrantOn handler instead.
+func GrantOwner[Data any](owner func(event.Of[Data]) uuid.UUID, actions []string, eventNames ...string) GranterOption {
+	if owner == nil {
+		panic("[goes/contrib/auth.GrantOwner] owner is nil")
+	}
+
+	return GrantOn(func(tg TargetedGranter, evt event.Of[Data]) error {
+		ownerID := owner(evt)
+		if ownerID == uuid.Nil {
+			return nil
+		}
+
+		if err := tg.GrantToActor(tg.Context(), ownerID, actions...); err != nil {
+			return fmt.Errorf("grant permissions to owner: %w [owner=%v]", err, ownerID)
+		}
+
+		return nil
+	}, eventNames...)
+}
+
 // NewGranter returns a new permission granter background task.
 //
 //	var events []string
@@ -161,6 +161,38 @@ func TestGrantOn(t *testing.T) {
 	gt.ExpectPermissions(ctx, actor.AggregateID(), target, actions)
 }
 
+func TestGrantOwner(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gt := NewGrantTest(t)
+
+	actors, _ := gt.actors.Repository(auth.UUIDActor)
+	owner := auth.NewUUIDActor(uuid.New())
+	actors.Save(ctx, owner)
+
+	actions := []string{"view", "update"}
+
+	gt.Run(ctx, auth.GrantOwner(func(evt event.Of[uuid.UUID]) uuid.UUID {
+		return evt.Data()
+	}, actions, "created"))
+
+	target := aggregate.Ref{
+		Name: "foo",
+		ID:   uuid.New(),
+	}
+
+	if err := gt.bus.Publish(ctx, event.New(
+		"created",
+		owner.AggregateID(),
+		event.Aggregate(target.ID, target.Name, 1),
+	).Any()); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	gt.ExpectPermissions(ctx, owner.AggregateID(), target, actions)
+}
+
 // GrantTest provides a testing suite for the auth package, allowing tests to be
 // run against the Granter implementation.
 type GrantTest struct {
@@ -0,0 +1,36 @@
+package flags
+
+import "github.com/modernice/goes/codec"
+
+// Flag events
+const (
+	FlagDefined        = "goes.contrib.flags.flag.defined"
+	FlagEnabled        = "goes.contrib.flags.flag.enabled"
+	FlagDisabled       = "goes.contrib.flags.flag.disabled"
+	FlagRolloutChanged = "goes.contrib.flags.flag.rollout_changed"
+	FlagTargeted       = "goes.contrib.flags.flag.targeted"
+	FlagUntargeted     = "goes.contrib.flags.flag.untargeted"
+)
+
+// FlagEvents are all events of a Flag.
+var FlagEvents = [...]string{
+	FlagDefined,
+	FlagEnabled,
+	FlagDisabled,
+	FlagRolloutChanged,
+	FlagTargeted,
+	FlagUntargeted,
+}
+
+// FlagDefinedData is the event data for FlagDefined.
+type FlagDefinedData string
+
+// RegisterEvents registers the events of the flags package into a registry.
+func RegisterEvents(r codec.Registerer) {
+	codec.Register[FlagDefinedData](r, FlagDefined)
+	codec.Register[struct{}](r, FlagEnabled)
+	codec.Register[struct{}](r, FlagDisabled)
+	codec.Register[int](r, FlagRolloutChanged)
+	codec.Register[[]string](r, FlagTargeted)
+	codec.Register[[]string](r, FlagUntargeted)
+}
@@ -0,0 +1,229 @@
+// Package flags implements event-sourced feature flags.
+//
+// A Flag is an aggregate that is identified by a unique key. A flag can be
+// enabled or disabled, rolled out to a percentage of subjects (e.g. users or
+// tenants), and targeted at specific subjects. The Flags projection provides
+// the current state of all flags to the services that evaluate them, and is
+// updated as soon as flag events are published over the event bus.
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+)
+
+// FlagAggregate is the name of the Flag aggregate.
+const FlagAggregate = "goes.contrib.flags.flag"
+
+var (
+	// ErrEmptyKey is returned when trying to define a flag with an empty key.
+	ErrEmptyKey = errors.New("empty key")
+
+	// ErrMissingKey is returned when trying to change a flag before it was
+	// defined.
+	ErrMissingKey = errors.New("missing key")
+
+	// ErrAlreadyDefined is returned when trying to define a flag that was
+	// already defined.
+	ErrAlreadyDefined = errors.New("flag already defined")
+
+	// ErrInvalidRollout is returned when trying to roll out a flag to a
+	// percentage that is not within 0 and 100.
+	ErrInvalidRollout = errors.New("rollout must be within 0 and 100")
+)
+
+// State is the state of a feature flag.
+type State struct {
+	// ID is the aggregate id of the flag.
+	ID uuid.UUID
+
+	// Key is the unique key of the flag.
+	Key string
+
+	// Enabled reports whether the flag is enabled. A disabled flag is disabled
+	// for all subjects.
+	Enabled bool
+
+	// Rollout is the percentage of subjects for which the enabled flag is
+	// active.
+	Rollout int
+
+	// Targets are the subjects for which the enabled flag is always active.
+	Targets []string
+}
+
+// Flag is a feature flag.
+//
+//	flag := flags.NewFlag(uuid.New())
+//	flag.Define("new-checkout")
+//	flag.Rollout(20)
+//	flag.Target("user-1", "user-2")
+//	flag.Enable()
+type Flag struct {
+	*aggregate.Base
+	State
+}
+
+// NewFlag returns the flag with the given id.
+func NewFlag(id uuid.UUID) *Flag {
+	f := &Flag{
+		Base:  aggregate.New(FlagAggregate, id),
+		State: State{ID: id},
+	}
+
+	for _, name := range FlagEvents {
+		f.RegisterEventHandler(name, f.State.apply)
+	}
+
+	return f
+}
+
+// Define defines the flag with the given key, which must not be empty. Define
+// must be called before any other method that changes the flag.
+func (f *Flag) Define(key string) error {
+	if key == "" {
+		return ErrEmptyKey
+	}
+	if f.Key != "" {
+		return fmt.Errorf("%w: %q", ErrAlreadyDefined, f.Key)
+	}
+	aggregate.Next(f, FlagDefined, FlagDefinedData(key))
+	return nil
+}
+
+// Enable enables the flag.
+func (f *Flag) Enable() error {
+	if err := f.checkKey(); err != nil {
+		return err
+	}
+	if !f.Enabled {
+		aggregate.Next(f, FlagEnabled, struct{}{})
+	}
+	return nil
+}
+
+// Disable disables the flag.
+func (f *Flag) Disable() error {
+	if err := f.checkKey(); err != nil {
+		return err
+	}
+	if f.Enabled {
+		aggregate.Next(f, FlagDisabled, struct{}{})
+	}
+	return nil
+}
+
+// Rollout rolls out the flag to the given percentage of subjects. Whether a
+// subject is within the rollout is determined by a hash of the flag key and
+// the subject, so a subject stays within the rollout when the percentage is
+// increased.
+func (f *Flag) Rollout(percent int) error {
+	if err := f.checkKey(); err != nil {
+		return err
+	}
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("%w: %d", ErrInvalidRollout, percent)
+	}
+	if f.State.Rollout != percent {
+		aggregate.Next(f, FlagRolloutChanged, percent)
+	}
+	return nil
+}
+
+// Target activates the flag for the given subjects, regardless of the rollout.
+func (f *Flag) Target(subjects ...string) error {
+	if err := f.checkKey(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, subject := range subjects {
+		if !slices.Contains(f.Targets, subject) && !slices.Contains(missing, subject) {
+			missing = append(missing, subject)
+		}
+	}
+
+	if len(missing) > 0 {
+		aggregate.Next(f, FlagTargeted, missing)
+	}
+
+	return nil
+}
+
+// Untarget removes the given subjects from the targets of the flag.
+func (f *Flag) Untarget(subjects ...string) error {
+	if err := f.checkKey(); err != nil {
+		return err
+	}
+
+	var targeted []string
+	for _, subject := range subjects {
+		if slices.Contains(f.Targets, subject) && !slices.Contains(targeted, subject) {
+			targeted = append(targeted, subject)
+		}
+	}
+
+	if len(targeted) > 0 {
+		aggregate.Next(f, FlagUntargeted, targeted)
+	}
+
+	return nil
+}
+
+func (f *Flag) checkKey() error {
+	if f.Key == "" {
+		return ErrMissingKey
+	}
+	return nil
+}
+
+// Active returns whether the flag is active for the given subject. A flag is
+// active if it is enabled and the subject is either targeted or within the
+// rollout of the flag.
+func (s State) Active(subject string) bool {
+	if !s.Enabled {
+		return false
+	}
+
+	if slices.Contains(s.Targets, subject) {
+		return true
+	}
+
+	return bucket(s.Key, subject) < s.Rollout
+}
+
+// apply applies the given flag event to the state.
+func (s *State) apply(evt event.Event) {
+	switch evt.Name() {
+	case FlagDefined:
+		s.Key = string(evt.Data().(FlagDefinedData))
+	case FlagEnabled:
+		s.Enabled = true
+	case FlagDisabled:
+		s.Enabled = false
+	case FlagRolloutChanged:
+		s.Rollout = evt.Data().(int)
+	case FlagTargeted:
+		s.Targets = append(s.Targets, evt.Data().([]string)...)
+	case FlagUntargeted:
+		untargeted := evt.Data().([]string)
+		s.Targets = slices.DeleteFunc(s.Targets, func(subject string) bool {
+			return slices.Contains(untargeted, subject)
+		})
+	}
+}
+
+// bucket returns the rollout bucket (0-99) of the given subject.
+func bucket(key, subject string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(subject))
+	return int(h.Sum32() % 100)
+}
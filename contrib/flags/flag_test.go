@@ -0,0 +1,102 @@
+package flags_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/contrib/flags"
+)
+
+func TestFlag_Define(t *testing.T) {
+	flag := flags.NewFlag(uuid.New())
+
+	if err := flag.Enable(); !errors.Is(err, flags.ErrMissingKey) {
+		t.Fatalf("Enable() should fail with %q before the flag is defined; got %v", flags.ErrMissingKey, err)
+	}
+
+	if err := flag.Define(""); !errors.Is(err, flags.ErrEmptyKey) {
+		t.Fatalf("Define() should fail with %q; got %v", flags.ErrEmptyKey, err)
+	}
+
+	if err := flag.Define("foo"); err != nil {
+		t.Fatalf("Define() failed with %q", err)
+	}
+
+	if flag.Key != "foo" {
+		t.Fatalf("Key should be %q; is %q", "foo", flag.Key)
+	}
+
+	if err := flag.Define("bar"); !errors.Is(err, flags.ErrAlreadyDefined) {
+		t.Fatalf("Define() should fail with %q; got %v", flags.ErrAlreadyDefined, err)
+	}
+}
+
+func TestFlag_Active(t *testing.T) {
+	flag := flags.NewFlag(uuid.New())
+	flag.Define("foo")
+	flag.Target("bar")
+
+	if flag.Active("bar") {
+		t.Fatalf("disabled flag should not be active for targeted subjects")
+	}
+
+	flag.Enable()
+
+	if !flag.Active("bar") {
+		t.Fatalf("enabled flag should be active for targeted subjects")
+	}
+
+	if flag.Active("baz") {
+		t.Fatalf("flag without rollout should not be active for other subjects")
+	}
+
+	flag.Untarget("bar")
+
+	if flag.Active("bar") {
+		t.Fatalf("flag should not be active for untargeted subjects")
+	}
+
+	if err := flag.Rollout(101); !errors.Is(err, flags.ErrInvalidRollout) {
+		t.Fatalf("Rollout() should fail with %q; got %v", flags.ErrInvalidRollout, err)
+	}
+
+	flag.Rollout(100)
+
+	if !flag.Active("baz") {
+		t.Fatalf("flag with full rollout should be active for all subjects")
+	}
+
+	flag.Disable()
+
+	if flag.Active("baz") {
+		t.Fatalf("disabled flag should not be active")
+	}
+}
+
+func TestFlag_Rollout(t *testing.T) {
+	flag := flags.NewFlag(uuid.New())
+	flag.Define("foo")
+	flag.Enable()
+	flag.Rollout(30)
+
+	var active []string
+	for i := 0; i < 1000; i++ {
+		if subject := fmt.Sprint(i); flag.Active(subject) {
+			active = append(active, subject)
+		}
+	}
+
+	if len(active) < 200 || len(active) > 400 {
+		t.Fatalf("~30%% of subjects should be active; %d of 1000 are", len(active))
+	}
+
+	flag.Rollout(60)
+
+	for _, subject := range active {
+		if !flag.Active(subject) {
+			t.Fatalf("subject %q should stay active when the rollout is increased", subject)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package flags
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/projection/lookup"
+)
+
+// LookupKey looks up the aggregate id of a flag from a given flag key.
+const LookupKey = "key"
+
+// LookupTable provides lookups from flag keys to aggregate ids of those flags.
+type LookupTable struct {
+	*lookup.Lookup
+}
+
+var lookupEvents = [...]string{FlagDefined}
+
+// NewLookup returns a new lookup for aggregate ids of flags. Use the lookup in
+// command handlers that address flags by their keys.
+func NewLookup(store event.Store, bus event.Bus, opts ...lookup.Option) *LookupTable {
+	return &LookupTable{Lookup: lookup.New(store, bus, lookupEvents[:], opts...)}
+}
+
+// Flag returns the aggregate id of the flag with the given key.
+func (l *LookupTable) Flag(ctx context.Context, key string) (uuid.UUID, bool) {
+	select {
+	case <-ctx.Done():
+		return uuid.Nil, false
+	case <-l.Ready():
+	}
+	return l.Reverse(ctx, FlagAggregate, LookupKey, key)
+}
+
+// ProvideLookup implements lookup.Data.
+func (data FlagDefinedData) ProvideLookup(p lookup.Provider) {
+	p.Provide(LookupKey, string(data))
+}
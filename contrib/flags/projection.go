@@ -0,0 +1,150 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+// Flags is an in-memory projection of all feature flags. Flags is updated as
+// soon as flag events are published over the event bus, so that services can
+// evaluate flags without querying a database. A *Flags is thread-safe.
+//
+//	flags := flags.NewFlags(store, bus)
+//	errs, err := flags.Run(context.TODO())
+//	// handle err
+//
+//	if flags.Active(ctx, "new-checkout", userID) {
+//		// ...
+//	}
+type Flags struct {
+	schedule *schedule.Continuous
+
+	mux   sync.RWMutex
+	flags map[uuid.UUID]*State
+	keys  map[string]uuid.UUID
+
+	once  sync.Once
+	ready chan struct{}
+}
+
+// NewFlags returns the projection of all feature flags. Call f.Run() to start
+// the projection.
+func NewFlags(store event.Store, bus event.Bus, opts ...schedule.ContinuousOption) *Flags {
+	return &Flags{
+		schedule: schedule.Continuously(bus, store, FlagEvents[:], opts...),
+		flags:    make(map[uuid.UUID]*State),
+		keys:     make(map[string]uuid.UUID),
+		ready:    make(chan struct{}),
+	}
+}
+
+// Ready returns a channel that is closed when the flags have been projected
+// for the first time.
+func (f *Flags) Ready() <-chan struct{} {
+	return f.ready
+}
+
+// Run projects the flags until ctx is canceled. Any asynchronous errors are
+// sent into the returned channel.
+func (f *Flags) Run(ctx context.Context) (<-chan error, error) {
+	errs, err := f.schedule.Subscribe(ctx, f.ApplyJob)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to projection schedule: %w", err)
+	}
+
+	go f.schedule.Trigger(ctx)
+
+	return errs, nil
+}
+
+// ApplyJob applies the given projection job on the flags.
+func (f *Flags) ApplyJob(ctx projection.Job) error {
+	defer f.once.Do(func() { close(f.ready) })
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return ctx.Apply(ctx, f)
+}
+
+// ApplyEvent implements projection.EventApplier.
+func (f *Flags) ApplyEvent(evt event.Event) {
+	id, name, _ := evt.Aggregate()
+	if name != FlagAggregate {
+		return
+	}
+
+	state, ok := f.flags[id]
+	if !ok {
+		state = &State{ID: id}
+		f.flags[id] = state
+	}
+
+	state.apply(evt)
+
+	if evt.Name() == FlagDefined {
+		f.keys[state.Key] = id
+	}
+}
+
+// Flag returns the state of the flag with the given key, or false if the flag
+// does not exist. Flag waits until the flags are ready or ctx is canceled.
+func (f *Flags) Flag(ctx context.Context, key string) (State, bool) {
+	select {
+	case <-ctx.Done():
+		return State{}, false
+	case <-f.ready:
+	}
+
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	id, ok := f.keys[key]
+	if !ok {
+		return State{}, false
+	}
+
+	return clone(f.flags[id]), true
+}
+
+// Active returns whether the flag with the given key is active for the given
+// subject. Flags that do not exist are inactive.
+func (f *Flags) Active(ctx context.Context, key, subject string) bool {
+	state, ok := f.Flag(ctx, key)
+	return ok && state.Active(subject)
+}
+
+// All returns the states of all defined flags, sorted by key.
+func (f *Flags) All(ctx context.Context) []State {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-f.ready:
+	}
+
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	out := make([]State, 0, len(f.keys))
+	for _, id := range f.keys {
+		out = append(out, clone(f.flags[id]))
+	}
+
+	slices.SortFunc(out, func(a, b State) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	return out
+}
+
+func clone(s *State) State {
+	out := *s
+	out.Targets = slices.Clone(s.Targets)
+	return out
+}
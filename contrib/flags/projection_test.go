@@ -0,0 +1,102 @@
+package flags_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/contrib/flags"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/internal/testutil"
+)
+
+func TestFlags(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.WithBus(eventstore.New(), bus)
+	repo := flags.NewFlagRepository(repository.New(store))
+
+	flag := flags.NewFlag(uuid.New())
+	flag.Define("foo")
+	flag.Target("bar")
+	if err := repo.Save(ctx, flag); err != nil {
+		t.Fatalf("save flag: %v", err)
+	}
+
+	proj := flags.NewFlags(store, bus)
+	errs, err := proj.Run(ctx)
+	if err != nil {
+		t.Fatalf("run projection: %v", err)
+	}
+	go testutil.PanicOn(errs)
+
+	state, ok := proj.Flag(ctx, "foo")
+	if !ok {
+		t.Fatalf("Flag() should return the flag")
+	}
+
+	if state.ID != flag.AggregateID() || state.Enabled {
+		t.Fatalf("unexpected flag state: %+v", state)
+	}
+
+	if proj.Active(ctx, "foo", "bar") {
+		t.Fatalf("disabled flag should not be active")
+	}
+
+	flag.Enable()
+	if err := repo.Save(ctx, flag); err != nil {
+		t.Fatalf("save flag: %v", err)
+	}
+
+	<-time.After(100 * time.Millisecond)
+
+	if !proj.Active(ctx, "foo", "bar") {
+		t.Fatalf("flag should be active after it was enabled")
+	}
+
+	if all := proj.All(ctx); len(all) != 1 || all[0].Key != "foo" {
+		t.Fatalf("All() should return the flag; got %+v", all)
+	}
+
+	if _, ok := proj.Flag(ctx, "bar"); ok {
+		t.Fatalf("Flag() should not return unknown flags")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.WithBus(eventstore.New(), bus)
+	repo := flags.NewFlagRepository(repository.New(store))
+
+	look := flags.NewLookup(store, bus)
+	errs, err := look.Run(ctx)
+	if err != nil {
+		t.Fatalf("run lookup: %v", err)
+	}
+	go testutil.PanicOn(errs)
+
+	flag := flags.NewFlag(uuid.New())
+	flag.Define("foo")
+	if err := repo.Save(ctx, flag); err != nil {
+		t.Fatalf("save flag: %v", err)
+	}
+
+	<-time.After(100 * time.Millisecond)
+
+	id, ok := look.Flag(ctx, "foo")
+	if !ok {
+		t.Fatalf("Flag() should provide the flag id")
+	}
+
+	if id != flag.AggregateID() {
+		t.Fatalf("Flag() returned wrong flag id. %s != %s", id, flag.AggregateID())
+	}
+}
@@ -0,0 +1,14 @@
+package flags
+
+import (
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
+)
+
+// FlagRepository is the repository for Flags.
+type FlagRepository = aggregate.TypedRepository[*Flag]
+
+// NewFlagRepository returns the repository for Flags.
+func NewFlagRepository(repo aggregate.Repository) FlagRepository {
+	return repository.Typed(repo, NewFlag)
+}
@@ -0,0 +1,255 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client is a minimal client for the REST API of Elasticsearch and OpenSearch.
+type Client struct {
+	url      string
+	http     *http.Client
+	user     string
+	password string
+}
+
+// ClientOption is an option for a Client.
+type ClientOption func(*Client)
+
+// Error is returned by a Client if the search engine responds with an error.
+type Error struct {
+	// Status is the HTTP status code of the response.
+	Status int
+
+	// Body is the body of the response.
+	Body string
+}
+
+// Op is an operation of a bulk request.
+type Op struct {
+	// Index is the name of the index.
+	Index string
+
+	// ID is the id of the document.
+	ID string
+
+	// Doc is the document to index. Doc is ignored if Delete is true.
+	Doc any
+
+	// Delete deletes the document instead of indexing it.
+	Delete bool
+}
+
+// HTTPClient returns a ClientOption that sets the underlying *http.Client.
+// Defaults to http.DefaultClient.
+func HTTPClient(c *http.Client) ClientOption {
+	return func(client *Client) {
+		client.http = c
+	}
+}
+
+// BasicAuth returns a ClientOption that authenticates requests using HTTP
+// basic authentication.
+func BasicAuth(user, password string) ClientOption {
+	return func(client *Client) {
+		client.user = user
+		client.password = password
+	}
+}
+
+// NewClient returns a client for the Elasticsearch or OpenSearch cluster at the
+// given url, e.g. "http://localhost:9200".
+func NewClient(url string, opts ...ClientOption) *Client {
+	c := &Client{url: strings.TrimSuffix(url, "/"), http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CreateIndex creates an index with the given mapping.
+func (c *Client) CreateIndex(ctx context.Context, index string, mapping Mapping) error {
+	return c.do(ctx, http.MethodPut, "/"+url.PathEscape(index), map[string]any{"mappings": mapping}, nil)
+}
+
+// DeleteIndex deletes an index.
+func (c *Client) DeleteIndex(ctx context.Context, index string) error {
+	return c.do(ctx, http.MethodDelete, "/"+url.PathEscape(index), nil, nil)
+}
+
+// Get fetches the document with the given id into doc. Get reports whether the
+// document was found.
+func (c *Client) Get(ctx context.Context, index, id string, doc any) (bool, error) {
+	var resp struct {
+		Found  bool            `json:"found"`
+		Source json.RawMessage `json:"_source"`
+	}
+
+	if err := c.do(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil, &resp); err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !resp.Found {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(resp.Source, doc); err != nil {
+		return false, fmt.Errorf("decode document: %w", err)
+	}
+
+	return true, nil
+}
+
+// Bulk executes the given operations in a single bulk request.
+func (c *Client) Bulk(ctx context.Context, ops ...Op) error {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, op := range ops {
+		meta := map[string]string{"_index": op.Index, "_id": op.ID}
+		if op.Delete {
+			if err := enc.Encode(map[string]any{"delete": meta}); err != nil {
+				return fmt.Errorf("encode bulk operation: %w", err)
+			}
+			continue
+		}
+		if err := enc.Encode(map[string]any{"index": meta}); err != nil {
+			return fmt.Errorf("encode bulk operation: %w", err)
+		}
+		if err := enc.Encode(op.Doc); err != nil {
+			return fmt.Errorf("encode document %q: %w", op.ID, err)
+		}
+	}
+
+	var resp struct {
+		Errors bool                         `json:"errors"`
+		Items  []map[string]json.RawMessage `json:"items"`
+	}
+
+	if err := c.send(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &body, &resp); err != nil {
+		return err
+	}
+
+	if resp.Errors {
+		return fmt.Errorf("bulk request failed for some documents: %s", firstBulkError(resp.Items))
+	}
+
+	return nil
+}
+
+// AliasIndices returns the indices that the given alias points to.
+func (c *Client) AliasIndices(ctx context.Context, alias string) ([]string, error) {
+	var resp map[string]json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/_alias/"+url.PathEscape(alias), nil, &resp); err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	indices := make([]string, 0, len(resp))
+	for index := range resp {
+		indices = append(indices, index)
+	}
+
+	return indices, nil
+}
+
+// SwapAlias atomically points the given alias to index and removes it from the
+// old indices.
+func (c *Client) SwapAlias(ctx context.Context, alias, index string, old ...string) error {
+	actions := []map[string]any{{"add": map[string]string{"index": index, "alias": alias}}}
+	for _, o := range old {
+		actions = append(actions, map[string]any{"remove": map[string]string{"index": o, "alias": alias}})
+	}
+	return c.do(ctx, http.MethodPost, "/_aliases", map[string]any{"actions": actions}, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+	return c.send(ctx, method, path, "application/json", r, out)
+}
+
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %w", method, path, &Error{Status: resp.StatusCode, Body: string(b)})
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(b, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// IsNotFound reports whether err is an *Error with status 404.
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Status == http.StatusNotFound
+}
+
+// Error implements error.
+func (err *Error) Error() string {
+	return fmt.Sprintf("search engine responded with status %d: %s", err.Status, err.Body)
+}
+
+func firstBulkError(items []map[string]json.RawMessage) string {
+	for _, item := range items {
+		for _, raw := range item {
+			var result struct {
+				ID    string          `json:"_id"`
+				Error json.RawMessage `json:"error"`
+			}
+			if json.Unmarshal(raw, &result) == nil && len(result.Error) > 0 {
+				return fmt.Sprintf("%s: %s", result.ID, result.Error)
+			}
+		}
+	}
+	return "unknown error"
+}
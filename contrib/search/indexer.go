@@ -0,0 +1,276 @@
+// Package search maintains search indexes in Elasticsearch or OpenSearch from
+// events using the projection system.
+//
+// Most applications end up needing a search read model. An Indexer projects
+// chosen events into documents of a search index, one document per aggregate,
+// and can rebuild the index from the event store without downtime:
+//
+//	type OrderDoc struct {
+//		ID       uuid.UUID `json:"id"`
+//		Customer string    `json:"customer"`
+//		Status   string    `json:"status" search:"keyword"`
+//	}
+//
+//	ix := search.New[OrderDoc](search.NewClient("http://localhost:9200"), "orders",
+//		search.On(OrderPlaced, func(doc *OrderDoc, evt event.Of[OrderPlacedData]) {
+//			doc.ID, _, _ = evt.Aggregate()
+//			doc.Customer = evt.Data().Customer
+//			doc.Status = "placed"
+//		}),
+//		search.DeleteOn[OrderDoc](OrderArchived),
+//	)
+//
+//	if err := ix.Setup(ctx); err != nil { ... }
+//
+//	s := schedule.Continuously(bus, store, ix.Events())
+//	errs, err := s.Subscribe(ctx, ix.Apply)
+package search
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+// DefaultBatchSize is the default number of documents that an Indexer writes
+// in a single bulk request.
+const DefaultBatchSize = 500
+
+// Indexer maintains a search index from events. The index is accessed through
+// an alias, so that it can be rebuilt into a new index and swapped atomically.
+type Indexer[Doc any] struct {
+	client    *Client
+	alias     string
+	mapping   Mapping
+	batchSize int
+	appliers  map[string]func(*Doc, event.Event)
+	deletes   map[string]bool
+	events    []string
+}
+
+// Option is an option for an Indexer.
+type Option[Doc any] func(*Indexer[Doc])
+
+// On returns an Option that applies events with the given name to the document
+// of the event's aggregate. Events that do not belong to an aggregate are
+// ignored.
+func On[Data, Doc any](eventName string, fn func(*Doc, event.Of[Data])) Option[Doc] {
+	return func(ix *Indexer[Doc]) {
+		ix.addEvent(eventName)
+		ix.appliers[eventName] = func(doc *Doc, evt event.Event) {
+			fn(doc, event.Cast[Data](evt))
+		}
+	}
+}
+
+// DeleteOn returns an Option that deletes the document of an aggregate from the
+// index when one of the given events is applied.
+func DeleteOn[Doc any](eventNames ...string) Option[Doc] {
+	return func(ix *Indexer[Doc]) {
+		for _, name := range eventNames {
+			ix.addEvent(name)
+			ix.deletes[name] = true
+		}
+	}
+}
+
+// WithMapping returns an Option that overrides the index mapping. By default,
+// the mapping is derived from the document type using MappingOf.
+func WithMapping[Doc any](m Mapping) Option[Doc] {
+	return func(ix *Indexer[Doc]) {
+		ix.mapping = m
+	}
+}
+
+// BatchSize returns an Option that sets the number of documents that are
+// written in a single bulk request. Defaults to DefaultBatchSize.
+func BatchSize[Doc any](n int) Option[Doc] {
+	return func(ix *Indexer[Doc]) {
+		ix.batchSize = n
+	}
+}
+
+// New returns an Indexer that maintains the index behind the given alias.
+func New[Doc any](client *Client, alias string, opts ...Option[Doc]) *Indexer[Doc] {
+	ix := &Indexer[Doc]{
+		client:    client,
+		alias:     alias,
+		mapping:   MappingOf[Doc](),
+		batchSize: DefaultBatchSize,
+		appliers:  make(map[string]func(*Doc, event.Event)),
+		deletes:   make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(ix)
+	}
+	if ix.batchSize < 1 {
+		ix.batchSize = 1
+	}
+	return ix
+}
+
+// Alias returns the alias of the index.
+func (ix *Indexer[Doc]) Alias() string {
+	return ix.alias
+}
+
+// Events returns the names of the events that are indexed.
+func (ix *Indexer[Doc]) Events() []string {
+	return ix.events
+}
+
+// Setup creates the index and its alias if the alias does not exist yet.
+func (ix *Indexer[Doc]) Setup(ctx context.Context) error {
+	indices, err := ix.client.AliasIndices(ctx, ix.alias)
+	if err != nil {
+		return fmt.Errorf("get indices of %q alias: %w", ix.alias, err)
+	}
+
+	if len(indices) > 0 {
+		return nil
+	}
+
+	index := ix.newIndexName()
+	if err := ix.client.CreateIndex(ctx, index, ix.mapping); err != nil {
+		return fmt.Errorf("create %q index: %w", index, err)
+	}
+
+	if err := ix.client.SwapAlias(ctx, ix.alias, index); err != nil {
+		return fmt.Errorf("create %q alias: %w", ix.alias, err)
+	}
+
+	return nil
+}
+
+// Apply applies the events of a projection job to the index. Apply can be
+// used as the apply function of a projection schedule.
+func (ix *Indexer[Doc]) Apply(job projection.Job) error {
+	events, errs, err := job.Events(job, query.New(query.Name(ix.events...)))
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+	return ix.index(job, ix.alias, events, errs)
+}
+
+// Rebuild rebuilds the index from the events in the given store. The events
+// are indexed into a new index, and the alias is swapped to the new index
+// afterwards. The old index is deleted.
+func (ix *Indexer[Doc]) Rebuild(ctx context.Context, store event.Store) error {
+	index := ix.newIndexName()
+	if err := ix.client.CreateIndex(ctx, index, ix.mapping); err != nil {
+		return fmt.Errorf("create %q index: %w", index, err)
+	}
+
+	events, errs, err := store.Query(ctx, query.New(query.Name(ix.events...), query.SortByTime()))
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	if err := ix.index(ctx, index, events, errs); err != nil {
+		return fmt.Errorf("index events: %w", err)
+	}
+
+	old, err := ix.client.AliasIndices(ctx, ix.alias)
+	if err != nil {
+		return fmt.Errorf("get indices of %q alias: %w", ix.alias, err)
+	}
+
+	if err := ix.client.SwapAlias(ctx, ix.alias, index, old...); err != nil {
+		return fmt.Errorf("swap %q alias: %w", ix.alias, err)
+	}
+
+	for _, o := range old {
+		if err := ix.client.DeleteIndex(ctx, o); err != nil {
+			return fmt.Errorf("delete old %q index: %w", o, err)
+		}
+	}
+
+	return nil
+}
+
+func (ix *Indexer[Doc]) index(ctx context.Context, index string, events <-chan event.Event, errs <-chan error) error {
+	docs := make(map[uuid.UUID]*Doc)
+	deleted := make(map[uuid.UUID]bool)
+	var order []uuid.UUID
+
+	flush := func() error {
+		ops := make([]Op, 0, len(order))
+		for _, id := range order {
+			op := Op{Index: index, ID: id.String()}
+			if deleted[id] {
+				op.Delete = true
+			} else {
+				op.Doc = docs[id]
+			}
+			ops = append(ops, op)
+		}
+
+		if err := ix.client.Bulk(ctx, ops...); err != nil {
+			return fmt.Errorf("write documents: %w", err)
+		}
+
+		clear(docs)
+		clear(deleted)
+		order = order[:0]
+
+		return nil
+	}
+
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		id, _, _ := evt.Aggregate()
+		if id == uuid.Nil {
+			return nil
+		}
+
+		if _, ok := docs[id]; !ok {
+			if len(order) >= ix.batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+
+			doc := new(Doc)
+			if _, err := ix.client.Get(ctx, index, id.String(), doc); err != nil {
+				return fmt.Errorf("get document %q: %w", id, err)
+			}
+			docs[id] = doc
+			order = append(order, id)
+		}
+
+		if ix.deletes[evt.Name()] {
+			deleted[id] = true
+			*docs[id] = *new(Doc)
+			return nil
+		}
+
+		if apply, ok := ix.appliers[evt.Name()]; ok {
+			delete(deleted, id)
+			apply(docs[id], evt)
+		}
+
+		return nil
+	}, events, errs); err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+func (ix *Indexer[Doc]) addEvent(name string) {
+	for _, e := range ix.events {
+		if e == name {
+			return
+		}
+	}
+	ix.events = append(ix.events, name)
+}
+
+func (ix *Indexer[Doc]) newIndexName() string {
+	return fmt.Sprintf("%s_%d", ix.alias, time.Now().UnixNano())
+}
@@ -0,0 +1,115 @@
+package search_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/contrib/search"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/projection"
+)
+
+type orderDoc struct {
+	ID       uuid.UUID `json:"id"`
+	Customer string    `json:"customer"`
+	Status   string    `json:"status" search:"keyword"`
+}
+
+type orderPlaced struct{ Customer string }
+
+func newIndexer(client *search.Client) *search.Indexer[orderDoc] {
+	return search.New(client, "orders",
+		search.On("order.placed", func(doc *orderDoc, evt event.Of[orderPlaced]) {
+			doc.ID, _, _ = evt.Aggregate()
+			doc.Customer = evt.Data().Customer
+			doc.Status = "placed"
+		}),
+		search.On("order.shipped", func(doc *orderDoc, evt event.Of[struct{}]) {
+			doc.Status = "shipped"
+		}),
+		search.DeleteOn[orderDoc]("order.archived"),
+	)
+}
+
+func TestIndexer_Apply(t *testing.T) {
+	engine, srv := newFakeEngine(t)
+	ix := newIndexer(search.NewClient(srv.URL))
+
+	if err := ix.Setup(context.Background()); err != nil {
+		t.Fatalf("Setup failed with %q", err)
+	}
+
+	a, b := uuid.New(), uuid.New()
+	events := []event.Event{
+		event.New("order.placed", orderPlaced{Customer: "Bob"}, event.Aggregate(a, "order", 1)).Any(),
+		event.New("order.placed", orderPlaced{Customer: "Alice"}, event.Aggregate(b, "order", 1)).Any(),
+		event.New("order.shipped", struct{}{}, event.Aggregate(a, "order", 2)).Any(),
+		event.New("order.archived", struct{}{}, event.Aggregate(b, "order", 2)).Any(),
+	}
+
+	store := eventstore.New(slices.Clone(events)...)
+	job := projection.NewJob(context.Background(), store, query.New(query.SortByTime()))
+
+	if err := ix.Apply(job); err != nil {
+		t.Fatalf("Apply failed with %q", err)
+	}
+
+	docs := engine.docs("orders")
+
+	if len(docs) != 1 {
+		t.Fatalf("index should contain %d document; contains %d", 1, len(docs))
+	}
+
+	var doc orderDoc
+	if err := json.Unmarshal(docs[a.String()], &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+
+	if want := (orderDoc{ID: a, Customer: "Bob", Status: "shipped"}); doc != want {
+		t.Fatalf("document should be %v; is %v", want, doc)
+	}
+}
+
+func TestIndexer_Rebuild(t *testing.T) {
+	engine, srv := newFakeEngine(t)
+	ix := newIndexer(search.NewClient(srv.URL))
+
+	if err := ix.Setup(context.Background()); err != nil {
+		t.Fatalf("Setup failed with %q", err)
+	}
+
+	engine.mux.Lock()
+	oldIndex := engine.aliases["orders"]
+	engine.mux.Unlock()
+
+	id := uuid.New()
+	store := eventstore.New(
+		event.New("order.placed", orderPlaced{Customer: "Bob"}, event.Aggregate(id, "order", 1)).Any(),
+	)
+
+	if err := ix.Rebuild(context.Background(), store); err != nil {
+		t.Fatalf("Rebuild failed with %q", err)
+	}
+
+	engine.mux.Lock()
+	newIndex := engine.aliases["orders"]
+	_, oldExists := engine.indices[oldIndex]
+	engine.mux.Unlock()
+
+	if newIndex == oldIndex {
+		t.Fatalf("alias should point to a new index")
+	}
+
+	if oldExists {
+		t.Fatalf("old index should have been deleted")
+	}
+
+	if _, ok := engine.docs("orders")[id.String()]; !ok {
+		t.Fatalf("rebuilt index should contain the document of %s", id)
+	}
+}
@@ -0,0 +1,139 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mapping is the mapping of a search index.
+type Mapping struct {
+	Properties map[string]Property `json:"properties"`
+}
+
+// Property is the mapping of a field of a document.
+type Property struct {
+	Type       string              `json:"type,omitempty"`
+	Properties map[string]Property `json:"properties,omitempty"`
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// MappingOf derives the mapping of an index from the document type T. Field
+// names are taken from the `json` tags of the fields. Strings are mapped to
+// "text", numbers to "long" and "double", booleans to "boolean", time.Time to
+// "date" and uuid.UUID to "keyword". Structs are mapped to objects and slices
+// to their element type. The type of a field can be overridden using the
+// `search` tag, and fields with a `search:"-"` tag are not mapped:
+//
+//	type OrderDoc struct {
+//		ID       uuid.UUID `json:"id"`
+//		Customer string    `json:"customer"`
+//		Status   string    `json:"status" search:"keyword"`
+//		Notes    string    `json:"-"`
+//		Internal string    `json:"internal" search:"-"`
+//	}
+func MappingOf[T any]() Mapping {
+	return Mapping{Properties: properties(reflect.TypeOf((*T)(nil)).Elem())}
+}
+
+func properties(t reflect.Type) map[string]Property {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	props := make(map[string]Property)
+	if t.Kind() != reflect.Struct {
+		return props
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !(f.Anonymous && indirect(f.Type).Kind() == reflect.Struct) {
+			continue
+		}
+
+		name, ok := fieldName(f)
+		if !ok {
+			continue
+		}
+
+		typ := f.Tag.Get("search")
+		if typ == "-" {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened, like encoding/json does.
+		if f.Anonymous && name == f.Name && indirect(f.Type).Kind() == reflect.Struct {
+			for n, p := range properties(f.Type) {
+				props[n] = p
+			}
+			continue
+		}
+
+		if typ != "" {
+			props[name] = Property{Type: typ}
+			continue
+		}
+
+		if p, ok := property(f.Type); ok {
+			props[name] = p
+		}
+	}
+
+	return props
+}
+
+func property(t reflect.Type) (Property, bool) {
+	t = indirect(t)
+
+	switch t {
+	case timeType:
+		return Property{Type: "date"}, true
+	case uuidType:
+		return Property{Type: "keyword"}, true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Property{Type: "text"}, true
+	case reflect.Bool:
+		return Property{Type: "boolean"}, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Property{Type: "long"}, true
+	case reflect.Float32, reflect.Float64:
+		return Property{Type: "double"}, true
+	case reflect.Slice, reflect.Array:
+		return property(t.Elem())
+	case reflect.Struct:
+		return Property{Type: "object", Properties: properties(t)}, true
+	case reflect.Map:
+		return Property{Type: "object"}, true
+	default:
+		return Property{}, false
+	}
+}
+
+func fieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,55 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/modernice/goes/contrib/search"
+)
+
+type mappingDoc struct {
+	embeddedDoc
+
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Status   string    `json:"status" search:"keyword"`
+	Count    int       `json:"count"`
+	Price    float64   `json:"price"`
+	Active   bool      `json:"active"`
+	Created  time.Time `json:"created"`
+	Tags     []string  `json:"tags"`
+	Address  *address  `json:"address"`
+	Ignored  string    `json:"-"`
+	Internal string    `json:"internal" search:"-"`
+}
+
+type embeddedDoc struct {
+	Tenant string `json:"tenant" search:"keyword"`
+}
+
+type address struct {
+	City string `json:"city"`
+}
+
+func TestMappingOf(t *testing.T) {
+	want := search.Mapping{Properties: map[string]search.Property{
+		"tenant":  {Type: "keyword"},
+		"id":      {Type: "keyword"},
+		"name":    {Type: "text"},
+		"status":  {Type: "keyword"},
+		"count":   {Type: "long"},
+		"price":   {Type: "double"},
+		"active":  {Type: "boolean"},
+		"created": {Type: "date"},
+		"tags":    {Type: "text"},
+		"address": {Type: "object", Properties: map[string]search.Property{
+			"city": {Type: "text"},
+		}},
+	}}
+
+	if got := search.MappingOf[mappingDoc](); !cmp.Equal(want, got) {
+		t.Fatalf("unexpected mapping:\n%s", cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,112 @@
+package search_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeEngine is an in-memory fake of the parts of the Elasticsearch REST API
+// that are used by the search package.
+type fakeEngine struct {
+	mux     sync.Mutex
+	indices map[string]map[string]json.RawMessage
+	aliases map[string]string
+}
+
+func newFakeEngine(t *testing.T) (*fakeEngine, *httptest.Server) {
+	e := &fakeEngine{
+		indices: make(map[string]map[string]json.RawMessage),
+		aliases: make(map[string]string),
+	}
+	srv := httptest.NewServer(e)
+	t.Cleanup(srv.Close)
+	return e, srv
+}
+
+func (e *fakeEngine) docs(alias string) map[string]json.RawMessage {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return e.indices[e.resolve(alias)]
+}
+
+func (e *fakeEngine) resolve(name string) string {
+	if index, ok := e.aliases[name]; ok {
+		return index
+	}
+	return name
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodPost && parts[0] == "_bulk":
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]struct {
+				Index string `json:"_index"`
+				ID    string `json:"_id"`
+			}
+			json.Unmarshal(scanner.Bytes(), &action)
+			if meta, ok := action["delete"]; ok {
+				delete(e.indices[e.resolve(meta.Index)], meta.ID)
+				continue
+			}
+			meta := action["index"]
+			scanner.Scan()
+			e.indices[e.resolve(meta.Index)][meta.ID] = json.RawMessage(append([]byte(nil), scanner.Bytes()...))
+		}
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+
+	case r.Method == http.MethodGet && parts[0] == "_alias":
+		index, ok := e.aliases[parts[1]]
+		if !ok {
+			http.Error(w, `{}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{index: map[string]any{}})
+
+	case r.Method == http.MethodPost && parts[0] == "_aliases":
+		var req struct {
+			Actions []map[string]struct {
+				Index string `json:"index"`
+				Alias string `json:"alias"`
+			} `json:"actions"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, action := range req.Actions {
+			if add, ok := action["add"]; ok {
+				e.aliases[add.Alias] = add.Index
+			}
+		}
+		w.Write([]byte(`{}`))
+
+	case r.Method == http.MethodPut && len(parts) == 1:
+		e.indices[parts[0]] = make(map[string]json.RawMessage)
+		w.Write([]byte(`{}`))
+
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		delete(e.indices, parts[0])
+		w.Write([]byte(`{}`))
+
+	case r.Method == http.MethodGet && len(parts) == 3 && parts[1] == "_doc":
+		doc, ok := e.indices[e.resolve(parts[0])][parts[2]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"found":false}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"found": true, "_source": doc})
+
+	default:
+		http.Error(w, "not implemented", http.StatusNotImplemented)
+	}
+}
@@ -0,0 +1,231 @@
+// Package annotation provides mutable annotations for immutable events.
+//
+// Events must never be modified after they have been inserted into an event
+// store. Operators may still need to mark events, e.g. as "compensated" or
+// "erroneous". Annotations are stored separately from the events in an
+// annotation Store and can be joined with queried events using Join.
+package annotation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/streams"
+)
+
+const (
+	// TagCompensated marks an event whose effects have been compensated.
+	TagCompensated = "compensated"
+
+	// TagErroneous marks an event that was raised erroneously.
+	TagErroneous = "erroneous"
+)
+
+// ErrNotFound is returned by a Store if an event has no annotation.
+var ErrNotFound = errors.New("annotation not found")
+
+// Annotation contains the tags and notes of a single event.
+type Annotation struct {
+	EventID   uuid.UUID
+	EventName string
+	Tags      []string
+	Notes     []Note
+	UpdatedAt time.Time
+}
+
+// Note is a free-form note that is attached to an event.
+type Note struct {
+	Author string
+	Text   string
+	Time   time.Time
+}
+
+// Store is a database for annotations.
+type Store interface {
+	// Save saves the given annotation, replacing the existing annotation of
+	// the event.
+	Save(context.Context, Annotation) error
+
+	// Find returns the annotation of the event with the given id.
+	// Implementations must return ErrNotFound if the event has no annotation.
+	Find(context.Context, uuid.UUID) (Annotation, error)
+
+	// Query queries the annotations that match the given query.
+	Query(context.Context, Query) (<-chan Annotation, <-chan error, error)
+
+	// Delete deletes the annotation of the event with the given id.
+	Delete(context.Context, uuid.UUID) error
+}
+
+// Query is a query for annotations. Empty fields do not restrict the query.
+type Query struct {
+	// EventIDs restricts the query to the annotations of the given events.
+	EventIDs []uuid.UUID
+
+	// EventNames restricts the query to the annotations of events with the
+	// given names.
+	EventNames []string
+
+	// Tags restricts the query to annotations that have at least one of the
+	// given tags.
+	Tags []string
+}
+
+// Annotated is an event together with its annotation. If the event has no
+// annotation, Annotation is the zero value.
+type Annotated struct {
+	Event      event.Event
+	Annotation Annotation
+}
+
+// Test reports whether the given annotation matches the query.
+func (q Query) Test(a Annotation) bool {
+	if len(q.EventIDs) > 0 && !slices.Contains(q.EventIDs, a.EventID) {
+		return false
+	}
+
+	if len(q.EventNames) > 0 && !slices.Contains(q.EventNames, a.EventName) {
+		return false
+	}
+
+	if len(q.Tags) > 0 && !slices.ContainsFunc(q.Tags, a.HasTag) {
+		return false
+	}
+
+	return true
+}
+
+// HasTag reports whether the annotation has the given tag.
+func (a Annotation) HasTag(tag string) bool {
+	return slices.Contains(a.Tags, tag)
+}
+
+// IsZero reports whether the annotation is the zero value.
+func (a Annotation) IsZero() bool {
+	return a.EventID == uuid.Nil
+}
+
+// Tag adds the given tags to the annotation of the event.
+func Tag(ctx context.Context, store Store, evt event.Event, tags ...string) error {
+	return update(ctx, store, evt, func(a *Annotation) {
+		for _, tag := range tags {
+			if !a.HasTag(tag) {
+				a.Tags = append(a.Tags, tag)
+			}
+		}
+	})
+}
+
+// Untag removes the given tags from the annotation of the event.
+func Untag(ctx context.Context, store Store, evt event.Event, tags ...string) error {
+	return update(ctx, store, evt, func(a *Annotation) {
+		a.Tags = slices.DeleteFunc(a.Tags, func(tag string) bool {
+			return slices.Contains(tags, tag)
+		})
+	})
+}
+
+// Annotate adds a note to the annotation of the event.
+func Annotate(ctx context.Context, store Store, evt event.Event, author, text string) error {
+	return update(ctx, store, evt, func(a *Annotation) {
+		a.Notes = append(a.Notes, Note{
+			Author: author,
+			Text:   text,
+			Time:   time.Now(),
+		})
+	})
+}
+
+func update(ctx context.Context, store Store, evt event.Event, fn func(*Annotation)) error {
+	a, err := store.Find(ctx, evt.ID())
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("find annotation: %w", err)
+		}
+		a = Annotation{EventID: evt.ID(), EventName: evt.Name()}
+	}
+
+	fn(&a)
+	a.UpdatedAt = time.Now()
+
+	if err := store.Save(ctx, a); err != nil {
+		return fmt.Errorf("save annotation: %w", err)
+	}
+
+	return nil
+}
+
+// Join joins the provided events with their annotations. Every event that is
+// received from the provided channel is sent into the returned channel,
+// together with its annotation, if any. Errors from the provided error
+// channels and errors that occur while fetching annotations are sent into the
+// returned error channel.
+//
+//	events, errs, err := eventStore.Query(ctx, query.New(...))
+//	// handle err
+//	annotated, errs := annotation.Join(ctx, annotations, events, errs)
+func Join(ctx context.Context, store Store, events <-chan event.Event, errs ...<-chan error) (<-chan Annotated, <-chan error) {
+	out := make(chan Annotated)
+	outErrs := make(chan error)
+
+	go func() {
+		defer close(outErrs)
+		defer close(out)
+
+		errChan, stop := streams.FanIn(errs...)
+		defer stop()
+
+		push := func(err error) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case outErrs <- err:
+				return true
+			}
+		}
+
+		for {
+			if events == nil && errChan == nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-errChan:
+				if !ok {
+					errChan = nil
+					break
+				}
+				if !push(err) {
+					return
+				}
+			case evt, ok := <-events:
+				if !ok {
+					events = nil
+					break
+				}
+
+				a, err := store.Find(ctx, evt.ID())
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					if !push(fmt.Errorf("find annotation of %q event: %w", evt.Name(), err)) {
+						return
+					}
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case out <- Annotated{Event: evt, Annotation: a}:
+				}
+			}
+		}
+	}()
+
+	return out, outErrs
+}
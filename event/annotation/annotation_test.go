@@ -0,0 +1,119 @@
+package annotation_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/annotation"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestTag(t *testing.T) {
+	ctx := context.Background()
+	store := annotation.NewStore()
+	evt := event.New("foo", test.FooEventData{}).Any()
+
+	if err := annotation.Tag(ctx, store, evt, annotation.TagErroneous, annotation.TagCompensated); err != nil {
+		t.Fatalf("Tag() failed with %q", err)
+	}
+
+	if err := annotation.Untag(ctx, store, evt, annotation.TagErroneous); err != nil {
+		t.Fatalf("Untag() failed with %q", err)
+	}
+
+	a, err := store.Find(ctx, evt.ID())
+	if err != nil {
+		t.Fatalf("Find() failed with %q", err)
+	}
+
+	if a.EventName != "foo" {
+		t.Errorf("EventName should be %q; got %q", "foo", a.EventName)
+	}
+
+	if a.HasTag(annotation.TagErroneous) || !a.HasTag(annotation.TagCompensated) {
+		t.Errorf("annotation should only have the %q tag; got %v", annotation.TagCompensated, a.Tags)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	ctx := context.Background()
+	store := annotation.NewStore()
+	evt := event.New("foo", test.FooEventData{}).Any()
+
+	if err := annotation.Annotate(ctx, store, evt, "bob", "refunded manually"); err != nil {
+		t.Fatalf("Annotate() failed with %q", err)
+	}
+
+	a, err := store.Find(ctx, evt.ID())
+	if err != nil {
+		t.Fatalf("Find() failed with %q", err)
+	}
+
+	if len(a.Notes) != 1 || a.Notes[0].Author != "bob" || a.Notes[0].Text != "refunded manually" {
+		t.Errorf("annotation should have the note; got %v", a.Notes)
+	}
+}
+
+func TestStore_Query(t *testing.T) {
+	ctx := context.Background()
+	store := annotation.NewStore()
+	foo := event.New("foo", test.FooEventData{}).Any()
+	bar := event.New("bar", test.BarEventData{}).Any()
+
+	annotation.Tag(ctx, store, foo, annotation.TagErroneous)
+	annotation.Tag(ctx, store, bar, annotation.TagCompensated)
+
+	result, errs, err := store.Query(ctx, annotation.Query{Tags: []string{annotation.TagErroneous}})
+	if err != nil {
+		t.Fatalf("Query() failed with %q", err)
+	}
+
+	annotations, err := streams.Drain(ctx, result, errs)
+	if err != nil {
+		t.Fatalf("drain annotations: %v", err)
+	}
+
+	if len(annotations) != 1 || annotations[0].EventID != foo.ID() {
+		t.Fatalf("query should return the annotation of the %q event; got %v", "foo", annotations)
+	}
+}
+
+func TestFind_notFound(t *testing.T) {
+	store := annotation.NewStore()
+	evt := event.New("foo", test.FooEventData{}).Any()
+
+	if _, err := store.Find(context.Background(), evt.ID()); !errors.Is(err, annotation.ErrNotFound) {
+		t.Fatalf("Find() should fail with %q; got %q", annotation.ErrNotFound, err)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	ctx := context.Background()
+	store := annotation.NewStore()
+	foo := event.New("foo", test.FooEventData{}).Any()
+	bar := event.New("bar", test.BarEventData{}).Any()
+
+	annotation.Tag(ctx, store, bar, annotation.TagErroneous)
+
+	annotated, errs := annotation.Join(ctx, store, streams.New([]event.Event{foo, bar}))
+
+	result, err := streams.Drain(ctx, annotated, errs)
+	if err != nil {
+		t.Fatalf("Join() failed with %q", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Join() should return 2 events; got %d", len(result))
+	}
+
+	if !result[0].Annotation.IsZero() {
+		t.Errorf("%q event should have no annotation; got %v", "foo", result[0].Annotation)
+	}
+
+	if !result[1].Annotation.HasTag(annotation.TagErroneous) {
+		t.Errorf("%q event should be tagged as %q", "bar", annotation.TagErroneous)
+	}
+}
@@ -0,0 +1,80 @@
+package annotation
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type memstore struct {
+	mux         sync.RWMutex
+	annotations map[uuid.UUID]Annotation
+}
+
+// NewStore returns a thread-safe in-memory annotation Store.
+func NewStore() Store {
+	return &memstore{annotations: make(map[uuid.UUID]Annotation)}
+}
+
+func (s *memstore) Save(_ context.Context, a Annotation) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.annotations[a.EventID] = clone(a)
+	return nil
+}
+
+func (s *memstore) Find(_ context.Context, id uuid.UUID) (Annotation, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	a, ok := s.annotations[id]
+	if !ok {
+		return Annotation{}, ErrNotFound
+	}
+	return clone(a), nil
+}
+
+func (s *memstore) Query(ctx context.Context, q Query) (<-chan Annotation, <-chan error, error) {
+	s.mux.RLock()
+	var result []Annotation
+	for _, a := range s.annotations {
+		if q.Test(a) {
+			result = append(result, clone(a))
+		}
+	}
+	s.mux.RUnlock()
+
+	slices.SortFunc(result, func(a, b Annotation) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+
+	out, errs := make(chan Annotation), make(chan error)
+
+	go func() {
+		defer close(errs)
+		defer close(out)
+		for _, a := range result {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- a:
+			}
+		}
+	}()
+
+	return out, errs, nil
+}
+
+func (s *memstore) Delete(_ context.Context, id uuid.UUID) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.annotations, id)
+	return nil
+}
+
+func clone(a Annotation) Annotation {
+	a.Tags = slices.Clone(a.Tags)
+	a.Notes = slices.Clone(a.Notes)
+	return a
+}
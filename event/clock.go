@@ -0,0 +1,74 @@
+package event
+
+import (
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/internal/xtime"
+)
+
+// Clock provides the times of events.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// ClockFunc allows a function to be used as a Clock.
+type ClockFunc func() time.Time
+
+// Now returns fn().
+func (fn ClockFunc) Now() time.Time {
+	return fn()
+}
+
+// SystemClock is the Clock that is used by New if no other Clock is provided.
+// It returns the current system time with nanosecond precision.
+var SystemClock Clock = ClockFunc(xtime.Now)
+
+// WithClock returns an Option that sets the time of an event to the current
+// time of the given Clock. Use WithClock to take the time of events from a
+// synchronized time source, or to create events with deterministic times in
+// tests:
+//
+//	clock := event.Monotonic(ntpClock)
+//	evt := event.New("foo", data, event.WithClock(clock))
+//
+// Options are applied in order, so a Time option that is provided after
+// WithClock overrides the time of the Clock.
+func WithClock(c Clock) Option {
+	return func(evt *Evt[any]) {
+		evt.D.Time = c.Now()
+	}
+}
+
+type monotonicClock struct {
+	clock Clock
+
+	mux  sync.Mutex
+	last time.Time
+}
+
+// Monotonic returns a Clock that returns strictly increasing times. If the
+// given Clock returns a time that is not after the previously returned time,
+// for example because multiple events are raised within the resolution of the
+// clock, or because the clock was set back, the returned Clock breaks the tie
+// by returning the previous time plus 1 nanosecond. Events that are created
+// using the same monotonic Clock are therefore always sorted by time in the
+// order they were created.
+func Monotonic(c Clock) Clock {
+	return &monotonicClock{clock: c}
+}
+
+func (c *monotonicClock) Now() time.Time {
+	now := c.clock.Now()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if !now.After(c.last) {
+		now = c.last.Add(time.Nanosecond)
+	}
+	c.last = now
+
+	return now
+}
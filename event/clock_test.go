@@ -0,0 +1,48 @@
+package event_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+)
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := event.ClockFunc(func() time.Time { return now })
+
+	evt := event.New("foo", 3, event.WithClock(clock))
+	if !evt.Time().Equal(now) {
+		t.Fatalf("event should have the time of the clock. want=%v got=%v", now, evt.Time())
+	}
+
+	later := now.Add(time.Hour)
+	evt = event.New("foo", 3, event.WithClock(clock), event.Time(later))
+	if !evt.Time().Equal(later) {
+		t.Fatalf("Time option should override the clock. want=%v got=%v", later, evt.Time())
+	}
+}
+
+func TestMonotonic(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{now, now, now.Add(-time.Second), now.Add(time.Second)}
+
+	var i int
+	clock := event.Monotonic(event.ClockFunc(func() time.Time {
+		defer func() { i++ }()
+		return times[i]
+	}))
+
+	want := []time.Time{
+		now,
+		now.Add(time.Nanosecond),
+		now.Add(2 * time.Nanosecond),
+		now.Add(time.Second),
+	}
+
+	for _, w := range want {
+		if got := clock.Now(); !got.Equal(w) {
+			t.Fatalf("Now() should return %v; got %v", w, got)
+		}
+	}
+}
@@ -13,9 +13,10 @@ type chanbus struct {
 	sync.RWMutex
 
 	artificialDelay time.Duration
+	atomic          bool
 
 	events map[string]*eventSubscription
-	queue  chan event.Event
+	queue  chan []event.Event
 	done   chan struct{}
 }
 
@@ -59,6 +60,17 @@ func WithArtificialDelay(delay time.Duration) Option {
 	}
 }
 
+// AtomicPublish returns an Option that publishes the events of a single call to
+// Publish atomically: either all events are published, or none of them if the
+// context is canceled before the events could be published. The events of an
+// atomic publish are delivered to the subscribers in order, without events of
+// other publishers in between.
+func AtomicPublish() Option {
+	return func(c *chanbus) {
+		c.atomic = true
+	}
+}
+
 // New creates a new instance of an event bus with the provided options. The
 // returned event bus is safe for concurrent use and starts processing events
 // immediately. The artificial delay parameter can be set to simulate network
@@ -67,7 +79,7 @@ func New(opts ...Option) event.Bus {
 	bus := &chanbus{
 		artificialDelay: time.Millisecond,
 		events:          make(map[string]*eventSubscription),
-		queue:           make(chan event.Event),
+		queue:           make(chan []event.Event),
 		done:            make(chan struct{}),
 	}
 	for _, opt := range opts {
@@ -121,8 +133,13 @@ func (bus *chanbus) Subscribe(ctx context.Context, events ...string) (<-chan eve
 // is dispatched to all subscribed recipients. If an artificial delay is set, it
 // pauses for that duration before dispatching the next event. The function
 // returns an error if the context gets cancelled before all events are
-// published.
+// published. If the bus was created with the AtomicPublish option, the events
+// are published atomically.
 func (bus *chanbus) Publish(ctx context.Context, events ...event.Event) error {
+	if bus.atomic {
+		return bus.publishAtomic(ctx, events)
+	}
+
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
@@ -130,7 +147,7 @@ func (bus *chanbus) Publish(ctx context.Context, events ...event.Event) error {
 			select {
 			case <-ctx.Done():
 				return
-			case bus.queue <- evt:
+			case bus.queue <- []event.Event{evt}:
 				if bus.artificialDelay > 0 {
 					time.Sleep(bus.artificialDelay)
 				}
@@ -145,6 +162,18 @@ func (bus *chanbus) Publish(ctx context.Context, events ...event.Event) error {
 	}
 }
 
+func (bus *chanbus) publishAtomic(ctx context.Context, events []event.Event) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case bus.queue <- events:
+		if bus.artificialDelay > 0 {
+			time.Sleep(bus.artificialDelay)
+		}
+		return nil
+	}
+}
+
 func (bus *chanbus) subscribe(ctx context.Context, name string) (recipient, error) {
 	bus.Lock()
 	defer bus.Unlock()
@@ -177,8 +206,10 @@ func (bus *chanbus) subscribe(ctx context.Context, name string) (recipient, erro
 }
 
 func (bus *chanbus) work() {
-	for evt := range bus.queue {
-		bus.publish(evt)
+	for events := range bus.queue {
+		for _, evt := range events {
+			bus.publish(evt)
+		}
 	}
 }
 
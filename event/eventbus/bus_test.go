@@ -14,6 +14,14 @@ func TestChanbus(t *testing.T) {
 	eventbustest.RunWildcard(t, newBus)
 }
 
+func TestChanbus_AtomicPublish(t *testing.T) {
+	newBus := func(codec.Encoding) event.Bus {
+		return eventbus.New(eventbus.AtomicPublish())
+	}
+	eventbustest.RunCore(t, newBus)
+	eventbustest.RunAtomic(t, newBus)
+}
+
 func newBus(codec.Encoding) event.Bus {
 	return eventbus.New()
 }
@@ -0,0 +1,145 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+)
+
+// ErrDeprecatedEvent is returned by Deprecations.Publish if a deprecated event
+// is published and the RejectDeprecated option is used.
+var ErrDeprecatedEvent = errors.New("deprecated event")
+
+// Deprecations is an event bus decorator that enforces the deprecations of an
+// event registry (see codec.Deprecate), giving teams a managed path for
+// retiring events:
+//
+//	reg := codec.New(codec.Deprecate("shop.order.created", "shop.order.placed"))
+//	bus := eventbus.NewDeprecations(bus, reg, eventbus.MapSubscriptions())
+//
+// By default, publishing a deprecated event logs a warning and publishes the
+// event. Use the RejectDeprecated option to reject deprecated events instead,
+// and the OnDeprecated option to report deprecated events in another way.
+type Deprecations struct {
+	event.Bus
+
+	registry     *codec.Registry
+	reject       bool
+	mapSubs      bool
+	onDeprecated []func(event.Event, codec.Deprecation)
+}
+
+// DeprecationOption is an option for Deprecations.
+type DeprecationOption func(*Deprecations)
+
+// RejectDeprecated returns a DeprecationOption that makes Publish fail with an
+// error that unwraps to ErrDeprecatedEvent if any of the published events is
+// deprecated. None of the events are published in that case.
+func RejectDeprecated() DeprecationOption {
+	return func(d *Deprecations) {
+		d.reject = true
+	}
+}
+
+// OnDeprecated returns a DeprecationOption that calls fn for every deprecated
+// event that is published. When OnDeprecated is used, no warnings are logged.
+func OnDeprecated(fn func(event.Event, codec.Deprecation)) DeprecationOption {
+	return func(d *Deprecations) {
+		d.onDeprecated = append(d.onDeprecated, fn)
+	}
+}
+
+// MapSubscriptions returns a DeprecationOption that maps subscriptions to
+// deprecated events to their replacements. A subscription to a deprecated event
+// also receives the events that replace it, including the replacements of
+// deprecated replacements, so that consumers keep receiving events while the publishers migrate to the
+// replacement. Events are received with their published name, so consumers must
+// be able to handle the replacement events.
+func MapSubscriptions() DeprecationOption {
+	return func(d *Deprecations) {
+		d.mapSubs = true
+	}
+}
+
+// NewDeprecations returns a Deprecations that enforces the deprecations of the
+// given registry on the given bus.
+func NewDeprecations(bus event.Bus, reg *codec.Registry, opts ...DeprecationOption) *Deprecations {
+	d := &Deprecations{Bus: bus, registry: reg}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Publish publishes the given events over the underlying bus. Deprecated events
+// are reported, or rejected if the RejectDeprecated option is used.
+func (d *Deprecations) Publish(ctx context.Context, events ...event.Event) error {
+	for _, evt := range events {
+		dep, ok := d.registry.Deprecated(evt.Name())
+		if !ok {
+			continue
+		}
+
+		if d.reject {
+			return fmt.Errorf("%w %q [replacement=%q]", ErrDeprecatedEvent, dep.Name, dep.Replacement)
+		}
+
+		d.report(evt, dep)
+	}
+
+	return d.Bus.Publish(ctx, events...)
+}
+
+// Subscribe subscribes to the given events over the underlying bus. If the
+// MapSubscriptions option is used, the replacements of deprecated events are
+// subscribed to as well.
+func (d *Deprecations) Subscribe(ctx context.Context, names ...string) (<-chan event.Event, <-chan error, error) {
+	if !d.mapSubs {
+		return d.Bus.Subscribe(ctx, names...)
+	}
+
+	mapped := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			mapped = append(mapped, name)
+		}
+	}
+
+	for _, name := range names {
+		add(name)
+
+		// follow the chain of replacements, so that events which replace
+		// deprecated replacements are received as well
+		for current := name; ; {
+			dep, ok := d.registry.Deprecated(current)
+			if !ok || dep.Replacement == "" || seen[dep.Replacement] {
+				break
+			}
+			add(dep.Replacement)
+			current = dep.Replacement
+		}
+	}
+
+	return d.Bus.Subscribe(ctx, mapped...)
+}
+
+func (d *Deprecations) report(evt event.Event, dep codec.Deprecation) {
+	if len(d.onDeprecated) == 0 {
+		if dep.Replacement == "" {
+			log.Printf("[goes/event/eventbus.Deprecations] published deprecated event %q", dep.Name)
+		} else {
+			log.Printf("[goes/event/eventbus.Deprecations] published deprecated event %q; use %q instead", dep.Name, dep.Replacement)
+		}
+		return
+	}
+
+	for _, fn := range d.onDeprecated {
+		fn(evt, dep)
+	}
+}
@@ -0,0 +1,98 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/test"
+)
+
+func TestDeprecations_Publish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reg := codec.New(codec.Deprecate("foo", "bar"))
+
+	var reported []codec.Deprecation
+	bus := eventbus.NewDeprecations(eventbus.New(), reg, eventbus.OnDeprecated(func(_ event.Event, d codec.Deprecation) {
+		reported = append(reported, d)
+	}))
+
+	events, _, err := bus.Subscribe(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := bus.Publish(ctx, event.New("foo", test.FooEventData{}).Any()); err != nil {
+		t.Fatalf("Publish failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("deprecated event should be published")
+	case <-events:
+	}
+
+	if len(reported) != 1 || reported[0].Replacement != "bar" {
+		t.Fatalf("deprecated event should be reported; got %v", reported)
+	}
+}
+
+func TestRejectDeprecated(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reg := codec.New(codec.Deprecate("foo", "bar"))
+	bus := eventbus.NewDeprecations(eventbus.New(), reg, eventbus.RejectDeprecated())
+
+	events, _, err := bus.Subscribe(ctx, "foo", "bar")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	err = bus.Publish(ctx, event.New("bar", test.FooEventData{}).Any(), event.New("foo", test.FooEventData{}).Any())
+	if !errors.Is(err, eventbus.ErrDeprecatedEvent) {
+		t.Fatalf("Publish should fail with %q; got %v", eventbus.ErrDeprecatedEvent, err)
+	}
+
+	select {
+	case evt := <-events:
+		t.Fatalf("no events should be published; got %q", evt.Name())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMapSubscriptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reg := codec.New(codec.Deprecate("foo", "bar"), codec.Deprecate("bar", "baz"))
+	bus := eventbus.NewDeprecations(eventbus.New(), reg, eventbus.MapSubscriptions())
+
+	events, _, err := bus.Subscribe(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	publisher := eventbus.NewDeprecations(bus.Bus, reg, eventbus.OnDeprecated(func(event.Event, codec.Deprecation) {}))
+	for _, name := range []string{"foo", "bar", "baz"} {
+		if err := publisher.Publish(ctx, event.New(name, test.FooEventData{}).Any()); err != nil {
+			t.Fatalf("Publish failed with %q", err)
+		}
+	}
+
+	received := make(map[string]bool)
+	for len(received) < 3 {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("foo, bar and baz events should be received; got %v", received)
+		case evt := <-events:
+			received[evt.Name()] = true
+		}
+	}
+}
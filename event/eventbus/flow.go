@@ -0,0 +1,225 @@
+package eventbus
+
+import (
+	"context"
+	"slices"
+	"sync"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+)
+
+// Acknowledger is implemented by event buses that need to know when a
+// subscriber has finished processing an event. The *handler.Handler of the
+// event/handler package acknowledges events after calling their handler.
+type Acknowledger interface {
+	// Ack acknowledges that the event has been processed.
+	Ack(event.Event)
+}
+
+// FlowControl is an event bus decorator that limits the rate at which events
+// are delivered to each subscription, and the number of events that a
+// subscription processes concurrently. It protects fragile downstream services
+// that are fed by event handlers:
+//
+//	var bus event.Bus
+//	flow := eventbus.NewFlowControl(bus,
+//		eventbus.MaxInFlight(10),
+//		eventbus.MaxRate(50, 10),
+//	)
+//	h := handler.New(flow, handler.Workers(10))
+//
+// Limits apply to each subscription individually. A subscription that reached
+// a limit stops receiving from the underlying subscription, which pushes back
+// to the underlying bus. The JetStream driver of the NATS backend passes the
+// pushback to the JetStream server, which stops delivering messages to the
+// consumer when its MaxAckPending limit is reached.
+type FlowControl struct {
+	event.Bus
+
+	maxInFlight int
+	rate        float64
+	burst       int
+
+	mux      sync.Mutex
+	inFlight map[uuid.UUID][]*flowSubscription
+}
+
+// FlowOption is an option for a FlowControl.
+type FlowOption func(*FlowControl)
+
+// MaxInFlight returns a FlowOption that limits the number of events that a
+// subscription delivers without them being acknowledged using Ack. If a
+// subscriber never acknowledges events, its subscription stalls after n events.
+// Zero means no limit.
+func MaxInFlight(n int) FlowOption {
+	return func(f *FlowControl) {
+		f.maxInFlight = n
+	}
+}
+
+// MaxRate returns a FlowOption that limits the number of events per second that
+// a subscription delivers. Up to burst events are delivered at once after a
+// period of inactivity. Zero means no limit.
+func MaxRate(perSecond float64, burst int) FlowOption {
+	return func(f *FlowControl) {
+		f.rate = perSecond
+		f.burst = burst
+	}
+}
+
+// NewFlowControl returns a FlowControl that limits the subscriptions to the
+// given bus.
+func NewFlowControl(bus event.Bus, opts ...FlowOption) *FlowControl {
+	f := &FlowControl{
+		Bus:      bus,
+		inFlight: make(map[uuid.UUID][]*flowSubscription),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.burst < 1 {
+		f.burst = 1
+	}
+	return f
+}
+
+// Subscribe subscribes to the given events and delivers them within the limits
+// of the FlowControl.
+func (f *FlowControl) Subscribe(ctx context.Context, names ...string) (<-chan event.Event, <-chan error, error) {
+	events, errs, err := f.Bus.Subscribe(ctx, names...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub := &flowSubscription{
+		flow:   f,
+		tokens: float64(f.burst),
+		last:   stdtime.Now(),
+		slots:  make(chan struct{}, max(f.maxInFlight, 1)),
+	}
+
+	out := make(chan event.Event)
+	go sub.work(ctx, events, out)
+
+	return out, errs, nil
+}
+
+// Ack acknowledges that an event has been processed, which frees its slot in
+// the MaxInFlight limit of the subscription that delivered it. If multiple
+// subscriptions delivered the event, each call to Ack frees the slot of one of
+// them.
+func (f *FlowControl) Ack(evt event.Event) {
+	f.mux.Lock()
+	subs := f.inFlight[evt.ID()]
+	if len(subs) == 0 {
+		f.mux.Unlock()
+		return
+	}
+	sub := subs[0]
+	if len(subs) == 1 {
+		delete(f.inFlight, evt.ID())
+	} else {
+		f.inFlight[evt.ID()] = subs[1:]
+	}
+	f.mux.Unlock()
+
+	select {
+	case <-sub.slots:
+	default:
+	}
+}
+
+// InFlight returns the number of delivered events that have not been
+// acknowledged yet.
+func (f *FlowControl) InFlight() int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	var n int
+	for _, subs := range f.inFlight {
+		n += len(subs)
+	}
+	return n
+}
+
+// forget removes the in-flight events of a subscription that has been closed.
+func (f *FlowControl) forget(sub *flowSubscription) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	for id, subs := range f.inFlight {
+		subs = slices.DeleteFunc(subs, func(s *flowSubscription) bool { return s == sub })
+		if len(subs) == 0 {
+			delete(f.inFlight, id)
+		} else {
+			f.inFlight[id] = subs
+		}
+	}
+}
+
+type flowSubscription struct {
+	flow   *FlowControl
+	tokens float64
+	last   stdtime.Time
+	slots  chan struct{}
+}
+
+func (sub *flowSubscription) work(ctx context.Context, events <-chan event.Event, out chan<- event.Event) {
+	defer close(out)
+	defer sub.flow.forget(sub)
+
+	for evt := range events {
+		if !sub.wait(ctx) {
+			return
+		}
+
+		if sub.flow.maxInFlight > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case sub.slots <- struct{}{}:
+			}
+			sub.flow.mux.Lock()
+			sub.flow.inFlight[evt.ID()] = append(sub.flow.inFlight[evt.ID()], sub)
+			sub.flow.mux.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case out <- evt:
+		}
+	}
+}
+
+// wait waits until the rate limit allows to deliver the next event.
+func (sub *flowSubscription) wait(ctx context.Context) bool {
+	if sub.flow.rate <= 0 {
+		return true
+	}
+
+	now := stdtime.Now()
+	sub.tokens += now.Sub(sub.last).Seconds() * sub.flow.rate
+	sub.last = now
+	if burst := float64(sub.flow.burst); sub.tokens > burst {
+		sub.tokens = burst
+	}
+
+	if sub.tokens >= 1 {
+		sub.tokens--
+		return true
+	}
+
+	delay := stdtime.Duration((1 - sub.tokens) / sub.flow.rate * float64(stdtime.Second))
+	timer := stdtime.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		sub.last = stdtime.Now()
+		sub.tokens = 0
+		return true
+	}
+}
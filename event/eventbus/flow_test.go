@@ -0,0 +1,83 @@
+package eventbus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+)
+
+func TestFlowControl_MaxInFlight(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := eventbus.NewFlowControl(eventbus.New(), eventbus.MaxInFlight(2))
+
+	events, _, err := flow.Subscribe(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			flow.Publish(ctx, event.New("foo", i).Any())
+		}
+	}()
+
+	first := receiveEvent(t, events)
+	receiveEvent(t, events)
+
+	select {
+	case evt := <-events:
+		t.Fatalf("third event should not be delivered before an event is acknowledged; got %v", evt.Data())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if n := flow.InFlight(); n != 2 {
+		t.Fatalf("InFlight should return %d; got %d", 2, n)
+	}
+
+	flow.Ack(first)
+
+	receiveEvent(t, events)
+}
+
+func TestFlowControl_MaxRate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	flow := eventbus.NewFlowControl(eventbus.New(), eventbus.MaxRate(20, 1))
+
+	events, _, err := flow.Subscribe(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	go func() {
+		for i := 0; i < 4; i++ {
+			flow.Publish(ctx, event.New("foo", i).Any())
+		}
+	}()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		receiveEvent(t, events)
+	}
+
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Fatalf("4 events at 20 events/s should take at least %s; took %s", 150*time.Millisecond, elapsed)
+	}
+}
+
+func receiveEvent(t *testing.T, events <-chan event.Event) event.Event {
+	t.Helper()
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("no event received")
+		return nil
+	case evt := <-events:
+		return evt
+	}
+}
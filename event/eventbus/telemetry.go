@@ -0,0 +1,312 @@
+package eventbus
+
+import (
+	"context"
+	"sort"
+	"sync"
+	stdtime "time"
+
+	"github.com/modernice/goes/event"
+)
+
+// VersionedPayload is implemented by event data that has a schema version.
+type VersionedPayload interface {
+	PayloadVersion() int
+}
+
+// Telemetry is an event bus decorator that records which events are actually
+// published and consumed by a service, and with which payload versions. The
+// usage is recorded into a TelemetryRegistry, whose report can be used to
+// find dead events and consumers that have not been migrated to the latest
+// payload version of an event:
+//
+//	reg := eventbus.NewTelemetryRegistry()
+//	bus := eventbus.NewTelemetry(bus, reg, "billing")
+//
+//	// later
+//	report := reg.Report()
+//	log.Println(report.Dead())
+//
+// By default, the payload version of an event is returned by the
+// PayloadVersion method of its data if it implements VersionedPayload, or 0
+// otherwise.
+type Telemetry struct {
+	event.Bus
+
+	service   string
+	registry  *TelemetryRegistry
+	version   func(event.Event) int
+	onPublish []func(string, event.Event)
+	onReceive []func(string, event.Event)
+}
+
+// TelemetryOption is an option for Telemetry.
+type TelemetryOption func(*Telemetry)
+
+// PayloadVersion returns a TelemetryOption that determines the payload version
+// of events using the given function.
+func PayloadVersion(fn func(event.Event) int) TelemetryOption {
+	return func(t *Telemetry) {
+		t.version = fn
+	}
+}
+
+// OnPublish returns a TelemetryOption that calls fn with the service name for
+// every event that is published over the bus.
+func OnPublish(fn func(service string, evt event.Event)) TelemetryOption {
+	return func(t *Telemetry) {
+		t.onPublish = append(t.onPublish, fn)
+	}
+}
+
+// OnReceive returns a TelemetryOption that calls fn with the service name for
+// every event that is received from a subscription.
+func OnReceive(fn func(service string, evt event.Event)) TelemetryOption {
+	return func(t *Telemetry) {
+		t.onReceive = append(t.onReceive, fn)
+	}
+}
+
+// NewTelemetry returns a Telemetry that records the events that the given
+// service publishes and consumes over the given bus into the given registry.
+func NewTelemetry(bus event.Bus, registry *TelemetryRegistry, service string, opts ...TelemetryOption) *Telemetry {
+	t := &Telemetry{
+		Bus:      bus,
+		service:  service,
+		registry: registry,
+		version:  payloadVersion,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Publish records the published events and publishes them over the
+// underlying bus.
+func (t *Telemetry) Publish(ctx context.Context, events ...event.Event) error {
+	if err := t.Bus.Publish(ctx, events...); err != nil {
+		return err
+	}
+	for _, evt := range events {
+		t.registry.record(t.service, evt.Name(), t.version(evt), true)
+		for _, fn := range t.onPublish {
+			fn(t.service, evt)
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes to the given events over the underlying bus and records
+// the received events.
+func (t *Telemetry) Subscribe(ctx context.Context, names ...string) (<-chan event.Event, <-chan error, error) {
+	events, errs, err := t.Bus.Subscribe(ctx, names...)
+	if err != nil {
+		return events, errs, err
+	}
+
+	out := make(chan event.Event)
+
+	go func() {
+		defer close(out)
+		for evt := range events {
+			t.registry.record(t.service, evt.Name(), t.version(evt), false)
+			for _, fn := range t.onReceive {
+				fn(t.service, evt)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- evt:
+			}
+		}
+	}()
+
+	return out, errs, nil
+}
+
+func payloadVersion(evt event.Event) int {
+	if v, ok := evt.Data().(VersionedPayload); ok {
+		return v.PayloadVersion()
+	}
+	return 0
+}
+
+// TelemetryRegistry aggregates the event usage that is recorded by Telemetry
+// buses. A single registry can be shared by the buses of multiple services
+// within the same process. Reports of registries in different processes can be
+// combined using MergeReports.
+type TelemetryRegistry struct {
+	mux   sync.Mutex
+	usage map[usageKey]*Usage
+}
+
+type usageKey struct {
+	service string
+	name    string
+	version int
+}
+
+// Usage is the recorded usage of an event with a given payload version by a
+// service.
+type Usage struct {
+	Service string
+	Event   string
+	Version int
+
+	// Published is the number of times the service published the event.
+	Published int64
+
+	// Received is the number of times the service received the event.
+	Received int64
+
+	// LastPublished is the time the service last published the event.
+	LastPublished stdtime.Time
+
+	// LastReceived is the time the service last received the event.
+	LastReceived stdtime.Time
+}
+
+// TelemetryReport is a report of the recorded event usage.
+type TelemetryReport struct {
+	// Usage is the recorded usage, sorted by event name, version and service.
+	Usage []Usage
+}
+
+// NewTelemetryRegistry returns an empty TelemetryRegistry.
+func NewTelemetryRegistry() *TelemetryRegistry {
+	return &TelemetryRegistry{usage: make(map[usageKey]*Usage)}
+}
+
+func (r *TelemetryRegistry) record(service, name string, version int, published bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	key := usageKey{service, name, version}
+	u, ok := r.usage[key]
+	if !ok {
+		u = &Usage{Service: service, Event: name, Version: version}
+		r.usage[key] = u
+	}
+
+	now := stdtime.Now()
+	if published {
+		u.Published++
+		u.LastPublished = now
+	} else {
+		u.Received++
+		u.LastReceived = now
+	}
+}
+
+// Report returns a report of the recorded event usage.
+func (r *TelemetryRegistry) Report() TelemetryReport {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	usage := make([]Usage, 0, len(r.usage))
+	for _, u := range r.usage {
+		usage = append(usage, *u)
+	}
+
+	return TelemetryReport{Usage: sortUsage(usage)}
+}
+
+// MergeReports merges the reports of multiple registries, e.g. of different
+// services, into a single report.
+func MergeReports(reports ...TelemetryReport) TelemetryReport {
+	merged := make(map[usageKey]*Usage)
+	for _, report := range reports {
+		for _, u := range report.Usage {
+			key := usageKey{u.Service, u.Event, u.Version}
+			m, ok := merged[key]
+			if !ok {
+				m = &Usage{Service: u.Service, Event: u.Event, Version: u.Version}
+				merged[key] = m
+			}
+			m.Published += u.Published
+			m.Received += u.Received
+			if u.LastPublished.After(m.LastPublished) {
+				m.LastPublished = u.LastPublished
+			}
+			if u.LastReceived.After(m.LastReceived) {
+				m.LastReceived = u.LastReceived
+			}
+		}
+	}
+
+	usage := make([]Usage, 0, len(merged))
+	for _, u := range merged {
+		usage = append(usage, *u)
+	}
+
+	return TelemetryReport{Usage: sortUsage(usage)}
+}
+
+// Dead returns the names of the events that are published but not received by
+// any service, sorted by name.
+func (r TelemetryReport) Dead() []string {
+	published := make(map[string]bool)
+	received := make(map[string]bool)
+	for _, u := range r.Usage {
+		if u.Published > 0 {
+			published[u.Event] = true
+		}
+		if u.Received > 0 {
+			received[u.Event] = true
+		}
+	}
+
+	var dead []string
+	for name := range published {
+		if !received[name] {
+			dead = append(dead, name)
+		}
+	}
+	sort.Strings(dead)
+
+	return dead
+}
+
+// Outdated returns the usage of services that receive an event with a payload
+// version that is older than the latest published version of the event. These
+// are the consumers that still depend on an old payload version, so it cannot
+// be removed yet.
+func (r TelemetryReport) Outdated() []Usage {
+	latest := r.LatestVersions()
+
+	var outdated []Usage
+	for _, u := range r.Usage {
+		if u.Received > 0 && u.Version < latest[u.Event] {
+			outdated = append(outdated, u)
+		}
+	}
+
+	return outdated
+}
+
+// LatestVersions returns the latest published payload version of each event.
+func (r TelemetryReport) LatestVersions() map[string]int {
+	latest := make(map[string]int)
+	for _, u := range r.Usage {
+		if v, ok := latest[u.Event]; u.Published > 0 && (!ok || u.Version > v) {
+			latest[u.Event] = u.Version
+		}
+	}
+	return latest
+}
+
+func sortUsage(usage []Usage) []Usage {
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Event != b.Event {
+			return a.Event < b.Event
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Service < b.Service
+	})
+	return usage
+}
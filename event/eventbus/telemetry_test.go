@@ -0,0 +1,88 @@
+package eventbus_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+)
+
+type versionedData struct {
+	V int
+}
+
+func (d versionedData) PayloadVersion() int { return d.V }
+
+func TestTelemetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	reg := eventbus.NewTelemetryRegistry()
+
+	var published []string
+	orders := eventbus.NewTelemetry(bus, reg, "orders", eventbus.OnPublish(func(service string, evt event.Event) {
+		published = append(published, service+":"+evt.Name())
+	}))
+	billing := eventbus.NewTelemetry(bus, reg, "billing")
+
+	events, errs, err := billing.Subscribe(ctx, "order.placed")
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	received := make(chan event.Event)
+	go func() {
+		for evt := range events {
+			received <- evt
+		}
+	}()
+	go func() {
+		for err := range errs {
+			t.Errorf("subscription: %v", err)
+		}
+	}()
+
+	for _, evt := range []event.Event{
+		event.New("order.placed", versionedData{V: 1}).Any(),
+		event.New("order.placed", versionedData{V: 2}).Any(),
+		event.New("order.shipped", versionedData{V: 1}).Any(),
+	} {
+		if err := orders.Publish(ctx, evt); err != nil {
+			t.Fatalf("Publish failed with %q", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("event #%d was not received", i)
+		case <-received:
+		}
+	}
+
+	if want := []string{"orders:order.placed", "orders:order.placed", "orders:order.shipped"}; !slices.Equal(want, published) {
+		t.Fatalf("OnPublish hook should be called for %v; got %v", want, published)
+	}
+
+	report := reg.Report()
+
+	if want := []string{"order.shipped"}; !slices.Equal(want, report.Dead()) {
+		t.Fatalf("Dead should return %v; got %v", want, report.Dead())
+	}
+
+	outdated := report.Outdated()
+	if len(outdated) != 1 || outdated[0].Service != "billing" || outdated[0].Version != 1 {
+		t.Fatalf("billing should receive an outdated version of %q; got %+v", "order.placed", outdated)
+	}
+
+	merged := eventbus.MergeReports(report, report)
+	for i, u := range merged.Usage {
+		if u.Published != 2*report.Usage[i].Published || u.Received != 2*report.Usage[i].Received {
+			t.Fatalf("merged usage should be the sum of the usages; got %+v", u)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/time"
+)
+
+const (
+	// DefaultResubscribeInterval is the default interval after which a
+	// Watchdog retries a failed resubscription.
+	DefaultResubscribeInterval = stdtime.Second
+
+	// recentEvents is the number of recently delivered event ids that a
+	// watched subscription remembers to skip duplicates after resubscribing.
+	recentEvents = 1000
+)
+
+var (
+	// ErrSubscriptionClosed is passed to the OnResubscribe hook of a Watchdog
+	// if a subscription has been resubscribed because its event channel was
+	// closed by the underlying bus.
+	ErrSubscriptionClosed = errors.New("subscription closed")
+
+	// ErrSubscriptionStalled is passed to the OnResubscribe hook of a Watchdog
+	// if a subscription has been resubscribed because it did not deliver any
+	// events within the configured stall timeout.
+	ErrSubscriptionStalled = errors.New("subscription stalled")
+)
+
+// Watchdog is an event bus decorator that watches the liveness of
+// subscriptions. When a subscription stops delivering events, the Watchdog
+// automatically resubscribes to the underlying bus and fills the gap with the
+// events from the event store that were published since the last delivered
+// event.
+//
+// A subscription is considered dead if its event channel is closed although
+// the subscription's context is not canceled, or if the StallTimeout option is
+// used and no events were received within the timeout.
+//
+//	var bus event.Bus
+//	var store event.Store
+//	wd := eventbus.NewWatchdog(bus, store, eventbus.StallTimeout(5*time.Minute))
+//	events, errs, err := wd.Subscribe(context.TODO(), "foo", "bar")
+//
+// Events that are delivered through the gap-filling may be delivered out of
+// order relative to events that are published during the resubscription.
+// Recently delivered events are not delivered again. If the gap cannot be
+// filled (e.g. because the event store is unavailable), the error is reported
+// and the Watchdog retries to fill the gap in the configured resubscribe
+// interval until it succeeds.
+type Watchdog struct {
+	event.Bus
+
+	store               event.Store
+	stallTimeout        stdtime.Duration
+	resubscribeInterval stdtime.Duration
+	onResubscribe       func(error)
+}
+
+// WatchdogOption is an option for a Watchdog.
+type WatchdogOption func(*Watchdog)
+
+// StallTimeout returns a WatchdogOption that makes the Watchdog resubscribe if
+// a subscription did not deliver any events for the given duration. Choose a
+// timeout that is longer than the expected silence between events, because
+// every timeout causes a resubscription and an event store query. By default,
+// only closed subscriptions are detected.
+func StallTimeout(d stdtime.Duration) WatchdogOption {
+	return func(w *Watchdog) {
+		w.stallTimeout = d
+	}
+}
+
+// ResubscribeInterval returns a WatchdogOption that configures the interval
+// after which a failed resubscription or gap-filling is retried. Defaults to
+// DefaultResubscribeInterval.
+func ResubscribeInterval(d stdtime.Duration) WatchdogOption {
+	return func(w *Watchdog) {
+		w.resubscribeInterval = d
+	}
+}
+
+// OnResubscribe returns a WatchdogOption that registers a hook that is called
+// whenever a subscription is resubscribed, with either ErrSubscriptionClosed or
+// ErrSubscriptionStalled as the reason.
+func OnResubscribe(fn func(reason error)) WatchdogOption {
+	return func(w *Watchdog) {
+		w.onResubscribe = fn
+	}
+}
+
+// NewWatchdog returns a Watchdog that watches the subscriptions to the given
+// bus and fills gaps using the given event store.
+func NewWatchdog(bus event.Bus, store event.Store, opts ...WatchdogOption) *Watchdog {
+	w := &Watchdog{
+		Bus:                 bus,
+		store:               store,
+		resubscribeInterval: DefaultResubscribeInterval,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Subscribe subscribes to the given events and watches the subscription until
+// ctx is canceled. Errors of the underlying subscriptions and errors that occur
+// while resubscribing are sent into the returned error channel.
+func (w *Watchdog) Subscribe(ctx context.Context, names ...string) (<-chan event.Event, <-chan error, error) {
+	subCtx, cancel := context.WithCancel(ctx)
+	events, errs, err := w.Bus.Subscribe(subCtx, names...)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	sub := &watchedSubscription{
+		watchdog: w,
+		names:    names,
+		out:      make(chan event.Event),
+		outErrs:  make(chan error),
+		since:    stdtime.Now(),
+		recent:   make(map[uuid.UUID]struct{}),
+	}
+
+	go sub.work(ctx, cancel, events, errs)
+
+	return sub.out, sub.outErrs, nil
+}
+
+type watchedSubscription struct {
+	watchdog *Watchdog
+	names    []string
+	out      chan event.Event
+	outErrs  chan error
+
+	// since is the time of the latest delivered event, or the time of the
+	// subscription if no event has been delivered yet.
+	since stdtime.Time
+
+	// gap is the time from which missed events must be queried from the
+	// event store. gap is only valid if hasGap is true. The gap is kept until
+	// it has been filled successfully.
+	gap    stdtime.Time
+	hasGap bool
+
+	recent      map[uuid.UUID]struct{}
+	recentOrder []uuid.UUID
+}
+
+func (sub *watchedSubscription) work(ctx context.Context, cancel context.CancelFunc, events <-chan event.Event, errs <-chan error) {
+	defer close(sub.outErrs)
+	defer close(sub.out)
+	defer func() { cancel() }()
+
+	var stall *stdtime.Timer
+	var stalled <-chan stdtime.Time
+	if sub.watchdog.stallTimeout > 0 {
+		stall = stdtime.NewTimer(sub.watchdog.stallTimeout)
+		defer stall.Stop()
+		stalled = stall.C
+	}
+
+	var retryFill <-chan stdtime.Time
+
+	for {
+		var reason error
+
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if !sub.fail(ctx, err) {
+				return
+			}
+			continue
+		case evt, ok := <-events:
+			if !ok {
+				reason = ErrSubscriptionClosed
+				break
+			}
+			if stall != nil {
+				stall.Reset(sub.watchdog.stallTimeout)
+			}
+			if !sub.deliver(ctx, evt) {
+				return
+			}
+			continue
+		case <-stalled:
+			reason = ErrSubscriptionStalled
+		case <-retryFill:
+			retryFill = nil
+			if err := sub.fillGap(ctx); err != nil {
+				if !sub.fail(ctx, err) {
+					return
+				}
+				retryFill = stdtime.After(sub.watchdog.resubscribeInterval)
+			}
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		cancel()
+
+		var ok bool
+		if events, errs, cancel, ok = sub.resubscribe(ctx, reason); !ok {
+			return
+		}
+
+		retryFill = nil
+		if sub.hasGap {
+			retryFill = stdtime.After(sub.watchdog.resubscribeInterval)
+		}
+
+		if stall != nil {
+			stall.Reset(sub.watchdog.stallTimeout)
+		}
+	}
+}
+
+// resubscribe resubscribes to the underlying bus, retrying until it succeeds
+// or ctx is canceled, and then fills the gap from the event store. If the gap
+// cannot be filled, the gap is kept so that the caller can retry.
+func (sub *watchedSubscription) resubscribe(ctx context.Context, reason error) (<-chan event.Event, <-chan error, context.CancelFunc, bool) {
+	if sub.watchdog.onResubscribe != nil {
+		sub.watchdog.onResubscribe(reason)
+	}
+
+	// A gap that has not been filled yet starts before the current one.
+	if !sub.hasGap {
+		sub.gap = sub.since
+		sub.hasGap = true
+	}
+
+	for {
+		subCtx, cancel := context.WithCancel(ctx)
+		events, errs, err := sub.watchdog.Bus.Subscribe(subCtx, sub.names...)
+		if err == nil {
+			if err := sub.fillGap(ctx); err != nil && !sub.fail(ctx, err) {
+				cancel()
+				return nil, nil, cancel, false
+			}
+			return events, errs, cancel, true
+		}
+		cancel()
+
+		if !sub.fail(ctx, fmt.Errorf("resubscribe to %v events: %w", sub.names, err)) {
+			return nil, nil, cancel, false
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, cancel, false
+		case <-stdtime.After(sub.watchdog.resubscribeInterval):
+		}
+	}
+}
+
+// fillGap delivers the events from the event store that were published since
+// the start of the gap. The start of the gap advances with each delivered
+// event, and the gap is removed after all events have been delivered.
+func (sub *watchedSubscription) fillGap(ctx context.Context) error {
+	str, errs, err := sub.watchdog.store.Query(ctx, query.New(
+		query.Name(sub.names...),
+		query.Time(time.Min(sub.gap)),
+		query.SortByTime(),
+	))
+	if err != nil {
+		return fmt.Errorf("query missed events: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			return fmt.Errorf("query missed events: %w", err)
+		case evt, ok := <-str:
+			if !ok {
+				sub.hasGap = false
+				return nil
+			}
+			if !sub.deliver(ctx, evt) {
+				return ctx.Err()
+			}
+			sub.gap = evt.Time()
+		}
+	}
+}
+
+func (sub *watchedSubscription) deliver(ctx context.Context, evt event.Event) bool {
+	if _, ok := sub.recent[evt.ID()]; ok {
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case sub.out <- evt:
+	}
+
+	sub.recent[evt.ID()] = struct{}{}
+	sub.recentOrder = append(sub.recentOrder, evt.ID())
+	if len(sub.recentOrder) > recentEvents {
+		delete(sub.recent, sub.recentOrder[0])
+		sub.recentOrder = sub.recentOrder[1:]
+	}
+
+	if evt.Time().After(sub.since) {
+		sub.since = evt.Time()
+	}
+
+	return true
+}
+
+func (sub *watchedSubscription) fail(ctx context.Context, err error) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case sub.outErrs <- err:
+		return true
+	}
+}
@@ -0,0 +1,192 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/backend/testing/eventbustest"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+)
+
+func TestWatchdog(t *testing.T) {
+	eventbustest.RunCore(t, func(codec.Encoding) event.Bus {
+		return eventbus.NewWatchdog(eventbus.New(), eventstore.New())
+	})
+}
+
+func TestWatchdog_Subscribe_closed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := &flakyBus{Bus: eventbus.New(), closeFirst: make(chan struct{})}
+	store := eventstore.New()
+
+	reasons := make(chan error, 1)
+	wd := eventbus.NewWatchdog(bus, store, eventbus.OnResubscribe(func(reason error) {
+		reasons <- reason
+	}))
+
+	events, errs, err := wd.Subscribe(ctx, "foo", "bar")
+	if err != nil {
+		t.Fatalf("Subscribe() failed with %q", err)
+	}
+
+	// event that is missed by the subscription
+	missed := event.New("foo", test.FooEventData{}).Any()
+	if err := store.Insert(ctx, missed); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	close(bus.closeFirst)
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("subscription should have been resubscribed")
+	case reason := <-reasons:
+		if !errors.Is(reason, eventbus.ErrSubscriptionClosed) {
+			t.Fatalf("reason should be %q; is %q", eventbus.ErrSubscriptionClosed, reason)
+		}
+	}
+
+	expectEvent(t, events, errs, missed)
+
+	published := event.New("bar", test.BarEventData{}).Any()
+	if err := bus.Publish(ctx, published); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	expectEvent(t, events, errs, published)
+}
+
+func TestWatchdog_Subscribe_fillGapFailed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := &flakyBus{Bus: eventbus.New(), closeFirst: make(chan struct{})}
+	store := &failingQueryStore{Store: eventstore.New(), failures: 1}
+
+	wd := eventbus.NewWatchdog(bus, store, eventbus.ResubscribeInterval(20*time.Millisecond))
+
+	events, errs, err := wd.Subscribe(ctx, "foo", "bar")
+	if err != nil {
+		t.Fatalf("Subscribe() failed with %q", err)
+	}
+
+	missed := event.New("foo", test.FooEventData{}).Any()
+	if err := store.Insert(ctx, missed); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	close(bus.closeFirst)
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("gap-filling should have failed")
+	case err := <-errs:
+		if !errors.Is(err, errQueryFailed) {
+			t.Fatalf("error should be %q; is %q", errQueryFailed, err)
+		}
+	}
+
+	// a live event that is delivered before the gap is filled must not cause
+	// the missed event to be skipped
+	published := event.New("bar", test.BarEventData{}, event.Time(time.Now().Add(time.Second))).Any()
+	if err := bus.Publish(ctx, published); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	expectEvent(t, events, errs, published)
+	expectEvent(t, events, errs, missed)
+}
+
+func TestStallTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reasons := make(chan error, 10)
+	wd := eventbus.NewWatchdog(eventbus.New(), eventstore.New(), eventbus.StallTimeout(50*time.Millisecond), eventbus.OnResubscribe(func(reason error) {
+		reasons <- reason
+	}))
+
+	if _, _, err := wd.Subscribe(ctx, "foo"); err != nil {
+		t.Fatalf("Subscribe() failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("subscription should have been resubscribed")
+	case reason := <-reasons:
+		if !errors.Is(reason, eventbus.ErrSubscriptionStalled) {
+			t.Fatalf("reason should be %q; is %q", eventbus.ErrSubscriptionStalled, reason)
+		}
+	}
+}
+
+func expectEvent(t *testing.T, events <-chan event.Event, errs <-chan error, want event.Event) {
+	t.Helper()
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("%q event should have been received", want.Name())
+	case err := <-errs:
+		t.Fatal(err)
+	case evt := <-events:
+		if evt.ID() != want.ID() {
+			t.Fatalf("received event should be %q (%s); is %q (%s)", want.Name(), want.ID(), evt.Name(), evt.ID())
+		}
+	}
+}
+
+var errQueryFailed = errors.New("query failed")
+
+type failingQueryStore struct {
+	event.Store
+
+	mux      sync.Mutex
+	failures int
+}
+
+func (s *failingQueryStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, nil, errQueryFailed
+	}
+	return s.Store.Query(ctx, q)
+}
+
+// flakyBus is a bus whose first subscription is closed without an error when
+// closeFirst is closed.
+type flakyBus struct {
+	event.Bus
+
+	closeFirst chan struct{}
+	once       sync.Once
+}
+
+func (bus *flakyBus) Subscribe(ctx context.Context, names ...string) (<-chan event.Event, <-chan error, error) {
+	first := false
+	bus.once.Do(func() { first = true })
+
+	if !first {
+		return bus.Bus.Subscribe(ctx, names...)
+	}
+
+	events := make(chan event.Event)
+	errs := make(chan error)
+	go func() {
+		<-bus.closeFirst
+		close(events)
+		close(errs)
+	}()
+
+	return events, errs, nil
+}
@@ -0,0 +1,91 @@
+package eventstore
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+)
+
+// AtomicInserter is an event store that can insert the events of multiple
+// aggregates atomically: either all events are inserted, or none.
+type AtomicInserter interface {
+	// InsertAtomic inserts the given streams of events atomically. Each stream
+	// contains the events of a single aggregate and is validated like the
+	// events of a single call to Insert.
+	InsertAtomic(ctx context.Context, streams ...[]event.Event) error
+}
+
+// AtomicReplacer is an event store that can atomically replace events: either
+// the events are deleted and the replacements are inserted, or the store is
+// left unchanged.
+type AtomicReplacer interface {
+	// ReplaceAtomic deletes the events in remove and inserts the events in
+	// insert atomically.
+	ReplaceAtomic(ctx context.Context, remove, insert []event.Event) error
+}
+
+// InsertAtomic inserts the given streams of events atomically. If any of the
+// events already exists in the store, no events are inserted.
+func (s *memstore) InsertAtomic(ctx context.Context, streams ...[]event.Event) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	seen := make(map[uuid.UUID]struct{})
+	for _, events := range streams {
+		for _, evt := range events {
+			if _, ok := s.idMap[evt.ID()]; ok {
+				return errDuplicateEvent
+			}
+			if _, ok := seen[evt.ID()]; ok {
+				return errDuplicateEvent
+			}
+			seen[evt.ID()] = struct{}{}
+		}
+	}
+
+	for _, events := range streams {
+		for _, evt := range events {
+			s.idMap[evt.ID()] = evt
+			s.events = append(s.events, evt)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceAtomic deletes the events in remove and inserts the events in insert
+// atomically. If any of the inserted events already exists in the store and is
+// not removed, the store is left unchanged.
+func (s *memstore) ReplaceAtomic(ctx context.Context, remove, insert []event.Event) error {
+	defer s.reslice()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	removed := make(map[uuid.UUID]struct{}, len(remove))
+	for _, evt := range remove {
+		removed[evt.ID()] = struct{}{}
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(insert))
+	for _, evt := range insert {
+		if _, ok := s.idMap[evt.ID()]; ok {
+			if _, ok := removed[evt.ID()]; !ok {
+				return errDuplicateEvent
+			}
+		}
+		if _, ok := seen[evt.ID()]; ok {
+			return errDuplicateEvent
+		}
+		seen[evt.ID()] = struct{}{}
+	}
+
+	for id := range removed {
+		delete(s.idMap, id)
+	}
+	for _, evt := range insert {
+		s.idMap[evt.ID()] = evt
+	}
+
+	return nil
+}
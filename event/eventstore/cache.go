@@ -0,0 +1,412 @@
+package eventstore
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// DefaultCacheSize is the default number of aggregates whose events are
+// cached by a CacheStore.
+const DefaultCacheSize = 1000
+
+// CacheStore is an event store decorator that keeps the most recent events of
+// frequently queried aggregates in memory. Queries that target the events of a
+// single aggregate (as made by aggregate repositories when fetching an
+// aggregate) are served from the cache if the cache contains all requested
+// versions of the aggregate. All other queries are passed through to the
+// underlying store.
+//
+// The cache is a bounded LRU cache keyed by aggregate. Events that are inserted
+// through the CacheStore are appended to the cached streams. Events that are
+// inserted into the underlying store by other processes are not visible to
+// the cache; use the CacheTTL option to limit the staleness of the cache if
+// other processes write to the same aggregates.
+type CacheStore struct {
+	event.Store
+
+	size int
+	tail int
+	ttl  time.Duration
+
+	mux     sync.Mutex
+	lru     *list.List
+	entries map[event.AggregateRef]*list.Element
+	pending map[event.AggregateRef]uint64
+	fills   uint64
+	hits    uint64
+	misses  uint64
+}
+
+// CacheOption is an option for a CacheStore.
+type CacheOption func(*CacheStore)
+
+// CacheStats contains the hit and miss counts of a CacheStore.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type cacheEntry struct {
+	ref     event.AggregateRef
+	start   int
+	events  []event.Event
+	expires time.Time
+}
+
+// CacheSize returns a CacheOption that configures the maximum number of
+// aggregates whose events are cached. Defaults to DefaultCacheSize.
+func CacheSize(size int) CacheOption {
+	return func(s *CacheStore) {
+		s.size = size
+	}
+}
+
+// CacheTail returns a CacheOption that configures the maximum number of events
+// that are cached per aggregate. Only the most recent events of an aggregate
+// are kept. A tail of 0 (the default) caches all queried events.
+//
+// A limited tail works best in combination with aggregate snapshots, because
+// repositories then only query the events after the latest snapshot.
+func CacheTail(n int) CacheOption {
+	return func(s *CacheStore) {
+		s.tail = n
+	}
+}
+
+// CacheTTL returns a CacheOption that configures the duration after which a
+// cached stream expires. A TTL of 0 (the default) means that cached streams
+// never expire.
+func CacheTTL(ttl time.Duration) CacheOption {
+	return func(s *CacheStore) {
+		s.ttl = ttl
+	}
+}
+
+// Cache returns a CacheStore that caches the events of the provided store.
+func Cache(store event.Store, opts ...CacheOption) *CacheStore {
+	s := &CacheStore{
+		Store:   store,
+		size:    DefaultCacheSize,
+		lru:     list.New(),
+		entries: make(map[event.AggregateRef]*list.Element),
+		pending: make(map[event.AggregateRef]uint64),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Insert inserts the events into the underlying store and appends them to the
+// cached streams of their aggregates.
+func (s *CacheStore) Insert(ctx context.Context, events ...event.Event) error {
+	if err := s.Store.Insert(ctx, events...); err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, evt := range events {
+		id, name, v := evt.Aggregate()
+		if name == "" {
+			continue
+		}
+		ref := event.AggregateRef{Name: name, ID: id}
+		delete(s.pending, ref)
+
+		elem, ok := s.entries[ref]
+		if !ok {
+			continue
+		}
+		entry := elem.Value.(*cacheEntry)
+
+		if !entry.appendable(v) {
+			s.remove(elem)
+			continue
+		}
+
+		entry.events = append(entry.events, evt)
+		s.trim(entry)
+	}
+
+	return nil
+}
+
+// Delete deletes the events from the underlying store and removes the streams
+// of their aggregates from the cache.
+func (s *CacheStore) Delete(ctx context.Context, events ...event.Event) error {
+	err := s.Store.Delete(ctx, events...)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, evt := range events {
+		id, name, _ := evt.Aggregate()
+		ref := event.AggregateRef{Name: name, ID: id}
+		delete(s.pending, ref)
+		if elem, ok := s.entries[ref]; ok {
+			s.remove(elem)
+		}
+	}
+
+	return err
+}
+
+// Query queries events. If the query targets the events of a single aggregate,
+// the events are served from the cache if possible. Otherwise, the query is
+// passed through to the underlying store.
+func (s *CacheStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	ref, lower, ok := cacheable(q)
+	if !ok {
+		return s.Store.Query(ctx, q)
+	}
+
+	if events, ok := s.lookup(ref, lower); ok {
+		return serveCached(ctx, q, events)
+	}
+
+	events, err := s.fill(ctx, ref, lower)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return serveCached(ctx, q, events)
+}
+
+// Stats returns the hit and miss counts of the cache.
+func (s *CacheStore) Stats() CacheStats {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return CacheStats{Hits: s.hits, Misses: s.misses}
+}
+
+// Clear removes the streams of the given aggregates from the cache. If no
+// aggregates are provided, the whole cache is cleared.
+func (s *CacheStore) Clear(aggregates ...event.AggregateRef) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if len(aggregates) == 0 {
+		s.lru.Init()
+		clear(s.entries)
+		clear(s.pending)
+		return
+	}
+
+	for _, ref := range aggregates {
+		delete(s.pending, ref)
+		if elem, ok := s.entries[ref]; ok {
+			s.remove(elem)
+		}
+	}
+}
+
+func (s *CacheStore) lookup(ref event.AggregateRef, lower int) ([]event.Event, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	elem, ok := s.entries[ref]
+	if !ok {
+		s.misses++
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		s.remove(elem)
+		s.misses++
+		return nil, false
+	}
+
+	if lower < entry.start {
+		s.misses++
+		return nil, false
+	}
+
+	s.hits++
+	s.lru.MoveToFront(elem)
+
+	return append([]event.Event(nil), entry.events...), true
+}
+
+// fill queries the events of the aggregate from the underlying store and
+// caches them. The fill is registered as pending before the query is made.
+// Writes to the aggregate invalidate the pending fill, so that a fill that
+// raced with a write does not cache a stale stream.
+func (s *CacheStore) fill(ctx context.Context, ref event.AggregateRef, lower int) ([]event.Event, error) {
+	s.mux.Lock()
+	s.fills++
+	pending := s.fills
+	s.pending[ref] = pending
+	s.mux.Unlock()
+
+	events, err := s.queryStream(ctx, ref, lower)
+	if err != nil {
+		s.mux.Lock()
+		if p, ok := s.pending[ref]; ok && p == pending {
+			delete(s.pending, ref)
+		}
+		s.mux.Unlock()
+		return nil, err
+	}
+
+	entry := &cacheEntry{
+		ref:    ref,
+		start:  lower,
+		events: append([]event.Event(nil), events...),
+	}
+	if s.ttl > 0 {
+		entry.expires = time.Now().Add(s.ttl)
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	// The fill was invalidated by a write or superseded by another fill.
+	if p, ok := s.pending[ref]; !ok || p != pending {
+		return events, nil
+	}
+	delete(s.pending, ref)
+
+	s.trim(entry)
+
+	if elem, ok := s.entries[ref]; ok {
+		s.remove(elem)
+	}
+	s.entries[ref] = s.lru.PushFront(entry)
+
+	for s.size > 0 && s.lru.Len() > s.size {
+		s.remove(s.lru.Back())
+	}
+
+	return events, nil
+}
+
+func (s *CacheStore) queryStream(ctx context.Context, ref event.AggregateRef, lower int) ([]event.Event, error) {
+	str, errs, err := s.Store.Query(ctx, query.New(
+		query.Aggregate(ref.Name, ref.ID),
+		query.AggregateVersion(version.Min(lower)),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (s *CacheStore) trim(entry *cacheEntry) {
+	if s.tail <= 0 || len(entry.events) <= s.tail {
+		return
+	}
+	entry.events = append([]event.Event(nil), entry.events[len(entry.events)-s.tail:]...)
+	_, _, entry.start = entry.events[0].Aggregate()
+}
+
+func (s *CacheStore) remove(elem *list.Element) {
+	entry := s.lru.Remove(elem).(*cacheEntry)
+	delete(s.entries, entry.ref)
+}
+
+// appendable reports whether an event with the given aggregate version can be
+// appended to the cached stream without leaving a gap.
+func (entry *cacheEntry) appendable(v int) bool {
+	if len(entry.events) == 0 {
+		return v == max(entry.start, 1)
+	}
+	_, _, last := entry.events[len(entry.events)-1].Aggregate()
+	return v == last+1
+}
+
+// cacheable returns the aggregate that is targeted by the query, together with
+// the lowest aggregate version that the query may return. Only queries that
+// target exactly one aggregate are cacheable.
+func cacheable(q event.Query) (event.AggregateRef, int, bool) {
+	var ref event.AggregateRef
+
+	switch {
+	case len(q.Aggregates()) == 1 && len(q.AggregateNames()) == 0 && len(q.AggregateIDs()) == 0:
+		ref = q.Aggregates()[0]
+	case len(q.Aggregates()) == 0 && len(q.AggregateNames()) == 1 && len(q.AggregateIDs()) == 1:
+		ref = event.AggregateRef{Name: q.AggregateNames()[0], ID: q.AggregateIDs()[0]}
+	default:
+		return ref, 0, false
+	}
+
+	if ref.Name == "" {
+		return ref, 0, false
+	}
+
+	return ref, lowerVersion(q.AggregateVersions()), true
+}
+
+func lowerVersion(c version.Constraints) int {
+	if c == nil {
+		return 0
+	}
+
+	var lower int
+	if exact := c.Exact(); len(exact) > 0 {
+		lower = max(lower, minOf(exact))
+	}
+	if ranges := c.Ranges(); len(ranges) > 0 {
+		starts := make([]int, len(ranges))
+		for i, r := range ranges {
+			starts[i] = r.Start()
+		}
+		lower = max(lower, minOf(starts))
+	}
+	if mins := c.Min(); len(mins) > 0 {
+		lower = max(lower, minOf(mins))
+	}
+
+	return lower
+}
+
+func minOf(vals []int) int {
+	out := vals[0]
+	for _, v := range vals[1:] {
+		out = min(out, v)
+	}
+	return out
+}
+
+func serveCached(ctx context.Context, q event.Query, events []event.Event) (<-chan event.Event, <-chan error, error) {
+	filtered := events[:0]
+	for _, evt := range events {
+		if query.Test(q, evt) {
+			filtered = append(filtered, evt)
+		}
+	}
+	filtered = event.SortMulti(filtered, q.Sortings()...)
+
+	out := make(chan event.Event)
+	errs := make(chan error)
+
+	go func() {
+		defer close(errs)
+		defer close(out)
+		for _, evt := range filtered {
+			select {
+			case <-ctx.Done():
+				return
+			case out <- evt:
+			}
+		}
+	}()
+
+	return out, errs, nil
+}
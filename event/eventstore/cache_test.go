@@ -0,0 +1,195 @@
+package eventstore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestCache(t *testing.T) {
+	id := uuid.New()
+	events := aggregateEvents(id, 1, 3)
+
+	store := eventstore.Cache(eventstore.New(events...))
+
+	got := queryAggregate(t, store, id, 0)
+	test.AssertEqualEvents(t, events, got)
+
+	if stats := store.Stats(); stats.Misses != 1 || stats.Hits != 0 {
+		t.Fatalf("expected 1 miss and 0 hits; got %+v", stats)
+	}
+
+	got = queryAggregate(t, store, id, 2)
+	test.AssertEqualEvents(t, events[1:], got)
+
+	if stats := store.Stats(); stats.Hits != 1 {
+		t.Fatalf("expected 1 hit; got %+v", stats)
+	}
+
+	next := aggregateEvents(id, 4, 4)
+	if err := store.Insert(context.Background(), next...); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	got = queryAggregate(t, store, id, 0)
+	test.AssertEqualEvents(t, append(events, next...), got)
+
+	if stats := store.Stats(); stats.Hits != 2 {
+		t.Fatalf("expected 2 hits; got %+v", stats)
+	}
+}
+
+func TestCacheTail(t *testing.T) {
+	id := uuid.New()
+	events := aggregateEvents(id, 1, 5)
+
+	store := eventstore.Cache(eventstore.New(events...), eventstore.CacheTail(2))
+
+	queryAggregate(t, store, id, 0)
+
+	got := queryAggregate(t, store, id, 4)
+	test.AssertEqualEvents(t, events[3:], got)
+
+	got = queryAggregate(t, store, id, 3)
+	test.AssertEqualEvents(t, events[2:], got)
+
+	if stats := store.Stats(); stats.Hits != 1 || stats.Misses != 2 {
+		t.Fatalf("expected 1 hit and 2 misses; got %+v", stats)
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	store := eventstore.Cache(eventstore.New(append(aggregateEvents(a, 1, 2), aggregateEvents(b, 1, 2)...)...), eventstore.CacheSize(1))
+
+	queryAggregate(t, store, a, 0)
+	queryAggregate(t, store, b, 0)
+	queryAggregate(t, store, a, 0)
+
+	if stats := store.Stats(); stats.Hits != 0 || stats.Misses != 3 {
+		t.Fatalf("expected 0 hits and 3 misses; got %+v", stats)
+	}
+}
+
+func TestCache_Delete(t *testing.T) {
+	id := uuid.New()
+	events := aggregateEvents(id, 1, 2)
+	store := eventstore.Cache(eventstore.New(events...))
+
+	queryAggregate(t, store, id, 0)
+
+	if err := store.Delete(context.Background(), events[1]); err != nil {
+		t.Fatalf("Delete() failed with %q", err)
+	}
+
+	got := queryAggregate(t, store, id, 0)
+	test.AssertEqualEvents(t, events[:1], got)
+}
+
+func TestCache_fillRacesWithInsert(t *testing.T) {
+	id := uuid.New()
+	events := aggregateEvents(id, 1, 2)
+	slow := &slowQueryStore{
+		Store:   eventstore.New(events[0]),
+		queried: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	store := eventstore.Cache(slow)
+
+	filled := make(chan []event.Event)
+	go func() {
+		defer close(filled)
+		str, errs, err := store.Query(context.Background(), query.New(query.Aggregate("foo", id)))
+		if err != nil {
+			return
+		}
+		events, _ := streams.Drain(context.Background(), str, errs)
+		filled <- events
+	}()
+
+	<-slow.queried
+
+	if err := store.Insert(context.Background(), events[1]); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	close(slow.release)
+	test.AssertEqualEvents(t, events[:1], <-filled)
+
+	got := queryAggregate(t, store, id, 0)
+	test.AssertEqualEvents(t, events, got)
+}
+
+// slowQueryStore reads the events of a query from the underlying store and
+// then blocks until released, to simulate a write that happens while a query
+// is in flight.
+type slowQueryStore struct {
+	event.Store
+
+	once    sync.Once
+	queried chan struct{}
+	release chan struct{}
+}
+
+func (s *slowQueryStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	str, errs, err := s.Store.Query(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.once.Do(func() {
+		close(s.queried)
+		<-s.release
+	})
+
+	out := make(chan event.Event, len(events))
+	for _, evt := range events {
+		out <- evt
+	}
+	close(out)
+	errc := make(chan error)
+	close(errc)
+
+	return out, errc, nil
+}
+
+func aggregateEvents(id uuid.UUID, from, to int) []event.Event {
+	var out []event.Event
+	for v := from; v <= to; v++ {
+		out = append(out, event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", v)).Any())
+	}
+	return out
+}
+
+func queryAggregate(t *testing.T, store event.Store, id uuid.UUID, from int) []event.Event {
+	t.Helper()
+
+	str, errs, err := store.Query(context.Background(), query.New(
+		query.AggregateName("foo"),
+		query.AggregateID(id),
+		query.AggregateVersion(version.Min(from)),
+		query.SortBy(event.SortAggregateVersion, event.SortAsc),
+	))
+	if err != nil {
+		t.Fatalf("Query() failed with %q", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	return events
+}
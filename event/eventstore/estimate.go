@@ -0,0 +1,64 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// Estimator is an event store that can estimate the result of event queries
+// without fetching the events, typically by running a count query.
+type Estimator interface {
+	// Estimate returns the estimated result of the given query.
+	Estimate(context.Context, event.Query) (QueryEstimate, error)
+}
+
+// QueryEstimate is the estimated result of an event query.
+type QueryEstimate struct {
+	// Events is the number of events that match the query.
+	Events int64
+
+	// Size is the approximate size of the matching events in bytes, as stored
+	// by the event store. Size is 0 if the event store cannot estimate sizes.
+	Size int64
+
+	// Exact reports whether Events is the exact number of matching events at
+	// the time of the estimation.
+	Exact bool
+}
+
+// Estimate returns the estimated result of the given query. If the event store
+// implements Estimator, its estimation is returned. Otherwise, Estimate runs
+// the query and counts the returned events, in which case the returned Size is
+// 0. Use Estimate to decide whether the events of a query can be loaded into
+// memory at once, or whether they should be streamed:
+//
+//	est, err := eventstore.Estimate(ctx, store, query.New(query.Name("foo")))
+//	if err != nil {
+//		return err
+//	}
+//	if est.Size > 64<<20 {
+//		// stream the events
+//	}
+func Estimate(ctx context.Context, store event.Store, q event.Query) (QueryEstimate, error) {
+	if e, ok := store.(Estimator); ok {
+		return e.Estimate(ctx, q)
+	}
+
+	events, errs, err := store.Query(ctx, q)
+	if err != nil {
+		return QueryEstimate{}, fmt.Errorf("query events: %w", err)
+	}
+
+	est := QueryEstimate{Exact: true}
+	if err := streams.Walk(ctx, func(event.Event) error {
+		est.Events++
+		return nil
+	}, events, errs); err != nil {
+		return QueryEstimate{}, err
+	}
+
+	return est, nil
+}
@@ -0,0 +1,46 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+)
+
+type estimateStore struct {
+	event.Store
+}
+
+func (s *estimateStore) Estimate(context.Context, event.Query) (eventstore.QueryEstimate, error) {
+	return eventstore.QueryEstimate{Events: 42, Size: 1024}, nil
+}
+
+func TestEstimate(t *testing.T) {
+	ctx := context.Background()
+
+	store := eventstore.New(
+		event.New[any]("foo", test.FooEventData{}),
+		event.New[any]("foo", test.FooEventData{}),
+		event.New[any]("bar", test.BarEventData{}),
+	)
+
+	est, err := eventstore.Estimate(ctx, store, query.New(query.Name("foo")))
+	if err != nil {
+		t.Fatalf("Estimate failed with %q", err)
+	}
+
+	if want := (eventstore.QueryEstimate{Events: 2, Exact: true}); est != want {
+		t.Fatalf("Estimate should count the queried events. want=%v got=%v", want, est)
+	}
+
+	if est, err = eventstore.Estimate(ctx, &estimateStore{Store: store}, query.New()); err != nil {
+		t.Fatalf("Estimate failed with %q", err)
+	}
+
+	if want := (eventstore.QueryEstimate{Events: 42, Size: 1024}); est != want {
+		t.Fatalf("Estimate should return the estimate of the event store. want=%v got=%v", want, est)
+	}
+}
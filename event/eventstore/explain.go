@@ -0,0 +1,107 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/modernice/goes/event"
+)
+
+// ErrExplainUnsupported is returned by Explain if the event store does not
+// implement Explainer.
+var ErrExplainUnsupported = errors.New("event store does not support query plans")
+
+// Explainer is an event store that can explain how it executes event queries.
+type Explainer interface {
+	// Explain returns the query plan of the given query.
+	Explain(context.Context, event.Query, ...ExplainOption) (QueryPlan, error)
+}
+
+// ExplainOption is an option for explaining queries.
+type ExplainOption func(*ExplainConfig)
+
+// ExplainConfig is the configuration for explaining queries. Implementations
+// of Explainer build it from the provided options using NewExplainConfig.
+type ExplainConfig struct {
+	// Execute reports whether the query should be executed to collect
+	// execution statistics.
+	Execute bool
+}
+
+// ExplainExecution returns an ExplainOption that executes the query to collect
+// execution statistics, such as the number of examined documents. The query is
+// executed against the live database, so use it carefully for large queries.
+func ExplainExecution() ExplainOption {
+	return func(cfg *ExplainConfig) {
+		cfg.Execute = true
+	}
+}
+
+// NewExplainConfig returns the ExplainConfig of the given options.
+func NewExplainConfig(opts ...ExplainOption) ExplainConfig {
+	var cfg ExplainConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// QueryPlan is the normalized query plan of an event query.
+type QueryPlan struct {
+	// Stages are the stages of the plan, from the outermost stage to the
+	// innermost stage, e.g. ["FETCH", "IXSCAN"].
+	Stages []string
+
+	// Indexes are the names of the indexes that are used by the plan.
+	Indexes []string
+
+	// CollectionScan reports whether the plan scans all events.
+	CollectionScan bool
+
+	// InMemorySort reports whether the plan sorts the events in memory instead
+	// of using an index for sorting.
+	InMemorySort bool
+
+	// Executed reports whether the query was executed to collect execution
+	// statistics. The statistics below are only set if Executed is true.
+	Executed bool
+
+	// Returned is the number of returned events.
+	Returned int64
+
+	// KeysExamined is the number of examined index keys.
+	KeysExamined int64
+
+	// DocsExamined is the number of examined events.
+	DocsExamined int64
+
+	// Raw is the query plan as returned by the database.
+	Raw map[string]any
+}
+
+// UsesIndex reports whether the plan uses an index instead of scanning all
+// events.
+func (p QueryPlan) UsesIndex() bool {
+	return len(p.Indexes) > 0 && !p.CollectionScan
+}
+
+// Explain returns the query plan of the given query if the event store
+// implements Explainer. Otherwise, Explain returns ErrExplainUnsupported. Use
+// Explain to verify that queries hit indexes before running them against large
+// event stores:
+//
+//	plan, err := eventstore.Explain(ctx, store, query.New(query.Name("foo")))
+//	if err != nil {
+//		return err
+//	}
+//	if !plan.UsesIndex() {
+//		log.Printf("query scans all events: %v", plan.Stages)
+//	}
+func Explain(ctx context.Context, store event.Store, q event.Query, opts ...ExplainOption) (QueryPlan, error) {
+	e, ok := store.(Explainer)
+	if !ok {
+		return QueryPlan{}, fmt.Errorf("%w (%T)", ErrExplainUnsupported, store)
+	}
+	return e.Explain(ctx, q, opts...)
+}
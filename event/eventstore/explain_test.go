@@ -0,0 +1,45 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+)
+
+type explainStore struct {
+	event.Store
+
+	cfg eventstore.ExplainConfig
+}
+
+func (s *explainStore) Explain(_ context.Context, _ event.Query, opts ...eventstore.ExplainOption) (eventstore.QueryPlan, error) {
+	s.cfg = eventstore.NewExplainConfig(opts...)
+	return eventstore.QueryPlan{Stages: []string{"IXSCAN"}, Indexes: []string{"name"}}, nil
+}
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := eventstore.Explain(ctx, eventstore.New(), query.New()); !errors.Is(err, eventstore.ErrExplainUnsupported) {
+		t.Fatalf("Explain should fail with %q; got %q", eventstore.ErrExplainUnsupported, err)
+	}
+
+	store := &explainStore{Store: eventstore.New()}
+
+	plan, err := eventstore.Explain(ctx, store, query.New(), eventstore.ExplainExecution())
+	if err != nil {
+		t.Fatalf("Explain failed with %q", err)
+	}
+
+	if !plan.UsesIndex() {
+		t.Fatalf("plan should use an index")
+	}
+
+	if !store.cfg.Execute {
+		t.Fatalf("ExplainExecution option should be passed to the store")
+	}
+}
@@ -0,0 +1,197 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+)
+
+var (
+	// ErrLegalHold is returned by a store that is decorated by WithLegalHolds
+	// if events that are under a legal hold would be deleted.
+	ErrLegalHold = errors.New("events are under legal hold")
+
+	// ErrHoldNotFound is returned by LegalHolds.Lift if the hold does not exist.
+	ErrHoldNotFound = errors.New("legal hold not found")
+)
+
+// LegalHold protects events from deletion. A hold covers the event streams of
+// the given aggregates and the events that match the given query. While a hold
+// is active, covered events cannot be deleted, pruned, compacted, or otherwise
+// removed from a store that is decorated by WithLegalHolds.
+type LegalHold struct {
+	// ID identifies the hold.
+	ID string
+
+	// Reason describes why the events are held, e.g. the reference of a case.
+	Reason string
+
+	// Aggregates are the aggregates whose event streams are held. A reference
+	// with a nil ID holds the event streams of all aggregates with the name.
+	Aggregates []event.AggregateRef
+
+	// Query, if provided, holds the events that match the query, e.g. all
+	// events within a time range:
+	//
+	//	query.New(query.Time(time.Min(start), time.Max(end)))
+	Query event.Query
+
+	// Placed is the time at which the hold was placed.
+	Placed time.Time
+}
+
+// Covers reports whether the hold covers the given event.
+func (h LegalHold) Covers(evt event.Event) bool {
+	id, name, _ := evt.Aggregate()
+	for _, ref := range h.Aggregates {
+		if ref.Name == name && (ref.ID == uuid.Nil || ref.ID == id) {
+			return true
+		}
+	}
+	return h.Query != nil && event.Test(h.Query, evt)
+}
+
+// LegalHolds stores the legal holds of an application.
+type LegalHolds interface {
+	// Place places the given hold. Placing a hold with the ID of an existing
+	// hold replaces the existing hold.
+	Place(ctx context.Context, hold LegalHold) error
+
+	// Lift lifts the hold with the given ID. Lift returns an error that
+	// unwraps to ErrHoldNotFound if the hold does not exist.
+	Lift(ctx context.Context, id string) error
+
+	// Active returns the active holds.
+	Active(ctx context.Context) ([]LegalHold, error)
+}
+
+// NewMemoryLegalHolds returns in-memory LegalHolds.
+func NewMemoryLegalHolds() LegalHolds {
+	return &memoryHolds{holds: make(map[string]LegalHold)}
+}
+
+type memoryHolds struct {
+	mux   sync.RWMutex
+	holds map[string]LegalHold
+}
+
+func (h *memoryHolds) Place(_ context.Context, hold LegalHold) error {
+	if hold.ID == "" {
+		return errors.New("legal hold must have an ID")
+	}
+	if hold.Placed.IsZero() {
+		hold.Placed = time.Now()
+	}
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.holds[hold.ID] = hold
+
+	return nil
+}
+
+func (h *memoryHolds) Lift(_ context.Context, id string) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if _, ok := h.holds[id]; !ok {
+		return fmt.Errorf("%w [id=%s]", ErrHoldNotFound, id)
+	}
+	delete(h.holds, id)
+
+	return nil
+}
+
+func (h *memoryHolds) Active(context.Context) ([]LegalHold, error) {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	out := make([]LegalHold, 0, len(h.holds))
+	for _, hold := range h.holds {
+		out = append(out, hold)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+
+	return out, nil
+}
+
+// WithLegalHolds decorates the given event store with the given legal holds.
+// Deleting events that are covered by an active hold fails with an error that
+// unwraps to ErrLegalHold, and none of the events are deleted. Because pruning
+// (see aggregate/snapshot/prune), compaction (see aggregate/compact), and the
+// deletion of aggregates all delete events through the store, a single
+// decorated store enforces the holds for all of these operations:
+//
+//	holds := eventstore.NewMemoryLegalHolds()
+//	store := eventstore.WithLegalHolds(store, holds)
+//
+//	holds.Place(ctx, eventstore.LegalHold{
+//		ID:         "case-42",
+//		Aggregates: []event.AggregateRef{{Name: "order", ID: orderID}},
+//	})
+//
+// If the given store implements AtomicReplacer, so does the returned store.
+// The returned store implements Locker by forwarding to the given store.
+func WithLegalHolds(store event.Store, holds LegalHolds) event.Store {
+	s := &holdStore{Store: store, lockForwarder: lockForwarder{store}, holds: holds}
+	if replacer, ok := store.(AtomicReplacer); ok {
+		return &atomicHoldStore{holdStore: s, replacer: replacer}
+	}
+	return s
+}
+
+type holdStore struct {
+	event.Store
+	lockForwarder
+	holds LegalHolds
+}
+
+type atomicHoldStore struct {
+	*holdStore
+	replacer AtomicReplacer
+}
+
+// Delete deletes the given events if none of them is under an active legal
+// hold.
+func (s *holdStore) Delete(ctx context.Context, events ...event.Event) error {
+	if err := s.check(ctx, events); err != nil {
+		return err
+	}
+	return s.Store.Delete(ctx, events...)
+}
+
+// ReplaceAtomic replaces the given events if none of the removed events is
+// under an active legal hold.
+func (s *atomicHoldStore) ReplaceAtomic(ctx context.Context, remove, insert []event.Event) error {
+	if err := s.check(ctx, remove); err != nil {
+		return err
+	}
+	return s.replacer.ReplaceAtomic(ctx, remove, insert)
+}
+
+func (s *holdStore) check(ctx context.Context, events []event.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	holds, err := s.holds.Active(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch legal holds: %w", err)
+	}
+
+	for _, evt := range events {
+		for _, hold := range holds {
+			if hold.Covers(evt) {
+				return fmt.Errorf("%w [hold=%s, event=%s]", ErrLegalHold, hold.ID, evt.ID())
+			}
+		}
+	}
+
+	return nil
+}
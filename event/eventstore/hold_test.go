@@ -0,0 +1,95 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+)
+
+func TestWithLegalHolds(t *testing.T) {
+	ctx := context.Background()
+
+	held, other := uuid.New(), uuid.New()
+	heldEvents := aggregateEvents(held, 1, 3)
+	otherEvents := aggregateEvents(other, 1, 3)
+
+	holds := eventstore.NewMemoryLegalHolds()
+	store := eventstore.WithLegalHolds(eventstore.New(), holds)
+
+	if err := store.Insert(ctx, append(heldEvents, otherEvents...)...); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	if err := holds.Place(ctx, eventstore.LegalHold{
+		ID:         "case-1",
+		Aggregates: []event.AggregateRef{{Name: "foo", ID: held}},
+	}); err != nil {
+		t.Fatalf("Place failed with %q", err)
+	}
+
+	if err := store.Delete(ctx, heldEvents[2], otherEvents[2]); !errors.Is(err, eventstore.ErrLegalHold) {
+		t.Fatalf("Delete should fail with %q; got %v", eventstore.ErrLegalHold, err)
+	}
+
+	if _, err := store.Find(ctx, otherEvents[2].ID()); err != nil {
+		t.Fatalf("no events should be deleted if any of them is held; Find failed with %q", err)
+	}
+
+	replacer, ok := store.(eventstore.AtomicReplacer)
+	if !ok {
+		t.Fatalf("store should implement %T", replacer)
+	}
+
+	if err := replacer.ReplaceAtomic(ctx, heldEvents[:1], nil); !errors.Is(err, eventstore.ErrLegalHold) {
+		t.Fatalf("ReplaceAtomic should fail with %q; got %v", eventstore.ErrLegalHold, err)
+	}
+
+	if err := store.Delete(ctx, otherEvents[2]); err != nil {
+		t.Fatalf("Delete of events that are not held failed with %q", err)
+	}
+
+	if err := holds.Lift(ctx, "case-1"); err != nil {
+		t.Fatalf("Lift failed with %q", err)
+	}
+
+	if err := holds.Lift(ctx, "case-1"); !errors.Is(err, eventstore.ErrHoldNotFound) {
+		t.Fatalf("Lift should fail with %q; got %v", eventstore.ErrHoldNotFound, err)
+	}
+
+	if err := store.Delete(ctx, heldEvents[2]); err != nil {
+		t.Fatalf("Delete should succeed after the hold was lifted; got %q", err)
+	}
+}
+
+func TestWithLegalHolds_query(t *testing.T) {
+	ctx := context.Background()
+
+	events := aggregateEvents(uuid.New(), 1, 3)
+
+	holds := eventstore.NewMemoryLegalHolds()
+	store := eventstore.WithLegalHolds(eventstore.New(), holds)
+
+	if err := store.Insert(ctx, events...); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	if err := holds.Place(ctx, eventstore.LegalHold{
+		ID:    "case-1",
+		Query: query.New(query.ID(events[1].ID())),
+	}); err != nil {
+		t.Fatalf("Place failed with %q", err)
+	}
+
+	if err := store.Delete(ctx, events[0]); err != nil {
+		t.Fatalf("Delete of an event that is not held failed with %q", err)
+	}
+
+	if err := store.Delete(ctx, events[1]); !errors.Is(err, eventstore.ErrLegalHold) {
+		t.Fatalf("Delete should fail with %q; got %v", eventstore.ErrLegalHold, err)
+	}
+}
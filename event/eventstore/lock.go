@@ -0,0 +1,186 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/modernice/goes/event"
+)
+
+var (
+	// ErrLocked is returned by a Locker if an aggregate is locked by another
+	// lease.
+	ErrLocked = errors.New("aggregate is locked")
+
+	// ErrLockLost is returned by a Locker if a lease has expired and the lock
+	// was released or acquired by another lease.
+	ErrLockLost = errors.New("lock lost")
+
+	// ErrLockingUnsupported is returned by the Locker of a store decorator if
+	// the decorated store does not implement Locker.
+	ErrLockingUnsupported = errors.New("event store does not support locking")
+)
+
+// Lease is an exclusive lock on an aggregate that expires at a given time.
+type Lease struct {
+	// Aggregate is the locked aggregate.
+	Aggregate event.AggregateRef
+
+	// Token identifies the lease. Tokens increase with every lease that is
+	// acquired for the same aggregate, so that the holder of an expired lease
+	// can detect that the aggregate was locked by another lease.
+	Token int64
+
+	// Expires is the time at which the lease expires.
+	Expires time.Time
+}
+
+// Active reports whether the lease has not expired yet.
+func (l Lease) Active() bool {
+	return time.Now().Before(l.Expires)
+}
+
+// Locker is an event store that can lock the event streams of aggregates.
+// Locks are leases that expire after a given duration, so that a crashed
+// owner cannot lock an aggregate forever.
+type Locker interface {
+	// Lock acquires a lease on the given aggregate that expires after the given
+	// duration. Lock returns an error that unwraps to ErrLocked if the
+	// aggregate is locked by an active lease.
+	Lock(ctx context.Context, ref event.AggregateRef, ttl time.Duration) (Lease, error)
+
+	// Renew extends the given lease by the given duration, starting now. Renew
+	// returns an error that unwraps to ErrLockLost if the lease has expired.
+	Renew(ctx context.Context, lease Lease, ttl time.Duration) (Lease, error)
+
+	// Unlock releases the given lease. Releasing an expired lease is not an
+	// error.
+	Unlock(ctx context.Context, lease Lease) error
+
+	// Lease returns the active lease of the given aggregate, or false if the
+	// aggregate is not locked.
+	Lease(ctx context.Context, ref event.AggregateRef) (Lease, bool, error)
+}
+
+// lockForwarder implements Locker for store decorators by forwarding to the
+// decorated store. If the decorated store does not implement Locker, the
+// methods fail with ErrLockingUnsupported.
+type lockForwarder struct {
+	store event.Store
+}
+
+// Lock implements Locker.
+func (f lockForwarder) Lock(ctx context.Context, ref event.AggregateRef, ttl time.Duration) (Lease, error) {
+	locker, err := f.locker()
+	if err != nil {
+		return Lease{}, err
+	}
+	return locker.Lock(ctx, ref, ttl)
+}
+
+// Renew implements Locker.
+func (f lockForwarder) Renew(ctx context.Context, lease Lease, ttl time.Duration) (Lease, error) {
+	locker, err := f.locker()
+	if err != nil {
+		return lease, err
+	}
+	return locker.Renew(ctx, lease, ttl)
+}
+
+// Unlock implements Locker.
+func (f lockForwarder) Unlock(ctx context.Context, lease Lease) error {
+	locker, err := f.locker()
+	if err != nil {
+		return err
+	}
+	return locker.Unlock(ctx, lease)
+}
+
+// Lease implements Locker.
+func (f lockForwarder) Lease(ctx context.Context, ref event.AggregateRef) (Lease, bool, error) {
+	locker, err := f.locker()
+	if err != nil {
+		return Lease{}, false, err
+	}
+	return locker.Lease(ctx, ref)
+}
+
+func (f lockForwarder) locker() (Locker, error) {
+	if locker, ok := f.store.(Locker); ok {
+		return locker, nil
+	}
+	return nil, fmt.Errorf("%w [store=%T]", ErrLockingUnsupported, f.store)
+}
+
+type memLease struct {
+	token   int64
+	expires time.Time
+}
+
+// Lock implements Locker.
+func (s *memstore) Lock(ctx context.Context, ref event.AggregateRef, ttl time.Duration) (Lease, error) {
+	s.leaseMux.Lock()
+	defer s.leaseMux.Unlock()
+
+	if s.leases == nil {
+		s.leases = make(map[event.AggregateRef]memLease)
+	}
+
+	now := time.Now()
+	l := s.leases[ref]
+	if now.Before(l.expires) {
+		return Lease{}, fmt.Errorf("%w [aggregate=%s, expires=%v]", ErrLocked, ref, l.expires)
+	}
+
+	l.token++
+	l.expires = now.Add(ttl)
+	s.leases[ref] = l
+
+	return Lease{Aggregate: ref, Token: l.token, Expires: l.expires}, nil
+}
+
+// Renew implements Locker.
+func (s *memstore) Renew(ctx context.Context, lease Lease, ttl time.Duration) (Lease, error) {
+	s.leaseMux.Lock()
+	defer s.leaseMux.Unlock()
+
+	now := time.Now()
+	l := s.leases[lease.Aggregate]
+	if l.token != lease.Token || !now.Before(l.expires) {
+		return lease, fmt.Errorf("%w [aggregate=%s, token=%d]", ErrLockLost, lease.Aggregate, lease.Token)
+	}
+
+	l.expires = now.Add(ttl)
+	s.leases[lease.Aggregate] = l
+	lease.Expires = l.expires
+
+	return lease, nil
+}
+
+// Unlock implements Locker.
+func (s *memstore) Unlock(ctx context.Context, lease Lease) error {
+	s.leaseMux.Lock()
+	defer s.leaseMux.Unlock()
+
+	if l, ok := s.leases[lease.Aggregate]; ok && l.token == lease.Token {
+		l.expires = time.Time{}
+		s.leases[lease.Aggregate] = l
+	}
+
+	return nil
+}
+
+// Lease implements Locker.
+func (s *memstore) Lease(ctx context.Context, ref event.AggregateRef) (Lease, bool, error) {
+	s.leaseMux.Lock()
+	defer s.leaseMux.Unlock()
+
+	l, ok := s.leases[ref]
+	if !ok || !time.Now().Before(l.expires) {
+		return Lease{}, false, nil
+	}
+
+	return Lease{Aggregate: ref, Token: l.token, Expires: l.expires}, true, nil
+}
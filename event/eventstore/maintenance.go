@@ -0,0 +1,160 @@
+package eventstore
+
+import (
+	"context"
+	"time"
+)
+
+// Stats are storage statistics of an event store.
+type Stats struct {
+	// Events is the total number of stored events.
+	Events int64
+
+	// Size is the logical size of the stored events in bytes.
+	Size int64
+
+	// StorageSize is the storage that is allocated for the stored events in
+	// bytes, including free storage that can be reused.
+	StorageSize int64
+
+	// FreeStorageSize is the allocated storage in bytes that is free for reuse.
+	FreeStorageSize int64
+
+	// IndexSize is the total size of all indexes in bytes.
+	IndexSize int64
+
+	// IndexSizes are the sizes of the indexes in bytes, by index name.
+	IndexSizes map[string]int64
+
+	// Names are the statistics per event name, sorted by name.
+	Names []NameStats
+}
+
+// NameStats are storage statistics of the events with a given name.
+type NameStats struct {
+	// Name is the event name.
+	Name string
+
+	// Events is the number of stored events with the name.
+	Events int64
+
+	// Size is the logical size of the stored events with the name in bytes.
+	Size int64
+}
+
+// Maintainer is an event store that provides maintenance operations.
+type Maintainer interface {
+	// Stats returns storage statistics of the event store.
+	Stats(context.Context) (Stats, error)
+
+	// Compact releases the free storage of the event store. Compaction may
+	// block or slow down the event store, so it should be run within a
+	// low-traffic MaintenanceWindow.
+	Compact(context.Context) error
+}
+
+// Fragmentation returns the fraction of the allocated storage that is free for
+// reuse, in the range [0, 1].
+func (s Stats) Fragmentation() float64 {
+	if s.StorageSize <= 0 {
+		return 0
+	}
+	return float64(s.FreeStorageSize) / float64(s.StorageSize)
+}
+
+// MaintenanceWindow is a daily time window with low traffic, in which
+// maintenance tasks such as compaction can be run. Start and End are offsets
+// from midnight. If End is before Start, the window spans midnight.
+//
+//	// every night from 11 p.m. to 2 a.m.
+//	w := eventstore.MaintenanceWindow{Start: 23 * time.Hour, End: 2 * time.Hour}
+type MaintenanceWindow struct {
+	Start time.Duration
+	End   time.Duration
+
+	// Location is the time zone of the window. Defaults to time.Local.
+	Location *time.Location
+}
+
+// Contains reports whether t is within the window.
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	start, _ := w.Next(t)
+	return !start.After(t)
+}
+
+// Next returns the start and end of the next window at or after t. If t is
+// within a window, Next returns the start and end of that window.
+func (w MaintenanceWindow) Next(t time.Time) (start, end time.Time) {
+	loc := w.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+
+	length := w.End - w.Start
+	if length <= 0 {
+		length += 24 * time.Hour
+	}
+
+	// Start with the window that started yesterday, in case it spans midnight.
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d-1, 0, 0, 0, 0, loc)
+	for {
+		start = midnight.Add(w.Start)
+		end = start.Add(length)
+		if end.After(t) {
+			return start, end
+		}
+		y, m, d = midnight.Date()
+		midnight = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+}
+
+// RunMaintenance calls fn within every maintenance window until ctx is
+// canceled. The context that is passed to fn is canceled at the end of the
+// window. Errors returned by fn are sent into the returned channel, which is
+// closed when ctx is canceled.
+//
+//	errs := eventstore.RunMaintenance(ctx, w, store.Compact)
+func RunMaintenance(ctx context.Context, w MaintenanceWindow, fn func(context.Context) error) <-chan error {
+	out := make(chan error)
+
+	go func() {
+		defer close(out)
+
+		for {
+			start, end := w.Next(time.Now())
+
+			timer := time.NewTimer(time.Until(start))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			windowCtx, cancel := context.WithDeadline(ctx, end)
+			err := fn(windowCtx)
+			cancel()
+
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case out <- err:
+				}
+			}
+
+			// Wait for the window to end before computing the next window.
+			timer = time.NewTimer(time.Until(end))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+
+	return out
+}
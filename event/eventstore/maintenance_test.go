@@ -0,0 +1,99 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event/eventstore"
+)
+
+func TestMaintenanceWindow_Next(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		window    eventstore.MaintenanceWindow
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "before window",
+			window:    eventstore.MaintenanceWindow{Start: 2 * time.Hour, End: 4 * time.Hour, Location: time.UTC},
+			now:       day.Add(time.Hour),
+			wantStart: day.Add(2 * time.Hour),
+			wantEnd:   day.Add(4 * time.Hour),
+		},
+		{
+			name:      "within window",
+			window:    eventstore.MaintenanceWindow{Start: 2 * time.Hour, End: 4 * time.Hour, Location: time.UTC},
+			now:       day.Add(3 * time.Hour),
+			wantStart: day.Add(2 * time.Hour),
+			wantEnd:   day.Add(4 * time.Hour),
+		},
+		{
+			name:      "after window",
+			window:    eventstore.MaintenanceWindow{Start: 2 * time.Hour, End: 4 * time.Hour, Location: time.UTC},
+			now:       day.Add(5 * time.Hour),
+			wantStart: day.Add(26 * time.Hour),
+			wantEnd:   day.Add(28 * time.Hour),
+		},
+		{
+			name:      "spans midnight",
+			window:    eventstore.MaintenanceWindow{Start: 23 * time.Hour, End: 2 * time.Hour, Location: time.UTC},
+			now:       day.Add(time.Hour),
+			wantStart: day.Add(-time.Hour),
+			wantEnd:   day.Add(2 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.window.Next(tt.now)
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Fatalf("Next(%v) should return [%v, %v]; got [%v, %v]", tt.now, tt.wantStart, tt.wantEnd, start, end)
+			}
+
+			contains := !tt.now.Before(tt.wantStart)
+			if tt.window.Contains(tt.now) != contains {
+				t.Fatalf("Contains(%v) should return %v", tt.now, contains)
+			}
+		})
+	}
+}
+
+func TestRunMaintenance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	start := now.Sub(midnight)
+
+	w := eventstore.MaintenanceWindow{Start: start, End: start + time.Hour}
+
+	ran := make(chan time.Time, 1)
+	errs := eventstore.RunMaintenance(ctx, w, func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		ran <- deadline
+		return nil
+	})
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("maintenance should run within the current window")
+	case err := <-errs:
+		t.Fatal(err)
+	case deadline := <-ran:
+		if want := midnight.Add(start + time.Hour); !deadline.Equal(want) {
+			t.Fatalf("context deadline should be the end of the window (%v); is %v", want, deadline)
+		}
+	}
+
+	cancel()
+
+	if _, ok := <-errs; ok {
+		t.Fatalf("error channel should be closed after ctx is canceled")
+	}
+}
@@ -0,0 +1,186 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/internal/concurrent"
+)
+
+// MergedStore is an event store that reads events from multiple event stores,
+// e.g. the event stores of different bounded contexts. Queries are run against
+// every store and the results are merged into a single, sorted stream, so
+// projections can be built from the events of multiple stores without first
+// replicating them into a single store:
+//
+//	var orders, billing event.Store
+//	store := eventstore.Merge(orders, billing)
+//	s := schedule.Continuously(bus, store, []string{"order.placed", "invoice.paid"})
+//
+// Results are merged according to the sortings of the query. Queries without
+// sortings are sorted by event time. Each store must return its results in the
+// order of the query's sortings, which all event stores in this repository do.
+//
+// Writes go to the first store. Use the stores directly to insert events into
+// the other stores.
+type MergedStore struct {
+	stores []event.Store
+}
+
+// Merge returns a MergedStore that reads events from the given stores. Merge
+// panics if no stores are provided.
+func Merge(stores ...event.Store) *MergedStore {
+	if len(stores) == 0 {
+		panic("[goes/event/eventstore.Merge] no stores provided")
+	}
+	return &MergedStore{stores: stores}
+}
+
+// Stores returns the stores that are merged.
+func (s *MergedStore) Stores() []event.Store {
+	return s.stores
+}
+
+// Insert inserts the events into the first store.
+func (s *MergedStore) Insert(ctx context.Context, events ...event.Event) error {
+	return s.stores[0].Insert(ctx, events...)
+}
+
+// Find returns the event with the given id from the first store that contains
+// it.
+func (s *MergedStore) Find(ctx context.Context, id uuid.UUID) (event.Event, error) {
+	var err error
+	for _, store := range s.stores {
+		var evt event.Event
+		if evt, err = store.Find(ctx, id); err == nil {
+			return evt, nil
+		}
+	}
+	return nil, err
+}
+
+// Delete deletes each event from the store that contains it.
+func (s *MergedStore) Delete(ctx context.Context, events ...event.Event) error {
+	for _, evt := range events {
+		for _, store := range s.stores {
+			if _, err := store.Find(ctx, evt.ID()); err != nil {
+				continue
+			}
+			if err := store.Delete(ctx, evt); err != nil {
+				return fmt.Errorf("delete %q event (ID=%s): %w", evt.Name(), evt.ID(), err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// Query queries the events from all stores and merges the results according
+// to the sortings of the query. If the query has no sortings, the results are
+// sorted by event time.
+func (s *MergedStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	sortings := q.Sortings()
+	if len(sortings) == 0 {
+		q = query.Merge(q, query.New(query.SortByTime()))
+		sortings = q.Sortings()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	sources := make([]*mergeSource, len(s.stores))
+	for i, store := range s.stores {
+		events, errs, err := store.Query(ctx, q)
+		if err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("query store #%d: %w", i, err)
+		}
+		sources[i] = &mergeSource{events: events, errs: errs}
+	}
+
+	out := make(chan event.Event)
+	errs, fail := concurrent.Errors(ctx)
+
+	go func() {
+		defer cancel()
+		defer close(out)
+
+		for _, src := range sources {
+			if !src.next(ctx, fail) {
+				return
+			}
+		}
+
+		for {
+			var min *mergeSource
+			for _, src := range sources {
+				if src.head == nil {
+					continue
+				}
+				if min == nil || less(sortings, src.head, min.head) {
+					min = src
+				}
+			}
+
+			if min == nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- min.head:
+			}
+
+			if !min.next(ctx, fail) {
+				return
+			}
+		}
+	}()
+
+	return out, errs, nil
+}
+
+type mergeSource struct {
+	events <-chan event.Event
+	errs   <-chan error
+	head   event.Event
+}
+
+// next reads the next event of the source into head. head is nil if the source
+// has no more events. Errors of the source are passed to fail. next returns
+// false if ctx is canceled.
+func (src *mergeSource) next(ctx context.Context, fail func(error)) bool {
+	src.head = nil
+	for src.events != nil {
+		select {
+		case <-ctx.Done():
+			return false
+		case err, ok := <-src.errs:
+			if !ok {
+				src.errs = nil
+				break
+			}
+			fail(err)
+		case evt, ok := <-src.events:
+			if !ok {
+				src.events = nil
+				break
+			}
+			src.head = evt
+			return true
+		}
+	}
+	return true
+}
+
+func less(sortings []event.SortOptions, a, b event.Event) bool {
+	for _, opts := range sortings {
+		if cmp := opts.Sort.Compare(a, b); cmp != 0 {
+			return opts.Dir.Bool(cmp < 0)
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestMerge(t *testing.T) {
+	now := time.Now()
+	at := func(n int) event.Option { return event.Time(now.Add(time.Duration(n) * time.Second)) }
+
+	a := eventstore.New(
+		event.New("foo", test.FooEventData{}, at(0)).Any(),
+		event.New("foo", test.FooEventData{}, at(3)).Any(),
+		event.New("bar", test.BarEventData{}, at(4)).Any(),
+	)
+	b := eventstore.New(
+		event.New("foo", test.FooEventData{}, at(1)).Any(),
+		event.New("foo", test.FooEventData{}, at(2)).Any(),
+		event.New("foo", test.FooEventData{}, at(5)).Any(),
+	)
+
+	store := eventstore.Merge(a, b)
+
+	events := queryAll(t, store, query.New(query.Name("foo")))
+	if len(events) != 5 {
+		t.Fatalf("Query() should return %d events; got %d", 5, len(events))
+	}
+
+	for i, evt := range events {
+		if i > 0 && evt.Time().Before(events[i-1].Time()) {
+			t.Fatalf("events should be sorted by time")
+		}
+	}
+
+	events = queryAll(t, store, query.New(query.Name("foo"), query.SortBy(event.SortTime, event.SortDesc)))
+	if len(events) != 5 || !events[0].Time().Equal(now.Add(5*time.Second)) {
+		t.Fatalf("events should be sorted by the sortings of the query")
+	}
+}
+
+func TestMergedStore_Find(t *testing.T) {
+	evt := event.New("foo", test.FooEventData{}).Any()
+	store := eventstore.Merge(eventstore.New(), eventstore.New(evt))
+
+	found, err := store.Find(context.Background(), evt.ID())
+	if err != nil {
+		t.Fatalf("Find() failed with %q", err)
+	}
+
+	if found.ID() != evt.ID() {
+		t.Fatalf("Find() returned the wrong event")
+	}
+
+	if _, err := store.Find(context.Background(), uuid.New()); err == nil {
+		t.Fatalf("Find() should fail for unknown events")
+	}
+}
+
+func queryAll(t *testing.T, store event.Store, q event.Query) []event.Event {
+	str, errs, err := store.Query(context.Background(), q)
+	if err != nil {
+		t.Fatalf("Query() failed with %q", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("Query() failed with %q", err)
+	}
+
+	return events
+}
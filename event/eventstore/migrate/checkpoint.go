@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Checkpoint is the progress of a migration, from which a migration can be
+// resumed.
+type Checkpoint struct {
+	// Time is the time of the last migrated event.
+	Time time.Time `json:"time"`
+
+	// IDs are the IDs of the migrated events whose time is equal to Time.
+	IDs []uuid.UUID `json:"ids,omitempty"`
+
+	// Events is the number of migrated events.
+	Events int `json:"events"`
+
+	// Checksum is the checksum of the migrated events.
+	Checksum string `json:"checksum"`
+}
+
+// CheckpointStore stores the Checkpoint of a migration.
+type CheckpointStore interface {
+	// LoadCheckpoint returns the stored Checkpoint, or the zero Checkpoint if
+	// no Checkpoint has been stored.
+	LoadCheckpoint(context.Context) (Checkpoint, error)
+
+	// SaveCheckpoint stores the given Checkpoint.
+	SaveCheckpoint(context.Context, Checkpoint) error
+}
+
+type fileCheckpoints struct {
+	path string
+}
+
+// FileCheckpoints returns a CheckpointStore that stores the Checkpoint as JSON
+// in the file at the given path.
+func FileCheckpoints(path string) CheckpointStore {
+	return fileCheckpoints{path: path}
+}
+
+func (s fileCheckpoints) LoadCheckpoint(context.Context) (Checkpoint, error) {
+	var cp Checkpoint
+
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, fmt.Errorf("read file: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return cp, fmt.Errorf("decode checkpoint: %w", err)
+	}
+
+	return cp, nil
+}
+
+func (s fileCheckpoints) SaveCheckpoint(_ context.Context, cp Checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("encode checkpoint: %w", err)
+	}
+
+	// Write to a temporary file first so that a crash does not corrupt the
+	// previous checkpoint.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("rename file: %w", err)
+	}
+
+	return nil
+}
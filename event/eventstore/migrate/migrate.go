@@ -0,0 +1,416 @@
+// Package migrate copies the events and snapshots of an event store to another
+// event store, e.g. to move from the MongoDB event store to the Postgres event
+// store.
+//
+// A migration is usually combined with an eventstore.MirrorStore that mirrors
+// new writes to the new store while the existing events are copied:
+//
+//	store := eventstore.Mirror(mongoStore, []event.Store{postgresStore})
+//	errs, err := store.Run(ctx)
+//
+//	m := migrate.New(
+//		mongoStore, postgresStore,
+//		migrate.Snapshots(mongoSnapshots, postgresSnapshots),
+//		migrate.Checkpoints(migrate.FileCheckpoints("migration.json")),
+//		migrate.RateLimit(5000),
+//	)
+//
+//	res, err := m.Run(ctx)
+//	report, err := m.Verify(ctx)
+//	if !report.OK() {
+//		// do not switch stores
+//	}
+//
+// Events that already exist in the target store are skipped, so a migration
+// can safely run alongside a MirrorStore and be restarted at any time.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/time"
+)
+
+// DefaultBatchSize is the default number of events that are inserted into the
+// target store at once.
+const DefaultBatchSize = 100
+
+// Migrator copies the events and snapshots of a source store to a target
+// store.
+type Migrator struct {
+	source event.Store
+	target event.Store
+
+	sourceSnapshots snapshot.Store
+	targetSnapshots snapshot.Store
+
+	batchSize   int
+	rate        int
+	marshal     func(any) ([]byte, error)
+	checkpoints CheckpointStore
+	onProgress  []func(Progress)
+}
+
+// Option is an option for a Migrator.
+type Option func(*Migrator)
+
+// Result is the result of a migration.
+type Result struct {
+	// Events is the number of events that were read from the source store,
+	// including the events of previous runs that were resumed.
+	Events int
+
+	// Copied is the number of events that were inserted into the target store
+	// by this run.
+	Copied int
+
+	// Skipped is the number of events that already existed in the target
+	// store.
+	Skipped int
+
+	// Snapshots is the number of snapshots that were copied.
+	Snapshots int
+
+	// Checksum is the checksum of all events that were read from the source
+	// store. The checksum does not depend on the order of the events and is
+	// equal to Report.SourceChecksum after a complete migration.
+	Checksum string
+}
+
+// Progress is the progress of a running migration.
+type Progress struct {
+	// Events is the number of events that were read from the source store.
+	Events int
+
+	// Copied is the number of events that were inserted into the target store.
+	Copied int
+
+	// Skipped is the number of events that already existed in the target
+	// store.
+	Skipped int
+
+	// Time is the time of the last event that was migrated.
+	Time stdtime.Time
+}
+
+// Snapshots returns an Option that also copies the snapshots of the source
+// snapshot store to the target snapshot store. Snapshots are copied after all
+// events have been copied.
+func Snapshots(source, target snapshot.Store) Option {
+	return func(m *Migrator) {
+		m.sourceSnapshots = source
+		m.targetSnapshots = target
+	}
+}
+
+// BatchSize returns an Option that sets the number of events that are inserted
+// into the target store at once. Defaults to DefaultBatchSize.
+func BatchSize(n int) Option {
+	return func(m *Migrator) {
+		m.batchSize = n
+	}
+}
+
+// RateLimit returns an Option that limits the number of events that are copied
+// per second, to reduce the load on the stores while they serve production
+// traffic. A limit of 0 or less disables rate limiting.
+func RateLimit(eventsPerSecond int) Option {
+	return func(m *Migrator) {
+		m.rate = eventsPerSecond
+	}
+}
+
+// Encoding returns an Option that sets the encoding that is used to encode
+// event data when computing checksums. By default, event data is encoded as
+// JSON.
+func Encoding(enc codec.Encoding) Option {
+	return func(m *Migrator) {
+		m.marshal = enc.Marshal
+	}
+}
+
+// Checkpoints returns an Option that saves the progress of the migration to
+// the given CheckpointStore after every batch. Run resumes a migration from the
+// last checkpoint.
+func Checkpoints(store CheckpointStore) Option {
+	return func(m *Migrator) {
+		m.checkpoints = store
+	}
+}
+
+// OnProgress returns an Option that calls fn after every batch of copied
+// events.
+func OnProgress(fn func(Progress)) Option {
+	return func(m *Migrator) {
+		m.onProgress = append(m.onProgress, fn)
+	}
+}
+
+// New returns a Migrator that copies the events of source to target.
+func New(source, target event.Store, opts ...Option) *Migrator {
+	m := &Migrator{
+		source:    source,
+		target:    target,
+		batchSize: DefaultBatchSize,
+		marshal:   json.Marshal,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.batchSize < 1 {
+		m.batchSize = 1
+	}
+	return m
+}
+
+// Run copies all events of the source store that do not exist in the target
+// store to the target store, in the order of their time. If the Migrator has
+// a CheckpointStore, Run resumes from the last checkpoint. Run can be called
+// again after a failed run.
+func (m *Migrator) Run(ctx context.Context) (Result, error) {
+	var cp Checkpoint
+	if m.checkpoints != nil {
+		var err error
+		if cp, err = m.checkpoints.LoadCheckpoint(ctx); err != nil {
+			return Result{}, fmt.Errorf("load checkpoint: %w", err)
+		}
+	}
+
+	sum, err := decodeChecksum(cp.Checksum)
+	if err != nil {
+		return Result{}, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+
+	res := Result{Events: cp.Events}
+
+	q := query.New(query.SortByTime())
+	if !cp.Time.IsZero() {
+		q = query.New(query.Time(time.Min(cp.Time)), query.SortByTime())
+	}
+
+	str, errs, err := m.source.Query(ctx, q)
+	if err != nil {
+		return res, fmt.Errorf("query source store: %w", err)
+	}
+
+	batch := make([]event.Event, 0, m.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		start := stdtime.Now()
+
+		copied, err := m.insert(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		res.Copied += copied
+		res.Skipped += len(batch) - copied
+
+		last := batch[len(batch)-1].Time()
+		if !last.Equal(cp.Time) {
+			cp.Time = last
+			cp.IDs = nil
+		}
+		for _, evt := range batch {
+			if evt.Time().Equal(cp.Time) {
+				cp.IDs = append(cp.IDs, evt.ID())
+			}
+		}
+		cp.Events = res.Events
+		cp.Checksum = hex.EncodeToString(sum[:])
+
+		if m.checkpoints != nil {
+			if err := m.checkpoints.SaveCheckpoint(ctx, cp); err != nil {
+				return fmt.Errorf("save checkpoint: %w", err)
+			}
+		}
+
+		for _, fn := range m.onProgress {
+			fn(Progress{Events: res.Events, Copied: res.Copied, Skipped: res.Skipped, Time: cp.Time})
+		}
+
+		batch = batch[:0]
+
+		return m.wait(ctx, start, copied)
+	}
+
+	for {
+		if str == nil && errs == nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				break
+			}
+			return res, fmt.Errorf("event stream: %w", err)
+		case evt, ok := <-str:
+			if !ok {
+				str = nil
+				break
+			}
+
+			if evt.Time().Equal(cp.Time) && slices.Contains(cp.IDs, evt.ID()) {
+				continue
+			}
+
+			h, err := m.checksum(evt)
+			if err != nil {
+				return res, err
+			}
+			xor(&sum, h)
+			res.Events++
+
+			if batch = append(batch, evt); len(batch) >= m.batchSize {
+				if err := flush(); err != nil {
+					return res, err
+				}
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return res, err
+	}
+
+	res.Checksum = hex.EncodeToString(sum[:])
+
+	if m.sourceSnapshots != nil {
+		n, err := m.copySnapshots(ctx)
+		res.Snapshots = n
+		if err != nil {
+			return res, fmt.Errorf("copy snapshots: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+// insert inserts the events into the target store and returns the number of
+// inserted events. If the batch cannot be inserted at once, e.g. because some
+// of the events already exist, the events are inserted one by one and
+// existing events are skipped.
+func (m *Migrator) insert(ctx context.Context, events []event.Event) (int, error) {
+	if err := m.target.Insert(ctx, events...); err == nil {
+		return len(events), nil
+	}
+
+	var copied int
+	for _, evt := range events {
+		if _, err := m.target.Find(ctx, evt.ID()); err == nil {
+			continue
+		}
+		if err := m.target.Insert(ctx, evt); err != nil {
+			return copied, fmt.Errorf("insert %q event (ID=%s): %w", evt.Name(), evt.ID(), err)
+		}
+		copied++
+	}
+
+	return copied, nil
+}
+
+// wait blocks until the rate limit allows the next batch.
+func (m *Migrator) wait(ctx context.Context, start stdtime.Time, copied int) error {
+	if m.rate <= 0 || copied == 0 {
+		return nil
+	}
+
+	d := stdtime.Duration(copied)*stdtime.Second/stdtime.Duration(m.rate) - stdtime.Since(start)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := stdtime.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (m *Migrator) checksum(evt event.Event) ([sha256.Size]byte, error) {
+	data, err := m.marshal(evt.Data())
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("encode %q event data (ID=%s): %w", evt.Name(), evt.ID(), err)
+	}
+
+	id, name, v := evt.Aggregate()
+
+	// Times are compared with microsecond precision because not all stores
+	// persist nanoseconds.
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%s\x00%s\x00%d\x00", evt.ID(), evt.Name(), evt.Time().UnixMicro(), name, id, v)
+	h.Write(data)
+
+	var out [sha256.Size]byte
+	copy(out[:], h.Sum(nil))
+
+	return out, nil
+}
+
+func xor(sum *[sha256.Size]byte, h [sha256.Size]byte) {
+	for i := range sum {
+		sum[i] ^= h[i]
+	}
+}
+
+func decodeChecksum(s string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	if s == "" {
+		return sum, nil
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return sum, fmt.Errorf("decode checksum: %w", err)
+	}
+	if len(b) != sha256.Size {
+		return sum, fmt.Errorf("decode checksum: invalid length %d", len(b))
+	}
+	copy(sum[:], b)
+
+	return sum, nil
+}
+
+func snapshotChecksum(s snapshot.Snapshot) [sha256.Size]byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%d\x00", s.AggregateName(), s.AggregateID(), s.AggregateVersion(), s.Time().UnixMicro())
+	h.Write(s.State())
+
+	var out [sha256.Size]byte
+	copy(out[:], h.Sum(nil))
+
+	return out
+}
+
+// SnapshotRef references a snapshot of an aggregate.
+type SnapshotRef struct {
+	Name    string
+	ID      uuid.UUID
+	Version int
+}
+
+func refOf(s snapshot.Snapshot) SnapshotRef {
+	return SnapshotRef{Name: s.AggregateName(), ID: s.AggregateID(), Version: s.AggregateVersion()}
+}
@@ -0,0 +1,172 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/snapshot"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/eventstore/migrate"
+	"github.com/modernice/goes/event/test"
+)
+
+func TestMigrator_Run(t *testing.T) {
+	ctx := context.Background()
+
+	source := eventstore.New(makeEvents(10)...)
+	target := eventstore.New()
+
+	sourceSnaps := snapshot.NewStore()
+	targetSnaps := snapshot.NewStore()
+	snap, _ := snapshot.New(aggregate.New("foo", uuid.New(), aggregate.Version(3)), snapshot.Data([]byte("state")))
+	if err := sourceSnaps.Save(ctx, snap); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+
+	m := migrate.New(source, target, migrate.BatchSize(3), migrate.Snapshots(sourceSnaps, targetSnaps))
+
+	res, err := m.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	if res.Events != 10 || res.Copied != 10 || res.Snapshots != 1 {
+		t.Fatalf("Run should copy %d events and %d snapshot; got %+v", 10, 1, res)
+	}
+
+	report, err := m.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed with %q", err)
+	}
+
+	if !report.OK() {
+		t.Fatalf("Verify should report a successful migration; got %+v", report)
+	}
+
+	if report.SourceChecksum != res.Checksum {
+		t.Fatalf("checksum of Run should equal the source checksum of Verify")
+	}
+
+	if res, err = m.Run(ctx); err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	if res.Copied != 0 || res.Skipped != 10 || res.Snapshots != 0 {
+		t.Fatalf("a second Run should skip existing events and snapshots; got %+v", res)
+	}
+}
+
+func TestMigrator_Run_resume(t *testing.T) {
+	ctx := context.Background()
+
+	source := eventstore.New(makeEvents(10)...)
+	target := &failingStore{Store: eventstore.New(), failAfter: 4}
+	checkpoints := migrate.FileCheckpoints(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	m := migrate.New(source, target, migrate.BatchSize(2), migrate.Checkpoints(checkpoints))
+
+	if _, err := m.Run(ctx); !errors.Is(err, errMock) {
+		t.Fatalf("Run should fail with %q; got %q", errMock, err)
+	}
+
+	cp, err := checkpoints.LoadCheckpoint(ctx)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint failed with %q", err)
+	}
+
+	if cp.Events != 4 {
+		t.Fatalf("checkpoint should contain %d migrated events; got %d", 4, cp.Events)
+	}
+
+	target.failAfter = -1
+
+	res, err := m.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	if res.Events != 10 || res.Copied != 6 || res.Skipped != 0 {
+		t.Fatalf("resumed Run should only copy the remaining %d events; got %+v", 6, res)
+	}
+
+	report, err := m.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed with %q", err)
+	}
+
+	if !report.OK() || report.SourceChecksum != res.Checksum {
+		t.Fatalf("Verify should report a successful migration; got %+v", report)
+	}
+}
+
+func TestMigrator_Run_rateLimit(t *testing.T) {
+	m := migrate.New(eventstore.New(makeEvents(10)...), eventstore.New(), migrate.BatchSize(5), migrate.RateLimit(50))
+
+	start := time.Now()
+	if _, err := m.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	if dur := time.Since(start); dur < 180*time.Millisecond {
+		t.Fatalf("copying %d events at %d events/s should take at least %v; took %v", 10, 50, 200*time.Millisecond, dur)
+	}
+}
+
+func TestMigrator_Verify(t *testing.T) {
+	ctx := context.Background()
+
+	events := makeEvents(3)
+	changed := event.New("foo", test.FooEventData{A: "changed"}, event.ID(events[1].ID()), event.Time(events[1].Time())).Any()
+
+	m := migrate.New(eventstore.New(events...), eventstore.New(events[0], changed))
+
+	report, err := m.Verify(ctx)
+	if err != nil {
+		t.Fatalf("Verify failed with %q", err)
+	}
+
+	if report.OK() {
+		t.Fatalf("Verify should report a failed migration")
+	}
+
+	if len(report.Missing) != 1 || report.Missing[0] != events[2].ID() {
+		t.Fatalf("Verify should report the missing event; got %v", report.Missing)
+	}
+
+	if len(report.Mismatched) != 1 || report.Mismatched[0] != events[1].ID() {
+		t.Fatalf("Verify should report the mismatched event; got %v", report.Mismatched)
+	}
+}
+
+var errMock = errors.New("mock error")
+
+// failingStore fails to insert events after failAfter events were inserted.
+type failingStore struct {
+	event.Store
+
+	failAfter int
+	inserted  int
+}
+
+func (s *failingStore) Insert(ctx context.Context, events ...event.Event) error {
+	if s.failAfter >= 0 && s.inserted+len(events) > s.failAfter {
+		return errMock
+	}
+	s.inserted += len(events)
+	return s.Store.Insert(ctx, events...)
+}
+
+func makeEvents(n int) []event.Event {
+	now := time.Now()
+	events := make([]event.Event, n)
+	for i := range events {
+		events[i] = event.New("foo", test.FooEventData{A: "foo"}, event.Time(now.Add(time.Duration(i)*time.Millisecond))).Any()
+	}
+	return events
+}
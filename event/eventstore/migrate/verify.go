@@ -0,0 +1,159 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate/snapshot"
+	squery "github.com/modernice/goes/aggregate/snapshot/query"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// Report is the result of a verification of a migration.
+type Report struct {
+	// Events is the number of events in the source store.
+	Events int
+
+	// Snapshots is the number of snapshots in the source snapshot store.
+	Snapshots int
+
+	// Missing are the IDs of the events that do not exist in the target store.
+	Missing []uuid.UUID
+
+	// Mismatched are the IDs of the events whose checksums differ between the
+	// source and target store.
+	Mismatched []uuid.UUID
+
+	// MissingSnapshots are the snapshots that do not exist in the target
+	// snapshot store.
+	MissingSnapshots []SnapshotRef
+
+	// MismatchedSnapshots are the snapshots whose checksums differ between the
+	// source and target snapshot store.
+	MismatchedSnapshots []SnapshotRef
+
+	// SourceChecksum is the checksum of the events in the source store.
+	SourceChecksum string
+
+	// TargetChecksum is the checksum of the events in the target store that
+	// also exist in the source store.
+	TargetChecksum string
+}
+
+// OK reports whether every event and snapshot of the source store exists
+// unchanged in the target store.
+func (r Report) OK() bool {
+	return len(r.Missing) == 0 &&
+		len(r.Mismatched) == 0 &&
+		len(r.MissingSnapshots) == 0 &&
+		len(r.MismatchedSnapshots) == 0 &&
+		r.SourceChecksum == r.TargetChecksum
+}
+
+// Verify compares every event of the source store with the event that has the
+// same ID in the target store and reports missing and mismatched events. If
+// the Migrator copies snapshots, snapshots are verified as well. Events that
+// only exist in the target store are not reported, so Verify can be used while
+// new events are mirrored to both stores.
+func (m *Migrator) Verify(ctx context.Context) (Report, error) {
+	var report Report
+
+	str, errs, err := m.source.Query(ctx, query.New(query.SortByTime()))
+	if err != nil {
+		return report, fmt.Errorf("query source store: %w", err)
+	}
+
+	var sourceSum, targetSum [sha256.Size]byte
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		report.Events++
+
+		h, err := m.checksum(evt)
+		if err != nil {
+			return err
+		}
+		xor(&sourceSum, h)
+
+		copied, err := m.target.Find(ctx, evt.ID())
+		if err != nil {
+			report.Missing = append(report.Missing, evt.ID())
+			return nil
+		}
+
+		th, err := m.checksum(copied)
+		if err != nil {
+			return err
+		}
+		xor(&targetSum, th)
+
+		if th != h {
+			report.Mismatched = append(report.Mismatched, evt.ID())
+		}
+
+		return nil
+	}, str, errs); err != nil {
+		return report, fmt.Errorf("verify events: %w", err)
+	}
+
+	report.SourceChecksum = hex.EncodeToString(sourceSum[:])
+	report.TargetChecksum = hex.EncodeToString(targetSum[:])
+
+	if m.sourceSnapshots != nil {
+		if err := m.verifySnapshots(ctx, &report); err != nil {
+			return report, fmt.Errorf("verify snapshots: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+func (m *Migrator) verifySnapshots(ctx context.Context, report *Report) error {
+	str, errs, err := m.sourceSnapshots.Query(ctx, squery.New())
+	if err != nil {
+		return fmt.Errorf("query source snapshots: %w", err)
+	}
+
+	return streams.Walk(ctx, func(snap snapshot.Snapshot) error {
+		report.Snapshots++
+
+		copied, err := m.targetSnapshots.Version(ctx, snap.AggregateName(), snap.AggregateID(), snap.AggregateVersion())
+		if err != nil {
+			report.MissingSnapshots = append(report.MissingSnapshots, refOf(snap))
+			return nil
+		}
+
+		if snapshotChecksum(copied) != snapshotChecksum(snap) {
+			report.MismatchedSnapshots = append(report.MismatchedSnapshots, refOf(snap))
+		}
+
+		return nil
+	}, str, errs)
+}
+
+func (m *Migrator) copySnapshots(ctx context.Context) (int, error) {
+	str, errs, err := m.sourceSnapshots.Query(ctx, squery.New())
+	if err != nil {
+		return 0, fmt.Errorf("query source snapshots: %w", err)
+	}
+
+	var copied int
+	err = streams.Walk(ctx, func(snap snapshot.Snapshot) error {
+		if existing, err := m.targetSnapshots.Version(ctx, snap.AggregateName(), snap.AggregateID(), snap.AggregateVersion()); err == nil &&
+			snapshotChecksum(existing) == snapshotChecksum(snap) {
+			return nil
+		}
+
+		if err := m.targetSnapshots.Save(ctx, snap); err != nil {
+			return fmt.Errorf("save snapshot of %s(%s)@%d: %w", snap.AggregateName(), snap.AggregateID(), snap.AggregateVersion(), err)
+		}
+		copied++
+
+		return nil
+	}, str, errs)
+
+	return copied, err
+}
@@ -0,0 +1,451 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/internal/concurrent"
+)
+
+// DefaultMirrorRetryInterval is the default interval after which a failed
+// mirror operation is retried.
+const DefaultMirrorRetryInterval = time.Second
+
+// ErrMirrorRunning is returned by MirrorStore.Run if the mirror is already
+// running.
+var ErrMirrorRunning = errors.New("mirror is already running")
+
+// MirrorStore is an event store that writes to a primary store and
+// asynchronously mirrors all writes to one or more secondary stores. Reads are
+// always served by the primary store.
+//
+// A MirrorStore can be used to migrate between event store backends without
+// downtime: Start by mirroring the old store (primary) to the new store
+// (secondary), call CatchUp to copy the existing events, wait until Lag reports
+// no pending events, then switch the roles of the stores.
+//
+//	var mongoStore, postgresStore event.Store
+//	store := eventstore.Mirror(mongoStore, []event.Store{postgresStore})
+//	errs, err := store.Run(context.TODO())
+//	// handle err and errs
+//
+// Secondary stores are written to in the same order as the primary store.
+// Failed writes are retried until they succeed or the MirrorStore is stopped.
+// Mirror errors are reported to the error channel returned by Run.
+//
+// Pending writes are queued in memory, so they are lost if the process
+// crashes or is stopped before the writes have been mirrored. The primary
+// store remains the source of truth: after a restart, call CatchUp to
+// reconcile the secondary stores with the primary store. Use MirrorQueueSize
+// to limit the number of pending writes.
+type MirrorStore struct {
+	event.Store
+
+	retryInterval time.Duration
+	queueSize     int
+	mirrors       []*mirror
+
+	runMux  sync.Mutex
+	running bool
+}
+
+// MirrorOption is an option for a MirrorStore.
+type MirrorOption func(*MirrorStore)
+
+// MirrorLag reports the replication lag of a secondary store.
+type MirrorLag struct {
+	// Store is the secondary store.
+	Store event.Store
+
+	// Pending is the number of events that have not yet been mirrored to the
+	// store, including events that are pending deletion.
+	Pending int
+
+	// Since is the time at which the oldest pending write has been made to the
+	// primary store. Since is the zero time if there are no pending events.
+	Since time.Time
+
+	// LastError is the last error that occurred while mirroring to the store.
+	// LastError is reset after the next successful write.
+	LastError error
+}
+
+type mirror struct {
+	store event.Store
+
+	mux     sync.Mutex
+	ops     []mirrorOp
+	lastErr error
+	signal  chan struct{}
+
+	// slots limits the number of pending operations if a queue size is
+	// configured; otherwise slots is nil.
+	slots chan struct{}
+}
+
+type mirrorOp struct {
+	delete bool
+	events []event.Event
+	time   time.Time
+}
+
+// MirrorRetryInterval returns a MirrorOption that configures the interval
+// after which a failed mirror operation is retried. Defaults to
+// DefaultMirrorRetryInterval.
+func MirrorRetryInterval(d time.Duration) MirrorOption {
+	return func(s *MirrorStore) {
+		s.retryInterval = d
+	}
+}
+
+// MirrorQueueSize returns a MirrorOption that limits the number of pending
+// writes per secondary store. If the queue of a secondary store is full,
+// Insert and Delete block until the pending writes have been mirrored or the
+// context is canceled, before writing to the primary store. By default, the
+// queue is unbounded.
+func MirrorQueueSize(size int) MirrorOption {
+	return func(s *MirrorStore) {
+		s.queueSize = size
+	}
+}
+
+// Mirror returns a MirrorStore that writes to the primary store and mirrors
+// the writes to the provided secondary stores. Writes are buffered in memory
+// until the MirrorStore is started using Run.
+func Mirror(primary event.Store, secondaries []event.Store, opts ...MirrorOption) *MirrorStore {
+	s := &MirrorStore{
+		Store:         primary,
+		retryInterval: DefaultMirrorRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, secondary := range secondaries {
+		m := &mirror{
+			store:  secondary,
+			signal: make(chan struct{}, 1),
+		}
+		if s.queueSize > 0 {
+			m.slots = make(chan struct{}, s.queueSize)
+		}
+		s.mirrors = append(s.mirrors, m)
+	}
+	return s
+}
+
+// Primary returns the primary store.
+func (s *MirrorStore) Primary() event.Store {
+	return s.Store
+}
+
+// Insert inserts the events into the primary store and enqueues them for
+// mirroring to the secondary stores.
+func (s *MirrorStore) Insert(ctx context.Context, events ...event.Event) error {
+	if len(events) == 0 {
+		return s.Store.Insert(ctx)
+	}
+	if err := s.reserve(ctx); err != nil {
+		return err
+	}
+	if err := s.Store.Insert(ctx, events...); err != nil {
+		s.release()
+		return err
+	}
+	s.enqueue(mirrorOp{events: events, time: time.Now()})
+	return nil
+}
+
+// Delete deletes the events from the primary store and enqueues the deletion
+// for the secondary stores.
+func (s *MirrorStore) Delete(ctx context.Context, events ...event.Event) error {
+	if len(events) == 0 {
+		return s.Store.Delete(ctx)
+	}
+	if err := s.reserve(ctx); err != nil {
+		return err
+	}
+	if err := s.Store.Delete(ctx, events...); err != nil {
+		s.release()
+		return err
+	}
+	s.enqueue(mirrorOp{delete: true, events: events, time: time.Now()})
+	return nil
+}
+
+// reserve reserves a slot in the queue of each secondary store, blocking
+// until the slots are available or ctx is canceled.
+func (s *MirrorStore) reserve(ctx context.Context) error {
+	for i, m := range s.mirrors {
+		if m.slots == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			for _, m := range s.mirrors[:i] {
+				m.release()
+			}
+			return fmt.Errorf("wait for mirror queue: %w", ctx.Err())
+		case m.slots <- struct{}{}:
+		}
+	}
+	return nil
+}
+
+func (s *MirrorStore) release() {
+	for _, m := range s.mirrors {
+		m.release()
+	}
+}
+
+func (s *MirrorStore) enqueue(op mirrorOp) {
+	for _, m := range s.mirrors {
+		m.mux.Lock()
+		m.ops = append(m.ops, op)
+		m.mux.Unlock()
+		m.notify()
+	}
+}
+
+// Run starts mirroring writes to the secondary stores until ctx is canceled.
+// Errors that occur while mirroring are sent into the returned channel.
+// Callers must receive from the channel to prevent the mirror from blocking.
+func (s *MirrorStore) Run(ctx context.Context) (<-chan error, error) {
+	s.runMux.Lock()
+	defer s.runMux.Unlock()
+
+	if s.running {
+		return nil, ErrMirrorRunning
+	}
+	s.running = true
+
+	errs, fail := concurrent.Errors(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(len(s.mirrors))
+	for _, m := range s.mirrors {
+		go func(m *mirror) {
+			defer wg.Done()
+			m.work(ctx, s.retryInterval, fail)
+		}(m)
+	}
+
+	go func() {
+		wg.Wait()
+		s.runMux.Lock()
+		defer s.runMux.Unlock()
+		s.running = false
+	}()
+
+	return errs, nil
+}
+
+// Lag returns the replication lag of each secondary store, in the order the
+// secondary stores were provided to Mirror.
+func (s *MirrorStore) Lag() []MirrorLag {
+	out := make([]MirrorLag, len(s.mirrors))
+	for i, m := range s.mirrors {
+		m.mux.Lock()
+		lag := MirrorLag{Store: m.store, LastError: m.lastErr}
+		for _, op := range m.ops {
+			lag.Pending += len(op.events)
+		}
+		if len(m.ops) > 0 {
+			lag.Since = m.ops[0].time
+		}
+		m.mux.Unlock()
+		out[i] = lag
+	}
+	return out
+}
+
+// CatchUp reconciles the secondary stores with the primary store: events that
+// exist in the primary store but not in a secondary store are copied to the
+// secondary store, and events that exist in a secondary store but not in the
+// primary store are deleted from the secondary store. Use CatchUp to initially
+// fill a new secondary store with the existing events of the primary store,
+// and to recover the writes that were pending when the process stopped.
+// Writes that happen during CatchUp are mirrored as usual.
+func (s *MirrorStore) CatchUp(ctx context.Context) error {
+	str, errs, err := s.Store.Query(ctx, query.New(query.SortByTime()))
+	if err != nil {
+		return fmt.Errorf("query primary store: %w", err)
+	}
+
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		for _, m := range s.mirrors {
+			found, err := contains(ctx, m.store, evt)
+			if err != nil {
+				return fmt.Errorf("find %q event in secondary store: %w", evt.Name(), err)
+			}
+			if found {
+				continue
+			}
+			if err := m.store.Insert(ctx, evt); err != nil {
+				return fmt.Errorf("insert %q event into secondary store: %w", evt.Name(), err)
+			}
+		}
+		return nil
+	}, str, errs); err != nil {
+		return err
+	}
+
+	for _, m := range s.mirrors {
+		if err := s.pruneSecondary(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pruneSecondary deletes the events from the secondary store that do not
+// exist in the primary store.
+func (s *MirrorStore) pruneSecondary(ctx context.Context, m *mirror) error {
+	str, errs, err := m.store.Query(ctx, query.New(query.SortByTime()))
+	if err != nil {
+		return fmt.Errorf("query secondary store: %w", err)
+	}
+
+	var stale []event.Event
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		found, err := contains(ctx, s.Store, evt)
+		if err != nil {
+			return fmt.Errorf("find %q event in primary store: %w", evt.Name(), err)
+		}
+		if !found {
+			stale = append(stale, evt)
+		}
+		return nil
+	}, str, errs); err != nil {
+		return err
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	if err := m.store.Delete(ctx, stale...); err != nil {
+		return fmt.Errorf("delete %d events from secondary store: %w", len(stale), err)
+	}
+
+	return nil
+}
+
+// contains reports whether the store contains the event. Errors other than
+// event.ErrNotFound are returned.
+func contains(ctx context.Context, store event.Store, evt event.Event) (bool, error) {
+	if _, err := store.Find(ctx, evt.ID()); err != nil {
+		if errors.Is(err, event.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *mirror) release() {
+	if m.slots != nil {
+		<-m.slots
+	}
+}
+
+func (m *mirror) notify() {
+	select {
+	case m.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (m *mirror) work(ctx context.Context, retryInterval time.Duration, fail func(error)) {
+	var retry <-chan time.Time
+	for {
+		// while waiting for a retry, new writes are only enqueued
+		if retry == nil {
+			retry = m.flush(ctx, retryInterval, fail)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.signal:
+		case <-retry:
+			retry = nil
+		}
+	}
+}
+
+// flush applies the pending operations in order. If an operation fails, flush
+// returns a channel that fires when the operation should be retried.
+func (m *mirror) flush(ctx context.Context, retryInterval time.Duration, fail func(error)) <-chan time.Time {
+	for {
+		m.mux.Lock()
+		if len(m.ops) == 0 {
+			m.mux.Unlock()
+			return nil
+		}
+		op := m.ops[0]
+		m.mux.Unlock()
+
+		if err := m.apply(ctx, op); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			m.mux.Lock()
+			m.lastErr = err
+			m.mux.Unlock()
+
+			fail(err)
+
+			return time.After(retryInterval)
+		}
+
+		m.mux.Lock()
+		m.ops = m.ops[1:]
+		m.lastErr = nil
+		m.mux.Unlock()
+		m.release()
+	}
+}
+
+func (m *mirror) apply(ctx context.Context, op mirrorOp) error {
+	if op.delete {
+		if err := m.store.Delete(ctx, op.events...); err != nil {
+			return fmt.Errorf("mirror deletion of %d events: %w", len(op.events), err)
+		}
+		return nil
+	}
+
+	// Only insert the events that have not yet been inserted, in case a
+	// previous attempt partially succeeded or CatchUp already copied them.
+	missing := make([]event.Event, 0, len(op.events))
+	for _, evt := range op.events {
+		found, err := contains(ctx, m.store, evt)
+		if err != nil {
+			return fmt.Errorf("find %q event in secondary store: %w", evt.Name(), err)
+		}
+		if !found {
+			missing = append(missing, evt)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if err := m.store.Insert(ctx, missing...); err != nil {
+		return fmt.Errorf("mirror insertion of %d events: %w", len(missing), err)
+	}
+
+	return nil
+}
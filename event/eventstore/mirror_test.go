@@ -0,0 +1,236 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+)
+
+func TestMirror(t *testing.T) {
+	primary := eventstore.New()
+	secondary := eventstore.New()
+	store := eventstore.Mirror(primary, []event.Store{secondary})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	evt := event.New("foo", test.FooEventData{}).Any()
+	if err := store.Insert(ctx, evt); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	if lag := store.Lag()[0]; lag.Pending != 1 || lag.Since.IsZero() {
+		t.Fatalf("Lag() should report 1 pending event; got %+v", lag)
+	}
+
+	if _, err := secondary.Find(ctx, evt.ID()); err == nil {
+		t.Fatalf("event should not be mirrored before the mirror is started")
+	}
+
+	errs, err := store.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed with %q", err)
+	}
+	go func() {
+		for err := range errs {
+			t.Errorf("mirror error: %v", err)
+		}
+	}()
+
+	awaitLag(t, store)
+
+	if _, err := secondary.Find(ctx, evt.ID()); err != nil {
+		t.Fatalf("event should have been mirrored to secondary store; Find() failed with %q", err)
+	}
+
+	if err := store.Delete(ctx, evt); err != nil {
+		t.Fatalf("Delete() failed with %q", err)
+	}
+
+	awaitLag(t, store)
+
+	if _, err := secondary.Find(ctx, evt.ID()); err == nil {
+		t.Fatalf("event should have been deleted from secondary store")
+	}
+}
+
+func TestMirror_CatchUp(t *testing.T) {
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}).Any(),
+		event.New("foo", test.FooEventData{}).Any(),
+	}
+	primary := eventstore.New(events...)
+	secondary := eventstore.New(events[0])
+	store := eventstore.Mirror(primary, []event.Store{secondary})
+
+	if err := store.CatchUp(context.Background()); err != nil {
+		t.Fatalf("CatchUp() failed with %q", err)
+	}
+
+	for _, evt := range events {
+		if _, err := secondary.Find(context.Background(), evt.ID()); err != nil {
+			t.Fatalf("%q event should have been copied to secondary store", evt.ID())
+		}
+	}
+}
+
+func TestMirror_CatchUp_deletesStaleEvents(t *testing.T) {
+	kept := event.New("foo", test.FooEventData{}).Any()
+	stale := event.New("foo", test.FooEventData{}).Any()
+	primary := eventstore.New(kept)
+	secondary := eventstore.New(kept, stale)
+	store := eventstore.Mirror(primary, []event.Store{secondary})
+
+	if err := store.CatchUp(context.Background()); err != nil {
+		t.Fatalf("CatchUp() failed with %q", err)
+	}
+
+	if _, err := secondary.Find(context.Background(), kept.ID()); err != nil {
+		t.Fatalf("%q event should not have been deleted from secondary store", kept.ID())
+	}
+
+	if _, err := secondary.Find(context.Background(), stale.ID()); !errors.Is(err, event.ErrNotFound) {
+		t.Fatalf("%q event should have been deleted from secondary store; Find() returned %v", stale.ID(), err)
+	}
+}
+
+func TestMirror_CatchUp_findError(t *testing.T) {
+	primary := eventstore.New(event.New("foo", test.FooEventData{}).Any())
+	secondary := &unavailableStore{Store: eventstore.New()}
+	store := eventstore.Mirror(primary, []event.Store{secondary})
+
+	if err := store.CatchUp(context.Background()); !errors.Is(err, errUnavailable) {
+		t.Fatalf("CatchUp() should fail with %q; got %v", errUnavailable, err)
+	}
+
+	if secondary.inserted {
+		t.Fatalf("CatchUp() should not insert events if the secondary store cannot be read")
+	}
+}
+
+func TestMirrorQueueSize(t *testing.T) {
+	store := eventstore.Mirror(eventstore.New(), []event.Store{eventstore.New()}, eventstore.MirrorQueueSize(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := store.Insert(ctx, event.New("foo", test.FooEventData{}).Any()); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	blocked := event.New("foo", test.FooEventData{}).Any()
+	timeoutCtx, cancelTimeout := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelTimeout()
+
+	if err := store.Insert(timeoutCtx, blocked); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Insert() should block while the mirror queue is full; got %v", err)
+	}
+
+	if _, err := store.Find(ctx, blocked.ID()); !errors.Is(err, event.ErrNotFound) {
+		t.Fatalf("event should not be inserted into primary store while the mirror queue is full")
+	}
+
+	errs, err := store.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed with %q", err)
+	}
+	go func() {
+		for err := range errs {
+			t.Errorf("mirror error: %v", err)
+		}
+	}()
+
+	if err := store.Insert(ctx, blocked); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	awaitLag(t, store)
+}
+
+func TestMirror_retry(t *testing.T) {
+	secondary := &failingStore{Store: eventstore.New(), failures: 2}
+	store := eventstore.Mirror(eventstore.New(), []event.Store{secondary}, eventstore.MirrorRetryInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs, err := store.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed with %q", err)
+	}
+
+	evt := event.New("foo", test.FooEventData{}).Any()
+	if err := store.Insert(ctx, evt); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("expected mirror error")
+		case err := <-errs:
+			if !errors.Is(err, errInsertFailed) {
+				t.Fatalf("expected mirror error to be %q; got %q", errInsertFailed, err)
+			}
+		}
+	}
+
+	awaitLag(t, store)
+
+	if _, err := secondary.Find(ctx, evt.ID()); err != nil {
+		t.Fatalf("event should have been mirrored after retry")
+	}
+}
+
+var errInsertFailed = errors.New("insert failed")
+
+type failingStore struct {
+	event.Store
+	failures int
+}
+
+func (s *failingStore) Insert(ctx context.Context, events ...event.Event) error {
+	if s.failures > 0 {
+		s.failures--
+		return errInsertFailed
+	}
+	return s.Store.Insert(ctx, events...)
+}
+
+var errUnavailable = errors.New("store unavailable")
+
+type unavailableStore struct {
+	event.Store
+	inserted bool
+}
+
+func (s *unavailableStore) Find(context.Context, uuid.UUID) (event.Event, error) {
+	return nil, errUnavailable
+}
+
+func (s *unavailableStore) Insert(ctx context.Context, events ...event.Event) error {
+	s.inserted = true
+	return s.Store.Insert(ctx, events...)
+}
+
+func awaitLag(t *testing.T, store *eventstore.MirrorStore) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		if store.Lag()[0].Pending == 0 {
+			return
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("mirror did not catch up; lag is %+v", store.Lag()[0])
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
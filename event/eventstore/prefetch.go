@@ -0,0 +1,246 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/event"
+)
+
+// StreamStats are the memory stats of a query stream of a PrefetchStore.
+// Sizes of events are estimated using the size function of the store (see
+// PrefetchSize).
+type StreamStats struct {
+	// Query is the query of the stream.
+	Query event.Query
+
+	// Started is the time at which the stream was started.
+	Started time.Time
+
+	// Duration is the duration of the stream. For active streams, Duration is
+	// the duration since the stream was started.
+	Duration time.Duration
+
+	// Events is the number of events that were received by the consumer of
+	// the stream.
+	Events int
+
+	// Bytes is the estimated size of the events that were received by the
+	// consumer of the stream.
+	Bytes int64
+
+	// Buffered is the number of events that are currently buffered.
+	Buffered int
+
+	// BufferedBytes is the estimated size of the events that are currently
+	// buffered.
+	BufferedBytes int64
+
+	// PeakBuffered is the highest number of events that were buffered at the
+	// same time.
+	PeakBuffered int
+
+	// PeakBufferedBytes is the highest estimated size of the events that were
+	// buffered at the same time.
+	PeakBufferedBytes int64
+}
+
+// PrefetchStore is an event store that reads ahead the results of queries.
+// Use WithPrefetch to create a PrefetchStore.
+type PrefetchStore struct {
+	event.Store
+
+	prefetch int
+	maxBytes int64
+	size     func(event.Event) int64
+	onStream []func(StreamStats)
+
+	mux     sync.Mutex
+	streams map[*prefetchStream]struct{}
+}
+
+// PrefetchOption is an option for a PrefetchStore.
+type PrefetchOption func(*PrefetchStore)
+
+type prefetchStream struct {
+	mux   sync.Mutex
+	stats StreamStats
+}
+
+// MaxPrefetchBytes returns a PrefetchOption that limits the estimated size of
+// the events that are buffered per stream. A stream stops reading ahead once
+// the estimated size of its buffered events reaches the limit, and always
+// buffers at least a single event. A limit <= 0 disables the limit.
+func MaxPrefetchBytes(limit int64) PrefetchOption {
+	return func(s *PrefetchStore) {
+		s.maxBytes = limit
+	}
+}
+
+// PrefetchSize returns a PrefetchOption that sets the function that estimates
+// the size of an event. The default function returns the length of the event
+// name plus the length of the JSON-encoded event data, which requires encoding
+// the data of every streamed event.
+func PrefetchSize(fn func(event.Event) int64) PrefetchOption {
+	return func(s *PrefetchStore) {
+		s.size = fn
+	}
+}
+
+// OnStream returns a PrefetchOption that calls fn with the final stats of every
+// query stream when the stream is closed. Use OnStream to monitor the memory
+// usage and throughput of large replays.
+func OnStream(fn func(StreamStats)) PrefetchOption {
+	return func(s *PrefetchStore) {
+		s.onStream = append(s.onStream, fn)
+	}
+}
+
+// WithPrefetch decorates the given event store to read ahead up to n events of
+// every query result. Event stores typically stream the results of a query
+// while the consumer processes the events, so that a slow consumer also slows
+// down reading from the database. Prefetching decouples the consumer from the
+// database, trading memory for throughput:
+//
+//	store := eventstore.WithPrefetch(mongoStore, 1000,
+//		eventstore.MaxPrefetchBytes(64<<20), // at most 64 MiB per stream
+//		eventstore.OnStream(func(stats eventstore.StreamStats) {
+//			log.Printf("streamed %d events, peak buffer %d bytes", stats.Events, stats.PeakBufferedBytes)
+//		}),
+//	)
+//
+// The memory that is used by the streams of the store is tracked per stream and
+// can be monitored using Streams. Combine WithPrefetch with the batch size
+// option of the underlying event store (e.g. mongo.QueryBatchSize) to tune how
+// many events are fetched from the database per round trip.
+func WithPrefetch(store event.Store, n int, opts ...PrefetchOption) *PrefetchStore {
+	s := &PrefetchStore{
+		Store:    store,
+		prefetch: n,
+		size:     jsonSize,
+		streams:  make(map[*prefetchStream]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.prefetch <= 0 {
+		s.prefetch = 1
+	}
+	return s
+}
+
+// Streams returns the stats of the currently active query streams.
+func (s *PrefetchStore) Streams() []StreamStats {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	now := time.Now()
+	out := make([]StreamStats, 0, len(s.streams))
+	for str := range s.streams {
+		str.mux.Lock()
+		stats := str.stats
+		str.mux.Unlock()
+		stats.Duration = now.Sub(stats.Started)
+		out = append(out, stats)
+	}
+
+	return out
+}
+
+// Query queries the underlying event store and reads ahead the events of the
+// result.
+func (s *PrefetchStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	events, errs, err := s.Store.Query(ctx, q)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query events: %w", err)
+	}
+
+	str := &prefetchStream{stats: StreamStats{Query: q, Started: time.Now()}}
+
+	s.mux.Lock()
+	s.streams[str] = struct{}{}
+	s.mux.Unlock()
+
+	out := make(chan event.Event)
+
+	go func() {
+		defer s.finish(str)
+		defer close(out)
+
+		type buffered struct {
+			evt  event.Event
+			size int64
+		}
+
+		var buf []buffered
+		var bufBytes int64
+
+		for events != nil || len(buf) > 0 {
+			var recv <-chan event.Event
+			if events != nil && len(buf) < s.prefetch && (s.maxBytes <= 0 || len(buf) == 0 || bufBytes < s.maxBytes) {
+				recv = events
+			}
+
+			var send chan<- event.Event
+			var next buffered
+			if len(buf) > 0 {
+				send = out
+				next = buf[0]
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-recv:
+				if !ok {
+					events = nil
+					break
+				}
+				size := s.size(evt)
+				buf = append(buf, buffered{evt: evt, size: size})
+				bufBytes += size
+				str.update(func(stats *StreamStats) {
+					stats.Buffered = len(buf)
+					stats.BufferedBytes = bufBytes
+					stats.PeakBuffered = max(stats.PeakBuffered, len(buf))
+					stats.PeakBufferedBytes = max(stats.PeakBufferedBytes, bufBytes)
+				})
+			case send <- next.evt:
+				buf[0] = buffered{}
+				buf = buf[1:]
+				bufBytes -= next.size
+				str.update(func(stats *StreamStats) {
+					stats.Events++
+					stats.Bytes += next.size
+					stats.Buffered = len(buf)
+					stats.BufferedBytes = bufBytes
+				})
+			}
+		}
+	}()
+
+	return out, errs, nil
+}
+
+func (s *PrefetchStore) finish(str *prefetchStream) {
+	s.mux.Lock()
+	delete(s.streams, str)
+	s.mux.Unlock()
+
+	str.mux.Lock()
+	stats := str.stats
+	str.mux.Unlock()
+	stats.Duration = time.Since(stats.Started)
+
+	for _, fn := range s.onStream {
+		fn(stats)
+	}
+}
+
+func (str *prefetchStream) update(fn func(*StreamStats)) {
+	str.mux.Lock()
+	defer str.mux.Unlock()
+	fn(&str.stats)
+}
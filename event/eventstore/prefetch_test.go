@@ -0,0 +1,113 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestWithPrefetch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make([]event.Event, 10)
+	now := time.Now()
+	for i := range events {
+		events[i] = event.New("foo", test.FooEventData{}, event.Time(now.Add(time.Duration(i)*time.Second))).Any()
+	}
+
+	finished := make(chan eventstore.StreamStats, 1)
+	store := eventstore.WithPrefetch(
+		eventstore.New(events...),
+		3,
+		eventstore.PrefetchSize(func(event.Event) int64 { return 10 }),
+		eventstore.OnStream(func(stats eventstore.StreamStats) { finished <- stats }),
+	)
+
+	str, errs, err := store.Query(ctx, query.New(query.SortByTime()))
+	if err != nil {
+		t.Fatalf("Query failed with %q", err)
+	}
+
+	awaitBuffered(t, store, 3)
+
+	got, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		t.Fatalf("drain events: %v", err)
+	}
+
+	if len(got) != len(events) {
+		t.Fatalf("query should return %d events; got %d", len(events), len(got))
+	}
+
+	for i, evt := range got {
+		if evt.ID() != events[i].ID() {
+			t.Fatalf("events should be returned in order; got %v at index %d", evt.ID(), i)
+		}
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("stream stats should be reported")
+	case stats := <-finished:
+		if stats.Events != 10 || stats.Bytes != 100 || stats.PeakBuffered != 3 || stats.PeakBufferedBytes != 30 || stats.Buffered != 0 {
+			t.Fatalf("unexpected stream stats: %+v", stats)
+		}
+	}
+
+	if active := store.Streams(); len(active) != 0 {
+		t.Fatalf("finished streams should not be active; got %v", active)
+	}
+}
+
+func TestMaxPrefetchBytes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make([]event.Event, 10)
+	for i := range events {
+		events[i] = event.New("foo", test.FooEventData{}).Any()
+	}
+
+	store := eventstore.WithPrefetch(
+		eventstore.New(events...),
+		100,
+		eventstore.MaxPrefetchBytes(20),
+		eventstore.PrefetchSize(func(event.Event) int64 { return 10 }),
+	)
+
+	if _, _, err := store.Query(ctx, query.New()); err != nil {
+		t.Fatalf("Query failed with %q", err)
+	}
+
+	awaitBuffered(t, store, 2)
+
+	<-time.After(50 * time.Millisecond)
+
+	if stats := store.Streams(); stats[0].Buffered != 2 || stats[0].BufferedBytes != 20 {
+		t.Fatalf("stream should stop reading ahead at %d bytes; got %+v", 20, stats[0])
+	}
+}
+
+func awaitBuffered(t *testing.T, store *eventstore.PrefetchStore, n int) {
+	t.Helper()
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case <-deadline:
+			t.Fatalf("stream should buffer %d events; got %+v", n, store.Streams())
+		case <-time.After(5 * time.Millisecond):
+		}
+
+		if stats := store.Streams(); len(stats) == 1 && stats[0].Buffered == n {
+			return
+		}
+	}
+}
@@ -0,0 +1,525 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// ErrQuotaExceeded is returned by a QuotaStore if inserting events would
+// exceed the quota of a tenant.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+const (
+	// EventsPerDayLimit is the QuotaLimit of Quota.EventsPerDay.
+	EventsPerDayLimit = QuotaLimit("events/day")
+
+	// StorageLimit is the QuotaLimit of Quota.Storage.
+	StorageLimit = QuotaLimit("storage")
+)
+
+// QuotaLimit is a limit of a Quota.
+type QuotaLimit string
+
+// Quota limits the events of a tenant. A zero limit means no limit.
+type Quota struct {
+	// EventsPerDay is the maximum number of events that can be inserted per
+	// day (UTC).
+	EventsPerDay int64
+
+	// Storage is the maximum total size of the events in bytes.
+	Storage int64
+}
+
+// QuotaUsage is the quota usage of a tenant.
+type QuotaUsage struct {
+	// Tenant is the tenant.
+	Tenant string
+
+	// Quota is the quota of the tenant.
+	Quota Quota
+
+	// Day is the start of the current day (UTC).
+	Day time.Time
+
+	// Events is the number of events that were inserted on Day.
+	Events int64
+
+	// Storage is the total size of the events of the tenant in bytes.
+	Storage int64
+}
+
+// QuotaExceededError is returned by a QuotaStore if inserting events would
+// exceed the quota of a tenant. QuotaExceededError unwraps to ErrQuotaExceeded.
+type QuotaExceededError struct {
+	// Tenant is the tenant whose quota would be exceeded.
+	Tenant string
+
+	// Limit is the limit that would be exceeded.
+	Limit QuotaLimit
+
+	// Quota is the value of the limit.
+	Quota int64
+
+	// Usage is the usage of the limit before the insert.
+	Usage int64
+
+	// Requested is the usage that was requested by the insert.
+	Requested int64
+}
+
+// QuotaStore is an event store that enforces quotas per tenant. Use WithQuotas
+// to create a QuotaStore. A QuotaStore implements Locker by forwarding to the
+// underlying store.
+type QuotaStore struct {
+	event.Store
+	lockForwarder
+
+	tenant       func(event.Event) string
+	quotas       map[string]Quota
+	defaultQuota Quota
+	size         func(event.Event) int64
+	clock        event.Clock
+
+	syncMux sync.Mutex
+
+	mux      sync.Mutex
+	usage    map[string]*QuotaUsage
+	inflight map[uuid.UUID]reservation
+	syncing  *quotaSync
+}
+
+// reservation is the usage of an event of a tenant.
+type reservation struct {
+	id     uuid.UUID
+	tenant string
+	size   int64
+}
+
+// quotaSync tracks the reservations that are made while Sync queries the
+// events of the underlying store.
+type quotaSync struct {
+	// reserved are the events that were reserved during the sync. They are
+	// skipped by the query of the sync, and counted by delta instead.
+	reserved map[uuid.UUID]bool
+
+	// delta is the usage of the reserved events per tenant.
+	delta map[string]*QuotaUsage
+}
+
+// QuotaOption is an option for a QuotaStore.
+type QuotaOption func(*QuotaStore)
+
+// TenantQuota returns a QuotaOption that sets the quota of the given tenant.
+func TenantQuota(tenant string, quota Quota) QuotaOption {
+	return func(s *QuotaStore) {
+		s.quotas[tenant] = quota
+	}
+}
+
+// DefaultQuota returns a QuotaOption that sets the quota of tenants that have
+// no quota configured by TenantQuota. By default, such tenants are unlimited.
+func DefaultQuota(quota Quota) QuotaOption {
+	return func(s *QuotaStore) {
+		s.defaultQuota = quota
+	}
+}
+
+// EventSize returns a QuotaOption that sets the function that returns the size
+// of an event in bytes. By default, the size of an event is the length of its
+// name plus the length of its JSON-encoded data. Use EventSize to count the
+// size of events as they are encoded by the underlying store:
+//
+//	eventstore.EventSize(func(evt event.Event) int64 {
+//		b, _ := registry.Marshal(evt.Data())
+//		return int64(len(b))
+//	})
+func EventSize(fn func(event.Event) int64) QuotaOption {
+	return func(s *QuotaStore) {
+		s.size = fn
+	}
+}
+
+// QuotaClock returns a QuotaOption that sets the Clock that determines the day
+// of inserted events. Defaults to event.SystemClock.
+func QuotaClock(c event.Clock) QuotaOption {
+	return func(s *QuotaStore) {
+		s.clock = c
+	}
+}
+
+// WithQuotas decorates the given event store to enforce quotas per tenant when
+// events are inserted. The tenant function returns the tenant of an event. A
+// tenant can be anything that events are grouped by, e.g. a customer or the
+// bounded context of an aggregate. Events whose tenant is an empty string are
+// not limited:
+//
+//	store := eventstore.WithQuotas(mongoStore, func(evt event.Event) string {
+//		_, name, _ := evt.Aggregate()
+//		context, _, _ := strings.Cut(name, ".") // e.g. "shop.order" -> "shop"
+//		return context
+//	}, eventstore.DefaultQuota(eventstore.Quota{
+//		EventsPerDay: 100_000,
+//		Storage:      1 << 30,
+//	}))
+//
+// Inserting events that would exceed the quota of a tenant fails with a
+// *QuotaExceededError, and none of the events are inserted.
+//
+// The usage of tenants is tracked in-memory, per QuotaStore, so the limits
+// apply per process, not globally: if multiple processes insert events into the
+// same underlying store, each of them allows a tenant to use its full quota.
+// Call Sync to initialize the storage usage from the events of the underlying
+// store.
+func WithQuotas(store event.Store, tenant func(event.Event) string, opts ...QuotaOption) *QuotaStore {
+	s := &QuotaStore{
+		Store:         store,
+		lockForwarder: lockForwarder{store},
+		tenant:        tenant,
+		quotas:        make(map[string]Quota),
+		size:          jsonSize,
+		clock:         event.SystemClock,
+		usage:         make(map[string]*QuotaUsage),
+
+		inflight: make(map[uuid.UUID]reservation),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Insert inserts the given events into the underlying store if the quotas of
+// their tenants permit it.
+func (s *QuotaStore) Insert(ctx context.Context, events ...event.Event) error {
+	reservations := s.reservations(events)
+	if len(reservations) == 0 {
+		return s.Store.Insert(ctx, events...)
+	}
+
+	day := startOfDay(s.clock.Now())
+	requested := measure(reservations)
+	if err := s.reserve(day, reservations, requested); err != nil {
+		return err
+	}
+
+	err := s.Store.Insert(ctx, events...)
+	s.complete(day, reservations, requested, err)
+
+	return err
+}
+
+// Delete deletes the given events from the underlying store and releases their
+// storage from the quotas of their tenants.
+func (s *QuotaStore) Delete(ctx context.Context, events ...event.Event) error {
+	if err := s.Store.Delete(ctx, events...); err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for tenant, req := range measure(s.reservations(events)) {
+		if u, ok := s.usage[tenant]; ok {
+			u.Storage = max(0, u.Storage-req.Storage)
+		}
+	}
+
+	return nil
+}
+
+// Usage returns the quota usage of the given tenant.
+func (s *QuotaStore) Usage(tenant string) QuotaUsage {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return *s.usageOf(tenant, startOfDay(s.clock.Now()))
+}
+
+// Usages returns the quota usages of all tenants that have inserted events or
+// have a configured quota, sorted by tenant.
+func (s *QuotaStore) Usages() []QuotaUsage {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	day := startOfDay(s.clock.Now())
+	for tenant := range s.quotas {
+		s.usageOf(tenant, day)
+	}
+
+	out := make([]QuotaUsage, 0, len(s.usage))
+	for tenant := range s.usage {
+		out = append(out, *s.usageOf(tenant, day))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tenant < out[j].Tenant })
+
+	return out
+}
+
+// Sync recomputes the storage usage of all tenants, and the number of events
+// that were inserted today, from the events of the underlying store. Sync
+// queries all events of the store.
+//
+// Events that are inserted while Sync is running are counted by their
+// reservation instead of the query, so that they are counted exactly once.
+// Storage that is released by deleting events while Sync is running may still
+// be counted until the next Sync.
+func (s *QuotaStore) Sync(ctx context.Context) error {
+	s.syncMux.Lock()
+	defer s.syncMux.Unlock()
+
+	qs := s.startSync()
+	defer s.stopSync()
+
+	str, errs, err := s.Store.Query(ctx, query.New())
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	day := startOfDay(s.clock.Now())
+	usage := make(map[string]*QuotaUsage)
+
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		tenant := s.tenant(evt)
+		if tenant == "" || s.reservedDuring(qs, evt) {
+			return nil
+		}
+
+		u, ok := usage[tenant]
+		if !ok {
+			u = &QuotaUsage{Tenant: tenant, Quota: s.quotaOf(tenant), Day: day}
+			usage[tenant] = u
+		}
+
+		u.Storage += s.size(evt)
+		if !evt.Time().Before(day) {
+			u.Events++
+		}
+
+		return nil
+	}, str, errs); err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for tenant, delta := range qs.delta {
+		u, ok := usage[tenant]
+		if !ok {
+			u = &QuotaUsage{Tenant: tenant, Quota: s.quotaOf(tenant), Day: day}
+			usage[tenant] = u
+		}
+		u.Events += delta.Events
+		u.Storage += delta.Storage
+	}
+	s.usage = usage
+
+	return nil
+}
+
+// startSync starts tracking the reservations of inserts. Inserts that are
+// still running when the sync starts are tracked as well, because the query of
+// the sync may not see their events.
+func (s *QuotaStore) startSync() *quotaSync {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.syncing = &quotaSync{
+		reserved: make(map[uuid.UUID]bool),
+		delta:    make(map[string]*QuotaUsage),
+	}
+	for _, r := range s.inflight {
+		s.syncing.add(r)
+	}
+
+	return s.syncing
+}
+
+func (s *QuotaStore) stopSync() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.syncing = nil
+}
+
+// reservedDuring reports whether the given event was reserved during the
+// given sync.
+func (s *QuotaStore) reservedDuring(qs *quotaSync, evt event.Event) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return qs.reserved[evt.ID()]
+}
+
+func (qs *quotaSync) add(r reservation) {
+	qs.reserved[r.id] = true
+	d, ok := qs.delta[r.tenant]
+	if !ok {
+		d = &QuotaUsage{Tenant: r.tenant}
+		qs.delta[r.tenant] = d
+	}
+	d.Events++
+	d.Storage += r.size
+}
+
+func (qs *quotaSync) remove(r reservation) {
+	if !qs.reserved[r.id] {
+		return
+	}
+	delete(qs.reserved, r.id)
+	d := qs.delta[r.tenant]
+	d.Events--
+	d.Storage -= r.size
+}
+
+// reservations returns the usage of the given events that have a tenant.
+func (s *QuotaStore) reservations(events []event.Event) []reservation {
+	out := make([]reservation, 0, len(events))
+	for _, evt := range events {
+		tenant := s.tenant(evt)
+		if tenant == "" {
+			continue
+		}
+		out = append(out, reservation{id: evt.ID(), tenant: tenant, size: s.size(evt)})
+	}
+	return out
+}
+
+// measure returns the requested usage of the given reservations per tenant.
+func measure(reservations []reservation) map[string]QuotaUsage {
+	requested := make(map[string]QuotaUsage)
+	for _, r := range reservations {
+		req := requested[r.tenant]
+		req.Events++
+		req.Storage += r.size
+		requested[r.tenant] = req
+	}
+	return requested
+}
+
+func (s *QuotaStore) reserve(day time.Time, reservations []reservation, requested map[string]QuotaUsage) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	tenants := make([]string, 0, len(requested))
+	for tenant := range requested {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	for _, tenant := range tenants {
+		u, req := s.usageOf(tenant, day), requested[tenant]
+
+		if u.Quota.EventsPerDay > 0 && u.Events+req.Events > u.Quota.EventsPerDay {
+			return &QuotaExceededError{
+				Tenant:    tenant,
+				Limit:     EventsPerDayLimit,
+				Quota:     u.Quota.EventsPerDay,
+				Usage:     u.Events,
+				Requested: req.Events,
+			}
+		}
+
+		if u.Quota.Storage > 0 && u.Storage+req.Storage > u.Quota.Storage {
+			return &QuotaExceededError{
+				Tenant:    tenant,
+				Limit:     StorageLimit,
+				Quota:     u.Quota.Storage,
+				Usage:     u.Storage,
+				Requested: req.Storage,
+			}
+		}
+	}
+
+	for _, tenant := range tenants {
+		u, req := s.usageOf(tenant, day), requested[tenant]
+		u.Events += req.Events
+		u.Storage += req.Storage
+	}
+
+	for _, r := range reservations {
+		s.inflight[r.id] = r
+		if s.syncing != nil {
+			s.syncing.add(r)
+		}
+	}
+
+	return nil
+}
+
+// complete completes the given reservations after their events were
+// inserted. If the insert failed, the reserved usage is released.
+func (s *QuotaStore) complete(day time.Time, reservations []reservation, requested map[string]QuotaUsage, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, r := range reservations {
+		delete(s.inflight, r.id)
+		if err != nil && s.syncing != nil {
+			s.syncing.remove(r)
+		}
+	}
+
+	if err == nil {
+		return
+	}
+
+	for tenant, req := range requested {
+		u := s.usageOf(tenant, day)
+		u.Events = max(0, u.Events-req.Events)
+		u.Storage = max(0, u.Storage-req.Storage)
+	}
+}
+
+// usageOf returns the usage of the given tenant and resets the event count of
+// the tenant if the day has changed. s.mux must be locked.
+func (s *QuotaStore) usageOf(tenant string, day time.Time) *QuotaUsage {
+	u, ok := s.usage[tenant]
+	if !ok {
+		u = &QuotaUsage{Tenant: tenant, Day: day}
+		s.usage[tenant] = u
+	}
+
+	if !u.Day.Equal(day) {
+		u.Day = day
+		u.Events = 0
+	}
+	u.Quota = s.quotaOf(tenant)
+
+	return u
+}
+
+func (s *QuotaStore) quotaOf(tenant string) Quota {
+	if q, ok := s.quotas[tenant]; ok {
+		return q
+	}
+	return s.defaultQuota
+}
+
+// Error implements error.
+func (err *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota of tenant %q exceeded: %d + %d > %d", err.Limit, err.Tenant, err.Usage, err.Requested, err.Quota)
+}
+
+// Unwrap returns ErrQuotaExceeded.
+func (err *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func jsonSize(evt event.Event) int64 {
+	b, err := json.Marshal(evt.Data())
+	if err != nil {
+		return int64(len(evt.Name()))
+	}
+	return int64(len(evt.Name()) + len(b))
+}
@@ -0,0 +1,238 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func tenantOf(evt event.Event) string {
+	return evt.Data().(string)
+}
+
+func unitSize(event.Event) int64 { return 1 }
+
+func TestWithQuotas_eventsPerDay(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	store := eventstore.WithQuotas(eventstore.New(), tenantOf,
+		eventstore.TenantQuota("foo", eventstore.Quota{EventsPerDay: 2}),
+		eventstore.EventSize(unitSize),
+		eventstore.QuotaClock(event.ClockFunc(func() time.Time { return now })),
+	)
+
+	if err := store.Insert(ctx, event.New("a", "foo").Any(), event.New("a", "foo").Any()); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	err := store.Insert(ctx, event.New("a", "foo").Any(), event.New("a", "bar").Any())
+
+	var quotaErr *eventstore.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("Insert() should fail with %T; got %v", quotaErr, err)
+	}
+
+	if !errors.Is(err, eventstore.ErrQuotaExceeded) {
+		t.Fatalf("error should unwrap to %q", eventstore.ErrQuotaExceeded)
+	}
+
+	want := eventstore.QuotaExceededError{Tenant: "foo", Limit: eventstore.EventsPerDayLimit, Quota: 2, Usage: 2, Requested: 1}
+	if *quotaErr != want {
+		t.Fatalf("unexpected error\nwant: %+v\ngot:  %+v", want, *quotaErr)
+	}
+
+	if n := countEvents(t, store); n != 2 {
+		t.Fatalf("none of the rejected events should have been inserted; store has %d events", n)
+	}
+
+	if err := store.Insert(ctx, event.New("a", "bar").Any()); err != nil {
+		t.Fatalf("tenants without quota should not be limited; Insert() failed with %q", err)
+	}
+
+	now = now.Add(time.Hour)
+
+	if err := store.Insert(ctx, event.New("a", "foo").Any()); err != nil {
+		t.Fatalf("quota should be reset on the next day; Insert() failed with %q", err)
+	}
+
+	usage := store.Usage("foo")
+	if usage.Events != 1 || usage.Storage != 3 || !usage.Day.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestWithQuotas_storage(t *testing.T) {
+	ctx := context.Background()
+
+	store := eventstore.WithQuotas(eventstore.New(), tenantOf,
+		eventstore.DefaultQuota(eventstore.Quota{Storage: 3}),
+		eventstore.EventSize(unitSize),
+	)
+
+	events := []event.Event{event.New("a", "foo").Any(), event.New("a", "foo").Any(), event.New("a", "foo").Any()}
+	if err := store.Insert(ctx, events...); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	err := store.Insert(ctx, event.New("a", "foo").Any())
+
+	var quotaErr *eventstore.QuotaExceededError
+	if !errors.As(err, &quotaErr) || quotaErr.Limit != eventstore.StorageLimit {
+		t.Fatalf("Insert() should fail with a %q quota error; got %v", eventstore.StorageLimit, err)
+	}
+
+	if err := store.Delete(ctx, events[0]); err != nil {
+		t.Fatalf("Delete() failed with %q", err)
+	}
+
+	if err := store.Insert(ctx, event.New("a", "foo").Any()); err != nil {
+		t.Fatalf("deleted events should release storage; Insert() failed with %q", err)
+	}
+}
+
+func TestQuotaStore_Sync(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+	base := eventstore.New()
+	if err := base.Insert(ctx,
+		event.New("a", "foo", event.Time(now.AddDate(0, 0, -2))).Any(),
+		event.New("a", "foo", event.Time(now)).Any(),
+		event.New("a", "bar", event.Time(now)).Any(),
+		event.New("a", "", event.Time(now)).Any(),
+	); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	store := eventstore.WithQuotas(base, tenantOf,
+		eventstore.TenantQuota("baz", eventstore.Quota{EventsPerDay: 10}),
+		eventstore.EventSize(unitSize),
+	)
+
+	if err := store.Sync(ctx); err != nil {
+		t.Fatalf("Sync() failed with %q", err)
+	}
+
+	usages := store.Usages()
+	if len(usages) != 3 {
+		t.Fatalf("Usages() should return %d usages; got %d", 3, len(usages))
+	}
+
+	for i, want := range []struct {
+		tenant          string
+		events, storage int64
+	}{{"bar", 1, 1}, {"baz", 0, 0}, {"foo", 1, 2}} {
+		u := usages[i]
+		if u.Tenant != want.tenant || u.Events != want.events || u.Storage != want.storage {
+			t.Fatalf("unexpected usage of tenant %q: %+v", want.tenant, u)
+		}
+	}
+
+	if usages[1].Quota.EventsPerDay != 10 {
+		t.Fatalf("usage should report the quota of the tenant; got %+v", usages[1].Quota)
+	}
+}
+
+func TestQuotaStore_Sync_concurrentInsert(t *testing.T) {
+	tests := []struct {
+		name string
+		// seesInsert is whether the query of Sync returns the event that is
+		// inserted while Sync is running.
+		seesInsert bool
+	}{
+		{name: "query before insert"},
+		{name: "query after insert", seesInsert: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			base := &pausedQueryStore{
+				Store:    eventstore.New(event.New("a", "foo").Any()),
+				started:  make(chan struct{}),
+				resume:   make(chan struct{}),
+				snapshot: !tt.seesInsert,
+			}
+			store := eventstore.WithQuotas(base, tenantOf, eventstore.EventSize(unitSize))
+
+			synced := make(chan error)
+			go func() { synced <- store.Sync(ctx) }()
+
+			<-base.started
+			if err := store.Insert(ctx, event.New("a", "foo").Any()); err != nil {
+				t.Fatalf("Insert() failed with %q", err)
+			}
+			close(base.resume)
+
+			if err := <-synced; err != nil {
+				t.Fatalf("Sync() failed with %q", err)
+			}
+
+			if u := store.Usage("foo"); u.Events != 2 || u.Storage != 2 {
+				t.Fatalf("events inserted during Sync should be counted once; got %+v", u)
+			}
+		})
+	}
+}
+
+// pausedQueryStore pauses queries until resume is closed. If snapshot is true,
+// the events are queried before the query is paused.
+type pausedQueryStore struct {
+	event.Store
+
+	started  chan struct{}
+	resume   chan struct{}
+	snapshot bool
+}
+
+func (s *pausedQueryStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	var events []event.Event
+	if s.snapshot {
+		events = drainQuery(ctx, s.Store, q)
+	}
+
+	close(s.started)
+	<-s.resume
+
+	if !s.snapshot {
+		events = drainQuery(ctx, s.Store, q)
+	}
+
+	errs := make(chan error)
+	close(errs)
+
+	return streams.New(events), errs, nil
+}
+
+func drainQuery(ctx context.Context, store event.Store, q event.Query) []event.Event {
+	str, errs, err := store.Query(ctx, q)
+	if err != nil {
+		return nil
+	}
+	events, _ := streams.Drain(ctx, str, errs)
+	return events
+}
+
+func countEvents(t *testing.T, store event.Store) int {
+	t.Helper()
+
+	str, errs, err := store.Query(context.Background(), query.New())
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	return len(events)
+}
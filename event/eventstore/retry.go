@@ -0,0 +1,234 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+)
+
+const (
+	// DefaultRetryAttempts is the default number of times an operation of a
+	// RetryStore is attempted, including the first attempt.
+	DefaultRetryAttempts = 5
+
+	// DefaultRetryBackoff is the default delay before the first retry of an
+	// operation of a RetryStore.
+	DefaultRetryBackoff = 50 * time.Millisecond
+
+	// DefaultMaxRetryBackoff is the default maximum delay between two attempts
+	// of an operation of a RetryStore.
+	DefaultMaxRetryBackoff = 2 * time.Second
+)
+
+// ErrPartialInsert is returned by a RetryStore if an insert failed with a
+// transient error after only some of the events were inserted. Such an insert
+// cannot be retried safely and must be resolved by the caller.
+var ErrPartialInsert = errors.New("events were partially inserted")
+
+// RetryStore is an event store that retries operations that fail with
+// transient errors. Use WithRetry to create a RetryStore.
+//
+// A RetryStore implements Locker by forwarding to the underlying store. Lock
+// operations are not retried.
+type RetryStore struct {
+	event.Store
+	lockForwarder
+
+	transient   func(error) bool
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	onRetry     []func(op string, attempt int, err error)
+}
+
+// RetryOption is an option for a RetryStore.
+type RetryOption func(*RetryStore)
+
+// RetryAttempts returns a RetryOption that configures the maximum number of
+// times an operation is attempted, including the first attempt. Defaults to
+// DefaultRetryAttempts.
+func RetryAttempts(n int) RetryOption {
+	return func(s *RetryStore) {
+		s.maxAttempts = n
+	}
+}
+
+// RetryBackoff returns a RetryOption that configures the delay before the first
+// retry of an operation and the maximum delay between two attempts. The delay
+// doubles with every further retry. Defaults to DefaultRetryBackoff and
+// DefaultMaxRetryBackoff.
+func RetryBackoff(initial, max time.Duration) RetryOption {
+	return func(s *RetryStore) {
+		s.backoff = initial
+		s.maxBackoff = max
+	}
+}
+
+// OnRetry returns a RetryOption that calls fn before an operation is retried.
+// op is the name of the operation ("insert", "find", "query" or "delete"),
+// attempt is the number of the failed attempt, and err is its error.
+func OnRetry(fn func(op string, attempt int, err error)) RetryOption {
+	return func(s *RetryStore) {
+		s.onRetry = append(s.onRetry, fn)
+	}
+}
+
+// WithRetry decorates the given event store to retry operations that fail with
+// transient errors, e.g. network timeouts or an election of a new primary in a
+// MongoDB replica set. The transient function classifies the errors of the
+// underlying store; errors for which it returns false are returned immediately.
+// Operations are retried with exponential backoff until they succeed, the
+// error is not transient, the maximum number of attempts is reached, or the
+// context is canceled:
+//
+//	store := eventstore.WithRetry(mongoStore, mongo.IsTransientError,
+//		eventstore.RetryAttempts(10),
+//		eventstore.RetryBackoff(100*time.Millisecond, 5*time.Second),
+//	)
+//
+// Inserts are not blindly retried, because an insert that failed with a
+// transient error may still have been committed (e.g. if the connection was
+// lost while waiting for the acknowledgement). Before an insert is retried, the
+// store checks which of the events already exist. If all events exist, the
+// insert succeeded and no error is returned. If only some of the events exist,
+// the insert is not retried and an error that wraps ErrPartialInsert is
+// returned. Stores that insert events atomically (e.g. MongoDB with
+// transactions enabled) never cause partial inserts.
+//
+// Find and Delete are idempotent and retried as is. Query is only retried if
+// the query cannot be started; errors that occur while the events are streamed
+// are passed through, because the consumer may already have received events.
+func WithRetry(store event.Store, transient func(error) bool, opts ...RetryOption) *RetryStore {
+	s := &RetryStore{
+		Store:         store,
+		lockForwarder: lockForwarder{store},
+		transient:     transient,
+		maxAttempts:   DefaultRetryAttempts,
+		backoff:       DefaultRetryBackoff,
+		maxBackoff:    DefaultMaxRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Insert inserts the given events into the underlying store. If the insert
+// fails with a transient error, the insert is retried unless the events were
+// already inserted.
+func (s *RetryStore) Insert(ctx context.Context, events ...event.Event) error {
+	return s.retry(ctx, "insert", func() error {
+		return s.Store.Insert(ctx, events...)
+	}, func() (bool, error) {
+		return s.inserted(ctx, events)
+	})
+}
+
+// Find returns the event with the given id from the underlying store.
+func (s *RetryStore) Find(ctx context.Context, id uuid.UUID) (event.Event, error) {
+	var evt event.Event
+	err := s.retry(ctx, "find", func() error {
+		var err error
+		evt, err = s.Store.Find(ctx, id)
+		return err
+	}, nil)
+	return evt, err
+}
+
+// Query queries the underlying store. Only starting the query is retried.
+func (s *RetryStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	var (
+		events <-chan event.Event
+		errs   <-chan error
+	)
+	err := s.retry(ctx, "query", func() error {
+		var err error
+		events, errs, err = s.Store.Query(ctx, q)
+		return err
+	}, nil)
+	return events, errs, err
+}
+
+// Delete deletes the given events from the underlying store.
+func (s *RetryStore) Delete(ctx context.Context, events ...event.Event) error {
+	return s.retry(ctx, "delete", func() error {
+		return s.Store.Delete(ctx, events...)
+	}, nil)
+}
+
+// retry calls fn until it succeeds or fails with an error that should not be
+// retried. If provided, done is called before each retry and reports whether
+// the previous attempt was applied despite its error.
+func (s *RetryStore) retry(ctx context.Context, op string, fn func() error, done func() (bool, error)) error {
+	err := fn()
+
+	for attempt := 1; err != nil && attempt < s.maxAttempts && s.transient(err); attempt++ {
+		for _, fn := range s.onRetry {
+			fn(op, attempt, err)
+		}
+
+		timer := time.NewTimer(s.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		if done != nil {
+			ok, derr := done()
+			if derr != nil {
+				err = derr
+				continue
+			}
+			if ok {
+				return nil
+			}
+		}
+
+		err = fn()
+	}
+
+	return err
+}
+
+// inserted reports whether all of the given events exist in the underlying
+// store. If only some of the events exist, an error that wraps
+// ErrPartialInsert is returned.
+func (s *RetryStore) inserted(ctx context.Context, events []event.Event) (bool, error) {
+	var found int
+	for _, evt := range events {
+		if _, err := s.Store.Find(ctx, evt.ID()); err != nil {
+			if errors.Is(err, event.ErrNotFound) {
+				continue
+			}
+			return false, fmt.Errorf("find event: %w [id=%v]", err, evt.ID())
+		}
+		found++
+	}
+
+	switch found {
+	case 0:
+		return false, nil
+	case len(events):
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w [inserted=%d, events=%d]", ErrPartialInsert, found, len(events))
+	}
+}
+
+// delay returns the delay before the given retry, starting at 1.
+func (s *RetryStore) delay(retry int) time.Duration {
+	delay := s.backoff
+	for i := 1; i < retry && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	if s.maxBackoff > 0 && delay > s.maxBackoff {
+		return s.maxBackoff
+	}
+	return delay
+}
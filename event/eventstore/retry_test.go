@@ -0,0 +1,203 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+)
+
+var errTransient = errors.New("transient")
+
+func TestWithRetry(t *testing.T) {
+	ctx := context.Background()
+
+	flaky := &flakyStore{Store: eventstore.New(), failures: 2}
+
+	var retries []int
+	store := eventstore.WithRetry(flaky, isTransient,
+		eventstore.RetryBackoff(time.Millisecond, 10*time.Millisecond),
+		eventstore.OnRetry(func(op string, attempt int, err error) {
+			if op != "insert" || !errors.Is(err, errTransient) {
+				t.Errorf("unexpected retry of %q with %v", op, err)
+			}
+			retries = append(retries, attempt)
+		}),
+	)
+
+	evt := event.New("foo", test.FooEventData{}).Any()
+
+	if err := store.Insert(ctx, evt); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	if len(retries) != 2 || retries[0] != 1 || retries[1] != 2 {
+		t.Fatalf("insert should be retried twice; got retries %v", retries)
+	}
+
+	if _, err := store.Find(ctx, evt.ID()); err != nil {
+		t.Fatalf("event should be inserted; Find failed with %q", err)
+	}
+}
+
+func TestWithRetry_maxAttempts(t *testing.T) {
+	flaky := &flakyStore{Store: eventstore.New(), failures: 5}
+	store := eventstore.WithRetry(flaky, isTransient,
+		eventstore.RetryAttempts(3),
+		eventstore.RetryBackoff(time.Millisecond, time.Millisecond),
+	)
+
+	err := store.Insert(context.Background(), event.New("foo", test.FooEventData{}).Any())
+	if !errors.Is(err, errTransient) {
+		t.Fatalf("Insert should fail with %q; got %q", errTransient, err)
+	}
+
+	if flaky.calls != 3 {
+		t.Fatalf("insert should be attempted %d times; got %d", 3, flaky.calls)
+	}
+}
+
+func TestWithRetry_nonTransient(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	flaky := &flakyStore{Store: eventstore.New(), failures: 5, err: errPermanent}
+	store := eventstore.WithRetry(flaky, isTransient, eventstore.RetryBackoff(time.Millisecond, time.Millisecond))
+
+	if err := store.Insert(context.Background(), event.New("foo", test.FooEventData{}).Any()); !errors.Is(err, errPermanent) {
+		t.Fatalf("Insert should fail with %q; got %q", errPermanent, err)
+	}
+
+	if flaky.calls != 1 {
+		t.Fatalf("non-transient errors should not be retried; insert was attempted %d times", flaky.calls)
+	}
+}
+
+func TestWithRetry_committedInsert(t *testing.T) {
+	flaky := &flakyStore{Store: eventstore.New(), failures: 1, commit: true}
+	store := eventstore.WithRetry(flaky, isTransient, eventstore.RetryBackoff(time.Millisecond, time.Millisecond))
+
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}).Any(),
+		event.New("foo", test.FooEventData{}).Any(),
+	}
+
+	if err := store.Insert(context.Background(), events...); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	if flaky.calls != 1 {
+		t.Fatalf("committed insert should not be retried; insert was attempted %d times", flaky.calls)
+	}
+}
+
+func TestWithRetry_partialInsert(t *testing.T) {
+	ctx := context.Background()
+
+	inserted := event.New("foo", test.FooEventData{}).Any()
+	flaky := &flakyStore{Store: eventstore.New(inserted), failures: 1}
+	store := eventstore.WithRetry(flaky, isTransient, eventstore.RetryBackoff(time.Millisecond, time.Millisecond))
+
+	err := store.Insert(ctx, inserted, event.New("foo", test.FooEventData{}).Any())
+	if !errors.Is(err, eventstore.ErrPartialInsert) {
+		t.Fatalf("Insert should fail with %q; got %q", eventstore.ErrPartialInsert, err)
+	}
+
+	if flaky.calls != 1 {
+		t.Fatalf("partial insert should not be retried; insert was attempted %d times", flaky.calls)
+	}
+}
+
+func TestWithRetry_Query(t *testing.T) {
+	ctx := context.Background()
+
+	evt := event.New("foo", test.FooEventData{}).Any()
+	flaky := &flakyStore{Store: eventstore.New(evt), failures: 1}
+	store := eventstore.WithRetry(flaky, isTransient, eventstore.RetryBackoff(time.Millisecond, time.Millisecond))
+
+	str, errs, err := store.Query(ctx, query.New())
+	if err != nil {
+		t.Fatalf("Query failed with %q", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		t.Fatalf("drain events: %v", err)
+	}
+
+	if len(events) != 1 || events[0].ID() != evt.ID() {
+		t.Fatalf("query should return the event after a retry; got %v", events)
+	}
+}
+
+func TestWithRetry_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	flaky := &flakyStore{Store: eventstore.New(), failures: 5}
+	store := eventstore.WithRetry(flaky, isTransient, eventstore.RetryBackoff(time.Hour, time.Hour))
+
+	if err := store.Delete(ctx, event.New("foo", test.FooEventData{}).Any()); !errors.Is(err, errTransient) {
+		t.Fatalf("Delete should fail with %q; got %q", errTransient, err)
+	}
+
+	if flaky.calls != 1 {
+		t.Fatalf("operations should not be retried after the context is canceled; attempted %d times", flaky.calls)
+	}
+}
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+// flakyStore fails the first n operations with an error. If commit is true,
+// failed inserts still insert the events.
+type flakyStore struct {
+	event.Store
+
+	failures int
+	err      error
+	commit   bool
+	calls    int
+}
+
+func (s *flakyStore) fail() error {
+	s.calls++
+	if s.calls > s.failures {
+		return nil
+	}
+	if s.err != nil {
+		return s.err
+	}
+	return errTransient
+}
+
+func (s *flakyStore) Insert(ctx context.Context, events ...event.Event) error {
+	if err := s.fail(); err != nil {
+		if s.commit {
+			if err := s.Store.Insert(ctx, events...); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	return s.Store.Insert(ctx, events...)
+}
+
+func (s *flakyStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	if err := s.fail(); err != nil {
+		return nil, nil, err
+	}
+	return s.Store.Query(ctx, q)
+}
+
+func (s *flakyStore) Delete(ctx context.Context, events ...event.Event) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.Store.Delete(ctx, events...)
+}
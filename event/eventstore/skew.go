@@ -0,0 +1,193 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// Skew is a clock skew that was detected and corrected by a SkewStore.
+type Skew struct {
+	// Event is the event as it was provided to Insert.
+	Event event.Event
+
+	// Corrected is the corrected time of the event.
+	Corrected time.Time
+
+	// Previous is the time of the previous event of the aggregate, or the zero
+	// time if the event is not an aggregate event or the first event of its
+	// aggregate.
+	Previous time.Time
+}
+
+// Offset returns the duration by which the time of the event was corrected.
+func (s Skew) Offset() time.Duration {
+	return s.Corrected.Sub(s.Event.Time())
+}
+
+// SkewStore is an event store that detects and corrects clock skew between
+// the producers of events. Use CorrectSkew to create a SkewStore.
+type SkewStore struct {
+	event.Store
+
+	clock     event.Clock
+	maxFuture time.Duration
+	onSkew    []func(Skew)
+}
+
+// SkewOption is an option for a SkewStore.
+type SkewOption func(*SkewStore)
+
+// SkewClock returns a SkewOption that sets the Clock of the event store, which
+// is used to detect events that are too far in the future (see MaxFutureSkew).
+// Defaults to event.SystemClock.
+func SkewClock(c event.Clock) SkewOption {
+	return func(s *SkewStore) {
+		s.clock = c
+	}
+}
+
+// MaxFutureSkew returns a SkewOption that corrects the time of events that are
+// more than d after the current time of the event store. Such events are
+// corrected to the current time of the event store. By default, the times of
+// events in the future are not corrected.
+func MaxFutureSkew(d time.Duration) SkewOption {
+	return func(s *SkewStore) {
+		s.maxFuture = d
+	}
+}
+
+// OnSkew returns a SkewOption that calls fn for every corrected event. Use
+// OnSkew to monitor the clock skew of producers.
+func OnSkew(fn func(Skew)) SkewOption {
+	return func(s *SkewStore) {
+		s.onSkew = append(s.onSkew, fn)
+	}
+}
+
+// CorrectSkew decorates the given event store to detect and correct clock skew
+// between the producers of events before the events are inserted.
+//
+// When multiple instances of a service raise events for the same aggregate,
+// and the clocks of the instances are not in sync, an event may have an
+// earlier time than the previous event of the aggregate. Queries that sort
+// events by time then return the events of the aggregate in the wrong order.
+// The returned store corrects the time of such an event to 1 nanosecond after
+// the time of the previous event of the aggregate, so that the events of an
+// aggregate are always sorted by time in the order of their versions:
+//
+//	store := eventstore.CorrectSkew(mongoStore,
+//		eventstore.MaxFutureSkew(time.Minute),
+//		eventstore.OnSkew(func(s eventstore.Skew) {
+//			log.Printf("corrected time of %q event by %v", s.Event.Name(), s.Offset())
+//		}),
+//	)
+//
+// To find the previous event of an aggregate, the store queries the event
+// that precedes the first inserted event of every aggregate. Corrected events
+// keep their ids, names, data, and aggregate versions.
+func CorrectSkew(store event.Store, opts ...SkewOption) *SkewStore {
+	s := &SkewStore{Store: store, clock: event.SystemClock}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Insert corrects the times of the given events and inserts them into the
+// underlying event store.
+func (s *SkewStore) Insert(ctx context.Context, events ...event.Event) error {
+	corrected, err := s.correct(ctx, events)
+	if err != nil {
+		return err
+	}
+	return s.Store.Insert(ctx, corrected...)
+}
+
+func (s *SkewStore) correct(ctx context.Context, events []event.Event) ([]event.Event, error) {
+	var now time.Time
+	if s.maxFuture > 0 {
+		now = s.clock.Now()
+	}
+
+	previous := make(map[uuid.UUID]time.Time)
+	out := make([]event.Event, len(events))
+	var skews []Skew
+
+	for i, evt := range events {
+		out[i] = evt
+
+		t := evt.Time()
+		var prev time.Time
+
+		if s.maxFuture > 0 && t.Sub(now) > s.maxFuture {
+			t = now
+		}
+
+		if id, name, v := evt.Aggregate(); id != uuid.Nil {
+			var ok bool
+			if prev, ok = previous[id]; !ok && v > 1 {
+				var err error
+				if prev, err = s.previousTime(ctx, name, id, v-1); err != nil {
+					return nil, fmt.Errorf("find previous event of %s: %w", event.AggregateRef{Name: name, ID: id}, err)
+				}
+			}
+
+			if !prev.IsZero() && !t.After(prev) {
+				t = prev.Add(time.Nanosecond)
+			}
+			previous[id] = t
+		}
+
+		if !t.Equal(evt.Time()) {
+			out[i] = withTime(evt, t)
+			skews = append(skews, Skew{Event: evt, Corrected: t, Previous: prev})
+		}
+	}
+
+	for _, skew := range skews {
+		for _, fn := range s.onSkew {
+			fn(skew)
+		}
+	}
+
+	return out, nil
+}
+
+func (s *SkewStore) previousTime(ctx context.Context, name string, id uuid.UUID, v int) (time.Time, error) {
+	str, errs, err := s.Store.Query(ctx, query.New(
+		query.Aggregate(name, id),
+		query.AggregateVersion(version.Exact(v)),
+	))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("query events: %w", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(events) == 0 {
+		return time.Time{}, nil
+	}
+
+	return events[0].Time(), nil
+}
+
+func withTime(evt event.Event, t time.Time) event.Event {
+	return event.New(
+		evt.Name(),
+		evt.Data(),
+		event.ID(evt.ID()),
+		event.Time(t),
+		event.Aggregate(pick.AggregateID(evt), pick.AggregateName(evt), pick.AggregateVersion(evt)),
+	).Any()
+}
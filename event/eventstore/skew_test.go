@@ -0,0 +1,79 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+)
+
+func TestCorrectSkew(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	var skews []eventstore.Skew
+	store := eventstore.CorrectSkew(
+		eventstore.New(),
+		eventstore.SkewClock(event.ClockFunc(func() time.Time { return now })),
+		eventstore.MaxFutureSkew(time.Minute),
+		eventstore.OnSkew(func(s eventstore.Skew) { skews = append(skews, s) }),
+	)
+
+	if err := store.Insert(ctx, event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 1), event.Time(now)).Any()); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	// The second event was raised by a producer whose clock is behind.
+	skewed := event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 2), event.Time(now.Add(-time.Second))).Any()
+	// The third event was raised by a producer whose clock is far ahead.
+	future := event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 3), event.Time(now.Add(time.Hour))).Any()
+	// The fourth event was raised within the tolerated skew.
+	tolerated := event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 4), event.Time(now.Add(30*time.Second))).Any()
+
+	if err := store.Insert(ctx, skewed, future, tolerated); err != nil {
+		t.Fatalf("Insert failed with %q", err)
+	}
+
+	if len(skews) != 2 {
+		t.Fatalf("OnSkew should be called %d times; got %d", 2, len(skews))
+	}
+
+	if want := now.Add(time.Nanosecond); !skews[0].Corrected.Equal(want) || !skews[0].Previous.Equal(now) {
+		t.Fatalf("skewed event should be corrected to %v; got %v", want, skews[0])
+	}
+
+	if want := now.Add(2 * time.Nanosecond); !skews[1].Corrected.Equal(want) {
+		t.Fatalf("future event should be corrected to %v; got %v", want, skews[1].Corrected)
+	}
+
+	str, errs, err := store.Query(ctx, query.New(query.SortByTime()))
+	if err != nil {
+		t.Fatalf("Query failed with %q", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		t.Fatalf("drain events: %v", err)
+	}
+
+	for i, evt := range events {
+		if _, _, v := evt.Aggregate(); v != i+1 {
+			t.Fatalf("events should be sorted by version when sorted by time; got version %d at index %d", v, i)
+		}
+	}
+
+	if events[1].ID() != skewed.ID() {
+		t.Fatalf("corrected event should keep its id")
+	}
+
+	if !events[3].Time().Equal(tolerated.Time()) {
+		t.Fatalf("tolerated event should not be corrected")
+	}
+}
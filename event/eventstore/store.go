@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/google/uuid"
@@ -20,7 +21,7 @@ import (
 func New(events ...event.Event) event.Store {
 	store := &memstore{
 		idMap:  make(map[uuid.UUID]event.Event),
-		events: events,
+		events: slices.Clone(events),
 	}
 	for _, evt := range events {
 		store.idMap[evt.ID()] = evt
@@ -29,7 +30,7 @@ func New(events ...event.Event) event.Store {
 }
 
 var (
-	errEventNotFound  = errors.New("event not found")
+	errEventNotFound  = event.ErrNotFound
 	errDuplicateEvent = errors.New("duplicate event")
 )
 
@@ -37,6 +38,9 @@ type memstore struct {
 	mux    sync.RWMutex
 	events []event.Event
 	idMap  map[uuid.UUID]event.Event
+
+	leaseMux sync.Mutex
+	leases   map[event.AggregateRef]memLease
 }
 
 // Insert inserts the provided events into the in-memory event store. If an
@@ -61,7 +65,7 @@ func (s *memstore) insert(ctx context.Context, evt event.Event) error {
 	return nil
 }
 
-// Find returns the event with the given UUID or errEventNotFound if no such
+// Find returns the event with the given UUID or event.ErrNotFound if no such
 // event exists in the store.
 func (s *memstore) Find(ctx context.Context, id uuid.UUID) (event.Event, error) {
 	s.mux.RLock()
@@ -0,0 +1,32 @@
+package eventstore
+
+import (
+	"context"
+
+	"github.com/modernice/goes/event"
+)
+
+// Versioner is an event store that can look up the current version of an
+// aggregate without querying its events.
+type Versioner interface {
+	// AggregateVersion returns the highest version of the events of the given
+	// aggregate, or 0 if the store contains no events of the aggregate.
+	AggregateVersion(ctx context.Context, ref event.AggregateRef) (int, error)
+}
+
+// AggregateVersion returns the highest version of the events of the given
+// aggregate, or 0 if the store contains no events of the aggregate.
+func (s *memstore) AggregateVersion(ctx context.Context, ref event.AggregateRef) (int, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	var v int
+	for _, evt := range s.idMap {
+		id, name, version := evt.Aggregate()
+		if id == ref.ID && name == ref.Name && version > v {
+			v = version
+		}
+	}
+
+	return v, nil
+}
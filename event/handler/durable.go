@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/time"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// Progress stores the progress of durable event handlers (see [Durable]).
+type Progress interface {
+	// Load returns the time of the last handled event of the given handler,
+	// together with the ids of the handled events that occurred at that time.
+	// Load returns the zero time if the handler has not handled any events.
+	Load(ctx context.Context, handler string) (stdtime.Time, []uuid.UUID, error)
+
+	// Save saves the progress of the given handler.
+	Save(ctx context.Context, handler string, t stdtime.Time, ids []uuid.UUID) error
+}
+
+// Durable returns an [Option] that makes a [Handler] durable. A durable
+// [Handler] persists the time of the last handled event in the given
+// [Progress]. When a durable [Handler] is started, it first queries the given
+// store for the events that it missed while it was not running, and handles
+// them before it handles the events that it receives from the event bus. Live
+// events that are received during the catch-up are buffered, and events that
+// were already handled during the catch-up are skipped.
+//
+//	h := handler.New(bus, handler.Durable("order-emails", store, progress))
+//	event.HandleWith(h, sendConfirmation, "order_placed")
+//	errs, err := h.Run(ctx)
+//
+// The name identifies the [Handler] in the [Progress] and must be unique per
+// handler group; instances of the same service share their progress. When
+// starting the first time, a durable [Handler] handles all past events.
+//
+// A durable [Handler] delivers events at least once: an event whose handler
+// returned before the progress could be saved is handled again after a
+// restart. The progress is the time of the last handled event, so a durable
+// [Handler] should use a single worker, which is the default. With multiple
+// workers, events may complete out of order, and events that were still being
+// handled when the service stopped may be skipped after a restart.
+//
+// If the progress cannot be loaded or the missed events cannot be queried, the
+// error is sent to the error channel returned by [Handler.Run], and the
+// [Handler] stops handling events without saving any further progress. Cancel
+// the context of the [Handler] and run it again to retry the catch-up.
+//
+// Durable replaces the [Startup] option.
+func Durable(name string, store event.Store, progress Progress) Option {
+	return func(h *Handler) {
+		h.durable = &durable{
+			name:     name,
+			store:    store,
+			progress: progress,
+		}
+	}
+}
+
+type durable struct {
+	name     string
+	store    event.Store
+	progress Progress
+
+	mux  sync.Mutex
+	time stdtime.Time
+	ids  []uuid.UUID
+}
+
+// handled saves the progress of the handler after the given event was handled.
+func (d *durable) handled(ctx context.Context, evt event.Event) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	t := evt.Time()
+	switch {
+	case t.After(d.time):
+		d.time = t
+		d.ids = []uuid.UUID{evt.ID()}
+	case t.Equal(d.time):
+		d.ids = append(d.ids, evt.ID())
+	default:
+		return nil
+	}
+
+	if err := d.progress.Save(ctx, d.name, d.time, d.ids); err != nil {
+		return fmt.Errorf("save progress of %q handler: %w", d.name, err)
+	}
+
+	return nil
+}
+
+// catchUp pushes the events that the handler missed into the queue, and
+// returns the live events that were not handled during the catch-up. Live
+// events are buffered while the handler catches up.
+func (d *durable) catchUp(
+	ctx context.Context,
+	eventNames []string,
+	live <-chan event.Event,
+	liveErrs <-chan error,
+	push func(event.Event) error,
+) (<-chan event.Event, <-chan error, error) {
+	live, liveErrs = buffer(ctx, live), buffer(ctx, liveErrs)
+
+	since, ids, err := d.progress.Load(ctx, d.name)
+	if err != nil {
+		return live, liveErrs, fmt.Errorf("load progress of %q handler: %w", d.name, err)
+	}
+
+	d.mux.Lock()
+	d.time, d.ids = since, ids
+	d.mux.Unlock()
+
+	handled := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		handled[id] = true
+	}
+
+	q := query.New(query.Name(eventNames...), query.SortByTime())
+	if !since.IsZero() {
+		q = query.New(query.Name(eventNames...), query.Time(time.Min(since)), query.SortByTime())
+	}
+
+	str, errs, err := d.store.Query(ctx, q)
+	if err != nil {
+		return live, liveErrs, fmt.Errorf("query missed events of %q handler: %w", d.name, err)
+	}
+
+	var last stdtime.Time
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		if handled[evt.ID()] {
+			return nil
+		}
+		handled[evt.ID()] = true
+		last = evt.Time()
+		return push(evt)
+	}, str, errs); err != nil {
+		return live, liveErrs, fmt.Errorf("catch up %q handler: %w", d.name, err)
+	}
+
+	if last.Before(since) {
+		last = since
+	}
+
+	// Skip the live events that were already handled. Live events after the
+	// last caught-up event cannot have been handled, so the filter is removed
+	// once such an event is received.
+	return streams.Filter(live, func(evt event.Event) bool {
+		if handled == nil {
+			return true
+		}
+		if evt.Time().After(last) {
+			handled = nil
+			return true
+		}
+		return !handled[evt.ID()]
+	}), liveErrs, nil
+}
+
+// buffer returns a channel that receives the values of the given channel,
+// buffering values until they are received.
+func buffer[T any](ctx context.Context, in <-chan T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		var queue []T
+		for in != nil || len(queue) > 0 {
+			var send chan T
+			var next T
+			if len(queue) > 0 {
+				send, next = out, queue[0]
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					in = nil
+					continue
+				}
+				queue = append(queue, v)
+			case send <- next:
+				queue = queue[1:]
+			}
+		}
+	}()
+	return out
+}
+
+// MemoryProgress is a thread-safe in-memory [Progress]. Useful for testing.
+type MemoryProgress struct {
+	mux      sync.RWMutex
+	progress map[string]memoryProgress
+}
+
+type memoryProgress struct {
+	time stdtime.Time
+	ids  []uuid.UUID
+}
+
+// NewMemoryProgress returns an in-memory [Progress].
+func NewMemoryProgress() *MemoryProgress {
+	return &MemoryProgress{progress: make(map[string]memoryProgress)}
+}
+
+// Load implements [Progress].
+func (p *MemoryProgress) Load(_ context.Context, handler string) (stdtime.Time, []uuid.UUID, error) {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	progress := p.progress[handler]
+	return progress.time, append([]uuid.UUID(nil), progress.ids...), nil
+}
+
+// Save implements [Progress].
+func (p *MemoryProgress) Save(_ context.Context, handler string, t stdtime.Time, ids []uuid.UUID) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.progress[handler] = memoryProgress{time: t, ids: append([]uuid.UUID(nil), ids...)}
+	return nil
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
 	"github.com/modernice/goes/event/query"
 	"github.com/modernice/goes/helper/streams"
 	"github.com/modernice/goes/internal/concurrent"
@@ -41,6 +44,8 @@ type Handler struct {
 	startupStore event.Store
 	startupQuery func(event.Query) event.Query
 	workers      int
+	keyFunc      func(event.Event) string
+	durable      *durable
 
 	mux        sync.RWMutex
 	handlers   map[string]func(event.Event)
@@ -106,6 +111,31 @@ func Workers(n int) Option {
 	}
 }
 
+// ConcurrencyKey returns an [Option] that makes a [Handler] handle events with
+// the same key sequentially, in the order they were received, while events
+// with different keys are handled in parallel by the workers of the [Handler]
+// (see [Workers]). Events with an empty key are handled by any worker. Use
+// [PerAggregate] to preserve the order of events per aggregate.
+func ConcurrencyKey(fn func(event.Event) string) Option {
+	return func(h *Handler) {
+		h.keyFunc = fn
+	}
+}
+
+// PerAggregate returns a [ConcurrencyKey] option that handles the events of
+// the same aggregate sequentially, and events of different aggregates in
+// parallel. Events that do not belong to an aggregate are handled by any
+// worker.
+func PerAggregate() Option {
+	return ConcurrencyKey(func(evt event.Event) string {
+		id, name, _ := evt.Aggregate()
+		if id == uuid.Nil {
+			return ""
+		}
+		return name + ":" + id.String()
+	})
+}
+
 // New creates a new event handler with the provided bus and options. It sets up
 // an empty map for handlers and event names, applies the given options, and
 // ensures that there is at least one worker. The new handler is returned.
@@ -189,31 +219,51 @@ func (h *Handler) Run(ctx context.Context) (<-chan error, error) {
 		eventNames = append(eventNames, name)
 	}
 
-	events, errs, err := h.bus.Subscribe(ctx, eventNames...)
+	// The subscription is canceled if a durable handler fails to catch up.
+	subCtx, unsubscribe := context.WithCancel(ctx)
+
+	events, errs, err := h.bus.Subscribe(subCtx, eventNames...)
 	if err != nil {
+		unsubscribe()
 		return nil, fmt.Errorf("subscribe to events: %w [events=%v]", err, eventNames)
 	}
 
 	queueError, fail := concurrent.Errors(ctx)
 	queue := make(chan event.Event)
 
+	push := func(evt event.Event) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case queue <- evt:
+			return nil
+		}
+	}
+
 	go func() {
 		defer close(queue)
-		if err := streams.Walk(ctx, func(evt event.Event) error {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case queue <- evt:
-				return nil
+		defer unsubscribe()
+
+		if h.durable != nil {
+			var err error
+			// If the catch-up fails, live events are not handled, because
+			// saving their progress would skip the missed events.
+			if events, errs, err = h.durable.catchUp(subCtx, eventNames, events, errs, push); err != nil {
+				if !errors.Is(err, context.Canceled) {
+					fail(err)
+				}
+				return
 			}
-		}, events, errs); !errors.Is(err, context.Canceled) {
+		}
+
+		if err := streams.Walk(ctx, push, events, errs); !errors.Is(err, context.Canceled) {
 			fail(err)
 		}
 	}()
 
 	out := streams.FanInAll(queueError, h.handleEvents(ctx, queue))
 
-	if h.startupStore != nil {
+	if h.startupStore != nil && h.durable == nil {
 		go func() {
 			if err := h.startup(ctx, eventNames); err != nil {
 				fail(fmt.Errorf("startup handler: %w", err))
@@ -226,21 +276,69 @@ func (h *Handler) Run(ctx context.Context) (<-chan error, error) {
 
 func (h *Handler) handleEvents(ctx context.Context, events <-chan event.Event) <-chan error {
 	errs, fail := concurrent.Errors(ctx)
-	for i := 0; i < h.workers; i++ {
-		go func() {
-			for evt := range events {
-				fn, ok := h.EventHandler(evt.Name())
-				if !ok {
-					fail(fmt.Errorf("no handler for event %q", evt.Name()))
-					continue
+
+	work := func(events <-chan event.Event) {
+		for evt := range events {
+			fn, ok := h.EventHandler(evt.Name())
+			if !ok {
+				fail(fmt.Errorf("no handler for event %q", evt.Name()))
+				continue
+			}
+			fn(evt)
+			if h.durable != nil {
+				if err := h.durable.handled(ctx, evt); err != nil {
+					fail(err)
 				}
-				fn(evt)
 			}
-		}()
+			if ack, ok := h.bus.(eventbus.Acknowledger); ok {
+				ack.Ack(evt)
+			}
+		}
 	}
+
+	if h.keyFunc == nil || h.workers < 2 {
+		for i := 0; i < h.workers; i++ {
+			go work(events)
+		}
+		return errs
+	}
+
+	queues := make([]chan event.Event, h.workers)
+	for i := range queues {
+		queues[i] = make(chan event.Event)
+		go work(queues[i])
+	}
+
+	go h.dispatch(events, queues)
+
 	return errs
 }
 
+// dispatch sends events with the same concurrency key to the same queue, so
+// that they are handled sequentially by the same worker. Events without a key
+// are distributed round-robin.
+func (h *Handler) dispatch(events <-chan event.Event, queues []chan event.Event) {
+	defer func() {
+		for _, q := range queues {
+			close(q)
+		}
+	}()
+
+	var next int
+	for evt := range events {
+		var i int
+		if key := h.keyFunc(evt); key != "" {
+			hash := fnv.New32a()
+			hash.Write([]byte(key))
+			i = int(hash.Sum32() % uint32(len(queues)))
+		} else {
+			i = next
+			next = (next + 1) % len(queues)
+		}
+		queues[i] <- evt
+	}
+}
+
 func (h *Handler) startup(ctx context.Context, eventNames []string) error {
 	q := h.startupQuery(DefaultStartupQuery(eventNames))
 
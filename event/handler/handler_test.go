@@ -2,6 +2,10 @@ package handler_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -59,6 +63,87 @@ func TestHandler(t *testing.T) {
 	}
 }
 
+func TestHandler_acknowledgesEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.NewFlowControl(eventbus.New(), eventbus.MaxInFlight(1))
+	h := handler.New(bus)
+
+	handled := make(chan event.Event)
+	h.RegisterEventHandler("foo", func(evt event.Event) { handled <- evt })
+
+	if _, err := h.Run(ctx); err != nil {
+		t.Fatalf("Run() failed with %q", err)
+	}
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			bus.Publish(ctx, event.New("foo", test.FooEventData{}).Any())
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("event #%d was not handled; handler should acknowledge handled events", i)
+		case <-handled:
+		}
+	}
+}
+
+func TestPerAggregate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	h := handler.New(bus, handler.Workers(4), handler.PerAggregate())
+
+	var mux sync.Mutex
+	versions := make(map[uuid.UUID][]int)
+	handled := make(chan struct{})
+
+	h.RegisterEventHandler("foo", func(evt event.Event) {
+		id, _, v := evt.Aggregate()
+		// Handle the first events slowly to provoke reordering.
+		if v == 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		mux.Lock()
+		versions[id] = append(versions[id], v)
+		mux.Unlock()
+		handled <- struct{}{}
+	})
+
+	if _, err := h.Run(ctx); err != nil {
+		t.Fatalf("Run() failed with %q", err)
+	}
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	go func() {
+		for v := 1; v <= 5; v++ {
+			for _, id := range ids {
+				bus.Publish(ctx, event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", v)).Any())
+			}
+		}
+	}()
+
+	for i := 0; i < 5*len(ids); i++ {
+		select {
+		case <-time.After(3 * time.Second):
+			t.Fatalf("event #%d was not handled", i)
+		case <-handled:
+		}
+	}
+
+	for id, got := range versions {
+		if !slices.IsSorted(got) {
+			t.Fatalf("events of aggregate %s should be handled in order; got versions %v", id, got)
+		}
+	}
+}
+
 func TestStartup(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -282,3 +367,136 @@ func TestStartup_withQuery_replaces_ids(t *testing.T) {
 		t.Fatalf("bar event without matching ID was incorrectly handled")
 	}
 }
+
+func TestDurable(t *testing.T) {
+	bus := eventbus.New()
+	store := eventstore.New()
+	progress := handler.NewMemoryProgress()
+
+	now := time.Now()
+	events := make([]event.Event, 4)
+	for i := range events {
+		events[i] = event.New("foo", test.FooEventData{A: fmt.Sprint(i)}, event.Time(now.Add(time.Duration(i)*time.Second))).Any()
+	}
+
+	if err := store.Insert(context.Background(), events[:2]...); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	run := func(ctx context.Context) <-chan event.Event {
+		h := handler.New(bus, handler.Durable("foo-handler", store, progress))
+		handled := make(chan event.Event, len(events))
+		h.RegisterEventHandler("foo", func(evt event.Event) { handled <- evt })
+
+		errs, err := h.Run(ctx)
+		if err != nil {
+			t.Fatalf("Run() failed with %q", err)
+		}
+
+		go func() {
+			for err := range errs {
+				panic(err)
+			}
+		}()
+
+		return handled
+	}
+
+	expect := func(handled <-chan event.Event, want ...event.Event) {
+		t.Helper()
+		for _, evt := range want {
+			select {
+			case <-time.After(time.Second):
+				t.Fatalf("%q event was not handled", evt.Data())
+			case got := <-handled:
+				if got.ID() != evt.ID() {
+					t.Fatalf("expected %q event to be handled; got %q", evt.Data(), got.Data())
+				}
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	expect(run(ctx), events[:2]...)
+	cancel()
+
+	// The third event is inserted while the handler is not running.
+	if err := store.Insert(context.Background(), events[2]); err != nil {
+		t.Fatalf("Insert() failed with %q", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	handled := run(ctx)
+	expect(handled, events[2])
+
+	// Live events that were already handled during the catch-up are skipped.
+	if err := bus.Publish(ctx, events[2], events[3]); err != nil {
+		t.Fatalf("Publish() failed with %q", err)
+	}
+	expect(handled, events[3])
+
+	select {
+	case evt := <-handled:
+		t.Fatalf("no more events should be handled; got %q", evt.Data())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if last, ids, _ := progress.Load(ctx, "foo-handler"); !last.Equal(events[3].Time()) || len(ids) != 1 || ids[0] != events[3].ID() {
+		t.Fatalf("progress should be at the last handled event; got %v %v", last, ids)
+	}
+}
+
+func TestDurable_catchUpFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	progress := handler.NewMemoryProgress()
+
+	mockError := errors.New("mock error")
+	store := failingQueryStore{Store: eventstore.New(), err: mockError}
+
+	h := handler.New(bus, handler.Durable("foo-handler", store, progress))
+	handled := make(chan event.Event, 1)
+	h.RegisterEventHandler("foo", func(evt event.Event) { handled <- evt })
+
+	errs, err := h.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("catch-up error was not reported")
+	case err := <-errs:
+		if !errors.Is(err, mockError) {
+			t.Fatalf("expected error to wrap %q; got %q", mockError, err)
+		}
+	}
+
+	if err := bus.Publish(ctx, event.New("foo", test.FooEventData{}).Any()); err != nil {
+		t.Fatalf("Publish() failed with %q", err)
+	}
+
+	select {
+	case evt := <-handled:
+		t.Fatalf("live events should not be handled after a failed catch-up; got %q", evt.Data())
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if last, ids, _ := progress.Load(ctx, "foo-handler"); !last.IsZero() || len(ids) != 0 {
+		t.Fatalf("progress should not be saved after a failed catch-up; got %v %v", last, ids)
+	}
+}
+
+type failingQueryStore struct {
+	event.Store
+
+	err error
+}
+
+func (s failingQueryStore) Query(context.Context, event.Query) (<-chan event.Event, <-chan error, error) {
+	return nil, nil, s.err
+}
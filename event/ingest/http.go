@@ -0,0 +1,140 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultMaxBodySize is the default maximum size of a request body that is
+// accepted by Handler.
+const DefaultMaxBodySize = 1 << 20
+
+// HandlerOption is an option for Handler.
+type HandlerOption func(*handler)
+
+type handler struct {
+	ingester    *Ingester
+	source      string
+	maxBodySize int64
+}
+
+type jsonMessage struct {
+	ID       string            `json:"id"`
+	Source   string            `json:"source"`
+	Type     string            `json:"type"`
+	Time     time.Time         `json:"time"`
+	Data     json.RawMessage   `json:"data"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// HandlerSource returns a HandlerOption that sets the source of all messages
+// that are received by the handler, overriding the source in the request body.
+func HandlerSource(source string) HandlerOption {
+	return func(h *handler) {
+		h.source = source
+	}
+}
+
+// MaxBodySize returns a HandlerOption that limits the size of request bodies.
+// Defaults to DefaultMaxBodySize.
+func MaxBodySize(n int64) HandlerOption {
+	return func(h *handler) {
+		h.maxBodySize = n
+	}
+}
+
+// Handler returns an http.Handler that ingests messages that are POSTed as
+// JSON. The body is either a single message or an array of messages:
+//
+//	{
+//		"id": "4711",
+//		"source": "legacy-shop",
+//		"type": "order_created",
+//		"time": "2024-01-01T00:00:00Z",
+//		"data": {"orderId": "..."},
+//		"metadata": {"key": "value"}
+//	}
+//
+// The handler responds with 204 No Content if all messages were ingested, 400
+// Bad Request if the body is invalid or a message has an unknown type, and 500
+// Internal Server Error if ingesting failed. Because messages are
+// deduplicated, clients can safely retry failed requests.
+func Handler(ing *Ingester, opts ...HandlerOption) http.Handler {
+	h := &handler{ingester: ing, maxBodySize: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	msgs, err := h.decode(body)
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.ingester.Ingest(r.Context(), msgs...); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUnknownType) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) decode(body []byte) ([]Message, error) {
+	var raw []jsonMessage
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		var msg jsonMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return nil, err
+		}
+		raw = append(raw, msg)
+	}
+
+	msgs := make([]Message, len(raw))
+	for i, m := range raw {
+		if m.ID == "" || m.Type == "" {
+			return nil, errors.New("missing id or type")
+		}
+
+		msgs[i] = Message{
+			Source:   m.Source,
+			ID:       m.ID,
+			Type:     m.Type,
+			Time:     m.Time,
+			Data:     m.Data,
+			Metadata: m.Metadata,
+		}
+
+		if h.source != "" {
+			msgs[i].Source = h.source
+		}
+	}
+
+	return msgs, nil
+}
@@ -0,0 +1,265 @@
+// Package ingest ingests events from external systems.
+//
+// Legacy systems and third-party services usually publish their own messages
+// (e.g. via webhooks or message brokers) that do not follow the naming and
+// structure of goes events. An Ingester translates such messages into goes
+// events using user-defined translators, deduplicates them, inserts them into
+// an event store and optionally publishes them over an event bus, so that they
+// can be consumed by projections like any other event.
+//
+//	ing := ingest.New(store,
+//		ingest.Translate("order_created", ingest.TranslatorFunc(func(ctx context.Context, msg ingest.Message) ([]event.Event, error) {
+//			var data legacyOrder
+//			if err := json.Unmarshal(msg.Data, &data); err != nil {
+//				return nil, err
+//			}
+//			return []event.Event{
+//				event.New("shop.order.placed", OrderPlaced{...}, event.Aggregate(data.ID, "shop.order", 0)).Any(),
+//			}, nil
+//		})),
+//		ingest.Publish(bus),
+//	)
+//
+// Messages can be ingested directly using Ingester.Ingest, from a Source using
+// Ingester.Run, or over HTTP using Handler. Message brokers such as Kafka are
+// integrated by implementing a Source.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/internal/concurrent"
+)
+
+// Namespace is the UUID namespace that is used to derive the ids of ingested
+// events from the ids of their messages.
+var Namespace = uuid.MustParse("8a3a2e0c-5d0f-4c55-9f0b-4f3d7a6f1e21")
+
+// ErrUnknownType is returned by an Ingester if no translator is registered for
+// the type of a message.
+var ErrUnknownType = errors.New("unknown message type")
+
+// Message is a message from an external system.
+type Message struct {
+	// Source identifies the external system, e.g. "legacy-shop".
+	Source string
+
+	// ID is the id of the message within the source. Messages with the same
+	// source and id are ingested only once.
+	ID string
+
+	// Type is the type of the message within the source. The type determines
+	// the translator that is used to translate the message.
+	Type string
+
+	// Time is the time of the message. If Time is not zero, it is used as the
+	// time of the translated events, so that the events reflect when they
+	// actually happened. The n-th event of a message is shifted by n
+	// nanoseconds to keep the events ordered.
+	Time time.Time
+
+	// Data is the raw payload of the message.
+	Data []byte
+
+	// Metadata contains additional information about the message, e.g. headers.
+	Metadata map[string]string
+}
+
+// Translator translates messages from external systems into events.
+type Translator interface {
+	// Translate translates the message into events. The ids of the returned
+	// events are replaced by ids that are derived from the message. If an event
+	// belongs to an aggregate and its aggregate version is 0, the next version
+	// of the aggregate is assigned.
+	Translate(context.Context, Message) ([]event.Event, error)
+}
+
+// TranslatorFunc allows functions to be used as Translators.
+type TranslatorFunc func(context.Context, Message) ([]event.Event, error)
+
+// Source is a source of messages, e.g. a message broker topic.
+type Source interface {
+	// Messages returns a channel of messages from the source.
+	Messages(context.Context) (<-chan Message, <-chan error, error)
+}
+
+// Ingester ingests messages from external systems.
+type Ingester struct {
+	store       event.Store
+	bus         event.Bus
+	translators map[string]Translator
+}
+
+// Option is an option for an Ingester.
+type Option func(*Ingester)
+
+// Translate returns an Option that registers the translator for messages with
+// the given type.
+func Translate(msgType string, t Translator) Option {
+	return func(i *Ingester) {
+		i.translators[msgType] = t
+	}
+}
+
+// Publish returns an Option that makes the Ingester publish ingested events
+// over the given bus after inserting them into the event store.
+func Publish(bus event.Bus) Option {
+	return func(i *Ingester) {
+		i.bus = bus
+	}
+}
+
+// New returns an Ingester that inserts the ingested events into the given
+// event store.
+func New(store event.Store, opts ...Option) *Ingester {
+	i := &Ingester{
+		store:       store,
+		translators: make(map[string]Translator),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// EventID returns the id of the n-th event that is translated from the given
+// message. Event ids are derived deterministically from the source and id of
+// the message, which deduplicates messages that are delivered multiple times.
+func EventID(msg Message, n int) uuid.UUID {
+	return uuid.NewSHA1(Namespace, fmt.Appendf(nil, "%s\x00%s\x00%d", msg.Source, msg.ID, n))
+}
+
+// Ingest translates and ingests the given messages. Messages that have already
+// been ingested are skipped. Ingest returns the ingested events.
+func (i *Ingester) Ingest(ctx context.Context, msgs ...Message) ([]event.Event, error) {
+	var out []event.Event
+	for _, msg := range msgs {
+		events, err := i.ingest(ctx, msg)
+		if err != nil {
+			return out, fmt.Errorf("ingest %q message %q from %q: %w", msg.Type, msg.ID, msg.Source, err)
+		}
+		out = append(out, events...)
+	}
+	return out, nil
+}
+
+// Run ingests the messages from the given source until ctx is canceled or the
+// source is closed. Errors from the source and errors that occur while
+// ingesting messages are sent into the returned channel, which is closed when
+// ctx is canceled.
+func (i *Ingester) Run(ctx context.Context, src Source) (<-chan error, error) {
+	msgs, errs, err := src.Messages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to source: %w", err)
+	}
+
+	out, fail := concurrent.Errors(ctx)
+
+	go streams.ForEach(ctx, func(msg Message) {
+		if _, err := i.Ingest(ctx, msg); err != nil {
+			fail(err)
+		}
+	}, fail, msgs, errs)
+
+	return out, nil
+}
+
+func (i *Ingester) ingest(ctx context.Context, msg Message) ([]event.Event, error) {
+	translator, ok := i.translators[msg.Type]
+	if !ok {
+		return nil, ErrUnknownType
+	}
+
+	translated, err := translator.Translate(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("translate: %w", err)
+	}
+
+	versions := make(map[event.AggregateRef]int)
+
+	var events []event.Event
+	for n, evt := range translated {
+		id := EventID(msg, n)
+		if _, err := i.store.Find(ctx, id); err == nil {
+			continue
+		}
+
+		t := evt.Time()
+		if !msg.Time.IsZero() {
+			t = msg.Time.Add(time.Duration(n))
+		}
+		opts := []event.Option{event.ID(id), event.Time(t)}
+
+		if aid, aname, v := evt.Aggregate(); aname != "" {
+			if v == 0 {
+				ref := event.AggregateRef{Name: aname, ID: aid}
+				if v, err = i.nextVersion(ctx, ref, versions); err != nil {
+					return nil, err
+				}
+			}
+			opts = append(opts, event.Aggregate(aid, aname, v))
+		}
+
+		events = append(events, event.New(evt.Name(), evt.Data(), opts...).Any())
+	}
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	if err := i.store.Insert(ctx, events...); err != nil {
+		return nil, fmt.Errorf("insert events: %w", err)
+	}
+
+	if i.bus != nil {
+		if err := i.bus.Publish(ctx, events...); err != nil {
+			return events, fmt.Errorf("publish events: %w", err)
+		}
+	}
+
+	return events, nil
+}
+
+// nextVersion returns the next version of the given aggregate. The versions
+// that have been assigned to the events of the current message are tracked in
+// the provided map.
+func (i *Ingester) nextVersion(ctx context.Context, ref event.AggregateRef, versions map[event.AggregateRef]int) (int, error) {
+	if v, ok := versions[ref]; ok {
+		versions[ref] = v + 1
+		return v + 1, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	str, errs, err := i.store.Query(ctx, query.New(
+		query.Aggregate(ref.Name, ref.ID),
+		query.SortBy(event.SortAggregateVersion, event.SortDesc),
+	))
+	if err != nil {
+		return 0, fmt.Errorf("query version of %s: %w", ref, err)
+	}
+
+	var current int
+	if evt, err := streams.Take(ctx, 1, str, errs); err != nil {
+		return 0, fmt.Errorf("query version of %s: %w", ref, err)
+	} else if len(evt) > 0 {
+		_, _, current = evt[0].Aggregate()
+	}
+
+	versions[ref] = current + 1
+
+	return current + 1, nil
+}
+
+// Translate returns fn(ctx, msg).
+func (fn TranslatorFunc) Translate(ctx context.Context, msg Message) ([]event.Event, error) {
+	return fn(ctx, msg)
+}
@@ -0,0 +1,206 @@
+package ingest_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/ingest"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+)
+
+type legacyOrder struct {
+	OrderID uuid.UUID `json:"orderId"`
+	Total   int       `json:"total"`
+}
+
+type orderPlaced struct {
+	Total int
+}
+
+var translateOrder = ingest.TranslatorFunc(func(_ context.Context, msg ingest.Message) ([]event.Event, error) {
+	var data legacyOrder
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return nil, err
+	}
+	return []event.Event{
+		event.New("shop.order.placed", orderPlaced{Total: data.Total}, event.Aggregate(data.OrderID, "shop.order", 0)).Any(),
+	}, nil
+})
+
+func TestIngester_Ingest(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+	ing := ingest.New(store, ingest.Translate("order_created", translateOrder))
+
+	orderID := uuid.New()
+	msgTime := time.Now().Add(-time.Hour)
+	msg := ingest.Message{
+		Source: "legacy-shop",
+		ID:     "4711",
+		Type:   "order_created",
+		Time:   msgTime,
+		Data:   []byte(`{"orderId":"` + orderID.String() + `","total":42}`),
+	}
+
+	events, err := ing.Ingest(ctx, msg)
+	if err != nil {
+		t.Fatalf("Ingest() failed with %q", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Ingest() should return %d event; got %d", 1, len(events))
+	}
+
+	evt := events[0]
+	if evt.ID() != ingest.EventID(msg, 0) {
+		t.Fatalf("event id should be derived from the message")
+	}
+
+	if id, name, v := evt.Aggregate(); id != orderID || name != "shop.order" || v != 1 {
+		t.Fatalf("event should belong to %s:%s@%d; belongs to %s:%s@%d", "shop.order", orderID, 1, name, id, v)
+	}
+
+	if !evt.Time().Equal(msgTime) {
+		t.Fatalf("event time should be the message time %v; is %v", msgTime, evt.Time())
+	}
+
+	// duplicate message
+	if events, err := ing.Ingest(ctx, msg); err != nil || len(events) != 0 {
+		t.Fatalf("duplicate message should be skipped; got %d events and error %v", len(events), err)
+	}
+
+	next := msg
+	next.ID = "4712"
+	events, err = ing.Ingest(ctx, next)
+	if err != nil {
+		t.Fatalf("Ingest() failed with %q", err)
+	}
+
+	if _, _, v := events[0].Aggregate(); v != 2 {
+		t.Fatalf("next event should have version %d; has %d", 2, v)
+	}
+
+	str, errs := event.Must(store.Query(ctx, query.New()))
+	stored, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	if len(stored) != 2 {
+		t.Fatalf("store should contain %d events; contains %d", 2, len(stored))
+	}
+}
+
+func TestIngester_Ingest_unknownType(t *testing.T) {
+	ing := ingest.New(eventstore.New())
+
+	if _, err := ing.Ingest(context.Background(), ingest.Message{ID: "1", Type: "foo"}); !errors.Is(err, ingest.ErrUnknownType) {
+		t.Fatalf("Ingest() should fail with %q; got %q", ingest.ErrUnknownType, err)
+	}
+}
+
+func TestIngester_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := eventstore.New()
+	ing := ingest.New(store, ingest.Translate("order_created", translateOrder))
+
+	msgs := make(chan ingest.Message, 2)
+	msgs <- ingest.Message{Source: "legacy", ID: "1", Type: "order_created", Data: []byte(`{"orderId":"` + uuid.NewString() + `"}`)}
+	msgs <- ingest.Message{Source: "legacy", ID: "2", Type: "unknown"}
+	close(msgs)
+
+	errs, err := ing.Run(ctx, sourceFunc(func(context.Context) (<-chan ingest.Message, <-chan error, error) {
+		return msgs, nil, nil
+	}))
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("Run should report the error of the unknown message")
+	case err := <-errs:
+		if !errors.Is(err, ingest.ErrUnknownType) {
+			t.Fatalf("error should be %q; got %q", ingest.ErrUnknownType, err)
+		}
+	}
+
+	cancel()
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("error channel should be closed after ctx is canceled")
+	case _, ok := <-errs:
+		if ok {
+			t.Fatalf("error channel should be closed after ctx is canceled")
+		}
+	}
+
+	str, qerrs, err := store.Query(context.Background(), query.New())
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	events, err := streams.Drain(context.Background(), str, qerrs)
+	if err != nil {
+		t.Fatalf("query events: %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("store should contain %d event; contains %d", 1, len(events))
+	}
+}
+
+type sourceFunc func(context.Context) (<-chan ingest.Message, <-chan error, error)
+
+func (fn sourceFunc) Messages(ctx context.Context) (<-chan ingest.Message, <-chan error, error) {
+	return fn(ctx)
+}
+
+func TestHandler(t *testing.T) {
+	store := eventstore.New()
+	ing := ingest.New(store, ingest.Translate("order_created", translateOrder))
+	srv := httptest.NewServer(ingest.Handler(ing, ingest.HandlerSource("legacy-shop")))
+	defer srv.Close()
+
+	body := `[
+		{"id": "1", "type": "order_created", "data": {"orderId": "` + uuid.NewString() + `", "total": 1}},
+		{"id": "2", "type": "order_created", "data": {"orderId": "` + uuid.NewString() + `", "total": 2}}
+	]`
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("post messages: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status should be %d; is %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	if _, err := store.Find(context.Background(), ingest.EventID(ingest.Message{Source: "legacy-shop", ID: "2"}, 0)); err != nil {
+		t.Fatalf("event of message %q should have been ingested: %v", "2", err)
+	}
+
+	resp, err = http.Post(srv.URL, "application/json", strings.NewReader(`{"id": "3", "type": "unknown"}`))
+	if err != nil {
+		t.Fatalf("post message: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status should be %d; is %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
@@ -0,0 +1,217 @@
+// Package integration translates internal events into public integration
+// events.
+//
+// Events of an event-sourced service describe the internals of the service and
+// change whenever the service is refactored. Other services should not depend
+// on them. Instead, a service publishes a separate set of public events that
+// form a stable, versioned contract. A Catalog declares which public events
+// exist and how they are translated from internal events, and a Relay uses the
+// event store as an outbox to publish the public events over an external bus:
+//
+//	catalog := integration.NewCatalog(
+//		integration.Translate("shop.order.placed", integration.PublicEvent{
+//			Name:        "shop.order_placed",
+//			Version:     1,
+//			Description: "An order was placed by a customer.",
+//		}, func(evt event.Of[OrderPlaced]) (OrderPlacedV1, bool) {
+//			return OrderPlacedV1{OrderID: pick.AggregateID(evt), Total: evt.Data().Total}, true
+//		}),
+//	)
+//
+//	relay := integration.NewRelay(catalog, store, externalBus)
+//	errs, err := relay.Run(ctx)
+//
+// Public events are published under their subject, which includes the version
+// of the event (e.g. "shop.order_placed.v1"), so that multiple versions of a
+// public event can be published side by side while consumers migrate.
+package integration
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+)
+
+// Namespace is the UUID namespace that is used to derive the ids of public
+// events from the ids of the internal events they are translated from.
+var Namespace = uuid.MustParse("c6f3f8e2-4a0e-4d0b-8f66-3f5b7f0c9a11")
+
+// PublicEvent describes a public integration event.
+type PublicEvent struct {
+	// Name is the name of the public event, without the version.
+	Name string
+
+	// Version is the version of the public event. Breaking changes to the
+	// payload of a public event require a new version.
+	Version int
+
+	// Description documents the public event for consumers.
+	Description string
+
+	// Internal are the names of the internal events that are translated into
+	// the public event. Internal is filled by the Catalog.
+	Internal []string
+
+	// Type is the payload type of the public event. Type is filled by the
+	// Catalog.
+	Type reflect.Type
+}
+
+// Catalog declares the public events of a service and how they are translated
+// from internal events.
+type Catalog struct {
+	events       map[string]*PublicEvent
+	translations map[string][]translation
+	register     []func(codec.Registerer)
+}
+
+// CatalogOption is an option for a Catalog.
+type CatalogOption func(*Catalog)
+
+type translation struct {
+	subject   string
+	translate func(event.Event) (any, bool)
+}
+
+// Translate returns a CatalogOption that translates the internal events with
+// the given name into the given public event. If fn returns false, the
+// internal event is not published. An internal event may be translated into
+// multiple public events (e.g. multiple versions of the same public event),
+// and multiple internal events may be translated into the same public event.
+func Translate[Internal, Payload any](internal string, public PublicEvent, fn func(event.Of[Internal]) (Payload, bool)) CatalogOption {
+	return func(c *Catalog) {
+		subject := public.Subject()
+
+		pe, ok := c.events[subject]
+		if !ok {
+			public.Internal = nil
+			public.Type = reflect.TypeOf((*Payload)(nil)).Elem()
+			pe = &public
+			c.events[subject] = pe
+			c.register = append(c.register, func(r codec.Registerer) {
+				codec.Register[Payload](r, subject)
+			})
+		}
+		pe.Internal = append(pe.Internal, internal)
+
+		c.translations[internal] = append(c.translations[internal], translation{
+			subject: subject,
+			translate: func(evt event.Event) (any, bool) {
+				casted, ok := event.TryCast[Internal](evt)
+				if !ok {
+					return nil, false
+				}
+				return fn(casted)
+			},
+		})
+	}
+}
+
+// NewCatalog returns a new Catalog.
+func NewCatalog(opts ...CatalogOption) *Catalog {
+	c := &Catalog{
+		events:       make(map[string]*PublicEvent),
+		translations: make(map[string][]translation),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Subject returns the name under which the public event is published, which
+// is the name of the event followed by its version, e.g. "shop.order_placed.v1".
+func (e PublicEvent) Subject() string {
+	return fmt.Sprintf("%s.v%d", e.Name, e.Version)
+}
+
+// Events returns the public events of the Catalog, sorted by subject.
+func (c *Catalog) Events() []PublicEvent {
+	out := make([]PublicEvent, 0, len(c.events))
+	for _, pe := range c.events {
+		out = append(out, *pe)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out
+}
+
+// InternalEvents returns the names of the internal events that are translated
+// into public events, sorted by name.
+func (c *Catalog) InternalEvents() []string {
+	out := make([]string, 0, len(c.translations))
+	for name := range c.translations {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Subjects returns the subjects of the public events, sorted by subject.
+func (c *Catalog) Subjects() []string {
+	events := c.Events()
+	out := make([]string, len(events))
+	for i, pe := range events {
+		out[i] = pe.Subject()
+	}
+	return out
+}
+
+// Register registers the payloads of the public events under their subjects
+// into the given registry. Consumers and external buses use the registry to
+// encode and decode public events.
+func (c *Catalog) Register(r codec.Registerer) {
+	for _, register := range c.register {
+		register(r)
+	}
+}
+
+// Translate translates the given internal event into public events. The public
+// events have the time of the internal event and ids that are derived from the
+// id of the internal event, so that consumers can deduplicate public events
+// that are published multiple times. Public events do not belong to an
+// aggregate.
+func (c *Catalog) Translate(evt event.Event) []event.Event {
+	var out []event.Event
+	for _, t := range c.translations[evt.Name()] {
+		data, ok := t.translate(evt)
+		if !ok {
+			continue
+		}
+		out = append(out, event.New(t.subject, data, event.ID(PublicID(evt, t.subject)), event.Time(evt.Time())).Any())
+	}
+	return out
+}
+
+// PublicID returns the id of the public event with the given subject that is
+// translated from the given internal event.
+func PublicID(internal event.Event, subject string) uuid.UUID {
+	return uuid.NewSHA1(Namespace, fmt.Appendf(nil, "%s\x00%s", internal.ID(), subject))
+}
+
+// WriteMarkdown writes the documentation of the public events as Markdown to
+// the given writer.
+func (c *Catalog) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Public events\n")
+	for _, pe := range c.Events() {
+		fmt.Fprintf(&b, "\n## %s\n\n", pe.Subject())
+		if pe.Description != "" {
+			fmt.Fprintf(&b, "%s\n\n", pe.Description)
+		}
+		fmt.Fprintf(&b, "- Payload: `%s`\n", pe.Type)
+		fmt.Fprintf(&b, "- Translated from: `%s`\n", strings.Join(pe.Internal, "`, `"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
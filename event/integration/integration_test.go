@@ -0,0 +1,223 @@
+package integration_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	stdtime "time"
+
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/integration"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+)
+
+type orderPlacedV1 struct {
+	Total int
+}
+
+type orderPlacedV2 struct {
+	Total    int
+	Currency string
+}
+
+type placed struct {
+	Total int
+}
+
+func newCatalog() *integration.Catalog {
+	return integration.NewCatalog(
+		integration.Translate("order.placed", integration.PublicEvent{
+			Name:        "shop.order_placed",
+			Version:     1,
+			Description: "An order was placed.",
+		}, func(evt event.Of[placed]) (orderPlacedV1, bool) {
+			return orderPlacedV1{Total: evt.Data().Total}, evt.Data().Total > 0
+		}),
+		integration.Translate("order.placed", integration.PublicEvent{
+			Name:    "shop.order_placed",
+			Version: 2,
+		}, func(evt event.Of[placed]) (orderPlacedV2, bool) {
+			return orderPlacedV2{Total: evt.Data().Total, Currency: "EUR"}, true
+		}),
+	)
+}
+
+func TestCatalog_Translate(t *testing.T) {
+	c := newCatalog()
+
+	if want := []string{"shop.order_placed.v1", "shop.order_placed.v2"}; !slices.Equal(want, c.Subjects()) {
+		t.Fatalf("Subjects should return %v; got %v", want, c.Subjects())
+	}
+
+	if want := []string{"order.placed"}; !slices.Equal(want, c.InternalEvents()) {
+		t.Fatalf("InternalEvents should return %v; got %v", want, c.InternalEvents())
+	}
+
+	internal := event.New("order.placed", placed{Total: 42}).Any()
+	public := c.Translate(internal)
+
+	if len(public) != 2 {
+		t.Fatalf("Translate should return 2 events; got %d", len(public))
+	}
+
+	if public[0].Name() != "shop.order_placed.v1" || public[1].Name() != "shop.order_placed.v2" {
+		t.Fatalf("unexpected public events: %q, %q", public[0].Name(), public[1].Name())
+	}
+
+	if data := public[1].Data(); data != (orderPlacedV2{Total: 42, Currency: "EUR"}) {
+		t.Fatalf("unexpected data: %v", data)
+	}
+
+	for _, evt := range public {
+		if evt.ID() != integration.PublicID(internal, evt.Name()) {
+			t.Fatalf("public event should have derived id %s; got %s", integration.PublicID(internal, evt.Name()), evt.ID())
+		}
+		if !evt.Time().Equal(internal.Time()) {
+			t.Fatalf("public event should have time %v; got %v", internal.Time(), evt.Time())
+		}
+	}
+
+	if public := c.Translate(event.New("order.placed", placed{}).Any()); len(public) != 1 {
+		t.Fatalf("Translate should skip filtered public events; got %d events", len(public))
+	}
+
+	if public := c.Translate(event.New("foo", test.FooEventData{}).Any()); len(public) != 0 {
+		t.Fatalf("Translate should not translate unknown events; got %d events", len(public))
+	}
+}
+
+func TestCatalog_Register(t *testing.T) {
+	reg := codec.New()
+	newCatalog().Register(reg)
+
+	data, err := reg.New("shop.order_placed.v2")
+	if err != nil {
+		t.Fatalf("payload should be registered: %v", err)
+	}
+
+	if _, ok := data.(*orderPlacedV2); !ok {
+		t.Fatalf("registered payload should be %T; is %T", &orderPlacedV2{}, data)
+	}
+}
+
+func TestCatalog_WriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newCatalog().WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown failed with %q", err)
+	}
+
+	for _, want := range []string{"## shop.order_placed.v1", "An order was placed.", "## shop.order_placed.v2", "`order.placed`"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("documentation should contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestRelay_Flush(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := stdtime.Now()
+	events := []event.Event{
+		event.New("order.placed", placed{Total: 1}, event.Time(now)).Any(),
+		event.New("foo", test.FooEventData{}, event.Time(now)).Any(),
+		event.New("order.placed", placed{Total: 2}, event.Time(now)).Any(),
+	}
+
+	store := eventstore.New(slices.Clone(events)...)
+	bus := eventbus.New()
+
+	public := subscribe(ctx, t, bus, event.All)
+
+	relay := integration.NewRelay(newCatalog(), store, bus)
+
+	if err := relay.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed with %q", err)
+	}
+	expectPublished(t, public, 4)
+
+	if err := relay.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed with %q", err)
+	}
+	expectPublished(t, public, 0)
+
+	if err := store.Insert(ctx, event.New("order.placed", placed{Total: 3}, event.Time(now.Add(stdtime.Minute))).Any()); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	if err := relay.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed with %q", err)
+	}
+	expectPublished(t, public, 2)
+}
+
+func TestRelay_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := eventstore.New()
+	bus := eventbus.New()
+
+	public := subscribe(ctx, t, bus, "shop.order_placed.v2")
+
+	relay := integration.NewRelay(newCatalog(), store, bus, integration.PollInterval(10*stdtime.Millisecond))
+
+	errs, err := relay.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+	go func() {
+		for err := range errs {
+			t.Errorf("relay: %v", err)
+		}
+	}()
+
+	if _, err := relay.Run(ctx); !errors.Is(err, integration.ErrRunning) {
+		t.Fatalf("Run should fail with %q; got %q", integration.ErrRunning, err)
+	}
+
+	if err := store.Insert(ctx, event.New("order.placed", placed{Total: 1}).Any()); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	expectPublished(t, public, 1)
+}
+
+func subscribe(ctx context.Context, t *testing.T, bus event.Bus, names ...string) <-chan event.Event {
+	t.Helper()
+
+	events, errs, err := bus.Subscribe(ctx, names...)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	out := make(chan event.Event, 16)
+	go streams.ForEach(ctx, func(evt event.Event) { out <- evt }, func(error) {}, events, errs)
+
+	return out
+}
+
+func expectPublished(t *testing.T, public <-chan event.Event, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-stdtime.After(stdtime.Second):
+			t.Fatalf("expected %d published events; got %d", n, i)
+		case <-public:
+		}
+	}
+
+	select {
+	case evt := <-public:
+		t.Fatalf("unexpected published %q event", evt.Name())
+	case <-stdtime.After(50 * stdtime.Millisecond):
+	}
+}
@@ -0,0 +1,218 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/time"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/internal/concurrent"
+)
+
+// DefaultPollInterval is the default interval at which a Relay polls the event
+// store for new internal events.
+const DefaultPollInterval = stdtime.Second
+
+// ErrRunning is returned by Relay.Run if the Relay is already running.
+var ErrRunning = errors.New("relay is already running")
+
+// Checkpoint stores the position of a Relay within the event store.
+type Checkpoint interface {
+	// Load returns the time of the last published internal event, or the zero
+	// time if no event has been published yet.
+	Load(ctx context.Context) (stdtime.Time, []uuid.UUID, error)
+
+	// Save saves the time of the last published internal event, together with
+	// the ids of the published internal events that occurred at that time.
+	Save(ctx context.Context, t stdtime.Time, ids []uuid.UUID) error
+}
+
+// Relay publishes the public events of a Catalog over an external bus. The
+// event store acts as the outbox: internal events are inserted into the store
+// within the same operation that changes the aggregates, and the Relay
+// publishes their translations afterwards. A Relay delivers public events at
+// least once; consumers can deduplicate them using their ids.
+type Relay struct {
+	catalog      *Catalog
+	store        event.Store
+	bus          event.Bus
+	checkpoint   Checkpoint
+	pollInterval stdtime.Duration
+
+	flushMux   sync.Mutex
+	runningMux sync.Mutex
+	running    bool
+}
+
+// RelayOption is an option for a Relay.
+type RelayOption func(*Relay)
+
+// WithCheckpoint returns a RelayOption that stores the position of the Relay
+// in the given Checkpoint. Use a persistent Checkpoint in production, so that
+// public events are not published again after a restart. Defaults to a
+// MemoryCheckpoint.
+func WithCheckpoint(cp Checkpoint) RelayOption {
+	return func(r *Relay) {
+		r.checkpoint = cp
+	}
+}
+
+// PollInterval returns a RelayOption that sets the interval at which the Relay
+// polls the event store for new internal events. Defaults to
+// DefaultPollInterval.
+func PollInterval(d stdtime.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = d
+	}
+}
+
+// NewRelay returns a Relay that publishes the translations of the internal
+// events in the given store over the given external bus.
+func NewRelay(catalog *Catalog, store event.Store, bus event.Bus, opts ...RelayOption) *Relay {
+	r := &Relay{
+		catalog:      catalog,
+		store:        store,
+		bus:          bus,
+		pollInterval: DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.checkpoint == nil {
+		r.checkpoint = NewMemoryCheckpoint()
+	}
+	return r
+}
+
+// Run flushes the Relay at the configured poll interval until ctx is canceled.
+// Errors that occur while flushing are sent into the returned channel.
+func (r *Relay) Run(ctx context.Context) (<-chan error, error) {
+	r.runningMux.Lock()
+	defer r.runningMux.Unlock()
+
+	if r.running {
+		return nil, ErrRunning
+	}
+	r.running = true
+
+	out, fail := concurrent.Errors(ctx)
+
+	go func() {
+		defer func() {
+			r.runningMux.Lock()
+			defer r.runningMux.Unlock()
+			r.running = false
+		}()
+
+		ticker := stdtime.NewTicker(r.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+				fail(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Flush publishes the public events of all internal events that have been
+// inserted into the store since the last flush. The checkpoint is saved after
+// each published internal event, so that a failed flush resumes at the event
+// that failed.
+func (r *Relay) Flush(ctx context.Context) error {
+	r.flushMux.Lock()
+	defer r.flushMux.Unlock()
+
+	names := r.catalog.InternalEvents()
+	if len(names) == 0 {
+		return nil
+	}
+
+	since, ids, err := r.checkpoint.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+
+	opts := []query.Option{query.Name(names...), query.SortByTime()}
+	if !since.IsZero() {
+		opts = append(opts, query.Time(time.Min(since)))
+	}
+
+	str, errs, err := r.store.Query(ctx, query.New(opts...))
+	if err != nil {
+		return fmt.Errorf("query internal events: %w", err)
+	}
+
+	return streams.Walk(ctx, func(evt event.Event) error {
+		if seen[evt.ID()] {
+			return nil
+		}
+
+		for _, public := range r.catalog.Translate(evt) {
+			if err := r.bus.Publish(ctx, public); err != nil {
+				return fmt.Errorf("publish %q event (%s): %w", public.Name(), public.ID(), err)
+			}
+		}
+
+		if !evt.Time().Equal(since) {
+			since = evt.Time()
+			ids = ids[:0]
+			clear(seen)
+		}
+		ids = append(ids, evt.ID())
+		seen[evt.ID()] = true
+
+		if err := r.checkpoint.Save(ctx, since, ids); err != nil {
+			return fmt.Errorf("save checkpoint: %w", err)
+		}
+
+		return nil
+	}, str, errs)
+}
+
+// MemoryCheckpoint is a thread-safe in-memory Checkpoint. Useful for testing.
+type MemoryCheckpoint struct {
+	mux  sync.RWMutex
+	time stdtime.Time
+	ids  []uuid.UUID
+}
+
+// NewMemoryCheckpoint returns an in-memory Checkpoint.
+func NewMemoryCheckpoint() *MemoryCheckpoint {
+	return &MemoryCheckpoint{}
+}
+
+// Load implements Checkpoint.
+func (cp *MemoryCheckpoint) Load(context.Context) (stdtime.Time, []uuid.UUID, error) {
+	cp.mux.RLock()
+	defer cp.mux.RUnlock()
+	return cp.time, append([]uuid.UUID(nil), cp.ids...), nil
+}
+
+// Save implements Checkpoint.
+func (cp *MemoryCheckpoint) Save(_ context.Context, t stdtime.Time, ids []uuid.UUID) error {
+	cp.mux.Lock()
+	defer cp.mux.Unlock()
+	cp.time = t
+	cp.ids = append([]uuid.UUID(nil), ids...)
+	return nil
+}
@@ -0,0 +1,624 @@
+// Package replication asynchronously replicates events between the event
+// stores of multiple regions.
+//
+// Each region runs a Replicator that pulls the events of the other regions
+// into the local event store. Replication is idempotent: events that already
+// exist in the local store are skipped, so that events that were replicated
+// from region A into region B are not replicated back into region A.
+//
+//	origins := replication.NewMemoryOrigins()
+//	local := replication.Region{Name: "eu", Store: replication.Tag(euStore, "eu", origins)}
+//	r := replication.New(local, []replication.Region{
+//		{Name: "us", Store: usStore},
+//	}, replication.WithOrigins(origins))
+//
+//	errs, err := r.Run(ctx)
+//
+// If the same aggregate version has been written in two regions, the remote
+// event is not replicated and a Conflict is recorded instead. The aggregate is
+// then parked: the remaining events of the aggregate are not replicated from
+// that region, and the checkpoint of the region does not advance past the
+// conflicting event, until the conflict is resolved manually (e.g. by deleting
+// or compensating one of the conflicting events) and Resolve is called.
+// Report returns a reconciliation report of the replication.
+//
+// The position of a Replicator within a remote region is the time of the last
+// replicated event. Event stores do not provide a monotonic position, so an
+// event that is committed to a remote store later than events with a newer
+// time is missed if the checkpoint has already advanced past its time. Use the
+// Lookback option to rescan a window before the checkpoint on every sync;
+// events that are committed later than the window are still missed.
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/time"
+	"github.com/modernice/goes/event/query/version"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/internal/concurrent"
+)
+
+// DefaultPollInterval is the default interval at which a Replicator polls the
+// remote regions for new events.
+const DefaultPollInterval = 5 * stdtime.Second
+
+var (
+	// ErrRunning is returned by Replicator.Run if the Replicator is already
+	// running.
+	ErrRunning = errors.New("replicator is already running")
+
+	// ErrUnknownOrigin is returned by Origins if the origin of an event is
+	// unknown.
+	ErrUnknownOrigin = errors.New("unknown origin")
+)
+
+// Region is the event store of a region.
+type Region struct {
+	Name  string
+	Store event.Store
+}
+
+// Origins stores the regions in which events were originally written.
+type Origins interface {
+	// SetOrigin sets the origin region of the event with the given id.
+	SetOrigin(ctx context.Context, id uuid.UUID, region string) error
+
+	// Origin returns the origin region of the event with the given id.
+	// Implementations must return ErrUnknownOrigin if the origin is unknown.
+	Origin(ctx context.Context, id uuid.UUID) (string, error)
+}
+
+// Checkpoints store the position of a Replicator within the stores of the
+// remote regions.
+type Checkpoints interface {
+	// Load returns the time of the last replicated event of the given region,
+	// together with the ids of the replicated events that occurred at that
+	// time. Load returns the zero time if no event has been replicated yet.
+	Load(ctx context.Context, region string) (stdtime.Time, []uuid.UUID, error)
+
+	// Save saves the position of the Replicator within the given region.
+	Save(ctx context.Context, region string, t stdtime.Time, ids []uuid.UUID) error
+}
+
+// Conflict is an aggregate version that has been written in two regions.
+type Conflict struct {
+	// Region is the remote region in which the conflicting event was written.
+	Region string
+
+	AggregateName    string
+	AggregateID      uuid.UUID
+	AggregateVersion int
+
+	// LocalEvent is the id of the local event.
+	LocalEvent uuid.UUID
+
+	// RemoteEvent is the id of the remote event, which was not replicated.
+	RemoteEvent uuid.UUID
+
+	// DetectedAt is the time at which the conflict was detected.
+	DetectedAt stdtime.Time
+}
+
+// Report is a reconciliation report of a Replicator.
+type Report struct {
+	// Region is the local region.
+	Region string
+
+	// Remotes are the reports of the remote regions.
+	Remotes []RemoteReport
+}
+
+// RemoteReport is the replication report of a remote region.
+type RemoteReport struct {
+	Region string
+
+	// Replicated is the number of events that were replicated into the local
+	// store.
+	Replicated int
+
+	// Skipped is the number of events that already existed in the local store.
+	Skipped int
+
+	// Conflicts are the detected conflicts.
+	Conflicts []Conflict
+
+	// Checkpoint is the time of the last replicated event.
+	Checkpoint stdtime.Time
+
+	// LastSync is the time of the last successful synchronization.
+	LastSync stdtime.Time
+
+	// LastError is the error of the last failed synchronization. LastError is
+	// reset after the next successful synchronization.
+	LastError error
+}
+
+// Replicator replicates the events of remote regions into the store of the
+// local region.
+type Replicator struct {
+	local        Region
+	remotes      []Region
+	origins      Origins
+	checkpoints  Checkpoints
+	pollInterval stdtime.Duration
+	lookback     stdtime.Duration
+	onConflict   []func(Conflict)
+
+	syncMux sync.Mutex
+	// scanned is the time of the latest event that has been processed per
+	// remote region. Events that are rescanned are not reported again.
+	scanned map[string]stdtime.Time
+
+	reportMux sync.RWMutex
+	reports   map[string]*RemoteReport
+	parked    map[string]map[event.AggregateRef]Conflict
+
+	runMux  sync.Mutex
+	running bool
+}
+
+// Option is an option for a Replicator.
+type Option func(*Replicator)
+
+// WithOrigins returns an Option that records the origin region of replicated
+// events in the given Origins. Use Tag to record the origin of events that are
+// written in the local region.
+func WithOrigins(origins Origins) Option {
+	return func(r *Replicator) {
+		r.origins = origins
+	}
+}
+
+// WithCheckpoints returns an Option that stores the position of the Replicator
+// within the remote regions in the given Checkpoints. Use persistent
+// Checkpoints in production, so that the remote regions are not scanned from
+// the beginning after a restart. Defaults to in-memory checkpoints.
+func WithCheckpoints(cp Checkpoints) Option {
+	return func(r *Replicator) {
+		r.checkpoints = cp
+	}
+}
+
+// PollInterval returns an Option that sets the interval at which the
+// Replicator polls the remote regions. Defaults to DefaultPollInterval.
+func PollInterval(d stdtime.Duration) Option {
+	return func(r *Replicator) {
+		r.pollInterval = d
+	}
+}
+
+// Lookback returns an Option that makes the Replicator query the remote regions
+// from the given duration before the checkpoint, so that events that were
+// committed late, within the duration, are replicated too. Events that already
+// exist in the local store are skipped. By default, events are queried from
+// the checkpoint.
+func Lookback(d stdtime.Duration) Option {
+	return func(r *Replicator) {
+		r.lookback = d
+	}
+}
+
+// OnConflict returns an Option that calls fn for every detected Conflict.
+func OnConflict(fn func(Conflict)) Option {
+	return func(r *Replicator) {
+		r.onConflict = append(r.onConflict, fn)
+	}
+}
+
+// New returns a Replicator that replicates the events of the remote regions
+// into the store of the local region.
+func New(local Region, remotes []Region, opts ...Option) *Replicator {
+	r := &Replicator{
+		local:        local,
+		remotes:      remotes,
+		pollInterval: DefaultPollInterval,
+		scanned:      make(map[string]stdtime.Time),
+		reports:      make(map[string]*RemoteReport),
+		parked:       make(map[string]map[event.AggregateRef]Conflict),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.checkpoints == nil {
+		r.checkpoints = NewMemoryCheckpoints()
+	}
+	for _, remote := range remotes {
+		r.reports[remote.Name] = &RemoteReport{Region: remote.Name}
+		r.parked[remote.Name] = make(map[event.AggregateRef]Conflict)
+	}
+	return r
+}
+
+// Run synchronizes the remote regions at the configured poll interval until
+// ctx is canceled. Errors are sent into the returned channel.
+func (r *Replicator) Run(ctx context.Context) (<-chan error, error) {
+	r.runMux.Lock()
+	defer r.runMux.Unlock()
+
+	if r.running {
+		return nil, ErrRunning
+	}
+	r.running = true
+
+	out, fail := concurrent.Errors(ctx)
+
+	go func() {
+		defer func() {
+			r.runMux.Lock()
+			defer r.runMux.Unlock()
+			r.running = false
+		}()
+
+		ticker := stdtime.NewTicker(r.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := r.Sync(ctx); err != nil && ctx.Err() == nil {
+				fail(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Sync replicates the new events of all remote regions into the local store.
+// A failing region does not prevent the other regions from being synchronized.
+func (r *Replicator) Sync(ctx context.Context) error {
+	r.syncMux.Lock()
+	defer r.syncMux.Unlock()
+
+	var errs []error
+	for _, remote := range r.remotes {
+		err := r.sync(ctx, remote)
+
+		r.reportMux.Lock()
+		report := r.reports[remote.Name]
+		report.LastError = err
+		if err == nil {
+			report.LastSync = stdtime.Now()
+		}
+		r.reportMux.Unlock()
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("region %q: %w", remote.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Replicator) sync(ctx context.Context, remote Region) error {
+	since, ids, err := r.checkpoints.Load(ctx, remote.Name)
+	if err != nil {
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+
+	opts := []query.Option{query.SortByTime()}
+	if !since.IsZero() {
+		opts = append(opts, query.Time(time.Min(since.Add(-r.lookback))))
+	}
+
+	str, errs, err := remote.Store.Query(ctx, query.New(opts...))
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	// The checkpoint is frozen at the first event of a parked aggregate, so
+	// that the aggregate is replicated from there after the conflict has been
+	// resolved.
+	var frozen bool
+	scanned := r.scanned[remote.Name]
+
+	return streams.Walk(ctx, func(evt event.Event) error {
+		if seen[evt.ID()] {
+			return nil
+		}
+
+		if id, name, _ := evt.Aggregate(); id != uuid.Nil && name != "" && r.isParked(remote.Name, event.AggregateRef{Name: name, ID: id}) {
+			frozen = true
+			return nil
+		}
+
+		rescan := !evt.Time().After(scanned)
+		if !rescan {
+			scanned = evt.Time()
+			r.scanned[remote.Name] = scanned
+		}
+
+		parked, err := r.replicate(ctx, remote, evt, rescan)
+		if err != nil {
+			return fmt.Errorf("replicate %q event (%s): %w", evt.Name(), evt.ID(), err)
+		}
+
+		if parked {
+			frozen = true
+		}
+
+		// Events before the checkpoint were committed late and do not move
+		// the checkpoint.
+		if frozen || evt.Time().Before(since) {
+			return nil
+		}
+
+		if !evt.Time().Equal(since) {
+			since = evt.Time()
+			ids = ids[:0]
+			clear(seen)
+		}
+		ids = append(ids, evt.ID())
+		seen[evt.ID()] = true
+
+		if err := r.checkpoints.Save(ctx, remote.Name, since, ids); err != nil {
+			return fmt.Errorf("save checkpoint: %w", err)
+		}
+
+		r.reportMux.Lock()
+		r.reports[remote.Name].Checkpoint = since
+		r.reportMux.Unlock()
+
+		return nil
+	}, str, errs)
+}
+
+// replicate replicates the given event into the local store. If the event
+// conflicts with a local event, the aggregate of the event is parked and
+// replicate reports true. Rescanned events that already exist in the local
+// store are not counted as skipped again.
+func (r *Replicator) replicate(ctx context.Context, remote Region, evt event.Event, rescan bool) (bool, error) {
+	if _, err := r.local.Store.Find(ctx, evt.ID()); err == nil {
+		if !rescan {
+			r.updateReport(remote.Name, func(report *RemoteReport) { report.Skipped++ })
+		}
+		return false, nil
+	}
+
+	if id, name, v := evt.Aggregate(); id != uuid.Nil && name != "" {
+		local, err := r.findVersion(ctx, name, id, v)
+		if err != nil {
+			return false, fmt.Errorf("check for conflicts: %w", err)
+		}
+
+		if local != nil {
+			conflict := Conflict{
+				Region:           remote.Name,
+				AggregateName:    name,
+				AggregateID:      id,
+				AggregateVersion: v,
+				LocalEvent:       local.ID(),
+				RemoteEvent:      evt.ID(),
+				DetectedAt:       stdtime.Now(),
+			}
+			r.updateReport(remote.Name, func(report *RemoteReport) {
+				report.Conflicts = append(report.Conflicts, conflict)
+				r.parked[remote.Name][event.AggregateRef{Name: name, ID: id}] = conflict
+			})
+			for _, fn := range r.onConflict {
+				fn(conflict)
+			}
+			return true, nil
+		}
+	}
+
+	origin := remote.Name
+	if r.origins != nil {
+		o, err := r.origins.Origin(ctx, evt.ID())
+		if err != nil && !errors.Is(err, ErrUnknownOrigin) {
+			return false, fmt.Errorf("get origin: %w", err)
+		}
+		if err == nil {
+			origin = o
+		}
+	}
+
+	if err := r.local.Store.Insert(ctx, evt); err != nil {
+		return false, fmt.Errorf("insert event: %w", err)
+	}
+
+	// The origin is set after inserting the event, because a tagged local
+	// store sets the local region as the origin.
+	if r.origins != nil {
+		if err := r.origins.SetOrigin(ctx, evt.ID(), origin); err != nil {
+			return false, fmt.Errorf("set origin to %q: %w", origin, err)
+		}
+	}
+
+	r.updateReport(remote.Name, func(report *RemoteReport) { report.Replicated++ })
+
+	return false, nil
+}
+
+func (r *Replicator) findVersion(ctx context.Context, name string, id uuid.UUID, v int) (event.Event, error) {
+	str, errs, err := r.local.Store.Query(ctx, query.New(
+		query.Aggregate(name, id),
+		query.AggregateVersion(version.Exact(v)),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+
+	return events[0], nil
+}
+
+func (r *Replicator) isParked(region string, ref event.AggregateRef) bool {
+	r.reportMux.RLock()
+	defer r.reportMux.RUnlock()
+	_, ok := r.parked[region][ref]
+	return ok
+}
+
+// Resolve unparks the aggregate of the given conflict. Call Resolve after the
+// conflict has been resolved in the event stores. The events of the aggregate
+// are then replicated again, starting at the conflicting event. If the
+// conflict still exists, it is detected again and the aggregate is parked
+// again.
+func (r *Replicator) Resolve(c Conflict) {
+	r.reportMux.Lock()
+	defer r.reportMux.Unlock()
+	delete(r.parked[c.Region], event.AggregateRef{Name: c.AggregateName, ID: c.AggregateID})
+}
+
+// Parked returns the conflicts of the aggregates that are currently parked.
+func (r *Replicator) Parked() []Conflict {
+	r.reportMux.RLock()
+	defer r.reportMux.RUnlock()
+
+	var out []Conflict
+	for _, remote := range r.remotes {
+		for _, c := range r.parked[remote.Name] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (r *Replicator) updateReport(region string, fn func(*RemoteReport)) {
+	r.reportMux.Lock()
+	defer r.reportMux.Unlock()
+	fn(r.reports[region])
+}
+
+// Report returns the reconciliation report of the Replicator.
+func (r *Replicator) Report() Report {
+	r.reportMux.RLock()
+	defer r.reportMux.RUnlock()
+
+	report := Report{Region: r.local.Name}
+	for _, remote := range r.remotes {
+		rr := *r.reports[remote.Name]
+		rr.Conflicts = append([]Conflict(nil), rr.Conflicts...)
+		report.Remotes = append(report.Remotes, rr)
+	}
+
+	return report
+}
+
+// Conflicts returns all conflicts of the report.
+func (r Report) Conflicts() []Conflict {
+	var out []Conflict
+	for _, remote := range r.Remotes {
+		out = append(out, remote.Conflicts...)
+	}
+	return out
+}
+
+// Tag returns an event store that records the given region as the origin of
+// the events that are inserted into the provided store. A Replicator that uses
+// the same Origins corrects the origin of the events it replicates.
+func Tag(store event.Store, region string, origins Origins) event.Store {
+	return &taggedStore{Store: store, region: region, origins: origins}
+}
+
+type taggedStore struct {
+	event.Store
+
+	region  string
+	origins Origins
+}
+
+func (s *taggedStore) Insert(ctx context.Context, events ...event.Event) error {
+	if err := s.Store.Insert(ctx, events...); err != nil {
+		return err
+	}
+	for _, evt := range events {
+		if err := s.origins.SetOrigin(ctx, evt.ID(), s.region); err != nil {
+			return fmt.Errorf("set origin of %q event (%s) to %q: %w", evt.Name(), evt.ID(), s.region, err)
+		}
+	}
+	return nil
+}
+
+// Origin returns the origin region of the given event, or an empty string if
+// the origin is unknown.
+func Origin(ctx context.Context, origins Origins, evt event.Event) (string, error) {
+	origin, err := origins.Origin(ctx, evt.ID())
+	if errors.Is(err, ErrUnknownOrigin) {
+		return "", nil
+	}
+	return origin, err
+}
+
+// MemoryOrigins is a thread-safe in-memory Origins. Useful for testing.
+type MemoryOrigins struct {
+	mux     sync.RWMutex
+	origins map[uuid.UUID]string
+}
+
+// NewMemoryOrigins returns in-memory Origins.
+func NewMemoryOrigins() *MemoryOrigins {
+	return &MemoryOrigins{origins: make(map[uuid.UUID]string)}
+}
+
+// SetOrigin implements Origins.
+func (o *MemoryOrigins) SetOrigin(_ context.Context, id uuid.UUID, region string) error {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.origins[id] = region
+	return nil
+}
+
+// Origin implements Origins.
+func (o *MemoryOrigins) Origin(_ context.Context, id uuid.UUID) (string, error) {
+	o.mux.RLock()
+	defer o.mux.RUnlock()
+	if origin, ok := o.origins[id]; ok {
+		return origin, nil
+	}
+	return "", ErrUnknownOrigin
+}
+
+// MemoryCheckpoints are thread-safe in-memory Checkpoints.
+type MemoryCheckpoints struct {
+	mux         sync.RWMutex
+	checkpoints map[string]memoryCheckpoint
+}
+
+type memoryCheckpoint struct {
+	time stdtime.Time
+	ids  []uuid.UUID
+}
+
+// NewMemoryCheckpoints returns in-memory Checkpoints.
+func NewMemoryCheckpoints() *MemoryCheckpoints {
+	return &MemoryCheckpoints{checkpoints: make(map[string]memoryCheckpoint)}
+}
+
+// Load implements Checkpoints.
+func (cp *MemoryCheckpoints) Load(_ context.Context, region string) (stdtime.Time, []uuid.UUID, error) {
+	cp.mux.RLock()
+	defer cp.mux.RUnlock()
+	c := cp.checkpoints[region]
+	return c.time, append([]uuid.UUID(nil), c.ids...), nil
+}
+
+// Save implements Checkpoints.
+func (cp *MemoryCheckpoints) Save(_ context.Context, region string, t stdtime.Time, ids []uuid.UUID) error {
+	cp.mux.Lock()
+	defer cp.mux.Unlock()
+	cp.checkpoints[region] = memoryCheckpoint{time: t, ids: append([]uuid.UUID(nil), ids...)}
+	return nil
+}
@@ -0,0 +1,270 @@
+package replication_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/replication"
+	"github.com/modernice/goes/event/test"
+)
+
+func TestReplicator_Sync(t *testing.T) {
+	ctx := context.Background()
+
+	origins := replication.NewMemoryOrigins()
+	eu := replication.Region{Name: "eu", Store: replication.Tag(eventstore.New(), "eu", origins)}
+	us := replication.Region{Name: "us", Store: eventstore.New()}
+
+	now := time.Now()
+	aggregateID := uuid.New()
+
+	euEvents := []event.Event{
+		event.New("foo", test.FooEventData{}, event.Time(now), event.Aggregate(aggregateID, "foo", 1)).Any(),
+		event.New("foo", test.FooEventData{}, event.Time(now.Add(time.Second)), event.Aggregate(aggregateID, "foo", 2)).Any(),
+	}
+	usEvents := []event.Event{
+		event.New("foo", test.FooEventData{}, event.Time(now), event.Aggregate(aggregateID, "foo", 1)).Any(),
+		event.New("foo", test.FooEventData{}, event.Time(now.Add(time.Second)), event.Aggregate(aggregateID, "foo", 2)).Any(),
+		event.New("bar", test.BarEventData{}, event.Time(now.Add(2*time.Second))).Any(),
+	}
+
+	if err := eu.Store.Insert(ctx, euEvents[0]); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+	// the first event was replicated from eu to us before the regions diverged
+	if err := us.Store.Insert(ctx, euEvents[0], usEvents[1], usEvents[2]); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+	if err := eu.Store.Insert(ctx, euEvents[1]); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	var conflicts []replication.Conflict
+	r := replication.New(eu, []replication.Region{us},
+		replication.WithOrigins(origins),
+		replication.OnConflict(func(c replication.Conflict) { conflicts = append(conflicts, c) }),
+	)
+
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed with %q", err)
+	}
+
+	if _, err := eu.Store.Find(ctx, usEvents[2].ID()); err != nil {
+		t.Fatalf("%q event should be replicated: %v", "bar", err)
+	}
+
+	if _, err := eu.Store.Find(ctx, usEvents[1].ID()); err == nil {
+		t.Fatalf("conflicting event should not be replicated")
+	}
+
+	report := r.Report()
+	if len(report.Remotes) != 1 {
+		t.Fatalf("report should have 1 remote region; has %d", len(report.Remotes))
+	}
+
+	rr := report.Remotes[0]
+	if rr.Replicated != 1 || rr.Skipped != 1 || len(rr.Conflicts) != 1 {
+		t.Fatalf("unexpected report: %+v", rr)
+	}
+
+	c := report.Conflicts()[0]
+	if c.AggregateVersion != 2 || c.LocalEvent != euEvents[1].ID() || c.RemoteEvent != usEvents[1].ID() {
+		t.Fatalf("unexpected conflict: %+v", c)
+	}
+
+	if len(conflicts) != 1 {
+		t.Fatalf("OnConflict should be called once; was called %d times", len(conflicts))
+	}
+
+	if origin, _ := replication.Origin(ctx, origins, usEvents[2]); origin != "us" {
+		t.Fatalf("origin of replicated event should be %q; is %q", "us", origin)
+	}
+
+	if origin, _ := replication.Origin(ctx, origins, euEvents[1]); origin != "eu" {
+		t.Fatalf("origin of local event should be %q; is %q", "eu", origin)
+	}
+
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed with %q", err)
+	}
+
+	if rr := r.Report().Remotes[0]; rr.Replicated != 1 || rr.Skipped != 1 || len(rr.Conflicts) != 1 {
+		t.Fatalf("second Sync should not process events again: %+v", rr)
+	}
+}
+
+func TestReplicator_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eu := replication.Region{Name: "eu", Store: eventstore.New()}
+	us := replication.Region{Name: "us", Store: eventstore.New()}
+
+	r := replication.New(eu, []replication.Region{us}, replication.PollInterval(10*time.Millisecond))
+
+	errs, err := r.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+	go func() {
+		for err := range errs {
+			t.Errorf("replicator: %v", err)
+		}
+	}()
+
+	if _, err := r.Run(ctx); !errors.Is(err, replication.ErrRunning) {
+		t.Fatalf("Run should fail with %q; got %q", replication.ErrRunning, err)
+	}
+
+	evt := event.New("foo", test.FooEventData{}).Any()
+	if err := us.Store.Insert(ctx, evt); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	deadline := time.After(time.Second)
+	for {
+		if _, err := eu.Store.Find(ctx, evt.ID()); err == nil {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("event was not replicated")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestReplicator_Sync_parksConflictingAggregate(t *testing.T) {
+	ctx := context.Background()
+
+	eu := replication.Region{Name: "eu", Store: eventstore.New()}
+	us := replication.Region{Name: "us", Store: eventstore.New()}
+
+	now := time.Now()
+	aggregateID := uuid.New()
+
+	local := event.New("foo", test.FooEventData{}, event.Time(now), event.Aggregate(aggregateID, "foo", 1)).Any()
+	remote := []event.Event{
+		event.New("foo", test.FooEventData{}, event.Time(now), event.Aggregate(aggregateID, "foo", 1)).Any(),
+		event.New("foo", test.FooEventData{}, event.Time(now.Add(time.Second)), event.Aggregate(aggregateID, "foo", 2)).Any(),
+		event.New("bar", test.BarEventData{}, event.Time(now.Add(2*time.Second))).Any(),
+	}
+
+	if err := eu.Store.Insert(ctx, local); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+	if err := us.Store.Insert(ctx, remote...); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	r := replication.New(eu, []replication.Region{us})
+
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed with %q", err)
+	}
+
+	if _, err := eu.Store.Find(ctx, remote[1].ID()); err == nil {
+		t.Fatalf("events of a parked aggregate should not be replicated")
+	}
+
+	if _, err := eu.Store.Find(ctx, remote[2].ID()); err != nil {
+		t.Fatalf("events of other aggregates should be replicated: %v", err)
+	}
+
+	if rr := r.Report().Remotes[0]; !rr.Checkpoint.IsZero() {
+		t.Fatalf("checkpoint should not advance past the conflicting event; is %v", rr.Checkpoint)
+	}
+
+	parked := r.Parked()
+	if len(parked) != 1 || parked[0].RemoteEvent != remote[0].ID() {
+		t.Fatalf("aggregate of the conflicting event should be parked; parked: %+v", parked)
+	}
+
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed with %q", err)
+	}
+
+	if rr := r.Report().Remotes[0]; rr.Replicated != 1 || rr.Skipped != 0 || len(rr.Conflicts) != 1 {
+		t.Fatalf("second Sync should not process events again: %+v", rr)
+	}
+
+	// resolve the conflict by discarding the local event
+	if err := eu.Store.Delete(ctx, local); err != nil {
+		t.Fatalf("delete event: %v", err)
+	}
+	r.Resolve(parked[0])
+
+	if err := r.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed with %q", err)
+	}
+
+	for _, evt := range remote[:2] {
+		if _, err := eu.Store.Find(ctx, evt.ID()); err != nil {
+			t.Fatalf("events of a resolved aggregate should be replicated: %v", err)
+		}
+	}
+
+	if rr := r.Report().Remotes[0]; !rr.Checkpoint.Equal(remote[2].Time()) {
+		t.Fatalf("checkpoint should be %v; is %v", remote[2].Time(), rr.Checkpoint)
+	}
+
+	if len(r.Parked()) != 0 {
+		t.Fatalf("no aggregate should be parked; parked: %+v", r.Parked())
+	}
+}
+
+func TestLookback(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+	first := event.New("foo", test.FooEventData{}, event.Time(now)).Any()
+	late := event.New("foo", test.FooEventData{}, event.Time(now.Add(-time.Second))).Any()
+
+	tests := []struct {
+		name       string
+		opts       []replication.Option
+		replicated bool
+	}{
+		{name: "default", replicated: false},
+		{name: "lookback", opts: []replication.Option{replication.Lookback(time.Minute)}, replicated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eu := replication.Region{Name: "eu", Store: eventstore.New()}
+			us := replication.Region{Name: "us", Store: eventstore.New()}
+
+			r := replication.New(eu, []replication.Region{us}, tt.opts...)
+
+			if err := us.Store.Insert(ctx, first); err != nil {
+				t.Fatalf("insert event: %v", err)
+			}
+
+			if err := r.Sync(ctx); err != nil {
+				t.Fatalf("Sync failed with %q", err)
+			}
+
+			// an event that is committed after the checkpoint advanced past its time
+			if err := us.Store.Insert(ctx, late); err != nil {
+				t.Fatalf("insert event: %v", err)
+			}
+
+			if err := r.Sync(ctx); err != nil {
+				t.Fatalf("Sync failed with %q", err)
+			}
+
+			if _, err := eu.Store.Find(ctx, late.ID()); (err == nil) != tt.replicated {
+				t.Fatalf("late event should be replicated: %t; Find() returned %v", tt.replicated, err)
+			}
+
+			if rr := r.Report().Remotes[0]; !rr.Checkpoint.Equal(first.Time()) {
+				t.Fatalf("checkpoint should stay at %v; is %v", first.Time(), rr.Checkpoint)
+			}
+		})
+	}
+}
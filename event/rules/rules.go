@@ -0,0 +1,389 @@
+// Package rules forwards events using simple routing rules.
+//
+// Not every integration justifies a saga or a dedicated service. A Rule
+// describes a lightweight integration: "when an event matches a predicate,
+// republish it under another name or dispatch a command". An Engine executes
+// rules for the events that are published over an event bus:
+//
+//	engine := rules.New(bus,
+//		rules.Add(rules.Rule{
+//			Name:   "notify-billing",
+//			Events: []string{"shop.order.placed"},
+//			When:   rules.AggregateName("shop.order"),
+//			Actions: []rules.Action{
+//				rules.Republish(bus, "billing.order.placed"),
+//			},
+//		}),
+//		rules.Deduplicate(rules.NewMemoryDeduplicator()),
+//		rules.Startup(store),
+//	)
+//
+//	errs, err := engine.Run(ctx)
+//
+// Rules can also be loaded from configuration using LoadSpecs.
+//
+// Events and commands that are created by actions have ids that are derived
+// from the rule and the triggering event, so that consumers can deduplicate
+// them if an event is forwarded multiple times. The Engine itself remembers
+// which events have been forwarded by which rules using a Deduplicator, and
+// with the Startup option catches up on events that were published while the
+// Engine was not running or whose forwarding failed.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/internal/concurrent"
+)
+
+// Namespace is the UUID namespace that is used to derive the ids of forwarded
+// events and dispatched commands.
+var Namespace = uuid.MustParse("2b0c64f4-8a52-4e0e-9a9b-6c1e1fd3e5a7")
+
+// ErrRunning is returned by Engine.Run if the Engine is already running.
+var ErrRunning = errors.New("engine is already running")
+
+// Rule is a routing rule.
+type Rule struct {
+	// Name is the unique name of the rule. The name is used to deduplicate
+	// forwarded events and must not change once the rule is in use.
+	Name string
+
+	// Events are the names of the events that trigger the rule.
+	Events []string
+
+	// When is an optional predicate that an event must match to trigger the rule.
+	When Predicate
+
+	// Actions are executed in order when the rule is triggered.
+	Actions []Action
+}
+
+// Predicate reports whether an event matches.
+type Predicate func(event.Event) bool
+
+// Action is executed when a rule is triggered.
+type Action interface {
+	// Execute executes the action for the given rule and triggering event.
+	Execute(ctx context.Context, rule string, evt event.Event) error
+}
+
+// ActionFunc allows functions to be used as Actions.
+type ActionFunc func(ctx context.Context, rule string, evt event.Event) error
+
+// Deduplicator remembers which events have been forwarded by which rules.
+type Deduplicator interface {
+	// Processed reports whether the given key has been marked as processed.
+	Processed(ctx context.Context, key string) (bool, error)
+
+	// MarkProcessed marks the given key as processed.
+	MarkProcessed(ctx context.Context, key string) error
+}
+
+// Engine executes rules for the events that are published over an event bus.
+type Engine struct {
+	bus          event.Bus
+	rules        []Rule
+	dedup        Deduplicator
+	startupStore event.Store
+	startupQuery []query.Option
+
+	mux     sync.Mutex
+	running bool
+}
+
+// Option is an option for an Engine.
+type Option func(*Engine)
+
+// Add returns an Option that adds rules to the Engine.
+func Add(rules ...Rule) Option {
+	return func(e *Engine) {
+		e.rules = append(e.rules, rules...)
+	}
+}
+
+// Deduplicate returns an Option that makes the Engine skip events that have
+// already been forwarded by a rule. Use a persistent Deduplicator in
+// production, so that events are not forwarded again after a restart.
+func Deduplicate(d Deduplicator) Option {
+	return func(e *Engine) {
+		e.dedup = d
+	}
+}
+
+// Startup returns an Option that makes the Engine query the events of its rules
+// from the given event store when it starts, so that events that were missed
+// while the Engine was not running are forwarded. Startup should be used
+// together with Deduplicate. By default, all events of the rules are queried,
+// sorted by time. If query options are provided, they replace the default query.
+func Startup(store event.Store, opts ...query.Option) Option {
+	return func(e *Engine) {
+		e.startupStore = store
+		e.startupQuery = opts
+	}
+}
+
+// New returns an Engine that executes rules for the events that are published
+// over the given bus.
+func New(bus event.Bus, opts ...Option) *Engine {
+	e := &Engine{bus: bus}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Rules returns the rules of the Engine.
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Run subscribes to the events of the rules and executes the rules until ctx
+// is canceled. Errors that occur while executing rules are sent into the
+// returned channel. Events whose forwarding failed are not marked as processed
+// and are forwarded again on the next startup.
+func (e *Engine) Run(ctx context.Context) (<-chan error, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	if e.running {
+		return nil, ErrRunning
+	}
+
+	names := e.eventNames()
+
+	events, errs, err := e.bus.Subscribe(ctx, names...)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to %v events: %w", names, err)
+	}
+
+	e.running = true
+
+	out, fail := concurrent.Errors(ctx)
+
+	go func() {
+		defer func() {
+			e.mux.Lock()
+			defer e.mux.Unlock()
+			e.running = false
+		}()
+
+		if e.startupStore != nil {
+			if err := e.startup(ctx, names, fail); err != nil {
+				fail(fmt.Errorf("startup: %w", err))
+			}
+		}
+
+		streams.ForEach(ctx, func(evt event.Event) {
+			e.Handle(ctx, evt, fail)
+		}, fail, events, errs)
+	}()
+
+	return out, nil
+}
+
+// Handle executes the rules that match the given event. Errors are passed to
+// fail. Handle can be used to feed events into the Engine without a bus.
+func (e *Engine) Handle(ctx context.Context, evt event.Event, fail func(error)) {
+	for _, rule := range e.rules {
+		if err := e.execute(ctx, rule, evt); err != nil {
+			fail(fmt.Errorf("rule %q: %q event (%s): %w", rule.Name, evt.Name(), evt.ID(), err))
+		}
+	}
+}
+
+func (e *Engine) execute(ctx context.Context, rule Rule, evt event.Event) error {
+	if !rule.Matches(evt) {
+		return nil
+	}
+
+	key := rule.Name + ":" + evt.ID().String()
+
+	if e.dedup != nil {
+		processed, err := e.dedup.Processed(ctx, key)
+		if err != nil {
+			return fmt.Errorf("check if processed: %w", err)
+		}
+		if processed {
+			return nil
+		}
+	}
+
+	for i, action := range rule.Actions {
+		if err := action.Execute(ctx, rule.Name, evt); err != nil {
+			return fmt.Errorf("action #%d: %w", i, err)
+		}
+	}
+
+	if e.dedup != nil {
+		if err := e.dedup.MarkProcessed(ctx, key); err != nil {
+			return fmt.Errorf("mark as processed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) startup(ctx context.Context, names []string, fail func(error)) error {
+	opts := e.startupQuery
+	if len(opts) == 0 {
+		opts = []query.Option{query.Name(names...), query.SortByTime()}
+	}
+
+	str, errs, err := e.startupStore.Query(ctx, query.New(opts...))
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	return streams.Walk(ctx, func(evt event.Event) error {
+		e.Handle(ctx, evt, fail)
+		return nil
+	}, str, errs)
+}
+
+func (e *Engine) eventNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, rule := range e.rules {
+		for _, name := range rule.Events {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Matches reports whether the given event triggers the rule.
+func (r Rule) Matches(evt event.Event) bool {
+	var named bool
+	for _, name := range r.Events {
+		if name == evt.Name() || name == event.All {
+			named = true
+			break
+		}
+	}
+	return named && (r.When == nil || r.When(evt))
+}
+
+// AggregateName returns a Predicate that matches events of aggregates with one
+// of the given names.
+func AggregateName(names ...string) Predicate {
+	return func(evt event.Event) bool {
+		name := pick.AggregateName(evt)
+		for _, n := range names {
+			if n == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// And returns a Predicate that matches events that match all of the given
+// predicates.
+func And(preds ...Predicate) Predicate {
+	return func(evt event.Event) bool {
+		for _, p := range preds {
+			if !p(evt) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns a Predicate that matches events that match any of the given
+// predicates.
+func Or(preds ...Predicate) Predicate {
+	return func(evt event.Event) bool {
+		for _, p := range preds {
+			if p(evt) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// DerivedID returns the id of an event or command that is created by the given
+// rule from the given event under the given name.
+func DerivedID(rule string, evt event.Event, name string) uuid.UUID {
+	return uuid.NewSHA1(Namespace, fmt.Appendf(nil, "%s\x00%s\x00%s", rule, evt.ID(), name))
+}
+
+// Republish returns an Action that publishes the triggering event under the
+// given name over the given bus. The republished event has the data and time
+// of the triggering event, but does not belong to an aggregate.
+func Republish(bus event.Bus, name string) Action {
+	return ActionFunc(func(ctx context.Context, rule string, evt event.Event) error {
+		forwarded := event.New(name, evt.Data(), event.ID(DerivedID(rule, evt, name)), event.Time(evt.Time()))
+		if err := bus.Publish(ctx, forwarded.Any()); err != nil {
+			return fmt.Errorf("publish %q event: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Dispatch returns an Action that dispatches the command with the given name
+// over the given bus. The payload of the command is returned by payload, or is
+// the data of the triggering event if payload is nil.
+func Dispatch(bus command.Bus, name string, payload func(event.Event) any, opts ...command.DispatchOption) Action {
+	return ActionFunc(func(ctx context.Context, rule string, evt event.Event) error {
+		var pl any
+		if payload != nil {
+			pl = payload(evt)
+		} else {
+			pl = evt.Data()
+		}
+
+		cmd := command.New(name, pl, command.ID(DerivedID(rule, evt, name)))
+		if err := bus.Dispatch(ctx, cmd.Any(), opts...); err != nil {
+			return fmt.Errorf("dispatch %q command: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Execute returns fn(ctx, rule, evt).
+func (fn ActionFunc) Execute(ctx context.Context, rule string, evt event.Event) error {
+	return fn(ctx, rule, evt)
+}
+
+// MemoryDeduplicator is a thread-safe in-memory Deduplicator. Useful for
+// testing.
+type MemoryDeduplicator struct {
+	mux       sync.RWMutex
+	processed map[string]struct{}
+}
+
+// NewMemoryDeduplicator returns an in-memory Deduplicator.
+func NewMemoryDeduplicator() *MemoryDeduplicator {
+	return &MemoryDeduplicator{processed: make(map[string]struct{})}
+}
+
+// Processed implements Deduplicator.
+func (d *MemoryDeduplicator) Processed(_ context.Context, key string) (bool, error) {
+	d.mux.RLock()
+	defer d.mux.RUnlock()
+	_, ok := d.processed[key]
+	return ok, nil
+}
+
+// MarkProcessed implements Deduplicator.
+func (d *MemoryDeduplicator) MarkProcessed(_ context.Context, key string) error {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.processed[key] = struct{}{}
+	return nil
+}
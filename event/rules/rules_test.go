@@ -0,0 +1,218 @@
+package rules_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/rules"
+	"github.com/modernice/goes/event/test"
+)
+
+func TestEngine_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+
+	forwarded, _, err := bus.Subscribe(ctx, "bar")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	engine := rules.New(bus, rules.Add(rules.Rule{
+		Name:    "foo-to-bar",
+		Events:  []string{"foo"},
+		When:    rules.AggregateName("foo"),
+		Actions: []rules.Action{rules.Republish(bus, "bar")},
+	}))
+
+	errs, err := engine.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+	go func() {
+		for err := range errs {
+			t.Errorf("engine: %v", err)
+		}
+	}()
+
+	if _, err := engine.Run(ctx); !errors.Is(err, rules.ErrRunning) {
+		t.Fatalf("Run should fail with %q if the engine is running; got %q", rules.ErrRunning, err)
+	}
+
+	ignored := event.New("foo", test.FooEventData{A: "ignored"}, event.Aggregate(uuid.New(), "bar", 1))
+	evt := event.New("foo", test.FooEventData{A: "foo"}, event.Aggregate(uuid.New(), "foo", 1)).Any()
+
+	if err := bus.Publish(ctx, ignored.Any(), evt); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("event was not forwarded")
+	case got := <-forwarded:
+		if got.ID() != rules.DerivedID("foo-to-bar", evt, "bar") {
+			t.Fatalf("forwarded event should have the derived id")
+		}
+		if got.Data() != evt.Data() {
+			t.Fatalf("forwarded event should have data %v; has %v", evt.Data(), got.Data())
+		}
+	}
+
+	select {
+	case got := <-forwarded:
+		t.Fatalf("only one event should be forwarded; got %v", got.Data())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEngine_Handle_deduplicate(t *testing.T) {
+	ctx := context.Background()
+
+	var mux sync.Mutex
+	var calls int
+	action := rules.ActionFunc(func(context.Context, string, event.Event) error {
+		mux.Lock()
+		defer mux.Unlock()
+		calls++
+		if calls == 1 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+
+	engine := rules.New(eventbus.New(),
+		rules.Add(rules.Rule{Name: "rule", Events: []string{"foo"}, Actions: []rules.Action{action}}),
+		rules.Deduplicate(rules.NewMemoryDeduplicator()),
+	)
+
+	evt := event.New("foo", test.FooEventData{}).Any()
+
+	var failed []error
+	fail := func(err error) { failed = append(failed, err) }
+
+	for i := 0; i < 3; i++ {
+		engine.Handle(ctx, evt, fail)
+	}
+
+	if len(failed) != 1 {
+		t.Fatalf("the first execution should fail; got errors %v", failed)
+	}
+
+	if calls != 2 {
+		t.Fatalf("a failed event should be retried, and a forwarded event should not be forwarded again; action was called %d times", calls)
+	}
+}
+
+func TestStartup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	missed := event.New("foo", test.FooEventData{A: "missed"}).Any()
+	store := eventstore.New(missed)
+
+	cmds := &commandBus{}
+
+	engine := rules.New(eventbus.New(),
+		rules.Add(rules.Rule{
+			Name:   "dispatch",
+			Events: []string{"foo"},
+			Actions: []rules.Action{rules.Dispatch(cmds, "do", func(evt event.Event) any {
+				return evt.Data().(test.FooEventData).A
+			})},
+		}),
+		rules.Startup(store),
+	)
+
+	if _, err := engine.Run(ctx); err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(cmds.dispatched()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("missed event was not forwarded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cmd := cmds.dispatched()[0]
+	if cmd.Name() != "do" || cmd.Payload() != "missed" {
+		t.Fatalf("unexpected command %q with payload %v", cmd.Name(), cmd.Payload())
+	}
+
+	if cmd.ID() != rules.DerivedID("dispatch", missed, "do") {
+		t.Fatalf("command should have the derived id")
+	}
+}
+
+func TestLoadSpecs(t *testing.T) {
+	specs, err := rules.LoadSpecs(strings.NewReader(`[
+		{"name": "a", "events": ["foo"], "aggregates": ["foo"], "republish": ["bar"]},
+		{"name": "b", "events": ["foo"], "dispatch": ["do"]}
+	]`))
+	if err != nil {
+		t.Fatalf("LoadSpecs failed with %q", err)
+	}
+
+	if _, err := rules.Rules(rules.Targets{Events: eventbus.New()}, specs...); err == nil {
+		t.Fatalf("Rules should fail without a command bus")
+	}
+
+	rs, err := rules.Rules(rules.Targets{Events: eventbus.New(), Commands: &commandBus{}}, specs...)
+	if err != nil {
+		t.Fatalf("Rules failed with %q", err)
+	}
+
+	if len(rs) != 2 || len(rs[0].Actions) != 1 || len(rs[1].Actions) != 1 {
+		t.Fatalf("unexpected rules %v", rs)
+	}
+
+	evt := event.New("foo", test.FooEventData{}, event.Aggregate(uuid.New(), "bar", 1)).Any()
+	if rs[0].Matches(evt) {
+		t.Fatalf("rule %q should not match events of %q aggregates", rs[0].Name, "bar")
+	}
+	if !rs[1].Matches(evt) {
+		t.Fatalf("rule %q should match %q events", rs[1].Name, "foo")
+	}
+
+	for _, invalid := range []string{
+		`[{"events": ["foo"], "republish": ["bar"]}]`,
+		`[{"name": "a", "republish": ["bar"]}]`,
+		`[{"name": "a", "events": ["foo"]}]`,
+		`[{"name": "a", "events": ["foo"], "republish": ["bar"]}, {"name": "a", "events": ["foo"], "republish": ["bar"]}]`,
+	} {
+		if _, err := rules.LoadSpecs(strings.NewReader(invalid)); err == nil {
+			t.Errorf("LoadSpecs should fail for %s", invalid)
+		}
+	}
+}
+
+type commandBus struct {
+	command.Bus
+
+	mux  sync.Mutex
+	cmds []command.Command
+}
+
+func (bus *commandBus) Dispatch(_ context.Context, cmd command.Command, _ ...command.DispatchOption) error {
+	bus.mux.Lock()
+	defer bus.mux.Unlock()
+	bus.cmds = append(bus.cmds, cmd)
+	return nil
+}
+
+func (bus *commandBus) dispatched() []command.Command {
+	bus.mux.Lock()
+	defer bus.mux.Unlock()
+	return append([]command.Command(nil), bus.cmds...)
+}
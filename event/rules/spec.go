@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/event"
+)
+
+// Spec is the configuration of a Rule. Specs are used to define rules in
+// configuration files instead of code:
+//
+//	[
+//		{
+//			"name": "notify-billing",
+//			"events": ["shop.order.placed"],
+//			"aggregates": ["shop.order"],
+//			"republish": ["billing.order.placed"]
+//		},
+//		{
+//			"name": "reserve-stock",
+//			"events": ["shop.order.placed"],
+//			"dispatch": ["stock.reserve"]
+//		}
+//	]
+type Spec struct {
+	// Name is the name of the rule.
+	Name string `json:"name"`
+
+	// Events are the names of the events that trigger the rule.
+	Events []string `json:"events"`
+
+	// Aggregates optionally restricts the rule to events of aggregates with
+	// one of the given names.
+	Aggregates []string `json:"aggregates,omitempty"`
+
+	// Republish are the names under which the triggering event is republished.
+	Republish []string `json:"republish,omitempty"`
+
+	// Dispatch are the names of the commands that are dispatched with the data
+	// of the triggering event as the payload.
+	Dispatch []string `json:"dispatch,omitempty"`
+}
+
+// Targets are the buses that the actions of rules created from Specs use.
+type Targets struct {
+	Events   event.Bus
+	Commands command.Bus
+}
+
+// LoadSpecs decodes a JSON array of Specs from the given reader and validates
+// them.
+func LoadSpecs(r io.Reader) ([]Spec, error) {
+	var specs []Spec
+	if err := json.NewDecoder(r).Decode(&specs); err != nil {
+		return nil, fmt.Errorf("decode specs: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i, s := range specs {
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("spec #%d: %w", i, err)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("spec #%d: duplicate rule name %q", i, s.Name)
+		}
+		names[s.Name] = true
+	}
+
+	return specs, nil
+}
+
+// Validate validates the Spec.
+func (s Spec) Validate() error {
+	if s.Name == "" {
+		return errors.New("missing name")
+	}
+	if len(s.Events) == 0 {
+		return fmt.Errorf("rule %q: missing events", s.Name)
+	}
+	if len(s.Republish) == 0 && len(s.Dispatch) == 0 {
+		return fmt.Errorf("rule %q: no actions", s.Name)
+	}
+	return nil
+}
+
+// Rule returns the Rule that is described by the Spec. An error is returned if
+// the Spec is invalid or if it requires a bus that is missing in targets.
+func (s Spec) Rule(targets Targets) (Rule, error) {
+	if err := s.Validate(); err != nil {
+		return Rule{}, err
+	}
+
+	rule := Rule{Name: s.Name, Events: s.Events}
+
+	if len(s.Aggregates) > 0 {
+		rule.When = AggregateName(s.Aggregates...)
+	}
+
+	if len(s.Republish) > 0 && targets.Events == nil {
+		return Rule{}, fmt.Errorf("rule %q: republishing events requires an event bus", s.Name)
+	}
+	for _, name := range s.Republish {
+		rule.Actions = append(rule.Actions, Republish(targets.Events, name))
+	}
+
+	if len(s.Dispatch) > 0 && targets.Commands == nil {
+		return Rule{}, fmt.Errorf("rule %q: dispatching commands requires a command bus", s.Name)
+	}
+	for _, name := range s.Dispatch {
+		rule.Actions = append(rule.Actions, Dispatch(targets.Commands, name, nil))
+	}
+
+	return rule, nil
+}
+
+// Rules returns the Rules that are described by the given Specs.
+func Rules(targets Targets, specs ...Spec) ([]Rule, error) {
+	rules := make([]Rule, len(specs))
+	for i, s := range specs {
+		r, err := s.Rule(targets)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = r
+	}
+	return rules, nil
+}
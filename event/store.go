@@ -2,6 +2,7 @@ package event
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 
@@ -43,6 +44,11 @@ const (
 
 // #endregion sortings
 
+// ErrNotFound is returned by a Store if an event cannot be found. Store
+// implementations wrap ErrNotFound in the error returned by Find, so that
+// callers can distinguish missing events from other failures.
+var ErrNotFound = errors.New("event not found")
+
 // #region store
 //
 // Store is an interface that provides methods for managing Event storage,
@@ -56,8 +62,8 @@ type Store interface {
 	Insert(context.Context, ...Event) error
 
 	// Find retrieves the Event with the specified UUID from the Store. It returns
-	// an error if the Event could not be found or if there was an issue accessing
-	// the Store.
+	// an error that wraps ErrNotFound if the Event could not be found, or another
+	// error if there was an issue accessing the Store.
 	Find(context.Context, uuid.UUID) (Event, error)
 
 	// Query searches for Events in the Store that match the provided Query and
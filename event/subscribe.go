@@ -0,0 +1,114 @@
+package event
+
+import (
+	"context"
+	"fmt"
+)
+
+// SubscribeOption is an option for Subscribe.
+type SubscribeOption func(*subscription)
+
+type subscription struct {
+	mappers []func(context.Context, Event) (Event, error)
+}
+
+// Map returns a SubscribeOption that transforms the events of a subscription
+// before they are received, e.g. to decrypt event data or to resolve the
+// references of events. Map transforms each event once per subscription,
+// instead of once in every handler of the event. If fn returns a nil event, the
+// event is dropped. If fn returns an error, the event is dropped and the error
+// is sent into the error channel of the subscription. Multiple Map options are
+// applied in the order they are provided.
+//
+//	events, errs, err := event.Subscribe(ctx, bus, []string{"foo"}, event.Map(
+//		func(ctx context.Context, evt event.Event) (event.Event, error) {
+//			return decrypt(ctx, evt)
+//		},
+//	))
+func Map(fn func(context.Context, Event) (Event, error)) SubscribeOption {
+	return func(s *subscription) {
+		s.mappers = append(s.mappers, fn)
+	}
+}
+
+// Subscribe subscribes to the given events using the provided Subscriber and
+// applies the given options to the subscription. Without options, Subscribe
+// returns the channels of the underlying subscription as-is.
+func Subscribe(ctx context.Context, sub Subscriber, names []string, opts ...SubscribeOption) (<-chan Event, <-chan error, error) {
+	var cfg subscription
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	events, errs, err := sub.Subscribe(ctx, names...)
+	if err != nil || len(cfg.mappers) == 0 {
+		return events, errs, err
+	}
+
+	out, outErrs := make(chan Event), make(chan error)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		for events != nil || errs != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case outErrs <- err:
+				}
+			case evt, ok := <-events:
+				if !ok {
+					events = nil
+					break
+				}
+
+				evt, err := cfg.transform(ctx, evt)
+				if err != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case outErrs <- err:
+					}
+					break
+				}
+
+				if evt == nil {
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case out <- evt:
+				}
+			}
+		}
+	}()
+
+	return out, outErrs, nil
+}
+
+func (s subscription) transform(ctx context.Context, evt Event) (Event, error) {
+	for _, fn := range s.mappers {
+		name, id := evt.Name(), evt.ID()
+
+		var err error
+		if evt, err = fn(ctx, evt); err != nil {
+			return nil, fmt.Errorf("map %q event: %w [id=%s]", name, err, id)
+		}
+
+		if evt == nil {
+			return nil, nil
+		}
+	}
+	return evt, nil
+}
@@ -0,0 +1,93 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+)
+
+func TestSubscribe_Map(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	mockError := errors.New("mock error")
+
+	events, errs, err := event.Subscribe(ctx, bus, []string{"foo"},
+		event.Map(func(_ context.Context, evt event.Event) (event.Event, error) {
+			switch data := evt.Data().(string); data {
+			case "fail":
+				return nil, mockError
+			case "drop":
+				return nil, nil
+			default:
+				return event.New[any](evt.Name(), strings.ToUpper(data), event.ID(evt.ID())).Any(), nil
+			}
+		}),
+		event.Map(func(_ context.Context, evt event.Event) (event.Event, error) {
+			return event.New[any](evt.Name(), evt.Data().(string)+"!", event.ID(evt.ID())).Any(), nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	go func() {
+		for _, data := range []string{"fail", "drop", "bar"} {
+			if err := bus.Publish(ctx, event.New[any]("foo", data).Any()); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("timed out")
+	case evt := <-events:
+		t.Fatalf("event should have been dropped; got %v", evt.Data())
+	case err := <-errs:
+		if !errors.Is(err, mockError) {
+			t.Fatalf("error should unwrap to %q; got %q", mockError, err)
+		}
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("timed out")
+	case err := <-errs:
+		t.Fatal(err)
+	case evt := <-events:
+		if evt.Data() != "BAR!" {
+			t.Fatalf("event data should be mapped to %q; is %q", "BAR!", evt.Data())
+		}
+	}
+}
+
+func TestSubscribe_noOptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+
+	events, _, err := event.Subscribe(ctx, bus, []string{"foo"})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	evt := event.New[any]("foo", "bar").Any()
+	go bus.Publish(ctx, evt)
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("timed out")
+	case received := <-events:
+		if received.ID() != evt.ID() {
+			t.Fatalf("received wrong event")
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package gtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/handler"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/pick"
+)
+
+// CommandTest tests that handling a command by an aggregate in a given state
+// produces exactly the expected events.
+//
+//	gtest.Given(auth.NewUser,
+//		gtest.Past("auth.user.created", auth.UserCreation{Username: "Alice"}),
+//	).When(
+//		command.New("auth.user.rename", "Bob"),
+//	).Then(
+//		gtest.Emits("auth.user.renamed", auth.UserRenamed{Old: "Alice", New: "Bob"}),
+//	).Run(t)
+//
+// The aggregate is created with the id of the command, or with a random id if
+// the command has no aggregate, and the past events are applied to it before
+// the command is handled using the aggregate's HandleCommand method.
+type CommandTest[A handler.Aggregate] struct {
+	newFunc   func(uuid.UUID) A
+	history   []PastEvent
+	cmd       command.Command
+	matchers  []EventMatcher
+	unordered bool
+	wantErr   func(error) bool
+}
+
+// PastEvent is an event that is applied to the aggregate of a [CommandTest]
+// before the command is handled.
+type PastEvent struct {
+	Name string
+	Data any
+}
+
+// EventMatcher matches an event that is produced by the aggregate of a
+// [CommandTest].
+type EventMatcher struct {
+	// Name is the name of the expected event.
+	Name string
+
+	// Match reports whether the payload of the event matches. If Match returns
+	// an error, the payload does not match. A nil Match matches any payload.
+	Match func(any) error
+}
+
+// Past returns a PastEvent with the given name and data.
+func Past(name string, data any) PastEvent {
+	return PastEvent{Name: name, Data: data}
+}
+
+// Emits returns an EventMatcher that matches events with the given name and a
+// payload that is equal to data. Payloads are compared using go-cmp.
+func Emits[Data any](name string, data Data, opts ...cmp.Option) EventMatcher {
+	return EventMatcher{
+		Name: name,
+		Match: func(got any) error {
+			gotData, ok := got.(Data)
+			if !ok {
+				return fmt.Errorf("payload should be %T; is %T", data, got)
+			}
+			if !cmp.Equal(data, gotData, opts...) {
+				return fmt.Errorf("payload does not match:\n%s", cmp.Diff(data, gotData, opts...))
+			}
+			return nil
+		},
+	}
+}
+
+// EmitsMatching returns an EventMatcher that matches events with the given name
+// and a payload for which match returns true.
+func EmitsMatching[Data any](name string, match func(Data) bool) EventMatcher {
+	return EventMatcher{
+		Name: name,
+		Match: func(got any) error {
+			gotData, ok := got.(Data)
+			if !ok {
+				var zero Data
+				return fmt.Errorf("payload should be %T; is %T", zero, got)
+			}
+			if !match(gotData) {
+				return fmt.Errorf("payload %v does not match", gotData)
+			}
+			return nil
+		},
+	}
+}
+
+// EmitsSignal returns an EventMatcher that matches events with the given name,
+// regardless of their payload.
+func EmitsSignal(name string) EventMatcher {
+	return EventMatcher{Name: name}
+}
+
+// Given returns a CommandTest for the aggregate that is created by newFunc and
+// has the given past events applied.
+func Given[A handler.Aggregate](newFunc func(uuid.UUID) A, history ...PastEvent) *CommandTest[A] {
+	return &CommandTest[A]{newFunc: newFunc, history: history}
+}
+
+// When sets the command that is handled by the aggregate.
+func (test *CommandTest[A]) When(cmd command.Command) *CommandTest[A] {
+	test.cmd = cmd
+	return test
+}
+
+// Then sets the events that the aggregate must produce, in the given order.
+// Calling Then without matchers expects the aggregate to produce no events.
+func (test *CommandTest[A]) Then(matchers ...EventMatcher) *CommandTest[A] {
+	test.matchers = matchers
+	test.unordered = false
+	return test
+}
+
+// ThenUnordered sets the events that the aggregate must produce, in any order.
+func (test *CommandTest[A]) ThenUnordered(matchers ...EventMatcher) *CommandTest[A] {
+	test.matchers = matchers
+	test.unordered = true
+	return test
+}
+
+// ThenFails expects the command to fail with an error for which match returns
+// true, and the aggregate to produce no events. A nil match accepts any error.
+func (test *CommandTest[A]) ThenFails(match func(error) bool) *CommandTest[A] {
+	if match == nil {
+		match = func(error) bool { return true }
+	}
+	test.wantErr = match
+	test.matchers = nil
+	return test
+}
+
+// Run runs the test.
+func (test *CommandTest[A]) Run(t *testing.T) {
+	t.Helper()
+
+	if test.cmd == nil {
+		t.Fatalf("CommandTest has no command. Did you forget to call When()?")
+	}
+
+	changes, err := test.handle()
+
+	if test.wantErr != nil {
+		if err == nil {
+			t.Errorf("%q command should fail", test.cmd.Name())
+		} else if !test.wantErr(err) {
+			t.Errorf("%q command failed with unexpected error %q", test.cmd.Name(), err)
+		}
+	} else if err != nil {
+		t.Errorf("%q command failed with %q", test.cmd.Name(), err)
+		return
+	}
+
+	if err := test.match(changes); err != nil {
+		t.Errorf("%q command: %v", test.cmd.Name(), err)
+	}
+}
+
+func (test *CommandTest[A]) handle() ([]event.Event, error) {
+	id := test.cmd.Aggregate().ID
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+
+	a := test.newFunc(id)
+
+	history := make([]event.Event, len(test.history))
+	for i, past := range test.history {
+		history[i] = event.New(past.Name, past.Data, event.Aggregate(id, pick.AggregateName(a), i+1)).Any()
+	}
+
+	if err := aggregate.ApplyHistory(a, history); err != nil {
+		return nil, fmt.Errorf("apply history: %w", err)
+	}
+
+	if err := a.HandleCommand(command.NewContext(context.Background(), test.cmd)); err != nil {
+		return a.AggregateChanges(), err
+	}
+
+	return a.AggregateChanges(), nil
+}
+
+func (test *CommandTest[A]) match(changes []event.Event) error {
+	if test.unordered {
+		return matchUnordered(test.matchers, changes)
+	}
+
+	if len(changes) != len(test.matchers) {
+		return fmt.Errorf("should produce %d events; produced %d %s", len(test.matchers), len(changes), eventNames(changes))
+	}
+
+	for i, m := range test.matchers {
+		if err := m.matches(changes[i]); err != nil {
+			return fmt.Errorf("event #%d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func matchUnordered(matchers []EventMatcher, changes []event.Event) error {
+	if len(changes) != len(matchers) {
+		return fmt.Errorf("should produce %d events; produced %d %s", len(matchers), len(changes), eventNames(changes))
+	}
+
+	matched := make([]bool, len(changes))
+	for _, m := range matchers {
+		var found bool
+		var payloadErr error
+		for i, evt := range changes {
+			if matched[i] || evt.Name() != m.Name {
+				continue
+			}
+			if err := m.matches(evt); err != nil {
+				payloadErr = err
+				continue
+			}
+			matched[i] = true
+			found = true
+			break
+		}
+		if !found {
+			if payloadErr != nil {
+				return fmt.Errorf("no matching event: %w", payloadErr)
+			}
+			return fmt.Errorf("should produce %q event; produced %s", m.Name, eventNames(changes))
+		}
+	}
+
+	return nil
+}
+
+func (m EventMatcher) matches(evt event.Event) error {
+	if evt.Name() != m.Name {
+		return fmt.Errorf("should be %q; is %q", m.Name, evt.Name())
+	}
+
+	if m.Match == nil {
+		return nil
+	}
+
+	if err := m.Match(evt.Data()); err != nil {
+		return fmt.Errorf("%q event: %w", m.Name, err)
+	}
+
+	return nil
+}
+
+func eventNames(events []event.Event) []string {
+	names := make([]string, len(events))
+	for i, evt := range events {
+		names[i] = evt.Name()
+	}
+	return names
+}
@@ -0,0 +1,105 @@
+package gtest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/handler"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/exp/gtest"
+)
+
+var errSameName = errors.New("same name")
+
+type userCreated struct {
+	Name string
+}
+
+type userRenamed struct {
+	Old string
+	New string
+}
+
+type user struct {
+	*aggregate.Base
+	*handler.BaseHandler
+
+	Name string
+}
+
+func newUser(id uuid.UUID) *user {
+	u := &user{
+		Base:        aggregate.New("user", id),
+		BaseHandler: handler.NewBase(),
+	}
+
+	event.ApplyWith(u, func(evt event.Of[userCreated]) { u.Name = evt.Data().Name }, "user.created")
+	event.ApplyWith(u, func(evt event.Of[userRenamed]) { u.Name = evt.Data().New }, "user.renamed")
+
+	command.HandleWith(u, func(ctx command.Ctx[string]) error {
+		if ctx.Payload() == u.Name {
+			return errSameName
+		}
+		aggregate.Next(u, "user.renamed", userRenamed{Old: u.Name, New: ctx.Payload()})
+		aggregate.Next(u, "user.name_changed", struct{}{})
+		return nil
+	}, "user.rename")
+
+	return u
+}
+
+func TestGiven(t *testing.T) {
+	gtest.Given(newUser,
+		gtest.Past("user.created", userCreated{Name: "Alice"}),
+	).When(
+		command.New("user.rename", "Bob").Any(),
+	).Then(
+		gtest.Emits("user.renamed", userRenamed{Old: "Alice", New: "Bob"}),
+		gtest.EmitsSignal("user.name_changed"),
+	).Run(t)
+}
+
+func TestCommandTest_ThenUnordered(t *testing.T) {
+	gtest.Given(newUser,
+		gtest.Past("user.created", userCreated{Name: "Alice"}),
+	).When(
+		command.New("user.rename", "Bob").Any(),
+	).ThenUnordered(
+		gtest.EmitsSignal("user.name_changed"),
+		gtest.EmitsMatching("user.renamed", func(data userRenamed) bool {
+			return data.New == "Bob"
+		}),
+	).Run(t)
+}
+
+func TestCommandTest_ThenFails(t *testing.T) {
+	gtest.Given(newUser,
+		gtest.Past("user.created", userCreated{Name: "Alice"}),
+	).When(
+		command.New("user.rename", "Alice").Any(),
+	).ThenFails(func(err error) bool {
+		return errors.Is(err, errSameName)
+	}).Run(t)
+}
+
+func TestCommandTest_aggregateID(t *testing.T) {
+	id := uuid.New()
+
+	var got uuid.UUID
+	gtest.Given(func(id uuid.UUID) *user {
+		got = id
+		return newUser(id)
+	}).When(
+		command.New("user.rename", "Bob", command.Aggregate("user", id)).Any(),
+	).ThenUnordered(
+		gtest.EmitsSignal("user.renamed"),
+		gtest.EmitsSignal("user.name_changed"),
+	).Run(t)
+
+	if got != id {
+		t.Fatalf("aggregate should be created with the id of the command (%s); got %s", id, got)
+	}
+}
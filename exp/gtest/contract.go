@@ -0,0 +1,275 @@
+package gtest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/modernice/goes/codec"
+)
+
+// Contract declares the events that a consumer (e.g. a projection or another
+// service) relies on, together with the shape of their payloads. A producer
+// verifies that its codec registry satisfies the contracts of its consumers
+// using [Contract.Run] or [Contract.Verify], so that breaking changes to
+// events are detected in tests instead of in production.
+//
+//	var contract = gtest.NewContract("billing",
+//		gtest.Expect[OrderPlaced]("shop.order.placed"),
+//		gtest.Expect[OrderCanceled]("shop.order.canceled"),
+//	)
+//
+//	func TestBillingContract(t *testing.T) {
+//		reg := codec.New()
+//		shop.RegisterEvents(reg)
+//		contract.Run(t, reg)
+//	}
+//
+// The payload types that are passed to [Expect] only need to declare the
+// fields that the consumer actually reads. Producers may add fields to their
+// payloads without breaking the contract, but must not remove or change the
+// type of the declared fields.
+type Contract struct {
+	Consumer string
+	Events   map[string]reflect.Type
+}
+
+// ContractOption is an option for a [Contract].
+type ContractOption func(*Contract)
+
+// ContractViolation is a single violation of a [Contract].
+type ContractViolation struct {
+	// Event is the name of the event that violates the contract.
+	Event string
+
+	// Path is the path to the violating field within the payload. Path is
+	// empty if the event itself is missing or the payload type is incompatible.
+	Path string
+
+	// Reason describes the violation.
+	Reason string
+}
+
+// ContractError is returned by [Contract.Verify] if the contract is violated.
+type ContractError struct {
+	Consumer   string
+	Violations []ContractViolation
+}
+
+// Expect returns a ContractOption that adds the event with the given name to
+// the contract. The payload of the event must be compatible with the type
+// parameter.
+func Expect[Data any](event string) ContractOption {
+	return func(c *Contract) {
+		c.Events[event] = reflect.TypeOf((*Data)(nil)).Elem()
+	}
+}
+
+// ExpectSignal returns a ContractOption that adds the event with the given name
+// to the contract without making any assumptions about its payload.
+func ExpectSignal(event string) ContractOption {
+	return func(c *Contract) {
+		c.Events[event] = nil
+	}
+}
+
+// NewContract returns the contract of the given consumer.
+func NewContract(consumer string, opts ...ContractOption) *Contract {
+	c := &Contract{
+		Consumer: consumer,
+		Events:   make(map[string]reflect.Type),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run verifies the contract against the provided registries and reports every
+// violation to the given testing.T.
+func (c *Contract) Run(t *testing.T, registries ...*codec.Registry) {
+	t.Helper()
+
+	err := c.Verify(registries...)
+
+	var cerr *ContractError
+	if !errors.As(err, &cerr) {
+		if err != nil {
+			t.Error(err)
+		}
+		return
+	}
+
+	for _, v := range cerr.Violations {
+		t.Errorf("contract of %q violated: %s", c.Consumer, v)
+	}
+}
+
+// Verify verifies that the provided registries satisfy the contract. Every
+// event of the contract must be registered in one of the registries, and the
+// registered payload type must be compatible with the payload type that the
+// consumer expects. If the contract is violated, a *ContractError is returned.
+func (c *Contract) Verify(registries ...*codec.Registry) error {
+	factories := make(map[string]func() any)
+	for _, reg := range registries {
+		for name, factory := range reg.Map() {
+			factories[name] = factory
+		}
+	}
+
+	names := make([]string, 0, len(c.Events))
+	for name := range c.Events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations []ContractViolation
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			violations = append(violations, ContractViolation{
+				Event:  name,
+				Reason: "event is not registered",
+			})
+			continue
+		}
+
+		want := c.Events[name]
+		if want == nil {
+			continue
+		}
+
+		got := reflect.TypeOf(factory())
+		for got != nil && got.Kind() == reflect.Pointer {
+			got = got.Elem()
+		}
+
+		for _, v := range compareShapes("", want, got, make(map[[2]reflect.Type]bool)) {
+			v.Event = name
+			violations = append(violations, v)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ContractError{
+			Consumer:   c.Consumer,
+			Violations: violations,
+		}
+	}
+
+	return nil
+}
+
+// String returns a string representation of the violation.
+func (v ContractViolation) String() string {
+	if v.Path == "" {
+		return fmt.Sprintf("%q event: %s", v.Event, v.Reason)
+	}
+	return fmt.Sprintf("%q event: %s: %s", v.Event, v.Path, v.Reason)
+}
+
+// Error implements error.
+func (err *ContractError) Error() string {
+	lines := make([]string, len(err.Violations))
+	for i, v := range err.Violations {
+		lines[i] = v.String()
+	}
+	return fmt.Sprintf("contract of %q violated:\n\t%s", err.Consumer, strings.Join(lines, "\n\t"))
+}
+
+// compareShapes compares the shape of the got type against the want type. seen
+// contains the pairs of struct types that are being compared further up the
+// path, so that recursive types are compared only once.
+func compareShapes(path string, want, got reflect.Type, seen map[[2]reflect.Type]bool) []ContractViolation {
+	for want.Kind() == reflect.Pointer {
+		want = want.Elem()
+	}
+
+	if got == nil {
+		return []ContractViolation{{Path: path, Reason: fmt.Sprintf("expected %s, got no payload", want)}}
+	}
+
+	for got.Kind() == reflect.Pointer {
+		got = got.Elem()
+	}
+
+	if want.Kind() == reflect.Interface {
+		return nil
+	}
+
+	if want.Kind() != got.Kind() {
+		return []ContractViolation{{Path: path, Reason: fmt.Sprintf("expected %s, got %s", want.Kind(), got.Kind())}}
+	}
+
+	switch want.Kind() {
+	case reflect.Struct:
+		pair := [2]reflect.Type{want, got}
+		if seen[pair] {
+			return nil
+		}
+		seen[pair] = true
+		defer delete(seen, pair)
+		return compareStructs(path, want, got, seen)
+	case reflect.Slice, reflect.Array:
+		return compareShapes(path+"[]", want.Elem(), got.Elem(), seen)
+	case reflect.Map:
+		if out := compareShapes(path+"[key]", want.Key(), got.Key(), seen); len(out) > 0 {
+			return out
+		}
+		return compareShapes(path+"[]", want.Elem(), got.Elem(), seen)
+	}
+
+	return nil
+}
+
+func compareStructs(path string, want, got reflect.Type, seen map[[2]reflect.Type]bool) []ContractViolation {
+	gotFields := structFields(got)
+
+	var out []ContractViolation
+	for name, wantField := range structFields(want) {
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		gotField, ok := gotFields[name]
+		if !ok {
+			out = append(out, ContractViolation{Path: fieldPath, Reason: "field is missing"})
+			continue
+		}
+
+		out = append(out, compareShapes(fieldPath, wantField.Type, gotField.Type, seen)...)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+
+	return out
+}
+
+// structFields returns the exported fields of a struct type, keyed by their
+// encoded name (the name in the json tag, if provided).
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	out := make(map[string]reflect.StructField)
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		out[name] = field
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package gtest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/exp/gtest"
+)
+
+type orderPlaced struct {
+	OrderID string
+	Total   int
+	Items   []orderItem
+	Note    string
+}
+
+type orderItem struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"qty"`
+}
+
+type billingOrderPlaced struct {
+	OrderID string
+	Items   []struct {
+		SKU string `json:"sku"`
+	}
+}
+
+func TestContract_Verify(t *testing.T) {
+	reg := codec.New()
+	codec.Register[orderPlaced](reg, "shop.order.placed")
+	codec.Register[struct{}](reg, "shop.order.canceled")
+
+	contract := gtest.NewContract(
+		"billing",
+		gtest.Expect[billingOrderPlaced]("shop.order.placed"),
+		gtest.ExpectSignal("shop.order.canceled"),
+	)
+
+	if err := contract.Verify(reg); err != nil {
+		t.Fatalf("Verify() failed with %q", err)
+	}
+
+	contract.Run(t, reg)
+}
+
+func TestContract_Verify_missingEvent(t *testing.T) {
+	reg := codec.New()
+
+	contract := gtest.NewContract("billing", gtest.Expect[billingOrderPlaced]("shop.order.placed"))
+
+	violations := contractViolations(t, contract.Verify(reg))
+
+	if len(violations) != 1 || violations[0].Event != "shop.order.placed" {
+		t.Fatalf("expected a single violation for %q event; got %v", "shop.order.placed", violations)
+	}
+}
+
+func TestContract_Verify_incompatibleField(t *testing.T) {
+	type producerItem struct {
+		SKU int `json:"sku"`
+	}
+
+	type producerData struct {
+		Items []producerItem
+	}
+
+	reg := codec.New()
+	codec.Register[producerData](reg, "shop.order.placed")
+
+	contract := gtest.NewContract("billing", gtest.Expect[billingOrderPlaced]("shop.order.placed"))
+
+	violations := contractViolations(t, contract.Verify(reg))
+
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations; got %v", violations)
+	}
+
+	if violations[0].Path != "Items[].sku" {
+		t.Errorf("expected violation of %q; got %q", "Items[].sku", violations[0].Path)
+	}
+
+	if violations[1].Path != "OrderID" {
+		t.Errorf("expected violation of %q; got %q", "OrderID", violations[1].Path)
+	}
+}
+
+func TestContract_Verify_recursiveType(t *testing.T) {
+	type producerNode struct {
+		Name     string
+		Parent   *producerNode
+		Children []producerNode
+	}
+
+	type consumerNode struct {
+		Name     int
+		Parent   *consumerNode
+		Children []consumerNode
+	}
+
+	reg := codec.New()
+	codec.Register[producerNode](reg, "tree.node.added")
+
+	contract := gtest.NewContract("search", gtest.Expect[consumerNode]("tree.node.added"))
+
+	violations := contractViolations(t, contract.Verify(reg))
+
+	if len(violations) != 1 || violations[0].Path != "Name" {
+		t.Fatalf("expected a single violation of %q; got %v", "Name", violations)
+	}
+
+	compatible := gtest.NewContract("search", gtest.Expect[producerNode]("tree.node.added"))
+	if err := compatible.Verify(reg); err != nil {
+		t.Fatalf("Verify() failed with %q", err)
+	}
+}
+
+func contractViolations(t *testing.T, err error) []gtest.ContractViolation {
+	t.Helper()
+
+	var cerr *gtest.ContractError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("Verify() should fail with %T; got %v", cerr, err)
+	}
+
+	return cerr.Violations
+}
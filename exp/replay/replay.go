@@ -0,0 +1,288 @@
+// Package replay provides a debug runner that replays a recorded event sequence
+// through projections and aggregates, one event at a time.
+//
+// This is an experimental package. The API may change at any time.
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+// ErrEnd is returned by a Replayer if there are no more events to replay.
+var ErrEnd = errors.New("end of replay")
+
+// Replayer replays a recorded event sequence through projections and
+// aggregates. Events are replayed in a deterministic order, one at a time and
+// without concurrency, so that a bug that occurred in production can be
+// reproduced and stepped through locally.
+//
+//	r, err := replay.Load(ctx, store, query.New(query.AggregateName("shop.order")),
+//		replay.Projection("summary", func() projection.Target[any] { return NewSummary() }),
+//		replay.Aggregate("order", func() aggregate.Aggregate { return NewOrder(id) }),
+//	)
+//	r.BreakAt(func(evt event.Event) bool { return evt.Name() == "shop.order.canceled" })
+//	evt, err := r.Continue()
+//	summary := r.Inspect("summary").(*Summary)
+type Replayer struct {
+	events      []event.Event
+	targets     []*target
+	breakpoints []func(event.Event) bool
+	pos         int
+}
+
+// Option is an option for a Replayer.
+type Option func(*Replayer)
+
+// StepError is returned by a Replayer if a target fails to apply an event.
+type StepError struct {
+	// Position is the position of the event within the replayed sequence.
+	Position int
+
+	// Target is the name of the target that failed.
+	Target string
+
+	// Event is the event that failed to be applied.
+	Event event.Event
+
+	// Err is the underlying error. If the target panicked, Err wraps
+	// projection.ErrPanic.
+	Err error
+}
+
+type target struct {
+	name    string
+	newFunc func() any
+	apply   func(any, event.Event) error
+	state   any
+}
+
+// Projection returns an Option that adds a projection with the given name to
+// the replay. newFunc must return a new, empty instance of the projection.
+// Guards and progress of the projection are respected.
+func Projection(name string, newFunc func() projection.Target[any]) Option {
+	return func(r *Replayer) {
+		r.targets = append(r.targets, &target{
+			name:    name,
+			newFunc: func() any { return newFunc() },
+			apply: func(state any, evt event.Event) error {
+				return projection.TryApply(state.(projection.Target[any]), []event.Event{evt}, projection.OnError(projection.Fail, nil))
+			},
+		})
+	}
+}
+
+// Aggregate returns an Option that adds an aggregate with the given name to the
+// replay. newFunc must return a new, empty instance of the aggregate. Only the
+// events of the aggregate are applied to it.
+func Aggregate(name string, newFunc func() aggregate.Aggregate) Option {
+	return func(r *Replayer) {
+		r.targets = append(r.targets, &target{
+			name:    name,
+			newFunc: func() any { return newFunc() },
+			apply: func(state any, evt event.Event) (err error) {
+				a := state.(aggregate.Aggregate)
+
+				id, name, _ := a.Aggregate()
+				if evtID, evtName, _ := evt.Aggregate(); evtID != id || evtName != name {
+					return nil
+				}
+
+				defer func() {
+					if r := recover(); r != nil {
+						err = fmt.Errorf("%w: %v", projection.ErrPanic, r)
+					}
+				}()
+
+				return aggregate.ApplyHistory(a, []event.Event{evt})
+			},
+		})
+	}
+}
+
+// New returns a Replayer that replays the given events. The events are sorted
+// by time; events with the same time are sorted by aggregate name, aggregate id
+// and aggregate version.
+func New(events []event.Event, opts ...Option) *Replayer {
+	r := &Replayer{
+		events: event.SortMulti(
+			append([]event.Event(nil), events...),
+			event.SortOptions{Sort: event.SortTime, Dir: event.SortAsc},
+			event.SortOptions{Sort: event.SortAggregateName, Dir: event.SortAsc},
+			event.SortOptions{Sort: event.SortAggregateID, Dir: event.SortAsc},
+			event.SortOptions{Sort: event.SortAggregateVersion, Dir: event.SortAsc},
+		),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.Reset()
+	return r
+}
+
+// Load returns a Replayer that replays the events that are returned by the
+// given query.
+func Load(ctx context.Context, store event.Store, q event.Query, opts ...Option) (*Replayer, error) {
+	str, errs, err := store.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return nil, fmt.Errorf("query events: %w", err)
+	}
+
+	return New(events, opts...), nil
+}
+
+// BreakAt adds a breakpoint to the Replayer. Continue stops before an event
+// for which the breakpoint returns true.
+func (r *Replayer) BreakAt(breakpoint func(event.Event) bool) {
+	r.breakpoints = append(r.breakpoints, breakpoint)
+}
+
+// BreakAtEvent adds a breakpoint that stops before the event with the given id.
+func (r *Replayer) BreakAtEvent(id uuid.UUID) {
+	r.BreakAt(func(evt event.Event) bool { return evt.ID() == id })
+}
+
+// ClearBreakpoints removes all breakpoints.
+func (r *Replayer) ClearBreakpoints() {
+	r.breakpoints = nil
+}
+
+// Events returns the replayed event sequence.
+func (r *Replayer) Events() []event.Event {
+	return r.events
+}
+
+// Position returns the number of events that have been replayed.
+func (r *Replayer) Position() int {
+	return r.pos
+}
+
+// Done reports whether all events have been replayed.
+func (r *Replayer) Done() bool {
+	return r.pos >= len(r.events)
+}
+
+// Peek returns the next event without replaying it. Peek returns nil if all
+// events have been replayed.
+func (r *Replayer) Peek() event.Event {
+	if r.Done() {
+		return nil
+	}
+	return r.events[r.pos]
+}
+
+// Now returns the time of the last replayed event. Now returns the zero time if
+// no event has been replayed yet. Use Now instead of time.Now to inspect
+// time-dependent state deterministically.
+func (r *Replayer) Now() time.Time {
+	if r.pos == 0 {
+		return time.Time{}
+	}
+	return r.events[r.pos-1].Time()
+}
+
+// Next replays the next event and returns it. Next returns ErrEnd if all
+// events have been replayed, or a *StepError if a target fails to apply the
+// event. A failed event is still counted as replayed.
+func (r *Replayer) Next() (event.Event, error) {
+	if r.Done() {
+		return nil, ErrEnd
+	}
+
+	evt := r.events[r.pos]
+	r.pos++
+
+	for _, t := range r.targets {
+		if err := t.apply(t.state, evt); err != nil {
+			return evt, &StepError{
+				Position: r.pos - 1,
+				Target:   t.name,
+				Event:    evt,
+				Err:      err,
+			}
+		}
+	}
+
+	return evt, nil
+}
+
+// Continue replays events until a breakpoint is hit, a target fails or all
+// events have been replayed. If a breakpoint is hit, the event that hit the
+// breakpoint is returned without replaying it. Otherwise, the last replayed
+// event is returned. Continue always replays at least one event, so that
+// calling Continue repeatedly advances past a breakpoint.
+func (r *Replayer) Continue() (event.Event, error) {
+	evt, err := r.Next()
+	if err != nil {
+		return evt, err
+	}
+
+	for !r.Done() {
+		next := r.Peek()
+		for _, bp := range r.breakpoints {
+			if bp(next) {
+				return next, nil
+			}
+		}
+
+		if evt, err = r.Next(); err != nil {
+			return evt, err
+		}
+	}
+
+	return evt, nil
+}
+
+// Seek resets the Replayer and replays the events up to the given position.
+func (r *Replayer) Seek(pos int) error {
+	r.Reset()
+	for r.pos < pos {
+		if _, err := r.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset resets the Replayer to the beginning of the event sequence and
+// recreates all targets.
+func (r *Replayer) Reset() {
+	r.pos = 0
+	for _, t := range r.targets {
+		t.state = t.newFunc()
+	}
+}
+
+// Inspect returns the current state of the target with the given name, or nil
+// if there is no such target.
+func (r *Replayer) Inspect(name string) any {
+	for _, t := range r.targets {
+		if t.name == name {
+			return t.state
+		}
+	}
+	return nil
+}
+
+// Error implements error.
+func (err *StepError) Error() string {
+	return fmt.Sprintf("replay #%d %q event (%s) in %q: %v", err.Position, err.Event.Name(), err.Event.ID(), err.Target, err.Err)
+}
+
+// Unwrap returns the underlying error.
+func (err *StepError) Unwrap() error {
+	return err.Err
+}
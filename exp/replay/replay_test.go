@@ -0,0 +1,125 @@
+package replay_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/exp/replay"
+	"github.com/modernice/goes/internal/projectiontest"
+	"github.com/modernice/goes/projection"
+)
+
+type counter struct {
+	*aggregate.Base
+
+	Value int
+}
+
+func newCounter(id uuid.UUID) *counter {
+	c := &counter{Base: aggregate.New("counter", id)}
+	event.ApplyWith(c, func(event.Of[int]) { c.Value++ }, "counter.incremented")
+	event.ApplyWith(c, func(event.Of[int]) { panic("boom") }, "counter.broken")
+	return c
+}
+
+func TestReplayer(t *testing.T) {
+	id := uuid.New()
+	events := newEvents(id, "counter.incremented", "counter.incremented", "counter.incremented")
+
+	r := replay.New(reversed(events),
+		replay.Aggregate("counter", func() aggregate.Aggregate { return newCounter(id) }),
+		replay.Projection("proj", func() projection.Target[any] { return projectiontest.NewMockProjection() }),
+	)
+
+	test.AssertEqualEvents(t, events, r.Events())
+
+	evt, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next() failed with %q", err)
+	}
+
+	if evt.ID() != events[0].ID() {
+		t.Fatalf("Next() should return the first event")
+	}
+
+	if !r.Now().Equal(events[0].Time()) {
+		t.Fatalf("Now() should return %v; got %v", events[0].Time(), r.Now())
+	}
+
+	if c := r.Inspect("counter").(*counter); c.Value != 1 {
+		t.Fatalf("Value should be %d; is %d", 1, c.Value)
+	}
+
+	r.BreakAtEvent(events[2].ID())
+
+	evt, err = r.Continue()
+	if err != nil {
+		t.Fatalf("Continue() failed with %q", err)
+	}
+
+	if evt.ID() != events[2].ID() || r.Position() != 2 {
+		t.Fatalf("Continue() should stop before the 3rd event; stopped at position %d", r.Position())
+	}
+
+	r.Inspect("proj").(*projectiontest.MockProjection).ExpectApplied(t, events[:2]...)
+
+	if _, err := r.Continue(); err != nil {
+		t.Fatalf("Continue() failed with %q", err)
+	}
+
+	if _, err := r.Next(); !errors.Is(err, replay.ErrEnd) {
+		t.Fatalf("Next() should fail with %q; got %q", replay.ErrEnd, err)
+	}
+
+	if err := r.Seek(1); err != nil {
+		t.Fatalf("Seek() failed with %q", err)
+	}
+
+	if c := r.Inspect("counter").(*counter); c.Value != 1 {
+		t.Fatalf("Value should be %d after Seek(1); is %d", 1, c.Value)
+	}
+}
+
+func TestReplayer_Next_panic(t *testing.T) {
+	id := uuid.New()
+	events := newEvents(id, "counter.incremented", "counter.broken")
+
+	r := replay.New(events, replay.Aggregate("counter", func() aggregate.Aggregate { return newCounter(id) }))
+
+	_, err := r.Continue()
+
+	var stepErr *replay.StepError
+	if !errors.As(err, &stepErr) {
+		t.Fatalf("Continue() should fail with %T; got %v", stepErr, err)
+	}
+
+	if stepErr.Position != 1 || stepErr.Target != "counter" {
+		t.Fatalf("StepError should report position %d of %q; reports position %d of %q", 1, "counter", stepErr.Position, stepErr.Target)
+	}
+
+	if !errors.Is(err, projection.ErrPanic) {
+		t.Fatalf("Continue() should fail with %q; got %q", projection.ErrPanic, err)
+	}
+}
+
+func newEvents(id uuid.UUID, names ...string) []event.Event {
+	now := time.Now()
+	events := make([]event.Event, len(names))
+	for i, name := range names {
+		events[i] = event.New(name, 0, event.Aggregate(id, "counter", i+1), event.Time(now.Add(time.Duration(i)*time.Millisecond))).Any()
+	}
+	return events
+}
+
+func reversed(events []event.Event) []event.Event {
+	out := make([]event.Event, len(events))
+	for i, evt := range events {
+		out[len(events)-1-i] = evt
+	}
+	return out
+}
@@ -0,0 +1,270 @@
+// Package goestest provides a harness for integration tests of event-sourced
+// applications.
+//
+// Setting up an event-sourced environment for an integration test requires
+// wiring an event bus, an event store, registries for events and commands, a
+// command bus, and an aggregate repository. A Harness does this with a single
+// call, using in-memory components by default:
+//
+//	func TestPlaceOrder(t *testing.T) {
+//		h := goestest.New(t,
+//			goestest.RegisterEvents(order.RegisterEvents),
+//			goestest.RegisterCommands(order.RegisterCommands),
+//		)
+//
+//		orders := handler.New(order.New, h.Repository, h.CommandBus)
+//		errs, err := orders.Handle(h.Context())
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		h.Watch(errs)
+//
+//		cmd := order.Place(uuid.New(), ...)
+//		if err := h.CommandBus.Dispatch(h.Context(), cmd.Any(), dispatch.Sync()); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+//
+// To run tests against real backends, e.g. MongoDB and NATS in docker
+// containers, pass them using the EventStore and EventBus options. The
+// components of a Harness are stopped when the test finishes.
+package goestest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/aggregate/repository"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/cmdbus"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+// DefaultTimeout is the default timeout of a Harness.
+const DefaultTimeout = 30 * time.Second
+
+// Runner is a component that runs until its context is canceled, like event
+// handlers, command handlers, and the command bus.
+type Runner interface {
+	Run(context.Context) (<-chan error, error)
+}
+
+// Harness is an event-sourcing environment for integration tests. The fields
+// of a Harness are ready to use after New returns.
+type Harness struct {
+	// Events is the registry of event data.
+	Events *codec.Registry
+
+	// Commands is the registry of command payloads.
+	Commands *codec.Registry
+
+	// EventBus is the event bus.
+	EventBus event.Bus
+
+	// EventStore is the event store. Events that are inserted into the store
+	// are published over the EventBus.
+	EventStore event.Store
+
+	// CommandBus is the command bus.
+	CommandBus *cmdbus.Bus[int]
+
+	// Repository is the aggregate repository that uses the EventStore.
+	Repository *repository.Repository
+
+	t      testing.TB
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	registerEvents   []func(codec.Registerer)
+	registerCommands []func(codec.Registerer)
+	cmdbusOpts       []cmdbus.Option
+	repoOpts         []repository.Option
+	timeout          time.Duration
+}
+
+// Option is an option for a Harness.
+type Option func(*Harness)
+
+// EventBus returns an Option that replaces the in-memory event bus of a
+// Harness.
+func EventBus(bus event.Bus) Option {
+	return func(h *Harness) {
+		h.EventBus = bus
+	}
+}
+
+// EventStore returns an Option that replaces the in-memory event store of a
+// Harness. The Harness publishes the events that are inserted into the store
+// over its event bus.
+func EventStore(store event.Store) Option {
+	return func(h *Harness) {
+		h.EventStore = store
+	}
+}
+
+// RegisterEvents returns an Option that registers event data using the given
+// functions, like the RegisterEvents functions of aggregate packages.
+func RegisterEvents(fns ...func(codec.Registerer)) Option {
+	return func(h *Harness) {
+		h.registerEvents = append(h.registerEvents, fns...)
+	}
+}
+
+// RegisterCommands returns an Option that registers command payloads using the
+// given functions, like the RegisterCommands functions of aggregate packages.
+func RegisterCommands(fns ...func(codec.Registerer)) Option {
+	return func(h *Harness) {
+		h.registerCommands = append(h.registerCommands, fns...)
+	}
+}
+
+// CommandBusOptions returns an Option that passes the given options to the
+// command bus of a Harness.
+func CommandBusOptions(opts ...cmdbus.Option) Option {
+	return func(h *Harness) {
+		h.cmdbusOpts = append(h.cmdbusOpts, opts...)
+	}
+}
+
+// RepositoryOptions returns an Option that passes the given options to the
+// aggregate repository of a Harness.
+func RepositoryOptions(opts ...repository.Option) Option {
+	return func(h *Harness) {
+		h.repoOpts = append(h.repoOpts, opts...)
+	}
+}
+
+// Timeout returns an Option that cancels the context of a Harness after the
+// given duration, so that a hanging test fails instead of blocking until the
+// test binary times out. A zero Duration disables the timeout. Default is
+// DefaultTimeout.
+func Timeout(d time.Duration) Option {
+	return func(h *Harness) {
+		h.timeout = d
+	}
+}
+
+// New returns a Harness for the given test. The context of the Harness is
+// canceled and its components are stopped when the test finishes.
+func New(t testing.TB, opts ...Option) *Harness {
+	t.Helper()
+
+	h := &Harness{t: t, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.timeout > 0 {
+		h.ctx, h.cancel = context.WithTimeout(context.Background(), h.timeout)
+	} else {
+		h.ctx, h.cancel = context.WithCancel(context.Background())
+	}
+
+	t.Cleanup(func() {
+		h.cancel()
+		h.wg.Wait()
+	})
+
+	h.Events = codec.New()
+	cmdbus.RegisterEvents(h.Events)
+	for _, fn := range h.registerEvents {
+		fn(h.Events)
+	}
+
+	h.Commands = command.NewRegistry()
+	for _, fn := range h.registerCommands {
+		fn(h.Commands)
+	}
+
+	if h.EventBus == nil {
+		h.EventBus = eventbus.New()
+	}
+
+	if h.EventStore == nil {
+		h.EventStore = eventstore.New()
+	}
+	h.EventStore = eventstore.WithBus(h.EventStore, h.EventBus)
+
+	h.CommandBus = cmdbus.New[int](h.Commands, h.EventBus, h.cmdbusOpts...)
+	h.Run(h.CommandBus)
+
+	h.Repository = repository.New(h.EventStore, h.repoOpts...)
+
+	return h
+}
+
+// Context returns the context of the Harness, which is canceled when the test
+// finishes.
+func (h *Harness) Context() context.Context {
+	return h.ctx
+}
+
+// Run runs the given component until the test finishes. If the component
+// fails to start, the test fails immediately. Asynchronous errors of the
+// component fail the test.
+func (h *Harness) Run(r Runner) {
+	h.t.Helper()
+
+	errs, err := r.Run(h.ctx)
+	if err != nil {
+		h.t.Fatalf("run %T: %v", r, err)
+	}
+
+	h.Watch(errs)
+}
+
+// Watch fails the test for every error that is received from the given
+// channel until the test finishes.
+func (h *Harness) Watch(errs <-chan error) {
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		for {
+			select {
+			case <-h.ctx.Done():
+				return
+			case err, ok := <-errs:
+				if !ok {
+					return
+				}
+				if h.ctx.Err() == nil {
+					h.t.Errorf("async error: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Continuously returns a Continuous schedule for the given events that uses
+// the event bus and event store of the Harness.
+func (h *Harness) Continuously(eventNames []string, opts ...schedule.ContinuousOption) *schedule.Continuous {
+	return schedule.Continuously(h.EventBus, h.EventStore, eventNames, opts...)
+}
+
+// Periodically returns a Periodic schedule for the given events that uses the
+// event store of the Harness.
+func (h *Harness) Periodically(interval time.Duration, eventNames []string) *schedule.Periodic {
+	return schedule.Periodically(h.EventStore, interval, eventNames)
+}
+
+// Project subscribes to the given schedule until the test finishes. If the
+// subscription fails, the test fails immediately. Errors of projection jobs
+// fail the test.
+func (h *Harness) Project(s projection.Schedule, apply func(projection.Job) error, opts ...projection.SubscribeOption) {
+	h.t.Helper()
+
+	errs, err := s.Subscribe(h.ctx, apply, opts...)
+	if err != nil {
+		h.t.Fatalf("subscribe to %T: %v", s, err)
+	}
+
+	h.Watch(errs)
+}
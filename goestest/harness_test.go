@@ -0,0 +1,100 @@
+package goestest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/command"
+	"github.com/modernice/goes/command/cmdbus/dispatch"
+	"github.com/modernice/goes/command/handler"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/goestest"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+type foo struct {
+	*aggregate.Base
+	*handler.BaseHandler
+
+	Val string
+}
+
+func newFoo(id uuid.UUID) *foo {
+	f := &foo{
+		Base:        aggregate.New("foo", id),
+		BaseHandler: handler.NewBase(),
+	}
+
+	event.ApplyWith(f, func(evt event.Of[test.FooEventData]) {
+		f.Val = evt.Data().A
+	}, "foo")
+
+	command.HandleWith(f, func(ctx command.Ctx[string]) error {
+		aggregate.Next(f, "foo", test.FooEventData{A: ctx.Payload()})
+		return nil
+	}, "set-foo")
+
+	return f
+}
+
+func TestHarness(t *testing.T) {
+	h := goestest.New(t,
+		goestest.RegisterEvents(func(r codec.Registerer) {
+			codec.Register[test.FooEventData](r, "foo")
+		}),
+		goestest.RegisterCommands(func(r codec.Registerer) {
+			codec.Register[string](r, "set-foo")
+		}),
+	)
+
+	errs, err := handler.New(newFoo, h.Repository, h.CommandBus).Handle(h.Context())
+	if err != nil {
+		t.Fatalf("Handle() failed with %q", err)
+	}
+	h.Watch(errs)
+
+	projected := make(chan string, 1)
+	h.Project(h.Continuously([]string{"foo"}), func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+		for _, evt := range events {
+			projected <- event.Cast[test.FooEventData](evt).Data().A
+		}
+		return nil
+	})
+
+	id := uuid.New()
+	cmd := command.New("set-foo", "bar", command.Aggregate("foo", id))
+	if err := h.CommandBus.Dispatch(h.Context(), cmd.Any(), dispatch.Sync()); err != nil {
+		t.Fatalf("Dispatch() failed with %q", err)
+	}
+
+	f := newFoo(id)
+	if err := h.Repository.Fetch(h.Context(), f); err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if f.Val != "bar" {
+		t.Fatalf("Val should be %q; is %q", "bar", f.Val)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("event should have been projected")
+	case val := <-projected:
+		if val != "bar" {
+			t.Fatalf("projected value should be %q; is %q", "bar", val)
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package grpcstatus
 
 import (
+	"errors"
+
+	commandpb "github.com/modernice/goes/api/proto/gen/command"
+	"github.com/modernice/goes/command"
 	"github.com/modernice/goes/internal/slice"
+	"golang.org/x/exp/constraints"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -22,3 +27,67 @@ func New(code codes.Code, message string, details ...proto.Message) *status.Stat
 	}
 	return st
 }
+
+// FromError returns a grpc *Status for the provided error. The status code is
+// derived from the command.ErrorKind of the error, and the error itself is
+// attached as a *commandpb.Error detail, so that clients can restore the error
+// code and details using AsError.
+func FromError(err error) *status.Status {
+	cerr := command.Error[int64](err)
+	return New(Code(command.ErrorKindOf(err)), err.Error(), commandpb.NewError(cerr))
+}
+
+// AsError restores the command error from a grpc status error that was
+// created by FromError. If the status has no *commandpb.Error detail, the
+// returned error has the message of the status and is classified using the
+// status code.
+func AsError[Code constraints.Integer](err error) *command.Err[Code] {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return command.Error[Code](err)
+	}
+
+	for _, detail := range st.Details() {
+		if pb, ok := detail.(*commandpb.Error); ok {
+			return commandpb.AsError[Code](pb)
+		}
+	}
+
+	return command.NewError[Code](0, errors.New(st.Message()), command.WithErrorKind(Kind(st.Code())))
+}
+
+// Code returns the grpc status code for the given error kind.
+func Code(kind command.ErrorKind) codes.Code {
+	switch kind {
+	case command.KindValidation:
+		return codes.InvalidArgument
+	case command.KindNotFound:
+		return codes.NotFound
+	case command.KindConflict:
+		return codes.Aborted
+	case command.KindInfrastructure:
+		return codes.Unavailable
+	default:
+		return codes.Unknown
+	}
+}
+
+// Kind returns the error kind for the given grpc status code.
+func Kind(code codes.Code) command.ErrorKind {
+	switch code {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return command.KindValidation
+	case codes.NotFound:
+		return command.KindNotFound
+	case codes.Aborted, codes.AlreadyExists:
+		return command.KindConflict
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal:
+		return command.KindInfrastructure
+	default:
+		return command.KindUnknown
+	}
+}
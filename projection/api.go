@@ -1,6 +1,8 @@
 package projection
 
 import (
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,12 +39,16 @@ type ProgressAware interface {
 }
 
 // Progressor can be embedded into a projection to implement ProgressAware.
+// Progress and SetProgress are safe for concurrent use, so that the progress
+// can be polled (e.g. by WaitFor) while the projection is applied.
 type Progressor struct {
 	// Time of the last applied events as elapsed nanoseconds since January 1, 1970 UTC.
 	LastEventTime int64
 
 	// LastEvents are the ids of last applied events that have LastEventTime as their time.
 	LastEvents []uuid.UUID
+
+	mux sync.RWMutex
 }
 
 // NewProgressor returns a new *Progressor that can be embeded into a projection
@@ -54,17 +60,21 @@ func NewProgressor() *Progressor {
 // Progress returns the projection progress in terms of the time and ids of the
 // last applied events. If p.LastEventTime is 0, the zero Time is returned.
 func (p *Progressor) Progress() (time.Time, []uuid.UUID) {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
 	var t time.Time
 	if p.LastEventTime > 0 {
 		t = time.Unix(0, p.LastEventTime)
 	}
-	return t, p.LastEvents
+	return t, slices.Clone(p.LastEvents)
 }
 
 // SetProgress sets the projection progress as the time of the latest applied
 // event. The ids of the applied events that have the given time should be
 // provided.
 func (p *Progressor) SetProgress(t time.Time, ids ...uuid.UUID) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
 	p.LastEvents = ids
 	if t.IsZero() {
 		p.LastEventTime = 0
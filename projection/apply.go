@@ -13,6 +13,13 @@ type ApplyOption func(*applyConfig)
 
 type applyConfig struct {
 	ignoreProgress bool
+	annul          bool
+	errorPolicies  []errorPolicy
+	deadLetter     func(event.Event, error)
+	maxRetries     int
+	before         []func(Step)
+	after          []func(Step)
+	snapshot       func(Target[any]) any
 }
 
 // IgnoreProgress returns an ApplyOption that makes Apply ignore the current
@@ -24,6 +31,16 @@ func IgnoreProgress() ApplyOption {
 	}
 }
 
+// AnnulCompensated returns an ApplyOption that removes compensated events
+// together with their compensating events before applying them to a
+// projection. See Annul for details. When used with ApplyStream, the events are
+// buffered until the stream is closed.
+func AnnulCompensated() ApplyOption {
+	return func(cfg *applyConfig) {
+		cfg.annul = true
+	}
+}
+
 // Apply applies events to the given projection.
 //
 // If the projection implements Guard, proj.GuardProjection(evt) is called for
@@ -31,8 +48,13 @@ func IgnoreProgress() ApplyOption {
 //
 // If the projection implements ProgressAware, the time of the last applied
 // event is applied to the projection by calling proj.SetProgress(evt).
+//
+// Apply panics with an *ApplyError if applying an event fails with the Fail
+// policy. Use TryApply to handle the error instead.
 func Apply(proj Target[any], events []event.Event, opts ...ApplyOption) {
-	ApplyStream(proj, streams.New(events), opts...)
+	if err := TryApply(proj, events, opts...); err != nil {
+		panic(err)
+	}
 }
 
 // ApplyStream applies events to the given projection.
@@ -42,15 +64,73 @@ func Apply(proj Target[any], events []event.Event, opts ...ApplyOption) {
 //
 // If the projection implements ProgressAware, the time of the last applied
 // event is applied to the projection by calling proj.SetProgress(evt).
+//
+// ApplyStream panics with an *ApplyError if applying an event fails with the
+// Fail policy. Use TryApplyStream to handle the error instead.
 func ApplyStream(target Target[any], events <-chan event.Event, opts ...ApplyOption) {
+	if err := TryApplyStream(target, events, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// TryApply applies events to the given projection, like Apply does. If applying
+// an event fails with the Fail policy (see OnError), TryApply stops and returns
+// an *ApplyError. The progress of the projection is updated to the last
+// successfully applied (or skipped) event.
+func TryApply(proj Target[any], events []event.Event, opts ...ApplyOption) error {
+	cfg := newApplyConfig(opts...)
+
+	if cfg.annul {
+		events = Annul(events)
+	}
+
+	var i int
+	return cfg.apply(proj, func() (event.Event, bool) {
+		if i >= len(events) {
+			return nil, false
+		}
+		i++
+		return events[i-1], true
+	})
+}
+
+// TryApplyStream applies events to the given projection, like ApplyStream does.
+// If applying an event fails with the Fail policy (see OnError),
+// TryApplyStream stops receiving from the stream and returns an *ApplyError.
+// The progress of the projection is updated to the last successfully applied
+// (or skipped) event.
+func TryApplyStream(target Target[any], events <-chan event.Event, opts ...ApplyOption) error {
 	cfg := newApplyConfig(opts...)
 
+	if cfg.annul {
+		var buf []event.Event
+		for evt := range events {
+			buf = append(buf, evt)
+		}
+		events = streams.New(Annul(buf))
+	}
+
+	return cfg.apply(target, func() (event.Event, bool) {
+		evt, ok := <-events
+		return evt, ok
+	})
+}
+
+func (cfg applyConfig) apply(target Target[any], next func() (event.Event, bool)) error {
 	progressor, isProgressor := target.(ProgressAware)
 	guard, hasGuard := target.(Guard)
 
 	var lastEventTime time.Time
 	var lastEvents []uuid.UUID
-	for evt := range events {
+	var applied int
+
+	defer func() {
+		if isProgressor && !lastEventTime.IsZero() {
+			progressor.SetProgress(lastEventTime, lastEvents...)
+		}
+	}()
+
+	for evt, ok := next(); ok; evt, ok = next() {
 		if hasGuard && !guard.GuardProjection(evt) {
 			continue
 		}
@@ -59,7 +139,14 @@ func ApplyStream(target Target[any], events <-chan event.Event, opts ...ApplyOpt
 			continue
 		}
 
-		target.ApplyEvent(evt)
+		cfg.debugBefore(target, evt, applied)
+		err := cfg.applyEvent(target, evt)
+		cfg.debugAfter(target, evt, applied, err)
+		applied++
+
+		if err != nil && !cfg.skip(evt, err) {
+			return &ApplyError{Event: evt, Err: err}
+		}
 
 		// Avoid unnecessary computations.
 		if !isProgressor {
@@ -76,13 +163,11 @@ func ApplyStream(target Target[any], events <-chan event.Event, opts ...ApplyOpt
 		lastEvents = append(lastEvents, evt.ID())
 	}
 
-	if isProgressor && !lastEventTime.IsZero() {
-		progressor.SetProgress(lastEventTime, lastEvents...)
-	}
+	return nil
 }
 
 func newApplyConfig(opts ...ApplyOption) applyConfig {
-	var cfg applyConfig
+	cfg := applyConfig{maxRetries: DefaultMaxRetries}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
@@ -0,0 +1,91 @@
+package projection
+
+import (
+	"context"
+	"fmt"
+	stdtime "time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/time"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// AsOfOption is an option for AsOf.
+type AsOfOption func(*asOfConfig)
+
+type asOfConfig struct {
+	events     []string
+	aggregates []string
+	applyOpts  []ApplyOption
+}
+
+// AsOfEvents returns an AsOfOption that only applies events with one of the
+// given names. By default, all events up to the given time are applied.
+func AsOfEvents(names ...string) AsOfOption {
+	return func(cfg *asOfConfig) {
+		cfg.events = append(cfg.events, names...)
+	}
+}
+
+// AsOfAggregates returns an AsOfOption that only applies events of aggregates
+// with one of the given names.
+func AsOfAggregates(names ...string) AsOfOption {
+	return func(cfg *asOfConfig) {
+		cfg.aggregates = append(cfg.aggregates, names...)
+	}
+}
+
+// AsOfApply returns an AsOfOption that passes the given ApplyOptions to
+// TryApply when applying the events.
+func AsOfApply(opts ...ApplyOption) AsOfOption {
+	return func(cfg *asOfConfig) {
+		cfg.applyOpts = append(cfg.applyOpts, opts...)
+	}
+}
+
+// AsOf rebuilds the given projection as it was at the given time, by applying
+// the events from the store that occurred at or before t, sorted by time. This
+// allows for historical reports without maintaining bitemporal read models:
+//
+//	report := NewRevenueReport() // a new, empty instance
+//	err := projection.AsOf(ctx, store, report, lastYear,
+//		projection.AsOfEvents("shop.order.placed", "shop.order.canceled"),
+//	)
+//
+// The projection should be a new, empty instance (a scratch copy) and not the
+// live read model, because the events are applied on top of its current
+// state. Guards and progress of the projection are respected, like in Apply.
+func AsOf(ctx context.Context, store event.Store, proj Target[any], t stdtime.Time, opts ...AsOfOption) error {
+	var cfg asOfConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	queryOpts := []query.Option{
+		query.Time(time.Max(t)),
+		query.SortByTime(),
+	}
+	if len(cfg.events) > 0 {
+		queryOpts = append(queryOpts, query.Name(cfg.events...))
+	}
+	if len(cfg.aggregates) > 0 {
+		queryOpts = append(queryOpts, query.AggregateName(cfg.aggregates...))
+	}
+
+	str, errs, err := store.Query(ctx, query.New(queryOpts...))
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	if err := TryApply(proj, events, cfg.applyOpts...); err != nil {
+		return fmt.Errorf("apply events: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package projection
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+)
+
+// TriggerRejected is the event name for rejecting an unauthorized trigger.
+const TriggerRejected = "goes.projection.schedule.trigger_rejected"
+
+// DefaultMaxTriggerAge is the default maximum age of signed triggers that are
+// accepted by a Service that authenticates triggers.
+const DefaultMaxTriggerAge = time.Minute
+
+var (
+	// ErrUnauthorizedTrigger is returned when a trigger is rejected by the
+	// Service that runs the triggered Schedule.
+	ErrUnauthorizedTrigger = errors.New("unauthorized trigger")
+
+	// ErrInvalidSignature is returned by an Authenticator if the signature of a
+	// trigger is invalid.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrUnknownPrincipal is returned by an Authenticator if it does not know
+	// the principal of a trigger.
+	ErrUnknownPrincipal = errors.New("unknown principal")
+)
+
+// TriggerRejectedData is the event data for rejecting a trigger.
+type TriggerRejectedData struct {
+	TriggerID uuid.UUID
+
+	// Reason is the reason for the rejection.
+	Reason string
+}
+
+// Credentials sign the triggers that are published by a Service.
+type Credentials interface {
+	// Principal returns the name of the principal that triggers schedules.
+	Principal() string
+
+	// Sign returns the signature of the given payload.
+	Sign(payload []byte) ([]byte, error)
+}
+
+// Authenticator verifies the signatures of triggers that are received by a
+// Service.
+type Authenticator interface {
+	// Verify verifies that the signature of the payload was created by the
+	// given principal. Verify returns an error that unwraps to
+	// ErrUnknownPrincipal or ErrInvalidSignature if the verification fails.
+	Verify(principal string, payload, signature []byte) error
+}
+
+// SignTriggers returns a ServiceOption that signs the triggers that are
+// published by the Service using the given Credentials.
+func SignTriggers(c Credentials) ServiceOption {
+	return func(svc *Service) {
+		svc.credentials = c
+	}
+}
+
+// Authenticate returns a ServiceOption that only accepts triggers that are
+// signed by a principal that is known to the given Authenticator. Triggers
+// without a valid signature are rejected: the triggering Service receives an
+// error that unwraps to ErrUnauthorizedTrigger, and the reason for the
+// rejection is reported in the error channel returned by Run.
+//
+// Signed triggers expire after DefaultMaxTriggerAge, and a trigger whose id was
+// already accepted is rejected, to prevent replays of captured triggers. Use
+// MaxTriggerAge to configure the expiry.
+//
+// The signature covers the id of the trigger, the name of the Schedule, the
+// principal, the time of the trigger, and whether projections are reset.
+// Custom queries and filters of a trigger (see Query, AggregateQuery and
+// Filter) cannot be signed, so triggers that provide them are rejected.
+func Authenticate(a Authenticator) ServiceOption {
+	return func(svc *Service) {
+		svc.auth = a
+	}
+}
+
+// AllowPrincipals returns a ServiceOption that only accepts triggers from the
+// given principals. Triggers from other principals are rejected. If
+// AllowPrincipals is used without Authenticate, the principal of a trigger is
+// not verified, so AllowPrincipals should always be combined with
+// Authenticate.
+func AllowPrincipals(principals ...string) ServiceOption {
+	return func(svc *Service) {
+		if svc.principals == nil {
+			svc.principals = make(map[string]bool)
+		}
+		for _, p := range principals {
+			svc.principals[p] = true
+		}
+	}
+}
+
+// MaxTriggerAge returns a ServiceOption that configures the maximum age of
+// signed triggers. Older triggers are rejected. A zero Duration disables the
+// expiry. Default is DefaultMaxTriggerAge.
+//
+// The ids of accepted triggers are remembered until the triggers expire. If the
+// expiry is disabled, they are remembered for the lifetime of the Service.
+func MaxTriggerAge(d time.Duration) ServiceOption {
+	return func(svc *Service) {
+		svc.maxTriggerAge = d
+	}
+}
+
+type hmacCredentials struct {
+	principal string
+	key       []byte
+}
+
+// HMAC returns Credentials that sign triggers as the given principal, using
+// HMAC-SHA256 with the given key.
+func HMAC(principal string, key []byte) Credentials {
+	return hmacCredentials{principal: principal, key: key}
+}
+
+func (c hmacCredentials) Principal() string {
+	return c.principal
+}
+
+func (c hmacCredentials) Sign(payload []byte) ([]byte, error) {
+	return signHMAC(c.key, payload), nil
+}
+
+// HMACAuthenticator is an Authenticator that verifies HMAC-SHA256 signatures
+// that were created using the HMAC Credentials. It maps principals to their
+// keys.
+type HMACAuthenticator map[string][]byte
+
+// Verify implements Authenticator.
+func (keys HMACAuthenticator) Verify(principal string, payload, signature []byte) error {
+	key, ok := keys[principal]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownPrincipal, principal)
+	}
+
+	if !hmac.Equal(signHMAC(key, payload), signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func signHMAC(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (svc *Service) sign(data *TriggeredData) error {
+	if svc.credentials == nil {
+		return nil
+	}
+
+	data.Principal = svc.credentials.Principal()
+	data.IssuedAt = time.Now()
+
+	sig, err := svc.credentials.Sign(signingPayload(*data))
+	if err != nil {
+		return fmt.Errorf("sign trigger: %w", err)
+	}
+	data.Signature = sig
+
+	return nil
+}
+
+// authorize returns an error if the Service does not accept the trigger.
+func (svc *Service) authorize(data TriggeredData) error {
+	if svc.principals != nil && !svc.principals[data.Principal] {
+		return fmt.Errorf("principal %q is not allowed", data.Principal)
+	}
+
+	if svc.auth == nil {
+		return nil
+	}
+
+	if data.Principal == "" || len(data.Signature) == 0 {
+		return errors.New("trigger is not signed")
+	}
+
+	if data.Trigger.Query != nil || data.Trigger.AggregateQuery != nil || len(data.Trigger.Filter) > 0 {
+		return errors.New("custom queries and filters of triggers are not signed")
+	}
+
+	if svc.maxTriggerAge > 0 && time.Since(data.IssuedAt) > svc.maxTriggerAge {
+		return fmt.Errorf("trigger expired at %v", data.IssuedAt.Add(svc.maxTriggerAge))
+	}
+
+	if err := svc.auth.Verify(data.Principal, signingPayload(data), data.Signature); err != nil {
+		return fmt.Errorf("verify signature of principal %q: %w", data.Principal, err)
+	}
+
+	if !svc.remember(data) {
+		return fmt.Errorf("trigger %s was already accepted", data.TriggerID)
+	}
+
+	return nil
+}
+
+// remember remembers the id of the given trigger until it expires, and reports
+// whether the id was not already remembered.
+func (svc *Service) remember(data TriggeredData) bool {
+	svc.seenMux.Lock()
+	defer svc.seenMux.Unlock()
+
+	if svc.seen == nil {
+		svc.seen = make(map[uuid.UUID]time.Time)
+	}
+
+	if svc.maxTriggerAge > 0 {
+		for id, issuedAt := range svc.seen {
+			if time.Since(issuedAt) > svc.maxTriggerAge {
+				delete(svc.seen, id)
+			}
+		}
+	}
+
+	if _, ok := svc.seen[data.TriggerID]; ok {
+		return false
+	}
+	svc.seen[data.TriggerID] = data.IssuedAt
+
+	return true
+}
+
+func (svc *Service) reject(ctx context.Context, data TriggeredData, reason error) error {
+	evt := event.New[any](TriggerRejected, TriggerRejectedData{
+		TriggerID: data.TriggerID,
+		Reason:    reason.Error(),
+	})
+	if err := svc.bus.Publish(ctx, evt); err != nil {
+		return fmt.Errorf("publish %q event: %w", evt.Name(), err)
+	}
+	return nil
+}
+
+// signingPayload returns the bytes of a trigger that are signed by Credentials.
+func signingPayload(data TriggeredData) []byte {
+	buf := make([]byte, 0, 64+len(data.Schedule)+len(data.Principal))
+	buf = append(buf, data.TriggerID[:]...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(data.IssuedAt.UnixNano()))
+	if data.Trigger.Reset {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data.Schedule)))
+	buf = append(buf, data.Schedule...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data.Principal)))
+	buf = append(buf, data.Principal...)
+	return buf
+}
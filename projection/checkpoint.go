@@ -0,0 +1,126 @@
+package projection
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+)
+
+// Checkpoint is the persisted progress of a projection in terms of the time
+// and ids of the last processed events. Unlike the progress of a ProgressAware
+// projection, a Checkpoint is stored independently of the read model, so that
+// a restarted service can resume a projection from its last Checkpoint.
+type Checkpoint struct {
+	// Time is the time of the last processed events.
+	Time time.Time
+
+	// Events are the ids of the last processed events that have Time as their
+	// time.
+	Events []uuid.UUID
+}
+
+// CheckpointStore persists the Checkpoints of projections.
+//
+// *MemoryCheckpointStore implements CheckpointStore. Use the MongoDB
+// implementation in the backend/mongo package to persist Checkpoints across
+// restarts.
+type CheckpointStore interface {
+	// Checkpoint returns the Checkpoint of the given projection. If no
+	// Checkpoint was saved for the projection, the zero Checkpoint is returned.
+	Checkpoint(ctx context.Context, projection string) (Checkpoint, error)
+
+	// SaveCheckpoint saves the Checkpoint of the given projection.
+	SaveCheckpoint(ctx context.Context, projection string, cp Checkpoint) error
+}
+
+// Checkpoints returns a SubscribeOption that persists the progress of the
+// subscription in the given CheckpointStore, using the given projection name.
+// Jobs of the subscription skip the events that were already processed
+// according to the Checkpoint, and the Checkpoint is advanced after each job
+// that was successfully applied.
+//
+// Jobs that are applied concurrently (see schedule.Workers) may finish out of
+// order, so that the Checkpoint could skip the events of a job that was still
+// running when the service was stopped. The schedules of the schedule package
+// therefore reject subscriptions that use Checkpoints with multiple workers.
+func Checkpoints(store CheckpointStore, projection string) SubscribeOption {
+	return func(s *Subscription) {
+		s.Checkpoints = store
+		s.CheckpointName = projection
+	}
+}
+
+// WithCheckpoint returns a JobOption that makes the job skip the events that
+// were already processed according to the Checkpoint of the given projection.
+// The Checkpoint is loaded from the store when the events of the job are
+// queried for the first time. Jobs that were created using WithReset ignore
+// the Checkpoint.
+func WithCheckpoint(store CheckpointStore, projection string) JobOption {
+	return func(j *job) {
+		j.checkpoints = store
+		j.checkpointName = projection
+	}
+}
+
+// Allows reports whether the given event was not yet processed according to
+// the Checkpoint. An event is allowed if its time is after the time of the
+// Checkpoint, or if it has the same time but is not one of the events of the
+// Checkpoint.
+func (cp Checkpoint) Allows(evt event.Event) bool {
+	if cp.Time.IsZero() || cp.Time.Before(evt.Time()) {
+		return true
+	}
+	return cp.Time.Equal(evt.Time()) && !slices.Contains(cp.Events, evt.ID())
+}
+
+// Advance returns the Checkpoint after processing the given events. Events
+// that are older than the Checkpoint do not change the Checkpoint.
+func (cp Checkpoint) Advance(events ...event.Event) Checkpoint {
+	for _, evt := range events {
+		switch t := evt.Time(); {
+		case t.After(cp.Time):
+			cp = Checkpoint{Time: t, Events: []uuid.UUID{evt.ID()}}
+		case t.Equal(cp.Time) && !slices.Contains(cp.Events, evt.ID()):
+			cp.Events = append(slices.Clone(cp.Events), evt.ID())
+		}
+	}
+	return cp
+}
+
+// Equal reports whether cp and other are the same Checkpoint.
+func (cp Checkpoint) Equal(other Checkpoint) bool {
+	return cp.Time.Equal(other.Time) && slices.Equal(cp.Events, other.Events)
+}
+
+// MemoryCheckpointStore is an in-memory CheckpointStore.
+type MemoryCheckpointStore struct {
+	mux         sync.RWMutex
+	checkpoints map[string]Checkpoint
+}
+
+// NewCheckpointStore returns an in-memory CheckpointStore.
+func NewCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]Checkpoint)}
+}
+
+// Checkpoint returns the Checkpoint of the given projection.
+func (s *MemoryCheckpointStore) Checkpoint(_ context.Context, projection string) (Checkpoint, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	cp := s.checkpoints[projection]
+	cp.Events = slices.Clone(cp.Events)
+	return cp, nil
+}
+
+// SaveCheckpoint saves the Checkpoint of the given projection.
+func (s *MemoryCheckpointStore) SaveCheckpoint(_ context.Context, projection string, cp Checkpoint) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	cp.Events = slices.Clone(cp.Events)
+	s.checkpoints[projection] = cp
+	return nil
+}
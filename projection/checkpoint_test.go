@@ -0,0 +1,90 @@
+package projection_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+func TestCheckpoint_Advance(t *testing.T) {
+	now := time.Now()
+	a := event.New("foo", test.FooEventData{}, event.Time(now)).Any()
+	b := event.New("foo", test.FooEventData{}, event.Time(now)).Any()
+	old := event.New("foo", test.FooEventData{}, event.Time(now.Add(-time.Minute))).Any()
+
+	cp := projection.Checkpoint{}.Advance(a, old, b)
+
+	want := projection.Checkpoint{Time: now, Events: []uuid.UUID{a.ID(), b.ID()}}
+	if !cp.Equal(want) {
+		t.Fatalf("checkpoint should be %v; got %v", want, cp)
+	}
+
+	if cp.Allows(a) || cp.Allows(b) || cp.Allows(old) {
+		t.Fatalf("checkpoint should not allow processed or older events")
+	}
+
+	if !cp.Allows(event.New("foo", test.FooEventData{}, event.Time(now)).Any()) {
+		t.Fatalf("checkpoint should allow unprocessed events with the same time")
+	}
+
+	if !cp.Allows(event.New("foo", test.FooEventData{}, event.Time(now.Add(time.Second))).Any()) {
+		t.Fatalf("checkpoint should allow newer events")
+	}
+}
+
+func TestWithCheckpoint(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := time.Now()
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}, event.Time(now)).Any(),
+		event.New("foo", test.FooEventData{}, event.Time(now.Add(time.Second))).Any(),
+		event.New("foo", test.FooEventData{}, event.Time(now.Add(2*time.Second))).Any(),
+	}
+	store := eventstore.New(events...)
+
+	checkpoints := projection.NewCheckpointStore()
+	if err := checkpoints.SaveCheckpoint(ctx, "foo", projection.Checkpoint{}.Advance(events[:2]...)); err != nil {
+		t.Fatalf("save checkpoint: %v", err)
+	}
+
+	job := projection.NewJob(ctx, store, query.New(query.SortByTime()), projection.WithCheckpoint(checkpoints, "foo"))
+
+	str, errs, err := job.Events(ctx)
+	if err != nil {
+		t.Fatalf("Events failed with %q", err)
+	}
+
+	got, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		t.Fatalf("drain events: %v", err)
+	}
+
+	if len(got) != 1 || got[0].ID() != events[2].ID() {
+		t.Fatalf("job should only return the events after the checkpoint; got %v", got)
+	}
+
+	job = projection.NewJob(ctx, store, query.New(query.SortByTime()), projection.WithCheckpoint(checkpoints, "foo"), projection.WithReset())
+
+	str, errs, err = job.Events(ctx)
+	if err != nil {
+		t.Fatalf("Events failed with %q", err)
+	}
+
+	if got, err = streams.Drain(ctx, str, errs); err != nil {
+		t.Fatalf("drain events: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("jobs with reset should ignore the checkpoint; got %d events", len(got))
+	}
+}
@@ -0,0 +1,43 @@
+package projection
+
+import (
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+)
+
+// Annul removes compensated events together with their compensating events
+// (see aggregate.Compensate) from the provided events, so that a projection
+// treats the pair as if neither event had ever happened. Compensating events
+// whose compensated event is not part of the provided events are kept, so that
+// the projection can revert the effects of events that have already been
+// applied in a previous projection job.
+func Annul(events []event.Event) []event.Event {
+	compensated := make(map[uuid.UUID]bool)
+	present := make(map[uuid.UUID]bool, len(events))
+	for _, evt := range events {
+		present[evt.ID()] = true
+	}
+	for _, evt := range events {
+		if c, ok := aggregate.IsCompensation(evt); ok && present[c.EventID] {
+			compensated[c.EventID] = true
+		}
+	}
+
+	if len(compensated) == 0 {
+		return events
+	}
+
+	out := make([]event.Event, 0, len(events))
+	for _, evt := range events {
+		if compensated[evt.ID()] {
+			continue
+		}
+		if c, ok := aggregate.IsCompensation(evt); ok && compensated[c.EventID] {
+			continue
+		}
+		out = append(out, evt)
+	}
+
+	return out
+}
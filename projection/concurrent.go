@@ -0,0 +1,115 @@
+package projection
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/modernice/goes/event"
+)
+
+// Concurrent is a projection base whose state of type S can be read
+// concurrently, e.g. by HTTP handlers, while projection jobs apply events to
+// it. Concurrent embeds *Base, so event handlers are registered as usual. Event
+// handlers mutate the state returned by State, while readers use Read:
+//
+//	type Orders struct {
+//		*projection.Concurrent[map[uuid.UUID]Order]
+//	}
+//
+//	func NewOrders() *Orders {
+//		o := &Orders{Concurrent: projection.NewConcurrent(make(map[uuid.UUID]Order))}
+//		event.ApplyWith(o, o.placed, "shop.order.placed")
+//		return o
+//	}
+//
+//	func (o *Orders) placed(evt event.Of[OrderPlaced]) {
+//		(*o.State())[pick.AggregateID(evt)] = Order{Total: evt.Data().Total}
+//	}
+//
+//	func (o *Orders) Find(id uuid.UUID) (order Order, ok bool) {
+//		o.Read(func(orders map[uuid.UUID]Order) { order, ok = orders[id] })
+//		return
+//	}
+//
+// By default, events are applied under the write lock of a sync.RWMutex and
+// readers hold the read lock. Use the CopyOnWrite option to let readers access
+// an immutable copy of the state without locking instead.
+type Concurrent[S any] struct {
+	*Base
+
+	mux       sync.RWMutex
+	state     S
+	clone     func(S) S
+	published atomic.Pointer[S]
+}
+
+// ConcurrentOption is an option for a Concurrent projection.
+type ConcurrentOption[S any] func(*Concurrent[S])
+
+// CopyOnWrite returns a ConcurrentOption that makes readers access an
+// immutable copy of the state, so that reads never wait for events to be
+// applied. After each applied event, the state is copied using clone and the
+// copy is published to readers. clone must return a deep copy of the state.
+// Copy-on-write is best suited for small states with many readers.
+func CopyOnWrite[S any](clone func(S) S) ConcurrentOption[S] {
+	return func(c *Concurrent[S]) {
+		c.clone = clone
+	}
+}
+
+// NewConcurrent returns a Concurrent projection with the given initial state.
+func NewConcurrent[S any](initial S, opts ...ConcurrentOption[S]) *Concurrent[S] {
+	c := &Concurrent[S]{Base: New(), state: initial}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.publish()
+	return c
+}
+
+// ApplyEvent implements Target. ApplyEvent calls the registered event handler
+// of the event while holding the write lock.
+func (c *Concurrent[S]) ApplyEvent(evt event.Event) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.Base.ApplyEvent(evt)
+	c.publish()
+}
+
+// State returns a pointer to the state. State must only be used by event
+// handlers and within Update, because the state is not protected otherwise.
+func (c *Concurrent[S]) State() *S {
+	return &c.state
+}
+
+// Read calls fn with the current state. fn must not modify the state or retain
+// references into it after returning. Read must not be called from event
+// handlers.
+func (c *Concurrent[S]) Read(fn func(S)) {
+	if c.clone != nil {
+		fn(*c.published.Load())
+		return
+	}
+
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	fn(c.state)
+}
+
+// Update calls fn with a pointer to the state while holding the write lock.
+// Use Update to modify the state outside of event handlers, e.g. to reset the
+// projection.
+func (c *Concurrent[S]) Update(fn func(*S)) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	fn(&c.state)
+	c.publish()
+}
+
+func (c *Concurrent[S]) publish() {
+	if c.clone == nil {
+		return
+	}
+	published := c.clone(c.state)
+	c.published.Store(&published)
+}
@@ -0,0 +1,72 @@
+package projection_test
+
+import (
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/projection"
+)
+
+type counter struct {
+	*projection.Concurrent[map[string]int]
+}
+
+func newCounter(opts ...projection.ConcurrentOption[map[string]int]) *counter {
+	c := &counter{Concurrent: projection.NewConcurrent(make(map[string]int), opts...)}
+	event.ApplyWith(c, c.count, "foo", "bar")
+	return c
+}
+
+func (c *counter) count(evt event.Of[test.FooEventData]) {
+	(*c.State())[evt.Name()]++
+}
+
+func (c *counter) get(name string) (n int) {
+	c.Read(func(counts map[string]int) { n = counts[name] })
+	return
+}
+
+func TestConcurrent(t *testing.T) {
+	tests := map[string][]projection.ConcurrentOption[map[string]int]{
+		"rwmutex":       nil,
+		"copy-on-write": {projection.CopyOnWrite(maps.Clone[map[string]int])},
+	}
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newCounter(opts...)
+
+			events := make([]event.Event, 100)
+			for i := range events {
+				events[i] = event.New("foo", test.FooEventData{}).Any()
+			}
+
+			var wg sync.WaitGroup
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				projection.Apply(c, events)
+			}()
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					c.get("foo")
+				}
+			}()
+			wg.Wait()
+
+			if n := c.get("foo"); n != 100 {
+				t.Fatalf("%q count should be %d; is %d", "foo", 100, n)
+			}
+
+			c.Update(func(counts *map[string]int) { *counts = make(map[string]int) })
+
+			if n := c.get("foo"); n != 0 {
+				t.Fatalf("%q count should be %d after reset; is %d", "foo", 0, n)
+			}
+		})
+	}
+}
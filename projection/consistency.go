@@ -0,0 +1,186 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/event"
+)
+
+// DefaultWaitInterval is the default interval at which WaitFor checks the
+// progress of a projection.
+const DefaultWaitInterval = 50 * time.Millisecond
+
+// ErrInvalidToken is returned by ParseToken if a token cannot be parsed.
+var ErrInvalidToken = errors.New("invalid consistency token")
+
+// Token is a consistency token that identifies the latest event of a write.
+// After handling a command, the write side returns the token of the raised
+// events to the caller, who can then wait until a read model reflects the
+// write (read-your-writes):
+//
+//	// write side
+//	token, err := projection.Save(ctx, repo, order)
+//	if err != nil { ... }
+//	w.Header().Set("X-Consistency-Token", token.String())
+//
+//	// read side
+//	token, err := projection.ParseToken(r.Header.Get("X-Consistency-Token"))
+//	if err := projection.WaitFor(ctx, summary, token); err != nil { ... }
+//
+// Commands that are handled by aggregate-based command handlers record the
+// token of the written events, which is returned in the report of a
+// synchronous dispatch (see report.Report.Token).
+type Token struct {
+	// Time is the time of the latest event.
+	Time time.Time
+
+	// EventID is the id of the latest event.
+	EventID uuid.UUID
+}
+
+// ProgressReporter reports the projection progress of a projection.
+// ProgressAware projections implement ProgressReporter.
+type ProgressReporter interface {
+	Progress() (time.Time, []uuid.UUID)
+}
+
+// ProgressFunc allows functions to be used as ProgressReporters, e.g. to
+// report the progress of a projection that is stored in a database.
+type ProgressFunc func() (time.Time, []uuid.UUID)
+
+// WaitOption is an option for WaitFor.
+type WaitOption func(*waitConfig)
+
+type waitConfig struct {
+	interval time.Duration
+}
+
+// WaitInterval returns a WaitOption that configures the interval at which
+// WaitFor checks the progress of a projection. Defaults to DefaultWaitInterval.
+func WaitInterval(d time.Duration) WaitOption {
+	return func(cfg *waitConfig) {
+		cfg.interval = d
+	}
+}
+
+// TokenOf returns the consistency token of the given events, which is the
+// token of the latest event. TokenOf returns the zero Token if no events are
+// provided.
+func TokenOf(events ...event.Event) Token {
+	var token Token
+	for _, evt := range events {
+		if token.EventID == uuid.Nil || evt.Time().After(token.Time) {
+			token = Token{Time: evt.Time(), EventID: evt.ID()}
+		}
+	}
+	return token
+}
+
+// ParseToken parses a token that was encoded using Token.String. An empty
+// string is parsed as the zero Token.
+func ParseToken(s string) (Token, error) {
+	if s == "" {
+		return Token{}, nil
+	}
+
+	nanos, id, ok := strings.Cut(s, ".")
+	if !ok {
+		return Token{}, fmt.Errorf("%w: %q", ErrInvalidToken, s)
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %q: %w", ErrInvalidToken, s, err)
+	}
+
+	eventID, err := uuid.Parse(id)
+	if err != nil {
+		return Token{}, fmt.Errorf("%w: %q: %w", ErrInvalidToken, s, err)
+	}
+
+	return Token{Time: time.Unix(0, n), EventID: eventID}, nil
+}
+
+// IsZero reports whether the token is the zero Token.
+func (t Token) IsZero() bool {
+	return t.EventID == uuid.Nil && t.Time.IsZero()
+}
+
+// String encodes the token as a string that can be parsed by ParseToken.
+func (t Token) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d.%s", t.Time.UnixNano(), t.EventID)
+}
+
+// ReachedBy reports whether a projection with the given progress reflects the
+// event of the token. The token is reached as soon as the progress is at or
+// after the time of the token's event, even if the projection did not apply
+// the event itself, because projections only apply the events they are
+// interested in.
+func (t Token) ReachedBy(progress time.Time, ids []uuid.UUID) bool {
+	if t.IsZero() || !progress.Before(t.Time) {
+		return true
+	}
+	return slices.Contains(ids, t.EventID)
+}
+
+// Save saves the aggregate using the given repository and returns the
+// consistency token of the changes that were saved. Save returns the zero
+// Token if the aggregate has no changes.
+func Save(ctx context.Context, repo aggregate.Repository, a aggregate.Aggregate) (Token, error) {
+	token := TokenOf(a.AggregateChanges()...)
+	if err := repo.Save(ctx, a); err != nil {
+		return Token{}, err
+	}
+	return token, nil
+}
+
+// WaitFor waits until the progress of the given projection reaches the token
+// (see Token.ReachedBy), or until ctx is canceled. WaitFor returns immediately
+// if the token is the zero Token.
+//
+// The progress of a projection only advances when the projection applies
+// events, so a projection that is not interested in the events of the token
+// reaches the token with the next event it applies. Create the token only
+// from the events that the projection is interested in to avoid waiting for
+// the next event.
+//
+// The progress of the projection is polled, so if the projection is applied
+// concurrently, its Progress method must be safe for concurrent use.
+// *Progressor is safe for concurrent use.
+func WaitFor(ctx context.Context, proj ProgressReporter, token Token, opts ...WaitOption) error {
+	cfg := waitConfig{interval: DefaultWaitInterval}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		if token.ReachedBy(proj.Progress()) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for consistency token: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Progress returns fn().
+func (fn ProgressFunc) Progress() (time.Time, []uuid.UUID) {
+	return fn()
+}
@@ -0,0 +1,59 @@
+package projection
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+)
+
+// DeadJob is a projection job that permanently failed.
+type DeadJob struct {
+	// Events are the events of the job.
+	Events []event.Event
+
+	// Err is the error of the last attempt to apply the job.
+	Err error
+
+	// Time is the time at which the job failed.
+	Time time.Time
+}
+
+// WithDeadLetter returns a SubscribeOption that delivers projection jobs that
+// permanently failed to the given handler. A job permanently fails if its apply
+// function returns an error and the job is not retried (see RetryPolicy), or
+// if all retries fail. Jobs that fail because the subscription is canceled are
+// not delivered. The error of a failed job is still sent into the error channel
+// of the subscription, together with the error of the handler, if any.
+//
+// Use the handler to persist failed jobs and replay them later by triggering
+// the schedule with the query of the dead job:
+//
+//	errs, err := s.Subscribe(ctx, apply, projection.WithDeadLetter(
+//		func(ctx context.Context, job projection.DeadJob) error {
+//			return deadJobs.Save(ctx, job)
+//		},
+//	))
+//
+//	// later
+//	err := s.Trigger(ctx, projection.Query(job.Query()))
+func WithDeadLetter(handler func(context.Context, DeadJob) error) SubscribeOption {
+	return func(s *Subscription) {
+		s.DeadLetter = handler
+	}
+}
+
+// Query returns a query for the events of the job, sorted by time. Trigger a
+// schedule with this query to replay the job. Note that the events of a
+// replayed job are ignored by progress-aware projections if their progress has
+// been advanced past the events in the meantime.
+func (j DeadJob) Query() event.Query {
+	// uuid.Nil prevents a job without events from matching all events.
+	ids := []uuid.UUID{uuid.Nil}
+	for _, evt := range j.Events {
+		ids = append(ids, evt.ID())
+	}
+	return query.New(query.ID(ids...), query.SortByTime())
+}
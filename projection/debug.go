@@ -0,0 +1,106 @@
+package projection
+
+import (
+	"github.com/modernice/goes/event"
+)
+
+// Step is a single event application that is passed to the BeforeApply and
+// AfterApply hooks.
+type Step struct {
+	// Event is the applied event.
+	Event event.Event
+
+	// Index is the position of the event among the events that were applied
+	// by the same call to Apply, starting at 0. Events that are rejected by a
+	// Guard or by the progress of the projection are not counted.
+	Index int
+
+	// State is a snapshot of the projection's state. In BeforeApply hooks, it
+	// is the state before the event is applied; in AfterApply hooks, it is the
+	// state after the event was applied. State is nil if the projection
+	// does not implement StateSnapshotter and the DebugState option is not
+	// used. See DebugState.
+	State any
+
+	// Err is the error of the event application. It is always nil in
+	// BeforeApply hooks.
+	Err error
+}
+
+// StateSnapshotter can be implemented by projections to provide the state
+// snapshots of the BeforeApply and AfterApply hooks.
+type StateSnapshotter interface {
+	// ProjectionState returns a snapshot of the projection's state. The
+	// snapshot must not share mutable data (maps, slices, pointers, mutexes)
+	// with the projection, because the projection keeps being modified after
+	// the snapshot was taken.
+	ProjectionState() any
+}
+
+// BeforeApply returns an ApplyOption that calls fn before each event is
+// applied to the projection. Together with AfterApply, it allows step-level
+// logging of a projection rebuild; a hook that blocks until it is released acts
+// as a conditional breakpoint:
+//
+//	err := job.Apply(ctx, proj, projection.BeforeApply(func(s projection.Step) {
+//		if s.Event.ID() == suspicious {
+//			log.Printf("before: %+v", s.State)
+//		}
+//	}))
+func BeforeApply(fn func(Step)) ApplyOption {
+	return func(cfg *applyConfig) {
+		cfg.before = append(cfg.before, fn)
+	}
+}
+
+// AfterApply returns an ApplyOption that calls fn after each event was applied
+// to the projection, including failed applications.
+func AfterApply(fn func(Step)) ApplyOption {
+	return func(cfg *applyConfig) {
+		cfg.after = append(cfg.after, fn)
+	}
+}
+
+// DebugState returns an ApplyOption that sets the function that takes the
+// state snapshots of the BeforeApply and AfterApply hooks. By default, the
+// snapshot is taken by the projection's ProjectionState method if it
+// implements StateSnapshotter. Projections are not copied implicitly, because
+// a copy would share maps, slices and mutexes with the projection; the State
+// of a Step is nil if neither is provided.
+func DebugState(fn func(Target[any]) any) ApplyOption {
+	return func(cfg *applyConfig) {
+		cfg.snapshot = fn
+	}
+}
+
+func (cfg applyConfig) debugBefore(target Target[any], evt event.Event, index int) {
+	if len(cfg.before) == 0 {
+		return
+	}
+	step := Step{Event: evt, Index: index, State: cfg.snapshotState(target)}
+	for _, fn := range cfg.before {
+		fn(step)
+	}
+}
+
+func (cfg applyConfig) debugAfter(target Target[any], evt event.Event, index int, err error) {
+	if len(cfg.after) == 0 {
+		return
+	}
+	step := Step{Event: evt, Index: index, State: cfg.snapshotState(target), Err: err}
+	for _, fn := range cfg.after {
+		fn(step)
+	}
+}
+
+func (cfg applyConfig) snapshotState(target Target[any]) any {
+	if cfg.snapshot != nil {
+		return cfg.snapshot(target)
+	}
+
+	if s, ok := target.(StateSnapshotter); ok {
+		return s.ProjectionState()
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+// Package emit lets projections emit events while a job is applied.
+//
+// Some projections produce facts of their own, e.g. a report projection that
+// emits "report.ready" once all parts of a report have been projected.
+// Publishing such events directly from an event handler of the projection
+// publishes them again whenever the projection is rebuilt from the event
+// history. Instead, projections embed an Outbox and emit events into it, and
+// the job is applied using Apply, which publishes the emitted events after the
+// job was applied successfully:
+//
+//	type Report struct {
+//		*projection.Base
+//		emit.Outbox
+//	}
+//
+//	func (r *Report) partCompleted(evt event.Of[PartCompletedData]) {
+//		if r.complete() {
+//			r.Emit(evt.Any(), "report.ready", ReportReadyData{ID: r.ID})
+//		}
+//	}
+//
+//	pub := emit.New(emit.Store(store), emit.Bus(bus))
+//	errs, err := schedule.Subscribe(ctx, func(job projection.Job) error {
+//		return emit.Apply(job, pub, report)
+//	})
+//
+// The ID of an emitted event is derived from the ID of the event that caused
+// it, so re-applying the same events emits events with the same IDs. A
+// Publisher that is created with the Store option inserts emitted events into
+// the event store and skips events that already exist, so rebuilds do not
+// publish duplicates. Without a store, consumers can use the IDs to
+// deduplicate events themselves.
+package emit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/projection"
+)
+
+// Namespace is the UUID namespace of the IDs of emitted events.
+var Namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("github.com/modernice/goes/projection/emit"))
+
+// Outbox collects the events that a projection emits. Projections embed an
+// Outbox to emit events. The zero value is ready to use. An Outbox is safe for
+// concurrent use.
+type Outbox struct {
+	mux     sync.Mutex
+	pending []event.Event
+	counts  map[string]int
+}
+
+// Publisher publishes the events that are emitted by projections.
+type Publisher struct {
+	bus   event.Bus
+	store event.Store
+}
+
+// Option is an option for a Publisher.
+type Option func(*Publisher)
+
+type emitter interface {
+	take() []event.Event
+}
+
+// Bus returns an Option that publishes emitted events over the given event
+// bus. If the Publisher also has a Store, only events that are not already in
+// the store are published. Do not use Bus if the store already publishes the
+// events that are inserted into it (see eventstore.WithBus).
+func Bus(bus event.Bus) Option {
+	return func(p *Publisher) {
+		p.bus = bus
+	}
+}
+
+// Store returns an Option that inserts emitted events into the given event
+// store. Events that already exist in the store are skipped.
+func Store(store event.Store) Option {
+	return func(p *Publisher) {
+		p.store = store
+	}
+}
+
+// New returns a Publisher. Use the Bus and Store options to configure where
+// emitted events are published to.
+func New(opts ...Option) *Publisher {
+	var p Publisher
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return &p
+}
+
+// Emit emits an event with the given name and data that was caused by the
+// given event. The ID of the emitted event is derived from the ID of the
+// cause, the name of the emitted event, and the number of events with that
+// name that the same cause has emitted before. The emitted event is returned.
+func (o *Outbox) Emit(cause event.Event, name string, data any, opts ...event.Option) event.Event {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+
+	if o.counts == nil {
+		o.counts = make(map[string]int)
+	}
+
+	key := cause.ID().String() + ":" + name
+	n := o.counts[key]
+	o.counts[key] = n + 1
+
+	id := uuid.NewSHA1(Namespace, []byte(key+":"+strconv.Itoa(n)))
+	evt := event.New[any](name, data, append([]event.Option{event.ID(id)}, opts...)...)
+	o.pending = append(o.pending, evt)
+
+	return evt
+}
+
+// Pending returns the events that have been emitted but not yet published.
+func (o *Outbox) Pending() []event.Event {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	out := make([]event.Event, len(o.pending))
+	copy(out, o.pending)
+	return out
+}
+
+func (o *Outbox) take() []event.Event {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	out := o.pending
+	o.pending = nil
+	o.counts = nil
+	return out
+}
+
+// Apply applies the job to the target and publishes the events that the
+// target emitted while the job was applied. The target must embed an Outbox;
+// otherwise, Apply only applies the job. Events that were emitted before
+// Apply was called are discarded. If applying the job fails, the emitted
+// events are discarded and the error is returned.
+func Apply(job projection.Job, p *Publisher, target projection.Target[any], opts ...projection.ApplyOption) error {
+	e, ok := target.(emitter)
+	if !ok {
+		return job.Apply(job, target, opts...)
+	}
+
+	e.take()
+
+	if err := job.Apply(job, target, opts...); err != nil {
+		e.take()
+		return err
+	}
+
+	return p.Publish(job, e.take()...)
+}
+
+// Publish publishes the given emitted events. If the Publisher has a Store,
+// events that already exist in the store are skipped and the remaining events
+// are inserted into the store. If the Publisher has a Bus, the remaining
+// events are published over the bus.
+func (p *Publisher) Publish(ctx context.Context, events ...event.Event) error {
+	if p.store != nil {
+		events = p.unpublished(ctx, events)
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	if p.store != nil {
+		if err := p.store.Insert(ctx, events...); err != nil {
+			return fmt.Errorf("insert emitted events: %w", err)
+		}
+	}
+
+	if p.bus != nil {
+		if err := p.bus.Publish(ctx, events...); err != nil {
+			return fmt.Errorf("publish emitted events: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *Publisher) unpublished(ctx context.Context, events []event.Event) []event.Event {
+	out := make([]event.Event, 0, len(events))
+	for _, evt := range events {
+		if _, err := p.store.Find(ctx, evt.ID()); err == nil {
+			continue
+		}
+		out = append(out, evt)
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package emit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/emit"
+)
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+
+	id := uuid.New()
+	source := eventstore.New(
+		event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 1)).Any(),
+		event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 2)).Any(),
+	)
+
+	emitted := eventstore.New()
+	bus := &recordingBus{Bus: eventbus.New()}
+	pub := emit.New(emit.Store(emitted), emit.Bus(bus))
+
+	// Applying the same events twice simulates a rebuild of the projection.
+	for i := 0; i < 2; i++ {
+		job := projection.NewJob(ctx, source, query.New(query.SortByAggregate()))
+		if err := emit.Apply(job, pub, &mockProjection{}); err != nil {
+			t.Fatalf("Apply failed with %q", err)
+		}
+	}
+
+	str, errs, err := emitted.Query(ctx, query.New(query.Name("foo.seen")))
+	if err != nil {
+		t.Fatalf("query emitted events: %v", err)
+	}
+
+	events, err := streams.Drain(ctx, str, errs)
+	if err != nil {
+		t.Fatalf("query emitted events: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("store should contain %d emitted events; got %d", 2, len(events))
+	}
+
+	if len(bus.published) != 2 {
+		t.Fatalf("%d emitted events should be published; got %d", 2, len(bus.published))
+	}
+}
+
+func TestApply_error(t *testing.T) {
+	ctx := context.Background()
+
+	source := eventstore.New(event.New("foo", test.FooEventData{}).Any())
+	bus := &recordingBus{Bus: eventbus.New()}
+	pub := emit.New(emit.Bus(bus))
+
+	job := projection.NewJob(ctx, source, query.New())
+	proj := &failingProjection{}
+
+	if err := emit.Apply(job, pub, proj); !errors.Is(err, errMock) {
+		t.Fatalf("Apply should fail with %q; got %q", errMock, err)
+	}
+
+	if len(bus.published) != 0 {
+		t.Fatalf("no events should be published if the job fails; got %d", len(bus.published))
+	}
+
+	if len(proj.Pending()) != 0 {
+		t.Fatalf("emitted events should be discarded if the job fails")
+	}
+}
+
+func TestOutbox_Emit(t *testing.T) {
+	var a, b emit.Outbox
+	cause := event.New("foo", test.FooEventData{}).Any()
+
+	first := a.Emit(cause, "bar", test.FooEventData{})
+	second := a.Emit(cause, "bar", test.FooEventData{})
+
+	if first.ID() == second.ID() {
+		t.Fatalf("events that are emitted by the same cause should have different IDs")
+	}
+
+	if b.Emit(cause, "bar", test.FooEventData{}).ID() != first.ID() {
+		t.Fatalf("emitted events should have deterministic IDs")
+	}
+}
+
+var errMock = errors.New("mock error")
+
+type mockProjection struct {
+	emit.Outbox
+}
+
+func (p *mockProjection) ApplyEvent(evt event.Event) {
+	p.Emit(evt, "foo.seen", test.FooEventData{})
+}
+
+// failingProjection emits an event and then fails to apply the event.
+type failingProjection struct {
+	emit.Outbox
+}
+
+func (p *failingProjection) ApplyEvent(evt event.Event) {}
+
+func (p *failingProjection) TryApplyEvent(evt event.Event) error {
+	p.Emit(evt, "foo.seen", test.FooEventData{})
+	return errMock
+}
+
+type recordingBus struct {
+	event.Bus
+	published []event.Event
+}
+
+func (b *recordingBus) Publish(ctx context.Context, events ...event.Event) error {
+	b.published = append(b.published, events...)
+	return nil
+}
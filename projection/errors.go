@@ -0,0 +1,177 @@
+package projection
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/modernice/goes/event"
+)
+
+// DefaultMaxRetries is the default number of times the application of an
+// event is retried if it fails with the Retry policy.
+const DefaultMaxRetries = 3
+
+const (
+	// Fail stops applying events and returns the error. Fail is the default
+	// policy for errors that are not classified by an OnError option.
+	Fail = ErrorPolicy(iota)
+
+	// Skip skips the event and reports it to the DeadLetter hook.
+	Skip
+
+	// Retry retries applying the event. If the event still fails after
+	// MaxRetries retries, the Fail policy is used.
+	Retry
+)
+
+// ErrPanic is wrapped by the error that is returned when a projection panics
+// while applying an event and the panic is recovered because of an OnError
+// option.
+var ErrPanic = errors.New("projection panicked")
+
+// ErrorPolicy determines what happens if applying an event to a projection
+// fails.
+type ErrorPolicy int
+
+// An ErrorApplier is a projection that reports errors while applying events.
+// If a projection implements ErrorApplier, TryApplyEvent is called instead of
+// ApplyEvent.
+type ErrorApplier interface {
+	TryApplyEvent(event.Event) error
+}
+
+// ApplyError is returned by TryApply and TryApplyStream if applying an event to
+// a projection fails with the Fail policy. Event is nil if the error was
+// returned by the event stream of a projection job instead of the projection.
+type ApplyError struct {
+	Event event.Event
+	Err   error
+}
+
+type errorPolicy struct {
+	policy     ErrorPolicy
+	classifier func(error) bool
+}
+
+// OnError returns an ApplyOption that applies the given policy to errors that
+// occur while applying events to a projection. The classifier reports whether
+// the policy applies to an error; a nil classifier matches all errors. If
+// multiple OnError options are provided, the first matching policy is used.
+//
+// When OnError is used, panics of the projection are recovered and treated as
+// errors that wrap ErrPanic. A malformed historical event can thereby be
+// skipped instead of permanently blocking a continuous projection:
+//
+//	projection.OnError(projection.Skip, func(err error) bool {
+//		return errors.Is(err, projection.ErrPanic)
+//	})
+//
+// When applying a projection job, errors of the job's event stream (e.g. events
+// whose data cannot be decoded) are classified as well. Only the Skip policy
+// can be applied to them; Retry is treated as Fail.
+func OnError(policy ErrorPolicy, classifier func(error) bool) ApplyOption {
+	return func(cfg *applyConfig) {
+		cfg.errorPolicies = append(cfg.errorPolicies, errorPolicy{
+			policy:     policy,
+			classifier: classifier,
+		})
+	}
+}
+
+// DeadLetter returns an ApplyOption that registers a hook that is called for
+// every event that is skipped because of the Skip policy. The event is nil if
+// the error was returned by the event stream of a projection job.
+func DeadLetter(fn func(event.Event, error)) ApplyOption {
+	return func(cfg *applyConfig) {
+		cfg.deadLetter = fn
+	}
+}
+
+// MaxRetries returns an ApplyOption that configures how often the application
+// of an event is retried if it fails with the Retry policy. Defaults to
+// DefaultMaxRetries. Note that a projection is not reset before a retry, so the
+// event handlers of a projection should not partially modify the projection
+// before they fail.
+func MaxRetries(n int) ApplyOption {
+	return func(cfg *applyConfig) {
+		cfg.maxRetries = n
+	}
+}
+
+// String returns the name of the policy.
+func (p ErrorPolicy) String() string {
+	switch p {
+	case Fail:
+		return "fail"
+	case Skip:
+		return "skip"
+	case Retry:
+		return "retry"
+	default:
+		return fmt.Sprintf("<unknown error policy %d>", int(p))
+	}
+}
+
+// Error implements error.
+func (err *ApplyError) Error() string {
+	if err.Event == nil {
+		return fmt.Sprintf("apply events: %v", err.Err)
+	}
+	return fmt.Sprintf("apply %q event (%s): %v", err.Event.Name(), err.Event.ID(), err.Err)
+}
+
+// Unwrap returns the underlying error.
+func (err *ApplyError) Unwrap() error {
+	return err.Err
+}
+
+// policy returns the ErrorPolicy for the given error.
+func (cfg applyConfig) policy(err error) ErrorPolicy {
+	for _, p := range cfg.errorPolicies {
+		if p.classifier == nil || p.classifier(err) {
+			return p.policy
+		}
+	}
+	return Fail
+}
+
+// skip reports whether the given error should be skipped, and reports it to
+// the DeadLetter hook if so.
+func (cfg applyConfig) skip(evt event.Event, err error) bool {
+	if cfg.policy(err) != Skip {
+		return false
+	}
+	if cfg.deadLetter != nil {
+		cfg.deadLetter(evt, err)
+	}
+	return true
+}
+
+// applyEvent applies the event to the target, retrying it if it fails with the
+// Retry policy.
+func (cfg applyConfig) applyEvent(target Target[any], evt event.Event) error {
+	for attempt := 0; ; attempt++ {
+		err := cfg.tryApplyEvent(target, evt)
+		if err == nil || cfg.policy(err) != Retry || attempt >= cfg.maxRetries {
+			return err
+		}
+	}
+}
+
+func (cfg applyConfig) tryApplyEvent(target Target[any], evt event.Event) (err error) {
+	if len(cfg.errorPolicies) > 0 {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w: %v", ErrPanic, r)
+			}
+		}()
+	}
+
+	if applier, ok := target.(ErrorApplier); ok {
+		return applier.TryApplyEvent(evt)
+	}
+
+	target.ApplyEvent(evt)
+
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package federation shares the state of read models between services.
+//
+// When multiple services need the same read model, each service would have to
+// subscribe to the same upstream events and re-project them. Instead, the
+// service that owns a read model exposes it using a Publisher, and other
+// services maintain a local Replica of the read model that is synced over the
+// event bus:
+//
+//	// upstream service
+//	pub := federation.NewPublisher(bus)
+//	pub.Expose("order_summary", func(ctx context.Context) (map[string]any, error) {
+//		return summaries.All(ctx)
+//	})
+//	errs, err := pub.Run(ctx)
+//
+//	errs, err := schedule.Subscribe(ctx, func(job projection.Job) error {
+//		return federation.Apply(job, pub, "order_summary", summaries, func(evt event.Event) []string {
+//			return []string{pick.AggregateID(evt).String()}
+//		}, summaries.State)
+//	})
+//
+//	// downstream service
+//	replica := federation.NewReplica[OrderSummary](bus, "order_summary")
+//	errs, err := replica.Run(ctx)
+//	<-replica.Synced()
+//	summary, ok := replica.Get(orderID.String())
+//
+// A Replica requests a snapshot of the read model when it starts, and then
+// applies the updates that are published by the Publisher. States are encoded
+// using encoding/json.
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/projection"
+)
+
+const (
+	// Updated is the name of the event that publishes updated states of a read
+	// model.
+	Updated = "goes.projection.federation.updated"
+
+	// SyncRequested is the name of the event that requests a snapshot of a
+	// read model.
+	SyncRequested = "goes.projection.federation.sync_requested"
+
+	// Synced is the name of the event that responds to a SyncRequested event
+	// with a batch of the states of a read model.
+	Synced = "goes.projection.federation.synced"
+)
+
+// UpdatedData is the event data of the Updated event.
+type UpdatedData struct {
+	ReadModel string
+	Entries   []Entry
+}
+
+// SyncRequestedData is the event data of the SyncRequested event.
+type SyncRequestedData struct {
+	RequestID uuid.UUID
+	ReadModel string
+}
+
+// SyncedData is the event data of the Synced event. A snapshot is split into
+// multiple Synced events; Last is true for the last batch of a snapshot.
+type SyncedData struct {
+	RequestID uuid.UUID
+	ReadModel string
+	Entries   []Entry
+	Last      bool
+
+	// Time is the time at which the Publisher took the snapshot, according to
+	// the clock of the Publisher. Replicas remove the states that are not part
+	// of the snapshot and are older than Time.
+	Time time.Time
+}
+
+// Entry is the state of a read model for a single key.
+type Entry struct {
+	// Key is the key of the state.
+	Key string
+
+	// State is the JSON-encoded state.
+	State []byte
+
+	// Deleted reports whether the state was deleted.
+	Deleted bool
+
+	// Time is the time of the state. Replicas ignore states that are older
+	// than their current state of the key.
+	Time time.Time
+}
+
+// RegisterEvents registers the federation events into an event registry.
+func RegisterEvents(r codec.Registerer) {
+	codec.Register[UpdatedData](r, Updated)
+	codec.Register[SyncRequestedData](r, SyncRequested)
+	codec.Register[SyncedData](r, Synced)
+}
+
+// Apply applies the job to the target and publishes the updated states of the
+// read model afterwards. The keys function returns the keys of the read model
+// that are affected by an event; it is called for every event that is
+// successfully applied to the target. The state function returns the current
+// state of a key, or false if the state was deleted. Updates are only
+// published if the job was applied successfully, as a single event for all
+// distinct keys.
+func Apply(
+	job projection.Job,
+	p *Publisher,
+	readModel string,
+	target projection.Target[any],
+	keys func(event.Event) []string,
+	state func(key string) (any, bool),
+	opts ...projection.ApplyOption,
+) error {
+	var changed []string
+	seen := make(map[string]bool)
+
+	opts = append(opts, projection.AfterApply(func(s projection.Step) {
+		if s.Err != nil {
+			return
+		}
+		for _, key := range keys(s.Event) {
+			if !seen[key] {
+				seen[key] = true
+				changed = append(changed, key)
+			}
+		}
+	}))
+
+	if err := job.Apply(job, target, opts...); err != nil {
+		return err
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	entries := make([]Entry, 0, len(changed))
+	for _, key := range changed {
+		s, ok := state(key)
+		if !ok {
+			entries = append(entries, Entry{Key: key, Deleted: true, Time: now})
+			continue
+		}
+
+		entry, err := newEntry(key, s, now)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+
+	return p.publish(job, readModel, entries)
+}
+
+func newEntry(key string, state any, t time.Time) (Entry, error) {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return Entry{}, fmt.Errorf("encode state: %w [key=%s]", err, key)
+	}
+	return Entry{Key: key, State: b, Time: t}, nil
+}
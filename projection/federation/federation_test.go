@@ -0,0 +1,307 @@
+package federation_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/internal/projectiontest"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/federation"
+)
+
+type summary struct {
+	Title string
+}
+
+func TestReplica(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	newPublisher(ctx, t, bus, map[string]any{
+		"a": summary{Title: "A"},
+		"b": summary{Title: "B"},
+	}, federation.SyncBatchSize(1))
+
+	replica, _ := runReplica(ctx, t, bus)
+
+	want := map[string]summary{"a": {Title: "A"}, "b": {Title: "B"}}
+	if got := replica.All(); len(got) != 2 || got["a"] != want["a"] || got["b"] != want["b"] {
+		t.Fatalf("replica should have the states %v after syncing; got %v", want, got)
+	}
+}
+
+func TestReplica_updates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	pub := newPublisher(ctx, t, bus, map[string]any{
+		"a": summary{Title: "A"},
+		"b": summary{Title: "B"},
+	})
+
+	replica, changes := runReplica(ctx, t, bus)
+
+	if err := pub.Update(ctx, "summaries", map[string]any{"a": summary{Title: "A2"}}); err != nil {
+		t.Fatalf("Update failed with %q", err)
+	}
+
+	if change := receive(t, changes); change.Key != "a" || change.State.Title != "A2" {
+		t.Fatalf("should receive the update of %q; got %+v", "a", change)
+	}
+
+	if got, ok := replica.Get("a"); !ok || got.Title != "A2" {
+		t.Fatalf("state of %q should be replaced by the update; got %+v", "a", got)
+	}
+
+	if err := pub.Delete(ctx, "summaries", "b"); err != nil {
+		t.Fatalf("Delete failed with %q", err)
+	}
+
+	if change := receive(t, changes); change.Key != "b" || !change.Deleted {
+		t.Fatalf("should receive the deletion of %q; got %+v", "b", change)
+	}
+
+	if _, ok := replica.Get("b"); ok {
+		t.Fatalf("state of %q should be invalidated", "b")
+	}
+}
+
+func TestReplica_Resync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+
+	var mux sync.Mutex
+	states := map[string]any{"a": summary{Title: "A"}, "b": summary{Title: "B"}}
+	pub := federation.NewPublisher(bus)
+	pub.Expose("summaries", func(context.Context) (map[string]any, error) {
+		mux.Lock()
+		defer mux.Unlock()
+		return states, nil
+	})
+	run(ctx, t, pub.Run)
+
+	replica, changes := runReplica(ctx, t, bus)
+
+	mux.Lock()
+	states = map[string]any{"a": summary{Title: "A"}}
+	mux.Unlock()
+
+	if err := replica.Resync(ctx); err != nil {
+		t.Fatalf("Resync failed with %q", err)
+	}
+
+	for {
+		change := receive(t, changes)
+		if change.Key == "b" && change.Deleted {
+			break
+		}
+	}
+
+	if _, ok := replica.Get("b"); ok {
+		t.Fatalf("state of %q should be removed after resyncing", "b")
+	}
+
+	if _, ok := replica.Get("a"); !ok {
+		t.Fatalf("state of %q should be kept after resyncing", "a")
+	}
+}
+
+func TestReplica_Resync_clockSkew(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+
+	// The clock of the publisher is an hour behind the clock of the replica.
+	publisherTime := func() time.Time { return time.Now().Add(-time.Hour) }
+
+	requests, errs, err := bus.Subscribe(ctx, federation.SyncRequested)
+	if err != nil {
+		t.Fatalf("subscribe to %q events: %v", federation.SyncRequested, err)
+	}
+	go func() {
+		var synced bool
+		for evt := range requests {
+			req := evt.Data().(federation.SyncRequestedData)
+			snapshotTime := publisherTime()
+
+			// "b" is created after the snapshot was taken, but before the
+			// snapshot is received by the replica.
+			if synced {
+				update := event.New(federation.Updated, federation.UpdatedData{
+					ReadModel: "summaries",
+					Entries:   []federation.Entry{{Key: "b", State: []byte(`{"Title":"B"}`), Time: publisherTime()}},
+				})
+				if err := bus.Publish(ctx, update.Any()); err != nil {
+					t.Errorf("publish event: %v", err)
+				}
+			}
+			synced = true
+
+			evt := event.New(federation.Synced, federation.SyncedData{
+				RequestID: req.RequestID,
+				ReadModel: req.ReadModel,
+				Entries:   []federation.Entry{{Key: "a", State: []byte(`{"Title":"A"}`), Time: snapshotTime}},
+				Last:      true,
+				Time:      snapshotTime,
+			})
+			if err := bus.Publish(ctx, evt.Any()); err != nil {
+				t.Errorf("publish event: %v", err)
+			}
+		}
+	}()
+	go func() {
+		for err := range errs {
+			t.Errorf("async error: %v", err)
+		}
+	}()
+
+	replica, _ := runReplica(ctx, t, bus)
+
+	if err := replica.Resync(ctx); err != nil {
+		t.Fatalf("Resync failed with %q", err)
+	}
+
+	<-time.After(50 * time.Millisecond)
+
+	if got, ok := replica.Get("b"); !ok || got.Title != "B" {
+		t.Fatalf("states that were updated after the snapshot was taken should be kept; got %+v", got)
+	}
+}
+
+func TestReplica_staleUpdate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	newPublisher(ctx, t, bus, map[string]any{"a": summary{Title: "A"}})
+	replica, _ := runReplica(ctx, t, bus)
+
+	evt := event.New(federation.Updated, federation.UpdatedData{
+		ReadModel: "summaries",
+		Entries: []federation.Entry{{
+			Key:   "a",
+			State: []byte(`{"Title":"stale"}`),
+			Time:  time.Now().Add(-time.Hour),
+		}},
+	})
+	if err := bus.Publish(ctx, evt.Any()); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	<-time.After(50 * time.Millisecond)
+
+	if got, _ := replica.Get("a"); got.Title != "A" {
+		t.Fatalf("updates older than the current state should be ignored; got %+v", got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id := uuid.New()
+	store := eventstore.New(
+		event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 1)).Any(),
+		event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 2)).Any(),
+	)
+
+	bus := eventbus.New()
+	pub := newPublisher(ctx, t, bus, nil)
+	replica, changes := runReplica(ctx, t, bus)
+
+	job := projection.NewJob(ctx, store, query.New(query.SortByAggregate()))
+	proj := projectiontest.NewMockProjection()
+
+	if err := federation.Apply(job, pub, "summaries", proj, func(evt event.Event) []string {
+		return []string{pick.AggregateID(evt).String()}
+	}, func(key string) (any, bool) {
+		return summary{Title: key}, true
+	}); err != nil {
+		t.Fatalf("Apply failed with %q", err)
+	}
+
+	if len(proj.AppliedEvents) != 2 {
+		t.Fatalf("job should be applied to the projection")
+	}
+
+	if change := receive(t, changes); change.Key != id.String() {
+		t.Fatalf("should receive the update of %q; got %+v", id, change)
+	}
+
+	select {
+	case change := <-changes:
+		t.Fatalf("updates of the same key should be published once; got another change %+v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if got, ok := replica.Get(id.String()); !ok || got.Title != id.String() {
+		t.Fatalf("replica should have the state of %q; got %+v", id, got)
+	}
+}
+
+func newPublisher(ctx context.Context, t *testing.T, bus event.Bus, states map[string]any, opts ...federation.PublisherOption) *federation.Publisher {
+	pub := federation.NewPublisher(bus, opts...)
+	pub.Expose("summaries", func(context.Context) (map[string]any, error) {
+		return states, nil
+	})
+	run(ctx, t, pub.Run)
+	return pub
+}
+
+func runReplica(ctx context.Context, t *testing.T, bus event.Bus) (*federation.Replica[summary], <-chan federation.Change[summary]) {
+	changes := make(chan federation.Change[summary], 10)
+	replica := federation.NewReplica(bus, "summaries", federation.OnChange(func(c federation.Change[summary]) {
+		changes <- c
+	}))
+	run(ctx, t, replica.Run)
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("replica should be synced")
+	case <-replica.Synced():
+	}
+
+	for len(changes) > 0 {
+		<-changes
+	}
+
+	return replica, changes
+}
+
+func run(ctx context.Context, t *testing.T, fn func(context.Context) (<-chan error, error)) {
+	errs, err := fn(ctx)
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+	go func() {
+		for err := range errs {
+			if ctx.Err() == nil {
+				t.Errorf("async error: %v", err)
+			}
+		}
+	}()
+}
+
+func receive(t *testing.T, changes <-chan federation.Change[summary]) federation.Change[summary] {
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("didn't receive change after %s", time.Second)
+		return federation.Change[summary]{}
+	case change := <-changes:
+		return change
+	}
+}
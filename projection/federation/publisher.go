@@ -0,0 +1,184 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// DefaultSyncBatchSize is the default number of states per Synced event.
+const DefaultSyncBatchSize = 100
+
+// Publisher exposes the states of read models to Replicas in other services.
+type Publisher struct {
+	bus       event.Bus
+	batchSize int
+
+	mux        sync.RWMutex
+	readModels map[string]func(context.Context) (map[string]any, error)
+}
+
+// PublisherOption is an option for a Publisher.
+type PublisherOption func(*Publisher)
+
+// SyncBatchSize returns a PublisherOption that configures the number of states
+// per Synced event. Defaults to DefaultSyncBatchSize.
+func SyncBatchSize(n int) PublisherOption {
+	return func(p *Publisher) {
+		p.batchSize = n
+	}
+}
+
+// NewPublisher returns a Publisher that communicates over the given event bus.
+func NewPublisher(bus event.Bus, opts ...PublisherOption) *Publisher {
+	p := &Publisher{
+		bus:        bus,
+		batchSize:  DefaultSyncBatchSize,
+		readModels: make(map[string]func(context.Context) (map[string]any, error)),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.batchSize <= 0 {
+		p.batchSize = DefaultSyncBatchSize
+	}
+	return p
+}
+
+// Expose exposes the read model with the given name. The snapshot function
+// returns the current states of the read model by key; it is called when a
+// Replica requests a snapshot of the read model.
+func (p *Publisher) Expose(readModel string, snapshot func(context.Context) (map[string]any, error)) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.readModels[readModel] = snapshot
+}
+
+// Update publishes the given states of the read model to its Replicas.
+func (p *Publisher) Update(ctx context.Context, readModel string, states map[string]any) error {
+	now := time.Now()
+	entries := make([]Entry, 0, len(states))
+	for key, state := range states {
+		entry, err := newEntry(key, state, now)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	return p.publish(ctx, readModel, entries)
+}
+
+// Delete publishes the deletion of the given keys of the read model to its
+// Replicas.
+func (p *Publisher) Delete(ctx context.Context, readModel string, keys ...string) error {
+	now := time.Now()
+	entries := make([]Entry, len(keys))
+	for i, key := range keys {
+		entries[i] = Entry{Key: key, Deleted: true, Time: now}
+	}
+	return p.publish(ctx, readModel, entries)
+}
+
+func (p *Publisher) publish(ctx context.Context, readModel string, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	evt := event.New(Updated, UpdatedData{ReadModel: readModel, Entries: entries})
+	if err := p.bus.Publish(ctx, evt.Any()); err != nil {
+		return fmt.Errorf("publish %q event: %w", evt.Name(), err)
+	}
+
+	return nil
+}
+
+// Run starts the Publisher in a new goroutine and returns a channel of
+// asynchronous errors, or a single error if the event bus fails to subscribe.
+// The Publisher responds to snapshot requests of Replicas of exposed read
+// models until ctx is canceled.
+func (p *Publisher) Run(ctx context.Context) (<-chan error, error) {
+	events, errs, err := p.bus.Subscribe(ctx, SyncRequested)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to %q event: %w", SyncRequested, err)
+	}
+
+	out := make(chan error)
+
+	go func() {
+		defer close(out)
+
+		fail := func(err error) {
+			select {
+			case <-ctx.Done():
+			case out <- err:
+			}
+		}
+
+		streams.ForEach(ctx, func(evt event.Event) {
+			data, ok := evt.Data().(SyncRequestedData)
+			if !ok {
+				return
+			}
+
+			if err := p.sync(ctx, data); err != nil {
+				fail(fmt.Errorf("sync %q read model: %w", data.ReadModel, err))
+			}
+		}, fail, events, errs)
+	}()
+
+	return out, nil
+}
+
+func (p *Publisher) sync(ctx context.Context, req SyncRequestedData) error {
+	p.mux.RLock()
+	snapshot, ok := p.readModels[req.ReadModel]
+	p.mux.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	states, err := snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	keys := make([]string, 0, len(states))
+	for key := range states {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for start := 0; start == 0 || start < len(keys); start += p.batchSize {
+		end := min(start+p.batchSize, len(keys))
+
+		data := SyncedData{
+			RequestID: req.RequestID,
+			ReadModel: req.ReadModel,
+			Entries:   make([]Entry, 0, end-start),
+			Last:      end == len(keys),
+			Time:      now,
+		}
+
+		for _, key := range keys[start:end] {
+			entry, err := newEntry(key, states[key], now)
+			if err != nil {
+				return err
+			}
+			data.Entries = append(data.Entries, entry)
+		}
+
+		evt := event.New(Synced, data)
+		if err := p.bus.Publish(ctx, evt.Any()); err != nil {
+			return fmt.Errorf("publish %q event: %w", evt.Name(), err)
+		}
+	}
+
+	return nil
+}
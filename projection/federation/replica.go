@@ -0,0 +1,268 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// Replica is a local replica of a read model that is exposed by a Publisher
+// in another service. A Replica is kept in sync by applying the updates that
+// are published by the Publisher. When an update is received, the previous
+// state of the key is invalidated and replaced by the new state.
+type Replica[T any] struct {
+	bus       event.Bus
+	readModel string
+	onChange  []func(Change[T])
+
+	mux     sync.RWMutex
+	entries map[string]replicaEntry[T]
+	syncs   map[uuid.UUID]*pendingSync
+
+	syncedOnce sync.Once
+	synced     chan struct{}
+}
+
+// Change is a change of a replicated read model.
+type Change[T any] struct {
+	// Key is the key of the changed state.
+	Key string
+
+	// State is the new state. If Deleted is true, State is the zero value.
+	State T
+
+	// Deleted reports whether the state was deleted.
+	Deleted bool
+
+	// Time is the time of the change.
+	Time time.Time
+}
+
+// ReplicaOption is an option for a Replica.
+type ReplicaOption[T any] func(*Replica[T])
+
+type replicaEntry[T any] struct {
+	state   T
+	deleted bool
+	time    time.Time
+}
+
+type pendingSync struct {
+	keys map[string]bool
+}
+
+// OnChange returns a ReplicaOption that registers a function that is called
+// for every change of the replicated read model, after the change was applied
+// to the Replica.
+func OnChange[T any](fn func(Change[T])) ReplicaOption[T] {
+	return func(r *Replica[T]) {
+		r.onChange = append(r.onChange, fn)
+	}
+}
+
+// NewReplica returns a Replica of the read model with the given name.
+func NewReplica[T any](bus event.Bus, readModel string, opts ...ReplicaOption[T]) *Replica[T] {
+	r := &Replica[T]{
+		bus:       bus,
+		readModel: readModel,
+		entries:   make(map[string]replicaEntry[T]),
+		syncs:     make(map[uuid.UUID]*pendingSync),
+		synced:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run starts the Replica in a new goroutine and returns a channel of
+// asynchronous errors, or a single error if the event bus fails to subscribe
+// or the snapshot of the read model cannot be requested. The Replica applies
+// the updates of the read model until ctx is canceled.
+func (r *Replica[T]) Run(ctx context.Context) (<-chan error, error) {
+	events, errs, err := r.bus.Subscribe(ctx, Updated, Synced)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to %v events: %w", []string{Updated, Synced}, err)
+	}
+
+	out := make(chan error)
+
+	go func() {
+		defer close(out)
+
+		fail := func(err error) {
+			select {
+			case <-ctx.Done():
+			case out <- err:
+			}
+		}
+
+		streams.ForEach(ctx, func(evt event.Event) {
+			if err := r.handle(evt); err != nil {
+				fail(err)
+			}
+		}, fail, events, errs)
+	}()
+
+	if err := r.Resync(ctx); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Resync requests a new snapshot of the read model. States that are not part
+// of the snapshot and were not updated after the snapshot was taken are
+// removed from the Replica. Run automatically requests a snapshot when the
+// Replica is started; Resync can be used to recover from missed updates.
+func (r *Replica[T]) Resync(ctx context.Context) error {
+	id := uuid.New()
+
+	r.mux.Lock()
+	r.syncs[id] = &pendingSync{keys: make(map[string]bool)}
+	r.mux.Unlock()
+
+	evt := event.New(SyncRequested, SyncRequestedData{RequestID: id, ReadModel: r.readModel})
+	if err := r.bus.Publish(ctx, evt.Any()); err != nil {
+		r.mux.Lock()
+		delete(r.syncs, id)
+		r.mux.Unlock()
+		return fmt.Errorf("publish %q event: %w", evt.Name(), err)
+	}
+
+	return nil
+}
+
+// Synced returns a channel that is closed when the Replica received the first
+// complete snapshot of the read model.
+func (r *Replica[T]) Synced() <-chan struct{} {
+	return r.synced
+}
+
+// Get returns the state of the given key, or false if the Replica has no
+// state for the key.
+func (r *Replica[T]) Get(key string) (T, bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	entry, ok := r.entries[key]
+	if !ok || entry.deleted {
+		var zero T
+		return zero, false
+	}
+	return entry.state, true
+}
+
+// All returns all states of the Replica by key.
+func (r *Replica[T]) All() map[string]T {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	out := make(map[string]T, len(r.entries))
+	for key, entry := range r.entries {
+		if !entry.deleted {
+			out[key] = entry.state
+		}
+	}
+	return out
+}
+
+func (r *Replica[T]) handle(evt event.Event) error {
+	switch data := evt.Data().(type) {
+	case UpdatedData:
+		if data.ReadModel != r.readModel {
+			return nil
+		}
+		return r.apply(data.Entries, nil)
+	case SyncedData:
+		if data.ReadModel != r.readModel {
+			return nil
+		}
+
+		r.mux.RLock()
+		pending, ok := r.syncs[data.RequestID]
+		r.mux.RUnlock()
+		if !ok {
+			return nil
+		}
+
+		if err := r.apply(data.Entries, pending); err != nil {
+			return err
+		}
+
+		if data.Last {
+			r.completeSync(data.RequestID, pending, data.Time)
+		}
+	}
+	return nil
+}
+
+func (r *Replica[T]) apply(entries []Entry, pending *pendingSync) error {
+	var changes []Change[T]
+
+	r.mux.Lock()
+	for _, e := range entries {
+		if pending != nil {
+			pending.keys[e.Key] = true
+		}
+
+		if current, ok := r.entries[e.Key]; ok && current.time.After(e.Time) {
+			continue
+		}
+
+		var state T
+		if !e.Deleted {
+			if err := json.Unmarshal(e.State, &state); err != nil {
+				r.mux.Unlock()
+				return fmt.Errorf("decode state: %w [read_model=%s, key=%s]", err, r.readModel, e.Key)
+			}
+		}
+
+		r.entries[e.Key] = replicaEntry[T]{state: state, deleted: e.Deleted, time: e.Time}
+		changes = append(changes, Change[T]{Key: e.Key, State: state, Deleted: e.Deleted, Time: e.Time})
+	}
+	r.mux.Unlock()
+
+	r.notify(changes)
+
+	return nil
+}
+
+// completeSync removes the states that are not part of a complete snapshot and
+// are older than the snapshot. Deleted states that are older than the snapshot
+// are forgotten. Only the times of the Publisher are compared, so that the
+// clocks of the Publisher and the Replica do not need to be synchronized.
+func (r *Replica[T]) completeSync(id uuid.UUID, pending *pendingSync, snapshotTime time.Time) {
+	var changes []Change[T]
+
+	r.mux.Lock()
+	delete(r.syncs, id)
+	for key, entry := range r.entries {
+		if pending.keys[key] || !entry.time.Before(snapshotTime) {
+			continue
+		}
+		if entry.deleted {
+			delete(r.entries, key)
+			continue
+		}
+		r.entries[key] = replicaEntry[T]{deleted: true, time: snapshotTime}
+		changes = append(changes, Change[T]{Key: key, Deleted: true, Time: snapshotTime})
+	}
+	r.mux.Unlock()
+
+	r.notify(changes)
+
+	r.syncedOnce.Do(func() { close(r.synced) })
+}
+
+func (r *Replica[T]) notify(changes []Change[T]) {
+	for _, change := range changes {
+		for _, fn := range r.onChange {
+			fn(change)
+		}
+	}
+}
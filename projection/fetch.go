@@ -0,0 +1,113 @@
+package projection
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/helper/streams"
+)
+
+// Fetch extracts the ids of the aggregates with the given name from the events
+// of the job and fetches these aggregates from the repository. Aggregates are
+// created using the provided factory and hydrated by the repository, which uses
+// snapshots if configured. Fetch is useful for projections that need the
+// current state of the aggregates that a job affects:
+//
+//	orders, err := projection.Fetch(job, repo, "order", NewOrder)
+//
+// Aggregates that are fetched from a job created by NewJob are cached within
+// the job, so every aggregate is fetched only once, even if the job is applied
+// to multiple projections. Cached aggregates are shared between callers and
+// must not be modified.
+func Fetch[A aggregate.Aggregate](job Job, repo aggregate.Repository, name string, factory func(uuid.UUID) A) ([]A, error) {
+	refs, errs, err := job.Aggregates(job, name)
+	if err != nil {
+		return nil, fmt.Errorf("extract aggregates: %w", err)
+	}
+
+	var out []A
+	if err := streams.Walk(job, func(ref aggregate.Ref) error {
+		a, err := fetchAggregate(job, repo, ref.ID, name, factory)
+		if err != nil {
+			return err
+		}
+		out = append(out, a)
+		return nil
+	}, refs, errs); err != nil {
+		return out, err
+	}
+
+	return out, nil
+}
+
+// FetchFirst fetches the first aggregate with the given name that can be
+// extracted from the events of the job (see Job.Aggregate). If the events of
+// the job do not belong to such an aggregate, an error that satisfies
+// errors.Is(err, ErrAggregateNotFound) is returned. Like Fetch, FetchFirst
+// caches the aggregate within the job.
+func FetchFirst[A aggregate.Aggregate](job Job, repo aggregate.Repository, name string, factory func(uuid.UUID) A) (A, error) {
+	id, err := job.Aggregate(job, name)
+	if err != nil {
+		var zero A
+		return zero, err
+	}
+	return fetchAggregate(job, repo, id, name, factory)
+}
+
+func fetchAggregate[A aggregate.Aggregate](job Job, repo aggregate.Repository, id uuid.UUID, name string, factory func(uuid.UUID) A) (A, error) {
+	ref := aggregate.Ref{Name: name, ID: id}
+
+	var cache *aggregateCache
+	if c, ok := job.(interface{ aggregateCache() *aggregateCache }); ok {
+		cache = c.aggregateCache()
+	}
+
+	if cached, ok := cache.get(ref).(A); ok {
+		return cached, nil
+	}
+
+	a := factory(id)
+	if err := repo.Fetch(job, a); err != nil {
+		var zero A
+		return zero, fmt.Errorf("fetch %s: %w", ref, err)
+	}
+
+	if cached, ok := cache.add(ref, a).(A); ok {
+		return cached, nil
+	}
+
+	return a, nil
+}
+
+// aggregateCache caches the aggregates that are fetched by Fetch and FetchFirst
+// within a job.
+type aggregateCache struct {
+	mux        sync.Mutex
+	aggregates map[aggregate.Ref]aggregate.Aggregate
+}
+
+func (c *aggregateCache) get(ref aggregate.Ref) aggregate.Aggregate {
+	if c == nil {
+		return nil
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.aggregates[ref]
+}
+
+// add caches the given aggregate and returns it. If another aggregate has been
+// cached for the same reference in the meantime, that aggregate is returned.
+func (c *aggregateCache) add(ref aggregate.Ref, a aggregate.Aggregate) aggregate.Aggregate {
+	if c == nil {
+		return a
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if cached, ok := c.aggregates[ref]; ok {
+		return cached
+	}
+	c.aggregates[ref] = a
+	return a
+}
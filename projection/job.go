@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/modernice/goes/aggregate"
 	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
 	"github.com/modernice/goes/event/query"
 	"github.com/modernice/goes/event/query/time"
 	"github.com/modernice/goes/helper/streams"
@@ -75,9 +76,28 @@ type Job interface {
 	// errors.Is(err, ErrAggregateNotFound) is returned.
 	Aggregate(_ context.Context, aggregateName string) (uuid.UUID, error)
 
+	// Estimate returns the estimated number and size of the events that are
+	// returned by the query of the job, before any filters are applied. Use
+	// Estimate to decide between loading the events into memory and streaming
+	// them, before fetching them:
+	//
+	//	var job Job
+	//	est, err := job.Estimate(job)
+	//	// handle err
+	//	if est.Events > 10000 {
+	//		// stream the events
+	//	}
+	//
+	// If the event store of the job implements eventstore.Estimator, the
+	// estimation is done by the event store, typically using a count query.
+	// Otherwise, the events are queried and counted, and the result is cached
+	// for subsequent queries of the job.
+	Estimate(context.Context) (eventstore.QueryEstimate, error)
+
 	// Apply applies the Job to the projection. It applies the events that
 	// would be returned by EventsFor(). A job may be applied concurrently to
-	// multiple projections.
+	// multiple projections. If applying an event fails, an *ApplyError is
+	// returned (see OnError).
 	Apply(context.Context, Target[any], ...ApplyOption) error
 }
 
@@ -96,6 +116,13 @@ type job struct {
 	filter      []event.Query
 	reset       bool
 	cache       *queryCache
+	aggregates  *aggregateCache
+
+	checkpoints    CheckpointStore
+	checkpointName string
+	checkpointOnce sync.Once
+	checkpoint     Checkpoint
+	checkpointErr  error
 }
 
 // WithFilter returns a JobOption that adds queries as filters to the Job.
@@ -144,6 +171,9 @@ func NewJob(ctx context.Context, store event.Store, q event.Query, opts ...JobOp
 		Context: ctx,
 		query:   q,
 		cache:   newQueryCache(store),
+		aggregates: &aggregateCache{
+			aggregates: make(map[aggregate.Ref]aggregate.Aggregate),
+		},
 	}
 	for _, opt := range opts {
 		opt(&j)
@@ -162,6 +192,15 @@ func (j *job) Events(ctx context.Context, filter ...event.Query) (<-chan event.E
 }
 
 func (j *job) queryEvents(ctx context.Context, q event.Query, filter ...event.Query) (<-chan event.Event, <-chan error, error) {
+	cp, err := j.loadCheckpoint(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	if min := q.Times().Min(); !cp.Time.IsZero() && (min.IsZero() || min.Before(cp.Time)) {
+		q = query.Merge(q, query.New(query.Time(time.Min(cp.Time))))
+	}
+
 	str, errs, err := j.runQuery(ctx, q)
 	if err != nil {
 		return nil, nil, err
@@ -175,9 +214,25 @@ func (j *job) queryEvents(ctx context.Context, q event.Query, filter ...event.Qu
 		str = event.Filter(str, filter...)
 	}
 
+	if !cp.Time.IsZero() {
+		str = streams.Filter(str, cp.Allows)
+	}
+
 	return str, errs, nil
 }
 
+// loadCheckpoint returns the checkpoint of the job, or the zero Checkpoint if
+// the job has no checkpoint or was created using WithReset.
+func (j *job) loadCheckpoint(ctx context.Context) (Checkpoint, error) {
+	if j.checkpoints == nil || j.reset {
+		return Checkpoint{}, nil
+	}
+	j.checkpointOnce.Do(func() {
+		j.checkpoint, j.checkpointErr = j.checkpoints.Checkpoint(ctx, j.checkpointName)
+	})
+	return j.checkpoint, j.checkpointErr
+}
+
 func (j *job) applyBeforeEvent(ctx context.Context, events <-chan event.Event, errs <-chan error) (<-chan event.Event, <-chan error) {
 	outErrs := make(chan error)
 	fail := func(err error) {
@@ -324,7 +379,7 @@ func (j *job) Aggregate(ctx context.Context, name string) (uuid.UUID, error) {
 			return done
 		}
 		return nil
-	}, tuples, errs); !errors.Is(err, done) {
+	}, tuples, errs); err != nil && !errors.Is(err, done) {
 		return uuid.Nil, err
 	}
 
@@ -349,16 +404,19 @@ func (j *job) Apply(ctx context.Context, target Target[any], opts ...ApplyOption
 		}
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	events, errs, err := j.EventsFor(ctx, target)
 	if err != nil {
 		return fmt.Errorf("fetch events: %w", err)
 	}
 
-	done := make(chan struct{})
+	cfg := newApplyConfig(opts...)
+	done := make(chan error, 1)
 
 	go func() {
-		defer close(done)
-		ApplyStream(target, events, opts...)
+		done <- TryApplyStream(target, events, opts...)
 	}()
 
 	for {
@@ -366,16 +424,29 @@ func (j *job) Apply(ctx context.Context, target Target[any], opts ...ApplyOption
 		case <-ctx.Done():
 			return ctx.Err()
 		case err, ok := <-errs:
-			if ok {
+			if !ok {
+				errs = nil
+				break
+			}
+			if !cfg.skip(nil, err) {
 				return err
 			}
-			errs = nil
-		case <-done:
-			return nil
+		case err := <-done:
+			return err
 		}
 	}
 }
 
+// Estimate returns the estimated number and size of the events that are
+// returned by the query of the job.
+func (j *job) Estimate(ctx context.Context) (eventstore.QueryEstimate, error) {
+	return j.cache.estimate(ctx, j.query)
+}
+
+func (j *job) aggregateCache() *aggregateCache {
+	return j.aggregates
+}
+
 func (j *job) runQuery(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
 	return j.cache.run(ctx, q)
 }
@@ -427,6 +498,33 @@ func (c *queryCache) run(ctx context.Context, q event.Query) (<-chan event.Event
 	return c.intercept(ctx, str, hash), errs, nil
 }
 
+func (c *queryCache) estimate(ctx context.Context, q event.Query) (eventstore.QueryEstimate, error) {
+	if e, ok := c.store.(eventstore.Estimator); ok {
+		est, err := e.Estimate(ctx, q)
+		if err != nil {
+			return est, fmt.Errorf("estimate events: %w", err)
+		}
+		return est, nil
+	}
+
+	// Counting the events of a query through the cache avoids fetching the
+	// events again when the job is applied.
+	str, errs, err := c.run(ctx, q)
+	if err != nil {
+		return eventstore.QueryEstimate{}, err
+	}
+
+	est := eventstore.QueryEstimate{Exact: true}
+	if err := streams.Walk(ctx, func(event.Event) error {
+		est.Events++
+		return nil
+	}, str, errs); err != nil {
+		return eventstore.QueryEstimate{}, err
+	}
+
+	return est, nil
+}
+
 func (c *queryCache) cached(hash [32]byte, lock bool) ([]event.Event, bool) {
 	var events []event.Event
 
@@ -2,14 +2,17 @@ package projection_test
 
 import (
 	"context"
+	"errors"
 	"log"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
 	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
 	"github.com/modernice/goes/event"
 	"github.com/modernice/goes/event/eventstore"
 	"github.com/modernice/goes/event/query"
@@ -397,6 +400,59 @@ func TestJob_Events_cache(t *testing.T) {
 	}
 }
 
+func TestJob_Estimate(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newEventStore(t)
+	delayedStore := newDelayedEventStore(store, 100*time.Millisecond)
+
+	job := projection.NewJob(ctx, delayedStore, query.New(query.Name("foo", "bar")))
+
+	est, err := job.Estimate(job)
+	if err != nil {
+		t.Fatalf("Estimate failed with %q", err)
+	}
+
+	if est.Events != 2 || !est.Exact {
+		t.Fatalf("Estimate should return exactly %d events; got %v", 2, est)
+	}
+
+	start := time.Now()
+	str, errs, err := job.Events(job)
+	if err != nil {
+		t.Fatalf("Events failed with %q", err)
+	}
+
+	if _, err := streams.Drain(ctx, str, errs); err != nil {
+		t.Fatalf("drain events: %v", err)
+	}
+
+	if dur := time.Since(start); dur >= 100*time.Millisecond {
+		t.Fatalf("Events should use the events that were counted by Estimate; took %v", dur)
+	}
+}
+
+func TestJob_Estimate_estimator(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newEventStore(t)
+	estimator := &estimatingEventStore{Store: store, estimate: eventstore.QueryEstimate{Events: 2, Size: 128}}
+
+	q := query.New(query.Name("foo", "bar"))
+	job := projection.NewJob(ctx, estimator, q)
+
+	est, err := job.Estimate(job)
+	if err != nil {
+		t.Fatalf("Estimate failed with %q", err)
+	}
+
+	if est != estimator.estimate {
+		t.Fatalf("Estimate should return the estimate of the event store. want=%v got=%v", estimator.estimate, est)
+	}
+
+	if !reflect.DeepEqual(estimator.queried, q) {
+		t.Fatalf("Estimate should estimate the query of the job")
+	}
+}
+
 func TestWithFilter(t *testing.T) {
 	ctx := context.Background()
 	now := time.Now()
@@ -506,3 +562,81 @@ func (s *delayedEventStore) Query(ctx context.Context, q event.Query) (<-chan ev
 
 	return s.Store.Query(ctx, q)
 }
+
+func TestFetch(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.New()
+	repo := &countingRepository{Repository: repository.New(store)}
+
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	for _, id := range ids {
+		a := aggregate.New("foo", id)
+		aggregate.Next(a, "foo", test.FooEventData{})
+		aggregate.Next(a, "foo", test.FooEventData{})
+		if err := repo.Save(ctx, a); err != nil {
+			t.Fatalf("save aggregate: %v", err)
+		}
+	}
+
+	job := projection.NewJob(ctx, store, query.New(query.AggregateName("foo")))
+	newFoo := func(id uuid.UUID) *aggregate.Base { return aggregate.New("foo", id) }
+
+	for i := 0; i < 2; i++ {
+		foos, err := projection.Fetch(job, repo, "foo", newFoo)
+		if err != nil {
+			t.Fatalf("Fetch failed with %q", err)
+		}
+
+		if len(foos) != len(ids) {
+			t.Fatalf("Fetch should return %d aggregates; got %d", len(ids), len(foos))
+		}
+
+		for _, foo := range foos {
+			if foo.AggregateVersion() != 2 {
+				t.Fatalf("fetched aggregate should have version %d; got %d", 2, foo.AggregateVersion())
+			}
+		}
+	}
+
+	first, err := projection.FetchFirst(job, repo, "foo", newFoo)
+	if err != nil {
+		t.Fatalf("FetchFirst failed with %q", err)
+	}
+	if first.AggregateVersion() != 2 {
+		t.Fatalf("fetched aggregate should have version %d; got %d", 2, first.AggregateVersion())
+	}
+
+	if repo.fetched != len(ids) {
+		t.Fatalf("aggregates should be fetched once per job; fetched %d times", repo.fetched)
+	}
+
+	if _, err := projection.FetchFirst(job, repo, "bar", newFoo); !errors.Is(err, projection.ErrAggregateNotFound) {
+		t.Fatalf("FetchFirst should fail with %q; got %q", projection.ErrAggregateNotFound, err)
+	}
+}
+
+type countingRepository struct {
+	*repository.Repository
+
+	mux     sync.Mutex
+	fetched int
+}
+
+func (r *countingRepository) Fetch(ctx context.Context, a aggregate.Aggregate) error {
+	r.mux.Lock()
+	r.fetched++
+	r.mux.Unlock()
+	return r.Repository.Fetch(ctx, a)
+}
+
+type estimatingEventStore struct {
+	event.Store
+
+	estimate eventstore.QueryEstimate
+	queried  event.Query
+}
+
+func (s *estimatingEventStore) Estimate(_ context.Context, q event.Query) (eventstore.QueryEstimate, error) {
+	s.queried = q
+	return s.estimate, nil
+}
@@ -0,0 +1,304 @@
+// Package join correlates events of two event streams within a time window.
+//
+// Some read models need to know when two related events have both occurred,
+// for example a "payment.initiated" and a "payment.confirmed" event of the
+// same payment, or when one of them is missing for too long. A Join correlates
+// such events by a key, and calls the provided callbacks with the joined pairs
+// or with timeout notifications:
+//
+//	j := join.New("payment.initiated", "payment.confirmed", 10*time.Minute,
+//		join.OnPair(func(p join.Pair) {
+//			// both events occurred within 10 minutes
+//		}),
+//		join.OnTimeout(func(t join.Timeout) {
+//			// the other event did not occur within 10 minutes
+//		}),
+//	)
+//
+//	s := schedule.Continuously(bus, store, j.Events())
+//	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+//		return job.Apply(job, j)
+//	})
+//
+// Timeouts are determined by the times of the applied events: when an event is
+// applied, every pending event that is older than the window relative to the
+// applied event times out. This makes joins deterministic when events are
+// replayed. To time out events when no further events are applied, call
+// Expire with the current time, or use Watch to do so periodically. Watch
+// advances the time of the Join from the times of the applied events, so that
+// it does not time out the events of a rebuild.
+package join
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/pick"
+)
+
+// Pair is a pair of joined events.
+type Pair struct {
+	// Key is the key by which the events were joined.
+	Key string
+
+	// Left is the event of the left event name.
+	Left event.Event
+
+	// Right is the event of the right event name.
+	Right event.Event
+}
+
+// Timeout is passed to the OnTimeout callbacks of a Join when no matching
+// event was applied within the window of an event.
+type Timeout struct {
+	// Key is the key of the event.
+	Key string
+
+	// Event is the event that timed out.
+	Event event.Event
+
+	// Deadline is the time until which a matching event was expected.
+	Deadline time.Time
+}
+
+// Join correlates the events of two event names within a time window. A *Join
+// is a projection.EventApplier and is safe for concurrent use.
+type Join struct {
+	left, right string
+	window      time.Duration
+	key         func(event.Event) (string, bool)
+	onPair      []func(Pair)
+	onTimeout   []func(Timeout)
+
+	mux       sync.Mutex
+	pending   map[side]map[string]event.Event
+	watermark time.Time
+
+	// lastEvent is the latest time of the applied events, and appliedAt is
+	// the wall-clock time at which the last event was applied.
+	lastEvent time.Time
+	appliedAt time.Time
+}
+
+// Option is an option for a Join.
+type Option func(*Join)
+
+type side int
+
+const (
+	leftSide side = iota
+	rightSide
+)
+
+// Key returns an Option that extracts the join key of an event using the
+// provided function. Events for which fn returns false are ignored. By default,
+// events are joined by their aggregate ids.
+func Key(fn func(event.Event) (string, bool)) Option {
+	return func(j *Join) {
+		j.key = fn
+	}
+}
+
+// OnPair returns an Option that calls fn for every pair of joined events.
+func OnPair(fn func(Pair)) Option {
+	return func(j *Join) {
+		j.onPair = append(j.onPair, fn)
+	}
+}
+
+// OnTimeout returns an Option that calls fn for every event that was not joined
+// within the window.
+func OnTimeout(fn func(Timeout)) Option {
+	return func(j *Join) {
+		j.onTimeout = append(j.onTimeout, fn)
+	}
+}
+
+// New returns a Join that joins the events with the left and right event names
+// if they have the same key and occurred within the given window. The order of
+// the events does not matter: a right event that is applied before its left
+// event is joined as well.
+func New(left, right string, window time.Duration, opts ...Option) *Join {
+	j := &Join{
+		left:   left,
+		right:  right,
+		window: window,
+		key:    aggregateKey,
+		pending: map[side]map[string]event.Event{
+			leftSide:  make(map[string]event.Event),
+			rightSide: make(map[string]event.Event),
+		},
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Events returns the event names of the Join.
+func (j *Join) Events() []string {
+	return []string{j.left, j.right}
+}
+
+// Pending returns the number of events that are waiting to be joined.
+func (j *Join) Pending() int {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	return len(j.pending[leftSide]) + len(j.pending[rightSide])
+}
+
+// ApplyEvent implements projection.EventApplier. If the event matches a pending
+// event of the other event name, the OnPair callbacks are called. Otherwise, the
+// event is kept until it is joined or times out. A pending event is replaced
+// by a newer event with the same name and key, in which case the older event
+// times out.
+func (j *Join) ApplyEvent(evt event.Event) {
+	var s side
+	switch evt.Name() {
+	case j.left:
+		s = leftSide
+	case j.right:
+		s = rightSide
+	default:
+		return
+	}
+
+	key, ok := j.key(evt)
+	if !ok {
+		return
+	}
+
+	j.mux.Lock()
+	var pairs []Pair
+	timeouts := j.expire(evt.Time())
+
+	if evt.Time().After(j.lastEvent) {
+		j.lastEvent = evt.Time()
+	}
+	j.appliedAt = time.Now()
+
+	other := j.pending[1-s]
+	if match, ok := other[key]; ok && within(match.Time(), evt.Time(), j.window) {
+		delete(other, key)
+		pair := Pair{Key: key, Left: match, Right: evt}
+		if s == leftSide {
+			pair.Left, pair.Right = evt, match
+		}
+		pairs = append(pairs, pair)
+	} else {
+		if replaced, ok := j.pending[s][key]; ok {
+			timeouts = append(timeouts, j.timeout(key, replaced))
+		}
+		j.pending[s][key] = evt
+	}
+	j.mux.Unlock()
+
+	j.notify(pairs, timeouts)
+}
+
+// Expire times out every pending event that is older than the window relative
+// to the given time. Use Expire to time out events when no further events are
+// applied to the Join.
+func (j *Join) Expire(now time.Time) {
+	j.mux.Lock()
+	timeouts := j.expire(now)
+	j.mux.Unlock()
+	j.notify(nil, timeouts)
+}
+
+// Watch times out pending events every interval until ctx is canceled, when no
+// further events are applied to the Join. Watch returns immediately.
+//
+// Watch does not use the current time. Instead, it expires events relative to
+// the latest time of the applied events, plus the time that has passed since
+// the last event was applied. While the events of a rebuild are applied, the
+// time of the Join therefore follows the times of the replayed events, and
+// pending events are not timed out just because they are old. Once the Join
+// has caught up and no further events are applied, the time of the Join
+// advances with the wall clock. Watch does nothing before the first event is
+// applied.
+func (j *Join) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				j.mux.Lock()
+				timeouts := j.watch(now)
+				j.mux.Unlock()
+				j.notify(nil, timeouts)
+			}
+		}
+	}()
+}
+
+// watch expires the pending events relative to the time of the last applied
+// event, advanced by the wall-clock time that has passed since it was applied.
+func (j *Join) watch(now time.Time) []Timeout {
+	if j.appliedAt.IsZero() {
+		return nil
+	}
+	return j.expire(j.lastEvent.Add(now.Sub(j.appliedAt)))
+}
+
+func (j *Join) expire(now time.Time) []Timeout {
+	if now.After(j.watermark) {
+		j.watermark = now
+	}
+
+	var timeouts []Timeout
+	for _, s := range []side{leftSide, rightSide} {
+		for key, evt := range j.pending[s] {
+			if j.watermark.Sub(evt.Time()) > j.window {
+				delete(j.pending[s], key)
+				timeouts = append(timeouts, j.timeout(key, evt))
+			}
+		}
+	}
+
+	sort.Slice(timeouts, func(a, b int) bool {
+		return timeouts[a].Deadline.Before(timeouts[b].Deadline)
+	})
+
+	return timeouts
+}
+
+func (j *Join) timeout(key string, evt event.Event) Timeout {
+	return Timeout{Key: key, Event: evt, Deadline: evt.Time().Add(j.window)}
+}
+
+func (j *Join) notify(pairs []Pair, timeouts []Timeout) {
+	for _, t := range timeouts {
+		for _, fn := range j.onTimeout {
+			fn(t)
+		}
+	}
+	for _, p := range pairs {
+		for _, fn := range j.onPair {
+			fn(p)
+		}
+	}
+}
+
+func within(a, b time.Time, window time.Duration) bool {
+	d := a.Sub(b)
+	if d < 0 {
+		d = -d
+	}
+	return d <= window
+}
+
+func aggregateKey(evt event.Event) (string, bool) {
+	id := pick.AggregateID(evt)
+	if id == uuid.Nil {
+		return "", false
+	}
+	return id.String(), true
+}
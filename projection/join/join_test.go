@@ -0,0 +1,172 @@
+package join_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/projection/join"
+)
+
+func TestJoin(t *testing.T) {
+	var pairs []join.Pair
+	var timeouts []join.Timeout
+
+	j := join.New("initiated", "confirmed", time.Minute,
+		join.OnPair(func(p join.Pair) { pairs = append(pairs, p) }),
+		join.OnTimeout(func(t join.Timeout) { timeouts = append(timeouts, t) }),
+	)
+
+	now := time.Now()
+	a, b := uuid.New(), uuid.New()
+
+	initiatedA := newEvent("initiated", a, now)
+	initiatedB := newEvent("initiated", b, now)
+	confirmedA := newEvent("confirmed", a, now.Add(30*time.Second))
+	unrelated := newEvent("foo", a, now.Add(40*time.Second))
+	confirmedB := newEvent("confirmed", b, now.Add(2*time.Minute))
+
+	for _, evt := range []event.Event{initiatedA, initiatedB, confirmedA, unrelated, confirmedB} {
+		j.ApplyEvent(evt)
+	}
+
+	if len(pairs) != 1 {
+		t.Fatalf("Join should emit %d pair; got %d", 1, len(pairs))
+	}
+
+	if pairs[0].Key != a.String() || pairs[0].Left != initiatedA || pairs[0].Right != confirmedA {
+		t.Fatalf("unexpected pair: %+v", pairs[0])
+	}
+
+	if len(timeouts) != 1 {
+		t.Fatalf("Join should emit %d timeout; got %d", 1, len(timeouts))
+	}
+
+	if timeouts[0].Event != initiatedB || !timeouts[0].Deadline.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected timeout: %+v", timeouts[0])
+	}
+
+	if j.Pending() != 1 {
+		t.Fatalf("the late confirmation should be pending; %d events are pending", j.Pending())
+	}
+}
+
+func TestJoin_reversedOrder(t *testing.T) {
+	var pairs []join.Pair
+	j := join.New("initiated", "confirmed", time.Minute, join.OnPair(func(p join.Pair) { pairs = append(pairs, p) }))
+
+	now := time.Now()
+	id := uuid.New()
+	initiated := newEvent("initiated", id, now)
+	confirmed := newEvent("confirmed", id, now.Add(time.Second))
+
+	j.ApplyEvent(confirmed)
+	j.ApplyEvent(initiated)
+
+	if len(pairs) != 1 || pairs[0].Left != initiated || pairs[0].Right != confirmed {
+		t.Fatalf("Join should pair events regardless of their order; got %+v", pairs)
+	}
+}
+
+func TestJoin_Expire(t *testing.T) {
+	var timeouts []join.Timeout
+	j := join.New("initiated", "confirmed", time.Minute, join.OnTimeout(func(t join.Timeout) { timeouts = append(timeouts, t) }))
+
+	now := time.Now()
+	j.ApplyEvent(newEvent("initiated", uuid.New(), now))
+
+	j.Expire(now.Add(30 * time.Second))
+	if len(timeouts) != 0 {
+		t.Fatalf("event should not time out within the window")
+	}
+
+	j.Expire(now.Add(2 * time.Minute))
+	if len(timeouts) != 1 {
+		t.Fatalf("event should time out after the window")
+	}
+}
+
+func TestKey(t *testing.T) {
+	var pairs []join.Pair
+	j := join.New("initiated", "confirmed", time.Minute,
+		join.Key(func(evt event.Event) (string, bool) {
+			return evt.Data().(string), true
+		}),
+		join.OnPair(func(p join.Pair) { pairs = append(pairs, p) }),
+	)
+
+	now := time.Now()
+	j.ApplyEvent(event.New[any]("initiated", "foo", event.Time(now)))
+	j.ApplyEvent(event.New[any]("confirmed", "foo", event.Time(now)))
+
+	if len(pairs) != 1 || pairs[0].Key != "foo" {
+		t.Fatalf("Join should pair events by the provided key; got %+v", pairs)
+	}
+}
+
+func TestJoin_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	timedOut := make(chan join.Timeout, 1)
+	j := join.New("initiated", "confirmed", 50*time.Millisecond, join.OnTimeout(func(t join.Timeout) { timedOut <- t }))
+
+	j.ApplyEvent(newEvent("initiated", uuid.New(), time.Now()))
+	j.Watch(ctx, 10*time.Millisecond)
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("timed out. Watch should expire pending events")
+	case <-timedOut:
+	}
+}
+
+func TestJoin_Watch_rebuild(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mux sync.Mutex
+	var pairs []join.Pair
+	var timeouts []join.Timeout
+	j := join.New("initiated", "confirmed", time.Minute,
+		join.OnPair(func(p join.Pair) {
+			mux.Lock()
+			defer mux.Unlock()
+			pairs = append(pairs, p)
+		}),
+		join.OnTimeout(func(t join.Timeout) {
+			mux.Lock()
+			defer mux.Unlock()
+			timeouts = append(timeouts, t)
+		}),
+	)
+
+	j.Watch(ctx, 5*time.Millisecond)
+
+	// Events of a rebuild are much older than the window.
+	id := uuid.New()
+	past := time.Now().Add(-time.Hour)
+	j.ApplyEvent(newEvent("initiated", id, past))
+
+	time.Sleep(30 * time.Millisecond)
+
+	j.ApplyEvent(newEvent("confirmed", id, past.Add(30*time.Second)))
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	if len(timeouts) != 0 {
+		t.Fatalf("Watch should not time out replayed events; got %+v", timeouts)
+	}
+
+	if len(pairs) != 1 {
+		t.Fatalf("replayed events should be joined; got %d pairs", len(pairs))
+	}
+}
+
+func newEvent(name string, id uuid.UUID, t time.Time) event.Event {
+	return event.New[any](name, struct{}{}, event.Time(t), event.Aggregate(id, "payment", 1))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
 	"sync"
 
@@ -67,6 +68,9 @@ type Provider interface {
 	// Provide provides the lookup value for the given key.
 	Provide(key string, value any)
 
+	// ProvideMany provides the lookup values for multiple keys at once.
+	ProvideMany(values map[string]any)
+
 	// Remove removes the lookup values for the given keys.
 	Remove(keys ...string)
 }
@@ -183,24 +187,16 @@ func (l *Lookup) Map() map[string]map[uuid.UUID]map[any]any {
 // Provider returns the lookup provider for the given aggregate. The returned Provider
 // is thread-safe.
 func (l *Lookup) Provider(aggregateName string, aggregateID uuid.UUID) Provider {
-	l.mux.RLock()
-	if prov, ok := l.providers[aggregateName]; ok {
-		l.mux.RUnlock()
-		return &provider{
-			mux:    &l.mux,
-			stores: make(map[uuid.UUID]*store),
-			active: prov.store(aggregateID),
-		}
-	}
-	l.mux.RUnlock()
-
 	l.mux.Lock()
 	defer l.mux.Unlock()
 
+	prov := l.provider(aggregateName)
+
 	return &provider{
 		mux:    &l.mux,
-		stores: make(map[uuid.UUID]*store),
-		active: l.provider(aggregateName).store(aggregateID),
+		stores: prov.stores,
+		ids:    prov.ids,
+		active: prov.store(aggregateID),
 	}
 }
 
@@ -221,10 +217,37 @@ func (l *Lookup) Lookup(ctx context.Context, aggregateName, key string, aggregat
 	return s.get(key)
 }
 
+// Values returns all lookup values of the given aggregate, keyed by their
+// lookup keys, or false if the aggregate has no lookup values. The values of
+// a single event (see Provider.ProvideMany) are always returned together.
+func (l *Lookup) Values(ctx context.Context, aggregateName string, aggregateID uuid.UUID) (map[string]any, bool) {
+	select {
+	case <-ctx.Done():
+		return nil, false
+	case <-l.Ready():
+	}
+
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+
+	prov, ok := l.providers[aggregateName]
+	if !ok {
+		return nil, false
+	}
+
+	s, ok := prov.stores[aggregateID]
+	if !ok || len(s.values) == 0 {
+		return nil, false
+	}
+
+	return maps.Clone(s.values), true
+}
+
 // Reverse returns the aggregate id that has the given value as the lookup value
 // for the given lookup key. If value is not comparable or if the value does not
 // exists for the given aggregate, uuid.Nil and false are returned. Otherwise
-// the aggregate id and true are returned.
+// the aggregate id and true are returned. Each lookup key has its own reverse
+// index, so equal values of different keys do not collide.
 func (l *Lookup) Reverse(ctx context.Context, aggregateName, key string, value any) (uuid.UUID, bool) {
 	if !isKeyable(value) {
 		return uuid.Nil, false
@@ -239,7 +262,12 @@ func (l *Lookup) Reverse(ctx context.Context, aggregateName, key string, value a
 	l.mux.RLock()
 	defer l.mux.RUnlock()
 
-	return l.provider(aggregateName).id(value)
+	prov, ok := l.providers[aggregateName]
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	return prov.id(key, value)
 }
 
 // Run runs the projection of the lookup table until ctx is canceled. Any
@@ -288,7 +316,7 @@ func (l *Lookup) provider(aggregateName string) *provider {
 	}
 	prov := &provider{
 		stores: make(map[uuid.UUID]*store),
-		ids:    make(map[any]uuid.UUID),
+		ids:    make(map[string]map[any]uuid.UUID),
 	}
 	l.providers[aggregateName] = prov
 	return prov
@@ -297,7 +325,7 @@ func (l *Lookup) provider(aggregateName string) *provider {
 type provider struct {
 	mux    *sync.RWMutex // only for providers returned by (*Lookup).Provider()
 	stores map[uuid.UUID]*store
-	ids    map[any]uuid.UUID
+	ids    map[string]map[any]uuid.UUID // reverse index per lookup key
 	active *store
 }
 
@@ -308,16 +336,23 @@ type provider struct {
 // reverse lookups. If the Provider is created using the *Lookup.Provider
 // method, it will be thread-safe.
 func (p *provider) Provide(key string, val any) {
-	p.Remove(key)
-
 	if p.mux != nil {
 		p.mux.Lock()
 		defer p.mux.Unlock()
 	}
-	p.active.provide(key, val)
+	p.provide(key, val)
+}
 
-	if p.active != nil && isKeyable(val) {
-		p.ids[val] = p.active.aggregateID
+// ProvideMany provides multiple lookup values at once. If the Provider is
+// created using the *Lookup.Provider method, concurrent lookups observe either
+// none or all of the provided values.
+func (p *provider) ProvideMany(values map[string]any) {
+	if p.mux != nil {
+		p.mux.Lock()
+		defer p.mux.Unlock()
+	}
+	for key, val := range values {
+		p.provide(key, val)
 	}
 }
 
@@ -327,25 +362,41 @@ func (p *provider) Remove(keys ...string) {
 		p.mux.Lock()
 		defer p.mux.Unlock()
 	}
-
 	for _, key := range keys {
-		val, ok := p.active.remove(key)
-		if !ok || p.active == nil {
-			continue
-		}
+		p.remove(key)
+	}
+}
 
-		if isKeyable(val) {
-			delete(p.ids, val)
-		}
+func (p *provider) provide(key string, val any) {
+	p.remove(key)
+	p.active.provide(key, val)
+
+	if !isKeyable(val) {
+		return
 	}
+
+	ids, ok := p.ids[key]
+	if !ok {
+		ids = make(map[any]uuid.UUID)
+		p.ids[key] = ids
+	}
+	ids[val] = p.active.aggregateID
 }
 
-func (p *provider) id(val any) (uuid.UUID, bool) {
-	if p.mux != nil {
-		p.mux.Lock()
-		defer p.mux.Unlock()
+func (p *provider) remove(key string) {
+	val, ok := p.active.remove(key)
+	if !ok || !isKeyable(val) {
+		return
+	}
+
+	// Another aggregate may have taken over the value in the meantime.
+	if ids := p.ids[key]; ids[val] == p.active.aggregateID {
+		delete(ids, val)
 	}
-	id, ok := p.ids[val]
+}
+
+func (p *provider) id(key string, val any) (uuid.UUID, bool) {
+	id, ok := p.ids[key][val]
 	return id, ok
 }
 
@@ -157,6 +157,62 @@ func TestLookup_removed(t *testing.T) {
 	}
 }
 
+func TestLookup_multipleKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.WithBus(eventstore.New(), bus)
+
+	aggregateID := uuid.New()
+	evt := event.New("user", UserEvent{Email: "bob", Username: "bob", ExternalID: 42}, event.Aggregate(aggregateID, "user", 1)).Any()
+
+	if err := store.Insert(ctx, evt); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	l := lookup.New(store, bus, []string{"user"})
+	errs, err := l.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed with %q", err)
+	}
+	go func() {
+		for range errs {
+		}
+	}()
+
+	values, ok := l.Values(ctx, "user", aggregateID)
+	if !ok {
+		t.Fatalf("Values should return the lookup values of the aggregate")
+	}
+	if len(values) != 3 || values["email"] != "bob" || values["username"] != "bob" || values["externalId"] != 42 {
+		t.Fatalf("Values returned unexpected values: %v", values)
+	}
+
+	for key, val := range values {
+		if id, ok := l.Reverse(ctx, "user", key, val); !ok || id != aggregateID {
+			t.Fatalf("Reverse(%q, %v) should return %q; got %q", key, val, aggregateID, id)
+		}
+	}
+
+	otherID := uuid.New()
+	l.Provider("user", otherID).ProvideMany(map[string]any{"email": "alice", "username": "bob"})
+
+	if id, ok := l.Reverse(ctx, "user", "username", "bob"); !ok || id != otherID {
+		t.Fatalf("Reverse(%q, %q) should return %q; got %q", "username", "bob", otherID, id)
+	}
+
+	if id, ok := l.Reverse(ctx, "user", "email", "bob"); !ok || id != aggregateID {
+		t.Fatalf("Reverse(%q, %q) should return %q; got %q", "email", "bob", aggregateID, id)
+	}
+
+	l.Provider("user", aggregateID).Remove("username")
+
+	if id, ok := l.Reverse(ctx, "user", "username", "bob"); !ok || id != otherID {
+		t.Fatalf("removing a value should not remove the reverse lookup of another aggregate; got %q", id)
+	}
+}
+
 // LookupEvent is a type used in testing the lookup package. It provides a Foo
 // field and implements the ProvideLookup method of the lookup.Provider
 // interface.
@@ -182,3 +238,18 @@ type LookupRemoveEvent struct{}
 func (LookupRemoveEvent) ProvideLookup(p lookup.Provider) {
 	p.Remove("foo")
 }
+
+// UserEvent provides multiple lookup values at once.
+type UserEvent struct {
+	Email      string
+	Username   string
+	ExternalID int
+}
+
+func (e UserEvent) ProvideLookup(p lookup.Provider) {
+	p.ProvideMany(map[string]any{
+		"email":      e.Email,
+		"username":   e.Username,
+		"externalId": e.ExternalID,
+	})
+}
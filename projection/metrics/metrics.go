@@ -0,0 +1,385 @@
+// Package metrics provides a projection that maintains basic business metrics
+// of aggregates.
+//
+// A Metrics projection counts the aggregates and events of every aggregate
+// name, measures the rate at which events are raised, and distributes the
+// aggregates by age into active and dormant aggregates. This gives product and
+// ops teams basic domain telemetry without writing custom projections:
+//
+//	m := metrics.New(store, bus, []string{"order.placed", "order.shipped"})
+//	errs, err := m.Run(ctx)
+//
+//	stats, ok := m.Stats(ctx, "order")
+//	log.Printf("%d orders, %d active, %.2f events/s", stats.Aggregates, stats.Active, stats.Rate)
+//
+// Only aggregate events are counted. Events of an aggregate that have already
+// been applied, identified by their aggregate version, are skipped, so that
+// the metrics are not skewed by jobs that apply the same events again.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+const (
+	// DefaultRateWindow is the default window over which event rates are
+	// measured.
+	DefaultRateWindow = time.Hour
+
+	// DefaultDormantAfter is the default duration without events after which
+	// an aggregate is considered dormant.
+	DefaultDormantAfter = 30 * 24 * time.Hour
+)
+
+// DefaultAgeBuckets are the default upper bounds of the age distribution of
+// aggregates.
+var DefaultAgeBuckets = []time.Duration{
+	time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	365 * 24 * time.Hour,
+}
+
+// rateResolution is the size of the buckets in which events are counted to
+// measure event rates.
+const rateResolution = time.Minute
+
+// Metrics is a projection that maintains business metrics per aggregate name.
+// A *Metrics is thread-safe.
+type Metrics struct {
+	scheduleOpts []schedule.ContinuousOption
+	schedule     *schedule.Continuous
+	clock        event.Clock
+	rateWindow   time.Duration
+	dormantAfter time.Duration
+	ageBuckets   []time.Duration
+
+	mux   sync.RWMutex
+	names map[string]*nameMetrics
+
+	once  sync.Once
+	ready chan struct{}
+}
+
+// Option is an option for a *Metrics.
+type Option func(*Metrics)
+
+// Stats are the metrics of an aggregate name.
+type Stats struct {
+	// AggregateName is the name of the aggregates.
+	AggregateName string
+
+	// Aggregates is the number of aggregates that raised at least one event.
+	Aggregates int
+
+	// Events is the number of events that were raised by the aggregates.
+	Events int
+
+	// Active is the number of aggregates that raised an event within the
+	// DormantAfter duration.
+	Active int
+
+	// Dormant is the number of aggregates that did not raise an event within
+	// the DormantAfter duration.
+	Dormant int
+
+	// RecentEvents is the number of events that were raised within the rate
+	// window.
+	RecentEvents int
+
+	// Rate is the number of events per second, measured over the rate window.
+	Rate float64
+
+	// Ages is the distribution of the aggregates by age. The age of an
+	// aggregate is the time since its first event.
+	Ages []AgeBucket
+
+	// FirstEvent is the time of the oldest event.
+	FirstEvent time.Time
+
+	// LastEvent is the time of the most recent event.
+	LastEvent time.Time
+}
+
+// AgeBucket is a bucket of the age distribution of aggregates.
+type AgeBucket struct {
+	// Max is the exclusive upper bound of the ages in the bucket. The last
+	// bucket, which contains the aggregates that are older than all other
+	// buckets, has a Max of 0.
+	Max time.Duration
+
+	// Aggregates is the number of aggregates in the bucket.
+	Aggregates int
+}
+
+type nameMetrics struct {
+	events     int
+	aggregates map[uuid.UUID]*aggregateMetrics
+	buckets    map[int64]int
+	first      time.Time
+	last       time.Time
+}
+
+type aggregateMetrics struct {
+	version int
+	first   time.Time
+	last    time.Time
+}
+
+// ScheduleOptions returns an Option that configures the continuous schedule
+// that is created by the projection.
+func ScheduleOptions(opts ...schedule.ContinuousOption) Option {
+	return func(m *Metrics) {
+		m.scheduleOpts = append(m.scheduleOpts, opts...)
+	}
+}
+
+// Clock returns an Option that provides the current time to the projection.
+// Defaults to event.SystemClock.
+func Clock(c event.Clock) Option {
+	return func(m *Metrics) {
+		m.clock = c
+	}
+}
+
+// RateWindow returns an Option that configures the window over which event
+// rates are measured. Events are counted in buckets of one minute, so the
+// window should be a multiple of a minute. Defaults to DefaultRateWindow.
+func RateWindow(d time.Duration) Option {
+	return func(m *Metrics) {
+		m.rateWindow = d
+	}
+}
+
+// DormantAfter returns an Option that configures the duration without events
+// after which an aggregate is considered dormant. Defaults to
+// DefaultDormantAfter.
+func DormantAfter(d time.Duration) Option {
+	return func(m *Metrics) {
+		m.dormantAfter = d
+	}
+}
+
+// AgeBuckets returns an Option that configures the upper bounds of the age
+// distribution of aggregates. Defaults to DefaultAgeBuckets.
+func AgeBuckets(bounds ...time.Duration) Option {
+	return func(m *Metrics) {
+		m.ageBuckets = bounds
+	}
+}
+
+// New returns a new metrics projection for the given events. Use m.Run() to
+// start the projection. The projection becomes ready after the first
+// projection job has been applied.
+func New(store event.Store, bus event.Bus, events []string, opts ...Option) *Metrics {
+	m := &Metrics{
+		clock:        event.SystemClock,
+		rateWindow:   DefaultRateWindow,
+		dormantAfter: DefaultDormantAfter,
+		ageBuckets:   DefaultAgeBuckets,
+		names:        make(map[string]*nameMetrics),
+		ready:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	m.ageBuckets = append([]time.Duration(nil), m.ageBuckets...)
+	sort.Slice(m.ageBuckets, func(i, j int) bool { return m.ageBuckets[i] < m.ageBuckets[j] })
+
+	m.schedule = schedule.Continuously(bus, store, events, m.scheduleOpts...)
+
+	return m
+}
+
+// Ready returns a channel that is closed when the projection is ready. The
+// projection becomes ready after the first projection job has been applied.
+func (m *Metrics) Ready() <-chan struct{} {
+	return m.ready
+}
+
+// Schedule returns the projection schedule of the metrics.
+func (m *Metrics) Schedule() *schedule.Continuous {
+	return m.schedule
+}
+
+// Run runs the projection until ctx is canceled. Any asynchronous errors are
+// sent into the returned channel.
+func (m *Metrics) Run(ctx context.Context) (<-chan error, error) {
+	errs, err := m.schedule.Subscribe(ctx, m.ApplyJob)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to projection schedule: %w", err)
+	}
+
+	go m.schedule.Trigger(ctx)
+
+	return errs, nil
+}
+
+// ApplyJob applies the given projection job on the metrics.
+func (m *Metrics) ApplyJob(ctx projection.Job) error {
+	defer m.once.Do(func() { close(m.ready) })
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return ctx.Apply(ctx, m)
+}
+
+// ApplyEvent implements projection.EventApplier.
+func (m *Metrics) ApplyEvent(evt event.Event) {
+	id, name, version := evt.Aggregate()
+	if name == "" || id == uuid.Nil {
+		return
+	}
+
+	nm, ok := m.names[name]
+	if !ok {
+		nm = &nameMetrics{
+			aggregates: make(map[uuid.UUID]*aggregateMetrics),
+			buckets:    make(map[int64]int),
+		}
+		m.names[name] = nm
+	}
+
+	am, ok := nm.aggregates[id]
+	if !ok {
+		am = &aggregateMetrics{first: evt.Time()}
+		nm.aggregates[id] = am
+	}
+
+	if version <= am.version {
+		return
+	}
+	am.version = version
+
+	t := evt.Time()
+	if t.Before(am.first) {
+		am.first = t
+	}
+	if t.After(am.last) {
+		am.last = t
+	}
+
+	nm.events++
+	if nm.first.IsZero() || t.Before(nm.first) {
+		nm.first = t
+	}
+	if t.After(nm.last) {
+		nm.last = t
+	}
+
+	now := m.clock.Now()
+	if !t.Before(m.windowStart(now)) {
+		nm.buckets[bucketOf(t)]++
+	}
+	m.pruneBuckets(nm, now)
+}
+
+// Stats returns the metrics of the given aggregate name, or false if no events
+// of the aggregate name have been applied. Stats blocks until the projection is
+// ready or ctx is canceled.
+func (m *Metrics) Stats(ctx context.Context, aggregateName string) (Stats, bool) {
+	select {
+	case <-ctx.Done():
+		return Stats{}, false
+	case <-m.Ready():
+	}
+
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	nm, ok := m.names[aggregateName]
+	if !ok {
+		return Stats{}, false
+	}
+
+	return m.stats(aggregateName, nm, m.clock.Now()), true
+}
+
+// All returns the metrics of all aggregate names, sorted by name. All blocks
+// until the projection is ready or ctx is canceled.
+func (m *Metrics) All(ctx context.Context) []Stats {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-m.Ready():
+	}
+
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	now := m.clock.Now()
+	out := make([]Stats, 0, len(m.names))
+	for name, nm := range m.names {
+		out = append(out, m.stats(name, nm, now))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AggregateName < out[j].AggregateName })
+
+	return out
+}
+
+func (m *Metrics) stats(name string, nm *nameMetrics, now time.Time) Stats {
+	stats := Stats{
+		AggregateName: name,
+		Aggregates:    len(nm.aggregates),
+		Events:        nm.events,
+		Ages:          make([]AgeBucket, len(m.ageBuckets)+1),
+		FirstEvent:    nm.first,
+		LastEvent:     nm.last,
+	}
+
+	for i, bound := range m.ageBuckets {
+		stats.Ages[i].Max = bound
+	}
+
+	for _, am := range nm.aggregates {
+		if now.Sub(am.last) >= m.dormantAfter {
+			stats.Dormant++
+		} else {
+			stats.Active++
+		}
+
+		age := now.Sub(am.first)
+		i := sort.Search(len(m.ageBuckets), func(i int) bool { return age < m.ageBuckets[i] })
+		stats.Ages[i].Aggregates++
+	}
+
+	start := bucketOf(m.windowStart(now))
+	for bucket, count := range nm.buckets {
+		if bucket >= start {
+			stats.RecentEvents += count
+		}
+	}
+
+	if m.rateWindow > 0 {
+		stats.Rate = float64(stats.RecentEvents) / m.rateWindow.Seconds()
+	}
+
+	return stats
+}
+
+func (m *Metrics) windowStart(now time.Time) time.Time {
+	return now.Add(-m.rateWindow)
+}
+
+func (m *Metrics) pruneBuckets(nm *nameMetrics, now time.Time) {
+	start := bucketOf(m.windowStart(now))
+	for bucket := range nm.buckets {
+		if bucket < start {
+			delete(nm.buckets, bucket)
+		}
+	}
+}
+
+func bucketOf(t time.Time) int64 {
+	return t.Truncate(rateResolution).Unix()
+}
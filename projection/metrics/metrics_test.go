@@ -0,0 +1,101 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.WithBus(eventstore.New(), bus)
+
+	now := time.Now()
+	active, dormant, other := uuid.New(), uuid.New(), uuid.New()
+
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}, event.Aggregate(dormant, "foo", 1), event.Time(now.Add(-60*24*time.Hour))).Any(),
+		event.New("foo", test.FooEventData{}, event.Aggregate(active, "foo", 1), event.Time(now.Add(-2*time.Hour))).Any(),
+		event.New("foo", test.FooEventData{}, event.Aggregate(active, "foo", 2), event.Time(now.Add(-10*time.Minute))).Any(),
+		event.New("foo", test.FooEventData{}, event.Aggregate(active, "foo", 3), event.Time(now.Add(-5*time.Minute))).Any(),
+		event.New("bar", test.BarEventData{}, event.Aggregate(other, "bar", 1), event.Time(now.Add(-time.Minute))).Any(),
+		event.New("bar", test.BarEventData{}).Any(),
+	}
+
+	if err := store.Insert(ctx, events...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	m := metrics.New(store, bus, []string{"foo", "bar"}, metrics.Clock(event.ClockFunc(func() time.Time { return now })))
+
+	errs, err := m.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() failed with %q", err)
+	}
+	go func() {
+		for err := range errs {
+			t.Errorf("projection: %v", err)
+		}
+	}()
+
+	stats, ok := m.Stats(ctx, "foo")
+	if !ok {
+		t.Fatalf("Stats(%q) should return metrics", "foo")
+	}
+
+	if stats.Aggregates != 2 {
+		t.Errorf("Aggregates should be %d; got %d", 2, stats.Aggregates)
+	}
+
+	if stats.Events != 4 {
+		t.Errorf("Events should be %d; got %d", 4, stats.Events)
+	}
+
+	if stats.Active != 1 || stats.Dormant != 1 {
+		t.Errorf("there should be 1 active and 1 dormant aggregate; got %d active and %d dormant", stats.Active, stats.Dormant)
+	}
+
+	if stats.RecentEvents != 2 {
+		t.Errorf("RecentEvents should be %d; got %d", 2, stats.RecentEvents)
+	}
+
+	if want := 2 / time.Hour.Seconds(); stats.Rate != want {
+		t.Errorf("Rate should be %v; got %v", want, stats.Rate)
+	}
+
+	wantAges := []int{0, 1, 0, 0, 1, 0}
+	if len(stats.Ages) != len(wantAges) {
+		t.Fatalf("Ages should have %d buckets; got %d", len(wantAges), len(stats.Ages))
+	}
+	for i, want := range wantAges {
+		if stats.Ages[i].Aggregates != want {
+			t.Errorf("age bucket %d (max=%v) should have %d aggregates; got %d", i, stats.Ages[i].Max, want, stats.Ages[i].Aggregates)
+		}
+	}
+
+	// applying the same events again must not change the metrics
+	if err := m.ApplyJob(projection.NewJob(ctx, store, query.New(query.Name("foo", "bar")))); err != nil {
+		t.Fatalf("ApplyJob() failed with %q", err)
+	}
+
+	all := m.All(ctx)
+	if len(all) != 2 || all[0].AggregateName != "bar" || all[1].AggregateName != "foo" {
+		t.Fatalf("All() should return the metrics of %q and %q; got %v", "bar", "foo", all)
+	}
+
+	if all[1].Events != 4 {
+		t.Errorf("Events should still be %d after applying the events again; got %d", 4, all[1].Events)
+	}
+}
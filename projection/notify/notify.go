@@ -0,0 +1,165 @@
+// Package notify publishes change notifications of read models.
+//
+// API layers that cache read models, or push updates to clients, need to know
+// when a read model changes. Subscribing to the domain events that are applied
+// to the read model couples the API layer to the internals of the projection.
+// Instead, projections publish "read model X changed for key Y" notifications
+// after applying a job, which API layers can subscribe to:
+//
+//	notifier := notify.New(bus)
+//
+//	// projection side
+//	errs, err := schedule.Subscribe(ctx, func(job projection.Job) error {
+//		return notify.Apply(job, notifier, "order_summary", summaries, func(evt event.Event) []string {
+//			return []string{pick.AggregateID(evt).String()}
+//		})
+//	})
+//
+//	// API side
+//	changes, errs, err := notifier.Subscribe(ctx, "order_summary")
+//	for change := range changes {
+//		cache.Delete(change.Key)
+//	}
+package notify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/modernice/goes/codec"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/projection"
+)
+
+// Changed is the name of the event that notifies about changes of a read model.
+const Changed = "goes.projection.read_model_changed"
+
+// ChangedData is the event data of the Changed event.
+type ChangedData struct {
+	ReadModel string
+	Keys      []string
+}
+
+// Change is a change of a read model for a single key.
+type Change struct {
+	ReadModel string
+	Key       string
+	Time      time.Time
+}
+
+// Notifier publishes and subscribes to change notifications of read models
+// over an event bus.
+type Notifier struct {
+	bus event.Bus
+}
+
+// RegisterEvents registers the Changed event into an event registry.
+func RegisterEvents(r codec.Registerer) {
+	codec.Register[ChangedData](r, Changed)
+}
+
+// New returns a Notifier that communicates over the given event bus.
+func New(bus event.Bus) *Notifier {
+	return &Notifier{bus: bus}
+}
+
+// Notify publishes a single notification that the read model changed for the
+// given keys. Notify does nothing if no keys are provided.
+func (n *Notifier) Notify(ctx context.Context, readModel string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	evt := event.New(Changed, ChangedData{ReadModel: readModel, Keys: keys})
+	if err := n.bus.Publish(ctx, evt.Any()); err != nil {
+		return fmt.Errorf("publish %q event: %w", evt.Name(), err)
+	}
+
+	return nil
+}
+
+// Subscribe subscribes to the changes of the given read models. If no read
+// models are provided, Subscribe subscribes to the changes of all read models.
+// A notification for multiple keys is split into a Change for each key.
+func (n *Notifier) Subscribe(ctx context.Context, readModels ...string) (<-chan Change, <-chan error, error) {
+	events, errs, err := n.bus.Subscribe(ctx, Changed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("subscribe to %q event: %w", Changed, err)
+	}
+
+	out := make(chan Change)
+	outErrs := make(chan error)
+
+	go func() {
+		defer close(out)
+		defer close(outErrs)
+
+		for events != nil || errs != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case outErrs <- err:
+				}
+			case evt, ok := <-events:
+				if !ok {
+					events = nil
+					break
+				}
+
+				data, ok := evt.Data().(ChangedData)
+				if !ok || (len(readModels) > 0 && !slices.Contains(readModels, data.ReadModel)) {
+					break
+				}
+
+				for _, key := range data.Keys {
+					select {
+					case <-ctx.Done():
+						return
+					case out <- Change{ReadModel: data.ReadModel, Key: key, Time: evt.Time()}:
+					}
+				}
+			}
+		}
+	}()
+
+	return out, outErrs, nil
+}
+
+// Apply applies the job to the target and notifies about the changes of the
+// read model afterwards. The keys function returns the keys of the read model
+// that are affected by an event; it is called for every event that is
+// successfully applied to the target. Notifications are only published if the
+// job was applied successfully, as a single notification for all distinct
+// keys.
+func Apply(job projection.Job, n *Notifier, readModel string, target projection.Target[any], keys func(event.Event) []string, opts ...projection.ApplyOption) error {
+	var changed []string
+	seen := make(map[string]bool)
+
+	opts = append(opts, projection.AfterApply(func(s projection.Step) {
+		if s.Err != nil {
+			return
+		}
+		for _, key := range keys(s.Event) {
+			if !seen[key] {
+				seen[key] = true
+				changed = append(changed, key)
+			}
+		}
+	}))
+
+	if err := job.Apply(job, target, opts...); err != nil {
+		return err
+	}
+
+	return n.Notify(job, readModel, changed...)
+}
@@ -0,0 +1,112 @@
+package notify_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/internal/projectiontest"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/notify"
+)
+
+func TestNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := notify.New(eventbus.New())
+
+	changes := subscribe(ctx, t, n, "foo")
+
+	if err := n.Notify(ctx, "bar", "a"); err != nil {
+		t.Fatalf("Notify failed with %q", err)
+	}
+
+	if err := n.Notify(ctx, "foo", "a", "b"); err != nil {
+		t.Fatalf("Notify failed with %q", err)
+	}
+
+	got := receive(t, changes, 2)
+	if got[0].ReadModel != "foo" || got[0].Key != "a" || got[1].Key != "b" {
+		t.Fatalf("should receive the changes of %q for keys %v; got %+v", "foo", []string{"a", "b"}, got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id := uuid.New()
+	store := eventstore.New(
+		event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 1)).Any(),
+		event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", 2)).Any(),
+	)
+
+	n := notify.New(eventbus.New())
+	changes := subscribe(ctx, t, n)
+
+	job := projection.NewJob(ctx, store, query.New(query.SortByAggregate()))
+	proj := projectiontest.NewMockProjection()
+
+	if err := notify.Apply(job, n, "foos", proj, func(evt event.Event) []string {
+		return []string{pick.AggregateID(evt).String()}
+	}); err != nil {
+		t.Fatalf("Apply failed with %q", err)
+	}
+
+	if len(proj.AppliedEvents) != 2 {
+		t.Fatalf("job should be applied to the projection")
+	}
+
+	got := receive(t, changes, 1)
+	if got[0].ReadModel != "foos" || got[0].Key != id.String() {
+		t.Fatalf("should receive a single change of %q for key %q; got %+v", "foos", id, got)
+	}
+
+	select {
+	case change := <-changes:
+		t.Fatalf("changes of the same key should be notified once; got another change %+v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func subscribe(ctx context.Context, t *testing.T, n *notify.Notifier, readModels ...string) <-chan notify.Change {
+	changes, errs, err := n.Subscribe(ctx, readModels...)
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	out := make(chan notify.Change, 16)
+	go func() {
+		for change := range changes {
+			out <- change
+		}
+	}()
+	go func() {
+		for err := range errs {
+			t.Errorf("subscription: %v", err)
+		}
+	}()
+
+	return out
+}
+
+func receive(t *testing.T, changes <-chan notify.Change, n int) []notify.Change {
+	var out []notify.Change
+	for len(out) < n {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("should receive %d changes; got %d", n, len(out))
+		case change := <-changes:
+			out = append(out, change)
+		}
+	}
+	return out
+}
@@ -1,12 +1,18 @@
 package projection_test
 
 import (
+	"context"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
+	"github.com/modernice/goes/aggregate"
+	"github.com/modernice/goes/aggregate/repository"
 	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
 	"github.com/modernice/goes/event/query"
 	"github.com/modernice/goes/event/test"
 	"github.com/modernice/goes/internal/projectiontest"
@@ -119,3 +125,356 @@ func TestApply_Guard(t *testing.T) {
 
 	proj.ExpectApplied(t, events[:2]...)
 }
+
+func TestAnnul(t *testing.T) {
+	a := aggregate.New("foo", uuid.New())
+	foo := aggregate.Next(a, "foo", test.FooEventData{}).Any()
+	bar := aggregate.Next(a, "bar", test.BarEventData{}).Any()
+	comp, _ := aggregate.Compensate(a, foo, "mistake")
+
+	earlier := event.New("baz", test.BazEventData{}).Any()
+	compEarlier := event.New(aggregate.CompensationEvent("baz"), aggregate.Compensation{EventID: earlier.ID()}).Any()
+
+	events := []event.Event{foo, bar, comp.Any(), compEarlier}
+
+	got := projection.Annul(events)
+	want := []event.Event{bar, compEarlier}
+
+	test.AssertEqualEvents(t, want, got)
+}
+
+func TestApply_AnnulCompensated(t *testing.T) {
+	proj := projectiontest.NewMockProjection()
+
+	a := aggregate.New("foo", uuid.New())
+	foo := aggregate.Next(a, "foo", test.FooEventData{}).Any()
+	bar := aggregate.Next(a, "bar", test.BarEventData{}).Any()
+	comp, _ := aggregate.Compensate(a, foo, "mistake")
+
+	projection.Apply(proj, []event.Event{foo, bar, comp.Any()}, projection.AnnulCompensated())
+
+	proj.ExpectApplied(t, bar)
+}
+
+func TestTryApply_fail(t *testing.T) {
+	proj := newFailingProjection("bar", 1)
+
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}).Any(),
+		event.New("bar", test.BarEventData{}).Any(),
+		event.New("baz", test.BazEventData{}).Any(),
+	}
+
+	err := projection.TryApply(proj, events)
+
+	var applyErr *projection.ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("TryApply() should fail with %T; got %v", applyErr, err)
+	}
+
+	if applyErr.Event.ID() != events[1].ID() {
+		t.Fatalf("ApplyError.Event should be the %q event; is the %q event", "bar", applyErr.Event.Name())
+	}
+
+	if !errors.Is(err, errMockApply) {
+		t.Fatalf("TryApply() should fail with %q; got %q", errMockApply, err)
+	}
+
+	test.AssertEqualEvents(t, events[:1], proj.applied)
+}
+
+func TestOnError_Skip(t *testing.T) {
+	proj := newFailingProjection("bar", -1)
+
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}).Any(),
+		event.New("bar", test.BarEventData{}).Any(),
+		event.New("baz", test.BazEventData{}).Any(),
+	}
+
+	var deadLetters []event.Event
+	err := projection.TryApply(proj, events,
+		projection.OnError(projection.Skip, func(err error) bool { return errors.Is(err, errMockApply) }),
+		projection.DeadLetter(func(evt event.Event, err error) {
+			deadLetters = append(deadLetters, evt)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("TryApply() failed with %q", err)
+	}
+
+	test.AssertEqualEvents(t, []event.Event{events[0], events[2]}, proj.applied)
+	test.AssertEqualEvents(t, events[1:2], deadLetters)
+}
+
+func TestOnError_Retry(t *testing.T) {
+	proj := newFailingProjection("bar", 2)
+
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}).Any(),
+		event.New("bar", test.BarEventData{}).Any(),
+	}
+
+	if err := projection.TryApply(proj, events, projection.OnError(projection.Retry, nil)); err != nil {
+		t.Fatalf("TryApply() failed with %q", err)
+	}
+
+	test.AssertEqualEvents(t, events, proj.applied)
+}
+
+func TestOnError_Retry_exhausted(t *testing.T) {
+	proj := newFailingProjection("bar", 5)
+
+	events := []event.Event{event.New("bar", test.BarEventData{}).Any()}
+
+	err := projection.TryApply(proj, events, projection.OnError(projection.Retry, nil), projection.MaxRetries(2))
+	if !errors.Is(err, errMockApply) {
+		t.Fatalf("TryApply() should fail with %q; got %q", errMockApply, err)
+	}
+
+	if proj.fails != 2 {
+		t.Fatalf("projection should fail %d more times; fails %d more times", 2, proj.fails)
+	}
+}
+
+func TestOnError_panic(t *testing.T) {
+	proj := projection.New()
+	proj.RegisterEventHandler("foo", func(event.Event) { panic("malformed event") })
+
+	events := []event.Event{event.New("foo", test.FooEventData{}).Any()}
+
+	var skipped int
+	projection.Apply(proj, events,
+		projection.OnError(projection.Skip, func(err error) bool { return errors.Is(err, projection.ErrPanic) }),
+		projection.DeadLetter(func(event.Event, error) { skipped++ }),
+	)
+
+	if skipped != 1 {
+		t.Fatalf("%d event should have been skipped; got %d", 1, skipped)
+	}
+}
+
+func TestBeforeApply_AfterApply(t *testing.T) {
+	proj := newFailingProjection("bar", -1)
+
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}).Any(),
+		event.New("bar", test.BarEventData{}).Any(),
+		event.New("baz", test.BazEventData{}).Any(),
+	}
+
+	var before, after []projection.Step
+	err := projection.TryApply(proj, events,
+		projection.OnError(projection.Skip, nil),
+		projection.BeforeApply(func(s projection.Step) { before = append(before, s) }),
+		projection.AfterApply(func(s projection.Step) { after = append(after, s) }),
+	)
+	if err != nil {
+		t.Fatalf("TryApply() failed with %q", err)
+	}
+
+	if len(before) != 3 || len(after) != 3 {
+		t.Fatalf("hooks should be called %d times; got %d (before) and %d (after)", 3, len(before), len(after))
+	}
+
+	for i, evt := range events {
+		if before[i].Event != evt || after[i].Event != evt || before[i].Index != i || after[i].Index != i {
+			t.Fatalf("step #%d should be for %q event", i, evt.Name())
+		}
+	}
+
+	if state := before[2].State.(failingProjection); len(state.applied) != 1 {
+		t.Fatalf("state before %q event should have %d applied events; got %d", "baz", 1, len(state.applied))
+	}
+
+	if state := after[2].State.(failingProjection); len(state.applied) != 2 {
+		t.Fatalf("state after %q event should have %d applied events; got %d", "baz", 2, len(state.applied))
+	}
+
+	if !errors.Is(after[1].Err, errMockApply) {
+		t.Fatalf("step of %q event should have error %q; got %q", "bar", errMockApply, after[1].Err)
+	}
+}
+
+func TestDebugState(t *testing.T) {
+	proj := newFailingProjection("", 0)
+
+	events := []event.Event{event.New("foo", test.FooEventData{}).Any()}
+
+	var states []any
+	projection.Apply(proj, events,
+		projection.DebugState(func(target projection.Target[any]) any {
+			return len(target.(*failingProjection).applied)
+		}),
+		projection.AfterApply(func(s projection.Step) { states = append(states, s.State) }),
+	)
+
+	if len(states) != 1 || states[0] != 1 {
+		t.Fatalf("AfterApply should receive the custom state snapshot; got %v", states)
+	}
+}
+
+func TestBeforeApply_noStateSnapshotter(t *testing.T) {
+	proj := projection.New()
+	proj.RegisterEventHandler("foo", func(event.Event) {})
+
+	events := []event.Event{event.New("foo", test.FooEventData{}).Any()}
+
+	var steps []projection.Step
+	projection.Apply(proj, events, projection.BeforeApply(func(s projection.Step) { steps = append(steps, s) }))
+
+	if len(steps) != 1 || steps[0].State != nil {
+		t.Fatalf("state of a projection that is not a StateSnapshotter should be nil; got %v", steps)
+	}
+}
+
+var errMockApply = errors.New("mock apply error")
+
+type failingProjection struct {
+	failOn  string
+	fails   int
+	applied []event.Event
+}
+
+// newFailingProjection returns a projection that fails to apply events with
+// the given name n times. If n is negative, the projection always fails.
+func newFailingProjection(failOn string, n int) *failingProjection {
+	return &failingProjection{failOn: failOn, fails: n}
+}
+
+func (proj *failingProjection) ApplyEvent(evt event.Event) {
+	proj.applied = append(proj.applied, evt)
+}
+
+func (proj *failingProjection) ProjectionState() any {
+	state := *proj
+	state.applied = append([]event.Event(nil), proj.applied...)
+	return state
+}
+
+func (proj *failingProjection) TryApplyEvent(evt event.Event) error {
+	if evt.Name() == proj.failOn && proj.fails != 0 {
+		proj.fails--
+		return errMockApply
+	}
+	proj.ApplyEvent(evt)
+	return nil
+}
+
+func TestToken(t *testing.T) {
+	now := time.Now()
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}, event.Time(now)).Any(),
+		event.New("bar", test.BarEventData{}, event.Time(now.Add(time.Second))).Any(),
+	}
+
+	token := projection.TokenOf(events...)
+
+	if token.EventID != events[1].ID() {
+		t.Fatalf("token should reference the latest event")
+	}
+
+	parsed, err := projection.ParseToken(token.String())
+	if err != nil {
+		t.Fatalf("ParseToken() failed with %q", err)
+	}
+
+	if !parsed.Time.Equal(token.Time) || parsed.EventID != token.EventID {
+		t.Fatalf("parsed token should be %v; is %v", token, parsed)
+	}
+
+	if _, err := projection.ParseToken("foo"); !errors.Is(err, projection.ErrInvalidToken) {
+		t.Fatalf("ParseToken() should fail with %q; got %v", projection.ErrInvalidToken, err)
+	}
+
+	if token.ReachedBy(now, []uuid.UUID{events[0].ID()}) {
+		t.Fatalf("token should not be reached by an earlier progress")
+	}
+
+	if !token.ReachedBy(events[1].Time(), []uuid.UUID{events[1].ID()}) {
+		t.Fatalf("token should be reached by the progress of its event")
+	}
+
+	if !token.ReachedBy(events[1].Time().Add(time.Nanosecond), nil) {
+		t.Fatalf("token should be reached by a later progress, even if its event was not applied")
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	proj := projection.NewProgressor()
+	evt := event.New("foo", test.FooEventData{}).Any()
+	token := projection.TokenOf(evt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := projection.WaitFor(ctx, proj, token, projection.WaitInterval(5*time.Millisecond)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitFor() should fail with %q; got %v", context.DeadlineExceeded, err)
+	}
+
+	// the projection is not interested in evt, but applies a later event
+	later := event.New("bar", test.BarEventData{}, event.Time(evt.Time().Add(time.Millisecond))).Any()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		proj.SetProgress(later.Time(), later.ID())
+	}()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := projection.WaitFor(ctx, proj, token, projection.WaitInterval(5*time.Millisecond)); err != nil {
+		t.Fatalf("WaitFor() failed with %q", err)
+	}
+}
+
+func TestSave(t *testing.T) {
+	store := eventstore.New()
+	repo := repository.New(store)
+
+	a := aggregate.New("foo", uuid.New())
+	aggregate.Next(a, "foo", test.FooEventData{})
+	evt := aggregate.Next(a, "foo", test.FooEventData{})
+
+	token, err := projection.Save(context.Background(), repo, a)
+	if err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	if token.EventID != evt.ID() {
+		t.Fatalf("token should reference the latest saved event")
+	}
+
+	if _, err := store.Find(context.Background(), evt.ID()); err != nil {
+		t.Fatalf("event should have been saved; Find() failed with %q", err)
+	}
+}
+
+func TestAsOf(t *testing.T) {
+	now := time.Now()
+	events := []event.Event{
+		event.New("foo", test.FooEventData{}, event.Time(now.Add(-3*time.Hour))).Any(),
+		event.New("bar", test.BarEventData{}, event.Time(now.Add(-2*time.Hour))).Any(),
+		event.New("foo", test.FooEventData{}, event.Time(now.Add(-time.Hour))).Any(),
+	}
+	store := eventstore.New(slices.Clone(events)...)
+
+	proj := projectiontest.NewMockProjection()
+	if err := projection.AsOf(context.Background(), store, proj, now.Add(-2*time.Hour)); err != nil {
+		t.Fatalf("AsOf failed with %q", err)
+	}
+
+	if len(proj.AppliedEvents) != 2 {
+		t.Fatalf("projection should have applied %d events; applied %d", 2, len(proj.AppliedEvents))
+	}
+	proj.ExpectApplied(t, events[:2]...)
+
+	proj = projectiontest.NewMockProjection()
+	if err := projection.AsOf(context.Background(), store, proj, now, projection.AsOfEvents("foo")); err != nil {
+		t.Fatalf("AsOf failed with %q", err)
+	}
+
+	if len(proj.AppliedEvents) != 2 {
+		t.Fatalf("projection should have applied %d events; applied %d", 2, len(proj.AppliedEvents))
+	}
+	proj.ExpectApplied(t, events[0], events[2])
+}
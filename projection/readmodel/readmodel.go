@@ -0,0 +1,431 @@
+// Package readmodel provides a concurrency-safe in-memory document store for
+// read models.
+//
+// Small services often need nothing more than a few maps to hold the read
+// models of their projections. A Store holds documents by their id, maintains
+// secondary indexes that are updated whenever a document is saved, and
+// queries documents by these indexes:
+//
+//	type Order struct {
+//		ID       uuid.UUID
+//		Customer uuid.UUID
+//		Status   string
+//	}
+//
+//	orders := readmodel.New(
+//		readmodel.Index("customer", func(o Order) any { return o.Customer }),
+//		readmodel.Index("status", func(o Order) any { return o.Status }),
+//	)
+//
+//	err := orders.Use(ctx, orderID, func(o *Order) error {
+//		o.Status = "open"
+//		return nil
+//	})
+//
+//	open, err := orders.Query(ctx, readmodel.Query[Order]{
+//		Where: map[string]any{"customer": customerID, "status": "open"},
+//		Limit: 10,
+//	})
+//
+// Documents are stored by value. If T is a pointer type, documents must not be
+// modified outside of Use, because the indexes of a Store are only updated when
+// a document is saved.
+package readmodel
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/persistence/model"
+)
+
+var (
+	// ErrUnknownIndex is returned by Store.Query when the query uses an index
+	// that was not configured.
+	ErrUnknownIndex = errors.New("unknown index")
+
+	// ErrDuplicate is returned when a saved document violates a unique index.
+	ErrDuplicate = errors.New("duplicate value of unique index")
+)
+
+// Store is a concurrency-safe in-memory document store. Use New to create a
+// Store.
+type Store[T any] struct {
+	indexes map[string]*index[T]
+	factory func(uuid.UUID) T
+
+	mux  sync.RWMutex
+	docs map[uuid.UUID]T
+
+	locks keyLock
+}
+
+// Option is an option for a Store.
+type Option[T any] func(*Store[T])
+
+type index[T any] struct {
+	key    func(T) any
+	unique bool
+	values map[any]map[uuid.UUID]struct{}
+	keys   map[uuid.UUID]any
+}
+
+// Index returns an Option that adds a secondary index to a Store. The key
+// function returns the indexed value of a document, or nil if the document
+// should not be indexed. Indexed values must be comparable.
+func Index[T any](name string, key func(T) any) Option[T] {
+	return func(s *Store[T]) {
+		s.indexes[name] = newIndex(key, false)
+	}
+}
+
+// Unique returns an Option that adds a unique secondary index to a Store.
+// Saving a document whose indexed value is already used by another document
+// fails with an error that unwraps to ErrDuplicate.
+func Unique[T any](name string, key func(T) any) Option[T] {
+	return func(s *Store[T]) {
+		s.indexes[name] = newIndex(key, true)
+	}
+}
+
+// Factory returns an Option that provides a factory function for documents.
+// Store.Use creates documents that do not exist using the factory function
+// instead of returning model.ErrNotFound.
+func Factory[T any](factory func(uuid.UUID) T) Option[T] {
+	return func(s *Store[T]) {
+		s.factory = factory
+	}
+}
+
+func newIndex[T any](key func(T) any, unique bool) *index[T] {
+	return &index[T]{
+		key:    key,
+		unique: unique,
+		values: make(map[any]map[uuid.UUID]struct{}),
+		keys:   make(map[uuid.UUID]any),
+	}
+}
+
+// New returns an in-memory document store.
+func New[T any](opts ...Option[T]) *Store[T] {
+	s := &Store[T]{
+		indexes: make(map[string]*index[T]),
+		docs:    make(map[uuid.UUID]T),
+		locks:   keyLock{locks: make(map[uuid.UUID]*lockEntry)},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Len returns the number of documents in the store.
+func (s *Store[T]) Len() int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return len(s.docs)
+}
+
+// Save saves the document with the given id and updates the indexes of the
+// store. If the document violates a unique index, an error that unwraps to
+// ErrDuplicate is returned and the document is not saved.
+func (s *Store[T]) Save(ctx context.Context, id uuid.UUID, doc T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	return s.save(id, doc)
+}
+
+func (s *Store[T]) save(id uuid.UUID, doc T) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	keys := make(map[string]any, len(s.indexes))
+	for name, idx := range s.indexes {
+		key := idx.key(doc)
+		if key == nil {
+			continue
+		}
+		if idx.unique {
+			for other := range idx.values[key] {
+				if other != id {
+					return fmt.Errorf("%w: %q index already contains %v [document=%s]", ErrDuplicate, name, key, other)
+				}
+			}
+		}
+		keys[name] = key
+	}
+
+	for name, idx := range s.indexes {
+		idx.remove(id)
+		if key, ok := keys[name]; ok {
+			idx.add(id, key)
+		}
+	}
+
+	s.docs[id] = doc
+
+	return nil
+}
+
+// Fetch returns the document with the given id, or an error that unwraps to
+// model.ErrNotFound if the document does not exist.
+func (s *Store[T]) Fetch(ctx context.Context, id uuid.UUID) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%w [id=%s]", model.ErrNotFound, id)
+	}
+
+	return doc, nil
+}
+
+// Use fetches the document with the given id, calls fn with a pointer to the
+// document, and saves the document if fn returns nil. Calls to Use, Save, and
+// Delete for the same document are serialized, so that a document is never
+// modified by multiple goroutines at the same time, while other documents can
+// be queried and updated concurrently. If the document does not exist and no
+// Factory was provided, an error that unwraps to model.ErrNotFound is returned.
+func (s *Store[T]) Use(ctx context.Context, id uuid.UUID, fn func(*T) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	s.mux.RLock()
+	doc, ok := s.docs[id]
+	s.mux.RUnlock()
+
+	if !ok {
+		if s.factory == nil {
+			return fmt.Errorf("%w [id=%s]", model.ErrNotFound, id)
+		}
+		doc = s.factory(id)
+	}
+
+	if err := fn(&doc); err != nil {
+		return err
+	}
+
+	if err := s.save(id, doc); err != nil {
+		return fmt.Errorf("save document: %w", err)
+	}
+
+	return nil
+}
+
+// Delete deletes the document with the given id. Deleting a document that does
+// not exist is not an error.
+func (s *Store[T]) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	unlock := s.locks.lock(id)
+	defer unlock()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, idx := range s.indexes {
+		idx.remove(id)
+	}
+	delete(s.docs, id)
+
+	return nil
+}
+
+// Query is a query for the documents of a Store.
+type Query[T any] struct {
+	// Where maps index names to values. Only documents whose indexed values
+	// are equal to the given values are returned. An empty Where matches all
+	// documents.
+	Where map[string]any
+
+	// Filter, if provided, is called for each document that matches Where.
+	// Only documents for which Filter returns true are returned.
+	Filter func(T) bool
+
+	// Sort, if provided, sorts the returned documents. Sort returns a negative
+	// number if a < b, a positive number if a > b, and zero if a == b.
+	// Documents are sorted by id by default.
+	Sort func(a, b T) int
+
+	// Offset is the number of matching documents to skip.
+	Offset int
+
+	// Limit is the maximum number of documents to return. A Limit <= 0 means
+	// no limit.
+	Limit int
+}
+
+// Query returns the documents that match the given query. If the query uses an
+// index that was not configured, an error that unwraps to ErrUnknownIndex is
+// returned.
+func (s *Store[T]) Query(ctx context.Context, q Query[T]) ([]T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	ids, err := s.match(q.Where)
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		id  uuid.UUID
+		doc T
+	}
+
+	results := make([]result, 0, len(ids))
+	for _, id := range ids {
+		doc := s.docs[id]
+		if q.Filter != nil && !q.Filter(doc) {
+			continue
+		}
+		results = append(results, result{id: id, doc: doc})
+	}
+
+	slices.SortFunc(results, func(a, b result) int {
+		if q.Sort != nil {
+			if c := q.Sort(a.doc, b.doc); c != 0 {
+				return c
+			}
+		}
+		return bytes.Compare(a.id[:], b.id[:])
+	})
+
+	if q.Offset > 0 {
+		results = results[min(q.Offset, len(results)):]
+	}
+
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+
+	docs := make([]T, len(results))
+	for i, r := range results {
+		docs[i] = r.doc
+	}
+
+	return docs, nil
+}
+
+// match returns the ids of the documents whose indexed values are equal to the
+// given values.
+func (s *Store[T]) match(where map[string]any) ([]uuid.UUID, error) {
+	if len(where) == 0 {
+		ids := make([]uuid.UUID, 0, len(s.docs))
+		for id := range s.docs {
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+
+	sets := make([]map[uuid.UUID]struct{}, 0, len(where))
+	for name, value := range where {
+		idx, ok := s.indexes[name]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownIndex, name)
+		}
+		sets = append(sets, idx.values[value])
+	}
+
+	// intersect, starting with the smallest set
+	slices.SortFunc(sets, func(a, b map[uuid.UUID]struct{}) int {
+		return len(a) - len(b)
+	})
+
+	var ids []uuid.UUID
+L:
+	for id := range sets[0] {
+		for _, set := range sets[1:] {
+			if _, ok := set[id]; !ok {
+				continue L
+			}
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (idx *index[T]) add(id uuid.UUID, key any) {
+	set, ok := idx.values[key]
+	if !ok {
+		set = make(map[uuid.UUID]struct{})
+		idx.values[key] = set
+	}
+	set[id] = struct{}{}
+	idx.keys[id] = key
+}
+
+func (idx *index[T]) remove(id uuid.UUID) {
+	key, ok := idx.keys[id]
+	if !ok {
+		return
+	}
+	delete(idx.keys, id)
+
+	set := idx.values[key]
+	delete(set, id)
+	if len(set) == 0 {
+		delete(idx.values, key)
+	}
+}
+
+// keyLock serializes the modifications of single documents.
+type keyLock struct {
+	mux   sync.Mutex
+	locks map[uuid.UUID]*lockEntry
+}
+
+type lockEntry struct {
+	mux  sync.Mutex
+	refs int
+}
+
+func (l *keyLock) lock(id uuid.UUID) func() {
+	l.mux.Lock()
+	e, ok := l.locks[id]
+	if !ok {
+		e = &lockEntry{}
+		l.locks[id] = e
+	}
+	e.refs++
+	l.mux.Unlock()
+
+	e.mux.Lock()
+
+	return func() {
+		e.mux.Unlock()
+
+		l.mux.Lock()
+		defer l.mux.Unlock()
+		if e.refs--; e.refs == 0 {
+			delete(l.locks, id)
+		}
+	}
+}
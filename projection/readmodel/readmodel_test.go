@@ -0,0 +1,253 @@
+package readmodel_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/modernice/goes/persistence/model"
+	"github.com/modernice/goes/projection/readmodel"
+)
+
+type order struct {
+	ID       uuid.UUID
+	Customer string
+	Status   string
+	Number   string
+	Total    int
+}
+
+func newStore() *readmodel.Store[order] {
+	return readmodel.New(
+		readmodel.Index("customer", func(o order) any { return o.Customer }),
+		readmodel.Index("status", func(o order) any { return o.Status }),
+		readmodel.Unique("number", func(o order) any {
+			if o.Number == "" {
+				return nil
+			}
+			return o.Number
+		}),
+	)
+}
+
+func TestStore_Fetch(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	o := order{ID: uuid.New(), Customer: "bob", Status: "open"}
+	if err := s.Save(ctx, o.ID, o); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	got, err := s.Fetch(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if !cmp.Equal(o, got) {
+		t.Fatalf("Fetch() returned wrong document\n%s", cmp.Diff(o, got))
+	}
+
+	if _, err := s.Fetch(ctx, uuid.New()); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("Fetch() should fail with %q; got %q", model.ErrNotFound, err)
+	}
+}
+
+func TestStore_Query(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	orders := []order{
+		{ID: uuid.New(), Customer: "bob", Status: "open", Total: 30},
+		{ID: uuid.New(), Customer: "bob", Status: "open", Total: 10},
+		{ID: uuid.New(), Customer: "bob", Status: "shipped", Total: 20},
+		{ID: uuid.New(), Customer: "alice", Status: "open", Total: 40},
+	}
+	for _, o := range orders {
+		if err := s.Save(ctx, o.ID, o); err != nil {
+			t.Fatalf("Save() failed with %q", err)
+		}
+	}
+
+	byTotal := func(a, b order) int { return a.Total - b.Total }
+
+	tests := []struct {
+		name  string
+		query readmodel.Query[order]
+		want  []order
+	}{
+		{
+			name:  "all",
+			query: readmodel.Query[order]{Sort: byTotal},
+			want:  []order{orders[1], orders[2], orders[0], orders[3]},
+		},
+		{
+			name:  "single index",
+			query: readmodel.Query[order]{Where: map[string]any{"status": "open"}, Sort: byTotal},
+			want:  []order{orders[1], orders[0], orders[3]},
+		},
+		{
+			name:  "multiple indexes",
+			query: readmodel.Query[order]{Where: map[string]any{"customer": "bob", "status": "open"}, Sort: byTotal},
+			want:  []order{orders[1], orders[0]},
+		},
+		{
+			name: "filter",
+			query: readmodel.Query[order]{
+				Where:  map[string]any{"customer": "bob"},
+				Filter: func(o order) bool { return o.Total >= 20 },
+				Sort:   byTotal,
+			},
+			want: []order{orders[2], orders[0]},
+		},
+		{
+			name:  "offset and limit",
+			query: readmodel.Query[order]{Sort: byTotal, Offset: 1, Limit: 2},
+			want:  []order{orders[2], orders[0]},
+		},
+		{
+			name:  "no match",
+			query: readmodel.Query[order]{Where: map[string]any{"customer": "carol"}},
+			want:  []order{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Query(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("Query() failed with %q", err)
+			}
+
+			if !cmp.Equal(tt.want, got) {
+				t.Fatalf("Query() returned wrong documents\n%s", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+
+	if _, err := s.Query(ctx, readmodel.Query[order]{Where: map[string]any{"foo": "bar"}}); !errors.Is(err, readmodel.ErrUnknownIndex) {
+		t.Fatalf("Query() should fail with %q; got %q", readmodel.ErrUnknownIndex, err)
+	}
+}
+
+func TestStore_Use(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	o := order{ID: uuid.New(), Customer: "bob", Status: "open"}
+	if err := s.Save(ctx, o.ID, o); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	if err := s.Use(ctx, o.ID, func(o *order) error {
+		o.Status = "shipped"
+		return nil
+	}); err != nil {
+		t.Fatalf("Use() failed with %q", err)
+	}
+
+	open, err := s.Query(ctx, readmodel.Query[order]{Where: map[string]any{"status": "open"}})
+	if err != nil {
+		t.Fatalf("Query() failed with %q", err)
+	}
+	if len(open) != 0 {
+		t.Fatalf("index should have been updated; got %d open orders", len(open))
+	}
+
+	shipped, err := s.Query(ctx, readmodel.Query[order]{Where: map[string]any{"status": "shipped"}})
+	if err != nil {
+		t.Fatalf("Query() failed with %q", err)
+	}
+	if len(shipped) != 1 || shipped[0].ID != o.ID {
+		t.Fatalf("Query() should return the updated order; got %v", shipped)
+	}
+
+	mockError := errors.New("mock error")
+	if err := s.Use(ctx, o.ID, func(o *order) error {
+		o.Status = "canceled"
+		return mockError
+	}); !errors.Is(err, mockError) {
+		t.Fatalf("Use() should fail with %q; got %q", mockError, err)
+	}
+
+	if got, _ := s.Fetch(ctx, o.ID); got.Status != "shipped" {
+		t.Fatalf("document should not be saved if fn fails; status is %q", got.Status)
+	}
+
+	if err := s.Use(ctx, uuid.New(), func(*order) error { return nil }); !errors.Is(err, model.ErrNotFound) {
+		t.Fatalf("Use() should fail with %q; got %q", model.ErrNotFound, err)
+	}
+}
+
+func TestStore_Use_concurrent(t *testing.T) {
+	ctx := context.Background()
+	s := readmodel.New(readmodel.Factory(func(id uuid.UUID) order {
+		return order{ID: id}
+	}))
+
+	id := uuid.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Use(ctx, id, func(o *order) error {
+				o.Total++
+				return nil
+			}); err != nil {
+				t.Errorf("Use() failed with %q", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.Fetch(ctx, id)
+	if err != nil {
+		t.Fatalf("Fetch() failed with %q", err)
+	}
+
+	if got.Total != 100 {
+		t.Fatalf("Total should be %d; is %d", 100, got.Total)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	ctx := context.Background()
+	s := newStore()
+
+	a := order{ID: uuid.New(), Number: "1"}
+	b := order{ID: uuid.New(), Number: "1"}
+
+	if err := s.Save(ctx, a.ID, a); err != nil {
+		t.Fatalf("Save() failed with %q", err)
+	}
+
+	if err := s.Save(ctx, a.ID, a); err != nil {
+		t.Fatalf("saving the same document twice should not fail; got %q", err)
+	}
+
+	err := s.Save(ctx, b.ID, b)
+	if !errors.Is(err, readmodel.ErrDuplicate) {
+		t.Fatalf("Save() should fail with %q; got %q", readmodel.ErrDuplicate, err)
+	}
+	if !strings.Contains(err.Error(), a.ID.String()) {
+		t.Fatalf("error should contain the id of the conflicting document; got %q", err)
+	}
+
+	if s.Len() != 1 {
+		t.Fatalf("store should contain %d document; got %d", 1, s.Len())
+	}
+
+	if err := s.Delete(ctx, a.ID); err != nil {
+		t.Fatalf("Delete() failed with %q", err)
+	}
+
+	if err := s.Save(ctx, b.ID, b); err != nil {
+		t.Fatalf("Save() should succeed after the conflicting document was deleted; got %q", err)
+	}
+}
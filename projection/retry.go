@@ -0,0 +1,118 @@
+package projection
+
+import "time"
+
+const (
+	// DefaultMaxAttempts is the default number of times a projection job is
+	// applied if it fails with a RetryPolicy, including the first attempt.
+	DefaultMaxAttempts = 3
+
+	// DefaultInitialBackoff is the default delay before the first retry of a
+	// failed projection job.
+	DefaultInitialBackoff = 100 * time.Millisecond
+
+	// DefaultMaxBackoff is the default maximum delay between two attempts to
+	// apply a projection job.
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// JobRetry is the retry policy of a subscription to a projection schedule.
+type JobRetry struct {
+	// MaxAttempts is the maximum number of times a job is applied, including
+	// the first attempt.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. The delay doubles
+	// with every further retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between two attempts.
+	MaxBackoff time.Duration
+
+	// Retryable reports whether a job that failed with the given error should
+	// be retried. If nil, all errors are retried.
+	Retryable func(error) bool
+}
+
+// RetryOption is an option for RetryPolicy.
+type RetryOption func(*JobRetry)
+
+// RetryPolicy returns a SubscribeOption that retries projection jobs whose
+// apply function returns an error, instead of dropping the job until the
+// schedule is triggered again. Jobs are retried with exponential backoff until
+// they succeed, the error is not retryable, or MaxAttempts is reached. Only the
+// last error of a job is sent into the error channel of the subscription.
+//
+// A job is applied again from the beginning, so the apply function should be
+// idempotent, e.g. by using a progress-aware projection:
+//
+//	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+//		return job.Apply(job, proj)
+//	}, projection.RetryPolicy(
+//		projection.MaxAttempts(5),
+//		projection.Backoff(time.Second, time.Minute),
+//		projection.RetryIf(func(err error) bool {
+//			return errors.Is(err, errTransient)
+//		}),
+//	))
+func RetryPolicy(opts ...RetryOption) SubscribeOption {
+	return func(s *Subscription) {
+		r := JobRetry{
+			MaxAttempts:    DefaultMaxAttempts,
+			InitialBackoff: DefaultInitialBackoff,
+			MaxBackoff:     DefaultMaxBackoff,
+		}
+		for _, opt := range opts {
+			opt(&r)
+		}
+		s.Retry = &r
+	}
+}
+
+// MaxAttempts returns a RetryOption that configures the maximum number of
+// times a job is applied, including the first attempt. Defaults to
+// DefaultMaxAttempts.
+func MaxAttempts(n int) RetryOption {
+	return func(r *JobRetry) {
+		r.MaxAttempts = n
+	}
+}
+
+// Backoff returns a RetryOption that configures the delay before the first
+// retry of a job and the maximum delay between two attempts. Defaults to
+// DefaultInitialBackoff and DefaultMaxBackoff.
+func Backoff(initial, max time.Duration) RetryOption {
+	return func(r *JobRetry) {
+		r.InitialBackoff = initial
+		r.MaxBackoff = max
+	}
+}
+
+// RetryIf returns a RetryOption that only retries jobs that fail with errors
+// for which fn returns true.
+func RetryIf(fn func(error) bool) RetryOption {
+	return func(r *JobRetry) {
+		r.Retryable = fn
+	}
+}
+
+// ShouldRetry reports whether a job that failed with the given error after the
+// given number of attempts should be retried.
+func (r JobRetry) ShouldRetry(err error, attempts int) bool {
+	if err == nil || attempts >= r.MaxAttempts {
+		return false
+	}
+	return r.Retryable == nil || r.Retryable(err)
+}
+
+// Delay returns the delay before the given retry, starting at 1.
+func (r JobRetry) Delay(retry int) time.Duration {
+	delay := r.InitialBackoff
+	for i := 1; i < retry && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if r.MaxBackoff > 0 && delay > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,25 @@
+package projection_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/projection"
+)
+
+func TestJobRetry_Delay(t *testing.T) {
+	r := projection.JobRetry{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for retry, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		if got := r.Delay(retry); got != want {
+			t.Errorf("Delay(%d) should return %v; got %v", retry, want, got)
+		}
+	}
+}
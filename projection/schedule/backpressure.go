@@ -0,0 +1,138 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/modernice/goes/projection"
+)
+
+// ErrJobQueueFull is sent into the error channel of a subscription to a
+// Continuous schedule if a projection job is rejected because the maximum
+// number of pending jobs is reached and the OverflowError strategy is used.
+var ErrJobQueueFull = errors.New("projection job queue is full")
+
+// Overflow is the strategy of a Continuous schedule for projection jobs that
+// are created while the maximum number of pending jobs is reached.
+type Overflow int
+
+const (
+	// OverflowBlock blocks the creation of jobs until a pending job has been
+	// applied. While blocked, the schedule does not receive events from the
+	// event bus, which applies backpressure to the bus.
+	OverflowBlock = Overflow(iota)
+
+	// OverflowDropOldest drops the oldest pending job to make room for the
+	// new job.
+	OverflowDropOldest
+
+	// OverflowError rejects the new job and sends an error that unwraps to
+	// ErrJobQueueFull into the error channel of the subscription.
+	OverflowError
+)
+
+// MaxPendingJobs returns a ContinuousOption that limits the number of
+// projection jobs that wait to be applied while the apply function of a
+// subscription is busy. When the limit is reached, new jobs are handled
+// according to the OnOverflow option, which defaults to OverflowBlock. Use
+// MaxPendingJobs to make slow projections degrade gracefully:
+//
+//	s := schedule.Continuously(
+//		bus, store, []string{"foo"},
+//		schedule.Debounce(time.Second),
+//		schedule.MaxPendingJobs(10),
+//		schedule.OnOverflow(schedule.OverflowDropOldest),
+//	)
+//
+// Jobs that are created for events that were published over the event bus only
+// contain those events. Dropping or rejecting such a job loses its events for
+// the projection, so the OverflowDropOldest and OverflowError strategies should
+// only be used for projections that can tolerate missing events, or that are
+// periodically rebuilt from the event store.
+func MaxPendingJobs(n int) ContinuousOption {
+	return func(c *Continuous) {
+		c.maxPendingJobs = n
+	}
+}
+
+// OnOverflow returns a ContinuousOption that configures how jobs are handled
+// when the maximum number of pending jobs is reached (see MaxPendingJobs).
+func OnOverflow(o Overflow) ContinuousOption {
+	return func(c *Continuous) {
+		c.overflow = o
+	}
+}
+
+// String returns the name of the strategy.
+func (o Overflow) String() string {
+	switch o {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowError:
+		return "error"
+	default:
+		return fmt.Sprintf("<unknown overflow strategy %d>", int(o))
+	}
+}
+
+// queueJobs returns a channel that receives the jobs from the given channel.
+// Jobs that cannot be passed on immediately are queued, up to the configured
+// maximum number of pending jobs.
+func (schedule *Continuous) queueJobs(ctx context.Context, jobs <-chan projection.Job, out chan<- error) <-chan projection.Job {
+	if schedule.maxPendingJobs <= 0 {
+		return jobs
+	}
+
+	queued := make(chan projection.Job)
+
+	go func() {
+		defer close(queued)
+
+		var pending []projection.Job
+		for jobs != nil || len(pending) > 0 {
+			var send chan<- projection.Job
+			var next projection.Job
+			if len(pending) > 0 {
+				send, next = queued, pending[0]
+			}
+
+			receive := jobs
+			if len(pending) >= schedule.maxPendingJobs && schedule.overflow == OverflowBlock {
+				receive = nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case send <- next:
+				pending = pending[1:]
+			case job, ok := <-receive:
+				if !ok {
+					jobs = nil
+					break
+				}
+
+				if len(pending) < schedule.maxPendingJobs {
+					pending = append(pending, job)
+					break
+				}
+
+				switch schedule.overflow {
+				case OverflowDropOldest:
+					pending = append(pending[1:], job)
+				case OverflowError:
+					select {
+					case <-ctx.Done():
+						return
+					case out <- fmt.Errorf("%w [max=%d]", ErrJobQueueFull, schedule.maxPendingJobs):
+					}
+				}
+			}
+		}
+	}()
+
+	return queued
+}
@@ -0,0 +1,149 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestMaxPendingJobs_block(t *testing.T) {
+	applied, events, rejected := runSlowProjection(t, 5, schedule.MaxPendingJobs(1))
+
+	want := []uuid.UUID{events[0].ID(), events[1].ID(), events[2].ID(), events[3].ID(), events[4].ID()}
+	if !cmp.Equal(want, applied) {
+		t.Fatalf("all jobs should have been applied\n%s", cmp.Diff(want, applied))
+	}
+
+	if rejected != 0 {
+		t.Fatalf("no jobs should have been rejected; got %d", rejected)
+	}
+}
+
+func TestMaxPendingJobs_dropOldest(t *testing.T) {
+	applied, events, _ := runSlowProjection(t, 5, schedule.MaxPendingJobs(2), schedule.OnOverflow(schedule.OverflowDropOldest))
+
+	want := []uuid.UUID{events[0].ID(), events[3].ID(), events[4].ID()}
+	if !cmp.Equal(want, applied) {
+		t.Fatalf("the oldest pending jobs should have been dropped\n%s", cmp.Diff(want, applied))
+	}
+}
+
+func TestMaxPendingJobs_error(t *testing.T) {
+	applied, events, rejected := runSlowProjection(t, 3, schedule.MaxPendingJobs(1), schedule.OnOverflow(schedule.OverflowError))
+
+	want := []uuid.UUID{events[0].ID(), events[1].ID()}
+	if !cmp.Equal(want, applied) {
+		t.Fatalf("new jobs should have been rejected\n%s", cmp.Diff(want, applied))
+	}
+
+	if rejected != 1 {
+		t.Fatalf("%d job should have been rejected; got %d", 1, rejected)
+	}
+}
+
+// runSlowProjection publishes n events to a Continuous schedule whose apply
+// function is blocked until all events have been published, and returns the
+// IDs of the events of the applied jobs, the published events, and the number
+// of rejected jobs. Errors other than schedule.ErrJobQueueFull fail the test.
+func runSlowProjection(t *testing.T, n int, opts ...schedule.ContinuousOption) ([]uuid.UUID, []event.Event, int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	s := schedule.Continuously(bus, eventstore.New(), []string{"foo"}, opts...)
+
+	started := make(chan struct{}, n)
+	release := make(chan struct{})
+	applied := make(chan uuid.UUID, n)
+
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		started <- struct{}{}
+		<-release
+
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+		for _, evt := range events {
+			applied <- evt.ID()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	events := make([]event.Event, n)
+	for i := range events {
+		events[i] = event.New[any]("foo", test.FooEventData{}).Any()
+	}
+
+	if err := bus.Publish(ctx, events[0]); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("first job should have been applied")
+	case <-started:
+	}
+
+	go func() {
+		for _, evt := range events[1:] {
+			if err := bus.Publish(ctx, evt); err != nil {
+				return
+			}
+		}
+	}()
+
+	var rejected int
+	timeout := time.After(200 * time.Millisecond)
+L:
+	for {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, schedule.ErrJobQueueFull) {
+				t.Fatal(err)
+			}
+			rejected++
+		case <-timeout:
+			break L
+		}
+	}
+
+	close(release)
+
+	var ids []uuid.UUID
+	for {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, schedule.ErrJobQueueFull) {
+				t.Fatal(err)
+			}
+			rejected++
+			continue
+		case id := <-applied:
+			ids = append(ids, id)
+			continue
+		case <-time.After(200 * time.Millisecond):
+		}
+		break
+	}
+
+	return ids, events, rejected
+}
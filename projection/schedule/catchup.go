@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+// CatchUp returns a ContinuousOption that makes subscriptions to the schedule
+// catch up with the event store before they start to apply published events.
+// When subscribing, the subscription first subscribes to the event bus, then
+// replays the historical events from the event store in a single job, and
+// finally switches to the live events of the event bus. Live events that were
+// already replayed are dropped, so that no event is applied twice and no event
+// that was published during the replay is missed:
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.CatchUp())
+//	errs, err := s.Subscribe(ctx, apply)
+//
+// Replayed events are detected using watermarks: a live event of an aggregate
+// is dropped if its version is not greater than the highest replayed version of
+// the aggregate, and a live event that does not belong to an aggregate is
+// dropped if its time is not after the time of the last replayed events (or it
+// is one of these events). The watermarks are kept for the lifetime of the
+// subscription, which requires memory for each replayed aggregate.
+//
+// The replay job replaces the startup job of the subscription. If the
+// projection.Startup option is provided, the query and options of its trigger
+// are used for the replay job. CatchUp cannot be combined with Interleave, and
+// subscriptions in standby mode (see Standby) ignore CatchUp.
+func CatchUp() ContinuousOption {
+	return func(c *Continuous) {
+		c.catchUp = true
+	}
+}
+
+// applyCatchUp replays the events from the event store and returns the event
+// and error channels that must be used by the subscription after the replay.
+// Live events that were already replayed are removed from the returned event
+// channel.
+func (schedule *Continuous) applyCatchUp(
+	ctx context.Context,
+	cfg projection.Subscription,
+	apply func(projection.Job) error,
+	events <-chan event.Event,
+	errs <-chan error,
+) (<-chan event.Event, <-chan error, error) {
+	live := collectLive(events, errs)
+
+	startup := projection.NewTrigger()
+	if cfg.Startup != nil {
+		startup = *cfg.Startup
+	}
+
+	q := startup.Query
+	if q == nil {
+		q = query.New(query.Name(schedule.eventNames...), query.SortByTime())
+	}
+
+	job := schedule.newJob(ctx, cfg, schedule.store, q, startup.JobOptions()...)
+	if err := applyJob(cfg, apply, job); err != nil {
+		live.stop()
+		return events, errs, err
+	}
+
+	marks, err := replayWatermarks(job)
+	if err != nil {
+		live.stop()
+		return events, errs, err
+	}
+
+	live.stop()
+
+	pending, pendingErrs := live.take()
+	if len(pendingErrs) > 0 {
+		errs = streams.FanInAll(streams.New(pendingErrs), errs)
+	}
+
+	out := make(chan event.Event)
+
+	go func() {
+		defer close(out)
+
+		forward := func(evt event.Event) bool {
+			if marks.replayed(evt) {
+				return true
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case out <- evt:
+				return true
+			}
+		}
+
+		for _, evt := range pending {
+			if !forward(evt) {
+				return
+			}
+		}
+
+		for evt := range events {
+			if !forward(evt) {
+				return
+			}
+		}
+	}()
+
+	return out, errs, nil
+}
+
+// watermarks are the highest replayed versions of aggregates, and the time and
+// ids of the last replayed events that do not belong to an aggregate.
+type watermarks struct {
+	versions map[event.AggregateRef]int
+	events   projection.Checkpoint
+}
+
+func replayWatermarks(job projection.Job) (watermarks, error) {
+	str, errs, err := job.Events(job)
+	if err != nil {
+		return watermarks{}, fmt.Errorf("query replayed events: %w", err)
+	}
+
+	marks := watermarks{versions: make(map[event.AggregateRef]int)}
+
+	if err := streams.Walk(job, func(evt event.Event) error {
+		id, name, v := evt.Aggregate()
+		if id == uuid.Nil {
+			marks.events = marks.events.Advance(evt)
+			return nil
+		}
+
+		ref := event.AggregateRef{Name: name, ID: id}
+		if current, ok := marks.versions[ref]; !ok || v > current {
+			marks.versions[ref] = v
+		}
+
+		return nil
+	}, str, errs); err != nil {
+		return watermarks{}, fmt.Errorf("query replayed events: %w", err)
+	}
+
+	return marks, nil
+}
+
+// replayed reports whether the given live event was already replayed.
+func (marks watermarks) replayed(evt event.Event) bool {
+	if id, name, v := evt.Aggregate(); id != uuid.Nil {
+		max, ok := marks.versions[event.AggregateRef{Name: name, ID: id}]
+		return ok && v <= max
+	}
+	return !marks.events.Time.IsZero() && !marks.events.Allows(evt)
+}
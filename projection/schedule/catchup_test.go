@@ -0,0 +1,93 @@
+package schedule_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestCatchUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	id := uuid.New()
+	now := time.Now()
+	a1 := event.New[any]("foo", test.FooEventData{}, event.Aggregate(id, "foo", 1), event.Time(now)).Any()
+	a2 := event.New[any]("foo", test.FooEventData{}, event.Aggregate(id, "foo", 2), event.Time(now.Add(time.Second))).Any()
+	n1 := event.New[any]("foo", test.FooEventData{}, event.Time(now.Add(2*time.Second))).Any()
+	a3 := event.New[any]("foo", test.FooEventData{}, event.Aggregate(id, "foo", 3), event.Time(now.Add(3*time.Second))).Any()
+	n2 := event.New[any]("foo", test.FooEventData{}, event.Time(now.Add(4*time.Second))).Any()
+
+	bus := eventbus.New()
+	store := eventstore.New(a1, a2, n1)
+	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.CatchUp())
+
+	applied := make(chan []event.Event, 2)
+	var replayed bool
+
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+
+		if !replayed {
+			replayed = true
+			// events that are published while the subscription catches up
+			if err := bus.Publish(job, a2, n1, a3); err != nil {
+				return err
+			}
+			if len(events) != 3 {
+				t.Errorf("replay job should contain %d events; got %d", 3, len(events))
+			}
+			return nil
+		}
+
+		applied <- events
+
+		return nil
+	}, projection.Startup())
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := bus.Publish(ctx, n2); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	var got []event.Event
+	for len(got) < 2 {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("timed out; got %v", got)
+		case err := <-errs:
+			t.Fatal(err)
+		case events := <-applied:
+			got = append(got, events...)
+		}
+	}
+
+	if got[0].ID() != a3.ID() || got[1].ID() != n2.ID() {
+		t.Fatalf("only live events that were not replayed should be applied; got %v", got)
+	}
+
+	select {
+	case events := <-applied:
+		t.Fatalf("no other events should be applied; got %v", events)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
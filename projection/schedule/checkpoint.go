@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+// ErrConcurrentCheckpoints is returned when subscribing to a schedule with both
+// projection.Checkpoints and more than one worker (see Workers). Jobs that are
+// applied concurrently may finish out of order, so that a checkpoint could skip
+// the events of a job that has not finished yet.
+var ErrConcurrentCheckpoints = errors.New("checkpoints cannot be used with multiple workers")
+
+// checkpointLocks contains a *sync.Mutex per checkpoint store and checkpoint
+// name. It serializes the updates of a checkpoint by different subscriptions,
+// so that a checkpoint cannot be overwritten with an older one.
+var checkpointLocks sync.Map
+
+type checkpointKey struct {
+	store any
+	name  string
+}
+
+// validateSubscription returns an error if the options of the subscription
+// cannot be combined.
+func validateSubscription(sub projection.Subscription) error {
+	if sub.Checkpoints != nil && sub.Workers > 1 {
+		return fmt.Errorf("%w [checkpoint=%s, workers=%d]", ErrConcurrentCheckpoints, sub.CheckpointName, sub.Workers)
+	}
+	return nil
+}
+
+// saveCheckpoint advances the checkpoint of the subscription to the events of
+// the job. The checkpoint is not saved if the job contains no new events.
+func saveCheckpoint(sub projection.Subscription, job projection.Job) error {
+	if sub.Checkpoints == nil {
+		return nil
+	}
+
+	str, errs, err := job.Events(job)
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	events, err := streams.Drain(job, str, errs)
+	if err != nil {
+		return fmt.Errorf("query events: %w", err)
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	mux := checkpointLock(sub)
+	mux.Lock()
+	defer mux.Unlock()
+
+	cp, err := sub.Checkpoints.Checkpoint(job, sub.CheckpointName)
+	if err != nil {
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+
+	next := cp.Advance(events...)
+	if next.Equal(cp) {
+		return nil
+	}
+
+	return sub.Checkpoints.SaveCheckpoint(job, sub.CheckpointName, next)
+}
+
+// checkpointLock returns the lock of the checkpoint of the subscription.
+// Checkpoint stores whose type is not comparable are locked per checkpoint
+// name.
+func checkpointLock(sub projection.Subscription) *sync.Mutex {
+	key := checkpointKey{name: sub.CheckpointName}
+	if reflect.TypeOf(sub.Checkpoints).Comparable() {
+		key.store = sub.Checkpoints
+	}
+
+	mux, _ := checkpointLocks.LoadOrStore(key, &sync.Mutex{})
+	return mux.(*sync.Mutex)
+}
@@ -0,0 +1,88 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestCheckpoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := time.Now()
+	store := eventstore.New(
+		event.New[any]("foo", test.FooEventData{}, event.Time(now)).Any(),
+		event.New[any]("foo", test.FooEventData{}, event.Time(now.Add(time.Second))).Any(),
+	)
+	checkpoints := projection.NewCheckpointStore()
+
+	if got := applyStartup(ctx, t, store, checkpoints); len(got) != 2 {
+		t.Fatalf("startup job should apply %d events; got %d", 2, len(got))
+	}
+
+	cp, err := checkpoints.Checkpoint(ctx, "foo")
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+
+	if !cp.Time.Equal(now.Add(time.Second)) {
+		t.Fatalf("checkpoint should be advanced to %v; got %v", now.Add(time.Second), cp.Time)
+	}
+
+	latest := event.New[any]("foo", test.FooEventData{}, event.Time(now.Add(2*time.Second))).Any()
+	if err := store.Insert(ctx, latest); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	// simulate a restarted service
+	got := applyStartup(ctx, t, store, checkpoints)
+	if len(got) != 1 || got[0].ID() != latest.ID() {
+		t.Fatalf("restarted subscription should resume from the checkpoint; got %v", got)
+	}
+}
+
+func TestCheckpoints_workers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := schedule.Continuously(eventbus.New(), eventstore.New(), []string{"foo"})
+
+	_, err := s.Subscribe(ctx, func(projection.Job) error { return nil },
+		projection.Checkpoints(projection.NewCheckpointStore(), "foo"),
+		schedule.Workers(4),
+	)
+	if !errors.Is(err, schedule.ErrConcurrentCheckpoints) {
+		t.Fatalf("Subscribe should fail with %q; got %q", schedule.ErrConcurrentCheckpoints, err)
+	}
+}
+
+func applyStartup(ctx context.Context, t *testing.T, store event.Store, checkpoints projection.CheckpointStore) []event.Event {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := schedule.Continuously(eventbus.New(), store, []string{"foo"})
+
+	var applied []event.Event
+	if _, err := s.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		applied, err = streams.Drain(job, str, errs)
+		return err
+	}, projection.Startup(), projection.Checkpoints(checkpoints, "foo")); err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	return applied
+}
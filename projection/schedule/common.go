@@ -2,11 +2,14 @@ package schedule
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/modernice/goes/event"
 	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/helper/streams"
 	"github.com/modernice/goes/projection"
 )
 
@@ -128,6 +131,7 @@ func (schedule *schedule) handleTriggers(
 
 func (schedule *schedule) applyJobs(
 	ctx context.Context,
+	sub projection.Subscription,
 	apply func(projection.Job) error,
 	jobs <-chan projection.Job,
 	out chan<- error,
@@ -135,8 +139,14 @@ func (schedule *schedule) applyJobs(
 ) {
 	defer close(done)
 	defer close(out)
+
+	if sub.Workers > 1 {
+		applyJobsConcurrently(ctx, sub, apply, jobs, out)
+		return
+	}
+
 	for job := range jobs {
-		if err := apply(job); err != nil {
+		if err := applyJob(sub, apply, job); err != nil {
 			select {
 			case <-ctx.Done():
 				return
@@ -149,7 +159,6 @@ func (schedule *schedule) applyJobs(
 func (schedule *schedule) applyStartupJob(
 	ctx context.Context,
 	sub projection.Subscription,
-	jobs chan<- projection.Job,
 	apply func(projection.Job) error,
 ) error {
 	if sub.Startup == nil {
@@ -161,7 +170,7 @@ func (schedule *schedule) applyStartupJob(
 		q = query.New(query.Name(schedule.eventNames...), query.SortByTime())
 	}
 
-	return apply(schedule.newJob(
+	return applyJob(sub, apply, schedule.newJob(
 		ctx,
 		sub,
 		schedule.store,
@@ -171,7 +180,148 @@ func (schedule *schedule) applyStartupJob(
 }
 
 func (schedule *schedule) newJob(ctx context.Context, sub projection.Subscription, store event.Store, q event.Query, opts ...projection.JobOption) projection.Job {
-	return projection.NewJob(ctx, store, q, append([]projection.JobOption{
-		projection.WithBeforeEvent(sub.BeforeEvent...),
-	}, opts...)...)
+	base := []projection.JobOption{projection.WithBeforeEvent(sub.BeforeEvent...)}
+	if sub.Checkpoints != nil {
+		base = append(base, projection.WithCheckpoint(sub.Checkpoints, sub.CheckpointName))
+	}
+	return projection.NewJob(ctx, store, q, append(base, opts...)...)
 }
+
+// applyJob calls apply with the given job. If the subscription has a
+// dead-letter handler, a job that permanently failed is delivered to the
+// handler, unless the job's context is canceled. If the subscription has a
+// checkpoint store, the checkpoint is advanced after the job was applied.
+func applyJob(sub projection.Subscription, apply func(projection.Job) error, job projection.Job) error {
+	err := retryJob(sub, apply, job)
+	if err == nil {
+		if err := saveCheckpoint(sub, job); err != nil {
+			return fmt.Errorf("save checkpoint: %w", err)
+		}
+		return nil
+	}
+
+	if sub.DeadLetter == nil || job.Err() != nil {
+		return err
+	}
+
+	if dlErr := deadLetter(sub, job, err); dlErr != nil {
+		return errors.Join(err, fmt.Errorf("dead-letter job: %w", dlErr))
+	}
+
+	return err
+}
+
+func deadLetter(sub projection.Subscription, job projection.Job, err error) error {
+	str, errs, qerr := job.Events(job)
+	if qerr != nil {
+		return fmt.Errorf("query events: %w", qerr)
+	}
+
+	events, qerr := streams.Drain(job, str, errs)
+	if qerr != nil {
+		return fmt.Errorf("query events: %w", qerr)
+	}
+
+	return sub.DeadLetter(job, projection.DeadJob{
+		Events: events,
+		Err:    err,
+		Time:   time.Now(),
+	})
+}
+
+// retryJob calls apply with the given job. If the subscription has a retry
+// policy, failed jobs are applied again after the backoff of the policy until
+// they succeed, the policy gives up, or the job's context is canceled.
+func retryJob(sub projection.Subscription, apply func(projection.Job) error, job projection.Job) error {
+	err := applyJobOnce(sub, apply, job)
+	if sub.Retry == nil {
+		return err
+	}
+
+	attempts := 1
+	for ; sub.Retry.ShouldRetry(err, attempts); attempts++ {
+		if job.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(sub.Retry.Delay(attempts))
+		select {
+		case <-job.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		err = applyJobOnce(sub, apply, job)
+	}
+
+	if err != nil && attempts > 1 {
+		return fmt.Errorf("%w [attempts=%d]", err, attempts)
+	}
+
+	return err
+}
+
+// applyJobOnce calls apply with the given job. If the subscription has a job
+// timeout, the job's context is canceled after the timeout and applyJobOnce
+// returns a *JobTimeoutError after apply has returned, so that a timed out job
+// does not run concurrently with the next job of the schedule. If apply returns
+// nil or an error that is not a context error, its result is returned instead.
+// If apply does not return within the grace period after the timeout, the job
+// is abandoned and an abandoned *JobTimeoutError is returned.
+func applyJobOnce(sub projection.Subscription, apply func(projection.Job) error, job projection.Job) error {
+	if sub.JobTimeout <= 0 {
+		return apply(job)
+	}
+
+	ctx, cancel := context.WithTimeout(job, sub.JobTimeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() { result <- apply(timeoutJob{Job: job, ctx: ctx}) }()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+	}
+
+	grace := sub.JobTimeoutGrace
+	if grace <= 0 {
+		grace = DefaultJobTimeoutGrace
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		// apply may have finished right at the deadline, or failed for a
+		// reason other than the canceled context.
+		if err == nil || !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+			return err
+		}
+		if job.Err() != nil {
+			return job.Err()
+		}
+		return &JobTimeoutError{Timeout: sub.JobTimeout}
+	case <-timer.C:
+		// apply ignores the canceled context. It returns into the buffered
+		// result channel whenever it finishes.
+		if job.Err() != nil {
+			return job.Err()
+		}
+		return &JobTimeoutError{Timeout: sub.JobTimeout, Abandoned: true}
+	}
+}
+
+// timeoutJob is a projection job whose context is replaced by ctx.
+type timeoutJob struct {
+	projection.Job
+
+	ctx context.Context
+}
+
+func (j timeoutJob) Deadline() (time.Time, bool) { return j.ctx.Deadline() }
+func (j timeoutJob) Done() <-chan struct{}       { return j.ctx.Done() }
+func (j timeoutJob) Err() error                  { return j.ctx.Err() }
+func (j timeoutJob) Value(key any) any           { return j.ctx.Value(key) }
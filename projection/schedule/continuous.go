@@ -42,8 +42,52 @@ type Continuous struct {
 
 	bus                    event.Bus
 	debounce               time.Duration
+	eventDebounce          map[string]time.Duration
 	debounceCap            time.Duration
 	debounceCapManuallySet bool
+	heartbeat              time.Duration
+	onIdle                 func(Idle)
+	onBuffer               []func(BufferStats)
+	highWatermark          int
+	onHighWatermark        func(BufferStats)
+	elected                <-chan struct{}
+	standbySize            int
+	interleave             int
+	batchSize              int
+	maxPendingJobs         int
+	overflow               Overflow
+	dedupeWindow           int
+	subscribeOpts          []event.SubscribeOption
+	drainTimeout           time.Duration
+	persistPending         func(context.Context, projection.Trigger) error
+	catchUp                bool
+}
+
+// Idle is passed to the heartbeat function of a Continuous schedule when no
+// events have been received for the configured heartbeat duration.
+type Idle struct {
+	// Since is the time at which the last event was received. If no event has
+	// been received yet, Since is the time at which the subscription started.
+	Since time.Time
+
+	// Duration is the duration for which no events have been received.
+	Duration time.Duration
+}
+
+// BufferStats describes the event buffer of a subscription to a Continuous
+// schedule. Events are buffered until a projection job is created for them,
+// which, when using the Debounce option, may take until the debounce cap is
+// reached.
+type BufferStats struct {
+	// Size is the number of buffered events.
+	Size int
+
+	// Since is the time at which the oldest buffered event was received.
+	Since time.Time
+
+	// Age is the duration for which the oldest buffered event has been
+	// buffered.
+	Age time.Duration
 }
 
 // ContinuousOption is an option for the Continuous schedule.
@@ -74,6 +118,34 @@ func Debounce(d time.Duration) ContinuousOption {
 	}
 }
 
+// DebouncePerEvent returns a ContinuousOption that overrides the debounce
+// duration of the Debounce option for the given events. Use DebouncePerEvent
+// to project high-frequency and low-frequency events with the same schedule:
+//
+//	s := schedule.Continuously(bus, store, []string{"telemetry.recorded", "admin.changed"},
+//		schedule.Debounce(time.Second),
+//		schedule.DebouncePerEvent(map[string]time.Duration{
+//			"telemetry.recorded": 10 * time.Second,
+//			"admin.changed":      0, // create jobs immediately
+//		}),
+//	)
+//
+// The debounce timer of a subscription is reset with the duration of the most
+// recently received event, so a job is created once no event has been received
+// for the duration of the last event. Events without an override use the
+// duration of the Debounce option. Unless the DebounceCap option is provided,
+// the debounce cap is computed from the duration of the last event.
+func DebouncePerEvent(durations map[string]time.Duration) ContinuousOption {
+	return func(c *Continuous) {
+		if c.eventDebounce == nil {
+			c.eventDebounce = make(map[string]time.Duration, len(durations))
+		}
+		for name, d := range durations {
+			c.eventDebounce[name] = d
+		}
+	}
+}
+
 // DebounceCap returns a ContinuousOption that specifies the maximum wait time
 // (cap) before force-triggering a projection job that was deferred by the
 // Debounce() option.
@@ -90,6 +162,88 @@ func DebounceCap(cap time.Duration) ContinuousOption {
 	}
 }
 
+// Heartbeat returns a ContinuousOption that makes a subscription to the
+// schedule call fn when no events have been received for the duration d, and
+// again every d until the next event is received. fn is called by the goroutine
+// that receives the events of the subscription, so a missing heartbeat
+// indicates that the subscription is blocked or has died, while a heartbeat
+// indicates that the subscription is alive but there is no traffic.
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Heartbeat(time.Minute, func(idle schedule.Idle) {
+//		log.Printf("no events received for %v", idle.Duration)
+//	}))
+func Heartbeat(d time.Duration, fn func(Idle)) ContinuousOption {
+	return func(c *Continuous) {
+		c.heartbeat = d
+		c.onIdle = fn
+	}
+}
+
+// ObserveBuffer returns a ContinuousOption that makes a subscription to the
+// schedule call fn with the stats of its event buffer whenever an event is
+// added to the buffer. Like the Heartbeat function, fn is called by the
+// goroutine that receives the events of the subscription, so it should return
+// quickly, e.g. by updating a gauge:
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Debounce(time.Second), schedule.ObserveBuffer(func(stats schedule.BufferStats) {
+//		bufferSize.Set(float64(stats.Size))
+//		bufferAge.Set(stats.Age.Seconds())
+//	}))
+func ObserveBuffer(fn func(BufferStats)) ContinuousOption {
+	return func(c *Continuous) {
+		c.onBuffer = append(c.onBuffer, fn)
+	}
+}
+
+// HighWatermark returns a ContinuousOption that makes a subscription to the
+// schedule call fn when its event buffer reaches the given size. fn is called
+// once per buffer: it is called again only after a projection job has been
+// created for the buffered events and the buffer reaches the size again. Use
+// HighWatermark to alert operators before a debounce buffer consumes all
+// memory during event storms.
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Debounce(time.Second), schedule.HighWatermark(10000, func(stats schedule.BufferStats) {
+//		log.Printf("%d events buffered for %v", stats.Size, stats.Age)
+//	}))
+func HighWatermark(size int, fn func(BufferStats)) ContinuousOption {
+	return func(c *Continuous) {
+		c.highWatermark = size
+		c.onHighWatermark = fn
+	}
+}
+
+// BatchSize returns a ContinuousOption that limits the number of events in a
+// projection job when using the Debounce option. A job is created as soon as
+// the event buffer of a subscription reaches n events, even if the debounce
+// timer hasn't fired yet, so that bursts of events are applied in multiple jobs
+// of at most n events instead of a single huge job.
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Debounce(time.Second), schedule.BatchSize(1000))
+func BatchSize(n int) ContinuousOption {
+	return func(c *Continuous) {
+		c.batchSize = n
+	}
+}
+
+// SubscribeWith returns a ContinuousOption that applies the given options to
+// the subscriptions of the schedule to the event bus. Use SubscribeWith with
+// event.Map to transform or enrich events before they are buffered by the
+// schedule and applied to projections:
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.SubscribeWith(
+//		event.Map(func(ctx context.Context, evt event.Event) (event.Event, error) {
+//			return decrypt(ctx, evt)
+//		}),
+//	))
+//
+// The options do not apply to events that are fetched from the event store,
+// e.g. by startup jobs and triggered jobs.
+func SubscribeWith(opts ...event.SubscribeOption) ContinuousOption {
+	return func(c *Continuous) {
+		c.subscribeOpts = append(c.subscribeOpts, opts...)
+	}
+}
+
 // Continuously returns a Continuous schedule that, when subscribed to,
 // subscribes to events with the given eventNames to create projection Jobs
 // for those events.
@@ -148,13 +302,50 @@ func Continuously(bus event.Bus, store event.Store, eventNames []string, opts ..
 // will be created and passed to apply.
 func (schedule *Continuous) Subscribe(ctx context.Context, apply func(projection.Job) error, opts ...projection.SubscribeOption) (<-chan error, error) {
 	cfg := projection.NewSubscription(opts...)
+	if err := validateSubscription(cfg); err != nil {
+		return nil, err
+	}
 
-	events, errs, err := schedule.bus.Subscribe(ctx, schedule.eventNames...)
+	events, errs, err := event.Subscribe(ctx, schedule.bus, schedule.eventNames, schedule.subscribeOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("subscribe to %v events: %w", schedule.eventNames, err)
 	}
+	events = schedule.dedupe(ctx, events)
 
 	out := make(chan error)
+
+	if schedule.elected != nil {
+		go schedule.standby(ctx, cfg, apply, events, errs, out)
+		return out, nil
+	}
+
+	if schedule.catchUp {
+		if events, errs, err = schedule.applyCatchUp(ctx, cfg, apply, events, errs); err != nil {
+			return nil, fmt.Errorf("catch up: %w", err)
+		}
+	} else if cfg.Startup != nil && schedule.interleave > 0 {
+		if events, errs, err = schedule.applyInterleavedStartup(ctx, cfg, apply, events, errs); err != nil {
+			return nil, fmt.Errorf("startup: %w", err)
+		}
+	} else if cfg.Startup != nil {
+		if err := schedule.applyStartupJob(ctx, cfg, apply); err != nil {
+			return nil, fmt.Errorf("startup: %w", err)
+		}
+	}
+
+	schedule.run(ctx, cfg, apply, events, errs, out)
+
+	return out, nil
+}
+
+func (schedule *Continuous) run(
+	ctx context.Context,
+	cfg projection.Subscription,
+	apply func(projection.Job) error,
+	events <-chan event.Event,
+	errs <-chan error,
+	out chan error,
+) {
 	jobs := make(chan projection.Job)
 	triggers := schedule.newTriggers()
 	done := make(chan struct{})
@@ -164,25 +355,27 @@ func (schedule *Continuous) Subscribe(ctx context.Context, apply func(projection
 		schedule.removeTriggers(triggers)
 	}()
 
-	if cfg.Startup != nil {
-		if err := schedule.applyStartupJob(ctx, cfg, jobs, apply); err != nil {
-			return nil, fmt.Errorf("startup: %w", err)
-		}
+	// When draining, the errors of applied jobs are forwarded by drain, which
+	// closes out after the pending events have been drained.
+	applied := out
+	var pending chan []event.Event
+	if schedule.draining() {
+		applied = make(chan error)
+		pending = make(chan []event.Event, 1)
+		go schedule.drain(ctx, cfg, apply, applied, pending, out)
 	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	go schedule.handleEvents(ctx, cfg, events, errs, jobs, out, &wg)
+	go schedule.handleEvents(ctx, cfg, events, errs, jobs, out, pending, &wg)
 	go schedule.handleTriggers(ctx, cfg, triggers, jobs, out, &wg)
-	go schedule.applyJobs(ctx, apply, jobs, out, done)
+	go schedule.applyJobs(ctx, cfg, apply, schedule.queueJobs(ctx, jobs, out), applied, done)
 
 	go func() {
 		wg.Wait()
 		close(jobs)
 	}()
-
-	return out, nil
 }
 
 func (schedule *Continuous) handleEvents(
@@ -192,6 +385,7 @@ func (schedule *Continuous) handleEvents(
 	errs <-chan error,
 	jobs chan<- projection.Job,
 	out chan<- error,
+	pending chan<- []event.Event,
 	wg *sync.WaitGroup,
 ) {
 	defer wg.Done()
@@ -207,6 +401,8 @@ func (schedule *Continuous) handleEvents(
 	var buf []event.Event
 	var debounce, debounceCap *time.Timer
 	var jobCreated bool
+	var bufferedSince time.Time
+	var watermarkReached bool
 
 	clearDebounce := func() {
 		mux.Lock()
@@ -225,6 +421,20 @@ func (schedule *Continuous) handleEvents(
 		}
 	}
 
+	if pending != nil {
+		// Runs after clearDebounce, so that no job is created for the pending
+		// events by a debounce timer.
+		defer func() {
+			mux.Lock()
+			defer mux.Unlock()
+			events := make([]event.Event, len(buf))
+			copy(events, buf)
+			buf = buf[:0]
+			jobCreated = true
+			pending <- events
+		}()
+	}
+
 	defer clearDebounce()
 
 	createJob := func() {
@@ -249,19 +459,55 @@ func (schedule *Continuous) handleEvents(
 
 		select {
 		case <-ctx.Done():
+			// Keep the buffered events for the drain.
+			return
 		case jobs <- job:
 		}
 
 		buf = buf[:0]
 		jobCreated = true
+		bufferedSince = time.Time{}
+		watermarkReached = false
+	}
+
+	observeBuffer := func() {
+		if len(schedule.onBuffer) == 0 && schedule.onHighWatermark == nil {
+			return
+		}
+
+		mux.Lock()
+		now := time.Now()
+		if bufferedSince.IsZero() {
+			bufferedSince = now
+		}
+		stats := BufferStats{Size: len(buf), Since: bufferedSince, Age: now.Sub(bufferedSince)}
+		reached := schedule.onHighWatermark != nil && !watermarkReached && stats.Size >= schedule.highWatermark
+		if reached {
+			watermarkReached = true
+		}
+		mux.Unlock()
+
+		for _, fn := range schedule.onBuffer {
+			fn(stats)
+		}
+
+		if reached {
+			schedule.onHighWatermark(stats)
+		}
 	}
 
 	addEvent := func(evt event.Event) {
 		clearDebounce()
 
 		buf = append(buf, evt)
+		observeBuffer()
+
+		mux.Lock()
+		full := schedule.batchSize > 0 && len(buf) >= schedule.batchSize
+		mux.Unlock()
 
-		if schedule.debounce <= 0 {
+		d := schedule.debounceOf(evt.Name())
+		if d <= 0 || full {
 			createJob()
 			return
 		}
@@ -269,17 +515,56 @@ func (schedule *Continuous) handleEvents(
 		mux.Lock()
 		defer mux.Unlock()
 
-		debounce = time.AfterFunc(schedule.debounce, createJob)
+		debounce = time.AfterFunc(d, createJob)
 
-		if cap := schedule.computeDebounceCap(); cap > 0 {
+		if cap := schedule.computeDebounceCap(d); cap > 0 {
 			debounceCap = time.AfterFunc(cap, createJob)
 		}
 	}
 
-	streams.ForEach(ctx, addEvent, fail, events, errs)
+	if schedule.heartbeat <= 0 || schedule.onIdle == nil {
+		streams.ForEach(ctx, addEvent, fail, events, errs)
+		return
+	}
+
+	lastEvent := time.Now()
+	heartbeat := time.NewTimer(schedule.heartbeat)
+	defer heartbeat.Stop()
+
+	for events != nil || errs != nil {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				break
+			}
+			fail(err)
+		case evt, ok := <-events:
+			if !ok {
+				events = nil
+				break
+			}
+			lastEvent = time.Now()
+			heartbeat.Reset(schedule.heartbeat)
+			addEvent(evt)
+		case now := <-heartbeat.C:
+			schedule.onIdle(Idle{Since: lastEvent, Duration: now.Sub(lastEvent)})
+			heartbeat.Reset(schedule.heartbeat)
+		}
+	}
+}
+
+// debounceOf returns the debounce duration for the event with the given name.
+func (s *Continuous) debounceOf(name string) time.Duration {
+	if d, ok := s.eventDebounce[name]; ok {
+		return d
+	}
+	return s.debounce
 }
 
-func (s *Continuous) computeDebounceCap() time.Duration {
+func (s *Continuous) computeDebounceCap(debounce time.Duration) time.Duration {
 	if s.debounceCap <= 0 {
 		return 0
 	}
@@ -288,9 +573,9 @@ func (s *Continuous) computeDebounceCap() time.Duration {
 		return s.debounceCap
 	}
 
-	if s.debounce <= defaultDebounceBarrier {
+	if debounce <= defaultDebounceBarrier {
 		return s.debounceCap
 	}
 
-	return s.debounce * 2
+	return debounce * 2
 }
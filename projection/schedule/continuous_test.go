@@ -3,6 +3,7 @@ package schedule_test
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -144,6 +145,182 @@ L:
 	proj.ExpectApplied(t, events[:3]...)
 }
 
+func TestDebouncePerEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	schedule := schedule.Continuously(
+		bus,
+		store,
+		[]string{"foo", "bar"},
+		schedule.Debounce(time.Second),
+		schedule.DebouncePerEvent(map[string]time.Duration{"bar": 0}),
+	)
+
+	proj := projectiontest.NewMockProjection()
+	appliedJobs := make(chan projection.Job)
+
+	errs, err := schedule.Subscribe(ctx, func(job projection.Job) error {
+		if err := job.Apply(job, proj); err != nil {
+			return err
+		}
+		appliedJobs <- job
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	events := []event.Event{
+		event.New[any]("foo", test.FooEventData{}),
+		event.New[any]("bar", test.FooEventData{}),
+	}
+
+	if err := bus.Publish(ctx, events[0]); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	case <-appliedJobs:
+		t.Fatalf("%q event should be debounced", "foo")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	start := time.Now()
+	if err := bus.Publish(ctx, events[1]); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timed out")
+	case err := <-errs:
+		t.Fatal(err)
+	case <-appliedJobs:
+		if took := time.Since(start); took >= 500*time.Millisecond {
+			t.Fatalf("%q event should not be debounced; job was created after %v", "bar", took)
+		}
+	}
+
+	proj.ExpectApplied(t, events...)
+}
+
+func TestBatchSize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	s := schedule.Continuously(
+		bus,
+		store,
+		[]string{"foo"},
+		schedule.Debounce(time.Hour),
+		schedule.BatchSize(3),
+	)
+
+	sizes := make(chan int)
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+		case sizes <- len(events):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	go func() {
+		for i := 0; i < 7; i++ {
+			if err := bus.Publish(ctx, event.New[any]("foo", test.FooEventData{})); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("job %d should have been created before the debounce timer fired", i+1)
+		case err := <-errs:
+			t.Fatal(err)
+		case size := <-sizes:
+			if size != 3 {
+				t.Fatalf("job should have %d events; has %d", 3, size)
+			}
+		}
+	}
+
+	select {
+	case size := <-sizes:
+		t.Fatalf("remaining event should be debounced; got job with %d events", size)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSubscribeWith(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.SubscribeWith(
+		event.Map(func(_ context.Context, evt event.Event) (event.Event, error) {
+			return event.New[any](evt.Name(), test.FooEventData{A: "mapped"}, event.ID(evt.ID())).Any(), nil
+		}),
+	))
+
+	applied := make(chan event.Event)
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+		for _, evt := range events {
+			applied <- evt
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := bus.Publish(ctx, event.New[any]("foo", test.FooEventData{A: "foo"}).Any()); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("timed out")
+	case err := <-errs:
+		t.Fatal(err)
+	case evt := <-applied:
+		if data := evt.Data().(test.FooEventData); data.A != "mapped" {
+			t.Fatalf("event should have been mapped before it was buffered; data is %v", data)
+		}
+	}
+}
+
 func TestDebounceCap(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -647,3 +824,131 @@ func TestContinuous_Subscribe_BeforeEvent(t *testing.T) {
 		t.Fatalf("projection job returned wrong events\n%s", cmp.Diff(want, events))
 	}
 }
+
+func TestHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	idle := make(chan schedule.Idle, 10)
+	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Heartbeat(50*time.Millisecond, func(i schedule.Idle) {
+		idle <- i
+	}))
+
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error { return nil })
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+	go func() {
+		for range errs {
+		}
+	}()
+
+	var first schedule.Idle
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("heartbeat should have been called")
+	case first = <-idle:
+	}
+
+	if first.Duration < 50*time.Millisecond {
+		t.Fatalf("Idle.Duration should be at least %v; is %v", 50*time.Millisecond, first.Duration)
+	}
+
+	if err := bus.Publish(ctx, event.New[any]("foo", test.FooEventData{})); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	// drain heartbeats that were sent before the event was received
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case <-deadline:
+			t.Fatalf("heartbeat should have been called after the event")
+		case i := <-idle:
+			if i.Since.After(first.Since) {
+				return
+			}
+		}
+	}
+}
+
+func TestHighWatermark(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	var mux sync.Mutex
+	var sizes []int
+	watermark := make(chan schedule.BufferStats, 3)
+
+	s := schedule.Continuously(
+		bus,
+		store,
+		[]string{"foo"},
+		schedule.Debounce(200*time.Millisecond),
+		schedule.ObserveBuffer(func(stats schedule.BufferStats) {
+			mux.Lock()
+			defer mux.Unlock()
+			sizes = append(sizes, stats.Size)
+		}),
+		schedule.HighWatermark(3, func(stats schedule.BufferStats) {
+			watermark <- stats
+		}),
+	)
+
+	applied := make(chan projection.Job)
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		applied <- job
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := bus.Publish(ctx, event.New[any]("foo", test.FooEventData{})); err != nil {
+			t.Fatalf("publish event: %v", err)
+		}
+	}
+
+	var stats schedule.BufferStats
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("high watermark should have been reached")
+	case err := <-errs:
+		t.Fatal(err)
+	case stats = <-watermark:
+	}
+
+	if stats.Size != 3 {
+		t.Fatalf("BufferStats.Size should be %d; is %d", 3, stats.Size)
+	}
+
+	if stats.Since.IsZero() {
+		t.Fatalf("BufferStats.Since should be set")
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("job should have been applied")
+	case <-applied:
+	}
+
+	select {
+	case <-watermark:
+		t.Fatalf("high watermark should only be reported once per buffer")
+	default:
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	if !cmp.Equal([]int{1, 2, 3, 4, 5}, sizes) {
+		t.Fatalf("buffer should have been observed after each event\n%s", cmp.Diff([]int{1, 2, 3, 4, 5}, sizes))
+	}
+}
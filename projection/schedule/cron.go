@@ -0,0 +1,408 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/projection"
+)
+
+// Calendar is a projection schedule that creates projection Jobs at the
+// wall-clock times of a cron expression.
+type Calendar struct {
+	*schedule
+
+	expr     CronExpr
+	location *time.Location
+}
+
+// CalendarOption is an option for a Calendar schedule.
+type CalendarOption func(*Calendar)
+
+// CronExpr is a parsed cron expression. Use ParseCron to parse an expression.
+type CronExpr struct {
+	expr   string
+	second cronField
+	minute cronField
+	hour   cronField
+	dom    cronField
+	month  cronField
+	dow    cronField
+	anyDom bool
+	anyDow bool
+}
+
+// cronField is a bit set of the values that match a field of an expression.
+type cronField uint64
+
+type cronBounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondBounds = cronBounds{name: "second", min: 0, max: 59}
+	minuteBounds = cronBounds{name: "minute", min: 0, max: 59}
+	hourBounds   = cronBounds{name: "hour", min: 0, max: 23}
+	domBounds    = cronBounds{name: "day of month", min: 1, max: 31}
+	monthBounds  = cronBounds{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowBounds = cronBounds{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// InLocation returns a CalendarOption that evaluates the cron expression in
+// the given time zone. Defaults to time.UTC.
+func InLocation(loc *time.Location) CalendarOption {
+	return func(c *Calendar) {
+		c.location = loc
+	}
+}
+
+// Cron returns a Calendar schedule that, when subscribed to, creates a
+// projection Job at every time that matches the given cron expression and
+// passes that Job to every subscriber of the schedule. Like a Periodic
+// schedule, a Calendar schedule fetches the entire history of the configured
+// events for every Job.
+//
+// The expression consists of five fields (minute, hour, day of month, month,
+// and day of week), or six fields if the first field specifies the second.
+// Fields support "*", values, ranges ("1-5"), steps ("*/15", "0-30/10"), and
+// lists ("1,15"). Months and days of the week may be given by their English
+// abbreviations ("jan", "mon"). Instead of an expression, one of the
+// descriptors "@yearly", "@monthly", "@weekly", "@daily", "@midnight", or
+// "@hourly" may be used. The expression is evaluated in UTC unless the
+// InLocation option is provided.
+//
+//	// Trigger a projection job every day at midnight UTC.
+//	s, err := schedule.Cron(store, "0 0 * * *", []string{"order_placed"})
+func Cron(store event.Store, expr string, eventNames []string, opts ...CalendarOption) (*Calendar, error) {
+	parsed, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Calendar{
+		schedule: newSchedule(store, eventNames),
+		expr:     parsed,
+		location: time.UTC,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// MustCron is like Cron, but panics if the cron expression is invalid.
+func MustCron(store event.Store, expr string, eventNames []string, opts ...CalendarOption) *Calendar {
+	c, err := Cron(store, expr, eventNames, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Expr returns the cron expression of the schedule.
+func (schedule *Calendar) Expr() CronExpr {
+	return schedule.expr
+}
+
+// Next returns the next time after t at which the schedule creates a Job, or
+// the zero Time if the cron expression never matches again.
+func (schedule *Calendar) Next(t time.Time) time.Time {
+	return schedule.expr.Next(t.In(schedule.location))
+}
+
+// Subscribe subscribes to the schedule and returns a channel of asynchronous
+// projection errors, or a single error if subscribing failed. When ctx is
+// canceled, the subscription is canceled and the returned error channel closed.
+//
+// When a projection Job is created, the apply function is called with that Job.
+// Use Job.Apply to apply the Job's events to a given projection:
+//
+//	var proj projection.Projection
+//	var s *schedule.Calendar
+//	s.Subscribe(context.TODO(), func(job projection.Job) error {
+//		return job.Apply(job, proj)
+//	})
+//
+// When the schedule is triggered by calling schedule.Trigger, a projection Job
+// will be created and passed to apply.
+func (schedule *Calendar) Subscribe(ctx context.Context, apply func(projection.Job) error, opts ...projection.SubscribeOption) (<-chan error, error) {
+	cfg := projection.NewSubscription(opts...)
+	if err := validateSubscription(cfg); err != nil {
+		return nil, err
+	}
+
+	out := make(chan error)
+	jobs := make(chan projection.Job)
+	triggers := schedule.newTriggers()
+	done := make(chan struct{})
+
+	go func() {
+		<-done
+		schedule.removeTriggers(triggers)
+	}()
+
+	if cfg.Startup != nil {
+		if err := schedule.applyStartupJob(ctx, cfg, apply); err != nil {
+			return nil, fmt.Errorf("startup: %w", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go schedule.handleCalendar(ctx, cfg, jobs, &wg)
+	go schedule.handleTriggers(ctx, cfg, triggers, jobs, out, &wg)
+	go schedule.applyJobs(ctx, cfg, apply, jobs, out, done)
+
+	go func() {
+		wg.Wait()
+		close(jobs)
+	}()
+
+	return out, nil
+}
+
+func (schedule *Calendar) handleCalendar(
+	ctx context.Context,
+	sub projection.Subscription,
+	jobs chan<- projection.Job,
+	wg *sync.WaitGroup,
+) {
+	defer wg.Done()
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		job := schedule.newJob(
+			ctx,
+			sub,
+			schedule.store,
+			query.New(
+				query.Name(schedule.eventNames...),
+				query.SortByTime(),
+			),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case jobs <- job:
+		}
+	}
+}
+
+// ParseCron parses the given cron expression. See Cron for the supported
+// syntax.
+func ParseCron(expr string) (CronExpr, error) {
+	spec := strings.TrimSpace(expr)
+	if d, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return CronExpr{}, fmt.Errorf("parse cron expression %q: expected 5 or 6 fields; got %d", expr, len(fields))
+	}
+
+	bounds := []cronBounds{secondBounds, minuteBounds, hourBounds, domBounds, monthBounds, dowBounds}
+	parsed := make([]cronField, len(fields))
+	for i, field := range fields {
+		f, err := parseCronField(field, bounds[i])
+		if err != nil {
+			return CronExpr{}, fmt.Errorf("parse cron expression %q: %w", expr, err)
+		}
+		parsed[i] = f
+	}
+
+	// 7 is an alias for Sunday
+	dow := parsed[5]
+	if dow.has(7) {
+		dow |= 1
+		dow &^= 1 << 7
+	}
+
+	return CronExpr{
+		expr:   expr,
+		second: parsed[0],
+		minute: parsed[1],
+		hour:   parsed[2],
+		dom:    parsed[3],
+		month:  parsed[4],
+		dow:    dow,
+		anyDom: strings.HasPrefix(fields[3], "*"),
+		anyDow: strings.HasPrefix(fields[5], "*"),
+	}, nil
+}
+
+// String returns the expression as it was passed to ParseCron.
+func (c CronExpr) String() string {
+	return c.expr
+}
+
+// Next returns the first time after t that matches the expression, in the
+// location of t. Next returns the zero Time if no such time exists within the
+// next five years, e.g. for "0 0 30 2 *".
+func (c CronExpr) Next(t time.Time) time.Time {
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	loc := t.Location()
+	limit := t.Year() + 5
+
+	for t.Year() <= limit {
+		if !c.month.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.matchDay(t) {
+			next := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			if !next.After(t) {
+				next = t.Add(time.Hour)
+			}
+			t = next
+			continue
+		}
+
+		if !c.hour.has(t.Hour()) {
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+
+		if !c.minute.has(t.Minute()) {
+			t = t.Add(time.Minute - time.Duration(t.Second())*time.Second)
+			continue
+		}
+
+		if !c.second.has(t.Second()) {
+			t = t.Add(time.Second)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchDay reports whether the day of t matches the expression. If both the
+// day of the month and the day of the week are restricted, a day matches if
+// either of them matches, like in crontab.
+func (c CronExpr) matchDay(t time.Time) bool {
+	dom, dow := c.dom.has(t.Day()), c.dow.has(int(t.Weekday()))
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func (f cronField) has(v int) bool {
+	return f&(1<<uint(v)) != 0
+}
+
+func parseCronField(field string, b cronBounds) (cronField, error) {
+	var f cronField
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", b.name, field)
+			}
+			rng, step = part[:i], s
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*":
+			lo, hi = b.min, b.max
+		case strings.Contains(rng, "-"):
+			i := strings.IndexByte(rng, '-')
+			var err error
+			if lo, err = b.value(rng[:i]); err != nil {
+				return 0, err
+			}
+			if hi, err = b.value(rng[i+1:]); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if lo, err = b.value(rng); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if step > 1 || strings.Contains(part, "/") {
+				hi = b.max
+			}
+		}
+
+		if lo > hi {
+			return 0, fmt.Errorf("invalid range in %s field %q", b.name, field)
+		}
+
+		for v := lo; v <= hi; v += step {
+			f |= 1 << uint(v)
+		}
+	}
+	return f, nil
+}
+
+func (b cronBounds) value(s string) (int, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", b.name, s)
+	}
+
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("%s %d out of range [%d, %d]", b.name, v, b.min, b.max)
+	}
+
+	return v, nil
+}
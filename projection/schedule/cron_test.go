@@ -0,0 +1,140 @@
+package schedule_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/internal/projectiontest"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestCronExpr_Next(t *testing.T) {
+	start := time.Date(2024, time.January, 15, 10, 17, 30, 0, time.UTC) // Monday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 15, 10, 18, 0, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2024, time.January, 15, 10, 20, 0, 0, time.UTC)},
+		{"0 */5 * * *", time.Date(2024, time.January, 15, 15, 0, 0, 0, time.UTC)},
+		{"@midnight", time.Date(2024, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * mon-fri", time.Date(2024, time.January, 16, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.January, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * fri", time.Date(2024, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"15,45 10 * * *", time.Date(2024, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{"*/20 * * * * *", time.Date(2024, time.January, 15, 10, 17, 40, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := schedule.ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron failed with %q", err)
+			}
+
+			if got := expr.Next(start); !got.Equal(tt.want) {
+				t.Fatalf("Next(%v) should return %v; got %v", start, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseCron_invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"foo * * * *",
+	} {
+		if _, err := schedule.ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) should fail", expr)
+		}
+	}
+}
+
+func TestCalendar_Next_location(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	s := schedule.MustCron(eventstore.New(), "0 0 * * *", nil, schedule.InLocation(loc))
+
+	start := time.Date(2024, time.January, 15, 10, 0, 0, 0, time.UTC)
+	want := time.Date(2024, time.January, 15, 22, 0, 0, 0, time.UTC)
+
+	if got := s.Next(start); !got.Equal(want) {
+		t.Fatalf("Next(%v) should return %v; got %v", start, want, got)
+	}
+}
+
+func TestCalendar_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	store := eventstore.New()
+	evt := event.New[any]("foo", test.FooEventData{})
+	if err := store.Insert(ctx, evt); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	s := schedule.MustCron(store, "* * * * * *", []string{"foo"})
+
+	proj := projectiontest.NewMockProjection()
+	applied := make(chan time.Time)
+
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		if err := job.Apply(job, proj); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+		case applied <- time.Now():
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	var times []time.Time
+L:
+	for {
+		select {
+		case err, ok := <-errs:
+			if !ok {
+				break L
+			}
+			t.Fatal(err)
+		case at := <-applied:
+			times = append(times, at)
+		}
+	}
+
+	if len(times) < 2 || len(times) > 3 {
+		t.Fatalf("a Job should have been created every second; got %d Jobs", len(times))
+	}
+
+	for _, at := range times {
+		if ms := at.Nanosecond() / int(time.Millisecond); ms > 500 {
+			t.Fatalf("Jobs should be created at the start of a second; created at %v", at)
+		}
+	}
+
+	if !proj.HasApplied(evt) {
+		t.Fatalf("projection should have applied events")
+	}
+}
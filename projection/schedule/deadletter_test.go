@@ -0,0 +1,156 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestWithDeadLetter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := eventstore.New()
+	events := []event.Event{
+		event.New[any]("foo", test.FooEventData{}).Any(),
+		event.New[any]("foo", test.FooEventData{}).Any(),
+		event.New[any]("bar", test.BarEventData{}).Any(),
+	}
+	if err := store.Insert(ctx, events...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	s := schedule.Continuously(eventbus.New(), store, []string{"foo"})
+
+	mockError := errors.New("mock error")
+	var calls atomic.Int32
+	applied := make(chan []uuid.UUID, 1)
+	dead := make(chan projection.DeadJob, 1)
+
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		if calls.Add(1) <= 2 {
+			return mockError
+		}
+
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+		applied <- eventIDs(events)
+
+		return nil
+	}, projection.RetryPolicy(
+		projection.MaxAttempts(2),
+		projection.Backoff(time.Millisecond, time.Millisecond),
+	), projection.WithDeadLetter(func(_ context.Context, job projection.DeadJob) error {
+		dead <- job
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	var job projection.DeadJob
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("failed job should have been delivered to the dead-letter handler")
+	case job = <-dead:
+	}
+
+	if calls.Load() != 2 {
+		t.Fatalf("job should have been retried before it was dead-lettered; was applied %d times", calls.Load())
+	}
+
+	if !errors.Is(job.Err, mockError) {
+		t.Fatalf("dead job should have error %q; got %q", mockError, job.Err)
+	}
+
+	want := []uuid.UUID{events[0].ID(), events[1].ID()}
+	if got := eventIDs(job.Events); !cmp.Equal(want, got) {
+		t.Fatalf("dead job should have the events of the job\n%s", cmp.Diff(want, got))
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("error of the failed job should have been reported")
+	case err := <-errs:
+		if !errors.Is(err, mockError) {
+			t.Fatalf("reported error should unwrap to %q; got %q", mockError, err)
+		}
+	}
+
+	if err := s.Trigger(ctx, projection.Query(job.Query())); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("dead job should have been replayed")
+	case err := <-errs:
+		t.Fatal(err)
+	case got := <-applied:
+		if !cmp.Equal(want, got) {
+			t.Fatalf("replayed job should have the events of the dead job\n%s", cmp.Diff(want, got))
+		}
+	}
+}
+
+func TestWithDeadLetter_handlerError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := schedule.Continuously(eventbus.New(), eventstore.New(), []string{"foo"})
+
+	mockError := errors.New("mock error")
+	handlerError := errors.New("handler error")
+
+	errs, err := s.Subscribe(ctx, func(projection.Job) error {
+		return mockError
+	}, projection.WithDeadLetter(func(context.Context, projection.DeadJob) error {
+		return handlerError
+	}))
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("timed out")
+	case err := <-errs:
+		if !errors.Is(err, mockError) || !errors.Is(err, handlerError) {
+			t.Fatalf("reported error should unwrap to %q and %q; got %q", mockError, handlerError, err)
+		}
+	}
+}
+
+func eventIDs(events []event.Event) []uuid.UUID {
+	ids := make([]uuid.UUID, len(events))
+	for i, evt := range events {
+		ids[i] = evt.ID()
+	}
+	return ids
+}
@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+)
+
+// Dedupe returns a ContinuousOption that protects projections from events that
+// are delivered multiple times by the event bus. A subscription to the schedule
+// remembers the IDs of the last window events it received and drops received
+// events whose IDs it remembers. Use Dedupe with event buses that deliver
+// events at least once (e.g. NATS JetStream) to protect projections that are
+// not idempotent, without each projection persisting its own inbox:
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Dedupe(10000))
+//
+// Events that are redelivered after more than window other events were
+// received are not detected. Events that are applied by startup jobs and
+// triggered jobs are fetched from the event store and are not deduplicated.
+func Dedupe(window int) ContinuousOption {
+	return func(c *Continuous) {
+		c.dedupeWindow = window
+	}
+}
+
+// dedupe returns a channel that receives the events from the given channel,
+// except for events whose IDs are within the dedupe window of the schedule.
+func (schedule *Continuous) dedupe(ctx context.Context, events <-chan event.Event) <-chan event.Event {
+	if schedule.dedupeWindow <= 0 {
+		return events
+	}
+
+	out := make(chan event.Event)
+
+	go func() {
+		defer close(out)
+
+		seen := make(map[uuid.UUID]struct{}, schedule.dedupeWindow)
+		ring := make([]uuid.UUID, schedule.dedupeWindow)
+		var next int
+
+		for evt := range events {
+			id := evt.ID()
+			if _, ok := seen[id]; ok {
+				continue
+			}
+
+			if len(seen) == len(ring) {
+				delete(seen, ring[next])
+			}
+			seen[id] = struct{}{}
+			ring[next] = id
+			next = (next + 1) % len(ring)
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- evt:
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,73 @@
+package schedule_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestDedupe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Dedupe(2))
+
+	applied := make(chan event.Event)
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		events, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		for evt := range events {
+			applied <- evt
+		}
+		return <-errs
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	a := event.New[any]("foo", test.FooEventData{})
+	b := event.New[any]("foo", test.FooEventData{})
+	c := event.New[any]("foo", test.FooEventData{})
+
+	// a is redelivered within the window and dropped; after b and c, a is
+	// outside of the window and delivered again.
+	published := []event.Event{a, a, b, a, c, a}
+	want := []event.Event{a, b, c, a}
+
+	for _, evt := range published {
+		if err := bus.Publish(ctx, evt); err != nil {
+			t.Fatalf("publish event: %v", err)
+		}
+	}
+
+	for i, w := range want {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("event #%d should have been applied", i+1)
+		case err := <-errs:
+			t.Fatal(err)
+		case evt := <-applied:
+			if evt.ID() != w.ID() {
+				t.Fatalf("event #%d should be %s; got %s", i+1, w.ID(), evt.ID())
+			}
+		}
+	}
+
+	select {
+	case evt := <-applied:
+		t.Fatalf("no more events should be applied; got %s", evt.ID())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,144 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/projection"
+)
+
+// DefaultDrainTimeout is the timeout for persisting the pending trigger of a
+// subscription (see PersistPending) if the DrainTimeout option is not provided.
+const DefaultDrainTimeout = 5 * time.Second
+
+// DrainTimeout returns a ContinuousOption that gracefully drains subscriptions
+// when their context is canceled. By default, events that are buffered by a
+// subscription (e.g. because of the Debounce option) when its context is
+// canceled are dropped. When draining, the buffered events are applied in a
+// final job after the running job has returned, and the error channel of the
+// subscription is closed after the final job has been applied. The final job
+// is canceled if it is not applied within the given timeout.
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"},
+//		schedule.Debounce(time.Second),
+//		schedule.DrainTimeout(10*time.Second),
+//	)
+//
+// Jobs that are queued because of the MaxPendingJobs option are not drained.
+func DrainTimeout(d time.Duration) ContinuousOption {
+	return func(c *Continuous) {
+		c.drainTimeout = d
+	}
+}
+
+// PersistPending returns a ContinuousOption that passes the events that could
+// not be applied when the context of a subscription is canceled to fn, as a
+// trigger whose query selects the events. Events cannot be applied if the
+// DrainTimeout option is not provided, or if the final job of the drain fails.
+// Persist the trigger and trigger the schedule with it on the next startup to
+// apply the events:
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"},
+//		schedule.Debounce(time.Second),
+//		schedule.PersistPending(func(ctx context.Context, t projection.Trigger) error {
+//			return triggers.Save(ctx, t)
+//		}),
+//	)
+//
+//	// on startup
+//	for _, t := range triggers.All() {
+//		err := s.Trigger(ctx, t.Options()...)
+//	}
+//
+// The query of the trigger fetches the events from the event store of the
+// schedule. fn is called with a context that is canceled after the drain
+// timeout, or after DefaultDrainTimeout if DrainTimeout is not provided.
+func PersistPending(fn func(context.Context, projection.Trigger) error) ContinuousOption {
+	return func(c *Continuous) {
+		c.persistPending = fn
+	}
+}
+
+func (schedule *Continuous) draining() bool {
+	return schedule.drainTimeout > 0 || schedule.persistPending != nil
+}
+
+// drain forwards the errors of applied jobs to out until errs is closed. Then,
+// it applies the pending events in a final job and/or persists them as a
+// trigger, and closes out.
+func (schedule *Continuous) drain(
+	ctx context.Context,
+	sub projection.Subscription,
+	apply func(projection.Job) error,
+	errs <-chan error,
+	pending <-chan []event.Event,
+	out chan<- error,
+) {
+	defer close(out)
+
+	for err := range errs {
+		select {
+		case <-ctx.Done():
+		case out <- err:
+		}
+	}
+
+	events := <-pending
+	if len(events) == 0 {
+		return
+	}
+
+	timeout := schedule.drainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+
+	report := func(err error) {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case out <- err:
+		}
+	}
+
+	if schedule.drainTimeout > 0 {
+		jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		err := applyJob(sub, apply, schedule.newJob(
+			jobCtx,
+			sub,
+			eventstore.New(events...),
+			query.New(query.SortBy(event.SortTime, event.SortAsc)),
+		))
+		cancel()
+
+		if err == nil {
+			return
+		}
+
+		report(fmt.Errorf("drain: apply job: %w", err))
+	}
+
+	if schedule.persistPending == nil {
+		return
+	}
+
+	ids := make([]uuid.UUID, len(events))
+	for i, evt := range events {
+		ids[i] = evt.ID()
+	}
+
+	persistCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	if err := schedule.persistPending(persistCtx, projection.NewTrigger(
+		projection.Query(query.New(query.ID(ids...), query.SortByTime())),
+	)); err != nil {
+		report(fmt.Errorf("drain: persist pending trigger: %w [events=%d]", err, len(events)))
+	}
+}
@@ -0,0 +1,138 @@
+package schedule_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestDrainTimeout(t *testing.T) {
+	applied, errs, events := runDrainedSubscription(t, schedule.DrainTimeout(time.Second))
+
+	want := eventIDs(events)
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("buffered events should have been applied in a final job")
+	case got := <-applied:
+		if !cmp.Equal(want, got) {
+			t.Fatalf("final job should have the buffered events\n%s", cmp.Diff(want, got))
+		}
+	}
+
+	assertClosed(t, errs)
+}
+
+func TestPersistPending(t *testing.T) {
+	persisted := make(chan projection.Trigger, 1)
+	store := eventstore.New()
+
+	applied, errs, events := runDrainedSubscription(t, schedule.PersistPending(func(_ context.Context, trigger projection.Trigger) error {
+		persisted <- trigger
+		return nil
+	}))
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("pending trigger should have been persisted")
+	case <-applied:
+		t.Fatalf("buffered events should not have been applied without a drain timeout")
+	case trigger := <-persisted:
+		if err := store.Insert(context.Background(), append(events, event.New[any]("foo", test.FooEventData{}).Any())...); err != nil {
+			t.Fatalf("insert events: %v", err)
+		}
+
+		str, qerrs, err := store.Query(context.Background(), trigger.Query)
+		if err != nil {
+			t.Fatalf("query events: %v", err)
+		}
+		got, err := streams.Drain(context.Background(), str, qerrs)
+		if err != nil {
+			t.Fatalf("query events: %v", err)
+		}
+
+		if want := eventIDs(events); !cmp.Equal(want, eventIDs(got)) {
+			t.Fatalf("query of the trigger should select the buffered events\n%s", cmp.Diff(want, eventIDs(got)))
+		}
+	}
+
+	assertClosed(t, errs)
+}
+
+// runDrainedSubscription subscribes to a debounced Continuous schedule,
+// publishes two events and cancels the subscription while the events are
+// buffered. It returns a channel that receives the IDs of the events of
+// applied jobs, the error channel of the subscription, and the published
+// events.
+func runDrainedSubscription(t *testing.T, opts ...schedule.ContinuousOption) (<-chan []uuid.UUID, <-chan error, []event.Event) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	buffered := make(chan int, 2)
+
+	s := schedule.Continuously(bus, eventstore.New(), []string{"foo"}, append([]schedule.ContinuousOption{
+		schedule.Debounce(time.Hour),
+		schedule.ObserveBuffer(func(stats schedule.BufferStats) { buffered <- stats.Size }),
+	}, opts...)...)
+
+	applied := make(chan []uuid.UUID, 1)
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+		applied <- eventIDs(events)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	events := []event.Event{
+		event.New[any]("foo", test.FooEventData{}).Any(),
+		event.New[any]("foo", test.FooEventData{}).Any(),
+	}
+	if err := bus.Publish(ctx, events...); err != nil {
+		t.Fatalf("publish events: %v", err)
+	}
+
+	for size := 0; size < len(events); {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("events should have been buffered")
+		case size = <-buffered:
+		}
+	}
+
+	return applied, errs, events
+}
+
+func assertClosed(t *testing.T, errs <-chan error) {
+	t.Helper()
+
+	for {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("error channel should have been closed")
+		case err, ok := <-errs:
+			if !ok {
+				return
+			}
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/modernice/goes/projection"
+)
+
+var (
+	// ErrDependencyCycle is returned by a Graph if its projections depend on
+	// each other in a cycle.
+	ErrDependencyCycle = errors.New("dependency cycle")
+
+	// ErrUnknownDependency is returned by a Graph if a projection depends on a
+	// projection that has not been added to the Graph.
+	ErrUnknownDependency = errors.New("unknown dependency")
+
+	// ErrDependencyFailed is returned by a Graph for projections that were not
+	// applied because one of their dependencies failed to apply the job.
+	ErrDependencyFailed = errors.New("dependency failed")
+)
+
+// Graph composes multiple projections into a single subscriber of a schedule
+// and applies each job to the projections in dependency order. Use a Graph if
+// a projection reads from other projections while being applied, e.g. a read
+// model that reads from lookup tables:
+//
+//	g := schedule.NewGraph()
+//	g.Add("lookup", func(job projection.Job) error { return job.Apply(job, lookup) })
+//	g.Add("orders", func(job projection.Job) error { return job.Apply(job, orders) }, schedule.DependsOn("lookup"))
+//
+//	errs, err := s.Subscribe(ctx, g.Apply)
+//
+// Projections without dependencies between them are applied in the order they
+// were added. The events of a job are cached by the job, so the projections of
+// a Graph share a single event query per job.
+//
+// A Graph can subscribe to multiple schedules. Jobs are applied one at a time,
+// so that the ordering also holds for jobs from different schedules within the
+// same process.
+type Graph struct {
+	mux   sync.Mutex
+	nodes map[string]*graphNode
+	names []string
+	order []string
+}
+
+// GraphOption is an option for a projection of a Graph.
+type GraphOption func(*graphNode)
+
+type graphNode struct {
+	name      string
+	apply     func(projection.Job) error
+	dependsOn []string
+}
+
+// DependsOn returns a GraphOption that makes a projection depend on the
+// projections with the given names. The projection is applied after its
+// dependencies, and is skipped if one of its dependencies fails.
+func DependsOn(names ...string) GraphOption {
+	return func(n *graphNode) {
+		n.dependsOn = append(n.dependsOn, names...)
+	}
+}
+
+// NewGraph returns an empty Graph.
+func NewGraph() *Graph {
+	return &Graph{nodes: make(map[string]*graphNode)}
+}
+
+// Add adds a projection with the given name to the Graph. If a projection with
+// the same name already exists, it is replaced.
+func (g *Graph) Add(name string, apply func(projection.Job) error, opts ...GraphOption) {
+	n := &graphNode{name: name, apply: apply}
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	if _, ok := g.nodes[name]; !ok {
+		g.names = append(g.names, name)
+	}
+	g.nodes[name] = n
+	g.order = nil
+}
+
+// Order returns the names of the projections in the order in which they are
+// applied. Order returns an error that unwraps to ErrDependencyCycle or
+// ErrUnknownDependency if the dependencies cannot be resolved.
+func (g *Graph) Order() ([]string, error) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.resolve()
+}
+
+func (g *Graph) resolve() ([]string, error) {
+	if g.order != nil {
+		return g.order, nil
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(g.nodes))
+	order := make([]string, 0, len(g.nodes))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path, name), " -> "))
+		}
+
+		state[name] = visiting
+		for _, dep := range g.nodes[name].dependsOn {
+			if _, ok := g.nodes[dep]; !ok {
+				return fmt.Errorf("%w: %q depends on %q", ErrUnknownDependency, name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+
+		return nil
+	}
+
+	for _, name := range g.names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	g.order = order
+
+	return order, nil
+}
+
+// Apply applies the job to the projections of the Graph in dependency order.
+// If a projection fails to apply the job, the projections that depend on it
+// are skipped, while the other projections are still applied. Apply returns
+// the joined errors of all failed and skipped projections.
+func (g *Graph) Apply(job projection.Job) error {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	order, err := g.resolve()
+	if err != nil {
+		return err
+	}
+
+	failed := make(map[string]bool)
+	var errs []error
+
+	for _, name := range order {
+		n := g.nodes[name]
+
+		var depFailed string
+		for _, dep := range n.dependsOn {
+			if failed[dep] {
+				depFailed = dep
+				break
+			}
+		}
+
+		if depFailed != "" {
+			failed[name] = true
+			errs = append(errs, fmt.Errorf("projection %q: %w: %q", name, ErrDependencyFailed, depFailed))
+			continue
+		}
+
+		if err := n.apply(job); err != nil {
+			failed[name] = true
+			errs = append(errs, fmt.Errorf("projection %q: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,127 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestGraph_Order(t *testing.T) {
+	g := schedule.NewGraph()
+	noop := func(projection.Job) error { return nil }
+
+	g.Add("orders", noop, schedule.DependsOn("customers", "products"))
+	g.Add("products", noop)
+	g.Add("customers", noop, schedule.DependsOn("products"))
+	g.Add("stats", noop)
+
+	order, err := g.Order()
+	if err != nil {
+		t.Fatalf("Order failed with %q", err)
+	}
+
+	if want := []string{"products", "customers", "orders", "stats"}; !slices.Equal(want, order) {
+		t.Fatalf("order should be %v; is %v", want, order)
+	}
+
+	g.Add("products", noop, schedule.DependsOn("orders"))
+	if _, err := g.Order(); !errors.Is(err, schedule.ErrDependencyCycle) {
+		t.Fatalf("Order should fail with %q; got %q", schedule.ErrDependencyCycle, err)
+	}
+
+	g.Add("products", noop, schedule.DependsOn("prices"))
+	if _, err := g.Order(); !errors.Is(err, schedule.ErrUnknownDependency) {
+		t.Fatalf("Order should fail with %q; got %q", schedule.ErrUnknownDependency, err)
+	}
+}
+
+func TestGraph_Apply(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	evt := event.New("foo", test.FooEventData{}).Any()
+	job := projection.NewJob(ctx, eventstore.New(evt), query.New())
+
+	var applied []string
+	apply := func(name string, err error) func(projection.Job) error {
+		return func(projection.Job) error {
+			applied = append(applied, name)
+			return err
+		}
+	}
+
+	mockErr := errors.New("mock error")
+
+	g := schedule.NewGraph()
+	g.Add("dependent", apply("dependent", nil), schedule.DependsOn("lookup"))
+	g.Add("transitive", apply("transitive", nil), schedule.DependsOn("dependent"))
+	g.Add("lookup", apply("lookup", mockErr))
+	g.Add("independent", apply("independent", nil))
+
+	err := g.Apply(job)
+
+	if !errors.Is(err, mockErr) {
+		t.Fatalf("Apply should fail with %q; got %q", mockErr, err)
+	}
+
+	if !errors.Is(err, schedule.ErrDependencyFailed) {
+		t.Fatalf("Apply should fail with %q; got %q", schedule.ErrDependencyFailed, err)
+	}
+
+	if want := []string{"lookup", "independent"}; !slices.Equal(want, applied) {
+		t.Fatalf("applied projections should be %v; got %v", want, applied)
+	}
+}
+
+func TestGraph_subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+	s := schedule.Continuously(bus, store, []string{"foo"})
+
+	applied := make(chan string, 2)
+	g := schedule.NewGraph()
+	g.Add("b", func(projection.Job) error { applied <- "b"; return nil }, schedule.DependsOn("a"))
+	g.Add("a", func(projection.Job) error { applied <- "a"; return nil })
+
+	errs, err := s.Subscribe(ctx, g.Apply)
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+	go func() {
+		for err := range errs {
+			t.Errorf("subscription: %v", err)
+		}
+	}()
+
+	evt := event.New("foo", test.FooEventData{}).Any()
+	if err := store.Insert(ctx, evt); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+	if err := bus.Publish(ctx, evt); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	for _, want := range []string{"a", "b"} {
+		select {
+		case <-time.After(time.Second):
+			t.Fatalf("projection %q was not applied", want)
+		case got := <-applied:
+			if got != want {
+				t.Fatalf("projection %q should be applied next; got %q", want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	stdtime "time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/event/query/time"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+// Interleave returns a ContinuousOption that interleaves the startup job of a
+// subscription with live jobs. By default, the startup job (see
+// projection.Startup) is applied as a single job before the subscription
+// starts to create jobs for published events, so that read models become
+// stale during long rebuilds. When Interleave is used, the startup catch-up is
+// applied in jobs of at most batchSize events, and the events that are
+// published while the subscription catches up are applied between these
+// batches.
+//
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Interleave(1000))
+//	errs, err := s.Subscribe(ctx, apply, projection.Startup())
+//
+// A live job may be applied before older events of the remaining catch-up
+// batches. To not break the order of events per aggregate, a live job contains
+// the events of its aggregates from the time of the last caught-up event, and
+// these events are applied again by later catch-up batches. Interleave is
+// therefore meant for projections that are applied per aggregate and implement
+// projection.ProgressAware, so that already applied events are skipped. A
+// projection that is shared by multiple aggregates may skip older events of
+// other aggregates after a live job has been applied to it.
+//
+// The Reset option of the startup trigger is only applied to the first batch.
+// Manual triggers are not received by a subscription before it has caught up,
+// and subscriptions in standby mode (see Standby) apply their startup job as a
+// single job.
+func Interleave(batchSize int) ContinuousOption {
+	return func(c *Continuous) {
+		c.interleave = batchSize
+	}
+}
+
+// applyInterleavedStartup applies the startup job of a subscription in batches,
+// and applies the events that are received from the event bus between the
+// batches. It returns the event and error channels that must be used by the
+// subscription after the catch-up. Errors of live jobs do not cancel the
+// catch-up and are received from the returned error channel instead.
+func (schedule *Continuous) applyInterleavedStartup(
+	ctx context.Context,
+	cfg projection.Subscription,
+	apply func(projection.Job) error,
+	events <-chan event.Event,
+	errs <-chan error,
+) (<-chan event.Event, <-chan error, error) {
+	live := collectLive(events, errs)
+
+	q := cfg.Startup.Query
+	if q == nil {
+		q = query.New(query.Name(schedule.eventNames...), query.SortByTime())
+	}
+
+	str, qerrs, err := schedule.store.Query(ctx, q)
+	if err != nil {
+		live.stop()
+		return events, errs, fmt.Errorf("query events: %w", err)
+	}
+
+	var (
+		batch    = make([]event.Event, 0, schedule.interleave)
+		cursor   stdtime.Time
+		liveErrs []error
+		reset    = cfg.Startup.Reset
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		startup := *cfg.Startup
+		startup.Reset = reset
+		reset = false
+
+		job := schedule.newJob(
+			ctx,
+			cfg,
+			eventstore.New(batch...),
+			query.New(query.SortByTime()),
+			startup.JobOptions()...,
+		)
+		if err := applyJob(cfg, apply, job); err != nil {
+			return err
+		}
+
+		cursor = batch[len(batch)-1].Time()
+		batch = batch[:0]
+
+		pending, pendingErrs := live.take()
+		liveErrs = append(liveErrs, pendingErrs...)
+		if err := schedule.applyLiveJob(ctx, cfg, apply, cursor, pending); err != nil {
+			liveErrs = append(liveErrs, fmt.Errorf("apply job: %w", err))
+		}
+
+		return nil
+	}
+
+	if err := streams.Walk(ctx, func(evt event.Event) error {
+		if batch = append(batch, evt); len(batch) >= schedule.interleave {
+			return flush()
+		}
+		return nil
+	}, str, qerrs); err != nil {
+		live.stop()
+		return events, errs, err
+	}
+
+	if err := flush(); err != nil {
+		live.stop()
+		return events, errs, err
+	}
+
+	live.stop()
+
+	pending, pendingErrs := live.take()
+	liveErrs = append(liveErrs, pendingErrs...)
+	if err := schedule.applyLiveJob(ctx, cfg, apply, cursor, pending); err != nil {
+		liveErrs = append(liveErrs, fmt.Errorf("apply job: %w", err))
+	}
+
+	if len(liveErrs) > 0 {
+		errs = streams.FanInAll(streams.New(liveErrs), errs)
+	}
+
+	return events, errs, nil
+}
+
+// applyLiveJob applies a job for the given live events. The job also contains
+// the events of the aggregates of the live events that occurred since the
+// given time, so that the aggregates are fully caught up by the job.
+func (schedule *Continuous) applyLiveJob(
+	ctx context.Context,
+	cfg projection.Subscription,
+	apply func(projection.Job) error,
+	since stdtime.Time,
+	events []event.Event,
+) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var refs []event.AggregateRef
+	var jobEvents []event.Event
+
+	for _, evt := range events {
+		if seen[evt.ID()] {
+			continue
+		}
+		seen[evt.ID()] = true
+		jobEvents = append(jobEvents, evt)
+
+		if id, name, _ := evt.Aggregate(); id != uuid.Nil {
+			refs = append(refs, event.AggregateRef{Name: name, ID: id})
+		}
+	}
+
+	if len(refs) > 0 {
+		opts := []query.Option{query.Name(schedule.eventNames...), query.Aggregates(refs...), query.SortByTime()}
+		if !since.IsZero() {
+			opts = append(opts, query.Time(time.Min(since)))
+		}
+
+		str, errs, err := schedule.store.Query(ctx, query.New(opts...))
+		if err != nil {
+			return fmt.Errorf("query events of live aggregates: %w", err)
+		}
+
+		if err := streams.Walk(ctx, func(evt event.Event) error {
+			if !seen[evt.ID()] {
+				seen[evt.ID()] = true
+				jobEvents = append(jobEvents, evt)
+			}
+			return nil
+		}, str, errs); err != nil {
+			return fmt.Errorf("query events of live aggregates: %w", err)
+		}
+	}
+
+	return applyJob(cfg, apply, schedule.newJob(
+		ctx,
+		cfg,
+		eventstore.New(jobEvents...),
+		query.New(query.SortByTime()),
+	))
+}
+
+// liveEvents collects the events and errors of a subscription while it catches
+// up.
+type liveEvents struct {
+	mux    sync.Mutex
+	events []event.Event
+	errs   []error
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func collectLive(events <-chan event.Event, errs <-chan error) *liveEvents {
+	l := &liveEvents{
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(l.stopped)
+		for events != nil || errs != nil {
+			select {
+			case <-l.done:
+				return
+			case evt, ok := <-events:
+				if !ok {
+					events = nil
+					break
+				}
+				l.mux.Lock()
+				l.events = append(l.events, evt)
+				l.mux.Unlock()
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					break
+				}
+				l.mux.Lock()
+				l.errs = append(l.errs, err)
+				l.mux.Unlock()
+			}
+		}
+	}()
+
+	return l
+}
+
+// take returns and removes the collected events and errors.
+func (l *liveEvents) take() ([]event.Event, []error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	events, errs := l.events, l.errs
+	l.events, l.errs = nil, nil
+	return events, errs
+}
+
+// stop stops collecting events. Events that have not been received yet remain
+// in the channels of the subscription.
+func (l *liveEvents) stop() {
+	select {
+	case <-l.done:
+	default:
+		close(l.done)
+	}
+	<-l.stopped
+}
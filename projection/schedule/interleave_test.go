@@ -0,0 +1,133 @@
+package schedule_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestInterleave(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	now := time.Now().Add(-time.Hour)
+	var ids []uuid.UUID
+	var storeEvents []event.Event
+	for i := 0; i < 10; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		storeEvents = append(storeEvents, event.New[any](
+			"foo",
+			test.FooEventData{},
+			event.Aggregate(id, "foo", 1),
+			event.Time(now.Add(time.Duration(i)*time.Second)),
+		))
+	}
+
+	if err := store.Insert(ctx, storeEvents...); err != nil {
+		t.Fatalf("insert events: %v", err)
+	}
+
+	// live event of the last aggregate of the catch-up
+	liveEvent := event.New[any]("foo", test.FooEventData{}, event.Aggregate(ids[9], "foo", 2)).Any()
+
+	sch := schedule.Continuously(bus, store, []string{"foo"}, schedule.Interleave(3))
+
+	var mux sync.Mutex
+	var jobs [][]event.Event
+
+	errs, err := sch.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+
+		mux.Lock()
+		first := len(jobs) == 0
+		jobs = append(jobs, events)
+		mux.Unlock()
+
+		if first {
+			if err := store.Insert(ctx, liveEvent); err != nil {
+				return err
+			}
+			if err := bus.Publish(ctx, liveEvent); err != nil {
+				return err
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		return nil
+	}, projection.Startup())
+	if err != nil {
+		t.Fatalf("Subscribe() failed with %q", err)
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	if len(jobs) < 3 {
+		t.Fatalf("at least 3 jobs should have been applied; got %d", len(jobs))
+	}
+
+	if len(jobs[0]) != 3 {
+		t.Fatalf("first catch-up batch should have 3 events; got %d", len(jobs[0]))
+	}
+
+	liveJob := -1
+	for i, events := range jobs {
+		for _, evt := range events {
+			if evt.ID() == liveEvent.ID() {
+				liveJob = i
+				break
+			}
+		}
+		if liveJob >= 0 {
+			break
+		}
+	}
+
+	if liveJob != 1 {
+		t.Fatalf("live event should have been applied after the first catch-up batch; applied in job %d", liveJob)
+	}
+
+	if len(jobs[1]) != 2 {
+		t.Fatalf("live job should contain the caught-up events of its aggregate; got %d events", len(jobs[1]))
+	}
+
+	if jobs[1][0].ID() != storeEvents[9].ID() || jobs[1][1].ID() != liveEvent.ID() {
+		t.Fatalf("live job should contain the events of aggregate %s sorted by time", ids[9])
+	}
+
+	var caughtUp int
+	for _, events := range jobs[2:] {
+		caughtUp += len(events)
+	}
+	if caughtUp < 7 {
+		t.Fatalf("remaining catch-up batches should contain at least 7 events; got %d", caughtUp)
+	}
+}
@@ -61,6 +61,9 @@ func Periodically(store event.Store, interval time.Duration, eventNames []string
 // will be created and passed to apply.
 func (schedule *Periodic) Subscribe(ctx context.Context, apply func(projection.Job) error, opts ...projection.SubscribeOption) (<-chan error, error) {
 	cfg := projection.NewSubscription(opts...)
+	if err := validateSubscription(cfg); err != nil {
+		return nil, err
+	}
 
 	ticker := time.NewTicker(schedule.interval)
 
@@ -76,7 +79,7 @@ func (schedule *Periodic) Subscribe(ctx context.Context, apply func(projection.J
 	}()
 
 	if cfg.Startup != nil {
-		if err := schedule.applyStartupJob(ctx, cfg, jobs, apply); err != nil {
+		if err := schedule.applyStartupJob(ctx, cfg, apply); err != nil {
 			return nil, fmt.Errorf("startup: %w", err)
 		}
 	}
@@ -86,7 +89,7 @@ func (schedule *Periodic) Subscribe(ctx context.Context, apply func(projection.J
 
 	go schedule.handleTicker(ctx, cfg, ticker, jobs, out, &wg)
 	go schedule.handleTriggers(ctx, cfg, triggers, jobs, out, &wg)
-	go schedule.applyJobs(ctx, apply, jobs, out, done)
+	go schedule.applyJobs(ctx, cfg, apply, jobs, out, done)
 
 	go func() {
 		wg.Wait()
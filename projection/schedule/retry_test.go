@@ -0,0 +1,113 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	mockError := errors.New("mock error")
+
+	calls, errs := runFailingJob(t, 2, mockError, projection.RetryPolicy(
+		projection.Backoff(time.Millisecond, 5*time.Millisecond),
+	))
+
+	if calls != 3 {
+		t.Fatalf("job should have been applied %d times; was %d", 3, calls)
+	}
+
+	if len(errs) != 0 {
+		t.Fatalf("no errors should have been reported; got %v", errs)
+	}
+}
+
+func TestRetryPolicy_maxAttempts(t *testing.T) {
+	mockError := errors.New("mock error")
+
+	calls, errs := runFailingJob(t, 10, mockError, projection.RetryPolicy(
+		projection.MaxAttempts(4),
+		projection.Backoff(time.Millisecond, 5*time.Millisecond),
+	))
+
+	if calls != 4 {
+		t.Fatalf("job should have been applied %d times; was %d", 4, calls)
+	}
+
+	if len(errs) != 1 {
+		t.Fatalf("the last error should have been reported; got %v", errs)
+	}
+
+	if !errors.Is(errs[0], mockError) {
+		t.Fatalf("error should unwrap to %q; got %q", mockError, errs[0])
+	}
+}
+
+func TestRetryPolicy_retryIf(t *testing.T) {
+	mockError := errors.New("mock error")
+
+	calls, errs := runFailingJob(t, 10, mockError, projection.RetryPolicy(
+		projection.Backoff(time.Millisecond, 5*time.Millisecond),
+		projection.RetryIf(func(err error) bool { return !errors.Is(err, mockError) }),
+	))
+
+	if calls != 1 {
+		t.Fatalf("job should not have been retried; was applied %d times", calls)
+	}
+
+	if len(errs) != 1 || !errors.Is(errs[0], mockError) {
+		t.Fatalf("%q error should have been reported; got %v", mockError, errs)
+	}
+}
+
+// runFailingJob triggers a single job whose apply function fails with err for
+// the first failures calls, and returns the number of calls of the apply
+// function and the errors of the subscription.
+func runFailingJob(t *testing.T, failures int, err error, opts ...projection.SubscribeOption) (int, []error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := schedule.Continuously(eventbus.New(), eventstore.New(), []string{"foo"})
+
+	calls := make(chan int, 100)
+	var n int
+	errs, serr := s.Subscribe(ctx, func(job projection.Job) error {
+		n++
+		calls <- n
+		if n <= failures {
+			return err
+		}
+		return nil
+	}, opts...)
+	if serr != nil {
+		t.Fatalf("Subscribe failed with %q", serr)
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	var (
+		count    int
+		reported []error
+	)
+	for {
+		select {
+		case count = <-calls:
+			continue
+		case err := <-errs:
+			reported = append(reported, err)
+			continue
+		case <-time.After(200 * time.Millisecond):
+		}
+		break
+	}
+
+	return count, reported
+}
@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+// Shards is a projection schedule that partitions events by the hash of their
+// aggregate id and runs an independent Continuous schedule per shard. Use
+// Sharded to create a Shards schedule.
+type Shards struct {
+	shards []*Continuous
+}
+
+// Sharded returns a Shards schedule that partitions the events with the given
+// eventNames into numShards shards. Each shard is a Continuous schedule that is
+// created using the given options, and only creates projection jobs for the
+// events of its shard. This includes jobs that fetch their events from the
+// event store, like startup jobs and triggered jobs.
+//
+// Because all events of an aggregate are assigned to the same shard, and each
+// shard applies its jobs one after another, the events of an aggregate are
+// still applied in order, while the shards are applied concurrently. Events
+// that do not belong to an aggregate are all assigned to the same shard.
+//
+// Each shard must apply its jobs to its own projections: either a separate
+// projection per shard, or projections per aggregate (e.g. fetched from a
+// projection repository within the job). A projection.ProgressAware projection
+// that is shared by multiple shards skips events, because its progress is
+// advanced by the jobs of every shard, and the shards do not wait for each
+// other (see Interleave for the same caveat):
+//
+//	s := schedule.Sharded(bus, store, []string{"foo", "bar"}, 4, schedule.Debounce(100*time.Millisecond))
+//	projections := make([]*FooProjection, s.Len())
+//	errs, err := s.SubscribeShard(ctx, func(shard int) func(projection.Job) error {
+//		projections[shard] = NewFooProjection()
+//		return func(job projection.Job) error {
+//			return job.Apply(job, projections[shard])
+//		}
+//	}, nil)
+//	for shard, errs := range errs {
+//		go logErrors(shard, errs)
+//	}
+//
+// Every shard subscribes to the event bus on its own and drops the events of
+// other shards. Sharding therefore scales the application of projection jobs,
+// not the delivery of events by the event bus. A numShards < 1 is treated as 1.
+func Sharded(bus event.Bus, store event.Store, eventNames []string, numShards int, opts ...ContinuousOption) *Shards {
+	if numShards < 1 {
+		numShards = 1
+	}
+
+	s := &Shards{shards: make([]*Continuous, numShards)}
+	for i := range s.shards {
+		// Events of other shards are dropped before they are transformed by
+		// the SubscribeWith options of the caller.
+		filter := SubscribeWith(event.Map(func(_ context.Context, evt event.Event) (event.Event, error) {
+			if ShardOf(pick.AggregateID(evt), numShards) != i {
+				return nil, nil
+			}
+			return evt, nil
+		}))
+
+		s.shards[i] = Continuously(
+			bus,
+			shardStore{Store: store, shard: i, numShards: numShards},
+			eventNames,
+			append([]ContinuousOption{filter}, opts...)...,
+		)
+	}
+
+	return s
+}
+
+// ShardOf returns the shard of the given aggregate id, given numShards shards.
+func ShardOf(aggregateID uuid.UUID, numShards int) int {
+	if numShards <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write(aggregateID[:])
+	return int(h.Sum32() % uint32(numShards))
+}
+
+// Len returns the number of shards.
+func (s *Shards) Len() int {
+	return len(s.shards)
+}
+
+// Shard returns the Continuous schedule of the given shard.
+func (s *Shards) Shard(i int) *Continuous {
+	return s.shards[i]
+}
+
+// Subscribe subscribes the given apply function to every shard of the schedule
+// and returns the channels of asynchronous projection errors, one per shard,
+// indexed by shard. The apply function is shared by all shards and called
+// concurrently for jobs of different shards, so it must be safe for concurrent
+// use and must not apply the jobs to a projection that is shared by the shards
+// (see Sharded); use SubscribeShard to apply each shard to its own projection.
+// If subscribing to any shard fails, the subscriptions to the other shards are
+// canceled and the error is returned. When ctx is canceled, all subscriptions
+// are canceled and the returned error channels closed.
+//
+// The options are applied to the subscriptions of all shards. Options that
+// identify a subscription (e.g. projection.Checkpoints) must therefore be
+// provided per shard using SubscribeShard.
+func (s *Shards) Subscribe(ctx context.Context, apply func(projection.Job) error, opts ...projection.SubscribeOption) ([]<-chan error, error) {
+	return s.SubscribeShard(ctx, func(int) func(projection.Job) error { return apply }, func(int) []projection.SubscribeOption {
+		return opts
+	})
+}
+
+// SubscribeShard is like Subscribe, but calls apply and opts with the index of
+// each shard to get the apply function and subscription options of the shard.
+// opts may be nil:
+//
+//	errs, err := s.SubscribeShard(ctx, func(shard int) func(projection.Job) error {
+//		return applyFoo
+//	}, func(shard int) []projection.SubscribeOption {
+//		return []projection.SubscribeOption{
+//			projection.Checkpoints(checkpoints, fmt.Sprintf("foo-%d", shard)),
+//		}
+//	})
+func (s *Shards) SubscribeShard(
+	ctx context.Context,
+	apply func(shard int) func(projection.Job) error,
+	opts func(shard int) []projection.SubscribeOption,
+) (_ []<-chan error, err error) {
+	// If subscribing to a shard fails, the subscriptions to the other shards
+	// are canceled. Otherwise, they are canceled together with the parent ctx.
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
+
+	out := make([]<-chan error, len(s.shards))
+	for i, shard := range s.shards {
+		var subOpts []projection.SubscribeOption
+		if opts != nil {
+			subOpts = opts(i)
+		}
+
+		errs, err := shard.Subscribe(ctx, apply(i), subOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("subscribe to shard: %w [shard=%d]", err, i)
+		}
+		out[i] = errs
+	}
+
+	return out, nil
+}
+
+// Trigger triggers every shard of the schedule.
+func (s *Shards) Trigger(ctx context.Context, opts ...projection.TriggerOption) error {
+	for i, shard := range s.shards {
+		if err := shard.Trigger(ctx, opts...); err != nil {
+			return fmt.Errorf("trigger shard: %w [shard=%d]", err, i)
+		}
+	}
+	return nil
+}
+
+// shardStore is an event store that only returns the events of a single shard
+// from queries.
+type shardStore struct {
+	event.Store
+
+	shard     int
+	numShards int
+}
+
+func (s shardStore) Query(ctx context.Context, q event.Query) (<-chan event.Event, <-chan error, error) {
+	events, errs, err := s.Store.Query(ctx, q)
+	if err != nil {
+		return events, errs, err
+	}
+	return streams.Filter(events, func(evt event.Event) bool {
+		return ShardOf(pick.AggregateID(evt), s.numShards) == s.shard
+	}), errs, nil
+}
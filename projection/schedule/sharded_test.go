@@ -0,0 +1,178 @@
+package schedule_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/pick"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestSharded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	const numShards = 4
+	s := schedule.Sharded(bus, store, []string{"foo"}, numShards)
+
+	if s.Len() != numShards {
+		t.Fatalf("schedule should have %d shards; got %d", numShards, s.Len())
+	}
+
+	var mux sync.Mutex
+	applied := make(map[uuid.UUID][]int)
+	shards := make(map[uuid.UUID]map[int]struct{})
+	var count int
+
+	errs, err := s.SubscribeShard(ctx, func(shard int) func(projection.Job) error {
+		return func(job projection.Job) error {
+			str, errs, err := job.Events(job)
+			if err != nil {
+				return err
+			}
+			events, err := streams.Drain(job, str, errs)
+			if err != nil {
+				return err
+			}
+
+			mux.Lock()
+			defer mux.Unlock()
+			for _, evt := range events {
+				id := pick.AggregateID(evt)
+				applied[id] = append(applied[id], pick.AggregateVersion(evt))
+				if shards[id] == nil {
+					shards[id] = make(map[int]struct{})
+				}
+				shards[id][shard] = struct{}{}
+				count++
+			}
+			return nil
+		}
+	}, nil)
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if len(errs) != numShards {
+		t.Fatalf("Subscribe should return %d error channels; got %d", numShards, len(errs))
+	}
+
+	for _, errs := range errs {
+		go func(errs <-chan error) {
+			for err := range errs {
+				t.Errorf("async error: %v", err)
+			}
+		}(errs)
+	}
+
+	ids := make([]uuid.UUID, 20)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	const versions = 3
+	for v := 1; v <= versions; v++ {
+		for _, id := range ids {
+			evt := event.New("foo", test.FooEventData{}, event.Aggregate(id, "foo", v))
+			if err := bus.Publish(ctx, evt.Any()); err != nil {
+				t.Fatalf("publish event: %v", err)
+			}
+		}
+	}
+
+	deadline := time.After(3 * time.Second)
+	for {
+		mux.Lock()
+		done := count >= len(ids)*versions
+		mux.Unlock()
+		if done {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("all events should be applied; applied %d of %d", count, len(ids)*versions)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	if count != len(ids)*versions {
+		t.Fatalf("every event should be applied once; applied %d events", count)
+	}
+
+	for _, id := range ids {
+		if len(shards[id]) != 1 {
+			t.Fatalf("events of an aggregate should be applied by a single shard; applied by %v", shards[id])
+		}
+		if _, ok := shards[id][schedule.ShardOf(id, numShards)]; !ok {
+			t.Fatalf("events of aggregate %s should be applied by shard %d; applied by %v", id, schedule.ShardOf(id, numShards), shards[id])
+		}
+		for i, v := range applied[id] {
+			if v != i+1 {
+				t.Fatalf("events of an aggregate should be applied in order; got versions %v", applied[id])
+			}
+		}
+	}
+}
+
+func TestSharded_Startup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var events []event.Event
+	for i := 0; i < 20; i++ {
+		events = append(events, event.New("foo", test.FooEventData{}, event.Aggregate(uuid.New(), "foo", 1)).Any())
+	}
+
+	bus := eventbus.New()
+	store := eventstore.New(events...)
+
+	const numShards = 3
+	s := schedule.Sharded(bus, store, []string{"foo"}, numShards)
+
+	var mux sync.Mutex
+	var count int
+
+	if _, err := s.SubscribeShard(ctx, func(shard int) func(projection.Job) error {
+		return func(job projection.Job) error {
+			str, errs, err := job.Events(job)
+			if err != nil {
+				return err
+			}
+			return streams.Walk(job, func(evt event.Event) error {
+				if got := schedule.ShardOf(pick.AggregateID(evt), numShards); got != shard {
+					t.Errorf("shard %d should only apply its own events; got event of shard %d", shard, got)
+				}
+				mux.Lock()
+				count++
+				mux.Unlock()
+				return nil
+			}, str, errs)
+		}
+	}, func(int) []projection.SubscribeOption {
+		return []projection.SubscribeOption{projection.Startup()}
+	}); err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	if count != len(events) {
+		t.Fatalf("startup jobs should apply every event once; applied %d of %d", count, len(events))
+	}
+}
@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
+	"github.com/modernice/goes/projection"
+)
+
+// DefaultStandbySize is the number of events that are buffered by a
+// subscription in standby mode if the Standby option is given a size <= 0.
+const DefaultStandbySize = 1000
+
+// Standby returns a ContinuousOption that starts subscriptions to the schedule
+// in warm standby mode. Use Standby for instances that are not the leader of a
+// projection, so that they can take over immediately when the leader fails.
+//
+// A subscription in standby mode subscribes to the event bus immediately, but
+// does not create projection jobs. Instead, it buffers the most recent events,
+// up to the given size, until the elected channel is closed. When the elected
+// channel is closed, the subscription applies a single job with the buffered
+// events and then continues like a normal subscription. The job of the buffered
+// events replaces the startup job of the subscription, so that a promoted
+// instance does not have to query the event store before it starts projecting.
+// Only if no events were buffered, the startup job (if configured) is applied
+// instead.
+//
+// The buffered events may contain events that have already been applied by the
+// previous leader, so projections should implement projection.ProgressAware
+// (e.g. by embedding *projection.Progressor) to skip them. The size of the
+// buffer bounds the staleness of read models after a failover: events that
+// were dropped from the buffer are not applied by the promoted instance.
+//
+// Manual triggers of the schedule are ignored by a subscription until it is
+// promoted.
+//
+//	var leader <-chan struct{} // closed when this instance becomes the leader
+//	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Standby(leader, 500))
+func Standby(elected <-chan struct{}, size int) ContinuousOption {
+	if size <= 0 {
+		size = DefaultStandbySize
+	}
+	return func(c *Continuous) {
+		c.elected = elected
+		c.standbySize = size
+	}
+}
+
+func (schedule *Continuous) standby(
+	ctx context.Context,
+	cfg projection.Subscription,
+	apply func(projection.Job) error,
+	events <-chan event.Event,
+	errs <-chan error,
+	out chan error,
+) {
+	var buf []event.Event
+
+L:
+	for {
+		select {
+		case <-ctx.Done():
+			close(out)
+			return
+		case <-schedule.elected:
+			break L
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				break
+			}
+			select {
+			case <-ctx.Done():
+			case out <- err:
+			}
+		case evt, ok := <-events:
+			if !ok {
+				events = nil
+				break
+			}
+			if buf = append(buf, evt); len(buf) > schedule.standbySize {
+				buf = buf[len(buf)-schedule.standbySize:]
+			}
+		}
+	}
+
+	if err := schedule.promote(ctx, cfg, apply, buf); err != nil {
+		select {
+		case <-ctx.Done():
+			close(out)
+			return
+		case out <- err:
+		}
+	}
+
+	schedule.run(ctx, cfg, apply, events, errs, out)
+}
+
+func (schedule *Continuous) promote(ctx context.Context, cfg projection.Subscription, apply func(projection.Job) error, buf []event.Event) error {
+	if len(buf) == 0 {
+		if err := schedule.applyStartupJob(ctx, cfg, apply); err != nil {
+			return fmt.Errorf("startup: %w", err)
+		}
+		return nil
+	}
+
+	job := schedule.newJob(
+		ctx,
+		cfg,
+		eventstore.New(buf...),
+		query.New(query.SortBy(event.SortTime, event.SortAsc)),
+	)
+
+	if err := applyJob(cfg, apply, job); err != nil {
+		return fmt.Errorf("apply standby job: %w", err)
+	}
+
+	return nil
+}
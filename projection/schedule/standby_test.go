@@ -0,0 +1,144 @@
+package schedule_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestStandby(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	storeEvent := event.New[any]("foo", test.FooEventData{})
+	if err := store.Insert(ctx, storeEvent); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	elected := make(chan struct{})
+	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Standby(elected, 2))
+
+	applied := make(chan []event.Event)
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+		applied <- events
+		return nil
+	}, projection.Startup())
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	events := []event.Event{
+		event.New[any]("foo", test.FooEventData{}),
+		event.New[any]("foo", test.FooEventData{}),
+		event.New[any]("foo", test.FooEventData{}),
+	}
+
+	if err := bus.Publish(ctx, events...); err != nil {
+		t.Fatalf("publish events: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	case <-applied:
+		t.Fatalf("no job should be applied in standby mode")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(elected)
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timed out. standby job not applied?")
+	case err := <-errs:
+		t.Fatal(err)
+	case got := <-applied:
+		if len(got) != 2 {
+			t.Fatalf("standby job should have the last %d buffered events; got %d", 2, len(got))
+		}
+		if got[0].ID() != events[1].ID() || got[1].ID() != events[2].ID() {
+			t.Fatalf("standby job should have the most recent events")
+		}
+	}
+
+	evt := event.New[any]("foo", test.FooEventData{})
+	if err := bus.Publish(ctx, evt); err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timed out. promoted subscription should create jobs for published events")
+	case err := <-errs:
+		t.Fatal(err)
+	case got := <-applied:
+		if len(got) != 1 || got[0].ID() != evt.ID() {
+			t.Fatalf("job should have the published event")
+		}
+	}
+}
+
+func TestStandby_startup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	store := eventstore.New()
+
+	storeEvent := event.New[any]("foo", test.FooEventData{})
+	if err := store.Insert(ctx, storeEvent); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	elected := make(chan struct{})
+	close(elected)
+
+	s := schedule.Continuously(bus, store, []string{"foo"}, schedule.Standby(elected, 10))
+
+	applied := make(chan []event.Event)
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+		applied <- events
+		return nil
+	}, projection.Startup())
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timed out. startup job not applied?")
+	case err := <-errs:
+		t.Fatal(err)
+	case got := <-applied:
+		if len(got) != 1 || got[0].ID() != storeEvent.ID() {
+			t.Fatalf("startup job should be applied if no events were buffered")
+		}
+	}
+}
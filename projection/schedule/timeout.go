@@ -0,0 +1,72 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modernice/goes/projection"
+)
+
+// DefaultJobTimeoutGrace is the default duration that a schedule waits for a
+// timed out job to return before it releases the next job.
+const DefaultJobTimeoutGrace = 5 * time.Second
+
+// JobTimeoutError is returned by a schedule if a projection job did not finish
+// within the timeout that was configured using the JobTimeout option.
+type JobTimeoutError struct {
+	// Timeout is the configured job timeout.
+	Timeout time.Duration
+
+	// Abandoned reports whether the apply function of the job was still running
+	// when the grace period after the timeout elapsed.
+	Abandoned bool
+}
+
+// JobTimeout returns a SubscribeOption that limits the duration of each
+// projection job of the subscription. When a job exceeds the timeout, the
+// job's context is canceled and a *JobTimeoutError is sent into the error
+// channel of the subscription, unless the apply function returns nil or an
+// error that is not caused by the canceled context. Startup jobs are limited as
+// well.
+//
+// After the timeout, the schedule waits for the apply function of the timed
+// out job to return for a grace period (see JobTimeoutGrace), so that a job
+// that respects the cancellation of its context never applies events to a
+// projection concurrently with the next job. If the apply function ignores the
+// cancellation and does not return within the grace period, the job is
+// abandoned: the *JobTimeoutError is reported with Abandoned set, the next job
+// is released, and the apply function keeps running in the background until it
+// returns. The apply function should therefore respect the cancellation of
+// the job's context:
+//
+//	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+//		return job.Apply(job, proj)
+//	}, schedule.JobTimeout(30*time.Second))
+func JobTimeout(d time.Duration) projection.SubscribeOption {
+	return func(s *projection.Subscription) {
+		s.JobTimeout = d
+	}
+}
+
+// JobTimeoutGrace returns a SubscribeOption that configures how long a schedule
+// waits for a job that exceeded its JobTimeout to return before the job is
+// abandoned and the next job is released. Defaults to DefaultJobTimeoutGrace.
+func JobTimeoutGrace(d time.Duration) projection.SubscribeOption {
+	return func(s *projection.Subscription) {
+		s.JobTimeoutGrace = d
+	}
+}
+
+// Error implements error.
+func (err *JobTimeoutError) Error() string {
+	if err.Abandoned {
+		return fmt.Sprintf("projection job timed out after %v and was abandoned", err.Timeout)
+	}
+	return fmt.Sprintf("projection job timed out after %v", err.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (err *JobTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
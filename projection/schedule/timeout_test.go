@@ -0,0 +1,195 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestJobTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := schedule.Continuously(eventbus.New(), eventstore.New(), []string{"foo"})
+
+	release := make(chan struct{})
+	canceled := make(chan struct{})
+	returned := make(chan struct{})
+	applied := make(chan struct{})
+
+	var calls atomic.Int32
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		if calls.Add(1) > 1 {
+			select {
+			case <-returned:
+			default:
+				t.Errorf("next job should not be applied before the timed out job returned")
+			}
+			close(applied)
+			return nil
+		}
+
+		<-job.Done()
+		close(canceled)
+		<-release // finish the in-flight apply after the cancellation
+		close(returned)
+
+		return job.Err()
+	}, schedule.JobTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("job context should have been canceled")
+	case <-canceled:
+	}
+
+	select {
+	case <-time.After(50 * time.Millisecond):
+	case err := <-errs:
+		t.Fatalf("timeout should not be reported before the job returned; got %v", err)
+	}
+
+	close(release)
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("timed out")
+	case err := <-errs:
+		var timeoutErr *schedule.JobTimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("expected %T error; got %v", timeoutErr, err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("error should unwrap to %q", context.DeadlineExceeded)
+		}
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("schedule should apply the next job after the timed out job returned")
+	case err := <-errs:
+		t.Fatal(err)
+	case <-applied:
+	}
+}
+
+func TestJobTimeout_finishedAtDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := schedule.Continuously(eventbus.New(), eventstore.New(), []string{"foo"})
+
+	errMock := errors.New("mock error")
+	results := make(chan error, 2)
+	results <- nil
+	results <- errMock
+
+	applied := make(chan struct{}, 2)
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		defer func() { applied <- struct{}{} }()
+		// finish right at the deadline, ignoring the canceled context
+		<-job.Done()
+		return <-results
+	}, schedule.JobTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("job should be applied")
+	case <-applied:
+	}
+
+	select {
+	case err := <-errs:
+		t.Fatalf("job that finished at the deadline should not fail; got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("timed out")
+	case err := <-errs:
+		if !errors.Is(err, errMock) {
+			t.Fatalf("error of the job should not be replaced by a timeout; got %v", err)
+		}
+	}
+}
+
+func TestJobTimeoutGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := schedule.Continuously(eventbus.New(), eventstore.New(), []string{"foo"})
+
+	release := make(chan struct{})
+	defer close(release)
+	applied := make(chan struct{})
+
+	var calls atomic.Int32
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		if calls.Add(1) > 1 {
+			close(applied)
+			return nil
+		}
+		// ignore the canceled context
+		<-release
+		return nil
+	}, schedule.JobTimeout(20*time.Millisecond), schedule.JobTimeoutGrace(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("hung job should be abandoned after the grace period")
+	case err := <-errs:
+		var timeoutErr *schedule.JobTimeoutError
+		if !errors.As(err, &timeoutErr) || !timeoutErr.Abandoned {
+			t.Fatalf("expected an abandoned %T error; got %v", timeoutErr, err)
+		}
+	}
+
+	if err := s.Trigger(ctx); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("schedule should apply the next job after the hung job was abandoned")
+	case err := <-errs:
+		t.Fatal(err)
+	case <-applied:
+	}
+}
@@ -0,0 +1,127 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+)
+
+// Workers returns a SubscribeOption that applies up to n projection jobs of the
+// subscription concurrently. Jobs that touch the same aggregate (i.e., that have
+// events of an aggregate with the same ID) are still applied in the order in
+// which they were created, so the apply function must only be safe for
+// concurrent use across different aggregates:
+//
+//	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+//		return job.Apply(job, proj)
+//	}, schedule.Workers(8))
+//
+// To determine the aggregates of a job, the events of the job are queried
+// before the job is applied. Jobs without aggregate events may be applied
+// concurrently to any other job. Workers cannot be combined with
+// projection.Checkpoints (see ErrConcurrentCheckpoints).
+func Workers(n int) projection.SubscribeOption {
+	return func(s *projection.Subscription) {
+		s.Workers = n
+	}
+}
+
+// applyJobsConcurrently applies the given jobs using sub.Workers workers. A job
+// waits for the previous jobs of its aggregates to finish before it is applied.
+func applyJobsConcurrently(
+	ctx context.Context,
+	sub projection.Subscription,
+	apply func(projection.Job) error,
+	jobs <-chan projection.Job,
+	out chan<- error,
+) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	fail := func(err error) {
+		select {
+		case <-ctx.Done():
+		case out <- err:
+		}
+	}
+
+	workers := make(chan struct{}, sub.Workers)
+
+	// last holds the done channel of the last job of each aggregate.
+	var mux sync.Mutex
+	last := make(map[uuid.UUID]chan struct{})
+
+	for job := range jobs {
+		ids, err := aggregateIDs(job)
+		if err != nil {
+			fail(fmt.Errorf("apply job: extract aggregates: %w", err))
+			continue
+		}
+
+		workers <- struct{}{}
+
+		done := make(chan struct{})
+		var wait []chan struct{}
+
+		mux.Lock()
+		for _, id := range ids {
+			if prev, ok := last[id]; ok {
+				wait = append(wait, prev)
+			}
+			last[id] = done
+		}
+		mux.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			defer func() {
+				mux.Lock()
+				defer mux.Unlock()
+				for _, id := range ids {
+					if last[id] == done {
+						delete(last, id)
+					}
+				}
+				close(done)
+			}()
+
+			for _, prev := range wait {
+				<-prev
+			}
+
+			if err := applyJob(sub, apply, job); err != nil {
+				fail(fmt.Errorf("apply job: %w", err))
+			}
+		}()
+	}
+}
+
+// aggregateIDs returns the distinct ids of the aggregates of the job's events.
+func aggregateIDs(job projection.Job) ([]uuid.UUID, error) {
+	str, errs, err := job.Aggregates(job)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := streams.Drain(job, str, errs)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]bool, len(refs))
+	ids := make([]uuid.UUID, 0, len(refs))
+	for _, ref := range refs {
+		if !seen[ref.ID] {
+			seen[ref.ID] = true
+			ids = append(ids, ref.ID)
+		}
+	}
+
+	return ids, nil
+}
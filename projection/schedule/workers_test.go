@@ -0,0 +1,133 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/modernice/goes/event"
+	"github.com/modernice/goes/event/eventbus"
+	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/test"
+	"github.com/modernice/goes/helper/streams"
+	"github.com/modernice/goes/projection"
+	"github.com/modernice/goes/projection/schedule"
+)
+
+func TestWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	s := schedule.Continuously(bus, eventstore.New(), []string{"foo"})
+
+	a, b := uuid.New(), uuid.New()
+	first := event.New[any]("foo", test.FooEventData{}, event.Aggregate(a, "foo", 1)).Any()
+	other := event.New[any]("foo", test.FooEventData{}, event.Aggregate(b, "foo", 1)).Any()
+	second := event.New[any]("foo", test.FooEventData{}, event.Aggregate(a, "foo", 2)).Any()
+
+	otherStarted := make(chan struct{})
+	var firstDone atomic.Bool
+	applied := make(chan uuid.UUID, 3)
+
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		str, errs, err := job.Events(job)
+		if err != nil {
+			return err
+		}
+		events, err := streams.Drain(job, str, errs)
+		if err != nil {
+			return err
+		}
+
+		switch id := events[0].ID(); id {
+		case first.ID():
+			select {
+			case <-time.After(time.Second):
+				return errors.New("jobs of different aggregates should be applied concurrently")
+			case <-otherStarted:
+			}
+			time.Sleep(50 * time.Millisecond)
+			firstDone.Store(true)
+		case other.ID():
+			close(otherStarted)
+		case second.ID():
+			if !firstDone.Load() {
+				return errors.New("jobs of the same aggregate should be applied in order")
+			}
+		}
+
+		applied <- events[0].ID()
+
+		return nil
+	}, schedule.Workers(2))
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	if err := bus.Publish(ctx, first, other, second); err != nil {
+		t.Fatalf("publish events: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out")
+		case err := <-errs:
+			t.Fatal(err)
+		case <-applied:
+		}
+	}
+}
+
+func TestWorkers_maxConcurrency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := eventbus.New()
+	s := schedule.Continuously(bus, eventstore.New(), []string{"foo"})
+
+	var running, maxRunning atomic.Int32
+	applied := make(chan struct{}, 10)
+
+	errs, err := s.Subscribe(ctx, func(job projection.Job) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			max := maxRunning.Load()
+			if n <= max || maxRunning.CompareAndSwap(max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		applied <- struct{}{}
+		return nil
+	}, schedule.Workers(3))
+	if err != nil {
+		t.Fatalf("Subscribe failed with %q", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		evt := event.New[any]("foo", test.FooEventData{}, event.Aggregate(uuid.New(), "foo", 1)).Any()
+		if err := bus.Publish(ctx, evt); err != nil {
+			t.Fatalf("publish event: %v", err)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		select {
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out")
+		case err := <-errs:
+			t.Fatal(err)
+		case <-applied:
+		}
+	}
+
+	if max := maxRunning.Load(); max != 3 {
+		t.Fatalf("up to %d jobs should have been applied concurrently; got %d", 3, max)
+	}
+}
@@ -35,6 +35,15 @@ type TriggeredData struct {
 	TriggerID uuid.UUID
 	Trigger   Trigger
 	Schedule  string
+
+	// Principal is the principal that signed the trigger. (optional)
+	Principal string
+
+	// IssuedAt is the time at which the trigger was signed. (optional)
+	IssuedAt time.Time
+
+	// Signature is the signature of the trigger. (optional)
+	Signature []byte
 }
 
 // TriggerAcceptedData is the event data for accepting a trigger.
@@ -48,6 +57,13 @@ type TriggerAcceptedData struct {
 type Service struct {
 	bus            event.Bus
 	triggerTimeout time.Duration
+	credentials    Credentials
+	auth           Authenticator
+	principals     map[string]bool
+	maxTriggerAge  time.Duration
+
+	seenMux sync.Mutex
+	seen    map[uuid.UUID]time.Time
 
 	schedulesMux sync.RWMutex
 	schedules    map[string]Schedule
@@ -123,6 +139,7 @@ type Schedule interface {
 func RegisterService(r codec.Registerer) {
 	codec.Register[TriggeredData](r, Triggered)
 	codec.Register[TriggerAcceptedData](r, TriggerAccepted)
+	codec.Register[TriggerRejectedData](r, TriggerRejected)
 }
 
 // ServiceOption is an option for creating a Service.
@@ -160,6 +177,7 @@ func NewService(bus event.Bus, opts ...ServiceOption) *Service {
 	svc := Service{
 		bus:            bus,
 		triggerTimeout: DefaultTriggerTimeout,
+		maxTriggerAge:  DefaultMaxTriggerAge,
 		schedules:      make(map[string]Schedule),
 	}
 	for _, opt := range opts {
@@ -181,18 +199,25 @@ func (svc *Service) Register(name string, s Schedule) {
 // TriggerAccepted event to be published by another Service. Should the
 // TriggerAccepted event not be published within the trigger timeout,
 // ErrUnhandledTrigger is returned. When ctx is canceled, ctx.Err() is returned.
+// If the Service that runs the Schedule rejects the trigger (see
+// Authenticate), an error that unwraps to ErrUnauthorizedTrigger is returned.
 func (svc *Service) Trigger(ctx context.Context, name string, opts ...TriggerOption) error {
-	events, errs, err := svc.bus.Subscribe(ctx, TriggerAccepted)
+	events, errs, err := svc.bus.Subscribe(ctx, TriggerAccepted, TriggerRejected)
 	if err != nil {
-		return fmt.Errorf("subscribe to %q event: %w", TriggerAccepted, err)
+		return fmt.Errorf("subscribe to %q and %q events: %w", TriggerAccepted, TriggerRejected, err)
 	}
 
 	id := uuid.New()
-	evt := event.New[any](Triggered, TriggeredData{
+	data := TriggeredData{
 		TriggerID: id,
 		Trigger:   NewTrigger(opts...),
 		Schedule:  name,
-	})
+	}
+	if err := svc.sign(&data); err != nil {
+		return err
+	}
+
+	evt := event.New[any](Triggered, data)
 	if err := svc.bus.Publish(ctx, evt); err != nil {
 		return fmt.Errorf("publish %q event: %w", evt.Name(), err)
 	}
@@ -205,12 +230,21 @@ func (svc *Service) Trigger(ctx context.Context, name string, opts ...TriggerOpt
 
 	done := errors.New("done")
 	if err := streams.Walk(ctx, func(evt event.Event) error {
-		data := evt.Data().(TriggerAcceptedData)
-		if data.TriggerID != id {
-			return nil
+		switch data := evt.Data().(type) {
+		case TriggerAcceptedData:
+			if data.TriggerID == id {
+				return done
+			}
+		case TriggerRejectedData:
+			if data.TriggerID == id {
+				return fmt.Errorf("%w: %s", ErrUnauthorizedTrigger, data.Reason)
+			}
 		}
-		return done
+		return nil
 	}, events, errs); !errors.Is(err, done) {
+		if errors.Is(err, ErrUnauthorizedTrigger) {
+			return err
+		}
 		if errors.Is(err, context.DeadlineExceeded) {
 			return ErrUnhandledTrigger
 		}
@@ -224,7 +258,9 @@ func (svc *Service) Trigger(ctx context.Context, name string, opts ...TriggerOpt
 // asynchronous errors, or a single error if the event bus fails to subscribe.
 // When another Service triggers a Schedule with a name that is registered in
 // svc, svc accepts that trigger by publishing a TriggerAccepted event and then
-// actually triggers the Schedule.
+// actually triggers the Schedule. Triggers that are not authorized (see
+// Authenticate and AllowPrincipals) are rejected by publishing a
+// TriggerRejected event.
 func (svc *Service) Run(ctx context.Context) (<-chan error, error) {
 	events, errs, err := svc.bus.Subscribe(ctx, Triggered)
 	if err != nil {
@@ -255,6 +291,14 @@ func (svc *Service) handleEvents(ctx context.Context, events <-chan event.Event,
 			return
 		}
 
+		if err := svc.authorize(data); err != nil {
+			if err := svc.reject(ctx, data, err); err != nil {
+				fail(err)
+			}
+			fail(fmt.Errorf("reject trigger of %q schedule: %w: %w", data.Schedule, ErrUnauthorizedTrigger, err))
+			return
+		}
+
 		evt = event.New[any](TriggerAccepted, TriggerAcceptedData{TriggerID: data.TriggerID})
 		if err := svc.bus.Publish(ctx, evt); err != nil {
 			fail(fmt.Errorf("publish %q event: %w", evt.Name(), err))
@@ -3,11 +3,13 @@ package projection_test
 import (
 	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/modernice/goes/event/eventbus"
 	"github.com/modernice/goes/event/eventstore"
+	"github.com/modernice/goes/event/query"
 	"github.com/modernice/goes/internal/projectiontest"
 	"github.com/modernice/goes/projection"
 	"github.com/modernice/goes/projection/schedule"
@@ -147,3 +149,172 @@ L:
 		t.Fatalf("Projection should have been reset")
 	}
 }
+
+func TestAuthenticate(t *testing.T) {
+	key := []byte("secret")
+
+	tests := []struct {
+		name    string
+		opts    []projection.ServiceOption
+		trigger []projection.ServiceOption
+		options []projection.TriggerOption
+		wantErr bool
+	}{
+		{
+			name:    "signed",
+			opts:    []projection.ServiceOption{projection.Authenticate(projection.HMACAuthenticator{"team-a": key})},
+			trigger: []projection.ServiceOption{projection.SignTriggers(projection.HMAC("team-a", key))},
+		},
+		{
+			name:    "unsigned",
+			opts:    []projection.ServiceOption{projection.Authenticate(projection.HMACAuthenticator{"team-a": key})},
+			wantErr: true,
+		},
+		{
+			name:    "wrong key",
+			opts:    []projection.ServiceOption{projection.Authenticate(projection.HMACAuthenticator{"team-a": key})},
+			trigger: []projection.ServiceOption{projection.SignTriggers(projection.HMAC("team-a", []byte("guess")))},
+			wantErr: true,
+		},
+		{
+			name:    "unknown principal",
+			opts:    []projection.ServiceOption{projection.Authenticate(projection.HMACAuthenticator{"team-a": key})},
+			trigger: []projection.ServiceOption{projection.SignTriggers(projection.HMAC("team-b", key))},
+			wantErr: true,
+		},
+		{
+			name: "principal not allowed",
+			opts: []projection.ServiceOption{
+				projection.Authenticate(projection.HMACAuthenticator{"team-a": key, "team-b": key}),
+				projection.AllowPrincipals("team-a"),
+			},
+			trigger: []projection.ServiceOption{projection.SignTriggers(projection.HMAC("team-b", key))},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			opts: []projection.ServiceOption{
+				projection.Authenticate(projection.HMACAuthenticator{"team-a": key}),
+				projection.MaxTriggerAge(time.Nanosecond),
+			},
+			trigger: []projection.ServiceOption{projection.SignTriggers(projection.HMAC("team-a", key))},
+			wantErr: true,
+		},
+		{
+			name:    "custom query",
+			opts:    []projection.ServiceOption{projection.Authenticate(projection.HMACAuthenticator{"team-a": key})},
+			trigger: []projection.ServiceOption{projection.SignTriggers(projection.HMAC("team-a", key))},
+			options: []projection.TriggerOption{projection.Query(query.New(query.Name("foo")))},
+			wantErr: true,
+		},
+		{
+			name:    "filter",
+			opts:    []projection.ServiceOption{projection.Authenticate(projection.HMACAuthenticator{"team-a": key})},
+			trigger: []projection.ServiceOption{projection.SignTriggers(projection.HMAC("team-a", key))},
+			options: []projection.TriggerOption{projection.Filter(query.New(query.Name("foo")))},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			bus := eventbus.New()
+			s := &triggerCountingSchedule{}
+
+			handler := projection.NewService(bus, append(tt.opts, projection.RegisterSchedule("example", s))...)
+			handlerErrors, err := handler.Run(ctx)
+			if err != nil {
+				t.Fatalf("Run failed with %q", err)
+			}
+
+			svc := projection.NewService(bus, append(tt.trigger, projection.TriggerTimeout(time.Second))...)
+			err = svc.Trigger(ctx, "example", append([]projection.TriggerOption{projection.Reset(true)}, tt.options...)...)
+
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Trigger failed with %q", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, projection.ErrUnauthorizedTrigger) {
+				t.Fatalf("Trigger should fail with %q; got %q", projection.ErrUnauthorizedTrigger, err)
+			}
+
+			select {
+			case <-time.After(time.Second):
+				t.Fatalf("rejection should be reported by the handler Service")
+			case err := <-handlerErrors:
+				if !errors.Is(err, projection.ErrUnauthorizedTrigger) {
+					t.Fatalf("handler Service should report %q; got %q", projection.ErrUnauthorizedTrigger, err)
+				}
+			}
+
+			if s.triggered.Load() != 0 {
+				t.Fatalf("rejected trigger should not trigger the schedule")
+			}
+		})
+	}
+}
+
+func TestAuthenticate_replay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := []byte("secret")
+	bus := eventbus.New()
+	s := &triggerCountingSchedule{}
+
+	handler := projection.NewService(bus,
+		projection.Authenticate(projection.HMACAuthenticator{"team-a": key}),
+		projection.RegisterSchedule("example", s),
+	)
+	handlerErrors, err := handler.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed with %q", err)
+	}
+
+	triggers, _, err := bus.Subscribe(ctx, projection.Triggered)
+	if err != nil {
+		t.Fatalf("subscribe to %q events: %v", projection.Triggered, err)
+	}
+
+	svc := projection.NewService(bus, projection.SignTriggers(projection.HMAC("team-a", key)), projection.TriggerTimeout(time.Second))
+	if err := svc.Trigger(ctx, "example"); err != nil {
+		t.Fatalf("Trigger failed with %q", err)
+	}
+
+	captured := <-triggers
+	if err := bus.Publish(ctx, captured); err != nil {
+		t.Fatalf("replay trigger: %v", err)
+	}
+
+	select {
+	case <-time.After(time.Second):
+		t.Fatalf("rejection of the replayed trigger should be reported by the handler Service")
+	case err := <-handlerErrors:
+		if !errors.Is(err, projection.ErrUnauthorizedTrigger) {
+			t.Fatalf("handler Service should report %q; got %q", projection.ErrUnauthorizedTrigger, err)
+		}
+	}
+
+	if n := s.triggered.Load(); n != 1 {
+		t.Fatalf("schedule should be triggered once; was triggered %d times", n)
+	}
+}
+
+type triggerCountingSchedule struct {
+	triggered atomic.Int64
+}
+
+func (s *triggerCountingSchedule) Subscribe(context.Context, func(projection.Job) error, ...projection.SubscribeOption) (<-chan error, error) {
+	return nil, nil
+}
+
+func (s *triggerCountingSchedule) Trigger(context.Context, ...projection.TriggerOption) error {
+	s.triggered.Add(1)
+	return nil
+}
@@ -2,6 +2,7 @@ package projection
 
 import (
 	"context"
+	"time"
 
 	"github.com/modernice/goes/event"
 )
@@ -17,6 +18,31 @@ type Subscription struct {
 	// BeforeEvent are the "before"-interceptors for the event streams created
 	// by a job's `EventsFor()` and `Apply()` methods.
 	BeforeEvent []func(context.Context, event.Event) ([]event.Event, error)
+
+	// JobTimeout is the maximum duration of a single job. A zero duration
+	// means no timeout.
+	JobTimeout time.Duration
+
+	// JobTimeoutGrace is the duration that a schedule waits for a timed out
+	// job to return before it releases the next job. A zero duration means the
+	// default grace period of the schedule.
+	JobTimeoutGrace time.Duration
+
+	// If provided, failed jobs are retried using this policy.
+	Retry *JobRetry
+
+	// Workers is the maximum number of jobs that are applied concurrently.
+	// Jobs that touch the same aggregate are applied in order. A value <= 1
+	// applies jobs one after another.
+	Workers int
+
+	// If provided, jobs that permanently failed are delivered to DeadLetter.
+	DeadLetter func(context.Context, DeadJob) error
+
+	// If provided, the progress of the subscription is persisted to
+	// Checkpoints under the name CheckpointName.
+	Checkpoints    CheckpointStore
+	CheckpointName string
 }
 
 // Startup returns a SubscribeOption that triggers an initial projection run